@@ -13,6 +13,8 @@ type BlackboxtargetData struct {
 	Url     string `json:"url"`
 	Service string `json:"service"`
 	Module  string `json:"module"`
+	// SopUrl is linked from the availability alert of the target
+	SopUrl string `json:"sopUrl,omitempty"`
 }
 
 // BlackboxTargetSpec defines the desired state of BlackboxTarget
@@ -23,15 +25,43 @@ type BlackboxTargetSpec struct {
 	BlackboxTargets []BlackboxtargetData `json:"blackboxTargets,omitempty"`
 }
 
+type BlackboxProbeState string
+
+var (
+	// BlackboxProbeReady is reported once the last probe of the target succeeded
+	BlackboxProbeReady BlackboxProbeState = "Ready"
+	// BlackboxProbePending is reported until Prometheus has a probe result for the target
+	BlackboxProbePending BlackboxProbeState = "Pending"
+	// BlackboxProbeFailed is reported when the probe could not be created or the last probe
+	// of the target failed
+	BlackboxProbeFailed BlackboxProbeState = "Failed"
+)
+
 // BlackboxTargetStatus defines the observed state of BlackboxTarget
 type BlackboxTargetStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
-	// Add custom validation using kubebuilder tags: https://book.kubebuilder.io/beyond_basics/generating_crd.html
+	// Deprecated: Phase is no longer set, see State. It is kept with its original integer
+	// type so the status of existing resources still decodes
 	Phase int `json:"phase"`
+	// State is completed once a probe and an availability alert exist for every target
+	State StatusPhase `json:"state,omitempty"`
+	// ObservedGeneration is the generation of the spec last reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Targets reports the state of the probe of each target
+	Targets []BlackboxTargetProbeStatus `json:"targets,omitempty"`
+}
+
+// BlackboxTargetProbeStatus is the state of the probe generated for a target
+type BlackboxTargetProbeStatus struct {
+	Service string `json:"service"`
+	Url     string `json:"url"`
+	// Probe is the name of the Probe in the observability namespace
+	Probe   string             `json:"probe,omitempty"`
+	State   BlackboxProbeState `json:"state"`
+	Message string             `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
 
 // BlackboxTarget is the Schema for the blackboxtargets API
 type BlackboxTarget struct {
@@ -152,6 +152,48 @@ type RHMISpec struct {
 	//
 	// url
 	DeadMansSnitchSecret string `json:"deadMansSnitchSecret,omitempty"`
+
+	// WebhookSecret is the name of an optional secret in the
+	// installation namespace configuring a generic webhook
+	// alert receiver. The secret must contain the following
+	// fields:
+	//
+	// url
+	//
+	// and may contain:
+	//
+	// token - sent as a bearer token
+	// severities - comma separated alert severities routed to
+	// the receiver, defaults to critical
+	WebhookSecret string `json:"webhookSecret,omitempty"`
+
+	// SlackSecret is the name of an optional secret in the
+	// installation namespace configuring a Slack alert
+	// receiver. The secret must contain the following fields:
+	//
+	// url - the Slack incoming webhook URL
+	//
+	// and may contain:
+	//
+	// channel
+	// severities
+	SlackSecret string `json:"slackSecret,omitempty"`
+
+	// TeamsSecret is the name of an optional secret in the
+	// installation namespace configuring a Microsoft Teams
+	// alert receiver. The secret must contain the following
+	// fields:
+	//
+	// url - the Teams incoming webhook URL
+	//
+	// and may contain:
+	//
+	// severities
+	//
+	// Teams receivers require Alertmanager v0.26.0 or later, the
+	// alertmanager configuration is not updated while an older
+	// version is installed
+	TeamsSecret string `json:"teamsSecret,omitempty"`
 }
 
 type PullSecretSpec struct {
@@ -191,6 +233,36 @@ type RHMIStatus struct {
 	ToQuota            string                        `json:"toQuota,omitempty"`
 	CustomSmtp         *CustomSmtpStatus             `json:"customSmtp,omitempty"`
 	CustomDomain       *CustomDomainStatus           `json:"customDomain,omitempty"`
+	// AlertSilences are the Alertmanager silences created by the operator for the
+	// duration of an upgrade or maintenance window
+	AlertSilences []AlertSilenceStatus `json:"alertSilences,omitempty"`
+	// SLOs are the service level objectives of the core components and their
+	// remaining error budget
+	SLOs []SLOStatus `json:"slos,omitempty"`
+	// CustomerDashboards are the dashboards imported from the customer ConfigMaps into
+	// the customer Grafana and the errors of the dashboards that could not be imported
+	CustomerDashboards []CustomerDashboardStatus `json:"customerDashboards,omitempty"`
+}
+
+type AlertSilenceStatus struct {
+	ID     string      `json:"id"`
+	Reason string      `json:"reason"`
+	EndsAt metav1.Time `json:"endsAt"`
+}
+
+type SLOStatus struct {
+	Name string `json:"name"`
+	// Objective is the percentage of good events targeted over the SLO period
+	Objective string `json:"objective"`
+	// ErrorBudgetRemaining is the percentage of the error budget left in the SLO period
+	ErrorBudgetRemaining string `json:"errorBudgetRemaining,omitempty"`
+}
+
+type CustomerDashboardStatus struct {
+	// Name identifies the dashboard as <namespace>/<configmap>/<key>
+	Name     string `json:"name"`
+	Imported bool   `json:"imported"`
+	Error    string `json:"error,omitempty"`
 }
 
 type RHMIStageStatus struct {
@@ -208,6 +280,18 @@ type RHMIProductStatus struct {
 	Mobile          bool            `json:"mobile,omitempty"`
 	Phase           StatusPhase     `json:"status"`
 	Uninstall       bool            `json:"uninstall,omitempty"`
+	// Components are the effective sizing of the product components configured
+	// by the quota
+	Components []ComponentSizingStatus `json:"components,omitempty"`
+}
+
+type ComponentSizingStatus struct {
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+	// Requests and Limits are the resources of the component, e.g. cpu=190m,memory=90Mi
+	Requests    string `json:"requests,omitempty"`
+	Limits      string `json:"limits,omitempty"`
+	Concurrency int32  `json:"concurrency,omitempty"`
 }
 
 // +kubebuilder:object:root=true
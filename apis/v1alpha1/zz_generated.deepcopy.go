@@ -114,6 +114,22 @@ func (in *APIManagementTenantStatus) DeepCopy() *APIManagementTenantStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertSilenceStatus) DeepCopyInto(out *AlertSilenceStatus) {
+	*out = *in
+	in.EndsAt.DeepCopyInto(&out.EndsAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertSilenceStatus.
+func (in *AlertSilenceStatus) DeepCopy() *AlertSilenceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertSilenceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertingEmailAddresses) DeepCopyInto(out *AlertingEmailAddresses) {
 	*out = *in
@@ -135,7 +151,7 @@ func (in *BlackboxTarget) DeepCopyInto(out *BlackboxTarget) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackboxTarget.
@@ -188,6 +204,21 @@ func (in *BlackboxTargetList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackboxTargetProbeStatus) DeepCopyInto(out *BlackboxTargetProbeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackboxTargetProbeStatus.
+func (in *BlackboxTargetProbeStatus) DeepCopy() *BlackboxTargetProbeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackboxTargetProbeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlackboxTargetSpec) DeepCopyInto(out *BlackboxTargetSpec) {
 	*out = *in
@@ -211,6 +242,11 @@ func (in *BlackboxTargetSpec) DeepCopy() *BlackboxTargetSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlackboxTargetStatus) DeepCopyInto(out *BlackboxTargetStatus) {
 	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]BlackboxTargetProbeStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackboxTargetStatus.
@@ -238,6 +274,21 @@ func (in *BlackboxtargetData) DeepCopy() *BlackboxtargetData {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentSizingStatus) DeepCopyInto(out *ComponentSizingStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentSizingStatus.
+func (in *ComponentSizingStatus) DeepCopy() *ComponentSizingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentSizingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CustomDomainStatus) DeepCopyInto(out *CustomDomainStatus) {
 	*out = *in
@@ -268,6 +319,21 @@ func (in *CustomSmtpStatus) DeepCopy() *CustomSmtpStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomerDashboardStatus) DeepCopyInto(out *CustomerDashboardStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomerDashboardStatus.
+func (in *CustomerDashboardStatus) DeepCopy() *CustomerDashboardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomerDashboardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullSecretSpec) DeepCopyInto(out *PullSecretSpec) {
 	*out = *in
@@ -345,6 +411,11 @@ func (in *RHMIList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RHMIProductStatus) DeepCopyInto(out *RHMIProductStatus) {
 	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentSizingStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RHMIProductStatus.
@@ -381,7 +452,7 @@ func (in *RHMIStageStatus) DeepCopyInto(out *RHMIStageStatus) {
 		in, out := &in.Products, &out.Products
 		*out = make(map[ProductName]RHMIProductStatus, len(*in))
 		for key, val := range *in {
-			(*out)[key] = val
+			(*out)[key] = *val.DeepCopy()
 		}
 	}
 }
@@ -416,6 +487,23 @@ func (in *RHMIStatus) DeepCopyInto(out *RHMIStatus) {
 		*out = new(CustomDomainStatus)
 		**out = **in
 	}
+	if in.AlertSilences != nil {
+		in, out := &in.AlertSilences, &out.AlertSilences
+		*out = make([]AlertSilenceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SLOs != nil {
+		in, out := &in.SLOs, &out.SLOs
+		*out = make([]SLOStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.CustomerDashboards != nil {
+		in, out := &in.CustomerDashboards, &out.CustomerDashboards
+		*out = make([]CustomerDashboardStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RHMIStatus.
@@ -427,3 +515,18 @@ func (in *RHMIStatus) DeepCopy() *RHMIStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLOStatus) DeepCopyInto(out *SLOStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLOStatus.
+func (in *SLOStatus) DeepCopy() *SLOStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SLOStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,440 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/rhmi"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/slo"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// Labels identifying the probes and alerts generated for a BlackboxTarget. Owner
+	// references can not be used as they are created in the observability namespace
+	blackboxTargetLabel          = "integreatly.org/blackboxtarget"
+	blackboxTargetNamespaceLabel = "integreatly.org/blackboxtarget-namespace"
+	// Labels added to the probe_* series of each target. The alerts select on them so they
+	// never match the probes of the products, which use the blackbox job and the same
+	// service label
+	blackboxTargetMetricLabel          = "blackboxtarget"
+	blackboxTargetNamespaceMetricLabel = "blackboxtarget_namespace"
+
+	blackboxJobName          = "blackboxtarget"
+	blackboxProbeInterval    = "30s"
+	blackboxAlertGroupName   = "blackbox-targets.rules"
+	blackboxTargetAlertName  = "RHOAMBlackboxTargetUnavailable"
+	blackboxTargetAlertFor   = "5m"
+	blackboxResyncInterval   = 5 * time.Minute
+	blackboxPendingInterval  = time.Minute
+	blackboxTargetNamePrefix = "blackboxtarget-"
+
+	defaultInstallationConfigMapName = "installation-config"
+)
+
+// +kubebuilder:rbac:groups=integreatly.org,resources=blackboxtargets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=integreatly.org,resources=blackboxtargets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=monitoring.rhobs,resources=probes;prometheusrules,verbs=get;list;create;update;delete
+
+// BlackboxTargetReconciler generates a Probe in the observability namespace for each target
+// of a BlackboxTarget, together with an availability alert per target
+type BlackboxTargetReconciler struct {
+	k8sclient.Client
+	Scheme *runtime.Scheme
+	// serverClient reads and writes the observability namespace, which is not cached by
+	// the manager
+	serverClient k8sclient.Client
+	namespace    string
+	log          l.Logger
+	// newProbeResultClient returns the client reading the probe results from the Prometheus
+	// of the observability namespace
+	newProbeResultClient func(oboNamespace string) (ProbeResultClient, error)
+}
+
+func New(mgr manager.Manager, namespace string) (*BlackboxTargetReconciler, error) {
+	restConfig := controllerruntime.GetConfigOrDie()
+	restConfig.Timeout = time.Second * 10
+
+	serverClient, err := k8sclient.New(restConfig, k8sclient.Options{
+		Scheme: mgr.GetScheme(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlackboxTargetReconciler{
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		serverClient: serverClient,
+		namespace:    namespace,
+		log:          l.NewLoggerWithContext(l.Fields{l.ControllerLogContext: "blackboxtarget_controller"}),
+		newProbeResultClient: func(oboNamespace string) (ProbeResultClient, error) {
+			return NewProbeResultClient(slo.GetServiceURL(oboNamespace))
+		},
+	}, nil
+}
+
+func (r *BlackboxTargetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&integreatlyv1alpha1.BlackboxTarget{}).
+		Complete(r)
+}
+
+// withCorrelationID returns a copy of the reconciler logging with a new correlation ID, so
+// the lines logged during a single reconcile can be told apart
+func (r *BlackboxTargetReconciler) withCorrelationID() *BlackboxTargetReconciler {
+	reconciler := *r
+	reconciler.log = r.log.WithCorrelationID(l.NewCorrelationID())
+	return &reconciler
+}
+
+func (r *BlackboxTargetReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	r = r.withCorrelationID()
+
+	installation, err := rhmi.GetRhmiCr(r.Client, ctx, r.namespace, r.log)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if installation == nil {
+		return ctrl.Result{}, nil
+	}
+	oboNamespace := config.GetOboNamespace(installation.Namespace)
+
+	blackboxTarget := &integreatlyv1alpha1.BlackboxTarget{}
+	err = r.Get(ctx, request.NamespacedName, blackboxTarget)
+	if k8serr.IsNotFound(err) {
+		return ctrl.Result{}, r.deleteGenerated(ctx, oboNamespace, request.Namespace, request.Name, nil)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if blackboxTarget.DeletionTimestamp != nil || installation.DeletionTimestamp != nil {
+		return ctrl.Result{}, r.deleteGenerated(ctx, oboNamespace, request.Namespace, request.Name, nil)
+	}
+
+	oboConfig, err := r.getObservabilityConfig(ctx, installation)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := r.reconcileProbes(ctx, blackboxTarget, oboNamespace, oboConfig.GetBlackboxExporterAddress(oboNamespace))
+	r.reconcileProbeResults(ctx, blackboxTarget, oboNamespace, &status)
+
+	var ruleErr error
+	if err := r.reconcileAlerts(ctx, installation, blackboxTarget, oboNamespace); err != nil {
+		ruleErr = fmt.Errorf("failed to reconcile availability alerts: %w", err)
+		status.State = integreatlyv1alpha1.PhaseFailed
+	}
+
+	if err := r.updateStatus(ctx, blackboxTarget, status); err != nil {
+		return ctrl.Result{}, err
+	}
+	if ruleErr != nil {
+		return ctrl.Result{}, ruleErr
+	}
+
+	// Probes and alerts live outside the cached namespaces so changes to them are not
+	// watched. Resync periodically to restore them if they are modified and to refresh the
+	// probe results, sooner while a result is missing
+	for _, target := range status.Targets {
+		if target.State == integreatlyv1alpha1.BlackboxProbePending {
+			return ctrl.Result{RequeueAfter: blackboxPendingInterval}, nil
+		}
+	}
+	return ctrl.Result{RequeueAfter: blackboxResyncInterval}, nil
+}
+
+func (r *BlackboxTargetReconciler) getObservabilityConfig(ctx context.Context, installation *integreatlyv1alpha1.RHMI) (*config.Observability, error) {
+	installationCfgMap := os.Getenv("INSTALLATION_CONFIG_MAP")
+	if installationCfgMap == "" {
+		installationCfgMap = installation.Spec.NamespacePrefix + defaultInstallationConfigMapName
+	}
+	configManager, err := config.NewManager(ctx, r.Client, installation.Namespace, installationCfgMap, installation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation config: %w", err)
+	}
+	oboConfig, err := configManager.ReadObservability()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read observability config: %w", err)
+	}
+	return oboConfig, nil
+}
+
+// reconcileProbeResults sets the state of the targets with a probe to the last result of
+// the probe in Prometheus. Targets are left pending while the result can not be read
+func (r *BlackboxTargetReconciler) reconcileProbeResults(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget, oboNamespace string, status *integreatlyv1alpha1.BlackboxTargetStatus) {
+	results := map[string]bool{}
+	var resultErr error
+	probeResultClient, err := r.newProbeResultClient(oboNamespace)
+	if err == nil {
+		results, err = probeResultClient.GetProbeResults(ctx, blackboxTarget)
+	}
+	if err != nil {
+		r.log.Warning("Failed to read probe results: " + err.Error())
+		resultErr = err
+	}
+
+	for i, target := range status.Targets {
+		if target.Probe == "" {
+			continue
+		}
+		success, ok := results[target.Service]
+		switch {
+		case resultErr != nil:
+			status.Targets[i].State = integreatlyv1alpha1.BlackboxProbePending
+			status.Targets[i].Message = fmt.Sprintf("failed to read the probe result: %v", resultErr)
+		case !ok:
+			status.Targets[i].State = integreatlyv1alpha1.BlackboxProbePending
+			status.Targets[i].Message = "waiting for the first probe result"
+		case !success:
+			status.Targets[i].State = integreatlyv1alpha1.BlackboxProbeFailed
+			status.Targets[i].Message = "the last probe failed"
+		default:
+			status.Targets[i].State = integreatlyv1alpha1.BlackboxProbeReady
+			status.Targets[i].Message = ""
+		}
+	}
+}
+
+// reconcileProbes creates a Probe for each target and deletes the probes of targets that
+// were removed from the spec, returning the resulting status
+func (r *BlackboxTargetReconciler) reconcileProbes(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget, oboNamespace, exporterAddress string) integreatlyv1alpha1.BlackboxTargetStatus {
+	status := integreatlyv1alpha1.BlackboxTargetStatus{
+		State:              integreatlyv1alpha1.PhaseCompleted,
+		ObservedGeneration: blackboxTarget.Generation,
+	}
+
+	probeNames := []string{}
+	for _, target := range blackboxTarget.Spec.BlackboxTargets {
+		targetStatus := integreatlyv1alpha1.BlackboxTargetProbeStatus{
+			Service: target.Service,
+			Url:     target.Url,
+			State:   integreatlyv1alpha1.BlackboxProbePending,
+		}
+
+		probeName, err := r.reconcileProbe(ctx, blackboxTarget, target, oboNamespace, exporterAddress)
+		if err != nil {
+			r.log.Errorf("Failed to reconcile probe", l.Fields{"blackboxTarget": blackboxTarget.Name, "service": target.Service}, err)
+			targetStatus.State = integreatlyv1alpha1.BlackboxProbeFailed
+			targetStatus.Message = err.Error()
+			status.State = integreatlyv1alpha1.PhaseFailed
+		} else {
+			targetStatus.Probe = probeName
+			probeNames = append(probeNames, probeName)
+		}
+		status.Targets = append(status.Targets, targetStatus)
+	}
+
+	if err := r.deleteGenerated(ctx, oboNamespace, blackboxTarget.Namespace, blackboxTarget.Name, probeNames); err != nil {
+		r.log.Error("Failed to delete probes of removed targets", err)
+		status.State = integreatlyv1alpha1.PhaseFailed
+	}
+
+	return status
+}
+
+func (r *BlackboxTargetReconciler) reconcileProbe(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget, target integreatlyv1alpha1.BlackboxtargetData, oboNamespace, exporterAddress string) (string, error) {
+	if target.Url == "" || target.Module == "" {
+		return "", fmt.Errorf("url and module are required")
+	}
+	if errs := validation.IsDNS1123Label(target.Service); len(errs) > 0 {
+		return "", fmt.Errorf("invalid service %q: %v", target.Service, errs)
+	}
+
+	probe := &monv1.Probe{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getProbeName(blackboxTarget.Name, target.Service),
+			Namespace: oboNamespace,
+		},
+	}
+	or, err := controllerutil.CreateOrUpdate(ctx, r.serverClient, probe, func() error {
+		probe.Labels = generatedLabels(blackboxTarget)
+		probe.Spec = monv1.ProbeSpec{
+			JobName: blackboxJobName,
+			ProberSpec: monv1.ProberSpec{
+				URL:  exporterAddress,
+				Path: "/probe",
+			},
+			Module:   target.Module,
+			Interval: monv1.Duration(blackboxProbeInterval),
+			Targets: monv1.ProbeTargets{
+				StaticConfig: &monv1.ProbeTargetStaticConfig{
+					Targets: []string{target.Url},
+					Labels: map[string]string{
+						"service":                          target.Service,
+						blackboxTargetMetricLabel:          blackboxTarget.Name,
+						blackboxTargetNamespaceMetricLabel: blackboxTarget.Namespace,
+					},
+				},
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if or != controllerutil.OperationResultNone {
+		r.log.Infof("Operation result", l.Fields{"probe": probe.Name, "result": or})
+	}
+	return probe.Name, nil
+}
+
+// reconcileAlerts generates an availability alert for each target in a single
+// PrometheusRule, or removes the rule if there are no targets
+func (r *BlackboxTargetReconciler) reconcileAlerts(ctx context.Context, installation *integreatlyv1alpha1.RHMI, blackboxTarget *integreatlyv1alpha1.BlackboxTarget, oboNamespace string) error {
+	rule := &monv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      blackboxTargetNamePrefix + blackboxTarget.Name,
+			Namespace: oboNamespace,
+		},
+	}
+
+	rules := getAvailabilityRules(installation, blackboxTarget)
+	if len(rules) == 0 {
+		err := r.serverClient.Delete(ctx, rule)
+		if err != nil && !k8serr.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	or, err := controllerutil.CreateOrUpdate(ctx, r.serverClient, rule, func() error {
+		rule.Labels = generatedLabels(blackboxTarget)
+		rule.Labels["integreatly"] = "yes"
+		rule.Spec = monv1.PrometheusRuleSpec{
+			Groups: []monv1.RuleGroup{
+				{
+					Name:  blackboxAlertGroupName,
+					Rules: rules,
+				},
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if or != controllerutil.OperationResultNone {
+		r.log.Infof("Operation result", l.Fields{"prometheusRule": rule.Name, "result": or})
+	}
+	return nil
+}
+
+func getAvailabilityRules(installation *integreatlyv1alpha1.RHMI, blackboxTarget *integreatlyv1alpha1.BlackboxTarget) []monv1.Rule {
+	var rules []monv1.Rule
+	for _, target := range blackboxTarget.Spec.BlackboxTargets {
+		if errs := validation.IsDNS1123Label(target.Service); len(errs) > 0 {
+			continue
+		}
+		sopUrl := target.SopUrl
+		if sopUrl == "" {
+			sopUrl = resources.SopUrlEndpointAvailableAlert
+		}
+		rules = append(rules, monv1.Rule{
+			Alert: blackboxTargetAlertName,
+			Annotations: map[string]string{
+				"sop_url": sopUrl,
+				"message": fmt.Sprintf("Blackbox target %s (%s) has been unavailable for more than %s", target.Service, target.Url, blackboxTargetAlertFor),
+			},
+			Expr:   intstr.FromString(fmt.Sprintf("%s < 1", probeSuccessSelector(blackboxTarget, target.Service))),
+			For:    monv1.Duration(blackboxTargetAlertFor),
+			Labels: map[string]string{"severity": "critical", "product": resources.InstallationNames[installation.Spec.Type], "service": target.Service},
+		})
+	}
+	return rules
+}
+
+// deleteGenerated deletes the probes generated for the BlackboxTarget except the ones
+// named in keep. When keep is nil the availability alerts are deleted too
+func (r *BlackboxTargetReconciler) deleteGenerated(ctx context.Context, oboNamespace, namespace, name string, keep []string) error {
+	probes := &monv1.ProbeList{}
+	err := r.serverClient.List(ctx, probes,
+		k8sclient.InNamespace(oboNamespace),
+		k8sclient.MatchingLabels{blackboxTargetLabel: name, blackboxTargetNamespaceLabel: namespace})
+	if err != nil {
+		return err
+	}
+
+	for _, probe := range probes.Items {
+		if contains(keep, probe.Name) {
+			continue
+		}
+		if err := r.serverClient.Delete(ctx, probe); err != nil && !k8serr.IsNotFound(err) {
+			return err
+		}
+		r.log.Infof("Deleted probe", l.Fields{"probe": probe.Name})
+	}
+
+	if keep != nil {
+		return nil
+	}
+	rule := &monv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      blackboxTargetNamePrefix + name,
+			Namespace: oboNamespace,
+		},
+	}
+	if err := r.serverClient.Delete(ctx, rule); err != nil && !k8serr.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *BlackboxTargetReconciler) updateStatus(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget, status integreatlyv1alpha1.BlackboxTargetStatus) error {
+	if reflect.DeepEqual(blackboxTarget.Status, status) {
+		return nil
+	}
+	blackboxTarget.Status = status
+	return r.Status().Update(ctx, blackboxTarget)
+}
+
+// probeSuccessSelector selects the probe_success series of a target of the BlackboxTarget,
+// or of all its targets when service is empty
+func probeSuccessSelector(blackboxTarget *integreatlyv1alpha1.BlackboxTarget, service string) string {
+	selector := fmt.Sprintf("probe_success{job='%s', %s='%s', %s='%s'",
+		blackboxJobName,
+		blackboxTargetMetricLabel, blackboxTarget.Name,
+		blackboxTargetNamespaceMetricLabel, blackboxTarget.Namespace)
+	if service != "" {
+		selector += fmt.Sprintf(", service='%s'", service)
+	}
+	return selector + "}"
+}
+
+func generatedLabels(blackboxTarget *integreatlyv1alpha1.BlackboxTarget) map[string]string {
+	return map[string]string{
+		blackboxTargetLabel:             blackboxTarget.Name,
+		blackboxTargetNamespaceLabel:    blackboxTarget.Namespace,
+		config.GetOboLabelSelectorKey(): config.GetOboLabelSelector(),
+	}
+}
+
+func getProbeName(blackboxTargetName, service string) string {
+	return fmt.Sprintf("%s%s-%s", blackboxTargetNamePrefix, blackboxTargetName, service)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
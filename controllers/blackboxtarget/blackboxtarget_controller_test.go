@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/utils"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	testNamespace    = "redhat-rhoam-operator"
+	testOboNamespace = "redhat-rhoam-operator-observability"
+)
+
+func TestBlackboxTargetReconciler_Reconcile(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installation := &integreatlyv1alpha1.RHMI{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rhoam",
+			Namespace: testNamespace,
+		},
+		Spec: integreatlyv1alpha1.RHMISpec{
+			Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
+		},
+	}
+	blackboxTarget := &integreatlyv1alpha1.BlackboxTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "console",
+			Namespace: testNamespace,
+		},
+		Spec: integreatlyv1alpha1.BlackboxTargetSpec{
+			BlackboxTargets: []integreatlyv1alpha1.BlackboxtargetData{
+				{Service: "admin-ui", Url: "https://admin.example.com", Module: "http_2xx"},
+				{Service: "developer-ui", Url: "https://developer.example.com", Module: "http_2xx", SopUrl: "https://sop.example.com"},
+				{Service: "Invalid_Service", Url: "https://invalid.example.com", Module: "http_2xx"},
+			},
+		},
+	}
+
+	client := utils.NewTestClient(scheme, installation, blackboxTarget)
+	r := &BlackboxTargetReconciler{
+		Client:       client,
+		Scheme:       scheme,
+		serverClient: client,
+		namespace:    testNamespace,
+		log:          l.NewLogger(),
+		newProbeResultClient: func(oboNamespace string) (ProbeResultClient, error) {
+			return &probeResultClientMock{results: map[string]bool{"admin-ui": true, "developer-ui": false}}, nil
+		},
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: blackboxTarget.Name, Namespace: testNamespace}}
+
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, service := range []string{"admin-ui", "developer-ui"} {
+		probe := &monv1.Probe{}
+		if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: getProbeName(blackboxTarget.Name, service), Namespace: testOboNamespace}, probe); err != nil {
+			t.Fatalf("expected probe for %s: %v", service, err)
+		}
+		if probe.Spec.JobName != blackboxJobName || probe.Spec.Targets.StaticConfig.Labels["service"] != service {
+			t.Errorf("unexpected probe spec for %s: %+v", service, probe.Spec)
+		}
+		if probe.Spec.Targets.StaticConfig.Labels[blackboxTargetMetricLabel] != blackboxTarget.Name {
+			t.Errorf("expected probe for %s to be labelled with the blackbox target: %v", service, probe.Spec.Targets.StaticConfig.Labels)
+		}
+		if probe.Spec.ProberSpec.URL != "blackbox-exporter."+testOboNamespace+".svc:9115" {
+			t.Errorf("unexpected exporter address for %s: %s", service, probe.Spec.ProberSpec.URL)
+		}
+	}
+
+	rule := &monv1.PrometheusRule{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: blackboxTargetNamePrefix + blackboxTarget.Name, Namespace: testOboNamespace}, rule); err != nil {
+		t.Fatalf("expected availability alerts: %v", err)
+	}
+	rules := rule.Spec.Groups[0].Rules
+	if len(rules) != 2 {
+		t.Fatalf("expected an alert per valid target, got %d", len(rules))
+	}
+	if rules[0].Annotations["sop_url"] != resources.SopUrlEndpointAvailableAlert || rules[1].Annotations["sop_url"] != "https://sop.example.com" {
+		t.Errorf("unexpected sop urls: %v, %v", rules[0].Annotations, rules[1].Annotations)
+	}
+	// The products probe their own endpoints with the blackbox job and the same service
+	// labels, the alerts must only select the probes of the BlackboxTarget
+	expectedExpr := "probe_success{job='blackboxtarget', blackboxtarget='console', blackboxtarget_namespace='" + testNamespace + "', service='admin-ui'} < 1"
+	if rules[0].Expr.String() != expectedExpr {
+		t.Errorf("unexpected expression: %s", rules[0].Expr.String())
+	}
+
+	updated := &integreatlyv1alpha1.BlackboxTarget{}
+	if err := client.Get(context.TODO(), request.NamespacedName, updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.State != integreatlyv1alpha1.PhaseFailed {
+		t.Errorf("expected state failed for invalid target, got %s", updated.Status.State)
+	}
+	if len(updated.Status.Targets) != 3 {
+		t.Fatalf("expected status for each target, got %v", updated.Status.Targets)
+	}
+	if updated.Status.Targets[0].State != integreatlyv1alpha1.BlackboxProbeReady ||
+		updated.Status.Targets[1].State != integreatlyv1alpha1.BlackboxProbeFailed ||
+		updated.Status.Targets[2].State != integreatlyv1alpha1.BlackboxProbeFailed {
+		t.Errorf("unexpected target states: %v", updated.Status.Targets)
+	}
+
+	// Removing a target deletes its probe
+	updated.Spec.BlackboxTargets = updated.Spec.BlackboxTargets[:1]
+	if err := client.Update(context.TODO(), updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	probes := &monv1.ProbeList{}
+	if err := client.List(context.TODO(), probes, k8sclient.InNamespace(testOboNamespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(probes.Items) != 1 || probes.Items[0].Name != getProbeName(blackboxTarget.Name, "admin-ui") {
+		t.Fatalf("expected only the admin-ui probe to remain, got %v", probes.Items)
+	}
+
+	// Deleting the BlackboxTarget deletes the probes and alerts
+	if err := client.Delete(context.TODO(), updated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.List(context.TODO(), probes, k8sclient.InNamespace(testOboNamespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(probes.Items) != 0 {
+		t.Fatalf("expected probes to be deleted, got %v", probes.Items)
+	}
+	ruleList := &monv1.PrometheusRuleList{}
+	if err := client.List(context.TODO(), ruleList, k8sclient.InNamespace(testOboNamespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(ruleList.Items) != 0 {
+		t.Fatalf("expected alerts to be deleted, got %v", ruleList.Items)
+	}
+}
+
+func TestBlackboxTargetReconciler_reconcileProbeResults(t *testing.T) {
+	blackboxTarget := &integreatlyv1alpha1.BlackboxTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: "console", Namespace: testNamespace},
+	}
+	newStatus := func() *integreatlyv1alpha1.BlackboxTargetStatus {
+		return &integreatlyv1alpha1.BlackboxTargetStatus{
+			Targets: []integreatlyv1alpha1.BlackboxTargetProbeStatus{
+				{Service: "admin-ui", Probe: "blackboxtarget-console-admin-ui"},
+				{Service: "developer-ui", Probe: "blackboxtarget-console-developer-ui"},
+				{Service: "invalid", State: integreatlyv1alpha1.BlackboxProbeFailed},
+			},
+		}
+	}
+
+	tests := []struct {
+		Name           string
+		Client         ProbeResultClient
+		ExpectedStates []integreatlyv1alpha1.BlackboxProbeState
+	}{
+		{
+			Name:   "reports the last probe result",
+			Client: &probeResultClientMock{results: map[string]bool{"admin-ui": true, "developer-ui": false}},
+			ExpectedStates: []integreatlyv1alpha1.BlackboxProbeState{
+				integreatlyv1alpha1.BlackboxProbeReady,
+				integreatlyv1alpha1.BlackboxProbeFailed,
+				integreatlyv1alpha1.BlackboxProbeFailed,
+			},
+		},
+		{
+			Name:   "is pending until the first probe result",
+			Client: &probeResultClientMock{results: map[string]bool{"admin-ui": true}},
+			ExpectedStates: []integreatlyv1alpha1.BlackboxProbeState{
+				integreatlyv1alpha1.BlackboxProbeReady,
+				integreatlyv1alpha1.BlackboxProbePending,
+				integreatlyv1alpha1.BlackboxProbeFailed,
+			},
+		},
+		{
+			Name:   "is pending while prometheus can not be queried",
+			Client: &probeResultClientMock{err: fmt.Errorf("connection refused")},
+			ExpectedStates: []integreatlyv1alpha1.BlackboxProbeState{
+				integreatlyv1alpha1.BlackboxProbePending,
+				integreatlyv1alpha1.BlackboxProbePending,
+				integreatlyv1alpha1.BlackboxProbeFailed,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			r := &BlackboxTargetReconciler{
+				log: l.NewLogger(),
+				newProbeResultClient: func(oboNamespace string) (ProbeResultClient, error) {
+					return tt.Client, nil
+				},
+			}
+			status := newStatus()
+			r.reconcileProbeResults(context.TODO(), blackboxTarget, testOboNamespace, status)
+			for i, expected := range tt.ExpectedStates {
+				if status.Targets[i].State != expected {
+					t.Errorf("expected %s to be %s, got %s: %s", status.Targets[i].Service, expected, status.Targets[i].State, status.Targets[i].Message)
+				}
+			}
+		})
+	}
+}
+
+type probeResultClientMock struct {
+	results map[string]bool
+	err     error
+}
+
+func (m *probeResultClientMock) GetProbeResults(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget) (map[string]bool, error) {
+	return m.results, m.err
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	prometheusApi "github.com/prometheus/client_golang/api"
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const probeResultRequestTimeout = 10 * time.Second
+
+// ProbeResultClient reads the last probe result of each target of a BlackboxTarget, keyed by
+// the service of the target
+type ProbeResultClient interface {
+	GetProbeResults(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget) (map[string]bool, error)
+}
+
+type prometheusProbeResultClient struct {
+	api prometheusv1.API
+}
+
+var _ ProbeResultClient = &prometheusProbeResultClient{}
+
+func NewProbeResultClient(prometheusURL string) (ProbeResultClient, error) {
+	client, err := prometheusApi.NewClient(prometheusApi.Config{Address: prometheusURL})
+	if err != nil {
+		return nil, err
+	}
+	return &prometheusProbeResultClient{api: prometheusv1.NewAPI(client)}, nil
+}
+
+func (c *prometheusProbeResultClient) GetProbeResults(ctx context.Context, blackboxTarget *integreatlyv1alpha1.BlackboxTarget) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeResultRequestTimeout)
+	defer cancel()
+
+	query := probeSuccessSelector(blackboxTarget, "")
+	result, _, err := c.api.Query(ctx, query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", query, err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s for %s", result.Type(), query)
+	}
+
+	results := map[string]bool{}
+	for _, sample := range vector {
+		service := string(sample.Metric["service"])
+		if service == "" {
+			continue
+		}
+		results[service] = sample.Value == 1
+	}
+	return results, nil
+}
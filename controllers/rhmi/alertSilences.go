@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	rhmiv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/products/cloudresources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/alertmanager"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/k8s"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/rhmi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// upgradeSilenceDuration is how far ahead the upgrade silence ends, it is extended while
+	// the upgrade is in progress
+	upgradeSilenceDuration = 2 * time.Hour
+	// maxUpgradeSilenceDuration bounds the upgrade silence so a stuck upgrade still alerts
+	maxUpgradeSilenceDuration = 12 * time.Hour
+	// maintenanceWindowDuration matches the one hour maintenance window of the CRO strategies
+	maintenanceWindowDuration = time.Hour
+	// alertSilenceInterval is how often the silences are reconciled
+	alertSilenceInterval = time.Minute
+)
+
+// addAlertSilenceRunnable reconciles the alert silences on an interval outside of the
+// installation reconcile, so a slow or unavailable Alertmanager never delays it. Runs only
+// on the leader
+func (r *RHMIReconciler) addAlertSilenceRunnable(mgr ctrl.Manager) error {
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(alertSilenceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reconcileInstallationAlertSilences(ctx)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}))
+}
+
+// reconcileInstallationAlertSilences reconciles the silences of the installation and patches
+// the silences reported on its status
+func (r *RHMIReconciler) reconcileInstallationAlertSilences(ctx context.Context) {
+	namespace, err := k8s.GetWatchNamespace()
+	if err != nil {
+		log.Warning("Failed to get watch namespace, alert silences not reconciled: " + err.Error())
+		return
+	}
+	installation, err := rhmi.GetRhmiCr(r.Client, ctx, namespace, log)
+	if err != nil {
+		log.Warning("Failed to get installation, alert silences not reconciled: " + err.Error())
+		return
+	}
+	if installation == nil || installation.DeletionTimestamp != nil {
+		return
+	}
+
+	original := installation.DeepCopy()
+	client := alertmanager.NewSilenceClient(
+		alertmanager.GetServiceURL(config.GetOboNamespace(installation.Namespace)),
+		alertmanager.ServiceAccountTokenFile,
+	)
+	r.reconcileAlertSilences(ctx, installation, client, log)
+	if reflect.DeepEqual(original.Status.AlertSilences, installation.Status.AlertSilences) {
+		return
+	}
+	if err := r.Status().Patch(ctx, installation, k8sclient.MergeFrom(original)); err != nil {
+		log.Warning("Failed to update alert silences status: " + err.Error())
+	}
+}
+
+// reconcileAlertSilences keeps the alerts expected during an upgrade or the weekly cloud
+// resource maintenance window silenced and expires the silences once the operation completes.
+// Failures are logged only as Alertmanager is not available until the observability stage
+func (r *RHMIReconciler) reconcileAlertSilences(ctx context.Context, installation *rhmiv1alpha1.RHMI, client alertmanager.SilenceClient, log l.Logger) {
+	day, hour, err := cloudresources.GetMaintenanceWindow(ctx, r.Client, installation.Namespace)
+	if err != nil {
+		log.Warning("Failed to get maintenance window, alert silences not reconciled: " + err.Error())
+		return
+	}
+
+	requests := getAlertSilenceRequests(installation, day, hour, time.Now().UTC())
+	if len(requests) == 0 && len(installation.Status.AlertSilences) == 0 {
+		return
+	}
+
+	silences, err := alertmanager.ReconcileSilences(ctx, client, requests)
+	if errors.Is(err, alertmanager.ErrUnauthorized) {
+		log.Warning("Failed to reconcile alert silences, the operator service account is not authorized to manage Alertmanager silences: " + err.Error())
+		return
+	}
+	if err != nil {
+		log.Warning("Failed to reconcile alert silences: " + err.Error())
+		return
+	}
+
+	var silenceStatus []rhmiv1alpha1.AlertSilenceStatus
+	for _, silence := range silences {
+		silenceStatus = append(silenceStatus, rhmiv1alpha1.AlertSilenceStatus{
+			ID:     silence.ID,
+			Reason: alertmanager.GetSilenceReason(silence),
+			EndsAt: metav1.NewTime(silence.EndsAt),
+		})
+	}
+	installation.Status.AlertSilences = silenceStatus
+}
+
+// getAlertSilenceRequests returns the silences required at the given time, scoped to the
+// alerts of the installation by the product label
+func getAlertSilenceRequests(installation *rhmiv1alpha1.RHMI, maintenanceDay time.Weekday, maintenanceHour int, now time.Time) []alertmanager.SilenceRequest {
+	installationName := installationNames[installation.Spec.Type]
+	if installationName == "" {
+		return nil
+	}
+	productMatcher := alertmanager.Matcher{Name: "product", Value: installationName, IsEqual: true}
+	storageAlerts := ".*PostgresInstanceUnavailable|.*RedisCacheUnavailable"
+
+	var requests []alertmanager.SilenceRequest
+
+	// ToVersion is also set on the initial install, only an upgrade has a previous version
+	if installation.Status.ToVersion != "" && installation.Status.Version != "" {
+		requests = append(requests, alertmanager.SilenceRequest{
+			Key:    fmt.Sprintf("upgrade-%s", installation.Status.ToVersion),
+			Reason: fmt.Sprintf("upgrade from %s to %s in progress", installation.Status.Version, installation.Status.ToVersion),
+			Matchers: []alertmanager.Matcher{
+				productMatcher,
+				{
+					Name:    "alertname",
+					Value:   fmt.Sprintf("%sUpgradeExpectedDuration.*|%s", strings.ToUpper(installationName), storageAlerts),
+					IsRegex: true,
+					IsEqual: true,
+				},
+			},
+			EndsAt:      now.Add(upgradeSilenceDuration),
+			MaxDuration: maxUpgradeSilenceDuration,
+		})
+	}
+
+	if start, open := getMaintenanceWindowStart(maintenanceDay, maintenanceHour, now); open {
+		requests = append(requests, alertmanager.SilenceRequest{
+			Key:    fmt.Sprintf("maintenance-%s", start.Format("2006-01-02T15")),
+			Reason: "cloud resource maintenance window in progress",
+			Matchers: []alertmanager.Matcher{
+				productMatcher,
+				{Name: "alertname", Value: storageAlerts, IsRegex: true, IsEqual: true},
+			},
+			EndsAt: start.Add(maintenanceWindowDuration),
+		})
+	}
+
+	return requests
+}
+
+// getMaintenanceWindowStart returns the start of the most recent weekly maintenance window
+// (UTC) and whether the window is open at the given time
+func getMaintenanceWindowStart(day time.Weekday, hour int, now time.Time) (time.Time, bool) {
+	now = now.UTC()
+	daysSince := (int(now.Weekday()) - int(day) + 7) % 7
+	start := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC).AddDate(0, 0, -daysSince)
+	if start.After(now) {
+		start = start.AddDate(0, 0, -7)
+	}
+	return start, now.Before(start.Add(maintenanceWindowDuration))
+}
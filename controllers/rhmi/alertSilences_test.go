@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+)
+
+func TestGetMaintenanceWindowStart(t *testing.T) {
+	// 2023-06-01 is a Thursday
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart time.Time
+		wantOpen  bool
+	}{
+		{
+			name:      "inside the window",
+			now:       time.Date(2023, 6, 1, 2, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2023, 6, 1, 2, 0, 0, 0, time.UTC),
+			wantOpen:  true,
+		},
+		{
+			name:      "before the window on the maintenance day",
+			now:       time.Date(2023, 6, 1, 1, 59, 0, 0, time.UTC),
+			wantStart: time.Date(2023, 5, 25, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "after the window closes",
+			now:       time.Date(2023, 6, 1, 3, 0, 0, 0, time.UTC),
+			wantStart: time.Date(2023, 6, 1, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "later in the week",
+			now:       time.Date(2023, 6, 5, 2, 30, 0, 0, time.UTC),
+			wantStart: time.Date(2023, 6, 1, 2, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, open := getMaintenanceWindowStart(time.Thursday, 2, tt.now)
+			if !start.Equal(tt.wantStart) || open != tt.wantOpen {
+				t.Errorf("expected start %v open %v, got %v %v", tt.wantStart, tt.wantOpen, start, open)
+			}
+		})
+	}
+}
+
+func TestGetAlertSilenceRequests(t *testing.T) {
+	outsideWindow := time.Date(2023, 6, 5, 12, 0, 0, 0, time.UTC)
+	insideWindow := time.Date(2023, 6, 1, 2, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		status   integreatlyv1alpha1.RHMIStatus
+		now      time.Time
+		wantKeys []string
+	}{
+		{
+			name:   "no silences when idle",
+			status: integreatlyv1alpha1.RHMIStatus{Version: "1.1.0"},
+			now:    outsideWindow,
+		},
+		{
+			name:   "no silence on initial install",
+			status: integreatlyv1alpha1.RHMIStatus{ToVersion: "1.1.0"},
+			now:    outsideWindow,
+		},
+		{
+			name:     "silence during upgrade",
+			status:   integreatlyv1alpha1.RHMIStatus{Version: "1.1.0", ToVersion: "1.2.0"},
+			now:      outsideWindow,
+			wantKeys: []string{"upgrade-1.2.0"},
+		},
+		{
+			name:     "silence during maintenance window",
+			status:   integreatlyv1alpha1.RHMIStatus{Version: "1.1.0"},
+			now:      insideWindow,
+			wantKeys: []string{"maintenance-2023-06-01T02"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installation := &integreatlyv1alpha1.RHMI{
+				Spec:   integreatlyv1alpha1.RHMISpec{Type: string(integreatlyv1alpha1.InstallationTypeManagedApi)},
+				Status: tt.status,
+			}
+			requests := getAlertSilenceRequests(installation, time.Thursday, 2, tt.now)
+			if len(requests) != len(tt.wantKeys) {
+				t.Fatalf("expected %d requests, got %v", len(tt.wantKeys), requests)
+			}
+			for i, request := range requests {
+				if request.Key != tt.wantKeys[i] {
+					t.Errorf("expected key %s, got %s", tt.wantKeys[i], request.Key)
+				}
+				if request.Matchers[0].Name != "product" || request.Matchers[0].Value != "rhoam" {
+					t.Errorf("expected silence to be scoped by product, got %v", request.Matchers)
+				}
+				if !request.EndsAt.After(tt.now) {
+					t.Errorf("expected silence to end after %v, got %v", tt.now, request.EndsAt)
+				}
+				if strings.HasPrefix(request.Key, "upgrade-") && request.MaxDuration != maxUpgradeSilenceDuration {
+					t.Errorf("expected the upgrade silence to be extended up to %v, got %v", maxUpgradeSilenceDuration, request.MaxDuration)
+				}
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/marketplace"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/owner"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/slo"
 	"github.com/operator-framework/operator-lifecycle-manager/pkg/lib/ownerutil"
 
 	cs "github.com/integr8ly/integreatly-operator/pkg/resources/custom-smtp"
@@ -147,6 +148,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, errors.Wrap(err, "failed to check rate limit alert config settings")
 	}
 
+	phase, err = r.checkUserGroupMappingsConfig(ctx, serverClient)
+	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+		events.HandleError(r.recorder, installation, phase, "Failed to check user group mappings config settings", err)
+		return phase, errors.Wrap(err, "failed to check user group mappings config settings")
+	}
+
+	phase, err = r.checkUserSyncRulesConfig(ctx, serverClient)
+	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+		events.HandleError(r.recorder, installation, phase, "Failed to check user sync rules config settings", err)
+		return phase, errors.Wrap(err, "failed to check user sync rules config settings")
+	}
+
 	// TODO MGDAPI-5833 : Remove block
 	observabilityConfig, err := r.ConfigManager.ReadObservability()
 	if err != nil {
@@ -212,13 +225,25 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		}
 
 		// Creates remaining OBO alerts
-		phase, err = obo.OboAlertsReconciler(r.log, r.installation).ReconcileAlerts(ctx, serverClient)
+		oboAlertsReconciler, err := obo.OboAlertsReconciler(r.log, r.installation)
+		if err != nil {
+			events.HandleError(r.recorder, installation, integreatlyv1alpha1.PhaseFailed, "Failed to get OBO alerts reconciler", err)
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		phase, err = oboAlertsReconciler.ReconcileAlerts(ctx, serverClient)
 		r.log.Infof("Reconcile OBO alerts", l.Fields{"phase": phase})
 		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 			events.HandleError(r.recorder, installation, phase, "Failed to reconcile OBO alerts", err)
 			return phase, err
 		}
 
+		phase, err = slo.NewAlertReconciler(r.log, r.installation).ReconcileAlerts(ctx, serverClient)
+		r.log.Infof("Reconcile SLO alerts", l.Fields{"phase": phase})
+		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+			events.HandleError(r.recorder, installation, phase, "Failed to reconcile SLO alerts", err)
+			return phase, err
+		}
+
 	}
 
 	events.HandleStageComplete(r.recorder, installation, integreatlyv1alpha1.BootstrapStage)
@@ -382,6 +407,87 @@ func (r *Reconciler) checkRateLimitAlertsConfig(ctx context.Context, serverClien
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
+// checkUserGroupMappingsConfig creates the user group mappings ConfigMap with the default
+// mappings, leaving any mappings configured by the customer unchanged. Invalid mappings
+// fail the bootstrap stage rather than being ignored by the user sync
+func (r *Reconciler) checkUserGroupMappingsConfig(ctx context.Context, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
+	mappingsConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      userHelper.GroupMappingsConfigMapName,
+			Namespace: r.installation.Namespace,
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, serverClient, mappingsConfig, func() error {
+		owner.AddIntegreatlyOwnerAnnotations(mappingsConfig, r.installation)
+
+		if mappingsConfig.Data == nil {
+			mappingsConfig.Data = map[string]string{}
+		}
+
+		if _, ok := mappingsConfig.Data[userHelper.GroupMappingsKey]; ok {
+			return nil
+		}
+
+		defaultConfigJSON, err := json.MarshalIndent(userHelper.DefaultGroupMappings(), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		mappingsConfig.Data[userHelper.GroupMappingsKey] = string(defaultConfigJSON)
+
+		return nil
+	}); err != nil {
+		return integreatlyv1alpha1.PhaseInProgress, err
+	}
+
+	if _, err := userHelper.ParseGroupMappings(mappingsConfig.Data[userHelper.GroupMappingsKey]); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("invalid %s ConfigMap: %w", userHelper.GroupMappingsConfigMapName, err)
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+// checkUserSyncRulesConfig creates the user sync rules ConfigMap with the default rules,
+// leaving any rules configured by the customer unchanged, and validates the rules
+func (r *Reconciler) checkUserSyncRulesConfig(ctx context.Context, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
+	rulesConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      userHelper.SyncRulesConfigMapName,
+			Namespace: r.installation.Namespace,
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, serverClient, rulesConfig, func() error {
+		owner.AddIntegreatlyOwnerAnnotations(rulesConfig, r.installation)
+
+		if rulesConfig.Data == nil {
+			rulesConfig.Data = map[string]string{}
+		}
+
+		if _, ok := rulesConfig.Data[userHelper.SyncRulesKey]; ok {
+			return nil
+		}
+
+		defaultConfigJSON, err := json.MarshalIndent(userHelper.DefaultSyncRules(), "", "  ")
+		if err != nil {
+			return err
+		}
+
+		rulesConfig.Data[userHelper.SyncRulesKey] = string(defaultConfigJSON)
+
+		return nil
+	}); err != nil {
+		return integreatlyv1alpha1.PhaseInProgress, err
+	}
+
+	if _, err := userHelper.ParseSyncRules(rulesConfig.Data[userHelper.SyncRulesKey]); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("invalid %s ConfigMap: %w", userHelper.SyncRulesConfigMapName, err)
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
 func (r *Reconciler) reconcileTenantOauthSecrets(ctx context.Context, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
 
 	allTenants, err := userHelper.GetMultiTenantUsers(ctx, serverClient)
@@ -993,3 +993,133 @@ func TestReconciler_checkCloudResourcesConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestReconciler_checkUserGroupMappingsConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	installation := &integreatlyv1alpha1.RHMI{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "rhoam",
+			Namespace: rhoamOperatorNs,
+		},
+	}
+	mappingsConfig := func(mappings string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      userHelper.GroupMappingsConfigMapName,
+				Namespace: rhoamOperatorNs,
+			},
+			Data: map[string]string{userHelper.GroupMappingsKey: mappings},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		serverClient k8sclient.Client
+		want         integreatlyv1alpha1.StatusPhase
+		wantErr      bool
+	}{
+		{
+			name:         "creates the default mappings",
+			serverClient: utils.NewTestClient(scheme),
+			want:         integreatlyv1alpha1.PhaseCompleted,
+		},
+		{
+			name:         "accepts valid customer mappings",
+			serverClient: utils.NewTestClient(scheme, mappingsConfig(`[{"openshiftGroup":"support","keycloakGroup":"support"}]`)),
+			want:         integreatlyv1alpha1.PhaseCompleted,
+		},
+		{
+			name:         "fails on invalid customer mappings",
+			serverClient: utils.NewTestClient(scheme, mappingsConfig(`[{"openshiftGroup":"support","keycloakGroup":"dedicated-admins"}]`)),
+			want:         integreatlyv1alpha1.PhaseFailed,
+			wantErr:      true,
+		},
+		{
+			name:         "fails on malformed customer mappings",
+			serverClient: utils.NewTestClient(scheme, mappingsConfig(`{"openshiftGroup":`)),
+			want:         integreatlyv1alpha1.PhaseFailed,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{
+				installation: installation,
+				log:          l.Logger{},
+			}
+			got, err := r.checkUserGroupMappingsConfig(context.TODO(), tt.serverClient)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkUserGroupMappingsConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("checkUserGroupMappingsConfig() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciler_checkUserSyncRulesConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	installation := &integreatlyv1alpha1.RHMI{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "rhoam",
+			Namespace: rhoamOperatorNs,
+		},
+	}
+	rulesConfig := func(rules string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      userHelper.SyncRulesConfigMapName,
+				Namespace: rhoamOperatorNs,
+			},
+			Data: map[string]string{userHelper.SyncRulesKey: rules},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		serverClient k8sclient.Client
+		want         integreatlyv1alpha1.StatusPhase
+		wantErr      bool
+	}{
+		{
+			name:         "creates the default rules",
+			serverClient: utils.NewTestClient(scheme),
+			want:         integreatlyv1alpha1.PhaseCompleted,
+		},
+		{
+			name:         "accepts valid customer rules",
+			serverClient: utils.NewTestClient(scheme, rulesConfig(`{"include":[{"groups":["developers"]}]}`)),
+			want:         integreatlyv1alpha1.PhaseCompleted,
+		},
+		{
+			name:         "fails on invalid customer rules",
+			serverClient: utils.NewTestClient(scheme, rulesConfig(`{"exclude":[{"userNamePattern":"("}]}`)),
+			want:         integreatlyv1alpha1.PhaseFailed,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reconciler{
+				installation: installation,
+				log:          l.Logger{},
+			}
+			got, err := r.checkUserSyncRulesConfig(context.TODO(), tt.serverClient)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkUserSyncRulesConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("checkUserSyncRulesConfig() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
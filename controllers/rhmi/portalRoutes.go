@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/integreatly-operator/pkg/products/threescale"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// portalRoutesInterval is how often the routes of the rate limited portals are checked
+const portalRoutesInterval = 30 * time.Second
+
+// addPortalRoutesRunnable points the routes of the rate limited portals back to the envoy
+// sidecar of system-app when zync resets them, rather than on the next installation reconcile.
+// Runs only on the leader. The 3scale namespace is not cached by the manager, so its routes are
+// read with a client of its own
+func (r *RHMIReconciler) addPortalRoutesRunnable(mgr ctrl.Manager) error {
+	routesClient, err := k8sclient.New(mgr.GetConfig(), k8sclient.Options{
+		Scheme: mgr.GetScheme(),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating client for portal routes: %v", err)
+	}
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(portalRoutesInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reconcilePortalRoutes(ctx, routesClient)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}))
+}
+
+func (r *RHMIReconciler) reconcilePortalRoutes(ctx context.Context, client k8sclient.Client) {
+	installation, configManager, err := r.getRHOAMConfigManager(ctx)
+	if err != nil {
+		log.Warning("Portal routes not reconciled: " + err.Error())
+		return
+	}
+	if configManager == nil {
+		return
+	}
+	threescaleConfig, err := configManager.ReadThreeScale()
+	if err != nil || threescaleConfig.GetNamespace() == "" {
+		// 3scale is not installed yet
+		return
+	}
+
+	rerouted, err := threescale.ReconcilePortalRoutes(ctx, client, installation.Namespace, threescaleConfig.GetNamespace())
+	if len(rerouted) > 0 {
+		log.Info("Routed the portal routes reset by zync back to the envoy sidecar: " + strings.Join(rerouted, ", "))
+	}
+	if err != nil {
+		log.Warning("Failed to reconcile the portal routes: " + err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/alerttest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewAlertsReconciler_CriticalMetricsMissing(t *testing.T) {
+	installation := &integreatlyv1alpha1.RHMI{
+		ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-rhoam-operator"},
+		Spec:       integreatlyv1alpha1.RHMISpec{Type: string(integreatlyv1alpha1.InstallationTypeManagedApi)},
+	}
+	criticalMetricsMissing := []alerttest.Alert{{
+		Labels:      map[string]string{"severity": "critical"},
+		Annotations: map[string]string{"message": "one or more critical metrics relating to RHOAM installation/upgrade have been missing for 30+ minutes"},
+	}}
+
+	reconciler := &RHMIReconciler{}
+	alerttest.RunAlertRuleTests(t, reconciler.newAlertsReconciler(installation), []alerttest.Test{
+		{
+			// rhoam_version is missing for 31m, present for 10m, missing for 31m and then present again
+			Name: "fires while rhoam_version is missing for 30m",
+			InputSeries: []alerttest.Series{
+				{Series: "rhoam_version", Values: "stale _x30 1+0x9 stale _x30 1+0x5"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{EvalTime: 29 * time.Minute, AlertName: "RHOAMCriticalMetricsMissing"},
+				{EvalTime: 30 * time.Minute, AlertName: "RHOAMCriticalMetricsMissing", ExpAlerts: criticalMetricsMissing},
+				{EvalTime: 31 * time.Minute, AlertName: "RHOAMCriticalMetricsMissing"},
+				{EvalTime: 70 * time.Minute, AlertName: "RHOAMCriticalMetricsMissing"},
+				{EvalTime: 71 * time.Minute, AlertName: "RHOAMCriticalMetricsMissing", ExpAlerts: criticalMetricsMissing},
+				{EvalTime: 72 * time.Minute, AlertName: "RHOAMCriticalMetricsMissing"},
+			},
+		},
+	})
+}
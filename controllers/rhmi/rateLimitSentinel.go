@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/integr8ly/integreatly-operator/pkg/products/marin3r"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// addRateLimitSentinelRunnable follows the failovers of the Redis of the rate limit service with
+// the sentinel topology, which the installation reconcile only catches every resync period. Runs
+// only on the leader. The redis Secrets of the rate limit namespace are not cached by the manager,
+// so they are read with a client of its own
+func (r *RHMIReconciler) addRateLimitSentinelRunnable(mgr ctrl.Manager) error {
+	sentinelClient, err := k8sclient.New(mgr.GetConfig(), k8sclient.Options{
+		Scheme: mgr.GetScheme(),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating client for rate limit sentinel: %v", err)
+	}
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(marin3r.SentinelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reconcileRateLimitSentinel(ctx, sentinelClient)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}))
+}
+
+func (r *RHMIReconciler) reconcileRateLimitSentinel(ctx context.Context, client k8sclient.Client) {
+	namespace, err := r.getRateLimitNamespace(ctx)
+	if err != nil {
+		log.Warning("Rate limit redis primary not resolved: " + err.Error())
+		return
+	}
+	if namespace == "" {
+		return
+	}
+	if err := marin3r.ReconcileSentinelPrimary(ctx, client, namespace); err != nil {
+		log.Warning("Failed to resolve the rate limit redis primary: " + err.Error())
+	}
+}
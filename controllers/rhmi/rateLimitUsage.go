@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	rhmiv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/products/marin3r"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/k8s"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/rhmi"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// addRateLimitUsageRunnable reads the rate limit counters outside of the installation
+// reconcile, as often as the windows of the limits need rather than on each reconcile. Runs
+// only on the leader. The rate limit namespace is not cached by the manager, so it is read
+// with a client of its own
+func (r *RHMIReconciler) addRateLimitUsageRunnable(mgr ctrl.Manager) error {
+	usageClient, err := k8sclient.New(mgr.GetConfig(), k8sclient.Options{
+		Scheme: mgr.GetScheme(),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating client for rate limit usage: %v", err)
+	}
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		timer := time.NewTimer(marin3r.MaxUsagePollInterval)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				timer.Reset(r.reconcileRateLimitUsage(ctx, usageClient))
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}))
+}
+
+// reconcileRateLimitUsage reports the rate limit usage of the installation and returns when it
+// should be reported next
+func (r *RHMIReconciler) reconcileRateLimitUsage(ctx context.Context, client k8sclient.Client) time.Duration {
+	namespace, err := r.getRateLimitNamespace(ctx)
+	if err != nil {
+		log.Warning("Rate limit usage not reported: " + err.Error())
+		return marin3r.MaxUsagePollInterval
+	}
+	if namespace == "" {
+		return marin3r.MaxUsagePollInterval
+	}
+
+	interval, err := marin3r.ReconcileUsage(ctx, client, namespace, time.Now())
+	if err != nil {
+		log.Warning("Failed to report rate limit usage: " + err.Error())
+	}
+	return interval
+}
+
+// getRateLimitNamespace returns the namespace of the rate limit service of the installation, or
+// an empty namespace when the installation does not rate limit or marin3r is not installed yet
+func (r *RHMIReconciler) getRateLimitNamespace(ctx context.Context) (string, error) {
+	_, configManager, err := r.getRHOAMConfigManager(ctx)
+	if err != nil || configManager == nil {
+		return "", err
+	}
+	marin3rConfig, err := configManager.ReadMarin3r()
+	if err != nil {
+		// marin3r is not installed yet
+		return "", nil
+	}
+	return marin3rConfig.GetNamespace(), nil
+}
+
+// getRHOAMConfigManager returns the installation along with its config manager, or nil when
+// there is no RHOAM installation to reconcile
+func (r *RHMIReconciler) getRHOAMConfigManager(ctx context.Context) (*rhmiv1alpha1.RHMI, config.ConfigReadWriter, error) {
+	namespace, err := k8s.GetWatchNamespace()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get watch namespace: %w", err)
+	}
+	installation, err := rhmi.GetRhmiCr(r.Client, ctx, namespace, log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get installation: %w", err)
+	}
+	if installation == nil || installation.DeletionTimestamp != nil || !rhmiv1alpha1.IsRHOAM(rhmiv1alpha1.InstallationType(installation.Spec.Type)) {
+		return nil, nil, nil
+	}
+
+	installationCfgMap := os.Getenv("INSTALLATION_CONFIG_MAP")
+	if installationCfgMap == "" {
+		installationCfgMap = installation.Spec.NamespacePrefix + DefaultInstallationConfigMapName
+	}
+	configManager, err := config.NewManager(ctx, r.Client, installation.Namespace, installationCfgMap, installation)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the installation config: %w", err)
+	}
+	return installation, configManager, nil
+}
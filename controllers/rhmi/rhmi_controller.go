@@ -26,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/cluster"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/k8s"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
@@ -266,6 +267,10 @@ func (r *RHMIReconciler) Reconcile(ctx context.Context, request ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	reconcileID := l.NewCorrelationID()
+	log := log.WithCorrelationID(reconcileID)
+	r.reconcileLogLevels(ctx, installation, log)
+
 	alertsClient, err := k8sclient.New(r.mgr.GetConfig(), k8sclient.Options{
 		Scheme: r.mgr.GetScheme(),
 	})
@@ -415,7 +420,7 @@ func (r *RHMIReconciler) Reconcile(ctx context.Context, request ctrl.Request) (c
 		stage := installStages[i]
 		var err error
 		var stagePhase rhmiv1alpha1.StatusPhase
-		var stageLog = l.NewLoggerWithContext(l.Fields{l.StageLogContext: stage.Name})
+		var stageLog = l.NewLoggerWithContext(l.Fields{l.StageLogContext: stage.Name}).WithCorrelationID(reconcileID)
 
 		if stage.Name == rhmiv1alpha1.BootstrapStage {
 			stagePhase, err = r.bootstrapStage(installation, configManager, stageLog, installationQuota, request)
@@ -477,6 +482,7 @@ func (r *RHMIReconciler) Reconcile(ctx context.Context, request ctrl.Request) (c
 			metrics.SetQuota(installation.Status.Quota, installation.Status.ToQuota)
 		}
 	}
+
 	metrics.SetStatus(installation)
 
 	err = r.updateStatusAndObject(originalInstallation, installation)
@@ -700,7 +706,8 @@ func (r *RHMIReconciler) handleUninstallProduct(installation *rhmiv1alpha1.RHMI,
 		if productStatus.Uninstall || installation.DeletionTimestamp != nil {
 			uninstall = true
 		}
-		phase, err := reconciler.Reconcile(context.TODO(), installation, productStatus, serverClient, quota.QuotaProductConfig{}, uninstall)
+		productCtx := audit.WithActor(context.TODO(), audit.ControllerActor("rhmi", productName))
+		phase, err := reconciler.Reconcile(productCtx, installation, productStatus, serverClient, quota.QuotaProductConfig{}, uninstall)
 		if err != nil {
 			merr.Add(fmt.Errorf("failed to reconcile product %s: %w", productName, err))
 		}
@@ -1020,8 +1027,38 @@ func (r *RHMIReconciler) bootstrapStage(installation *rhmiv1alpha1.RHMI, configM
 	return phase, nil
 }
 
+// reconcileLogLevels applies the log levels configured in the log levels ConfigMap of the
+// operator namespace, overridden per name by the log levels annotation on the RHMI CR.
+// Invalid configuration is logged and the previous levels are kept
+func (r *RHMIReconciler) reconcileLogLevels(ctx context.Context, installation *rhmiv1alpha1.RHMI, log l.Logger) {
+	configured := map[string]logrus.Level{}
+
+	configMap := &corev1.ConfigMap{}
+	err := r.Get(ctx, k8sclient.ObjectKey{Name: l.LogLevelsConfigMapName, Namespace: installation.Namespace}, configMap)
+	if err != nil && !k8serr.IsNotFound(err) {
+		log.Error("Failed to get log levels ConfigMap", err)
+		return
+	}
+	for _, spec := range []string{configMap.Data[l.LogLevelsKey], installation.Annotations[l.LogLevelsAnnotation]} {
+		levels, err := l.ParseLevels(spec)
+		if err != nil {
+			log.Error("Invalid log levels configuration", err)
+			return
+		}
+		for name, level := range levels {
+			configured[name] = level
+		}
+	}
+
+	previous := l.GetLevels()
+	l.SetLevels(configured)
+	if current := l.GetLevels(); current != previous {
+		log.Infof("Log levels updated", l.Fields{"levels": current})
+	}
+}
+
 func (r *RHMIReconciler) processStage(installation *rhmiv1alpha1.RHMI, stage *Stage,
-	configManager config.ConfigReadWriter, quotaconfig *quota.Quota, _ l.Logger) (rhmiv1alpha1.StatusPhase, error) {
+	configManager config.ConfigReadWriter, quotaconfig *quota.Quota, stageLog l.Logger) (rhmiv1alpha1.StatusPhase, error) {
 	incompleteStage := false
 	productVersionMismatchFound = false
 
@@ -1030,7 +1067,7 @@ func (r *RHMIReconciler) processStage(installation *rhmiv1alpha1.RHMI, stage *St
 
 	for productName := range stage.Products {
 		productStatus := stage.Products[productName]
-		productLog := l.NewLoggerWithContext(l.Fields{l.ProductLogContext: productStatus.Name})
+		productLog := l.NewLoggerWithContext(l.Fields{l.ProductLogContext: productStatus.Name}).WithCorrelationID(stageLog.CorrelationID())
 
 		reconciler, err := products.NewReconciler(productStatus.Name, r.restConfig, configManager, installation, r.mgr, productLog, r.productsInstallationLoader)
 
@@ -1053,7 +1090,8 @@ func (r *RHMIReconciler) processStage(installation *rhmiv1alpha1.RHMI, stage *St
 		if productStatus.Uninstall || installation.DeletionTimestamp != nil {
 			uninstall = true
 		}
-		productStatus.Phase, err = reconciler.Reconcile(context.TODO(), installation, &productStatus, serverClient, quotaconfig.GetProduct(productName), uninstall)
+		productCtx := audit.WithActor(context.TODO(), audit.ControllerActor("rhmi", string(productStatus.Name)))
+		productStatus.Phase, err = reconciler.Reconcile(productCtx, installation, &productStatus, serverClient, quotaconfig.GetProduct(productName), uninstall)
 
 		if err != nil {
 			if mErr == nil {
@@ -1200,7 +1238,19 @@ func (r *RHMIReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	r.controller = reconcileController
 
-	return nil
+	if err := r.addAlertSilenceRunnable(mgr); err != nil {
+		return err
+	}
+	if err := r.addRateLimitUsageRunnable(mgr); err != nil {
+		return err
+	}
+	if err := r.addRateLimitSentinelRunnable(mgr); err != nil {
+		return err
+	}
+	if err := r.addPortalRoutesRunnable(mgr); err != nil {
+		return err
+	}
+	return r.addSLOStatusRunnable(mgr)
 }
 
 func (r *RHMIReconciler) createInstallationCR(ctx context.Context, serverClient k8sclient.Client) (*rhmiv1alpha1.RHMI, error) {
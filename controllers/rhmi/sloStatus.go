@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	rhmiv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/k8s"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/rhmi"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/slo"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// sloStatusInterval is how often the SLO status is reconciled, the error budgets are
+// calculated over 7 days so they change slowly
+const sloStatusInterval = 5 * time.Minute
+
+// addSLOStatusRunnable reconciles the SLO status on an interval outside of the installation
+// reconcile, so a slow or unavailable Prometheus never delays it. Runs only on the leader
+func (r *RHMIReconciler) addSLOStatusRunnable(mgr ctrl.Manager) error {
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(sloStatusInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.reconcileInstallationSLOStatus(ctx)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}))
+}
+
+// reconcileInstallationSLOStatus reads the error budgets from Prometheus and patches the SLOs
+// reported on the installation status
+func (r *RHMIReconciler) reconcileInstallationSLOStatus(ctx context.Context) {
+	namespace, err := k8s.GetWatchNamespace()
+	if err != nil {
+		log.Warning("Failed to get watch namespace, SLO status not reconciled: " + err.Error())
+		return
+	}
+	installation, err := rhmi.GetRhmiCr(r.Client, ctx, namespace, log)
+	if err != nil {
+		log.Warning("Failed to get installation, SLO status not reconciled: " + err.Error())
+		return
+	}
+	if installation == nil || installation.DeletionTimestamp != nil {
+		return
+	}
+
+	budgetClient, err := slo.NewBudgetClient(slo.GetServiceURL(config.GetOboNamespace(installation.Namespace)))
+	if err != nil {
+		log.Warning("Failed to create SLO error budget client: " + err.Error())
+		return
+	}
+	original := installation.DeepCopy()
+	reconcileSLOStatus(ctx, installation, budgetClient, log)
+	if reflect.DeepEqual(original.Status.SLOs, installation.Status.SLOs) {
+		return
+	}
+	if err := r.Status().Patch(ctx, installation, k8sclient.MergeFrom(original)); err != nil {
+		log.Warning("Failed to update SLO status: " + err.Error())
+	}
+}
+
+// reconcileSLOStatus reports the objectives of the core components and their remaining
+// error budget. Failures are logged only as Prometheus is not available until the
+// observability stage, the objectives are then reported without a budget
+func reconcileSLOStatus(ctx context.Context, installation *rhmiv1alpha1.RHMI, client slo.BudgetClient, log l.Logger) {
+	budgets, err := client.GetErrorBudgetRemaining(ctx)
+	if err != nil {
+		log.Warning("Failed to get SLO error budgets: " + err.Error())
+	}
+	installation.Status.SLOs = slo.GetStatus(installation, budgets)
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rhmiv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type budgetClientMock struct {
+	budgets map[string]float64
+	err     error
+	ctx     context.Context
+}
+
+func (m *budgetClientMock) GetErrorBudgetRemaining(ctx context.Context) (map[string]float64, error) {
+	m.ctx = ctx
+	return m.budgets, m.err
+}
+
+func TestReconcileSLOStatus(t *testing.T) {
+	installation := &rhmiv1alpha1.RHMI{
+		ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-rhoam-operator"},
+		Spec:       rhmiv1alpha1.RHMISpec{Type: string(rhmiv1alpha1.InstallationTypeManagedApi), NamespacePrefix: "redhat-rhoam-"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &budgetClientMock{budgets: map[string]float64{"rhsso-availability": 0.25}}
+	reconcileSLOStatus(ctx, installation, client, l.NewLogger())
+	if client.ctx != ctx {
+		t.Error("expected the budgets to be read with the context of the caller")
+	}
+	budgets := map[string]string{}
+	for _, status := range installation.Status.SLOs {
+		budgets[status.Name] = status.ErrorBudgetRemaining
+	}
+	if len(budgets) != 6 || budgets["rhsso-availability"] != "25.00%" || budgets["rhsso-latency"] != "" {
+		t.Errorf("unexpected SLO status %v", installation.Status.SLOs)
+	}
+
+	reconcileSLOStatus(ctx, installation, &budgetClientMock{err: errors.New("connection refused")}, l.NewLogger())
+	for _, status := range installation.Status.SLOs {
+		if status.Objective == "" || status.ErrorBudgetRemaining != "" {
+			t.Errorf("expected the objectives to be reported without a budget, got %v", status)
+		}
+	}
+}
@@ -43,7 +43,7 @@ func New(mgr manager.Manager) (*TenantReconciler, error) {
 		Client: client,
 		Scheme: mgr.GetScheme(),
 		mgr:    mgr,
-		log:    l.Logger{},
+		log:    log,
 	}, nil
 }
 
@@ -54,21 +54,30 @@ type TenantReconciler struct {
 	log    l.Logger
 }
 
+// withCorrelationID returns a copy of the reconciler logging with a new correlation ID, so
+// the lines logged during a single reconcile can be told apart
+func (r *TenantReconciler) withCorrelationID() *TenantReconciler {
+	reconciler := *r
+	reconciler.log = r.log.WithCorrelationID(l.NewCorrelationID())
+	return &reconciler
+}
+
 func (r *TenantReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
-	log.Info(fmt.Sprintf("TenantReconciler request: %s", request))
+	r = r.withCorrelationID()
+	r.log.Info(fmt.Sprintf("TenantReconciler request: %s", request))
 
 	tenant, err := r.getAPIManagementTenant(request.Name, request.Namespace)
 	if err != nil {
 		if k8serr.IsNotFound(err) {
 			return ctrl.Result{}, nil
 		}
-		log.Error("failed to get APIManagementTenant", err)
+		r.log.Error("failed to get APIManagementTenant", err)
 		return ctrl.Result{}, err
 	}
 
 	isTenantVerified, rejectionReason, err := r.verifyAPIManagementTenant(tenant)
 	if err != nil {
-		log.Error("error verifying the APIManagementTenant CR", err)
+		r.log.Error("error verifying the APIManagementTenant CR", err)
 		if err1 := r.updateLastError(tenant, err.Error()); err1 != nil {
 			return ctrl.Result{}, err1
 		}
@@ -83,7 +92,7 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 			return ctrl.Result{}, err1
 		}
 
-		log.Warning(fmt.Sprintf("tenant %s in namespace %s will not be reconciled because %s", tenant.Name, tenant.Namespace, rejectionReason))
+		r.log.Warning(fmt.Sprintf("tenant %s in namespace %s will not be reconciled because %s", tenant.Name, tenant.Namespace, rejectionReason))
 		return ctrl.Result{}, nil
 	}
 
@@ -100,7 +109,7 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		return ctrl.Result{Requeue: true, RequeueAfter: 15 * time.Second}, nil
 	}
 	if err != nil {
-		log.Error("error reconciling tenant URL", err)
+		r.log.Error("error reconciling tenant URL", err)
 		if err1 := r.updateLastError(tenant, err.Error()); err1 != nil {
 			return ctrl.Result{Requeue: true, RequeueAfter: 15 * time.Second}, err1
 		}
@@ -112,7 +121,7 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, request ctrl.Request)
 		return ctrl.Result{}, err1
 	}
 
-	log.Info(fmt.Sprintf("TenantReconciler finished: %s", request))
+	r.log.Info(fmt.Sprintf("TenantReconciler finished: %s", request))
 	return ctrl.Result{}, nil
 }
 
@@ -142,7 +151,7 @@ func (r *TenantReconciler) getAPIManagementTenant(crName string, crNamespace str
 func (r *TenantReconciler) verifyAPIManagementTenant(tenant *v1alpha1.APIManagementTenant) (bool, string, error) {
 	// Skip verification if the tenant has already been verified
 	if tenant.Status.ProvisioningStatus != v1alpha1.ThreeScaleAccountReady && tenant.Status.ProvisioningStatus != v1alpha1.ThreeScaleAccountRequested && tenant.Status.ProvisioningStatus != v1alpha1.UserAnnotated {
-		log.Info(fmt.Sprintf("TenantReconciler verifyAPIManagementTenant: %v", tenant))
+		r.log.Info(fmt.Sprintf("TenantReconciler verifyAPIManagementTenant: %v", tenant))
 
 		// Fails if APIManagementTenant isn't from a namespace ending in -dev or -stage
 		if !strings.HasSuffix(tenant.Namespace, "-dev") && !strings.HasSuffix(tenant.Namespace, "-stage") {
@@ -185,7 +194,7 @@ func (r *TenantReconciler) verifyAPIManagementTenant(tenant *v1alpha1.APIManagem
 func (r *TenantReconciler) addAnnotationToUser(tenant *v1alpha1.APIManagementTenant) error {
 	// Only add the annotation to the User if its APIManagementTenant's ProvisioningStatus hasn't been set to a value yet.
 	if tenant.Status.ProvisioningStatus == "" {
-		log.Info(fmt.Sprintf("TenantReconciler addAnnotationToUser: %v", tenant))
+		r.log.Info(fmt.Sprintf("TenantReconciler addAnnotationToUser: %v", tenant))
 
 		user, err := r.getUserByTenantNamespace(tenant.Namespace)
 		if err != nil {
@@ -215,7 +224,7 @@ func (r *TenantReconciler) addAnnotationToUser(tenant *v1alpha1.APIManagementTen
 func (r *TenantReconciler) reconcileTenantUrl(tenant *v1alpha1.APIManagementTenant) (bool, error) {
 	tenantUrlReconciled := true
 	if tenant.Status.ProvisioningStatus != v1alpha1.ThreeScaleAccountReady {
-		log.Info(fmt.Sprintf("TenantReconciler reconcileTenantUrl: %v", tenant))
+		r.log.Info(fmt.Sprintf("TenantReconciler reconcileTenantUrl: %v", tenant))
 
 		tenantUrlReconciled = false // Reset value because tenant hasn't been reconciled yet
 		selector, err := labels.Parse("zync.3scale.net/route-to=system-provider")
@@ -298,7 +307,7 @@ func (r *TenantReconciler) updateLastError(tenant *v1alpha1.APIManagementTenant,
 	tenant.Status.LastError = message
 	err := r.Client.Status().Update(context.TODO(), tenant)
 	if err != nil {
-		log.Error("error updating status of APIManagementTenant CR", err)
+		r.log.Error("error updating status of APIManagementTenant CR", err)
 	}
 	return err
 }
@@ -82,7 +82,7 @@ func TestTenantReconciler_getAPIManagementTenant(t *testing.T) {
 			r := &TenantReconciler{
 				Client: tt.fields.Client,
 				Scheme: scheme,
-				log:    logger.Logger{},
+				log:    logger.NewLogger(),
 			}
 			got, err := r.getAPIManagementTenant(tt.args.crName, tt.args.crNamespace)
 			if (err != nil) != tt.wantErr {
@@ -154,7 +154,7 @@ func TestTenantReconciler_getUserByTenantNamespace(t *testing.T) {
 			r := &TenantReconciler{
 				Client: tt.fields.Client,
 				Scheme: scheme,
-				log:    logger.Logger{},
+				log:    logger.NewLogger(),
 			}
 			got, err := r.getUserByTenantNamespace(tt.args.ns)
 			if (err != nil) != tt.wantErr {
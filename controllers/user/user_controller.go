@@ -6,16 +6,22 @@ import (
 	"time"
 
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/rhmi"
 
 	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/integr8ly/integreatly-operator/utils"
 	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // +kubebuilder:rbac:groups=user.openshift.io,resources=groups,verbs=get;list;watch;create
@@ -27,11 +33,12 @@ var log = l.NewLoggerWithContext(l.Fields{l.ControllerLogContext: "user_controll
 // UserReconciler reconciles a User object
 type UserReconciler struct {
 	k8sclient.Client
-	Scheme *runtime.Scheme
-	mgr    manager.Manager
+	Scheme    *runtime.Scheme
+	mgr       manager.Manager
+	namespace string
 }
 
-func New(mgr manager.Manager) *UserReconciler {
+func New(mgr manager.Manager, namespace string) *UserReconciler {
 	restConfig := controllerruntime.GetConfigOrDie()
 	restConfig.Timeout = time.Second * 10
 
@@ -43,13 +50,15 @@ func New(mgr manager.Manager) *UserReconciler {
 	}
 
 	return &UserReconciler{
-		Client: client,
-		Scheme: mgr.GetScheme(),
-		mgr:    mgr,
+		Client:    client,
+		Scheme:    mgr.GetScheme(),
+		mgr:       mgr,
+		namespace: namespace,
 	}
 }
 
 func (r *UserReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	log := log.WithCorrelationID(l.NewCorrelationID())
 	log.Info("Reconciling User")
 
 	rhmiGroup := &usersv1.Group{
@@ -70,9 +79,14 @@ func (r *UserReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Re
 		return ctrl.Result{}, err
 	}
 
+	syncRules, err := r.getSyncRules(ctx, log)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	or, err := controllerutil.CreateOrUpdate(ctx, r.Client, rhmiGroup, func() error {
 
-		rhmiGroup.Users = mapUserNames(users, groups)
+		rhmiGroup.Users = mapUserNames(users, groups, syncRules)
 
 		return nil
 	})
@@ -81,11 +95,24 @@ func (r *UserReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Re
 	return ctrl.Result{}, err
 }
 
-func mapUserNames(users *usersv1.UserList, groups *usersv1.GroupList) []string {
+// getSyncRules reads the sync rules from the namespace of the RHMI CR, falling back to the
+// default rules while the CR does not exist
+func (r *UserReconciler) getSyncRules(ctx context.Context, log l.Logger) (*userHelper.SyncRules, error) {
+	installation, err := rhmi.GetRhmiCr(r.Client, ctx, r.namespace, log)
+	if err != nil {
+		return nil, err
+	}
+	if installation == nil {
+		return userHelper.DefaultSyncRules(), nil
+	}
+	return userHelper.GetSyncRules(ctx, r.Client, installation.Namespace)
+}
+
+func mapUserNames(users *usersv1.UserList, groups *usersv1.GroupList, syncRules *userHelper.SyncRules) []string {
 	var result = []string{}
 	for _, user := range users.Items {
 		// Certain users such as sre do not need to be added
-		if !userHelper.IsInExclusionGroup(user, groups) {
+		if !syncRules.IsExcluded(user, groups) {
 			result = append(result, user.Name)
 		}
 	}
@@ -96,5 +123,9 @@ func mapUserNames(users *usersv1.UserList, groups *usersv1.GroupList) []string {
 func (r *UserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&usersv1.User{}).
+		// Membership of rhmi-developers depends on the sync rules
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForObject{}, builder.WithPredicates(
+			utils.NamePredicate(userHelper.SyncRulesConfigMapName),
+		)).
 		Complete(r)
 }
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	usersv1 "github.com/openshift/api/user/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// pendingUsers records when a change to a user was first observed, so the time taken to
+// apply it to Keycloak can be reported once the sync succeeds
+type pendingUsers struct {
+	mu    sync.Mutex
+	since map[string]time.Time
+	now   func() time.Time
+}
+
+func newPendingUsers() *pendingUsers {
+	return &pendingUsers{
+		since: map[string]time.Time{},
+		now:   time.Now,
+	}
+}
+
+func (p *pendingUsers) observe(userName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.since[userName]; !ok {
+		p.since[userName] = p.now()
+	}
+}
+
+// done removes the user and returns how long ago the change was first observed
+func (p *pendingUsers) done(userName string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	since, ok := p.since[userName]
+	if !ok {
+		return 0
+	}
+	delete(p.since, userName)
+	return p.now().Sub(since)
+}
+
+// EnqueueUsersFromObject is an EventHandler that enqueues a request per OpenShift user
+// affected by a change to a User, Identity or Group. For groups, only the users whose
+// membership changed are enqueued. Example:
+// An update to the group Foo adding the user Bar will enqueue the following request:
+// { Name: "Bar", Namespace: "" }
+type EnqueueUsersFromObject struct {
+	pending *pendingUsers
+}
+
+var _ handler.EventHandler = &EnqueueUsersFromObject{}
+
+// Create implements EventHandler
+func (e *EnqueueUsersFromObject) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	e.addRequests(usersForObject(evt.Object), q)
+}
+
+// Update implements EventHandler
+func (e *EnqueueUsersFromObject) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	oldGroup, oldOk := evt.ObjectOld.(*usersv1.Group)
+	newGroup, newOk := evt.ObjectNew.(*usersv1.Group)
+	if oldOk && newOk {
+		e.addRequests(changedMembers(oldGroup.Users, newGroup.Users), q)
+		return
+	}
+
+	e.addRequests(usersForObject(evt.ObjectNew), q)
+}
+
+// Delete implements EventHandler
+func (e *EnqueueUsersFromObject) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	e.addRequests(usersForObject(evt.Object), q)
+}
+
+// Generic implements EventHandler
+func (e *EnqueueUsersFromObject) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	e.addRequests(usersForObject(evt.Object), q)
+}
+
+func (e *EnqueueUsersFromObject) addRequests(userNames []string, q workqueue.RateLimitingInterface) {
+	for _, userName := range userNames {
+		if userName == "" {
+			continue
+		}
+		e.pending.observe(userName)
+		q.Add(ctrl.Request{NamespacedName: types.NamespacedName{
+			Name: userName,
+		}})
+	}
+}
+
+func usersForObject(obj k8sclient.Object) []string {
+	switch o := obj.(type) {
+	case *usersv1.User:
+		return []string{o.Name}
+	case *usersv1.Identity:
+		return []string{o.User.Name}
+	case *usersv1.Group:
+		return o.Users
+	}
+	return nil
+}
+
+// changedMembers returns the users that are only present in one of the two lists
+func changedMembers(oldUsers, newUsers []string) []string {
+	oldSet := map[string]bool{}
+	for _, u := range oldUsers {
+		oldSet[u] = true
+	}
+	newSet := map[string]bool{}
+	for _, u := range newUsers {
+		newSet[u] = true
+	}
+
+	var changed []string
+	for _, u := range newUsers {
+		if !oldSet[u] {
+			changed = append(changed, u)
+		}
+	}
+	for _, u := range oldUsers {
+		if !newSet[u] {
+			changed = append(changed, u)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestEnqueueUsersFromObject(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Trigger  func(e *EnqueueUsersFromObject, q workqueue.RateLimitingInterface)
+		Expected []string
+	}{
+		{
+			Name: "user create enqueues the user",
+			Trigger: func(e *EnqueueUsersFromObject, q workqueue.RateLimitingInterface) {
+				e.Create(event.CreateEvent{Object: &usersv1.User{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}}, q)
+			},
+			Expected: []string{"alice"},
+		},
+		{
+			Name: "identity delete enqueues the identity's user",
+			Trigger: func(e *EnqueueUsersFromObject, q workqueue.RateLimitingInterface) {
+				e.Delete(event.DeleteEvent{Object: &usersv1.Identity{
+					ObjectMeta: metav1.ObjectMeta{Name: "idp:alice"},
+					User:       corev1.ObjectReference{Name: "alice"},
+				}}, q)
+			},
+			Expected: []string{"alice"},
+		},
+		{
+			Name: "group update enqueues only changed members",
+			Trigger: func(e *EnqueueUsersFromObject, q workqueue.RateLimitingInterface) {
+				e.Update(event.UpdateEvent{
+					ObjectOld: &usersv1.Group{Users: []string{"alice", "bob"}},
+					ObjectNew: &usersv1.Group{Users: []string{"bob", "carol"}},
+				}, q)
+			},
+			Expected: []string{"alice", "carol"},
+		},
+		{
+			Name: "group update without membership changes enqueues nothing",
+			Trigger: func(e *EnqueueUsersFromObject, q workqueue.RateLimitingInterface) {
+				e.Update(event.UpdateEvent{
+					ObjectOld: &usersv1.Group{Users: []string{"alice"}},
+					ObjectNew: &usersv1.Group{Users: []string{"alice"}},
+				}, q)
+			},
+			Expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer q.ShutDown()
+			e := &EnqueueUsersFromObject{pending: newPendingUsers()}
+
+			tt.Trigger(e, q)
+
+			var got []string
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				got = append(got, item.(ctrl.Request).Name)
+				q.Done(item)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.Expected) {
+				t.Fatalf("expected %v to be enqueued, got %v", tt.Expected, got)
+			}
+			for _, userName := range tt.Expected {
+				if _, ok := e.pending.since[userName]; !ok {
+					t.Fatalf("expected %s to be recorded as pending", userName)
+				}
+			}
+		})
+	}
+}
+
+func TestPendingUsers(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := newPendingUsers()
+	p.now = func() time.Time { return now }
+
+	p.observe("alice")
+	now = now.Add(5 * time.Second)
+	// A second event before the sync must not reset the observed time
+	p.observe("alice")
+	now = now.Add(5 * time.Second)
+
+	if lag := p.done("alice"); lag != 10*time.Second {
+		t.Fatalf("expected lag of 10s, got %s", lag)
+	}
+	if lag := p.done("alice"); lag != 0 {
+		t.Fatalf("expected no lag once done, got %s", lag)
+	}
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"time"
+)
+
+const (
+	defaultFullResyncInterval = 30 * time.Minute
+)
+
+type ControllerOptions struct {
+	// Namespace the operator and the RHMI CR are installed into
+	Namespace          string
+	FullResyncInterval time.Duration
+}
+
+type ControllerConfig interface {
+	ConfigureUserSyncController(*ControllerOptions)
+}
+
+func (c *ControllerOptions) Option(opts ...ControllerConfig) {
+	for _, opt := range opts {
+		opt.ConfigureUserSyncController(c)
+	}
+}
+
+func (c *ControllerOptions) Default() {
+	if c.FullResyncInterval == 0 {
+		c.FullResyncInterval = defaultFullResyncInterval
+	}
+}
+
+type WithNamespace string
+
+func (w WithNamespace) ConfigureUserSyncController(c *ControllerOptions) {
+	c.Namespace = string(w)
+}
+
+type WithFullResyncInterval time.Duration
+
+func (w WithFullResyncInterval) ConfigureUserSyncController(c *ControllerOptions) {
+	c.FullResyncInterval = time.Duration(w)
+}
@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/metrics"
+	"github.com/integr8ly/integreatly-operator/pkg/products/rhsso"
+	"github.com/integr8ly/integreatly-operator/pkg/products/rhssouser"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/rhmi"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
+	"github.com/integr8ly/integreatly-operator/utils"
+	keycloak "github.com/integr8ly/keycloak-client/apis/keycloak/v1alpha1"
+	keycloakCommon "github.com/integr8ly/keycloak-client/pkg/common"
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// fullResyncRequestName is enqueued by the resync ticker. OpenShift user names may not
+	// contain ":" so it can never clash with a user request
+	fullResyncRequestName = "rhmi:full-resync"
+
+	defaultInstallationConfigMapName = "installation-config"
+)
+
+// +kubebuilder:rbac:groups=user.openshift.io,resources=users;groups,verbs=watch;get;list
+// +kubebuilder:rbac:groups=user.openshift.io,resources=identities,verbs=watch;get;list
+// +kubebuilder:rbac:groups=config.openshift.io,resources=oauths,verbs=get;list
+// +kubebuilder:rbac:groups=keycloak.org,resources=keycloakusers,verbs=get;list;create;update;delete
+
+// UserSyncReconciler propagates changes to OpenShift users, identities and groups to the
+// RHSSO and user SSO Keycloak instances one user at a time, rather than listing every
+// user on each reconcile of the installation
+type UserSyncReconciler struct {
+	k8sclient.Client
+	Scheme *runtime.Scheme
+	// serverClient reads and writes the Keycloak namespaces, which are not cached by the
+	// manager
+	serverClient          k8sclient.Client
+	Log                   l.Logger
+	KeycloakClientFactory keycloakCommon.KeycloakClientFactory
+	cfg                   ControllerOptions
+	pending               *pendingUsers
+	resync                chan event.GenericEvent
+}
+
+func New(mgr manager.Manager, opts ...ControllerConfig) (*UserSyncReconciler, error) {
+	var cfg ControllerOptions
+
+	cfg.Option(opts...)
+	cfg.Default()
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.Timeout = time.Second * 10
+
+	serverClient, err := k8sclient.New(restConfig, k8sclient.Options{
+		Scheme: mgr.GetScheme(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserSyncReconciler{
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		serverClient:          serverClient,
+		Log:                   l.NewLoggerWithContext(l.Fields{l.ControllerLogContext: "usersync_controller"}),
+		KeycloakClientFactory: &keycloakCommon.LocalConfigKeycloakFactory{},
+		cfg:                   cfg,
+		pending:               newPendingUsers(),
+		resync:                make(chan event.GenericEvent),
+	}, nil
+}
+
+func (r *UserSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueueUsers := &EnqueueUsersFromObject{pending: r.pending}
+
+	err := ctrl.NewControllerManagedBy(mgr).
+		Named("usersync").
+		Watches(&source.Kind{Type: &usersv1.User{}}, enqueueUsers).
+		Watches(&source.Kind{Type: &usersv1.Identity{}}, enqueueUsers).
+		// Any group can be the subject of a group mapping, only members whose membership
+		// changed are enqueued
+		Watches(&source.Kind{Type: &usersv1.Group{}}, enqueueUsers).
+		Watches(&source.Channel{Source: r.resync}, &handler.EnqueueRequestForObject{}).
+		// The sync rules and group mappings apply to every user, a change to either
+		// triggers a full resync
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(func(k8sclient.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: fullResyncRequestName}}}
+		}), builder.WithPredicates(
+			predicate.And(
+				utils.NamespacePredicate(r.cfg.Namespace),
+				predicate.Or(
+					utils.NamePredicate(userHelper.SyncRulesConfigMapName),
+					utils.NamePredicate(userHelper.GroupMappingsConfigMapName),
+				),
+			),
+		)).
+		Complete(r)
+	if err != nil {
+		return err
+	}
+
+	// Periodic full resync as a safety net for missed events. Runs only on the leader
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		userHelper.SetIncrementalSyncEnabled(true)
+		defer userHelper.SetIncrementalSyncEnabled(false)
+
+		ticker := time.NewTicker(r.cfg.FullResyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case r.resync <- r.fullResyncEvent():
+			case <-ctx.Done():
+				return nil
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}))
+}
+
+func (r *UserSyncReconciler) fullResyncEvent() event.GenericEvent {
+	return event.GenericEvent{Object: &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fullResyncRequestName,
+		},
+	}}
+}
+
+// withCorrelationID returns a copy of the reconciler logging with a new correlation ID, so
+// the lines logged during a single reconcile can be told apart
+func (r *UserSyncReconciler) withCorrelationID() *UserSyncReconciler {
+	reconciler := *r
+	reconciler.Log = r.Log.WithCorrelationID(l.NewCorrelationID())
+	return &reconciler
+}
+
+func (r *UserSyncReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	r = r.withCorrelationID()
+	ctx = audit.WithActor(ctx, audit.ControllerActor("usersync"))
+
+	installation, err := rhmi.GetRhmiCr(r.Client, ctx, r.cfg.Namespace, r.Log)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if installation == nil || installation.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	if request.Name == fullResyncRequestName {
+		return ctrl.Result{}, r.fullResync(ctx, installation)
+	}
+
+	return ctrl.Result{}, r.syncUser(ctx, installation, request.Name)
+}
+
+func (r *UserSyncReconciler) syncUser(ctx context.Context, installation *integreatlyv1alpha1.RHMI, userName string) error {
+	targets, err := r.getSyncTargets(ctx, installation)
+	if err != nil {
+		metrics.IncUserSyncFailures(metrics.UserSyncIncremental)
+		return err
+	}
+
+	if targets.rhssoNamespace != "" {
+		or, err := rhsso.SyncOpenshiftUser(ctx, r.serverClient, targets.rhssoClient, installation, targets.rhssoNamespace, userName, r.Log)
+		if err != nil {
+			metrics.IncUserSyncFailures(metrics.UserSyncIncremental)
+			return fmt.Errorf("failed to sync user %s to rhsso: %w", userName, err)
+		}
+		r.Log.Infof("Operation result", l.Fields{"product": integreatlyv1alpha1.ProductRHSSO, "user": userName, "result": or})
+	}
+
+	if targets.rhssoUserNamespace != "" {
+		or, err := rhssouser.SyncAdminUser(ctx, r.serverClient, targets.userSSOClient, targets.rhssoUserNamespace, targets.groupMappings, userName)
+		if err != nil {
+			metrics.IncUserSyncFailures(metrics.UserSyncIncremental)
+			return fmt.Errorf("failed to sync user %s to user sso: %w", userName, err)
+		}
+		r.Log.Infof("Operation result", l.Fields{"product": integreatlyv1alpha1.ProductRHSSOUser, "user": userName, "result": or})
+	}
+
+	metrics.ObserveUserSyncLag(metrics.UserSyncIncremental, r.pending.done(userName))
+	return nil
+}
+
+func (r *UserSyncReconciler) fullResync(ctx context.Context, installation *integreatlyv1alpha1.RHMI) error {
+	r.Log.Info("Starting full resync of OpenShift users")
+	start := time.Now()
+
+	targets, err := r.getSyncTargets(ctx, installation)
+	if err != nil {
+		metrics.IncUserSyncFailures(metrics.UserSyncFull)
+		return err
+	}
+
+	if targets.rhssoNamespace != "" {
+		if err := rhsso.SyncOpenshiftUsers(ctx, r.serverClient, targets.rhssoClient, installation, targets.rhssoNamespace, r.Log); err != nil {
+			metrics.IncUserSyncFailures(metrics.UserSyncFull)
+			return fmt.Errorf("failed full resync of rhsso users: %w", err)
+		}
+	}
+
+	if targets.rhssoUserNamespace != "" {
+		if err := rhssouser.SyncAdminUsers(ctx, r.serverClient, targets.userSSOClient, targets.rhssoUserNamespace, targets.groupMappings, r.Log); err != nil {
+			metrics.IncUserSyncFailures(metrics.UserSyncFull)
+			return fmt.Errorf("failed full resync of user sso users: %w", err)
+		}
+	}
+
+	metrics.ObserveUserSyncLag(metrics.UserSyncFull, time.Since(start))
+	metrics.SetUserSyncLastFullResync(time.Now())
+	r.Log.Infof("Completed full resync of OpenShift users", l.Fields{"duration": time.Since(start).String()})
+	return nil
+}
+
+// syncTargets holds the namespaces of the Keycloak instances users are synchronised to.
+// An empty namespace means the product is not installed yet and is skipped
+type syncTargets struct {
+	rhssoNamespace     string
+	rhssoClient        keycloakCommon.KeycloakInterface
+	rhssoUserNamespace string
+	userSSOClient      keycloakCommon.KeycloakInterface
+	groupMappings      []userHelper.GroupMapping
+}
+
+func (r *UserSyncReconciler) getSyncTargets(ctx context.Context, installation *integreatlyv1alpha1.RHMI) (*syncTargets, error) {
+	targets := &syncTargets{}
+
+	installationCfgMap := os.Getenv("INSTALLATION_CONFIG_MAP")
+	if installationCfgMap == "" {
+		installationCfgMap = installation.Spec.NamespacePrefix + defaultInstallationConfigMapName
+	}
+	configManager, err := config.NewManager(ctx, r.Client, installation.Namespace, installationCfgMap, installation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read installation config: %w", err)
+	}
+
+	if isProductReconciled(installation, integreatlyv1alpha1.ProductRHSSO) {
+		rhssoConfig, err := configManager.ReadRHSSO()
+		if err != nil {
+			return nil, err
+		}
+		targets.rhssoNamespace = rhssoConfig.GetNamespace()
+
+		// The keycloak API is only used to resolve conflicting users in multitenant installations
+		if integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(installation.Spec.Type)) && targets.rhssoNamespace != "" {
+			kc := &keycloak.Keycloak{}
+			err = r.serverClient.Get(ctx, k8sclient.ObjectKey{Name: rhsso.GetKeycloakName(), Namespace: targets.rhssoNamespace}, kc)
+			if err != nil && !k8serr.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get rhsso keycloak: %w", err)
+			}
+			if err == nil {
+				targets.rhssoClient, err = r.KeycloakClientFactory.AuthenticatedClient(*kc)
+				if err != nil {
+					return nil, fmt.Errorf("failed to authenticate client in keycloak api: %w", err)
+				}
+			}
+		}
+	}
+
+	if isProductReconciled(installation, integreatlyv1alpha1.ProductRHSSOUser) {
+		rhssoUserConfig, err := configManager.ReadRHSSOUser()
+		if err != nil {
+			return nil, err
+		}
+		ns := rhssoUserConfig.GetNamespace()
+		if ns == "" {
+			return targets, nil
+		}
+
+		kc := &keycloak.Keycloak{}
+		err = r.serverClient.Get(ctx, k8sclient.ObjectKey{Name: rhssouser.GetKeycloakName(), Namespace: ns}, kc)
+		if k8serr.IsNotFound(err) {
+			return targets, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user sso keycloak: %w", err)
+		}
+		targets.userSSOClient, err = r.KeycloakClientFactory.AuthenticatedClient(*kc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate client in keycloak api: %w", err)
+		}
+		targets.groupMappings, err = userHelper.GetGroupMappings(ctx, r.Client, installation.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		targets.rhssoUserNamespace = ns
+	}
+
+	return targets, nil
+}
+
+// isProductReconciled returns true once the product has been reconciled at least once,
+// meaning its namespace and Keycloak instance have been created
+func isProductReconciled(installation *integreatlyv1alpha1.RHMI, product integreatlyv1alpha1.ProductName) bool {
+	productStatus, ok := installation.GetInstallStage().Products[product]
+	return ok && productStatus.Phase != integreatlyv1alpha1.PhaseNone
+}
@@ -26,7 +26,10 @@ import (
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/k8s"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 
@@ -41,16 +44,18 @@ import (
 	customMetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	v1 "github.com/openshift/api/apps/v1"
-	"github.com/sirupsen/logrus"
+	"go.uber.org/zap/zapcore"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	rhmiv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	blackboxtargetcontroller "github.com/integr8ly/integreatly-operator/controllers/blackboxtarget"
 	namespacecontroller "github.com/integr8ly/integreatly-operator/controllers/namespacelabel"
 	rhmicontroller "github.com/integr8ly/integreatly-operator/controllers/rhmi"
 	subscriptioncontroller "github.com/integr8ly/integreatly-operator/controllers/subscription"
 	tenantcontroller "github.com/integr8ly/integreatly-operator/controllers/tenant"
 	usercontroller "github.com/integr8ly/integreatly-operator/controllers/user"
+	usersynccontroller "github.com/integr8ly/integreatly-operator/controllers/usersync"
 	"github.com/integr8ly/integreatly-operator/pkg/addon"
 	"github.com/integr8ly/integreatly-operator/pkg/webhooks"
 	// +kubebuilder:scaffold:imports
@@ -76,6 +81,11 @@ func init() {
 	customMetrics.Registry.MustRegister(integreatlymetrics.CustomDomain)
 	customMetrics.Registry.MustRegister(integreatlymetrics.ThreeScalePortals)
 	customMetrics.Registry.MustRegister(integreatlymetrics.RhoamStateMetric)
+	customMetrics.Registry.MustRegister(integreatlymetrics.UserSyncLag)
+	customMetrics.Registry.MustRegister(integreatlymetrics.UserSyncFailures)
+	customMetrics.Registry.MustRegister(integreatlymetrics.UserSyncLastFullResync)
+	customMetrics.Registry.MustRegister(integreatlymetrics.RateLimitUsage)
+	customMetrics.Registry.MustRegister(integreatlymetrics.RateLimitUsageRatio)
 
 	integreatlymetrics.OperatorVersion.Add(1)
 	utilruntime.Must(v1.Install(clientgoscheme.Scheme))
@@ -95,6 +105,10 @@ func main() {
 	var probeAddr string
 	var addonInstanceName string
 	var heartbeatInterval time.Duration
+	var userFullResyncInterval time.Duration
+	var auditSink string
+	var auditConfigMapSize int
+	var logFormat string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8383", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
@@ -102,14 +116,28 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&addonInstanceName, "addon-instance-name", "addon-instance", "The addon instance name the addon is reporting status to.")
 	flag.DurationVar(&heartbeatInterval, "heartbeat-interval", 10*time.Second, "Time between heartbeats sent to addon instance")
+	flag.DurationVar(&userFullResyncInterval, "user-full-resync-interval", 30*time.Minute, "Time between full resyncs of OpenShift users to Keycloak")
+	flag.StringVar(&auditSink, "audit-sink", audit.SinkStdout, "Where the audit trail of changes to managed products is written. One of stdout, configmap or none")
+	flag.IntVar(&auditConfigMapSize, "audit-configmap-size", audit.DefaultConfigMapSize, "Number of audit entries retained when writing the audit trail to a ConfigMap")
+	flag.StringVar(&logFormat, "log-format", l.FormatText, "Format of the operator logs. One of text or json, json matches the controller-runtime logs")
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
-	logrus.SetFormatter(&logrus.TextFormatter{
-		ForceColors:      true,
-		FullTimestamp:    true,
-		QuoteEmptyFields: false,
-	})
+	if logFormat == l.FormatJSON {
+		ctrl.SetLogger(zap.New(zap.UseDevMode(false), func(o *zap.Options) {
+			o.TimeEncoder = zapcore.RFC3339NanoTimeEncoder
+		}))
+	} else {
+		ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	}
+	if err := l.ConfigureFormat(logFormat); err != nil {
+		setupLog.Error(err, "unable to configure log format")
+		os.Exit(1)
+	}
+
+	if err := resources.ValidateAlertRules(); err != nil {
+		setupLog.Error(err, "invalid alert rules")
+		os.Exit(1)
+	}
 
 	watchNamespace, err := k8s.GetWatchNamespace()
 	if err != nil {
@@ -147,6 +175,13 @@ func main() {
 		}
 	}
 
+	sink, err := audit.NewSink(auditSink, mgr.GetClient(), watchNamespace, auditConfigMapSize)
+	if err != nil {
+		setupLog.Error(err, "unable to configure audit sink")
+		os.Exit(1)
+	}
+	audit.SetSink(sink)
+
 	if err = rhmicontroller.New(mgr).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RHMI")
 		os.Exit(1)
@@ -159,10 +194,21 @@ func main() {
 			setupLog.Error(err, "unable to create controller", "controller", "Namespace")
 			os.Exit(1)
 		}
-		if err = usercontroller.New(mgr).SetupWithManager(mgr); err != nil {
+		if err = usercontroller.New(mgr, watchNamespace).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "User")
 			os.Exit(1)
 		}
+		userSyncCtrl, err := usersynccontroller.New(mgr,
+			usersynccontroller.WithNamespace(watchNamespace),
+			usersynccontroller.WithFullResyncInterval(userFullResyncInterval))
+		if err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "UserSync")
+			os.Exit(1)
+		}
+		if err = userSyncCtrl.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to setup controller", "controller", "UserSync")
+			os.Exit(1)
+		}
 	}
 
 	if isSandbox {
@@ -187,6 +233,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	blackboxTargetCtrl, err := blackboxtargetcontroller.New(mgr, watchNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BlackboxTarget")
+		os.Exit(1)
+	}
+	if err = blackboxTargetCtrl.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to setup controller", "controller", "BlackboxTarget")
+		os.Exit(1)
+	}
+
 	// Client to use before cache is created
 	restConfig := ctrl.GetConfigOrDie()
 	restConfig.Timeout = time.Second * 10
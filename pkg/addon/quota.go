@@ -83,6 +83,31 @@ var quotaConfig = `
                         "memory":"100Mi"
                     }
                 }
+            },
+            "envoy_sidecar":{
+                "concurrency":4,
+                "resources":{
+                    "requests":{
+                        "cpu":0.5,
+                        "memory":"150Mi"
+                    },
+                    "limits":{
+                        "cpu":1,
+                        "memory":"250Mi"
+                    }
+                }
+            },
+            "discovery_service":{
+                "resources":{
+                    "requests":{
+                        "cpu":0.2,
+                        "memory":"150Mi"
+                    },
+                    "limits":{
+                        "cpu":0.5,
+                        "memory":"300Mi"
+                    }
+                }
             }
         }
     },
@@ -161,6 +186,31 @@ var quotaConfig = `
                         "memory":"100Mi"
                     }
                 }
+            },
+            "envoy_sidecar":{
+                "concurrency":2,
+                "resources":{
+                    "requests":{
+                        "cpu":0.3,
+                        "memory":"120Mi"
+                    },
+                    "limits":{
+                        "cpu":0.6,
+                        "memory":"200Mi"
+                    }
+                }
+            },
+            "discovery_service":{
+                "resources":{
+                    "requests":{
+                        "cpu":0.1,
+                        "memory":"100Mi"
+                    },
+                    "limits":{
+                        "cpu":0.3,
+                        "memory":"200Mi"
+                    }
+                }
             }
         }
     },
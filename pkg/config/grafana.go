@@ -60,6 +60,16 @@ func (s *Grafana) SetHost(newHost string) {
 	s.config["HOST"] = newHost
 }
 
+// GetCustomerDashboardsNamespace returns the namespace the dashboard ConfigMaps of the customer
+// are imported from, importing customer dashboards is disabled when not set
+func (s *Grafana) GetCustomerDashboardsNamespace() string {
+	return s.config["CUSTOMER_DASHBOARDS_NAMESPACE"]
+}
+
+func (s *Grafana) SetCustomerDashboardsNamespace(namespace string) {
+	s.config["CUSTOMER_DASHBOARDS_NAMESPACE"] = namespace
+}
+
 func (s *Grafana) GetProductName() integreatlyv1alpha1.ProductName {
 	return integreatlyv1alpha1.ProductGrafana
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	oo "github.com/redhat-developer/observability-operator/v4/api/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -20,6 +22,12 @@ const (
 	AlertManagerEmailTemplateSecretFileName = "alertmanager-email-config.tmpl"
 	AlertManagerConfigTemplatePath          = "alertmanager/alertmanager-application-monitoring.yaml"
 	AlertManagerCustomTemplatePath          = "alertmanager/alertmanager-email-config.tmpl"
+	AlertManagerServiceName                 = "rhoam-alertmanager"
+	AlertManagerPort                        = 9093
+	PrometheusServiceName                   = "rhoam-prometheus"
+	PrometheusPort                          = 9090
+	BlackboxExporterServiceName             = "blackbox-exporter"
+	BlackboxExporterPort                    = 9115
 
 	// CR Overrides
 	AlertManagerOverride = "alertmanager"
@@ -41,43 +49,6 @@ func GetOboLabelSelectorKey() string {
 
 // TODO MGDAPI-5833: everything *Observability related to be removed
 
-var rhmiTemplateList = []string{
-	"endpointsdetailed",
-	"endpointsreport",
-	"endpointssummary",
-	"resources-by-namespace",
-	"resources-by-pod",
-	"cluster-resources",
-	"critical-slo-rhmi-alerts",
-	"cro-resources",
-	"rhoam-rhsso-availability-slo",
-}
-
-var managedAPITemplateList = []string{
-	"endpointsdetailed",
-	"endpointsreport",
-	"endpointssummary",
-	"resources-by-namespace",
-	"resources-by-pod",
-	"cluster-resources",
-	"critical-slo-managed-api-alerts",
-	"cro-resources",
-	"rhoam-rhsso-availability-slo",
-}
-
-var multitenantManagedAPITemplateList = []string{
-	"endpointsdetailed",
-	"endpointsreport",
-	"endpointssummary",
-	"resources-by-namespace",
-	"resources-by-pod",
-	"cluster-resources",
-	"critical-slo-managed-api-alerts",
-	"cro-resources",
-	"rhoam-rhsso-availability-slo",
-	"multitenancy-detailed",
-}
-
 type Observability struct {
 	Config ProductConfig
 }
@@ -153,17 +124,6 @@ func (m *Observability) GetWatchableCRDs() []runtime.Object {
 	}
 }
 
-func (m *Observability) GetDashboards(installType integreatlyv1alpha1.InstallationType) []string {
-	switch installType {
-	case integreatlyv1alpha1.InstallationTypeManagedApi:
-		return managedAPITemplateList
-	case integreatlyv1alpha1.InstallationTypeMultitenantManagedApi:
-		return multitenantManagedAPITemplateList
-	default:
-		return rhmiTemplateList
-	}
-}
-
 func (m *Observability) GetAlertManagerVersion() string {
 	return "v0.22.2"
 }
@@ -184,6 +144,15 @@ func (m *Observability) GetAlertManagerStorageRequest() string {
 	return "1Gi"
 }
 
+// GetBlackboxExporterAddress returns the host:port the probes of the observability namespace
+// are sent to, overridden by BLACKBOX_EXPORTER_ADDRESS
+func (m *Observability) GetBlackboxExporterAddress(oboNamespace string) string {
+	if address := m.Config["BLACKBOX_EXPORTER_ADDRESS"]; address != "" {
+		return address
+	}
+	return fmt.Sprintf("%s.%s.svc:%d", BlackboxExporterServiceName, oboNamespace, BlackboxExporterPort)
+}
+
 func (m *Observability) GetPrometheusVersion() string {
 	return "v2.29.2"
 }
@@ -14,6 +14,7 @@ import (
 	prometheusConfig "github.com/prometheus/common/config"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
+	"time"
 )
 
 // Custom metrics
@@ -169,6 +170,61 @@ var (
 			Help: "Measures if the last reconcile of the installation controller is delayed",
 		},
 	)
+
+	UserSyncLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rhoam_user_sync_lag_seconds",
+			Help:    "Time between an OpenShift user change being observed and it being applied to Keycloak",
+			Buckets: []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900},
+		},
+		[]string{
+			"sync", // "incremental/full"
+		},
+	)
+
+	UserSyncFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rhoam_user_sync_failures_total",
+			Help: "Number of failed attempts to synchronise OpenShift users to Keycloak",
+		},
+		[]string{
+			"sync", // "incremental/full"
+		},
+	)
+
+	UserSyncLastFullResync = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "rhoam_user_sync_last_full_resync_timestamp_seconds",
+			Help: "Unix timestamp of the last successful full resync of OpenShift users to Keycloak",
+		},
+	)
+
+	RateLimitUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rhoam_ratelimit_usage",
+			Help: "Requests counted by Limitador in the current window of a limit",
+		},
+		[]string{
+			"limit",  // conditions of the limit, e.g. "generic_key == slowpath"
+			"tenant", // empty outside of multitenant installations
+		},
+	)
+
+	RateLimitUsageRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "rhoam_ratelimit_usage_ratio",
+			Help: "Highest ratio of the requests counted by Limitador in the current window to the maximum of a limit",
+		},
+		[]string{
+			"limit",
+			"tenant",
+		},
+	)
+)
+
+const (
+	UserSyncIncremental = "incremental"
+	UserSyncFull        = "full"
 )
 
 const (
@@ -186,6 +242,14 @@ type PortalInfo struct {
 	IsAvailable bool
 }
 
+// RateLimitUsageSample is the usage of a Limitador counter of a limit
+type RateLimitUsageSample struct {
+	Limit    string
+	Tenant   string
+	Used     int64
+	MaxValue int64
+}
+
 type RhoamState struct {
 	Status    integreatlyv1alpha1.StatusPhase
 	Upgrading bool
@@ -257,6 +321,42 @@ func SetNoActivated3ScaleTenantAccount(username string) {
 	NoActivated3ScaleTenantAccount.WithLabelValues(username).Set(float64(1))
 }
 
+func ObserveUserSyncLag(sync string, lag time.Duration) {
+	UserSyncLag.WithLabelValues(sync).Observe(lag.Seconds())
+}
+
+func IncUserSyncFailures(sync string) {
+	UserSyncFailures.WithLabelValues(sync).Inc()
+}
+
+func SetUserSyncLastFullResync(t time.Time) {
+	UserSyncLastFullResync.Set(float64(t.Unix()))
+}
+
+// SetRateLimitUsage exposes the usage of the Limitador limits, the counters of a limit are
+// summed up per tenant
+func SetRateLimitUsage(samples []RateLimitUsageSample) {
+	RateLimitUsage.Reset()
+	RateLimitUsageRatio.Reset()
+
+	ratios := map[[2]string]float64{}
+	for _, sample := range samples {
+		key := [2]string{sample.Limit, sample.Tenant}
+		RateLimitUsage.WithLabelValues(sample.Limit, sample.Tenant).Add(float64(sample.Used))
+
+		ratio := 0.0
+		if sample.MaxValue > 0 {
+			ratio = float64(sample.Used) / float64(sample.MaxValue)
+		}
+		if current, ok := ratios[key]; !ok || ratio > current {
+			ratios[key] = ratio
+		}
+	}
+	for key, ratio := range ratios {
+		RateLimitUsageRatio.WithLabelValues(key[0], key[1]).Set(ratio)
+	}
+}
+
 func SetQuota(quota string, toQuota string) {
 	Quota.Reset()
 	Quota.WithLabelValues(quota, toQuota).Set(float64(1))
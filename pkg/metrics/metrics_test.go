@@ -7,6 +7,7 @@ import (
 
 	"github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/integr8ly/integreatly-operator/utils"
 	configv1 "github.com/openshift/api/config/v1"
@@ -225,3 +226,27 @@ func Test_GetStats(t *testing.T) {
 		})
 	}
 }
+
+func TestSetRateLimitUsage(t *testing.T) {
+	SetRateLimitUsage([]RateLimitUsageSample{
+		{Limit: "generic_key == slowpath", Used: 10, MaxValue: 100},
+		{Limit: "descriptor_rule == orders", Used: 4, MaxValue: 5},
+		{Limit: "descriptor_rule == orders", Used: 1, MaxValue: 5},
+		{Limit: "header_match == per-mt-limit", Tenant: "tenant-a", Used: 30, MaxValue: 60},
+	})
+
+	if got := testutil.ToFloat64(RateLimitUsage.WithLabelValues("descriptor_rule == orders", "")); got != 5 {
+		t.Errorf("expected the counters of the limit to be summed up, got %v", got)
+	}
+	if got := testutil.ToFloat64(RateLimitUsageRatio.WithLabelValues("descriptor_rule == orders", "")); got != 0.8 {
+		t.Errorf("expected the highest ratio of the limit, got %v", got)
+	}
+	if got := testutil.ToFloat64(RateLimitUsageRatio.WithLabelValues("header_match == per-mt-limit", "tenant-a")); got != 0.5 {
+		t.Errorf("expected the ratio of the tenant, got %v", got)
+	}
+
+	SetRateLimitUsage(nil)
+	if got := testutil.CollectAndCount(RateLimitUsage); got != 0 {
+		t.Errorf("expected the usage to be reset, got %d series", got)
+	}
+}
@@ -684,46 +684,57 @@ func (r *Reconciler) checkStsCredentialsPresent(client k8sclient.Client, operato
 func (r *Reconciler) reconcileCloudResourceStrategies(ctx context.Context, client k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
 	r.log.Info("reconciling cloud resource maintenance strategies")
 
-	maintenanceDay, _, err := addon.GetStringParameter(ctx, client, r.ConfigManager.GetOperatorNamespace(), MaintenanceDay)
+	day, hour, err := GetMaintenanceWindow(ctx, client, r.ConfigManager.GetOperatorNamespace())
 	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failure to get maintenance day parameter: %v", err)
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	timeConfig := croStrat.NewStrategyTimeConfig(3, 01, day, hour, 00)
+
+	err = croUtil.ReconcileStrategyMaps(ctx, client, timeConfig, croUtil.TierProduction, r.ConfigManager.GetOperatorNamespace())
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failure to reconcile strategy map: %v", err)
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+// GetMaintenanceWindow returns the weekly maintenance day and hour (UTC) from the addon
+// parameters, falling back to the defaults when they are not set
+func GetMaintenanceWindow(ctx context.Context, client k8sclient.Client, namespace string) (time.Weekday, int, error) {
+	maintenanceDay, _, err := addon.GetStringParameter(ctx, client, namespace, MaintenanceDay)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failure to get maintenance day parameter: %v", err)
 	}
 
 	var day time.Weekday
 	if maintenanceDay != "" {
 		parsedDay, err := strconv.ParseInt(maintenanceDay, 0, 64)
 		if err != nil {
-			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failure to parse maintenance day parameter: %v", err)
+			return 0, 0, fmt.Errorf("failure to parse maintenance day parameter: %v", err)
 		}
 		day = time.Weekday(parsedDay)
 	} else {
 		day = DefaultMaintenanceDay
 	}
 
-	maintenanceHour, _, err := addon.GetStringParameter(ctx, client, r.ConfigManager.GetOperatorNamespace(), MaintenanceHour)
+	maintenanceHour, _, err := addon.GetStringParameter(ctx, client, namespace, MaintenanceHour)
 	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failure to get maintenance hour parameter: %v", err)
+		return 0, 0, fmt.Errorf("failure to get maintenance hour parameter: %v", err)
 	}
 
 	var hour int
 	if maintenanceHour != "" {
 		parsedHour, err := strconv.ParseInt(maintenanceHour, 0, 64)
 		if err != nil {
-			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failure to parse maintenance hour parameter: %v", err)
+			return 0, 0, fmt.Errorf("failure to parse maintenance hour parameter: %v", err)
 		}
 		hour = int(parsedHour)
 	} else {
 		hour = DefaultMaintenanceHour
 	}
 
-	timeConfig := croStrat.NewStrategyTimeConfig(3, 01, day, hour, 00)
-
-	err = croUtil.ReconcileStrategyMaps(ctx, client, timeConfig, croUtil.TierProduction, r.ConfigManager.GetOperatorNamespace())
-	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failure to reconcile strategy map: %v", err)
-	}
-
-	return integreatlyv1alpha1.PhaseCompleted, nil
+	return day, hour, nil
 }
 
 func (r *Reconciler) setPlatformStrategyName(ctx context.Context, client k8sclient.Client) error {
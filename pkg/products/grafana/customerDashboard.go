@@ -1,718 +1,81 @@
 package grafana
 
-// This dashboard json is dynamically configured based on soft limits and perUnitRequests provided in the quota-configs-managed-api-service config map
-// present in the operator namespace for RHOAM installations
-// For example if there are softLimits provided of [500000,10000000,15000000] Five, Ten and Fifteen Million per day
-// Each of these soft limits are then dynamically added as queries to the Rate Limit Graph.
-//
-// Each of the hard limit and soft limits are calculated to a perMinute amount.
+import (
+	"fmt"
 
-func getCustomerMonitoringGrafanaRateLimitJSON(requestsPerUnit, activeQuota string) string {
-	return `{
-  "annotations": {
-    "list": [
-      {
-        "builtIn": 1,
-        "datasource": "-- Grafana --",
-        "enable": true,
-        "hide": true,
-        "iconColor": "rgba(0, 211, 255, 1)",
-        "name": "Annotations & Alerts",
-        "type": "dashboard"
-      }
-    ]
-  },
-  "editable": true,
-  "gnetId": null,
-  "graphTooltip": 0,
-  "id": 2,
-  "iteration": 1605286887536,
-  "links": [],
-  "panels": [
-    {
-      "collapsed": false,
-      "datasource": null,
-      "gridPos": {
-        "h": 1,
-        "w": 24,
-        "x": 0,
-        "y": 0
-      },
-      "id": 20,
-      "panels": [],
-      "title": "RHOAM API Rate Limiting",
-      "type": "row"
-    },
-    {
-      "cacheTimeout": null,
-      "colorBackground": true,
-      "colorPostfix": false,
-      "colorPrefix": false,
-      "colorValue": false,
-      "colors": [
-        "#299c46",
-        "rgba(237, 129, 40, 0.89)",
-        "#d44a3a"
-      ],
-      "datasource": null,
-      "description": "",
-      "format": "none",
-      "gauge": {
-        "maxValue": 100,
-        "minValue": 0,
-        "show": false,
-        "thresholdLabels": false,
-        "thresholdMarkers": true
-      },
-      "gridPos": {
-        "h": 5,
-        "w": 3,
-        "x": 0,
-        "y": 1
-      },
-      "id": 4,
-      "interval": null,
-      "links": [],
-      "mappingType": 1,
-      "mappingTypes": [
-        {
-          "name": "value to text",
-          "value": 1
-        },
-        {
-          "name": "range to text",
-          "value": 2
-        }
-      ],
-      "maxDataPoints": 100,
-      "nullPointMode": "connected",
-      "nullText": null,
-      "options": {},
-      "postfix": "",
-      "postfixFontSize": "50%",
-      "prefix": "",
-      "prefixFontSize": "50%",
-      "rangeMaps": [
-        {
-          "from": "null",
-          "text": "N/A",
-          "to": "null"
-        }
-      ],
-      "sparkline": {
-        "fillColor": "rgba(31, 118, 189, 0.18)",
-        "full": false,
-        "lineColor": "rgb(31, 120, 193)",
-        "show": false,
-        "ymax": null,
-        "ymin": null
-      },
-      "tableColumn": "",
-      "targets": [
-        {
-          "expr": "sum(increase(authorized_calls[1m]) or vector(0)) + sum(increase(limited_calls[1m]) or vector(0))",
-          "instant": true,
-          "refId": "A"
-        }
-      ],
-      "thresholds": "$perMinuteRequestsPerUnit",
-      "timeFrom": null,
-      "timeShift": null,
-      "title": "Last 1 Minute - No. Requests",
-      "transparent": true,
-      "type": "singlestat",
-      "valueFontSize": "80%",
-      "valueMaps": [
-        {
-          "op": "=",
-          "text": "N/A",
-          "value": "null"
-        }
-      ],
-      "valueName": "avg"
-    },
-    {
-      "cacheTimeout": null,
-      "colorBackground": true,
-      "colorValue": false,
-      "colors": [
-        "#299c46",
-        "rgba(237, 129, 40, 0.89)",
-        "#d44a3a"
-      ],
-      "datasource": null,
-      "description": "",
-      "format": "none",
-      "gauge": {
-        "maxValue": 100,
-        "minValue": 0,
-        "show": false,
-        "thresholdLabels": false,
-        "thresholdMarkers": true
-      },
-      "gridPos": {
-        "h": 5,
-        "w": 3,
-        "x": 3,
-        "y": 1
-      },
-      "id": 10,
-      "interval": null,
-      "links": [],
-      "mappingType": 1,
-      "mappingTypes": [
-        {
-          "name": "value to text",
-          "value": 1
-        },
-        {
-          "name": "range to text",
-          "value": 2
-        }
-      ],
-      "maxDataPoints": 100,
-      "nullPointMode": "connected",
-      "nullText": null,
-      "options": {},
-      "postfix": "",
-      "postfixFontSize": "50%",
-      "prefix": "",
-      "prefixFontSize": "50%",
-      "rangeMaps": [
-        {
-          "from": "null",
-          "text": "N/A",
-          "to": "null"
-        }
-      ],
-      "sparkline": {
-        "fillColor": "rgba(31, 118, 189, 0.18)",
-        "full": false,
-        "lineColor": "rgb(31, 120, 193)",
-        "show": false,
-        "ymax": null,
-        "ymin": null
-      },
-      "tableColumn": "",
-      "targets": [
-        {
-          "expr": "sum(increase(limited_calls[1m])) > 0 or vector(0)",
-          "instant": true,
-          "refId": "A"
-        }
-      ],
-      "thresholds": "1",
-      "timeFrom": null,
-      "timeShift": null,
-      "title": "Last 1 Minute - Rejected",
-      "type": "singlestat",
-      "valueFontSize": "80%",
-      "valueMaps": [
-        {
-          "op": "=",
-          "text": "N/A",
-          "value": "null"
-        }
-      ],
-      "valueName": "avg"
-    },
-    {
-      "cacheTimeout": null,
-      "colorBackground": false,
-      "colorPostfix": false,
-      "colorValue": false,
-      "colors": [
-        "#299c46",
-        "rgba(237, 129, 40, 0.89)",
-        "#d44a3a"
-      ],
-      "datasource": null,
-      "format": "none",
-      "gauge": {
-        "maxValue": 100,
-        "minValue": 0,
-        "show": false,
-        "thresholdLabels": false,
-        "thresholdMarkers": true
-      },
-      "gridPos": {
-        "h": 5,
-        "w": 3,
-        "x": 6,
-        "y": 1
-      },
-      "id": 22,
-      "interval": "",
-      "links": [],
-      "mappingType": 1,
-      "mappingTypes": [
-        {
-          "name": "value to text",
-          "value": 1
-        },
-        {
-          "name": "range to text",
-          "value": 2
-        }
-      ],
-      "maxDataPoints": 100,
-      "nullPointMode": "connected",
-      "nullText": null,
-      "options": {},
-      "postfix": "%",
-      "postfixFontSize": "50%",
-      "prefix": "",
-      "prefixFontSize": "50%",
-      "rangeMaps": [
-        {
-          "from": "null",
-          "text": "N/A",
-          "to": "null"
-        }
-      ],
-      "sparkline": {
-        "fillColor": "rgba(31, 118, 189, 0.18)",
-        "full": false,
-        "lineColor": "rgb(31, 120, 193)",
-        "show": false,
-        "ymax": null,
-        "ymin": null
-      },
-      "tableColumn": "",
-      "targets": [
-        {
-          "expr": "(sum(increase(limited_calls[1m])) > 0 or vector(0))/(sum(increase(authorized_calls[1m]) or vector(0)) + sum(increase(limited_calls[1m]) or vector(0)))*100 > 0 or vector(0)",
-          "instant": true,
-          "refId": "A"
-        }
-      ],
-      "thresholds": "",
-      "timeFrom": null,
-      "timeShift": null,
-      "title": "Last 1 Minute - Rejected/Requests",
-      "type": "singlestat",
-      "valueFontSize": "80%",
-      "valueMaps": [
-        {
-          "op": "=",
-          "text": "N/A",
-          "value": "null"
-        }
-      ],
-      "valueName": "avg"
-    },
-    {
-      "aliasColors": {},
-      "bars": false,
-      "dashLength": 10,
-      "dashes": false,
-      "datasource": null,
-      "decimals": 0,
-      "fill": 1,
-      "fillGradient": 4,
-      "gridPos": {
-        "h": 10,
-        "w": 15,
-        "x": 9,
-        "y": 1
-      },
-      "hiddenSeries": false,
-      "id": 2,
-      "interval": "1m",
-      "legend": {
-        "alignAsTable": false,
-        "avg": false,
-        "current": false,
-        "hideEmpty": false,
-        "hideZero": false,
-        "max": false,
-        "min": false,
-        "rightSide": false,
-        "show": true,
-        "total": false,
-        "values": false
-      },
-      "lines": true,
-      "linewidth": 1,
-      "nullPointMode": "null as zero",
-      "options": {
-        "dataLinks": []
-      },
-      "percentage": false,
-      "pointradius": 2,
-      "points": false,
-      "renderer": "flot",
-      "seriesOverrides": [],
-      "spaceLength": 10,
-      "stack": false,
-      "steppedLine": false,
-      "targets": [
-        {
-          "expr": "sum(increase(authorized_calls[1m]) or vector(0)) + sum(increase(limited_calls[1m]) or vector(0))",
-          "instant": false,
-          "interval": "30s",
-          "legendFormat": "No. of Requests",
-          "refId": "A"
-        },
-		{
-          "expr": "$perMinuteRequestsPerUnit",
-          "instant": false,
-          "interval": "30s",
-          "legendFormat": "Active Quota - ` + activeQuota + ` Per Day - Rate Limit - ` + requestsPerUnit + ` per minute",
-          "refId": "B"
-        }
-      ],
-      "thresholds": [],
-      "timeFrom": null,
-      "timeRegions": [],
-      "timeShift": null,
-      "title": "Per Minute API Requests",
-      "tooltip": {
-        "shared": true,
-        "sort": 0,
-        "value_type": "individual"
-      },
-      "type": "graph",
-      "xaxis": {
-        "buckets": null,
-        "mode": "time",
-        "name": null,
-        "show": true,
-        "values": []
-      },
-      "yaxes": [
-        {
-          "format": "short",
-          "label": null,
-          "logBase": 1,
-          "max": null,
-          "min": null,
-          "show": true
-        },
-        {
-          "format": "short",
-          "label": null,
-          "logBase": 1,
-          "max": null,
-          "min": null,
-          "show": false
-        }
-      ],
-      "yaxis": {
-        "align": false,
-        "alignLevel": null
-      }
-    },
-    {
-      "cacheTimeout": null,
-      "colorBackground": true,
-      "colorValue": false,
-      "colors": [
-        "#299c46",
-        "rgba(237, 129, 40, 0.89)",
-        "#d44a3a"
-      ],
-      "datasource": "Prometheus",
-      "description": "",
-      "format": "none",
-      "gauge": {
-        "maxValue": 100,
-        "minValue": 0,
-        "show": false,
-        "thresholdLabels": false,
-        "thresholdMarkers": true
-      },
-      "gridPos": {
-        "h": 5,
-        "w": 3,
-        "x": 0,
-        "y": 6
-      },
-      "hideTimeOverride": false,
-      "id": 6,
-      "interval": "",
-      "links": [],
-      "mappingType": 1,
-      "mappingTypes": [
-        {
-          "name": "value to text",
-          "value": 1
-        },
-        {
-          "name": "range to text",
-          "value": 2
-        }
-      ],
-      "maxDataPoints": 100,
-      "nullPointMode": "connected",
-      "nullText": null,
-      "options": {},
-      "postfix": "",
-      "postfixFontSize": "50%",
-      "prefix": "",
-      "prefixFontSize": "50%",
-      "rangeMaps": [
-        {
-          "from": "null",
-          "text": "N/A",
-          "to": "null"
-        }
-      ],
-      "sparkline": {
-        "fillColor": "rgba(31, 118, 189, 0.18)",
-        "full": false,
-        "lineColor": "rgb(31, 120, 193)",
-        "show": false,
-        "ymax": null,
-        "ymin": null
-      },
-      "tableColumn": "",
-      "targets": [
-        {
-          "expr": "sum(increase(authorized_calls[24h]) or vector(0)) + sum(increase(limited_calls[24h]) or vector(0)) > 0 or vector(0)",
-          "instant": true,
-          "refId": "A"
-        }
-      ],
-      "thresholds": "$perMinuteRequestsPerUnit*60*24",
-      "timeFrom": null,
-      "timeShift": null,
-      "title": "Last 24 Hours - No. Requests",
-      "type": "singlestat",
-      "valueFontSize": "80%",
-      "valueMaps": [
-        {
-          "op": "=",
-          "text": "N/A",
-          "value": "null"
-        }
-      ],
-      "valueName": "avg"
-    },
-    {
-      "cacheTimeout": null,
-      "colorBackground": true,
-      "colorValue": false,
-      "colors": [
-        "#299c46",
-        "rgba(237, 129, 40, 0.89)",
-        "#d44a3a"
-      ],
-      "datasource": "Prometheus",
-      "format": "none",
-      "gauge": {
-        "maxValue": 100,
-        "minValue": 0,
-        "show": false,
-        "thresholdLabels": false,
-        "thresholdMarkers": true
-      },
-      "gridPos": {
-        "h": 5,
-        "w": 3,
-        "x": 3,
-        "y": 6
-      },
-      "hideTimeOverride": false,
-      "id": 16,
-      "interval": "",
-      "links": [],
-      "mappingType": 1,
-      "mappingTypes": [
-        {
-          "name": "value to text",
-          "value": 1
-        },
-        {
-          "name": "range to text",
-          "value": 2
-        }
-      ],
-      "maxDataPoints": 100,
-      "nullPointMode": "connected",
-      "nullText": null,
-      "options": {},
-      "postfix": "",
-      "postfixFontSize": "50%",
-      "prefix": "",
-      "prefixFontSize": "50%",
-      "rangeMaps": [
-        {
-          "from": "null",
-          "text": "N/A",
-          "to": "null"
-        }
-      ],
-      "sparkline": {
-        "fillColor": "rgba(31, 118, 189, 0.18)",
-        "full": false,
-        "lineColor": "rgb(31, 120, 193)",
-        "show": false,
-        "ymax": null,
-        "ymin": null
-      },
-      "tableColumn": "",
-      "targets": [
-        {
-          "expr": "sum(increase(limited_calls[24h]) or vector(0))",
-          "format": "time_series",
-          "instant": true,
-          "refId": "A"
-        }
-      ],
-      "thresholds": "1",
-      "timeFrom": null,
-      "timeShift": null,
-      "title": "Last 24 Hours - Rejected",
-      "type": "singlestat",
-      "valueFontSize": "80%",
-      "valueMaps": [
-        {
-          "op": "=",
-          "text": "N/A",
-          "value": "null"
-        }
-      ],
-      "valueName": "first"
-    },
-    {
-      "cacheTimeout": null,
-      "colorBackground": false,
-      "colorValue": false,
-      "colors": [
-        "#299c46",
-        "rgba(237, 129, 40, 0.89)",
-        "#d44a3a"
-      ],
-      "datasource": null,
-      "description": "",
-      "format": "none",
-      "gauge": {
-        "maxValue": 100,
-        "minValue": 0,
-        "show": false,
-        "thresholdLabels": false,
-        "thresholdMarkers": true
-      },
-      "gridPos": {
-        "h": 5,
-        "w": 3,
-        "x": 6,
-        "y": 6
-      },
-      "id": 18,
-      "interval": "",
-      "links": [],
-      "mappingType": 1,
-      "mappingTypes": [
-        {
-          "name": "value to text",
-          "value": 1
-        },
-        {
-          "name": "range to text",
-          "value": 2
-        }
-      ],
-      "maxDataPoints": 100,
-      "nullPointMode": "connected",
-      "nullText": null,
-      "options": {},
-      "postfix": "%",
-      "postfixFontSize": "50%",
-      "prefix": "",
-      "prefixFontSize": "50%",
-      "rangeMaps": [
-        {
-          "from": "null",
-          "text": "N/A",
-          "to": "null"
-        }
-      ],
-      "sparkline": {
-        "fillColor": "rgba(31, 118, 189, 0.18)",
-        "full": false,
-        "lineColor": "rgb(31, 120, 193)",
-        "show": false,
-        "ymax": null,
-        "ymin": null
-      },
-      "tableColumn": "",
-      "targets": [
-        {
-          "expr": "(sum(increase(limited_calls[24h])) > 0 or vector(0))/(sum(increase(authorized_calls[24h]) or vector(0)) + sum(increase(limited_calls[24h]) or vector(0)))*100 > 0 or vector(0)",
-          "instant": true,
-          "legendFormat": "",
-          "refId": "A"
-        }
-      ],
-      "thresholds": "",
-      "timeFrom": null,
-      "timeShift": null,
-      "title": "Last 24 Hours -  Rejected/Requests",
-      "type": "singlestat",
-      "valueFontSize": "80%",
-      "valueMaps": [
-        {
-          "op": "=",
-          "text": "N/A",
-          "value": "null"
-        }
-      ],
-      "valueName": "avg"
-    }
-  ],
-  "refresh": "1m",
-  "schemaVersion": 21,
-  "style": "dark",
-  "tags": [],
-  "templating": {
-    "list": [
-      {
-        "current": {
-          "selected": false,
-          "text": "` + requestsPerUnit + `",
-          "value": "` + requestsPerUnit + `"
-        },
-        "hide": 2,
-        "label": null,
-        "name": "perMinuteRequestsPerUnit",
-        "options": [
-          {
-            "selected": true,
-            "text": "` + requestsPerUnit + `",
-            "value": "` + requestsPerUnit + `"
-          }
-        ],
-        "query": "` + requestsPerUnit + `",
-        "skipUrlSync": false,
-        "type": "constant"
-      }
-	]
-  },
-  "time": {
-    "from": "now-12h",
-    "to": "now"
-  },
-  "timepicker": {
-    "refresh_intervals": [
-      "5s",
-      "10s",
-      "30s",
-      "1m",
-      "5m",
-      "15m",
-      "30m",
-      "1h",
-      "2h",
-      "1d"
-    ]
-  },
-  "timezone": "",
-  "title": "Rate Limiting",
-  "uid": "66ab72e0d012aacf34f907be9d81cd9e",
-  "version": 1
-}`
+	"github.com/integr8ly/integreatly-operator/pkg/resources/dashboard"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
+)
+
+// This dashboard is dynamically configured based on the perUnitRequests and active quota provided in the
+// quota-configs-managed-api-service config map present in the operator namespace for RHOAM installations.
+// The limit is calculated to a perMinute amount and shown alongside the requests in the Rate Limit Graph.
+
+const (
+	// rateLimitDashboardUID is used to construct the url for the grafana dashboard in customer alerts. Please do not edit this value.
+	rateLimitDashboardUID = "66ab72e0d012aacf34f907be9d81cd9e"
+	// rateLimitDashboardVersion must be increased whenever the generated dashboards change
+	rateLimitDashboardVersion = 5
+
+	requestsPerUnitVariable = "perMinuteRequestsPerUnit"
+)
+
+func getCustomerMonitoringGrafanaRateLimitJSON(requestsPerUnit, activeQuota string) (string, error) {
+	return getRateLimitDashboard(requestsPerUnit, activeQuota).
+		AddPanel(dashboard.NewGraph("Per Minute Shadow Rate Limit Rejections",
+			dashboard.Target{Expr: fmt.Sprintf("sum by (limit_name) (increase(%s[1m]))", ratelimit.ShadowLimitedCallsMetric),
+				Interval: "30s", LegendFormat: "Would-be rejected - {{limit_name}}"}).
+			WithDescription("Requests the rate limit evaluated in shadow mode would have rejected, per descriptor. It is enforced once promoted in the rate-limit-shadow ConfigMap").
+			WithInterval("1m"),
+			dashboard.GridPos{H: 8, W: 24, X: 0, Y: 11}).
+		JSON()
 }
 
-// The UID above is used to construct the url for the grafana dashboard in customer alerts. Please do not edit this value.
+// getRateLimitDashboard returns the rate limit dashboard of the API usage of the installation,
+// the requests to the 3scale portals are counted in Limitador namespaces of their own
+func getRateLimitDashboard(requestsPerUnit, activeQuota string) *dashboard.Dashboard {
+	selector := fmt.Sprintf("{limitador_namespace='%s'}", ratelimit.RateLimitDomain)
+	requests := func(window string) string {
+		return fmt.Sprintf("sum(increase(authorized_calls%[1]s[%[2]s]) or vector(0)) + sum(increase(limited_calls%[1]s[%[2]s]) or vector(0))", selector, window)
+	}
+	rejected := func(window string) string {
+		return fmt.Sprintf("(sum(increase(limited_calls%s[%s])) > 0 or vector(0))", selector, window)
+	}
+	rejectedRatio := func(window string) string {
+		return fmt.Sprintf("%s/(%s)*100 > 0 or vector(0)", rejected(window), requests(window))
+	}
+
+	return dashboard.New("Rate Limiting", rateLimitDashboardUID).
+		WithVersion(rateLimitDashboardVersion).
+		WithRefresh("1m").
+		WithTimeRange("now-12h", "now").
+		WithConstant(requestsPerUnitVariable, requestsPerUnit).
+		AddPanel(dashboard.NewRow("RHOAM API Rate Limiting"),
+			dashboard.GridPos{H: 1, W: 24, X: 0, Y: 0}).
+		AddPanel(dashboard.NewSingleStat("Last 1 Minute - No. Requests", requests("1m")).
+			WithThresholds("$"+requestsPerUnitVariable, true).
+			WithTransparent(),
+			dashboard.GridPos{H: 5, W: 3, X: 0, Y: 1}).
+		AddPanel(dashboard.NewSingleStat("Last 1 Minute - Rejected", rejected("1m")).
+			WithThresholds("1", true),
+			dashboard.GridPos{H: 5, W: 3, X: 3, Y: 1}).
+		AddPanel(dashboard.NewSingleStat("Last 1 Minute - Rejected/Requests", rejectedRatio("1m")).
+			WithPostfix("%"),
+			dashboard.GridPos{H: 5, W: 3, X: 6, Y: 1}).
+		AddPanel(dashboard.NewGraph("Per Minute API Requests",
+			dashboard.Target{Expr: requests("1m"), Interval: "30s", LegendFormat: "No. of Requests"},
+			dashboard.Target{Expr: "$" + requestsPerUnitVariable, Interval: "30s",
+				LegendFormat: fmt.Sprintf("Active Quota - %s Per Day - Rate Limit - %s per minute", activeQuota, requestsPerUnit)}).
+			WithInterval("1m").
+			WithFillGradient(4),
+			dashboard.GridPos{H: 10, W: 15, X: 9, Y: 1}).
+		AddPanel(dashboard.NewSingleStat("Last 24 Hours - No. Requests", requests("24h")+" > 0 or vector(0)").
+			WithThresholds("$"+requestsPerUnitVariable+"*60*24", true),
+			dashboard.GridPos{H: 5, W: 3, X: 0, Y: 6}).
+		AddPanel(dashboard.NewSingleStat("Last 24 Hours - Rejected", rejected("24h")).
+			WithThresholds("1", true),
+			dashboard.GridPos{H: 5, W: 3, X: 3, Y: 6}).
+		AddPanel(dashboard.NewSingleStat("Last 24 Hours - Rejected/Requests", rejectedRatio("24h")).
+			WithPostfix("%"),
+			dashboard.GridPos{H: 5, W: 3, X: 6, Y: 6})
+}
@@ -0,0 +1,56 @@
+package grafana
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the generated dashboards")
+
+func TestRateLimitDashboardGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		json   func() (string, error)
+	}{
+		{
+			name:   "installation dashboard",
+			golden: "rate-limit.json",
+			json: func() (string, error) {
+				return getCustomerMonitoringGrafanaRateLimitJSON("13860", "20 Million")
+			},
+		},
+		{
+			name:   "tenant dashboard",
+			golden: "rate-limit-tenant.json",
+			json: func() (string, error) {
+				return getTenantRateLimitDashboard("250", "20 Million", "acme").JSON()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.json()
+			if err != nil {
+				t.Fatalf("failed to generate dashboard: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(golden, []byte(got+"\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file, run the test with -update to create it: %v", err)
+			}
+			if got+"\n" != string(expected) {
+				t.Errorf("dashboard does not match %s, run the test with -update and review the diff", golden)
+			}
+		})
+	}
+}
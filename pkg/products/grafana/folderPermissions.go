@@ -0,0 +1,255 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// grafanaAdminSecretName is created by the grafana-operator with the admin credentials of the Grafana instance
+	grafanaAdminSecretName  = "grafana-admin-credentials"
+	grafanaAdminUserKey     = "GF_SECURITY_ADMIN_USER"
+	grafanaAdminPasswordKey = "GF_SECURITY_ADMIN_PASSWORD"
+	grafanaServiceName      = "grafana-service"
+	grafanaServicePort      = 3000
+
+	// folderPermissionView is the Grafana permission level allowing to view the dashboards of a folder
+	folderPermissionView = 1
+
+	requestTimeout = 10 * time.Second
+)
+
+// FolderClient manages the Grafana folders and teams restricting the dashboards of each tenant
+type FolderClient interface {
+	// ListFolders returns the uid of each folder by title
+	ListFolders(ctx context.Context) (map[string]string, error)
+	EnsureFolder(ctx context.Context, title string) (string, error)
+	DeleteFolder(ctx context.Context, uid string) error
+	EnsureTeam(ctx context.Context, name string) (int64, error)
+	DeleteTeam(ctx context.Context, name string) error
+	// SetTeamMembers replaces the members of the team with the Grafana users of the logins,
+	// logins of users who have not signed in to Grafana yet are skipped
+	SetTeamMembers(ctx context.Context, teamID int64, logins []string) error
+	// SetFolderTeamPermission replaces the permissions of the folder so only the team, and
+	// the Grafana admins, can view its dashboards
+	SetFolderTeamPermission(ctx context.Context, folderUID string, teamID int64) error
+}
+
+// errNotFound is returned by the folder client when the Grafana API responds with 404
+var errNotFound = errors.New("not found")
+
+type folderClient struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+var _ FolderClient = &folderClient{}
+
+func NewFolderClient(baseURL, username, password string) FolderClient {
+	return &folderClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// newFolderClientFromSecret returns a FolderClient authenticated with the admin credentials of the Grafana instance
+func newFolderClientFromSecret(ctx context.Context, serverClient k8sclient.Client, namespace string) (FolderClient, error) {
+	secret := &corev1.Secret{}
+	if err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: grafanaAdminSecretName, Namespace: namespace}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get grafana admin credentials: %w", err)
+	}
+	baseURL := fmt.Sprintf("http://%s.%s.svc:%d", grafanaServiceName, namespace, grafanaServicePort)
+	return NewFolderClient(baseURL, string(secret.Data[grafanaAdminUserKey]), string(secret.Data[grafanaAdminPasswordKey])), nil
+}
+
+func (c *folderClient) ListFolders(ctx context.Context) (map[string]string, error) {
+	var folders []struct {
+		UID   string `json:"uid"`
+		Title string `json:"title"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/folders?limit=1000", nil, &folders); err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	uids := map[string]string{}
+	for _, folder := range folders {
+		uids[folder.Title] = folder.UID
+	}
+	return uids, nil
+}
+
+func (c *folderClient) EnsureFolder(ctx context.Context, title string) (string, error) {
+	folders, err := c.ListFolders(ctx)
+	if err != nil {
+		return "", err
+	}
+	if uid, ok := folders[title]; ok {
+		return uid, nil
+	}
+
+	created := struct {
+		UID string `json:"uid"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/api/folders", map[string]string{"title": title}, &created); err != nil {
+		return "", fmt.Errorf("failed to create folder %s: %w", title, err)
+	}
+	return created.UID, nil
+}
+
+func (c *folderClient) DeleteFolder(ctx context.Context, uid string) error {
+	err := c.do(ctx, http.MethodDelete, "/api/folders/"+uid, nil, nil)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("failed to delete folder %s: %w", uid, err)
+	}
+	return nil
+}
+
+func (c *folderClient) EnsureTeam(ctx context.Context, name string) (int64, error) {
+	teamID, found, err := c.getTeam(ctx, name)
+	if err != nil || found {
+		return teamID, err
+	}
+
+	created := struct {
+		TeamID int64 `json:"teamId"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/api/teams", map[string]string{"name": name}, &created); err != nil {
+		return 0, fmt.Errorf("failed to create team %s: %w", name, err)
+	}
+	return created.TeamID, nil
+}
+
+func (c *folderClient) DeleteTeam(ctx context.Context, name string) error {
+	teamID, found, err := c.getTeam(ctx, name)
+	if err != nil || !found {
+		return err
+	}
+	err = c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/teams/%d", teamID), nil, nil)
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("failed to delete team %s: %w", name, err)
+	}
+	return nil
+}
+
+func (c *folderClient) getTeam(ctx context.Context, name string) (int64, bool, error) {
+	search := struct {
+		Teams []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"teams"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/api/teams/search?name="+url.QueryEscape(name), nil, &search); err != nil {
+		return 0, false, fmt.Errorf("failed to search team %s: %w", name, err)
+	}
+	for _, team := range search.Teams {
+		if team.Name == name {
+			return team.ID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (c *folderClient) SetTeamMembers(ctx context.Context, teamID int64, logins []string) error {
+	var members []struct {
+		UserID int64  `json:"userId"`
+		Login  string `json:"login"`
+	}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/teams/%d/members", teamID), nil, &members); err != nil {
+		return fmt.Errorf("failed to list members of team %d: %w", teamID, err)
+	}
+
+	expected := map[string]bool{}
+	for _, login := range logins {
+		expected[login] = true
+	}
+	current := map[string]bool{}
+	for _, member := range members {
+		current[member.Login] = true
+		if expected[member.Login] {
+			continue
+		}
+		if err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/teams/%d/members/%d", teamID, member.UserID), nil, nil); err != nil && !errors.Is(err, errNotFound) {
+			return fmt.Errorf("failed to remove %s from team %d: %w", member.Login, teamID, err)
+		}
+	}
+
+	for _, login := range logins {
+		if current[login] {
+			continue
+		}
+		user := struct {
+			ID int64 `json:"id"`
+		}{}
+		err := c.do(ctx, http.MethodGet, "/api/users/lookup?loginOrEmail="+url.QueryEscape(login), nil, &user)
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up user %s: %w", login, err)
+		}
+		if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/teams/%d/members", teamID), map[string]int64{"userId": user.ID}, nil); err != nil {
+			return fmt.Errorf("failed to add %s to team %d: %w", login, teamID, err)
+		}
+	}
+	return nil
+}
+
+func (c *folderClient) SetFolderTeamPermission(ctx context.Context, folderUID string, teamID int64) error {
+	permissions := map[string]interface{}{
+		"items": []map[string]int64{{"teamId": teamID, "permission": folderPermissionView}},
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/folders/"+folderUID+"/permissions", permissions, nil); err != nil {
+		return fmt.Errorf("failed to set permissions of folder %s: %w", folderUID, err)
+	}
+	return nil
+}
+
+func (c *folderClient) do(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(c.username, c.password)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
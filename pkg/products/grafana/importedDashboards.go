@@ -0,0 +1,252 @@
+package grafana
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	grafanav1alpha1 "github.com/grafana-operator/grafana-operator/v4/api/integreatly/v1alpha1"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/addon"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// CustomerDashboardLabel selects the ConfigMaps the customer dashboards are imported from,
+	// every key of a selected ConfigMap ending in .json is imported as a dashboard
+	CustomerDashboardLabel = "integreatly.org/grafana-dashboard"
+	// importedDashboardLabel marks the GrafanaDashboards imported from customer ConfigMaps
+	importedDashboardLabel  = "integreatly.org/customer-dashboard"
+	importedDashboardPrefix = "customer-"
+	customerDashboardFolder = "Customer"
+	dashboardKeySuffix      = ".json"
+	prometheusDatasource    = "Prometheus"
+	// maxDashboardUIDLength is the longest uid Grafana accepts
+	maxDashboardUIDLength = 40
+	// CustomerDashboardsNamespaceParam is the addon parameter naming the namespace the customer
+	// dashboards are imported from, importing is disabled when it is not set
+	CustomerDashboardsNamespaceParam = "customer-dashboards-namespace"
+	// importedDashboardHashLength is the length of the hash of the ConfigMap and key appended to
+	// the name of an imported dashboard
+	importedDashboardHashLength = 8
+)
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// reconcileCustomerDashboardsNamespace sets the namespace the customer dashboards are imported
+// from to the value of the CustomerDashboardsNamespaceParam addon parameter
+func (r *Reconciler) reconcileCustomerDashboardsNamespace(ctx context.Context, serverClient k8sclient.Client, installation *integreatlyv1alpha1.RHMI) error {
+	namespace, _, err := addon.GetStringParameter(ctx, serverClient, installation.Namespace, CustomerDashboardsNamespaceParam)
+	if err != nil && !k8serr.IsNotFound(err) {
+		return fmt.Errorf("failed to get the customer dashboards namespace: %w", err)
+	}
+	if namespace == r.Config.GetCustomerDashboardsNamespace() {
+		return nil
+	}
+	r.Config.SetCustomerDashboardsNamespace(namespace)
+	if err := r.ConfigManager.WriteConfig(r.Config); err != nil {
+		return fmt.Errorf("error writing grafana config : %w", err)
+	}
+	return nil
+}
+
+// reconcileCustomerDashboards imports the dashboards of the labelled ConfigMaps in the customer
+// dashboards namespace as read only dashboards, and deletes the imported dashboards whose
+// ConfigMap or key was removed. Dashboards that can not be imported are reported on the
+// installation status and do not fail the reconcile
+func (r *Reconciler) reconcileCustomerDashboards(ctx context.Context, serverClient k8sclient.Client, installation *integreatlyv1alpha1.RHMI) (integreatlyv1alpha1.StatusPhase, error) {
+	var statuses []integreatlyv1alpha1.CustomerDashboardStatus
+	imported := map[string]bool{}
+
+	if namespace := r.Config.GetCustomerDashboardsNamespace(); namespace != "" {
+		configMaps := &corev1.ConfigMapList{}
+		if err := serverClient.List(ctx, configMaps, k8sclient.InNamespace(namespace), k8sclient.MatchingLabels{CustomerDashboardLabel: "true"}); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to list customer dashboard config maps: %w", err)
+		}
+		// usedUIDs holds what each uid is used by, a customer dashboard may neither replace a
+		// dashboard of the operator nor a customer dashboard imported before it
+		usedUIDs, err := r.getOperatorDashboardUIDs(ctx, serverClient)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+
+		sort.Slice(configMaps.Items, func(i, j int) bool { return configMaps.Items[i].Name < configMaps.Items[j].Name })
+		for _, configMap := range configMaps.Items {
+			var keys []string
+			for key := range configMap.Data {
+				if strings.HasSuffix(key, dashboardKeySuffix) {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				status := integreatlyv1alpha1.CustomerDashboardStatus{Name: fmt.Sprintf("%s/%s/%s", namespace, configMap.Name, key)}
+				name := getImportedDashboardName(configMap.Name, key)
+				uid, err := r.importCustomerDashboard(ctx, serverClient, name, configMap.Data[key], usedUIDs)
+				if err != nil {
+					status.Error = err.Error()
+					r.log.Warningf("Failed to import customer dashboard", l.Fields{"dashboard": status.Name, "error": err.Error()})
+				} else {
+					status.Imported = true
+					imported[name] = true
+					usedUIDs[uid] = fmt.Sprintf("the customer dashboard %s", status.Name)
+				}
+				statuses = append(statuses, status)
+			}
+		}
+	}
+
+	dashboards := &grafanav1alpha1.GrafanaDashboardList{}
+	if err := serverClient.List(ctx, dashboards, k8sclient.InNamespace(r.Config.GetOperatorNamespace()), k8sclient.HasLabels{importedDashboardLabel}); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to list imported dashboards: %w", err)
+	}
+	for i := range dashboards.Items {
+		dashboard := &dashboards.Items[i]
+		if imported[dashboard.Name] {
+			continue
+		}
+		if err := serverClient.Delete(ctx, dashboard); err != nil && !k8serr.IsNotFound(err) {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to delete imported dashboard %s: %w", dashboard.Name, err)
+		}
+		r.log.Infof("Deleted removed customer dashboard", l.Fields{"grafanaDashboard": dashboard.Name})
+	}
+
+	installation.Status.CustomerDashboards = statuses
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+// importCustomerDashboard imports the dashboard model and returns its uid
+func (r *Reconciler) importCustomerDashboard(ctx context.Context, serverClient k8sclient.Client, name, model string, usedUIDs map[string]string) (string, error) {
+	dashboardJSON, uid, datasources, err := prepareCustomerDashboard(model, getImportedDashboardUID(name), usedUIDs)
+	if err != nil {
+		return "", err
+	}
+
+	grafanaDB := &grafanav1alpha1.GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: r.Config.GetOperatorNamespace(),
+		},
+	}
+	opRes, err := controllerutil.CreateOrUpdate(ctx, serverClient, grafanaDB, func() error {
+		grafanaDB.Labels = map[string]string{
+			"monitoring-key":       "customer",
+			importedDashboardLabel: "true",
+		}
+		grafanaDB.Spec = grafanav1alpha1.GrafanaDashboardSpec{
+			Json:             dashboardJSON,
+			Datasources:      datasources,
+			CustomFolderName: customerDashboardFolder,
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create dashboard: %w", err)
+	}
+	if opRes != controllerutil.OperationResultNone {
+		r.log.Infof("Operation result grafana dashboard", l.Fields{"grafanaDashboard": grafanaDB.Name, "result": opRes})
+	}
+	return uid, nil
+}
+
+// getOperatorDashboardUIDs returns the uids of the dashboards owned by the operator, customer
+// dashboards may not replace them
+func (r *Reconciler) getOperatorDashboardUIDs(ctx context.Context, serverClient k8sclient.Client) (map[string]string, error) {
+	dashboards := &grafanav1alpha1.GrafanaDashboardList{}
+	if err := serverClient.List(ctx, dashboards, k8sclient.InNamespace(r.Config.GetOperatorNamespace())); err != nil {
+		return nil, fmt.Errorf("failed to list dashboards: %w", err)
+	}
+	operatorDashboard := "a dashboard of the operator"
+	uids := map[string]string{rateLimitDashboardUID: operatorDashboard}
+	for _, dashboard := range dashboards.Items {
+		if _, ok := dashboard.Labels[importedDashboardLabel]; ok {
+			continue
+		}
+		model := struct {
+			UID string `json:"uid"`
+		}{}
+		if err := json.Unmarshal([]byte(dashboard.Spec.Json), &model); err == nil && model.UID != "" {
+			uids[model.UID] = operatorDashboard
+		}
+	}
+	return uids, nil
+}
+
+// prepareCustomerDashboard validates the dashboard model, makes it read only and returns it with
+// its uid. The datasource inputs of exported dashboards are mapped to the Prometheus datasource
+// of the customer Grafana
+func prepareCustomerDashboard(model, defaultUID string, usedUIDs map[string]string) (string, string, []grafanav1alpha1.GrafanaDashboardDatasource, error) {
+	dashboard := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(model), &dashboard); err != nil {
+		return "", "", nil, fmt.Errorf("invalid dashboard JSON: %w", err)
+	}
+
+	if title, ok := dashboard["title"].(string); !ok || title == "" {
+		return "", "", nil, fmt.Errorf("dashboard title is required")
+	}
+	uid, ok := dashboard["uid"].(string)
+	if !ok || uid == "" {
+		uid = defaultUID
+	}
+	if len(uid) > maxDashboardUIDLength {
+		return "", "", nil, fmt.Errorf("dashboard uid %s is longer than %d characters", uid, maxDashboardUIDLength)
+	}
+	if usedBy, ok := usedUIDs[uid]; ok {
+		return "", "", nil, fmt.Errorf("dashboard uid %s is already used by %s", uid, usedBy)
+	}
+	if _, ok := dashboard["panels"].([]interface{}); !ok {
+		return "", "", nil, fmt.Errorf("dashboard panels are required")
+	}
+
+	var datasources []grafanav1alpha1.GrafanaDashboardDatasource
+	if inputs, ok := dashboard["__inputs"].([]interface{}); ok {
+		for _, input := range inputs {
+			fields, ok := input.(map[string]interface{})
+			if !ok || fields["type"] != "datasource" {
+				continue
+			}
+			if fields["pluginId"] != "prometheus" {
+				return "", "", nil, fmt.Errorf("unsupported datasource %v, only prometheus datasources are available", fields["pluginId"])
+			}
+			name, _ := fields["name"].(string)
+			datasources = append(datasources, grafanav1alpha1.GrafanaDashboardDatasource{InputName: name, DatasourceName: prometheusDatasource})
+		}
+	}
+
+	dashboard["uid"] = uid
+	dashboard["editable"] = false
+	delete(dashboard, "id")
+
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return string(data), uid, datasources, nil
+}
+
+// getImportedDashboardName returns the name of the GrafanaDashboard imported from the key of the
+// ConfigMap. A hash of the ConfigMap and key is appended, so names that are sanitised or
+// truncated to the same value stay unique
+func getImportedDashboardName(configMap, key string) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(configMap+"/"+key)))[:importedDashboardHashLength]
+	name := strings.ToLower(fmt.Sprintf("%s%s-%s", importedDashboardPrefix, configMap, strings.TrimSuffix(key, dashboardKeySuffix)))
+	name = strings.Trim(invalidNameCharacters.ReplaceAllString(name, "-"), "-")
+	if maxLength := 63 - len(hash) - 1; len(name) > maxLength {
+		name = strings.TrimSuffix(name[:maxLength], "-")
+	}
+	return name + "-" + hash
+}
+
+func getImportedDashboardUID(name string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:32]
+}
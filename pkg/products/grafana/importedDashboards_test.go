@@ -0,0 +1,302 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	grafanav1alpha1 "github.com/grafana-operator/grafana-operator/v4/api/integreatly/v1alpha1"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPrepareCustomerDashboard(t *testing.T) {
+	usedUIDs := map[string]string{
+		rateLimitDashboardUID: "a dashboard of the operator",
+		"imported":            "the customer dashboard customer-dashboards/orders/overview.json",
+	}
+
+	tests := []struct {
+		name        string
+		model       string
+		wantErr     string
+		wantUID     string
+		datasources int
+	}{
+		{
+			name:        "exported dashboard",
+			model:       `{"id":12,"title":"Orders","uid":"orders","editable":true,"panels":[],"__inputs":[{"name":"DS_PROMETHEUS","type":"datasource","pluginId":"prometheus"}]}`,
+			wantUID:     "orders",
+			datasources: 1,
+		},
+		{
+			name:    "uid is generated",
+			model:   `{"title":"Orders","panels":[]}`,
+			wantUID: "default",
+		},
+		{
+			name:    "invalid json",
+			model:   `{"title":`,
+			wantErr: "invalid dashboard JSON",
+		},
+		{
+			name:    "missing title",
+			model:   `{"panels":[]}`,
+			wantErr: "title is required",
+		},
+		{
+			name:    "missing panels",
+			model:   `{"title":"Orders"}`,
+			wantErr: "panels are required",
+		},
+		{
+			name:    "uid too long",
+			model:   `{"title":"Orders","uid":"` + strings.Repeat("a", 41) + `","panels":[]}`,
+			wantErr: "longer than 40 characters",
+		},
+		{
+			name:    "operator uid",
+			model:   `{"title":"Orders","uid":"` + rateLimitDashboardUID + `","panels":[]}`,
+			wantErr: "used by a dashboard of the operator",
+		},
+		{
+			name:    "uid of an imported dashboard",
+			model:   `{"title":"Orders","uid":"imported","panels":[]}`,
+			wantErr: "used by the customer dashboard customer-dashboards/orders/overview.json",
+		},
+		{
+			name:    "unsupported datasource",
+			model:   `{"title":"Orders","panels":[],"__inputs":[{"name":"DS_LOKI","type":"datasource","pluginId":"loki"}]}`,
+			wantErr: "unsupported datasource loki",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, uid, datasources, err := prepareCustomerDashboard(tt.model, "default", usedUIDs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			parsed := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(model), &parsed); err != nil {
+				t.Fatalf("invalid dashboard json: %v", err)
+			}
+			if uid != tt.wantUID || parsed["uid"] != tt.wantUID || parsed["editable"] != false {
+				t.Errorf("expected a read only dashboard with uid %s, got %v %v", tt.wantUID, parsed["uid"], parsed["editable"])
+			}
+			if _, ok := parsed["id"]; ok {
+				t.Errorf("expected the id to be removed")
+			}
+			if len(datasources) != tt.datasources {
+				t.Fatalf("expected %d datasources, got %v", tt.datasources, datasources)
+			}
+			for _, datasource := range datasources {
+				if datasource.DatasourceName != "Prometheus" {
+					t.Errorf("expected the input to be mapped to Prometheus, got %v", datasource)
+				}
+			}
+		})
+	}
+}
+
+func TestGetImportedDashboardName(t *testing.T) {
+	name := getImportedDashboardName("orders", "Overview.json")
+	if !strings.HasPrefix(name, "customer-orders-overview-") || len(name) != len("customer-orders-overview-")+importedDashboardHashLength {
+		t.Errorf("unexpected name %s", name)
+	}
+	if other := getImportedDashboardName("orders", "overview.json"); other == name {
+		t.Errorf("expected keys differing in case to get different names, got %s", other)
+	}
+
+	long := strings.Repeat("a", 80)
+	first, second := getImportedDashboardName(long, "first.json"), getImportedDashboardName(long, "second.json")
+	if len(first) > 63 || len(second) > 63 || first == second {
+		t.Errorf("expected unique names of at most 63 characters, got %s %s", first, second)
+	}
+}
+
+func TestReconcileCustomerDashboardsNamespace(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	installation := &integreatlyv1alpha1.RHMI{ObjectMeta: metav1.ObjectMeta{Namespace: "redhat-rhoam-operator"}}
+	parameters := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "addon-managed-api-service-parameters", Namespace: installation.Namespace},
+		Data:       map[string][]byte{CustomerDashboardsNamespaceParam: []byte("customer-dashboards")},
+	}
+
+	tests := []struct {
+		name          string
+		objects       []runtime.Object
+		namespace     string
+		wantNamespace string
+		wantWrite     bool
+	}{
+		{
+			name:          "namespace is read from the addon parameters",
+			objects:       []runtime.Object{parameters},
+			wantNamespace: "customer-dashboards",
+			wantWrite:     true,
+		},
+		{
+			name:          "namespace is unset when the parameter is removed",
+			namespace:     "customer-dashboards",
+			wantNamespace: "",
+			wantWrite:     true,
+		},
+		{
+			name:          "config is not written when unchanged",
+			objects:       []runtime.Object{parameters},
+			namespace:     "customer-dashboards",
+			wantNamespace: "customer-dashboards",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := false
+			reconciler := getBasicReconciler()
+			reconciler.Config = config.NewGrafana(config.ProductConfig{"CUSTOMER_DASHBOARDS_NAMESPACE": tt.namespace})
+			reconciler.ConfigManager = &config.ConfigReadWriterMock{
+				WriteConfigFunc: func(config config.ConfigReadable) error {
+					written = true
+					return nil
+				},
+			}
+
+			err := reconciler.reconcileCustomerDashboardsNamespace(context.TODO(), utils.NewTestClient(scheme, tt.objects...), installation)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if namespace := reconciler.Config.GetCustomerDashboardsNamespace(); namespace != tt.wantNamespace {
+				t.Errorf("expected namespace %q, got %q", tt.wantNamespace, namespace)
+			}
+			if written != tt.wantWrite {
+				t.Errorf("expected config written to be %t, got %t", tt.wantWrite, written)
+			}
+		})
+	}
+}
+
+func TestReconcileCustomerDashboards(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operatorNamespace := "customer-monitoring-operator"
+	customerNamespace := "customer-dashboards"
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "orders",
+			Namespace: customerNamespace,
+			Labels:    map[string]string{CustomerDashboardLabel: "true"},
+		},
+		Data: map[string]string{
+			"overview.json": `{"title":"Orders","uid":"orders","panels":[]}`,
+			"summary.json":  `{"title":"Summary","uid":"orders","panels":[]}`,
+			"broken.json":   `{"title":"Broken","uid":"` + rateLimitDashboardUID + `","panels":[]}`,
+			"README.md":     "not a dashboard",
+		},
+	}
+	unlabelledConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: customerNamespace},
+		Data:       map[string]string{"other.json": `{"title":"Other","panels":[]}`},
+	}
+	staleDashboard := &grafanav1alpha1.GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "customer-removed-dashboard",
+			Namespace: operatorNamespace,
+			Labels:    map[string]string{importedDashboardLabel: "true"},
+		},
+	}
+	operatorDashboard := &grafanav1alpha1.GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: rateLimitDashBoardName, Namespace: operatorNamespace},
+	}
+
+	t.Run("imports the dashboards of labelled config maps", func(t *testing.T) {
+		client := utils.NewTestClient(scheme, configMap, unlabelledConfigMap, staleDashboard, operatorDashboard)
+		installation := &integreatlyv1alpha1.RHMI{}
+		reconciler := getBasicReconciler()
+		reconciler.Config = config.NewGrafana(config.ProductConfig{
+			"OPERATOR_NAMESPACE":            operatorNamespace,
+			"CUSTOMER_DASHBOARDS_NAMESPACE": customerNamespace,
+		})
+
+		phase, err := reconciler.reconcileCustomerDashboards(context.TODO(), client, installation)
+		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+			t.Fatalf("unexpected result %s %v", phase, err)
+		}
+
+		dashboard := &grafanav1alpha1.GrafanaDashboard{}
+		if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: getImportedDashboardName("orders", "overview.json"), Namespace: operatorNamespace}, dashboard); err != nil {
+			t.Fatalf("expected the customer dashboard to be imported: %v", err)
+		}
+		if dashboard.Labels["monitoring-key"] != "customer" || dashboard.Spec.CustomFolderName != customerDashboardFolder {
+			t.Errorf("unexpected dashboard %v %s", dashboard.Labels, dashboard.Spec.CustomFolderName)
+		}
+
+		dashboards := &grafanav1alpha1.GrafanaDashboardList{}
+		if err := client.List(context.TODO(), dashboards, k8sclient.HasLabels{importedDashboardLabel}); err != nil {
+			t.Fatal(err)
+		}
+		if len(dashboards.Items) != 1 {
+			t.Errorf("expected only the valid dashboard to be imported, got %d", len(dashboards.Items))
+		}
+		err = client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(staleDashboard), &grafanav1alpha1.GrafanaDashboard{})
+		if !k8serr.IsNotFound(err) {
+			t.Errorf("expected the removed customer dashboard to be deleted, got %v", err)
+		}
+		if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(operatorDashboard), &grafanav1alpha1.GrafanaDashboard{}); err != nil {
+			t.Errorf("expected the operator dashboard to be kept: %v", err)
+		}
+
+		statuses := installation.Status.CustomerDashboards
+		if len(statuses) != 3 {
+			t.Fatalf("expected the status of all dashboards, got %v", statuses)
+		}
+		if statuses[0].Name != "customer-dashboards/orders/broken.json" || statuses[0].Imported || statuses[0].Error == "" {
+			t.Errorf("expected the import error to be reported, got %v", statuses[0])
+		}
+		if statuses[1].Name != "customer-dashboards/orders/overview.json" || !statuses[1].Imported {
+			t.Errorf("expected the dashboard to be reported as imported, got %v", statuses[1])
+		}
+		if statuses[2].Name != "customer-dashboards/orders/summary.json" || statuses[2].Imported || !strings.Contains(statuses[2].Error, "already used by the customer dashboard customer-dashboards/orders/overview.json") {
+			t.Errorf("expected the duplicate uid to be reported, got %v", statuses[2])
+		}
+	})
+
+	t.Run("removes imported dashboards when disabled", func(t *testing.T) {
+		client := utils.NewTestClient(scheme, configMap, staleDashboard)
+		installation := &integreatlyv1alpha1.RHMI{}
+		reconciler := getBasicReconciler()
+		reconciler.Config = config.NewGrafana(config.ProductConfig{"OPERATOR_NAMESPACE": operatorNamespace})
+
+		phase, err := reconciler.reconcileCustomerDashboards(context.TODO(), client, installation)
+		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+			t.Fatalf("unexpected result %s %v", phase, err)
+		}
+		err = client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(staleDashboard), &grafanav1alpha1.GrafanaDashboard{})
+		if !k8serr.IsNotFound(err) {
+			t.Errorf("expected the imported dashboard to be deleted, got %v", err)
+		}
+		if installation.Status.CustomerDashboards != nil {
+			t.Errorf("expected no customer dashboard status, got %v", installation.Status.CustomerDashboards)
+		}
+	})
+}
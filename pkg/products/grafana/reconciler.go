@@ -183,6 +183,25 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, err
 	}
 
+	if integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(installation.Spec.Type)) {
+		phase, err = r.reconcileTenantGrafanaDashboards(ctx, client, activeQuota)
+		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+			events.HandleError(r.recorder, installation, phase, "Failed to reconcile tenant grafana dashboards", err)
+			return phase, err
+		}
+	}
+
+	if err := r.reconcileCustomerDashboardsNamespace(ctx, client, installation); err != nil {
+		events.HandleError(r.recorder, installation, integreatlyv1alpha1.PhaseFailed, "Failed to reconcile customer grafana dashboards namespace", err)
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	phase, err = r.reconcileCustomerDashboards(ctx, client, installation)
+	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+		events.HandleError(r.recorder, installation, phase, "Failed to reconcile customer grafana dashboards", err)
+		return phase, err
+	}
+
 	if string(r.Config.GetProductVersion()) != string(integreatlyv1alpha1.VersionGrafana) {
 		r.Config.SetProductVersion(string(integreatlyv1alpha1.VersionGrafana))
 		if err := r.ConfigManager.WriteConfig(r.Config); err != nil {
@@ -190,7 +209,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		}
 	}
 
-	alertsReconciler := r.newAlertReconciler(r.log, r.installation.Spec.Type, config.GetOboNamespace(r.installation.Namespace))
+	alertsReconciler, err := r.newAlertReconciler(r.log, r.installation.Spec.Type, config.GetOboNamespace(r.installation.Namespace))
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
 	if phase, err := alertsReconciler.ReconcileAlerts(ctx, client); err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.recorder, installation, phase, "Failed to reconcile grafana alerts", err)
 		return phase, err
@@ -234,6 +256,11 @@ func (r *Reconciler) reconcileSecrets(ctx context.Context, client k8sclient.Clie
 
 func (r *Reconciler) reconcileGrafanaDashboards(ctx context.Context, serverClient k8sclient.Client, dashboard string, limitConfig marin3rconfig.RateLimitConfig, activeQuota string) (integreatlyv1alpha1.StatusPhase, error) {
 
+	dashboardJSON, err := getCustomerMonitoringGrafanaRateLimitJSON(fmt.Sprintf("%d", limitConfig.RequestsPerUnit), activeQuota)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to generate dashboard %s: %w", dashboard, err)
+	}
+
 	grafanaDB := &grafanav1alpha1.GrafanaDashboard{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      dashboard,
@@ -247,7 +274,7 @@ func (r *Reconciler) reconcileGrafanaDashboards(ctx context.Context, serverClien
 		}
 
 		grafanaDB.Spec = grafanav1alpha1.GrafanaDashboardSpec{
-			Json: getCustomerMonitoringGrafanaRateLimitJSON(fmt.Sprintf("%d", limitConfig.RequestsPerUnit), activeQuota),
+			Json: dashboardJSON,
 		}
 		return nil
 	})
@@ -260,6 +287,20 @@ func (r *Reconciler) reconcileGrafanaDashboards(ctx context.Context, serverClien
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
+func (r *Reconciler) reconcileTenantGrafanaDashboards(ctx context.Context, serverClient k8sclient.Client, activeQuota string) (integreatlyv1alpha1.StatusPhase, error) {
+	marin3rConfig, err := r.ConfigManager.ReadMarin3r()
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("could not retrieve marin3r config: %w", err)
+	}
+
+	folderClient, err := newFolderClientFromSecret(ctx, serverClient, r.Config.GetOperatorNamespace())
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	return r.reconcileTenantDashboards(ctx, serverClient, marin3rConfig.GetNamespace(), activeQuota, folderClient)
+}
+
 func (r *Reconciler) scaleDeployment(ctx context.Context, client k8sclient.Client, name string, namespace string, scaleValue int32) (integreatlyv1alpha1.StatusPhase, error) {
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		deployment := &appsv1.Deployment{
@@ -395,6 +436,9 @@ func (r *Reconciler) reconcileComponents(ctx context.Context, client k8sclient.C
 				},
 			},
 		}
+		if integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(r.installation.Spec.Type)) {
+			setTenantUserAuth(grafana)
+		}
 		return nil
 	})
 
@@ -417,10 +461,29 @@ func (r *Reconciler) reconcileComponents(ctx context.Context, client k8sclient.C
 	return r.reconcileServiceAccount(ctx, client)
 }
 
+// setTenantUserAuth signs in the users authenticated by the OAuth proxy instead of allowing
+// anonymous access, so the dashboard folder of each tenant can be restricted to its users.
+// Only the proxy in the pod is trusted to set the user header
+func setTenantUserAuth(grafana *grafanav1alpha1.Grafana) {
+	grafana.Spec.Config.AuthAnonymous.Enabled = boolPtr(false)
+	grafana.Spec.Config.AuthProxy = &grafanav1alpha1.GrafanaConfigAuthProxy{
+		Enabled:        boolPtr(true),
+		HeaderName:     "X-Forwarded-User",
+		HeaderProperty: "username",
+		AutoSignUp:     boolPtr(true),
+		Whitelist:      "127.0.0.1, ::1",
+	}
+	for i, container := range grafana.Spec.Containers {
+		if container.Name == "grafana-proxy" {
+			grafana.Spec.Containers[i].Args = append(container.Args, "-pass-user-headers=true")
+		}
+	}
+}
+
 func (r *Reconciler) configDataSource(ctx context.Context, client k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
 	prometheusService := &corev1.Service{}
 	namespace := config.GetOboNamespace(r.installation.Namespace)
-	err := client.Get(ctx, k8sclient.ObjectKey{Name: "rhoam-prometheus", Namespace: namespace}, prometheusService)
+	err := client.Get(ctx, k8sclient.ObjectKey{Name: config.PrometheusServiceName, Namespace: namespace}, prometheusService)
 	if err != nil {
 		if !k8serr.IsNotFound(err) {
 			return integreatlyv1alpha1.PhaseFailed, err
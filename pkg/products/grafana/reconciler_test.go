@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	grafanav1alpha1 "github.com/grafana-operator/grafana-operator/v4/api/integreatly/v1alpha1"
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	"github.com/integr8ly/integreatly-operator/pkg/config"
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
@@ -109,3 +110,26 @@ func getBasicReconciler() *Reconciler {
 		Config:       &config.Grafana{},
 	}
 }
+
+func TestSetTenantUserAuth(t *testing.T) {
+	grafana := &grafanav1alpha1.Grafana{
+		Spec: grafanav1alpha1.GrafanaSpec{
+			Config: grafanav1alpha1.GrafanaConfig{
+				AuthAnonymous: &grafanav1alpha1.GrafanaConfigAuthAnonymous{Enabled: boolPtr(true)},
+			},
+			Containers: []corev1.Container{{Name: "grafana-proxy", Args: []string{"-provider=openshift"}}},
+		},
+	}
+
+	setTenantUserAuth(grafana)
+	if *grafana.Spec.Config.AuthAnonymous.Enabled {
+		t.Error("expected anonymous access to be disabled")
+	}
+	authProxy := grafana.Spec.Config.AuthProxy
+	if authProxy == nil || !*authProxy.Enabled || authProxy.HeaderName != "X-Forwarded-User" || authProxy.Whitelist != "127.0.0.1, ::1" {
+		t.Errorf("expected the users signed in by the OAuth proxy, got %+v", authProxy)
+	}
+	if args := grafana.Spec.Containers[0].Args; args[len(args)-1] != "-pass-user-headers=true" {
+		t.Errorf("expected the OAuth proxy to pass the user headers, got %v", args)
+	}
+}
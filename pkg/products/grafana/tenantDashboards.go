@@ -0,0 +1,228 @@
+package grafana
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	grafanav1alpha1 "github.com/grafana-operator/grafana-operator/v4/api/integreatly/v1alpha1"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/dashboard"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// tenantDashboardLabel holds the name of the tenant a rate limit dashboard was generated for
+	tenantDashboardLabel = "integreatly.org/tenant"
+	tenantFolderPrefix   = "tenant-"
+)
+
+// reconcileTenantDashboards creates a rate limit dashboard for each active tenant of a multitenant
+// installation and deletes the dashboards of tenants that no longer exist. Each dashboard is
+// placed in a folder of its own, restricted to a Grafana team holding the users of the tenant.
+// The folders and teams of removed tenants are deleted
+func (r *Reconciler) reconcileTenantDashboards(ctx context.Context, serverClient k8sclient.Client, rateLimitNamespace, activeQuota string, folderClient FolderClient) (integreatlyv1alpha1.StatusPhase, error) {
+	tenants, err := userHelper.GetMultiTenantUsers(ctx, serverClient)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to get tenants: %w", err)
+	}
+
+	tenantUsers := map[string][]string{}
+	for _, tenant := range tenants {
+		tenantUsers[tenant.TenantName] = append(tenantUsers[tenant.TenantName], tenant.Username)
+	}
+
+	if len(tenantUsers) > 0 {
+		limitPerTenant, err := getLimitPerTenant(ctx, serverClient, rateLimitNamespace)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		if limitPerTenant == "" {
+			r.log.Infof("Waiting for the tenant rate limit", l.Fields{"configMap": marin3rconfig.MultitenantLimitConfigMapName, "ns": rateLimitNamespace})
+			return integreatlyv1alpha1.PhaseAwaitingComponents, nil
+		}
+
+		for tenant, users := range tenantUsers {
+			if err := r.reconcileTenantDashboard(ctx, serverClient, tenant, limitPerTenant, activeQuota); err != nil {
+				return integreatlyv1alpha1.PhaseFailed, err
+			}
+			if err := restrictTenantFolder(ctx, folderClient, tenant, users); err != nil {
+				return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to restrict the dashboards of tenant %s: %w", tenant, err)
+			}
+		}
+	}
+
+	dashboards := &grafanav1alpha1.GrafanaDashboardList{}
+	if err := serverClient.List(ctx, dashboards, k8sclient.InNamespace(r.Config.GetOperatorNamespace()), k8sclient.HasLabels{tenantDashboardLabel}); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to list tenant dashboards: %w", err)
+	}
+	for i := range dashboards.Items {
+		dashboard := &dashboards.Items[i]
+		if _, ok := tenantUsers[dashboard.Labels[tenantDashboardLabel]]; ok {
+			continue
+		}
+		if err := serverClient.Delete(ctx, dashboard); err != nil && !k8serr.IsNotFound(err) {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to delete dashboard %s: %w", dashboard.Name, err)
+		}
+		r.log.Infof("Deleted dashboard of removed tenant", l.Fields{"grafanaDashboard": dashboard.Name})
+	}
+
+	removed, err := deleteRemovedTenantFolders(ctx, folderClient, tenantUsers)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	for _, tenant := range removed {
+		r.log.Infof("Deleted dashboard folder and team of removed tenant", l.Fields{"tenant": tenant})
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+func (r *Reconciler) reconcileTenantDashboard(ctx context.Context, serverClient k8sclient.Client, tenant, limitPerTenant, activeQuota string) error {
+	dashboardJSON, err := getTenantRateLimitDashboard(limitPerTenant, activeQuota, tenant).JSON()
+	if err != nil {
+		return fmt.Errorf("failed to generate dashboard of tenant %s: %w", tenant, err)
+	}
+
+	grafanaDB := &grafanav1alpha1.GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", rateLimitDashBoardName, tenant),
+			Namespace: r.Config.GetOperatorNamespace(),
+		},
+	}
+
+	opRes, err := controllerutil.CreateOrUpdate(ctx, serverClient, grafanaDB, func() error {
+		grafanaDB.Labels = map[string]string{
+			"monitoring-key":     "customer",
+			tenantDashboardLabel: tenant,
+		}
+
+		grafanaDB.Spec = grafanav1alpha1.GrafanaDashboardSpec{
+			Json:             dashboardJSON,
+			CustomFolderName: tenantFolderPrefix + tenant,
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile dashboard of tenant %s: %w", tenant, err)
+	}
+	if opRes != controllerutil.OperationResultNone {
+		r.log.Infof("Operation result grafana dashboard", l.Fields{"grafanaDashboard": grafanaDB.Name, "result": opRes})
+	}
+	return nil
+}
+
+// restrictTenantFolder restricts the dashboard folder of the tenant to the Grafana team named
+// after it and sets the users of the tenant as the members of the team
+func restrictTenantFolder(ctx context.Context, folderClient FolderClient, tenant string, users []string) error {
+	folderUID, err := folderClient.EnsureFolder(ctx, tenantFolderPrefix+tenant)
+	if err != nil {
+		return err
+	}
+	teamID, err := folderClient.EnsureTeam(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	if err := folderClient.SetTeamMembers(ctx, teamID, users); err != nil {
+		return err
+	}
+	return folderClient.SetFolderTeamPermission(ctx, folderUID, teamID)
+}
+
+// deleteRemovedTenantFolders deletes the team and the dashboard folder of the tenants without
+// users and returns the names of the tenants removed. The team is deleted first, so a team
+// left over by a failure is deleted on the next reconcile
+func deleteRemovedTenantFolders(ctx context.Context, folderClient FolderClient, tenantUsers map[string][]string) ([]string, error) {
+	folders, err := folderClient.ListFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for title, uid := range folders {
+		tenant := strings.TrimPrefix(title, tenantFolderPrefix)
+		if tenant == title {
+			continue
+		}
+		if _, ok := tenantUsers[tenant]; ok {
+			continue
+		}
+		if err := folderClient.DeleteTeam(ctx, tenant); err != nil {
+			return nil, err
+		}
+		if err := folderClient.DeleteFolder(ctx, uid); err != nil {
+			return nil, err
+		}
+		removed = append(removed, tenant)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// getTenantRateLimitDashboard returns the rate limit dashboard of a tenant. Limitador does not
+// label its metrics by tenant, the usage of the tenant counters is exported by the operator as
+// the rhoam_ratelimit_usage metrics instead, see metrics.SetRateLimitUsage
+func getTenantRateLimitDashboard(limitPerTenant, activeQuota, tenant string) *dashboard.Dashboard {
+	selector := fmt.Sprintf("{tenant='%s'}", tenant)
+	requests := fmt.Sprintf("sum(rhoam_ratelimit_usage%s) or vector(0)", selector)
+	usageRatio := fmt.Sprintf("max(rhoam_ratelimit_usage_ratio%s)", selector)
+
+	return dashboard.New(fmt.Sprintf("Rate Limiting - %s", tenant), getTenantDashboardUID(tenant)).
+		WithVersion(rateLimitDashboardVersion).
+		WithRefresh("1m").
+		WithTimeRange("now-12h", "now").
+		WithConstant(requestsPerUnitVariable, limitPerTenant).
+		AddPanel(dashboard.NewRow("RHOAM API Rate Limiting"),
+			dashboard.GridPos{H: 1, W: 24, X: 0, Y: 0}).
+		AddPanel(dashboard.NewSingleStat("Current Minute - No. Requests", requests).
+			WithDescription("Requests counted by the rate limit of the tenant in the current minute").
+			WithValueName("current").
+			WithThresholds("$"+requestsPerUnitVariable, true).
+			WithTransparent(),
+			dashboard.GridPos{H: 5, W: 4, X: 0, Y: 1}).
+		AddPanel(dashboard.NewSingleStat("Current Minute - Limit Used", usageRatio+"*100 or vector(0)").
+			WithValueName("current").
+			WithThresholds("80,100", true).
+			WithPostfix("%"),
+			dashboard.GridPos{H: 5, W: 5, X: 4, Y: 1}).
+		AddPanel(dashboard.NewGraph("Per Minute API Requests",
+			dashboard.Target{Expr: fmt.Sprintf("sum(max_over_time(rhoam_ratelimit_usage%s[1m])) or vector(0)", selector), Interval: "1m", LegendFormat: "No. of Requests"},
+			dashboard.Target{Expr: "$" + requestsPerUnitVariable, Interval: "1m",
+				LegendFormat: fmt.Sprintf("Active Quota - %s Per Day - Tenant Rate Limit - %s per minute", activeQuota, limitPerTenant)}).
+			WithInterval("1m").
+			WithFillGradient(4),
+			dashboard.GridPos{H: 10, W: 15, X: 9, Y: 1}).
+		AddPanel(dashboard.NewSingleStat("Last 24 Hours - Minutes at the Limit", fmt.Sprintf("count_over_time((%s >= 1)[24h:1m]) or vector(0)", usageRatio)).
+			WithDescription("Minutes in which the tenant reached its rate limit, the further requests of those minutes were rejected").
+			WithValueName("current").
+			WithThresholds("1", true),
+			dashboard.GridPos{H: 5, W: 9, X: 0, Y: 6})
+}
+
+// getLimitPerTenant returns the per minute limit of each tenant set by the rate limit service,
+// or an empty string when the rate limit service has not set it yet
+func getLimitPerTenant(ctx context.Context, serverClient k8sclient.Client, namespace string) (string, error) {
+	configMap := &corev1.ConfigMap{}
+	err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: marin3rconfig.MultitenantLimitConfigMapName, Namespace: namespace}, configMap)
+	if k8serr.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get tenant rate limit: %w", err)
+	}
+	return configMap.Data[marin3rconfig.MultitenantLimitKey], nil
+}
+
+// getTenantDashboardUID returns a stable uid of the dashboard of the tenant, Grafana limits uids to 40 characters
+func getTenantDashboardUID(tenant string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(rateLimitDashBoardName+"-"+tenant)))[:32]
+}
@@ -0,0 +1,240 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	grafanav1alpha1 "github.com/grafana-operator/grafana-operator/v4/api/integreatly/v1alpha1"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	"github.com/integr8ly/integreatly-operator/utils"
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeFolderClient struct {
+	folders     map[string]string
+	permissions map[string]int64
+	teams       map[string]int64
+	members     map[int64][]string
+}
+
+func newFakeFolderClient() *fakeFolderClient {
+	return &fakeFolderClient{folders: map[string]string{}, permissions: map[string]int64{}, teams: map[string]int64{}, members: map[int64][]string{}}
+}
+
+func (c *fakeFolderClient) ListFolders(_ context.Context) (map[string]string, error) {
+	folders := map[string]string{}
+	for title, uid := range c.folders {
+		folders[title] = uid
+	}
+	return folders, nil
+}
+
+func (c *fakeFolderClient) EnsureFolder(_ context.Context, title string) (string, error) {
+	c.folders[title] = "uid-" + title
+	return c.folders[title], nil
+}
+
+func (c *fakeFolderClient) DeleteFolder(_ context.Context, uid string) error {
+	for title, folderUID := range c.folders {
+		if folderUID == uid {
+			delete(c.folders, title)
+		}
+	}
+	return nil
+}
+
+func (c *fakeFolderClient) EnsureTeam(_ context.Context, name string) (int64, error) {
+	if _, ok := c.teams[name]; !ok {
+		c.teams[name] = int64(len(c.teams) + 1)
+	}
+	return c.teams[name], nil
+}
+
+func (c *fakeFolderClient) DeleteTeam(_ context.Context, name string) error {
+	delete(c.teams, name)
+	return nil
+}
+
+func (c *fakeFolderClient) SetTeamMembers(_ context.Context, teamID int64, logins []string) error {
+	c.members[teamID] = logins
+	return nil
+}
+
+func (c *fakeFolderClient) SetFolderTeamPermission(_ context.Context, folderUID string, teamID int64) error {
+	c.permissions[folderUID] = teamID
+	return nil
+}
+
+func TestReconcileTenantDashboards(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operatorNamespace := "customer-monitoring-operator"
+	tenantUser := &usersv1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "acme", Annotations: map[string]string{"tenant": "yes"}},
+	}
+	limitConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: marin3rconfig.MultitenantLimitConfigMapName, Namespace: "marin3r"},
+		Data:       map[string]string{marin3rconfig.MultitenantLimitKey: "250"},
+	}
+	staleDashboard := &grafanav1alpha1.GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rate-limit-removed",
+			Namespace: operatorNamespace,
+			Labels:    map[string]string{tenantDashboardLabel: "removed"},
+		},
+	}
+	installationDashboard := &grafanav1alpha1.GrafanaDashboard{
+		ObjectMeta: metav1.ObjectMeta{Name: rateLimitDashBoardName, Namespace: operatorNamespace},
+	}
+
+	reconciler := getBasicReconciler()
+	reconciler.Config = config.NewGrafana(config.ProductConfig{"OPERATOR_NAMESPACE": operatorNamespace})
+
+	t.Run("waits for the tenant rate limit", func(t *testing.T) {
+		client := utils.NewTestClient(scheme, tenantUser)
+		phase, err := reconciler.reconcileTenantDashboards(context.TODO(), client, "marin3r", "100 Million", newFakeFolderClient())
+		if err != nil || phase != integreatlyv1alpha1.PhaseAwaitingComponents {
+			t.Fatalf("expected to wait for the rate limit, got %s %v", phase, err)
+		}
+	})
+
+	t.Run("creates the dashboards of active tenants and deletes the others", func(t *testing.T) {
+		client := utils.NewTestClient(scheme, tenantUser, limitConfigMap, staleDashboard, installationDashboard)
+		folderClient := newFakeFolderClient()
+		folderClient.folders["tenant-removed"] = "uid-tenant-removed"
+		folderClient.folders["General"] = "uid-general"
+		folderClient.teams["removed"] = 10
+
+		phase, err := reconciler.reconcileTenantDashboards(context.TODO(), client, "marin3r", "100 Million", folderClient)
+		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+			t.Fatalf("unexpected result %s %v", phase, err)
+		}
+
+		dashboard := &grafanav1alpha1.GrafanaDashboard{}
+		if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: "rate-limit-acme", Namespace: operatorNamespace}, dashboard); err != nil {
+			t.Fatalf("expected the tenant dashboard to be created: %v", err)
+		}
+		if dashboard.Labels["monitoring-key"] != "customer" || dashboard.Spec.CustomFolderName != "tenant-acme" {
+			t.Errorf("unexpected dashboard %v", dashboard.ObjectMeta.Labels)
+		}
+		if !strings.Contains(dashboard.Spec.Json, "rhoam_ratelimit_usage{tenant='acme'}") || !strings.Contains(dashboard.Spec.Json, `"query": "250"`) {
+			t.Errorf("expected the dashboard to show the usage and limit of the tenant")
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(dashboard.Spec.Json), &parsed); err != nil {
+			t.Fatalf("invalid dashboard json: %v", err)
+		}
+		if parsed["uid"] != getTenantDashboardUID("acme") || len(getTenantDashboardUID("acme")) > 40 {
+			t.Errorf("unexpected uid %v", parsed["uid"])
+		}
+
+		err = client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(staleDashboard), &grafanav1alpha1.GrafanaDashboard{})
+		if !k8serr.IsNotFound(err) {
+			t.Errorf("expected the dashboard of the removed tenant to be deleted, got %v", err)
+		}
+		if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(installationDashboard), &grafanav1alpha1.GrafanaDashboard{}); err != nil {
+			t.Errorf("expected the installation dashboard to be kept: %v", err)
+		}
+
+		if folderClient.permissions["uid-tenant-acme"] != folderClient.teams["acme"] {
+			t.Errorf("expected the folder to be restricted to the tenant team, got %v", folderClient.permissions)
+		}
+		if members := folderClient.members[folderClient.teams["acme"]]; len(members) != 1 || members[0] != "acme" {
+			t.Errorf("expected the tenant user to be the member of the team, got %v", members)
+		}
+		if _, ok := folderClient.folders["tenant-removed"]; ok {
+			t.Errorf("expected the folder of the removed tenant to be deleted, got %v", folderClient.folders)
+		}
+		if _, ok := folderClient.teams["removed"]; ok {
+			t.Errorf("expected the team of the removed tenant to be deleted, got %v", folderClient.teams)
+		}
+		if _, ok := folderClient.folders["General"]; !ok {
+			t.Errorf("expected the folders not created for tenants to be kept, got %v", folderClient.folders)
+		}
+	})
+}
+
+func TestFolderClient(t *testing.T) {
+	var permissions string
+	var added, removed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders":
+			_, _ = w.Write([]byte(`[{"uid":"abc","title":"tenant-acme"},{"uid":"def","title":"tenant-removed"}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/teams/search":
+			if r.URL.Query().Get("name") == "removed" {
+				_, _ = w.Write([]byte(`{"teams":[{"id":9,"name":"removed"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"teams":[]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/teams":
+			_, _ = w.Write([]byte(`{"teamId":7}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/teams/7/members":
+			_, _ = w.Write([]byte(`[{"userId":3,"login":"former"}]`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/teams/7/members/3":
+			removed = append(removed, "former")
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/users/lookup":
+			if r.URL.Query().Get("loginOrEmail") != "acme-admin" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":5}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/teams/7/members":
+			body, _ := io.ReadAll(r.Body)
+			added = append(added, string(body))
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/folders/abc/permissions":
+			body, _ := io.ReadAll(r.Body)
+			permissions = string(body)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodDelete && (r.URL.Path == "/api/teams/9" || r.URL.Path == "/api/folders/def"):
+			removed = append(removed, r.URL.Path)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewFolderClient(server.URL, "admin", "secret")
+	// acme-dev has not signed in to Grafana yet
+	if err := restrictTenantFolder(context.TODO(), client, "acme", []string{"acme-admin", "acme-dev"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if permissions != `{"items":[{"permission":1,"teamId":7}]}` {
+		t.Errorf("unexpected permissions %s", permissions)
+	}
+	if len(added) != 1 || added[0] != `{"userId":5}` {
+		t.Errorf("expected the signed up tenant user to be added to the team, got %v", added)
+	}
+
+	tenants, err := deleteRemovedTenantFolders(context.TODO(), client, map[string][]string{"acme": {"acme-admin"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tenants) != 1 || tenants[0] != "removed" {
+		t.Errorf("expected the removed tenant to be cleaned up, got %v", tenants)
+	}
+	if strings.Join(removed, ",") != "former,/api/teams/9,/api/folders/def" {
+		t.Errorf("expected the former member, the team and then the folder to be deleted, got %v", removed)
+	}
+}
@@ -6,136 +6,315 @@ import (
 	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
 	"github.com/integr8ly/integreatly-operator/pkg/resources"
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"net/mail"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+const (
+	// scopedAlertPrefix prefixes the PrometheusRules of scoped alerts so rules of
+	// removed scopes can be found and deleted
+	scopedAlertPrefix = "api-usage-scope-"
+
+	// productScopeUnsupportedMessage is reported for alerts scoped to a 3scale product. The
+	// Limitador counters are only keyed by tenant, and Limitador labels its authorized_calls
+	// and limited_calls metrics by limitador_namespace, so the requests of a product can't
+	// be told apart
+	productScopeUnsupportedMessage = "product scoped alerts are not supported, the rate limit usage is not counted per 3scale product"
+	// tenantScopeUnsupportedMessage is reported for alerts scoped to a tenant outside of
+	// multitenant installations, where the requests are not counted per tenant
+	tenantScopeUnsupportedMessage = "tenant scoped alerts are only supported in multitenant installations"
+)
+
 var (
 	totalRequestsMetric = "authorized_calls"
+	// tenantUsageMetric is the number of requests counted by the rate limit of a tenant in
+	// the current window, exported by the operator from the Limitador counters, see
+	// ReconcileUsage
+	tenantUsageMetric = "rhoam_ratelimit_usage"
+	scopeValueRegexp  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// usageSelector selects the requests to the APIs, leaving out the requests to the 3scale
+	// portals and the shadow limits which are counted in their own Limitador namespaces
+	usageSelector = fmt.Sprintf("{limitador_namespace='%s'}", ratelimit.RateLimitDomain)
 )
 
-func (r *Reconciler) newAlertsReconciler(grafanaDashboardURL string, namespace string) (resources.AlertReconciler, error) {
+func (r *Reconciler) newAlertsReconciler(grafanaDashboardURL string, namespace string) (resources.AlertReconciler, map[string]marin3rconfig.AlertConfigStatus, error) {
 
 	requestsAllowedPerSecond, err := r.getRateLimitInSeconds(r.RateLimitConfig.Unit, r.RateLimitConfig.RequestsPerUnit)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	alerts, err := mapAlertsConfiguration(r.log, namespace, r.RateLimitConfig.Unit, r.RateLimitConfig.RequestsPerUnit, requestsAllowedPerSecond, r.AlertsConfig, grafanaDashboardURL, r.installation.Spec.Type)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create alerts from configuration: %w", err)
-	}
+	alerts, statuses := mapAlertsConfiguration(r.log, namespace, r.RateLimitConfig.Unit, r.RateLimitConfig.RequestsPerUnit, requestsAllowedPerSecond, r.AlertsConfig, grafanaDashboardURL, r.installation.Spec.Type)
 
 	return &resources.AlertReconcilerImpl{
 		ProductName:  "3Scale",
 		Installation: r.installation,
 		Log:          r.log,
 		Alerts:       alerts,
-	}, nil
+	}, statuses, nil
 }
 
 // mapAlertsConfiguration maps each value from alertsConfig into a
 // resources.AlertConfiguration object, resulting into a list of the
-// prometheus alerts to be created
-func mapAlertsConfiguration(logger l.Logger, namespace, rateLimitUnit string, rateLimitRequestsPerUnit uint32, requestsAllowedPerSecond float64, alertsConfig map[string]*marin3rconfig.AlertConfig, grafanaDashboardURL string, installationName string) ([]resources.AlertConfiguration, error) {
+// prometheus alerts to be created. Alerts scoped to a tenant are grouped into
+// a single AlertConfiguration per scope. Invalid alert configs are skipped and
+// reported in the returned statuses
+func mapAlertsConfiguration(logger l.Logger, namespace, rateLimitUnit string, rateLimitRequestsPerUnit uint32, requestsAllowedPerSecond float64, alertsConfig map[string]*marin3rconfig.AlertConfig, grafanaDashboardURL string, installationName string) ([]resources.AlertConfiguration, map[string]marin3rconfig.AlertConfigStatus) {
 	result := make([]resources.AlertConfiguration, 0, len(alertsConfig))
+	statuses := make(map[string]marin3rconfig.AlertConfigStatus, len(alertsConfig))
+	scopedAlerts := map[string]*resources.AlertConfiguration{}
 
-	for alertName, alertConfig := range alertsConfig {
+	prefix := ""
+	if installationName == string(integreatlyv1alpha1.InstallationTypeManagedApi) {
+		prefix = "marin3r-"
+	}
 
-		if installationName == string(integreatlyv1alpha1.InstallationTypeManagedApi) {
-			alertName = "marin3r-" + alertName
-		}
+	// Iterate in a stable order so the alerts are not reordered on every reconcile
+	keys := make([]string, 0, len(alertsConfig))
+	for key := range alertsConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-		switch alertConfig.Type {
-		case marin3rconfig.AlertTypeSpike:
-			expr := fmt.Sprintf(
-				"max_over_time((sum(increase(authorized_calls[1m])) + sum(increase(limited_calls[1m])))[%s:]) > %d",
-				alertConfig.Period, rateLimitRequestsPerUnit)
-			annotations := map[string]string{
-				"message":        fmt.Sprintf("hard limit of %d breached at least once in the last %s", rateLimitRequestsPerUnit, alertConfig.Period),
-				"grafanaConsole": grafanaDashboardURL,
-			}
-			alert := mapSpikeAlert(alertConfig, alertName, namespace, expr, annotations, installationName)
-			result = append(result, alert)
-		case marin3rconfig.AlertTypeThreshold:
+	for _, key := range keys {
+		alertConfig := alertsConfig[key]
 
-			usageFrequencyMins, err := intervalToMinutes(alertConfig.Period)
-			if err != nil {
-				return nil, err
+		scope, err := validateAlertScope(alertConfig)
+		if err != nil {
+			statuses[key] = marin3rconfig.AlertConfigStatus{Message: err.Error()}
+			continue
+		}
+		if scope != "" {
+			if alertConfig.Scope.Product != "" {
+				statuses[key] = marin3rconfig.AlertConfigStatus{Scope: scope, Message: productScopeUnsupportedMessage}
+				continue
 			}
-			requestsAllowedOverTimePeriod := requestsAllowedPerSecond * float64(usageFrequencyMins*60)
-
-			minRateValue, maxRateValue, err := parsePercentageRange(
-				alertConfig.Threshold.MinRate,
-				alertConfig.Threshold.MaxRate,
-			)
-			if err != nil {
-				return nil, err
+			if !integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(installationName)) {
+				statuses[key] = marin3rconfig.AlertConfigStatus{Scope: scope, Message: tenantScopeUnsupportedMessage}
+				continue
 			}
+		}
 
-			lowerExpr := increaseExpr(totalRequestsMetric, alertConfig.Period, ">=", requestsAllowedOverTimePeriod, &minRateValue)
-			upperExpr := increaseExpr(totalRequestsMetric, alertConfig.Period, "<=", requestsAllowedOverTimePeriod, maxRateValue)
+		limit := rateLimitRequestsPerUnit
+		if alertConfig.Limit != nil {
+			limit = *alertConfig.Limit
+		}
+		allowedPerSecond := requestsAllowedPerSecond * float64(limit) / float64(rateLimitRequestsPerUnit)
+
+		rule, err := mapAlertRule(alertConfig, limit, allowedPerSecond, rateLimitUnit, grafanaDashboardURL, installationName)
+		if err != nil {
+			statuses[key] = marin3rconfig.AlertConfigStatus{Scope: scope, Message: err.Error()}
+			continue
+		}
+		if rule == nil {
+			logger.Infof("Unsupported Alert Type found", l.Fields{"alertName": key})
+			statuses[key] = marin3rconfig.AlertConfigStatus{Scope: scope, Message: fmt.Sprintf("unsupported alert type %q", alertConfig.Type)}
+			continue
+		}
 
-			// Get the complete expression by ANDing the lower and the upper if the
-			// upper limit is set, if not, assign the lower one
-			expr := *lowerExpr
-			upperMessage := "100%"
-			if upperExpr != nil {
-				expr = fmt.Sprintf("%s and %s", expr, *upperExpr)
-				upperMessage = *alertConfig.Threshold.MaxRate
+		if scope == "" {
+			alert := resources.AlertConfiguration{
+				AlertName: prefix + key,
+				GroupName: "api-usage.rules",
+				Namespace: namespace,
+				Rules:     []monv1.Rule{*rule},
 			}
-			annotations := map[string]string{
-				"message": fmt.Sprintf(
-					"Total API usage in your API Management service is between %s and %s of the allowable threshold, %d requests per %s, during the last %s",
-					alertConfig.Threshold.MinRate, upperMessage, rateLimitRequestsPerUnit, rateLimitUnit, alertConfig.Period,
-				),
-				"grafanaConsole": grafanaDashboardURL,
+			if alertConfig.Type == marin3rconfig.AlertTypeSpike {
+				alert.GroupName = "ratelimit-spike.rules"
+				alert.Interval = alertConfig.Period
 			}
-			alert := mapThresholdAlert(alertConfig, alertName, namespace, expr, annotations, installationName)
-
 			result = append(result, alert)
-		default:
-			logger.Infof("Unsupported Alert Type found", l.Fields{"alertName": alertName})
+			statuses[key] = marin3rconfig.AlertConfigStatus{Valid: true, PrometheusRule: alert.AlertName}
+			continue
 		}
+		alert, ok := scopedAlerts[scope]
+		if !ok {
+			alert = &resources.AlertConfiguration{
+				AlertName: prefix + scopedAlertPrefix + scope,
+				GroupName: fmt.Sprintf("api-usage-%s.rules", scope),
+				Namespace: namespace,
+				Rules:     []monv1.Rule{},
+			}
+			scopedAlerts[scope] = alert
+		}
+		alert.Rules = append(alert.Rules.([]monv1.Rule), *rule)
+		statuses[key] = marin3rconfig.AlertConfigStatus{Valid: true, Scope: scope, PrometheusRule: alert.AlertName}
+	}
 
+	scopes := make([]string, 0, len(scopedAlerts))
+	for scope := range scopedAlerts {
+		scopes = append(scopes, scope)
 	}
-	return result, nil
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		result = append(result, *scopedAlerts[scope])
+	}
+
+	return result, statuses
 }
 
-func mapSpikeAlert(alertConfig *marin3rconfig.AlertConfig, alertName string, namespace string, expr string, annotations map[string]string, installationName string) resources.AlertConfiguration {
-	return resources.AlertConfiguration{
-		AlertName: alertName,
-		GroupName: "ratelimit-spike.rules",
-		Namespace: namespace,
-		Interval:  alertConfig.Period,
-		Rules: []monv1.Rule{
-			{
-				Alert:       alertConfig.RuleName,
-				Annotations: annotations,
-				Expr:        intstr.FromString(expr),
-				Labels:      map[string]string{"severity": alertConfig.Level, "product": installationName},
+// mapAlertRule returns the prometheus rule of an alert config, or nil if
+// the alert type is not supported. The rules of tenant scoped alerts are built
+// from the usage of the tenant counters, see tenantUsageMetric
+func mapAlertRule(alertConfig *marin3rconfig.AlertConfig, limit uint32, requestsAllowedPerSecond float64, rateLimitUnit string, grafanaDashboardURL string, installationName string) (*monv1.Rule, error) {
+	tenant := ""
+	if alertConfig.Scope != nil {
+		tenant = alertConfig.Scope.Tenant
+	}
+	labels := map[string]string{"severity": alertConfig.Level, "product": installationName}
+	if tenant != "" {
+		labels[marin3rconfig.ScopeTenantLabel] = tenant
+	}
+	if len(alertConfig.Emails) > 0 {
+		labels[marin3rconfig.RecipientsLabel] = strings.Join(alertConfig.Emails, ", ")
+	}
+
+	var rule *monv1.Rule
+	switch alertConfig.Type {
+	case marin3rconfig.AlertTypeSpike:
+		if _, err := intervalToMinutes(alertConfig.Period); err != nil {
+			return nil, err
+		}
+		expr := fmt.Sprintf(
+			"max_over_time((sum(increase(authorized_calls%s[1m])) + sum(increase(limited_calls%s[1m])))[%s:]) > %d",
+			usageSelector, usageSelector, alertConfig.Period, limit)
+		message := fmt.Sprintf("hard limit of %d breached at least once in the last %s", limit, alertConfig.Period)
+		// The tenant counters stop at the limit of the tenant, the further requests of the
+		// window are rejected. Without a limit on the alert, the alert fires once the tenant
+		// reached its own limit
+		switch {
+		case tenant != "" && alertConfig.Limit != nil:
+			expr = fmt.Sprintf("max_over_time(sum(%s)[%s:]) >= %d", tenantUsageSelector(tenant), alertConfig.Period, limit)
+			message = fmt.Sprintf("tenant %s reached the hard limit of %d at least once in the last %s", tenant, limit, alertConfig.Period)
+		case tenant != "":
+			expr = fmt.Sprintf("max_over_time(max(%s)[%s:]) >= 1", tenantUsageRatioSelector(tenant), alertConfig.Period)
+			message = fmt.Sprintf("tenant %s reached its rate limit at least once in the last %s", tenant, alertConfig.Period)
+		}
+		rule = &monv1.Rule{
+			Alert: alertConfig.RuleName,
+			Annotations: map[string]string{
+				"message":        message,
+				"grafanaConsole": grafanaDashboardURL,
 			},
-		},
+			Expr:   intstr.FromString(expr),
+			Labels: labels,
+		}
+	case marin3rconfig.AlertTypeThreshold:
+		if alertConfig.Threshold == nil {
+			return nil, fmt.Errorf("threshold must be set for alerts of type %s", marin3rconfig.AlertTypeThreshold)
+		}
+
+		usageFrequencyMins, err := intervalToMinutes(alertConfig.Period)
+		if err != nil {
+			return nil, err
+		}
+		requestsAllowedOverTimePeriod := requestsAllowedPerSecond * float64(usageFrequencyMins*60)
+
+		minRateValue, maxRateValue, err := parsePercentageRange(
+			alertConfig.Threshold.MinRate,
+			alertConfig.Threshold.MaxRate,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// The tenant usage restarts from zero in each window of the limit, which increase
+		// counts as a counter reset, so the increase adds up the requests of each window
+		requests := totalRequestsMetric + usageSelector
+		usageDescription := "Total API usage in your API Management service"
+		if tenant != "" {
+			requests = tenantUsageSelector(tenant)
+			usageDescription = fmt.Sprintf("API usage of tenant %s", tenant)
+		}
+		lowerExpr := increaseExpr(requests, alertConfig.Period, ">=", requestsAllowedOverTimePeriod, &minRateValue)
+		upperExpr := increaseExpr(requests, alertConfig.Period, "<=", requestsAllowedOverTimePeriod, maxRateValue)
+
+		// Get the complete expression by ANDing the lower and the upper if the
+		// upper limit is set, if not, assign the lower one
+		expr := *lowerExpr
+		upperMessage := "100%"
+		if upperExpr != nil {
+			expr = fmt.Sprintf("%s and %s", expr, *upperExpr)
+			upperMessage = *alertConfig.Threshold.MaxRate
+		}
+		rule = &monv1.Rule{
+			Alert: alertConfig.RuleName,
+			Annotations: map[string]string{
+				"message": fmt.Sprintf(
+					"%s is between %s and %s of the allowable threshold, %d requests per %s, during the last %s",
+					usageDescription, alertConfig.Threshold.MinRate, upperMessage, limit, rateLimitUnit, alertConfig.Period,
+				),
+				"grafanaConsole": grafanaDashboardURL,
+			},
+			Expr:   intstr.FromString(expr),
+			Labels: labels,
+		}
+	default:
+		return nil, nil
+	}
+
+	if err := resources.ValidateAlertRule(*rule); err != nil {
+		return nil, err
 	}
+	return rule, nil
 }
 
-func mapThresholdAlert(alertConfig *marin3rconfig.AlertConfig, alertName string, namespace string, expr string, annotations map[string]string, installationName string) resources.AlertConfiguration {
-	return resources.AlertConfiguration{
-		AlertName: alertName,
-		GroupName: "api-usage.rules",
-		Namespace: namespace,
-		Rules: []monv1.Rule{
-			{
-				Alert:       alertConfig.RuleName,
-				Annotations: annotations,
-				Expr:        intstr.FromString(expr),
-				Labels:      map[string]string{"severity": alertConfig.Level, "product": installationName},
-			},
-		},
+// validateAlertScope validates the parts of an alert config shared by all
+// alert types and returns the name of its scope, empty if it is not scoped
+func validateAlertScope(alertConfig *marin3rconfig.AlertConfig) (string, error) {
+	if alertConfig == nil {
+		return "", fmt.Errorf("alert config must not be empty")
+	}
+	if alertConfig.RuleName == "" {
+		return "", fmt.Errorf("ruleName must be set")
+	}
+	if alertConfig.Limit != nil && *alertConfig.Limit == 0 {
+		return "", fmt.Errorf("limit must be greater than 0")
+	}
+	for _, email := range alertConfig.Emails {
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email {
+			return "", fmt.Errorf("invalid email address %q", email)
+		}
+	}
+
+	if alertConfig.Scope == nil {
+		return "", nil
+	}
+	var scope []string
+	if tenant := alertConfig.Scope.Tenant; tenant != "" {
+		if !scopeValueRegexp.MatchString(tenant) {
+			return "", fmt.Errorf("invalid tenant %q, must consist of lower case alphanumeric characters or '-'", tenant)
+		}
+		scope = append(scope, "tenant", tenant)
+	}
+	if product := alertConfig.Scope.Product; product != "" {
+		if !scopeValueRegexp.MatchString(product) {
+			return "", fmt.Errorf("invalid product %q, must consist of lower case alphanumeric characters or '-'", product)
+		}
+		scope = append(scope, "product", product)
+	}
+	if len(scope) == 0 {
+		return "", fmt.Errorf("scope must select a tenant or a product")
 	}
+	return strings.Join(scope, "-"), nil
+}
+
+// tenantUsageSelector selects the usage of the rate limit counters of a tenant
+func tenantUsageSelector(tenant string) string {
+	return fmt.Sprintf("%s{%s='%s'}", tenantUsageMetric, marin3rconfig.ScopeTenantLabel, tenant)
+}
+
+// tenantUsageRatioSelector selects the ratio of the usage to the maximum of the rate limit
+// counters of a tenant
+func tenantUsageRatioSelector(tenant string) string {
+	return fmt.Sprintf("%s_ratio{%s='%s'}", tenantUsageMetric, marin3rconfig.ScopeTenantLabel, tenant)
 }
 
 func increaseExpr(totalRequestsMetric, period string, comparisonOperator string, requestsAllowedOverTimePeriod float64, percenteageLimit *int) *string {
@@ -0,0 +1,304 @@
+package marin3r
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/alerttest"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+func TestMapAlertsConfiguration(t *testing.T) {
+	maxRate := "90%"
+	invalidMaxRate := "70%"
+	tenantLimit := uint32(600)
+
+	alertsConfig := map[string]*marin3rconfig.AlertConfig{
+		"api-usage-alert-level1": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "RHOAMApiUsageLevel1ThresholdExceeded",
+			Level:     "info",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "80%", MaxRate: &maxRate},
+			Period:    "4h",
+		},
+		"acme-usage": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "TenantApiUsageThresholdExceeded",
+			Level:     "warning",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "80%"},
+			Period:    "1h",
+			Scope:     &marin3rconfig.AlertScope{Tenant: "acme"},
+			Limit:     &tenantLimit,
+			Emails:    []string{"ops@acme.example.com", "api@acme.example.com"},
+		},
+		"acme-spike": {
+			Type:     marin3rconfig.AlertTypeSpike,
+			RuleName: "TenantApiUsageOverLimit",
+			Level:    "warning",
+			Period:   "30m",
+			Scope:    &marin3rconfig.AlertScope{Tenant: "acme"},
+		},
+		"acme-orders-usage": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "ProductApiUsageThresholdExceeded",
+			Level:     "info",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "50%"},
+			Period:    "2h",
+			Scope:     &marin3rconfig.AlertScope{Tenant: "acme", Product: "orders"},
+		},
+		"invalid-range": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "InvalidRange",
+			Level:     "info",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "80%", MaxRate: &invalidMaxRate},
+			Period:    "1h",
+		},
+		"invalid-scope": {
+			Type:     marin3rconfig.AlertTypeSpike,
+			RuleName: "InvalidScope",
+			Level:    "warning",
+			Period:   "30m",
+			Scope:    &marin3rconfig.AlertScope{Tenant: "Acme Corp"},
+		},
+		"invalid-email": {
+			Type:     marin3rconfig.AlertTypeSpike,
+			RuleName: "InvalidEmail",
+			Level:    "warning",
+			Period:   "30m",
+			Emails:   []string{"not an address"},
+		},
+		"invalid-level": {
+			Type:     marin3rconfig.AlertTypeSpike,
+			RuleName: "InvalidLevel",
+			Level:    "page",
+			Period:   "30m",
+		},
+		"unsupported-type": {
+			Type:     "Forecast",
+			RuleName: "Unsupported",
+			Level:    "info",
+			Period:   "30m",
+		},
+	}
+
+	alerts, statuses := mapAlertsConfiguration(getLogger(), "observability", "minute", 6000, 100, alertsConfig, "https://grafana", string(integreatlyv1alpha1.InstallationTypeManagedApi))
+
+	alertNames := []string{}
+	for _, alert := range alerts {
+		alertNames = append(alertNames, alert.AlertName)
+	}
+	expectedNames := []string{"marin3r-api-usage-alert-level1"}
+	if strings.Join(alertNames, ",") != strings.Join(expectedNames, ",") {
+		t.Fatalf("expected alerts %v, got %v", expectedNames, alertNames)
+	}
+
+	usageRule := alerts[0].Rules.([]monv1.Rule)[0]
+	if expr := usageRule.Expr.String(); !strings.Contains(expr, "authorized_calls{limitador_namespace='apicast-ratelimit'}[4h]") {
+		t.Errorf("expected the usage of the API rate limit namespace, got %s", expr)
+	}
+
+	expectedStatuses := map[string]marin3rconfig.AlertConfigStatus{
+		"api-usage-alert-level1": {Valid: true, PrometheusRule: "marin3r-api-usage-alert-level1"},
+		"acme-usage":             {Scope: "tenant-acme", Message: tenantScopeUnsupportedMessage},
+		"acme-spike":             {Scope: "tenant-acme", Message: tenantScopeUnsupportedMessage},
+		"acme-orders-usage":      {Scope: "tenant-acme-product-orders", Message: productScopeUnsupportedMessage},
+	}
+	for key, status := range statuses {
+		if expected, ok := expectedStatuses[key]; ok {
+			if status != expected {
+				t.Errorf("expected status %v for %s, got %v", expected, key, status)
+			}
+			continue
+		}
+		if status.Valid || status.Message == "" {
+			t.Errorf("expected %s to be reported as invalid, got %v", key, status)
+		}
+	}
+	if len(statuses) != len(alertsConfig) {
+		t.Errorf("expected a status for each alert, got %v", statuses)
+	}
+}
+
+// TestMapAlertsConfiguration_tenantScope evaluates the alerts scoped to a tenant against the
+// usage of the tenant counters exported by the operator
+func TestMapAlertsConfiguration_tenantScope(t *testing.T) {
+	tenantLimit := uint32(600)
+	alertsConfig := map[string]*marin3rconfig.AlertConfig{
+		"acme-usage": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "TenantApiUsageThresholdExceeded",
+			Level:     "warning",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "80%"},
+			Period:    "10m",
+			Scope:     &marin3rconfig.AlertScope{Tenant: "acme"},
+			Limit:     &tenantLimit,
+		},
+		"acme-spike": {
+			Type:     marin3rconfig.AlertTypeSpike,
+			RuleName: "TenantApiUsageOverLimit",
+			Level:    "warning",
+			Period:   "30m",
+			Scope:    &marin3rconfig.AlertScope{Tenant: "acme"},
+		},
+		"acme-orders-usage": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "ProductApiUsageThresholdExceeded",
+			Level:     "info",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "50%"},
+			Period:    "2h",
+			Scope:     &marin3rconfig.AlertScope{Tenant: "acme", Product: "orders"},
+		},
+	}
+	installationType := string(integreatlyv1alpha1.InstallationTypeMultitenantManagedApi)
+	alerts, statuses := mapAlertsConfiguration(getLogger(), "observability", "minute", 6000, 100, alertsConfig, "https://grafana", installationType)
+
+	expectedStatuses := map[string]marin3rconfig.AlertConfigStatus{
+		"acme-usage":        {Valid: true, Scope: "tenant-acme", PrometheusRule: "api-usage-scope-tenant-acme"},
+		"acme-spike":        {Valid: true, Scope: "tenant-acme", PrometheusRule: "api-usage-scope-tenant-acme"},
+		"acme-orders-usage": {Scope: "tenant-acme-product-orders", Message: productScopeUnsupportedMessage},
+	}
+	for key, expected := range expectedStatuses {
+		if statuses[key] != expected {
+			t.Errorf("expected status %v for %s, got %v", expected, key, statuses[key])
+		}
+	}
+	if len(alerts) != 1 || len(alerts[0].Rules.([]monv1.Rule)) != 2 {
+		t.Fatalf("expected a single PrometheusRule with the two rules of the tenant, got %v", alerts)
+	}
+
+	installation := getBasicInstallation()
+	installation.Spec.Type = installationType
+	alertReconciler := &resources.AlertReconcilerImpl{
+		ProductName:  "3Scale",
+		Installation: installation,
+		Log:          getLogger(),
+		Alerts:       alerts,
+	}
+
+	labels := map[string]string{"severity": "warning", "product": installationType, "tenant": "acme"}
+	alerttest.RunAlertRuleTests(t, alertReconciler, []alerttest.Test{
+		{
+			// The usage is read twice per one minute window, 500 requests per minute are 5000 of
+			// the 6000 allowed in the 10m period
+			Name:     "tenant usage over the threshold",
+			Interval: 30 * time.Second,
+			InputSeries: []alerttest.Series{
+				{Series: `rhoam_ratelimit_usage{limit="tenant", tenant="acme"}`, Values: strings.Repeat("250 500 ", 40)},
+				{Series: `rhoam_ratelimit_usage_ratio{limit="tenant", tenant="acme"}`, Values: "0+0x80"},
+				{Series: `rhoam_ratelimit_usage{limit="tenant", tenant="other"}`, Values: strings.Repeat("500 600 ", 40)},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{
+					EvalTime:  15 * time.Minute,
+					AlertName: "TenantApiUsageThresholdExceeded",
+					ExpAlerts: []alerttest.Alert{{
+						Labels: labels,
+						Annotations: map[string]string{
+							"message": "API usage of tenant acme is between 80% and 100% of the allowable threshold, 600 requests per minute, during the last 10m",
+						},
+					}},
+				},
+				{EvalTime: 15 * time.Minute, AlertName: "TenantApiUsageOverLimit"},
+			},
+		},
+		{
+			Name: "tenant at its rate limit",
+			InputSeries: []alerttest.Series{
+				{Series: `rhoam_ratelimit_usage{limit="tenant", tenant="acme"}`, Values: "0+0x10"},
+				{Series: `rhoam_ratelimit_usage_ratio{limit="tenant", tenant="acme"}`, Values: "0 0.5 1 0 0 0 0 0 0 0 0"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{
+					EvalTime:  5 * time.Minute,
+					AlertName: "TenantApiUsageOverLimit",
+					ExpAlerts: []alerttest.Alert{{
+						Labels: labels,
+						Annotations: map[string]string{
+							"message": "tenant acme reached its rate limit at least once in the last 30m",
+						},
+					}},
+				},
+				{EvalTime: 5 * time.Minute, AlertName: "TenantApiUsageThresholdExceeded"},
+			},
+		},
+	})
+}
+
+// TestMapAlertsConfiguration_limitadorMetrics evaluates the usage alerts against the labels
+// Limitador exposes, the requests are only labelled by Limitador namespace
+func TestMapAlertsConfiguration_limitadorMetrics(t *testing.T) {
+	maxRate := "90%"
+	alertsConfig := map[string]*marin3rconfig.AlertConfig{
+		"api-usage-alert-level1": {
+			Type:      marin3rconfig.AlertTypeThreshold,
+			RuleName:  "RHOAMApiUsageLevel1ThresholdExceeded",
+			Level:     "info",
+			Threshold: &marin3rconfig.AlertThresholdConfig{MinRate: "80%", MaxRate: &maxRate},
+			Period:    "10m",
+		},
+		"api-usage-spike": {
+			Type:     marin3rconfig.AlertTypeSpike,
+			RuleName: "RHOAMApiUsageOverLimit",
+			Level:    "warning",
+			Period:   "30m",
+		},
+	}
+	// 6000 requests per minute, 60000 in the 10m period of the threshold alert
+	alerts, _ := mapAlertsConfiguration(getLogger(), "observability", "minute", 6000, 100, alertsConfig, "https://grafana", string(integreatlyv1alpha1.InstallationTypeManagedApi))
+	installation := getBasicInstallation()
+	installation.Spec.Type = string(integreatlyv1alpha1.InstallationTypeManagedApi)
+	alertReconciler := &resources.AlertReconcilerImpl{
+		ProductName:  "3Scale",
+		Installation: installation,
+		Log:          getLogger(),
+		Alerts:       alerts,
+	}
+
+	limitadorLabels := `job="ratelimit", namespace="redhat-rhoam-marin3r", pod="ratelimit-7d9c8b5f4-x2x9q", instance="10.128.2.15:8080", endpoint="http", service="ratelimit"`
+	alerttest.RunAlertRuleTests(t, alertReconciler, []alerttest.Test{
+		{
+			Name: "API usage between the thresholds",
+			InputSeries: []alerttest.Series{
+				{Series: fmt.Sprintf(`authorized_calls{limitador_namespace="apicast-ratelimit", %s}`, limitadorLabels), Values: "0+5000x20"},
+				{Series: fmt.Sprintf(`limited_calls{limitador_namespace="apicast-ratelimit", %s}`, limitadorLabels), Values: "0+0x20"},
+				// the requests to the 3scale portals are counted in their own Limitador namespace
+				{Series: fmt.Sprintf(`authorized_calls{limitador_namespace="system-provider-ratelimit", %s}`, limitadorLabels), Values: "0+10000x20"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{
+					EvalTime:  15 * time.Minute,
+					AlertName: "RHOAMApiUsageLevel1ThresholdExceeded",
+					ExpAlerts: []alerttest.Alert{{
+						Labels: map[string]string{"severity": "info", "product": string(integreatlyv1alpha1.InstallationTypeManagedApi)},
+						Annotations: map[string]string{
+							"message": "Total API usage in your API Management service is between 80% and 90% of the allowable threshold, 6000 requests per minute, during the last 10m",
+						},
+					}},
+				},
+				{EvalTime: 15 * time.Minute, AlertName: "RHOAMApiUsageOverLimit"},
+			},
+		},
+		{
+			Name: "API usage over the limit",
+			InputSeries: []alerttest.Series{
+				{Series: fmt.Sprintf(`authorized_calls{limitador_namespace="apicast-ratelimit", %s}`, limitadorLabels), Values: "0+6000x20"},
+				{Series: fmt.Sprintf(`limited_calls{limitador_namespace="apicast-ratelimit", %s}`, limitadorLabels), Values: "0+1000x20"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{
+					EvalTime:  5 * time.Minute,
+					AlertName: "RHOAMApiUsageOverLimit",
+					ExpAlerts: []alerttest.Alert{{
+						Labels: map[string]string{"severity": "warning", "product": string(integreatlyv1alpha1.InstallationTypeManagedApi)},
+					}},
+				},
+				{EvalTime: 15 * time.Minute, AlertName: "RHOAMApiUsageLevel1ThresholdExceeded"},
+			},
+		},
+	})
+}
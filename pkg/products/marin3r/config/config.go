@@ -12,11 +12,23 @@ import (
 const (
 	RateLimitConfigMapName = "sku-limits-managed-api-service"
 	AlertConfigMapName     = "rate-limit-alerts"
-	ManagedApiServiceQuota = "RHOAM SERVICE SKU"
+	// AlertStatusConfigMapName holds the validation result of each alert in the AlertConfigMapName ConfigMap
+	AlertStatusConfigMapName = "rate-limit-alerts-status"
+	ManagedApiServiceQuota   = "RHOAM SERVICE SKU"
+	// MultitenantLimitConfigMapName holds the per minute limit of each tenant in multitenant installations
+	MultitenantLimitConfigMapName = "multitenant-config"
+	MultitenantLimitKey           = "mulitenantLimit"
 
 	AlertTypeThreshold = "Threshold"
 	AlertTypeSpike     = "Spike"
 
+	// ScopeTenantLabel labels the usage of the rate limit counters of a tenant, and the
+	// alerts scoped to a tenant
+	ScopeTenantLabel = "tenant"
+	// RecipientsLabel holds the notification addresses of an alert, alertmanager
+	// sends alerts with this label to the listed addresses
+	RecipientsLabel = "recipients"
+
 	DefaultRateLimitUnit     = "minute"
 	DefaultRateLimitRequests = 13860
 )
@@ -32,6 +44,29 @@ type AlertConfig struct {
 	RuleName  string                `json:"ruleName"`
 	Period    string                `json:"period"`
 	Threshold *AlertThresholdConfig `json:"threshold,omitempty"`
+	// Scope restricts the alert to the API usage of a tenant in multitenant installations.
+	// The alert applies to the whole installation when not set. Alerts scoped to a 3scale
+	// product are rejected, the rate limit counters are not kept per product
+	Scope *AlertScope `json:"scope,omitempty"`
+	// Limit is the number of requests per rate limit unit the thresholds are relative
+	// to, defaulting to the rate limit of the installation
+	Limit *uint32 `json:"limit,omitempty"`
+	// Emails are notified in addition to the business unit when the alert fires
+	Emails []string `json:"emails,omitempty"`
+}
+
+type AlertScope struct {
+	Tenant  string `json:"tenant,omitempty"`
+	Product string `json:"product,omitempty"`
+}
+
+// AlertConfigStatus is the validation feedback of an alert config, written to the
+// AlertStatusConfigMapName ConfigMap
+type AlertConfigStatus struct {
+	Valid          bool   `json:"valid"`
+	Scope          string `json:"scope,omitempty"`
+	PrometheusRule string `json:"prometheusRule,omitempty"`
+	Message        string `json:"message,omitempty"`
 }
 
 type AlertThresholdConfig struct {
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DescriptorConfigMapName holds the customer descriptor rules under the DescriptorConfigKey key
+	DescriptorConfigMapName = "rate-limit-descriptors"
+	DescriptorConfigKey     = "descriptors"
+
+	// DescriptorRuleKey is the key of the descriptor entry holding the name of the matched rule
+	DescriptorRuleKey = "descriptor_rule"
+
+	// Values of DescriptorRule.PerKey, the requests matching the rule are counted per value
+	PerKeyUserKey  = "user_key"
+	PerKeyAppID    = "app_id"
+	PerKeyClientIP = "client_ip"
+
+	// remoteAddressDescriptorKey is the key envoy sets for the client IP in remote_address actions
+	remoteAddressDescriptorKey = "remote_address"
+)
+
+var (
+	descriptorRuleNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	// reservedDescriptorValues are used by the descriptors of the global and tenant limits
+	reservedDescriptorValues = []string{"slowpath", "per-mt-limit"}
+	httpMethods              = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+	}
+)
+
+// DescriptorRule limits the API requests matching the path prefix and method, in addition to the
+// global rate limit. The requests are counted together, or separately per PerKey value
+type DescriptorRule struct {
+	Name       string `json:"name"`
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	Method     string `json:"method,omitempty"`
+	// PerKey is one of user_key, app_id or client_ip
+	PerKey          string `json:"perKey,omitempty"`
+	Unit            string `json:"unit"`
+	RequestsPerUnit uint32 `json:"requests_per_unit"`
+}
+
+// GetDescriptorRules returns the descriptor rules configured in the namespace, no rules are
+// returned when the DescriptorConfigMapName ConfigMap does not exist
+func GetDescriptorRules(ctx context.Context, client k8sclient.Client, namespace string) ([]DescriptorRule, error) {
+	var rules []DescriptorRule
+	err := getFromJSONConfigMap(
+		ctx, client,
+		DescriptorConfigMapName, namespace, DescriptorConfigKey,
+		&rules,
+	)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := ValidateDescriptorRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid %s ConfigMap: %w", DescriptorConfigMapName, err)
+	}
+	return rules, nil
+}
+
+// ValidateDescriptorRules checks the rules can be translated to envoy rate limit actions and
+// Limitador limits
+func ValidateDescriptorRules(rules []DescriptorRule) error {
+	names := map[string]bool{}
+	for _, rule := range rules {
+		if !descriptorRuleNameRegexp.MatchString(rule.Name) {
+			return fmt.Errorf("descriptor rule name %q must consist of lower case alphanumeric characters or '-'", rule.Name)
+		}
+		for _, reserved := range reservedDescriptorValues {
+			if rule.Name == reserved {
+				return fmt.Errorf("descriptor rule name %q is reserved", rule.Name)
+			}
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate descriptor rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.PathPrefix == "" && rule.Method == "" && rule.PerKey == "" {
+			return fmt.Errorf("descriptor rule %s: one of pathPrefix, method or perKey is required", rule.Name)
+		}
+		if rule.PathPrefix != "" && !strings.HasPrefix(rule.PathPrefix, "/") {
+			return fmt.Errorf("descriptor rule %s: pathPrefix %q must start with /", rule.Name, rule.PathPrefix)
+		}
+		if rule.Method != "" && !contains(httpMethods, rule.Method) {
+			return fmt.Errorf("descriptor rule %s: unsupported method %q", rule.Name, rule.Method)
+		}
+		if rule.PerKey != "" && !contains([]string{PerKeyUserKey, PerKeyAppID, PerKeyClientIP}, rule.PerKey) {
+			return fmt.Errorf("descriptor rule %s: perKey must be one of %s, %s or %s", rule.Name, PerKeyUserKey, PerKeyAppID, PerKeyClientIP)
+		}
+		if _, ok := conversionFactors[rule.Unit]; !ok {
+			return fmt.Errorf("descriptor rule %s: unsupported unit %q", rule.Name, rule.Unit)
+		}
+		if rule.RequestsPerUnit == 0 {
+			return fmt.Errorf("descriptor rule %s: requests_per_unit must be greater than 0", rule.Name)
+		}
+	}
+	return nil
+}
+
+// CounterKey returns the descriptor key the requests matching the rule are counted by
+func (r DescriptorRule) CounterKey() string {
+	switch r.PerKey {
+	case PerKeyUserKey, PerKeyAppID:
+		return r.PerKey
+	case PerKeyClientIP:
+		return remoteAddressDescriptorKey
+	default:
+		return DescriptorRuleKey
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDescriptorRules(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := GetDescriptorRules(context.TODO(), utils.NewTestClient(scheme), "redhat-test-operator")
+	if err != nil || rules != nil {
+		t.Fatalf("expected no rules without the ConfigMap, got %v %v", rules, err)
+	}
+
+	client := utils.NewTestClient(scheme, &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: DescriptorConfigMapName, Namespace: "redhat-test-operator"},
+		Data: map[string]string{
+			DescriptorConfigKey: `[{"name": "orders", "pathPrefix": "/orders", "perKey": "client_ip", "unit": "minute", "requests_per_unit": 10}]`,
+		},
+	})
+	rules, err = GetDescriptorRules(context.TODO(), client, "redhat-test-operator")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Name != "orders" || rules[0].CounterKey() != "remote_address" {
+		t.Errorf("unexpected rules %v", rules)
+	}
+}
+
+func TestValidateDescriptorRules(t *testing.T) {
+	scenarios := []struct {
+		Name    string
+		Rules   []DescriptorRule
+		WantErr string
+	}{
+		{
+			Name: "Valid rules",
+			Rules: []DescriptorRule{
+				{Name: "orders", PathPrefix: "/orders", Method: "POST", PerKey: PerKeyAppID, Unit: Minute, RequestsPerUnit: 10},
+				{Name: "per-client", PerKey: PerKeyClientIP, Unit: Second, RequestsPerUnit: 5},
+			},
+		},
+		{
+			Name:    "Invalid name",
+			Rules:   []DescriptorRule{{Name: "Orders", PathPrefix: "/orders", Unit: Minute, RequestsPerUnit: 10}},
+			WantErr: "lower case alphanumeric",
+		},
+		{
+			Name:    "Reserved name",
+			Rules:   []DescriptorRule{{Name: "slowpath", PathPrefix: "/orders", Unit: Minute, RequestsPerUnit: 10}},
+			WantErr: "reserved",
+		},
+		{
+			Name: "Duplicate name",
+			Rules: []DescriptorRule{
+				{Name: "orders", PathPrefix: "/orders", Unit: Minute, RequestsPerUnit: 10},
+				{Name: "orders", Method: "GET", Unit: Minute, RequestsPerUnit: 10},
+			},
+			WantErr: "duplicate",
+		},
+		{
+			Name:    "No match",
+			Rules:   []DescriptorRule{{Name: "orders", Unit: Minute, RequestsPerUnit: 10}},
+			WantErr: "one of pathPrefix, method or perKey is required",
+		},
+		{
+			Name:    "Relative path",
+			Rules:   []DescriptorRule{{Name: "orders", PathPrefix: "orders", Unit: Minute, RequestsPerUnit: 10}},
+			WantErr: "must start with /",
+		},
+		{
+			Name:    "Unsupported method",
+			Rules:   []DescriptorRule{{Name: "orders", Method: "get", Unit: Minute, RequestsPerUnit: 10}},
+			WantErr: "unsupported method",
+		},
+		{
+			Name:    "Unsupported key",
+			Rules:   []DescriptorRule{{Name: "orders", PerKey: "account", Unit: Minute, RequestsPerUnit: 10}},
+			WantErr: "perKey must be one of",
+		},
+		{
+			Name:    "Unsupported unit",
+			Rules:   []DescriptorRule{{Name: "orders", PathPrefix: "/orders", Unit: "week", RequestsPerUnit: 10}},
+			WantErr: "unsupported unit",
+		},
+		{
+			Name:    "No requests",
+			Rules:   []DescriptorRule{{Name: "orders", PathPrefix: "/orders", Unit: Minute}},
+			WantErr: "greater than 0",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			err := ValidateDescriptorRules(scenario.Rules)
+			if scenario.WantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), scenario.WantErr) {
+				t.Errorf("expected error containing %q, got %v", scenario.WantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PortalConfigMapName holds the rate limit configuration of the 3scale admin and developer
+	// portals under the PortalConfigKey key
+	PortalConfigMapName = "rate-limit-portals"
+	PortalConfigKey     = "portals"
+
+	// DefaultPortalSessionCookie is the cookie 3scale keeps the portal sessions in
+	DefaultPortalSessionCookie = "user_session"
+
+	// PortalLimitKey is the key of the descriptor entry holding which portal limit the request
+	// is counted by, PortalLimitPerClientIP or PortalLimitPerSession
+	PortalLimitKey         = "portal_limit"
+	PortalLimitPerClientIP = "per_client_ip"
+	PortalLimitPerSession  = "per_session"
+	// PortalSessionKey is the key of the descriptor entry holding the session of the request,
+	// the requests without a session are only counted per client IP
+	PortalSessionKey = "portal_session"
+	// PortalClientIPKey is the key envoy sets for the client IP in remote_address actions
+	PortalClientIPKey = remoteAddressDescriptorKey
+)
+
+// sessionCookieRegexp matches the cookie names which can be looked up without escaping
+var sessionCookieRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// PortalConfig enables the envoy sidecar of the 3scale system-app, rate limiting the requests
+// to the admin and developer portals per client IP and per session. Each portal is limited
+// separately
+type PortalConfig struct {
+	Enabled     bool             `json:"enabled"`
+	PerClientIP *RateLimitConfig `json:"per_client_ip,omitempty"`
+	PerSession  *RateLimitConfig `json:"per_session,omitempty"`
+	// SessionCookie is the cookie the requests are counted by for the per session limit,
+	// defaulting to DefaultPortalSessionCookie
+	SessionCookie string `json:"session_cookie,omitempty"`
+}
+
+// GetPortalConfig returns the rate limit configuration of the portals of the namespace, nil is
+// returned when the PortalConfigMapName ConfigMap does not exist
+func GetPortalConfig(ctx context.Context, client k8sclient.Client, namespace string) (*PortalConfig, error) {
+	portalConfig := &PortalConfig{}
+	err := getFromJSONConfigMap(
+		ctx, client,
+		PortalConfigMapName, namespace, PortalConfigKey,
+		portalConfig,
+	)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := portalConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s ConfigMap: %w", PortalConfigMapName, err)
+	}
+	return portalConfig, nil
+}
+
+// IsEnabled returns whether the portals are rate limited
+func (c *PortalConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetSessionCookie returns the session cookie, defaulting to DefaultPortalSessionCookie
+func (c *PortalConfig) GetSessionCookie() string {
+	if c == nil || c.SessionCookie == "" {
+		return DefaultPortalSessionCookie
+	}
+	return c.SessionCookie
+}
+
+// PortalLimit is a limit of the portals, named after the PortalLimitKey value of its descriptor
+type PortalLimit struct {
+	Name string
+	RateLimitConfig
+}
+
+// Limits returns the configured limits of the portals
+func (c *PortalConfig) Limits() []PortalLimit {
+	var limits []PortalLimit
+	if c == nil {
+		return limits
+	}
+	if c.PerClientIP != nil {
+		limits = append(limits, PortalLimit{Name: PortalLimitPerClientIP, RateLimitConfig: *c.PerClientIP})
+	}
+	if c.PerSession != nil {
+		limits = append(limits, PortalLimit{Name: PortalLimitPerSession, RateLimitConfig: *c.PerSession})
+	}
+	return limits
+}
+
+// CounterKey returns the descriptor key the requests are counted by for the limit
+func (l PortalLimit) CounterKey() string {
+	if l.Name == PortalLimitPerSession {
+		return PortalSessionKey
+	}
+	return PortalClientIPKey
+}
+
+// Validate checks the limits can be translated to Limitador limits, at least one of them is
+// required once enabled
+func (c *PortalConfig) Validate() error {
+	if c.Enabled && c.PerClientIP == nil && c.PerSession == nil {
+		return fmt.Errorf("at least one of per_client_ip and per_session is required")
+	}
+	for _, limit := range c.Limits() {
+		if _, ok := conversionFactors[limit.Unit]; !ok {
+			return fmt.Errorf("%s: unsupported unit %q", limit.Name, limit.Unit)
+		}
+		if limit.RequestsPerUnit == 0 {
+			return fmt.Errorf("%s: requests_per_unit must be greater than 0", limit.Name)
+		}
+	}
+	if c.SessionCookie != "" && !sessionCookieRegexp.MatchString(c.SessionCookie) {
+		return fmt.Errorf("session_cookie %q must only contain letters, digits and underscores", c.SessionCookie)
+	}
+	return nil
+}
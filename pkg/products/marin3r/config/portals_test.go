@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPortalConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	portalConfig, err := GetPortalConfig(context.TODO(), utils.NewTestClient(scheme), "redhat-test-operator")
+	if err != nil || portalConfig != nil || portalConfig.IsEnabled() || portalConfig.GetSessionCookie() != DefaultPortalSessionCookie {
+		t.Fatalf("expected the portals not to be rate limited without the ConfigMap, got %v %v", portalConfig, err)
+	}
+
+	scenarios := []struct {
+		Name    string
+		Config  string
+		WantErr bool
+		Assert  func(*PortalConfig) bool
+	}{
+		{
+			Name:   "Per client IP and per session limits",
+			Config: `{"enabled": true, "per_client_ip": {"unit": "minute", "requests_per_unit": 300}, "per_session": {"unit": "second", "requests_per_unit": 20}, "session_cookie": "admin_session"}`,
+			Assert: func(c *PortalConfig) bool {
+				limits := c.Limits()
+				return c.IsEnabled() && c.GetSessionCookie() == "admin_session" && len(limits) == 2 &&
+					limits[0].CounterKey() == PortalClientIPKey && limits[1].CounterKey() == PortalSessionKey
+			},
+		},
+		{
+			Name:   "Disabled without limits",
+			Config: `{"enabled": false}`,
+			Assert: func(c *PortalConfig) bool { return !c.IsEnabled() },
+		},
+		{
+			Name:    "Enabled without limits",
+			Config:  `{"enabled": true}`,
+			WantErr: true,
+		},
+		{
+			Name:    "Unsupported unit",
+			Config:  `{"enabled": true, "per_client_ip": {"unit": "week", "requests_per_unit": 300}}`,
+			WantErr: true,
+		},
+		{
+			Name:    "No requests per unit",
+			Config:  `{"enabled": true, "per_session": {"unit": "minute", "requests_per_unit": 0}}`,
+			WantErr: true,
+		},
+		{
+			Name:    "Session cookie to escape",
+			Config:  `{"enabled": true, "per_session": {"unit": "minute", "requests_per_unit": 60}, "session_cookie": "user-session"}`,
+			WantErr: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			client := utils.NewTestClient(scheme, &corev1.ConfigMap{
+				ObjectMeta: v1.ObjectMeta{Name: PortalConfigMapName, Namespace: "redhat-test-operator"},
+				Data:       map[string]string{PortalConfigKey: scenario.Config},
+			})
+			portalConfig, err := GetPortalConfig(context.TODO(), client, "redhat-test-operator")
+			if (err != nil) != scenario.WantErr {
+				t.Fatalf("expected error %v, got %v", scenario.WantErr, err)
+			}
+			if scenario.Assert != nil && !scenario.Assert(portalConfig) {
+				t.Errorf("unexpected portal config %v", portalConfig)
+			}
+		})
+	}
+}
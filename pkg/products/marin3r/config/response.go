@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ResponseConfigMapName holds the rate limit response configuration under the ResponseConfigKey key
+	ResponseConfigMapName = "rate-limit-response"
+	ResponseConfigKey     = "response"
+
+	// DefaultRejectionTemplate is the body of the rejected requests when no template is configured
+	DefaultRejectionTemplate = `{"code": 429, "error": "Too Many Requests", "message": "The API rate limit has been exceeded", "retry_after": "%RESP(X-RATELIMIT-RESET)%"}`
+)
+
+// commandOperatorRegexp matches the envoy command operators of a template, e.g. %RESP(X-RATELIMIT-RESET)%
+var commandOperatorRegexp = regexp.MustCompile(`%[A-Z_]+(\([^)]*\))?(:[0-9]+)?%`)
+
+// ResponseConfig enables the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// Retry-After headers in the API responses, and a JSON body in the rejected requests
+type ResponseConfig struct {
+	Enabled bool `json:"enabled"`
+	// Template is the JSON body of the rejected requests, envoy command operators
+	// such as %RESP(X-RATELIMIT-RESET)% are substituted
+	Template string `json:"template,omitempty"`
+}
+
+// GetResponseConfig returns the rate limit response configuration of the namespace, nil is
+// returned when the ResponseConfigMapName ConfigMap does not exist
+func GetResponseConfig(ctx context.Context, client k8sclient.Client, namespace string) (*ResponseConfig, error) {
+	responseConfig := &ResponseConfig{}
+	err := getFromJSONConfigMap(
+		ctx, client,
+		ResponseConfigMapName, namespace, ResponseConfigKey,
+		responseConfig,
+	)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := ValidateRejectionTemplate(responseConfig.Template); err != nil {
+		return nil, fmt.Errorf("invalid %s ConfigMap: %w", ResponseConfigMapName, err)
+	}
+	return responseConfig, nil
+}
+
+// IsEnabled returns whether the rate limit headers and rejection body are enabled
+func (c *ResponseConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetTemplate returns the rejection body template, defaulting to DefaultRejectionTemplate
+func (c *ResponseConfig) GetTemplate() string {
+	if c == nil || c.Template == "" {
+		return DefaultRejectionTemplate
+	}
+	return c.Template
+}
+
+// ValidateRejectionTemplate checks the template is a JSON document once its command operators
+// are substituted
+func ValidateRejectionTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	substituted := commandOperatorRegexp.ReplaceAllString(template, "0")
+	if !json.Valid([]byte(substituted)) {
+		return fmt.Errorf("rejection template must be a JSON document: %s", template)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetResponseConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responseConfig, err := GetResponseConfig(context.TODO(), utils.NewTestClient(scheme), "redhat-test-operator")
+	if err != nil || responseConfig.IsEnabled() {
+		t.Fatalf("expected the rate limit response to be disabled without the ConfigMap, got %v %v", responseConfig, err)
+	}
+	if responseConfig.GetTemplate() != DefaultRejectionTemplate {
+		t.Errorf("expected the default template, got %s", responseConfig.GetTemplate())
+	}
+
+	client := utils.NewTestClient(scheme, &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: ResponseConfigMapName, Namespace: "redhat-test-operator"},
+		Data: map[string]string{
+			ResponseConfigKey: `{"enabled": true, "template": "{\"message\": \"slow down\", \"retry_after\": %RESP(X-RATELIMIT-RESET)%}"}`,
+		},
+	})
+	responseConfig, err = GetResponseConfig(context.TODO(), client, "redhat-test-operator")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !responseConfig.IsEnabled() || responseConfig.GetTemplate() != `{"message": "slow down", "retry_after": %RESP(X-RATELIMIT-RESET)%}` {
+		t.Errorf("unexpected response config %v", responseConfig)
+	}
+}
+
+func TestValidateRejectionTemplate(t *testing.T) {
+	scenarios := []struct {
+		Name     string
+		Template string
+		WantErr  bool
+	}{
+		{Name: "No template"},
+		{Name: "Default template", Template: DefaultRejectionTemplate},
+		{Name: "Command operator as a value", Template: `{"code": %RESPONSE_CODE%, "reset": %RESP(X-RATELIMIT-RESET):10%}`},
+		{Name: "Not JSON", Template: "Too Many Requests", WantErr: true},
+		{Name: "Truncated JSON", Template: `{"error": "Too Many Requests"`, WantErr: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			if err := ValidateRejectionTemplate(scenario.Template); (err != nil) != scenario.WantErr {
+				t.Errorf("ValidateRejectionTemplate() error = %v, wantErr %v", err, scenario.WantErr)
+			}
+		})
+	}
+}
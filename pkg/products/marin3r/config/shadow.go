@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ShadowConfigMapName holds the shadow mode configuration under the ShadowConfigKey key
+	ShadowConfigMapName = "rate-limit-shadow"
+	ShadowConfigKey     = "shadow"
+)
+
+// ShadowConfig enables the shadow mode of the rate limit. In shadow mode a new rate limit
+// from a quota change is evaluated without being enforced, and the previous rate limit is
+// enforced until the new one is promoted
+type ShadowConfig struct {
+	Enabled bool `json:"enabled"`
+	// Promoted enforces the shadow rate limit equal to it
+	Promoted *RateLimitConfig `json:"promoted,omitempty"`
+}
+
+// GetShadowConfig returns the shadow mode configuration of the namespace, nil is returned
+// when the ShadowConfigMapName ConfigMap does not exist
+func GetShadowConfig(ctx context.Context, client k8sclient.Client, namespace string) (*ShadowConfig, error) {
+	shadowConfig := &ShadowConfig{}
+	err := getFromJSONConfigMap(
+		ctx, client,
+		ShadowConfigMapName, namespace, ShadowConfigKey,
+		shadowConfig,
+	)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if shadowConfig.Promoted != nil {
+		if _, ok := conversionFactors[shadowConfig.Promoted.Unit]; !ok {
+			return nil, fmt.Errorf("invalid %s ConfigMap: unsupported unit %q", ShadowConfigMapName, shadowConfig.Promoted.Unit)
+		}
+	}
+	return shadowConfig, nil
+}
+
+// IsEnabled returns whether the shadow mode is enabled
+func (s *ShadowConfig) IsEnabled() bool {
+	return s != nil && s.Enabled
+}
+
+// ResolveRateLimits returns the rate limit to enforce and the rate limit to evaluate in
+// shadow mode, if any, given the rate limit of the quota and the currently enforced one.
+// The quota rate limit is enforced straight away when the shadow mode is disabled, no
+// rate limit is enforced yet, or it has been promoted
+func (s *ShadowConfig) ResolveRateLimits(quotaLimit RateLimitConfig, enforcedLimit *RateLimitConfig) (RateLimitConfig, *RateLimitConfig) {
+	if !s.IsEnabled() || enforcedLimit == nil || *enforcedLimit == quotaLimit {
+		return quotaLimit, nil
+	}
+	if s.Promoted != nil && *s.Promoted == quotaLimit {
+		return quotaLimit, nil
+	}
+
+	return *enforcedLimit, &quotaLimit
+}
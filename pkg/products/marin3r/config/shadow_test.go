@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetShadowConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shadowConfig, err := GetShadowConfig(context.TODO(), utils.NewTestClient(scheme), "redhat-test-operator")
+	if err != nil || shadowConfig != nil || shadowConfig.IsEnabled() {
+		t.Fatalf("expected the shadow mode to be disabled without the ConfigMap, got %v %v", shadowConfig, err)
+	}
+
+	client := utils.NewTestClient(scheme, &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: ShadowConfigMapName, Namespace: "redhat-test-operator"},
+		Data: map[string]string{
+			ShadowConfigKey: `{"enabled": true, "promoted": {"unit": "minute", "requests_per_unit": 20000}}`,
+		},
+	})
+	shadowConfig, err = GetShadowConfig(context.TODO(), client, "redhat-test-operator")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !shadowConfig.IsEnabled() || shadowConfig.Promoted == nil || shadowConfig.Promoted.RequestsPerUnit != 20000 {
+		t.Errorf("unexpected shadow config %v", shadowConfig)
+	}
+
+	client = utils.NewTestClient(scheme, &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: ShadowConfigMapName, Namespace: "redhat-test-operator"},
+		Data: map[string]string{
+			ShadowConfigKey: `{"enabled": true, "promoted": {"unit": "week", "requests_per_unit": 20000}}`,
+		},
+	})
+	if _, err = GetShadowConfig(context.TODO(), client, "redhat-test-operator"); err == nil {
+		t.Errorf("expected an error for an unsupported unit")
+	}
+}
+
+func TestResolveRateLimits(t *testing.T) {
+	quotaLimit := RateLimitConfig{Unit: Minute, RequestsPerUnit: 20000}
+	enforcedLimit := &RateLimitConfig{Unit: Minute, RequestsPerUnit: 13860}
+
+	scenarios := []struct {
+		Name          string
+		ShadowConfig  *ShadowConfig
+		EnforcedLimit *RateLimitConfig
+		WantEnforced  RateLimitConfig
+		WantShadow    *RateLimitConfig
+	}{
+		{
+			Name:          "Shadow mode not configured",
+			EnforcedLimit: enforcedLimit,
+			WantEnforced:  quotaLimit,
+		},
+		{
+			Name:          "Shadow mode disabled",
+			ShadowConfig:  &ShadowConfig{},
+			EnforcedLimit: enforcedLimit,
+			WantEnforced:  quotaLimit,
+		},
+		{
+			Name:         "No rate limit enforced yet",
+			ShadowConfig: &ShadowConfig{Enabled: true},
+			WantEnforced: quotaLimit,
+		},
+		{
+			Name:          "Quota unchanged",
+			ShadowConfig:  &ShadowConfig{Enabled: true},
+			EnforcedLimit: &quotaLimit,
+			WantEnforced:  quotaLimit,
+		},
+		{
+			Name:          "Quota changed",
+			ShadowConfig:  &ShadowConfig{Enabled: true},
+			EnforcedLimit: enforcedLimit,
+			WantEnforced:  *enforcedLimit,
+			WantShadow:    &quotaLimit,
+		},
+		{
+			Name:          "Previous rate limit promoted",
+			ShadowConfig:  &ShadowConfig{Enabled: true, Promoted: &RateLimitConfig{Unit: Minute, RequestsPerUnit: 13860}},
+			EnforcedLimit: enforcedLimit,
+			WantEnforced:  *enforcedLimit,
+			WantShadow:    &quotaLimit,
+		},
+		{
+			Name:          "Shadow rate limit promoted",
+			ShadowConfig:  &ShadowConfig{Enabled: true, Promoted: &RateLimitConfig{Unit: Minute, RequestsPerUnit: 20000}},
+			EnforcedLimit: enforcedLimit,
+			WantEnforced:  quotaLimit,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			enforced, shadow := scenario.ShadowConfig.ResolveRateLimits(quotaLimit, scenario.EnforcedLimit)
+			if enforced != scenario.WantEnforced {
+				t.Errorf("expected %v to be enforced, got %v", scenario.WantEnforced, enforced)
+			}
+			if (shadow == nil) != (scenario.WantShadow == nil) || (shadow != nil && *shadow != *scenario.WantShadow) {
+				t.Errorf("expected %v to be shadowed, got %v", scenario.WantShadow, shadow)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+)
+
+// RateLimitStorageMode is where Limitador keeps the counters of the limits
+type RateLimitStorageMode string
+
+// RedisTopology is how the Redis of the redis storage modes is provisioned
+type RedisTopology string
+
+const (
+	// StorageModeRedis increments the counters in Redis on every request
+	StorageModeRedis RateLimitStorageMode = "redis"
+	// StorageModeRedisCached keeps the counters in memory and flushes them to Redis
+	// periodically, trading accuracy across replicas for latency
+	StorageModeRedisCached RateLimitStorageMode = "redis_cached"
+	// StorageModeInMemory keeps the counters in memory only, they are lost on restarts. The
+	// limits would be enforced per replica so the rate limit service runs a single replica
+	StorageModeInMemory RateLimitStorageMode = "in_memory"
+
+	// RedisTopologyStandalone uses the Redis provisioned by the cloud resource operator
+	RedisTopologyStandalone RedisTopology = "standalone"
+	// RedisTopologySentinel uses the primary of a Redis replication group monitored by Sentinel,
+	// the Sentinels are set in the RedisSentinelSecretName Secret. Limitador increments the
+	// counters on every hit so it can not read from the replicas, they provide the failover
+	RedisTopologySentinel RedisTopology = "sentinel"
+
+	// RedisSentinelSecretName holds the comma separated host:port addresses of the Sentinels
+	// under the RedisSentinelAddressesKey key, the name of the monitored primary under the
+	// RedisSentinelMasterNameKey key and optionally the password of both under the
+	// RedisSentinelPasswordKey key
+	RedisSentinelSecretName    = "ratelimit-redis-sentinel"
+	RedisSentinelAddressesKey  = "SENTINELS"
+	RedisSentinelMasterNameKey = "MASTER_NAME"
+	RedisSentinelPasswordKey   = "PASSWORD"
+)
+
+// RateLimitStorageConfig is the storage of the rate limit counters of a quota, the counters
+// are stored in the standalone Redis when not set
+type RateLimitStorageConfig struct {
+	Mode     RateLimitStorageMode `json:"mode,omitempty"`
+	Topology RedisTopology        `json:"topology,omitempty"`
+	// Size is the size of the standalone Redis, defaulting to the cloud resource operator's
+	Size string `json:"size,omitempty"`
+	// Cache tunes the StorageModeRedisCached mode, Limitador's defaults are used when not set
+	Cache *RateLimitCacheConfig `json:"cache,omitempty"`
+}
+
+type RateLimitCacheConfig struct {
+	FlushingPeriodMs       int64 `json:"flushing_period_ms,omitempty"`
+	MaxTTLCachedCountersMs int64 `json:"max_ttl_cached_counters_ms,omitempty"`
+	TTLRatioCachedCounters int64 `json:"ttl_ratio_cached_counters,omitempty"`
+}
+
+// GetMode returns the storage mode, defaulting to StorageModeRedis
+func (c RateLimitStorageConfig) GetMode() RateLimitStorageMode {
+	if c.Mode == "" {
+		return StorageModeRedis
+	}
+	return c.Mode
+}
+
+// GetTopology returns the Redis topology, defaulting to RedisTopologyStandalone
+func (c RateLimitStorageConfig) GetTopology() RedisTopology {
+	if c.Topology == "" {
+		return RedisTopologyStandalone
+	}
+	return c.Topology
+}
+
+// UsesRedis returns whether the counters are stored in Redis
+func (c RateLimitStorageConfig) UsesRedis() bool {
+	return c.GetMode() != StorageModeInMemory
+}
+
+// UsesStandaloneRedis returns whether the counters are stored in the Redis provisioned by the
+// cloud resource operator
+func (c RateLimitStorageConfig) UsesStandaloneRedis() bool {
+	return c.UsesRedis() && c.GetTopology() == RedisTopologyStandalone
+}
+
+// Validate checks the storage against the number of replicas of the rate limit service sized by
+// the quota, which must be at most one with the StorageModeInMemory mode
+func (c RateLimitStorageConfig) Validate(replicas int32) error {
+	switch c.GetMode() {
+	case StorageModeRedis, StorageModeRedisCached, StorageModeInMemory:
+	default:
+		return fmt.Errorf("unknown rate limit storage mode %s", c.Mode)
+	}
+	switch c.GetTopology() {
+	case RedisTopologyStandalone, RedisTopologySentinel:
+	default:
+		return fmt.Errorf("unknown rate limit redis topology %s", c.Topology)
+	}
+	if c.Cache != nil && c.GetMode() != StorageModeRedisCached {
+		return fmt.Errorf("the rate limit cache is only supported by the %s storage mode", StorageModeRedisCached)
+	}
+	if c.GetMode() == StorageModeInMemory && replicas > 1 {
+		return fmt.Errorf("the %s storage mode requires a single rate limit replica, got %d", StorageModeInMemory, replicas)
+	}
+	return nil
+}
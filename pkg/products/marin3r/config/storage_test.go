@@ -0,0 +1,64 @@
+package config
+
+import "testing"
+
+func TestRateLimitStorageConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         RateLimitStorageConfig
+		replicas       int32
+		wantErr        bool
+		wantRedis      bool
+		wantStandalone bool
+	}{
+		{
+			name:           "defaults to the standalone redis",
+			wantRedis:      true,
+			wantStandalone: true,
+		},
+		{
+			name:      "redis cached with sentinel",
+			config:    RateLimitStorageConfig{Mode: StorageModeRedisCached, Topology: RedisTopologySentinel, Cache: &RateLimitCacheConfig{FlushingPeriodMs: 100}},
+			wantRedis: true,
+		},
+		{
+			name:     "in memory",
+			config:   RateLimitStorageConfig{Mode: StorageModeInMemory},
+			replicas: 1,
+		},
+		{
+			name:     "in memory with multiple replicas",
+			config:   RateLimitStorageConfig{Mode: StorageModeInMemory},
+			replicas: 3,
+			wantErr:  true,
+		},
+		{
+			name:    "unknown mode",
+			config:  RateLimitStorageConfig{Mode: "disk"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown topology",
+			config:  RateLimitStorageConfig{Topology: "cluster"},
+			wantErr: true,
+		},
+		{
+			name:    "cache without the redis cached mode",
+			config:  RateLimitStorageConfig{Cache: &RateLimitCacheConfig{FlushingPeriodMs: 100}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(tt.replicas); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.config.UsesRedis() != tt.wantRedis || tt.config.UsesStandaloneRedis() != tt.wantStandalone {
+				t.Errorf("unexpected redis usage for %v", tt.config)
+			}
+		})
+	}
+}
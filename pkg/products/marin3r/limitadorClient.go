@@ -1,68 +1,102 @@
 package marin3r
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
+// LimitadorClientInterface reads and deletes the limits and counters of a Limitador namespace
+// through the Limitador HTTP API
 type LimitadorClientInterface interface {
-	GetLimitsByName(string) ([]limitadorLimit, error)
-	DeleteLimitsByNameUsingPod(string, string) error
+	GetLimits(ctx context.Context, namespace string) ([]limitadorLimit, error)
+	DeleteLimits(ctx context.Context, namespace string) error
+	GetCounters(ctx context.Context, namespace string) ([]limitadorCounter, error)
 }
 
 type LimitadorClient struct {
-	PodExecutor resources.PodExecutorInterface
-	PodName     string
-	Namespace   string
+	// URL of the Limitador HTTP API, e.g. http://ratelimit:8080
+	URL        string
+	HTTPClient *http.Client
 }
 
 var _ LimitadorClientInterface = &LimitadorClient{}
 
-func NewLimitadorClient(podExecutor resources.PodExecutorInterface, nameSpace, podName string) *LimitadorClient {
+// limitadorCounter is the state of a limit for a set of descriptor values
+type limitadorCounter struct {
+	Limit            limitadorLimit    `json:"limit"`
+	SetVariables     map[string]string `json:"set_variables"`
+	Remaining        int64             `json:"remaining"`
+	ExpiresInSeconds int64             `json:"expires_in_seconds"`
+}
+
+func NewLimitadorClient(limitadorURL string) *LimitadorClient {
 	return &LimitadorClient{
-		PodExecutor: podExecutor,
-		Namespace:   nameSpace,
-		PodName:     podName,
+		URL: strings.TrimSuffix(limitadorURL, "/"),
+		HTTPClient: &http.Client{
+			Transport: &http.Transport{
+				DisableKeepAlives: true,
+				IdleConnTimeout:   10 * time.Second,
+			},
+			Timeout: 10 * time.Second,
+		},
 	}
 }
 
-func (l LimitadorClient) GetLimitsByName(limitName string) ([]limitadorLimit, error) {
-	response, _, err := l.PodExecutor.ExecuteRemoteCommand(l.Namespace, l.PodName, []string{"/bin/sh",
-		"-c", fmt.Sprintf("wget -qO - http://127.0.0.1:8080/limits/%s", limitName)})
-	if err != nil {
+// GetLimits returns the limits of the Limitador namespace
+func (l *LimitadorClient) GetLimits(ctx context.Context, namespace string) ([]limitadorLimit, error) {
+	limits := []limitadorLimit{}
+	if err := l.do(ctx, http.MethodGet, "limits", namespace, &limits); err != nil {
 		return nil, err
 	}
+	return limits, nil
+}
 
-	limitadorLimitsInRedis := []limitadorLimit{}
-	err = json.Unmarshal([]byte(response), &limitadorLimitsInRedis)
-	if err != nil {
+// DeleteLimits deletes the limits of the Limitador namespace along with their counters
+func (l *LimitadorClient) DeleteLimits(ctx context.Context, namespace string) error {
+	return l.do(ctx, http.MethodDelete, "limits", namespace, nil)
+}
+
+// GetCounters returns the counters of the limits of the Limitador namespace
+func (l *LimitadorClient) GetCounters(ctx context.Context, namespace string) ([]limitadorCounter, error) {
+	counters := []limitadorCounter{}
+	if err := l.do(ctx, http.MethodGet, "counters", namespace, &counters); err != nil {
 		return nil, err
 	}
-
-	return limitadorLimitsInRedis, nil
+	return counters, nil
 }
 
-func (l LimitadorClient) DeleteLimitsByNameUsingPod(limitName, rateLimitPodIP string) error {
-	client := &http.Client{
-		Transport: &http.Transport{
-			DisableKeepAlives: true,
-			IdleConnTimeout:   10 * time.Second,
-		},
-		Timeout: 10 * time.Second,
-	}
-	url := fmt.Sprintf("http://%s:8080/limits/%s", rateLimitPodIP, limitName)
-	req, err := http.NewRequest("DELETE", url, nil)
+func (l *LimitadorClient) do(ctx context.Context, method, resource, namespace string, v interface{}) error {
+	endpoint := fmt.Sprintf("%s/%s/%s", l.URL, resource, url.PathEscape(namespace))
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
 	if err != nil {
 		return err
 	}
 
-	_, err = client.Do(req)
+	resp, err := l.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to %s %s: %w", method, endpoint, err)
 	}
+	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response of %s %s: %w", method, endpoint, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned status %d: %s", method, endpoint, resp.StatusCode, string(body))
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response of %s %s: %w", method, endpoint, err)
+	}
 	return nil
 }
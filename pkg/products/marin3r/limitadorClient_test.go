@@ -0,0 +1,146 @@
+package marin3r
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
+)
+
+// fakeLimitador serves the limits and counters endpoints of the Limitador HTTP API
+type fakeLimitador struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	limits   map[string][]limitadorLimit
+	counters map[string][]limitadorCounter
+	// deleted holds the namespaces whose limits were deleted
+	deleted []string
+	// status is returned instead of the resources when set
+	status int
+	// rawResponse is returned instead of the resources when set
+	rawResponse string
+	// deleteStatus is returned by the delete requests when set
+	deleteStatus int
+}
+
+func newFakeLimitador(t *testing.T, limits map[string][]limitadorLimit) *fakeLimitador {
+	fake := &fakeLimitador{
+		limits:   limits,
+		counters: map[string][]limitadorCounter{},
+	}
+	if fake.limits == nil {
+		fake.limits = map[string][]limitadorLimit{}
+	}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.serveHTTP))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (f *fakeLimitador) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	if f.rawResponse != "" {
+		_, _ = w.Write([]byte(f.rawResponse))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resource, namespace := parts[0], parts[1]
+
+	var response interface{}
+	switch {
+	case resource == "limits" && req.Method == http.MethodGet:
+		response = f.limits[namespace]
+	case resource == "limits" && req.Method == http.MethodDelete:
+		if f.deleteStatus != 0 {
+			w.WriteHeader(f.deleteStatus)
+			return
+		}
+		delete(f.limits, namespace)
+		f.deleted = append(f.deleted, namespace)
+		return
+	case resource == "counters" && req.Method == http.MethodGet:
+		response = f.counters[namespace]
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Limitador returns an empty list for unknown namespaces
+	if reflect.ValueOf(response).IsNil() {
+		response = []interface{}{}
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeLimitador) deletedNamespaces() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deleted
+}
+
+func TestLimitadorClient(t *testing.T) {
+	limit := limitadorLimit{
+		Namespace:  ratelimit.RateLimitDomain,
+		MaxValue:   10,
+		Seconds:    60,
+		Conditions: []string{"generic_key == slowpath"},
+		Variables:  []string{"generic_key"},
+	}
+	fake := newFakeLimitador(t, map[string][]limitadorLimit{ratelimit.RateLimitDomain: {limit}})
+	fake.counters[ratelimit.RateLimitDomain] = []limitadorCounter{{
+		Limit:            limit,
+		SetVariables:     map[string]string{"generic_key": "slowpath"},
+		Remaining:        4,
+		ExpiresInSeconds: 30,
+	}}
+	client := NewLimitadorClient(fake.URL + "/")
+
+	limits, err := client.GetLimits(context.TODO(), ratelimit.RateLimitDomain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(limits, []limitadorLimit{limit}) {
+		t.Errorf("unexpected limits %v", limits)
+	}
+
+	counters, err := client.GetCounters(context.TODO(), ratelimit.RateLimitDomain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(counters) != 1 || counters[0].Remaining != 4 || counters[0].Limit.MaxValue != 10 {
+		t.Errorf("unexpected counters %v", counters)
+	}
+
+	if err := client.DeleteLimits(context.TODO(), ratelimit.RateLimitDomain); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	limits, err = client.GetLimits(context.TODO(), ratelimit.RateLimitDomain)
+	if err != nil || len(limits) != 0 {
+		t.Errorf("expected the limits to be deleted, got %v %v", limits, err)
+	}
+
+	fake.status = http.StatusInternalServerError
+	if _, err := client.GetLimits(context.TODO(), ratelimit.RateLimitDomain); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected an error with the response status, got %v", err)
+	}
+	if err := client.DeleteLimits(context.TODO(), ratelimit.RateLimitDomain); err == nil {
+		t.Errorf("expected an error deleting the limits")
+	}
+}
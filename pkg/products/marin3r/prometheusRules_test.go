@@ -0,0 +1,84 @@
+package marin3r
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/alerttest"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/marketplace"
+)
+
+func TestAlertRules(t *testing.T) {
+	installation := getBasicInstallation()
+	installation.Spec.Type = string(integreatlyv1alpha1.InstallationTypeManagedApi)
+
+	r, err := NewReconciler(getBasicConfig(), installation, &marketplace.MarketplaceInterfaceMock{}, setupRecorder(), getLogger(), localProductDeclaration)
+	if err != nil {
+		t.Fatalf("unexpected error creating reconciler: %v", err)
+	}
+	alertReconciler, err := r.newAlertReconciler(getLogger(), installation.Spec.Type, config.GetOboNamespace(installation.Namespace))
+	if err != nil {
+		t.Fatalf("unexpected error creating alert reconciler: %v", err)
+	}
+
+	productNamespace := r.Config.GetNamespace()
+	operatorNamespace := r.Config.GetOperatorNamespace()
+
+	alerttest.RunAlertRuleTests(t, alertReconciler, []alerttest.Test{
+		{
+			Name: "rate limit endpoint down",
+			InputSeries: []alerttest.Series{
+				{
+					Series: fmt.Sprintf(`kube_endpoint_address_available{endpoint="ratelimit", namespace=%q}`, productNamespace),
+					Values: "1 1 0+0x10",
+				},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{EvalTime: 6 * time.Minute, AlertName: "Marin3rRateLimitServiceEndpointDown"},
+				{
+					EvalTime:  7 * time.Minute,
+					AlertName: "Marin3rRateLimitServiceEndpointDown",
+					ExpAlerts: []alerttest.Alert{{
+						Labels: map[string]string{
+							"severity":  "warning",
+							"product":   "rhoam",
+							"endpoint":  "ratelimit",
+							"namespace": productNamespace,
+						},
+						Annotations: map[string]string{
+							"message": fmt.Sprintf("No ratelimit endpoints in namespace %s. Expected at least 1.", productNamespace),
+						},
+					}},
+				},
+			},
+		},
+		{
+			Name: "operator pod not ready",
+			InputSeries: []alerttest.Series{
+				{
+					Series: fmt.Sprintf(`kube_pod_status_ready{condition="true", namespace=%q, pod="marin3r-controller-manager-1"}`, operatorNamespace),
+					Values: "0+0x10",
+				},
+				{
+					Series: fmt.Sprintf(`kube_pod_status_phase{phase="Running", namespace=%q, pod="marin3r-controller-manager-1"}`, operatorNamespace),
+					Values: "1+0x10",
+				},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{EvalTime: 4 * time.Minute, AlertName: "Marin3rOperatorPod"},
+				{
+					EvalTime:  5 * time.Minute,
+					AlertName: "Marin3rOperatorPod",
+					ExpAlerts: []alerttest.Alert{{
+						Labels:      map[string]string{"severity": "warning", "product": "rhoam"},
+						Annotations: map[string]string{"message": "Marin3r operator has no pods in ready state."},
+					}},
+				},
+				{EvalTime: 5 * time.Minute, AlertName: "Marin3rWebhookPod"},
+			},
+		},
+	})
+}
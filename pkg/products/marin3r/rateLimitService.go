@@ -8,6 +8,7 @@ import (
 	"github.com/integr8ly/integreatly-operator/pkg/config"
 	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
 	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
 	"gopkg.in/yaml.v2"
@@ -17,11 +18,13 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	k8spointer "k8s.io/utils/pointer"
 	"reflect"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -30,12 +33,24 @@ const (
 	headerKey                     = "tenant"
 	mtUnit                        = "minute"
 	possibleTenants               = 200
-	multitenantLimitConfigMap     = "multitenant-config"
-	multitenantRateLimit          = "mulitenantLimit"
+	multitenantLimitConfigMap     = marin3rconfig.MultitenantLimitConfigMapName
+	multitenantRateLimit          = marin3rconfig.MultitenantLimitKey
 	multitenantDescriptorValue    = "per-mt-limit"
 	RateLimitingConfigMapName     = "ratelimit-config"
 	RateLimitingConfigMapDataName = "apicast-ratelimiting.yaml"
 	rateLimitImage                = "quay.io/3scale/limitador:v0.5.1"
+	// rateLimitHeadersEnv makes Limitador return the limit, remaining requests and reset of the
+	// descriptors, the envoy sidecars build the X-RateLimit-* headers from them
+	rateLimitHeadersEnv     = "RATE_LIMIT_HEADERS"
+	rateLimitHeadersVersion = "DRAFT_VERSION_03"
+	// limitNameInPrometheusLabelsEnv adds the name of the limit to the limited_calls metric of
+	// Limitador, the shadow limits are named after their descriptor
+	limitNameInPrometheusLabelsEnv = "LIMIT_NAME_IN_PROMETHEUS_LABELS"
+	// shadowGlobalLimitName and shadowTenantLimitName name the shadow limits of the global and
+	// per tenant descriptors, the shadow limits of the descriptor rules are named after the rule
+	shadowGlobalLimitName = "global"
+	shadowTenantLimitName = "tenant"
+	redisURLEnv           = "REDIS_URL"
 )
 
 type RateLimitServiceReconciler struct {
@@ -43,23 +58,30 @@ type RateLimitServiceReconciler struct {
 	RedisSecretName string
 	Installation    *integreatlyv1alpha1.RHMI
 	RateLimitConfig marin3rconfig.RateLimitConfig
-	PodExecutor     resources.PodExecutorInterface
-	ConfigManager   config.ConfigReadWriter
+	// ShadowRateLimitConfig is evaluated in the RateLimitShadowDomain namespace without being enforced
+	ShadowRateLimitConfig *marin3rconfig.RateLimitConfig
+	// StorageConfig is the storage of the counters, Redis when not set
+	StorageConfig marin3rconfig.RateLimitStorageConfig
+	// ResponseConfig enables the rate limit headers of the API responses
+	ResponseConfig *marin3rconfig.ResponseConfig
+	// LimitadorURL is the URL of the Limitador HTTP API, defaulting to the http port of the rate limit service
+	LimitadorURL  string
+	ConfigManager config.ConfigReadWriter
 }
 
-func NewRateLimitServiceReconciler(config marin3rconfig.RateLimitConfig, installation *integreatlyv1alpha1.RHMI, namespace, redisSecretName string, podExecutor resources.PodExecutorInterface, configManager config.ConfigReadWriter) *RateLimitServiceReconciler {
+func NewRateLimitServiceReconciler(config marin3rconfig.RateLimitConfig, installation *integreatlyv1alpha1.RHMI, namespace, redisSecretName string, configManager config.ConfigReadWriter) *RateLimitServiceReconciler {
 	return &RateLimitServiceReconciler{
 		RateLimitConfig: config,
 		Installation:    installation,
 		Namespace:       namespace,
 		RedisSecretName: redisSecretName,
-		PodExecutor:     podExecutor,
 		ConfigManager:   configManager,
 	}
 }
 
 type limitadorLimit struct {
 	Namespace  string   `yaml:"namespace" json:"namespace"`
+	Name       string   `yaml:"name,omitempty" json:"name,omitempty"`
 	MaxValue   uint32   `yaml:"max_value" json:"max_value"`
 	Seconds    uint64   `yaml:"seconds" json:"seconds"`
 	Conditions []string `yaml:"conditions" json:"conditions"`
@@ -131,13 +153,17 @@ func (r *RateLimitServiceReconciler) reconcileConfigMap(ctx context.Context, cli
 func (r *RateLimitServiceReconciler) reconcileDeployment(ctx context.Context, client k8sclient.Client, productConfig quota.ProductConfig) (integreatlyv1alpha1.StatusPhase, error) {
 	currentRateLimit := ""
 
-	redisSecret, err := r.getRedisSecret(ctx, client)
-	if err != nil {
-		if k8sError.IsNotFound(err) {
-			return integreatlyv1alpha1.PhaseAwaitingComponents, nil
-		} else {
-			return integreatlyv1alpha1.PhaseFailed, err
+	storageEnvs := []corev1.EnvVar{}
+	if r.StorageConfig.UsesRedis() {
+		redisSecret, err := r.getRedisSecret(ctx, client)
+		if err != nil {
+			if k8sError.IsNotFound(err) {
+				return integreatlyv1alpha1.PhaseAwaitingComponents, nil
+			} else {
+				return integreatlyv1alpha1.PhaseFailed, err
+			}
 		}
+		storageEnvs = getStorageEnvs(r.StorageConfig, string(redisSecret.Data["URL"]))
 	}
 
 	deployment := &appsv1.Deployment{
@@ -148,7 +174,7 @@ func (r *RateLimitServiceReconciler) reconcileDeployment(ctx context.Context, cl
 	}
 
 	key := k8sclient.ObjectKeyFromObject(deployment)
-	err = client.Get(ctx, key, deployment)
+	err := client.Get(ctx, key, deployment)
 	if err != nil {
 		if !k8sError.IsNotFound(err) {
 			return integreatlyv1alpha1.PhaseFailed, err
@@ -186,15 +212,18 @@ func (r *RateLimitServiceReconciler) reconcileDeployment(ctx context.Context, cl
 				Name:  "RUST_LOG",
 				Value: "info",
 			},
-			{
-				Name:  "REDIS_URL",
-				Value: fmt.Sprintf("redis://%s", string(redisSecret.Data["URL"])),
-			},
 			{
 				Name:  "LIMITS_FILE",
 				Value: fmt.Sprintf("/srv/runtime_data/current/config/%s", limitsFile),
 			},
 		}
+		envs = append(envs, storageEnvs...)
+		if r.ResponseConfig.IsEnabled() {
+			envs = append(envs, corev1.EnvVar{Name: rateLimitHeadersEnv, Value: rateLimitHeadersVersion})
+		}
+		if r.ShadowRateLimitConfig != nil {
+			envs = append(envs, corev1.EnvVar{Name: limitNameInPrometheusLabelsEnv, Value: "true"})
+		}
 
 		deployment.Spec.Template.ObjectMeta = v1.ObjectMeta{
 			Labels: map[string]string{
@@ -293,6 +322,10 @@ func (r *RateLimitServiceReconciler) reconcileDeployment(ctx context.Context, cl
 		if err != nil {
 			return err
 		}
+		// in memory counters are per replica, more replicas would multiply the limits
+		if !r.StorageConfig.UsesRedis() {
+			deployment.Spec.Replicas = k8spointer.Int32(1)
+		}
 
 		return nil
 	})
@@ -345,6 +378,39 @@ func (r *RateLimitServiceReconciler) reconcileService(ctx context.Context, clien
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
+// getStorageEnvs returns the environment variables selecting the Redis storage of Limitador,
+// which keeps the counters in memory without them
+func getStorageEnvs(storageConfig marin3rconfig.RateLimitStorageConfig, redisURL string) []corev1.EnvVar {
+	envs := []corev1.EnvVar{
+		{
+			Name:  redisURLEnv,
+			Value: getRedisURLEnvValue(redisURL),
+		},
+	}
+	if storageConfig.GetMode() != marin3rconfig.StorageModeRedisCached {
+		return envs
+	}
+
+	envs = append(envs, corev1.EnvVar{Name: "REDIS_LOCAL_CACHE_ENABLED", Value: "true"})
+	if cache := storageConfig.Cache; cache != nil {
+		for name, value := range map[string]int64{
+			"REDIS_LOCAL_CACHE_FLUSHING_PERIOD_MS":         cache.FlushingPeriodMs,
+			"REDIS_LOCAL_CACHE_MAX_TTL_CACHED_COUNTERS_MS": cache.MaxTTLCachedCountersMs,
+			"REDIS_LOCAL_CACHE_TTL_RATIO_CACHED_COUNTERS":  cache.TTLRatioCachedCounters,
+		} {
+			if value > 0 {
+				envs = append(envs, corev1.EnvVar{Name: name, Value: strconv.FormatInt(value, 10)})
+			}
+		}
+		sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	}
+	return envs
+}
+
+func getRedisURLEnvValue(redisURL string) string {
+	return fmt.Sprintf("redis://%s", redisURL)
+}
+
 func (r *RateLimitServiceReconciler) getRedisSecret(ctx context.Context, client k8sclient.Client) (*corev1.Secret, error) {
 	secret := &corev1.Secret{}
 	err := client.Get(ctx, k8sclient.ObjectKey{
@@ -365,6 +431,9 @@ func (r *RateLimitServiceReconciler) uniqueKey(ratelimitConfig marin3rconfig.Rat
 	} else {
 		str = fmt.Sprintf("%s/%d/%s", ratelimitConfig.Unit, ratelimitConfig.RequestsPerUnit, currentRateLimit)
 	}
+	if r.ShadowRateLimitConfig != nil {
+		str = fmt.Sprintf("%s/shadow/%s/%d", str, r.ShadowRateLimitConfig.Unit, r.ShadowRateLimitConfig.RequestsPerUnit)
+	}
 
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(str)))
 }
@@ -375,9 +444,38 @@ func GetRateLimitFromConfig(c *corev1.ConfigMap) (*limitadorLimit, error) {
 	if err != nil {
 		return nil, fmt.Errorf(fmt.Sprintf("error unmarshalling ratelimiting config from configmap '%s'", c.Name), err)
 	}
+	if len(ratelimitconfig) == 0 {
+		return nil, fmt.Errorf("no rate limit found in configmap '%s'", c.Name)
+	}
 	return &ratelimitconfig[0], nil
 }
 
+// GetEnforcedRateLimit returns the rate limit the rate limit service is configured with
+// in the namespace, nil is returned when it is not configured yet
+func GetEnforcedRateLimit(ctx context.Context, client k8sclient.Client, namespace string) (*marin3rconfig.RateLimitConfig, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := client.Get(ctx, k8sclient.ObjectKey{Name: RateLimitingConfigMapName, Namespace: namespace}, configMap); err != nil {
+		if k8sError.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	limit, err := GetRateLimitFromConfig(configMap)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := GetSecondsInUnit(limit.Seconds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &marin3rconfig.RateLimitConfig{
+		Unit:            unit,
+		RequestsPerUnit: limit.MaxValue,
+	}, nil
+}
+
 func (r *RateLimitServiceReconciler) getUnitInSeconds(rateLimitUnit string) (uint64, error) {
 	if rateLimitUnit == "second" {
 		return 1, nil
@@ -546,11 +644,29 @@ func (r *RateLimitServiceReconciler) ensureLimits(ctx context.Context, client k8
 	}
 
 	// Get current limits in redis
-	limitadorClient := NewLimitadorClient(r.PodExecutor, r.Namespace, rateLimitPods.Items[0].Name)
-	limitadorLimitsInRedis, err := limitadorClient.GetLimitsByName(ratelimit.RateLimitDomain)
+	limitadorClient, err := r.getLimitadorClient(ctx, client)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	limitadorLimitsInRedis, err := limitadorClient.GetLimits(ctx, ratelimit.RateLimitDomain)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
+	if r.ShadowRateLimitConfig != nil {
+		shadowLimitsInRedis, err := limitadorClient.GetLimits(ctx, ratelimit.RateLimitShadowDomain)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		limitadorLimitsInRedis = append(limitadorLimitsInRedis, shadowLimitsInRedis...)
+	}
+	// the portal limits are read even when disabled, so they are removed once disabled
+	for _, namespace := range ratelimit.RateLimitPortalDomains {
+		portalLimitsInRedis, err := limitadorClient.GetLimits(ctx, namespace)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		limitadorLimitsInRedis = append(limitadorLimitsInRedis, portalLimitsInRedis...)
+	}
 
 	// Get limits from configuration
 	limitadorSetting, err := r.getLimitadorSetting(ctx, client)
@@ -560,7 +676,16 @@ func (r *RateLimitServiceReconciler) ensureLimits(ctx context.Context, client k8
 
 	// If there are difference, delete the limits and delete a pod to reload the limits from the config map
 	if r.differentLimitSettings(limitadorLimitsInRedis, limitadorSetting) {
-		phase, err := r.deleteRedisLimits(ctx, client, limitadorClient)
+		phase, err := r.deleteRedisLimits(ctx, limitadorClient)
+		audit.Record(ctx, audit.Entry{
+			Product:  string(integreatlyv1alpha1.ProductMarin3r),
+			Action:   audit.ActionUpdate,
+			Resource: "ratelimit/" + ratelimit.RateLimitDomain,
+			Reason:   "rate limit configuration changed",
+			Before:   limitadorLimitsInRedis,
+			After:    limitadorSetting,
+			Result:   audit.ResultOf(err),
+		})
 
 		if phase != integreatlyv1alpha1.PhaseCompleted {
 			return phase, err
@@ -581,35 +706,177 @@ func (r *RateLimitServiceReconciler) ensureLimits(ctx context.Context, client k8
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func (r *RateLimitServiceReconciler) deleteRedisLimits(ctx context.Context, client k8sclient.Client, limitadorClient *LimitadorClient) (integreatlyv1alpha1.StatusPhase, error) {
+func (r *RateLimitServiceReconciler) deleteRedisLimits(ctx context.Context, limitadorClient LimitadorClientInterface) (integreatlyv1alpha1.StatusPhase, error) {
+	namespaces := append([]string{ratelimit.RateLimitDomain, ratelimit.RateLimitShadowDomain}, ratelimit.RateLimitPortalDomains...)
+	for _, namespace := range namespaces {
+		if err := limitadorClient.DeleteLimits(ctx, namespace); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+// getLimitadorClient returns a client of the Limitador HTTP API exposed by the rate limit service
+func (r *RateLimitServiceReconciler) getLimitadorClient(ctx context.Context, client k8sclient.Client) (LimitadorClientInterface, error) {
+	if r.LimitadorURL != "" {
+		return NewLimitadorClient(r.LimitadorURL), nil
+	}
 
 	rateLimitService := &corev1.Service{}
 	if err := client.Get(ctx, k8sclient.ObjectKey{Name: quota.RateLimitName, Namespace: r.Namespace}, rateLimitService); err != nil {
-		return integreatlyv1alpha1.PhaseFailed, err
+		return nil, err
 	}
-
-	if err := limitadorClient.DeleteLimitsByNameUsingPod(ratelimit.RateLimitDomain, rateLimitService.Spec.ClusterIP); err != nil {
-		return integreatlyv1alpha1.PhaseFailed, err
+	for _, port := range rateLimitService.Spec.Ports {
+		if port.Name == "http" {
+			return NewLimitadorClient(fmt.Sprintf("http://%s:%d", rateLimitService.Spec.ClusterIP, port.Port)), nil
+		}
 	}
 
-	return integreatlyv1alpha1.PhaseCompleted, nil
+	return nil, fmt.Errorf("http port not found in %s service", quota.RateLimitName)
 }
 
 func (r *RateLimitServiceReconciler) getLimitadorSetting(ctx context.Context, client k8sclient.Client) ([]limitadorLimit, error) {
+	var limitadorLimit []limitadorLimit
+	var err error
 	if !integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(r.Installation.Spec.Type)) {
-		limitadorLimit, err := r.getRHOAMLimitadorSetting()
+		limitadorLimit, err = r.getRHOAMLimitadorSetting()
+	} else {
+		limitadorLimit, err = r.getMultitenantRHOAMLimitadorSetting(ctx, client)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshall rate limit config: %v", err)
+	}
+
+	descriptorLimits, err := r.getDescriptorLimitadorSetting(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	limitadorLimit = append(limitadorLimit, descriptorLimits...)
+
+	shadowLimits, err := r.getShadowLimitadorSetting(limitadorLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	portalLimits, err := r.getPortalLimitadorSetting(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	limitadorLimit = append(limitadorLimit, portalLimits...)
+
+	return append(limitadorLimit, shadowLimits...), nil
+}
+
+// getShadowLimitadorSetting returns a copy of every limit of the API requests, with the global
+// limit set to the shadow rate limit. The copies are kept in a separate namespace the envoy
+// sidecar of apicast reports the requests to without enforcing the result, the rejections are
+// only recorded in the Limitador metrics, labelled with the name of the descriptor of the limit.
+// No limit is returned without a shadow rate limit
+func (r *RateLimitServiceReconciler) getShadowLimitadorSetting(limits []limitadorLimit) ([]limitadorLimit, error) {
+	if r.ShadowRateLimitConfig == nil {
+		return nil, nil
+	}
+
+	unitInSeconds, err := r.getUnitInSeconds(r.ShadowRateLimitConfig.Unit)
+	if err != nil {
+		return nil, err
+	}
+
+	globalCondition := fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue)
+	var shadowLimits []limitadorLimit
+	for _, limit := range limits {
+		limit.Namespace = ratelimit.RateLimitShadowDomain
+		limit.Name = getShadowLimitName(limit)
+		if len(limit.Conditions) == 1 && limit.Conditions[0] == globalCondition {
+			limit.MaxValue = r.ShadowRateLimitConfig.RequestsPerUnit
+			limit.Seconds = unitInSeconds
+		}
+		shadowLimits = append(shadowLimits, limit)
+	}
+	return shadowLimits, nil
+}
+
+// getShadowLimitName returns the name of the descriptor a shadow limit counts the requests of
+func getShadowLimitName(limit limitadorLimit) string {
+	for _, condition := range limit.Conditions {
+		key, value, _ := strings.Cut(condition, " == ")
+		switch key {
+		case genericKey:
+			return shadowGlobalLimitName
+		case headerMatch:
+			return shadowTenantLimitName
+		case marin3rconfig.DescriptorRuleKey:
+			return value
+		}
+	}
+	return strings.Join(limit.Conditions, ",")
+}
+
+// getDescriptorLimitadorSetting returns the limits of the descriptor rules configured by the
+// customer, matching the descriptors the envoy sidecar of apicast sends for the rules
+func (r *RateLimitServiceReconciler) getDescriptorLimitadorSetting(ctx context.Context, client k8sclient.Client) ([]limitadorLimit, error) {
+	rules, err := marin3rconfig.GetDescriptorRules(ctx, client, r.Installation.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []limitadorLimit
+	for _, rule := range rules {
+		unitInSeconds, err := r.getUnitInSeconds(rule.Unit)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshall rate limit config: %v", err)
+			return nil, err
 		}
-		return limitadorLimit, nil
+		limits = append(limits, limitadorLimit{
+			Namespace: ratelimit.RateLimitDomain,
+			MaxValue:  rule.RequestsPerUnit,
+			Seconds:   unitInSeconds,
+			Conditions: []string{
+				fmt.Sprintf("%s == %s", marin3rconfig.DescriptorRuleKey, rule.Name),
+			},
+			Variables: []string{
+				rule.CounterKey(),
+			},
+		})
 	}
+	return limits, nil
+}
 
-	limitadorLimit, err := r.getMultitenantRHOAMLimitadorSetting(ctx, client)
+// getPortalLimitadorSetting returns the limits of the 3scale admin and developer portals, in
+// the Limitador namespace of each portal, matching the descriptors the envoy sidecar of
+// system-app sends when the portals are rate limited
+func (r *RateLimitServiceReconciler) getPortalLimitadorSetting(ctx context.Context, client k8sclient.Client) ([]limitadorLimit, error) {
+	portalConfig, err := marin3rconfig.GetPortalConfig(ctx, client, r.Installation.Namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshall rate limit config: %v", err)
+		return nil, err
+	}
+	if !portalConfig.IsEnabled() {
+		return nil, nil
 	}
 
-	return limitadorLimit, nil
+	var limits []limitadorLimit
+	for _, namespace := range ratelimit.RateLimitPortalDomains {
+		for _, portalLimit := range portalConfig.Limits() {
+			unitInSeconds, err := r.getUnitInSeconds(portalLimit.Unit)
+			if err != nil {
+				return nil, err
+			}
+			limits = append(limits, limitadorLimit{
+				Namespace: namespace,
+				MaxValue:  portalLimit.RequestsPerUnit,
+				Seconds:   unitInSeconds,
+				Conditions: []string{
+					fmt.Sprintf("%s == %s", marin3rconfig.PortalLimitKey, portalLimit.Name),
+				},
+				Variables: []string{
+					portalLimit.CounterKey(),
+				},
+			})
+		}
+	}
+	return limits, nil
 }
 
 func (r *RateLimitServiceReconciler) differentLimitSettings(redisLimits []limitadorLimit, currentLimits []limitadorLimit) bool {
@@ -628,6 +895,10 @@ func sortByNamespaceAndMaxValue(elems []limitadorLimit) {
 		if elems[i].Namespace != elems[j].Namespace {
 			return elems[i].Namespace < elems[j].Namespace
 		}
-		return elems[i].MaxValue < elems[j].MaxValue
+		if elems[i].MaxValue != elems[j].MaxValue {
+			return elems[i].MaxValue < elems[j].MaxValue
+		}
+		// descriptor rules may share the max value of other limits
+		return strings.Join(elems[i].Conditions, ",") < strings.Join(elems[j].Conditions, ",")
 	})
 }
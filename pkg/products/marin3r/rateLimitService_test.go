@@ -3,12 +3,12 @@ package marin3r
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"reflect"
 	"testing"
 
 	moqclient "github.com/integr8ly/integreatly-operator/pkg/client"
 	"github.com/integr8ly/integreatly-operator/pkg/config"
-	"github.com/integr8ly/integreatly-operator/pkg/resources"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
 	"github.com/integr8ly/integreatly-operator/utils"
@@ -41,11 +41,34 @@ func TestRateLimitService(t *testing.T) {
 		},
 	}
 
-	podExecutorMock := &resources.PodExecutorInterfaceMock{
-		ExecuteRemoteCommandFunc: func(ns string, podName string, command []string) (string, string, error) {
-			return "[{\"namespace\":\"apicast-ratelimit\",\"max_value\":1,\"seconds\":60,\"name\":null,\"conditions\":[\"generic_key == slowpath\"],\"variables\":[\"generic_key\"]}]", "", nil
-		},
-	}
+	limitador := newFakeLimitador(t, map[string][]limitadorLimit{
+		ratelimit.RateLimitDomain: {{
+			Namespace:  ratelimit.RateLimitDomain,
+			MaxValue:   1,
+			Seconds:    60,
+			Conditions: []string{"generic_key == slowpath"},
+			Variables:  []string{"generic_key"},
+		}},
+	})
+
+	// the shadow limits are kept apart so the other scenarios find their limits unchanged
+	shadowLimitador := newFakeLimitador(t, map[string][]limitadorLimit{
+		ratelimit.RateLimitDomain: {{
+			Namespace:  ratelimit.RateLimitDomain,
+			MaxValue:   1,
+			Seconds:    60,
+			Conditions: []string{"generic_key == slowpath"},
+			Variables:  []string{"generic_key"},
+		}},
+		ratelimit.RateLimitShadowDomain: {{
+			Namespace:  ratelimit.RateLimitShadowDomain,
+			Name:       shadowGlobalLimitName,
+			MaxValue:   2,
+			Seconds:    60,
+			Conditions: []string{"generic_key == slowpath"},
+			Variables:  []string{"generic_key"},
+		}},
+	})
 
 	scenarios := []struct {
 		Name          string
@@ -61,7 +84,7 @@ func TestRateLimitService(t *testing.T) {
 				RequestsPerUnit: 1,
 			},
 				&integreatlyv1alpha1.RHMI{}, "redhat-test-marin3r", "ratelimit-redis",
-				podExecutorMock, &config.ConfigReadWriterMock{},
+				&config.ConfigReadWriterMock{},
 			),
 			ProductConfig: &quota.ProductConfigMock{
 				ConfigureFunc: func(obj metav1.Object) error {
@@ -143,7 +166,6 @@ func TestRateLimitService(t *testing.T) {
 				&integreatlyv1alpha1.RHMI{},
 				"redhat-test-marin3r",
 				"ratelimit-redis",
-				podExecutorMock,
 				&config.ConfigReadWriterMock{},
 			),
 			ProductConfig: &quota.ProductConfigMock{
@@ -195,7 +217,7 @@ func TestRateLimitService(t *testing.T) {
 				Unit:            "minute",
 				RequestsPerUnit: 1,
 			},
-				&integreatlyv1alpha1.RHMI{}, "redhat-test-marin3r", "ratelimit-redis", resources.PodExecutor{}, &config.ConfigReadWriterMock{},
+				&integreatlyv1alpha1.RHMI{}, "redhat-test-marin3r", "ratelimit-redis", &config.ConfigReadWriterMock{},
 			),
 			ProductConfig: &quota.ProductConfigMock{
 				ConfigureFunc: func(obj metav1.Object) error {
@@ -240,7 +262,6 @@ func TestRateLimitService(t *testing.T) {
 				},
 				"redhat-test-marin3r",
 				"ratelimit-redis",
-				podExecutorMock,
 				&config.ConfigReadWriterMock{},
 			),
 			ProductConfig: &quota.ProductConfigMock{
@@ -259,11 +280,148 @@ func TestRateLimitService(t *testing.T) {
 				}),
 			),
 		},
+
+		{
+			Name:     "In memory storage deployed without redis",
+			InitObjs: []runtime.Object{rateLimitPod},
+			Reconciler: &RateLimitServiceReconciler{
+				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+				StorageConfig:   marin3rconfig.RateLimitStorageConfig{Mode: marin3rconfig.StorageModeInMemory},
+				Installation:    &integreatlyv1alpha1.RHMI{},
+				Namespace:       "redhat-test-marin3r",
+				RedisSecretName: "ratelimit-redis",
+				ConfigManager:   &config.ConfigReadWriterMock{},
+			},
+			ProductConfig: &quota.ProductConfigMock{
+				ConfigureFunc: func(obj metav1.Object) error {
+					replicas := int32(3)
+					obj.(*appsv1.Deployment).Spec.Replicas = &replicas
+					return nil
+				},
+			},
+			Assert: allOf(
+				assertNoError,
+				assertPhase(integreatlyv1alpha1.PhaseCompleted),
+				assertDeployment(func(deployment *appsv1.Deployment, e error) error {
+					if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 1 {
+						return fmt.Errorf("expected a single replica with in memory storage, got %v", deployment.Spec.Replicas)
+					}
+					return nil
+				}),
+				assertDeployment(assertEnvs(map[string]func(string) error{
+					"REDIS_URL": func(url string) error {
+						if url != "" {
+							return fmt.Errorf("unexpected REDIS_URL with in memory storage: %s", url)
+						}
+						return nil
+					},
+					rateLimitHeadersEnv: expectEnv(rateLimitHeadersEnv, ""),
+				})),
+			),
+		},
+
+		{
+			Name:     "Rate limit headers enabled with the rate limit response",
+			InitObjs: []runtime.Object{rateLimitPod},
+			Reconciler: &RateLimitServiceReconciler{
+				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+				StorageConfig:   marin3rconfig.RateLimitStorageConfig{Mode: marin3rconfig.StorageModeInMemory},
+				ResponseConfig:  &marin3rconfig.ResponseConfig{Enabled: true},
+				Installation:    &integreatlyv1alpha1.RHMI{},
+				Namespace:       "redhat-test-marin3r",
+				RedisSecretName: "ratelimit-redis",
+				ConfigManager:   &config.ConfigReadWriterMock{},
+			},
+			ProductConfig: &quota.ProductConfigMock{
+				ConfigureFunc: func(obj metav1.Object) error {
+					return nil
+				},
+			},
+			Assert: allOf(
+				assertNoError,
+				assertPhase(integreatlyv1alpha1.PhaseCompleted),
+				assertDeployment(assertEnvs(map[string]func(string) error{
+					rateLimitHeadersEnv: expectEnv(rateLimitHeadersEnv, "DRAFT_VERSION_03"),
+				})),
+			),
+		},
+
+		{
+			Name:     "Limit names added to the metrics with a shadow rate limit",
+			InitObjs: []runtime.Object{rateLimitPod},
+			Reconciler: &RateLimitServiceReconciler{
+				RateLimitConfig:       marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+				ShadowRateLimitConfig: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 2},
+				StorageConfig:         marin3rconfig.RateLimitStorageConfig{Mode: marin3rconfig.StorageModeInMemory},
+				Installation:          &integreatlyv1alpha1.RHMI{},
+				Namespace:             "redhat-test-marin3r",
+				RedisSecretName:       "ratelimit-redis",
+				ConfigManager:         &config.ConfigReadWriterMock{},
+				LimitadorURL:          shadowLimitador.URL,
+			},
+			ProductConfig: &quota.ProductConfigMock{
+				ConfigureFunc: func(obj metav1.Object) error {
+					return nil
+				},
+			},
+			Assert: allOf(
+				assertNoError,
+				assertPhase(integreatlyv1alpha1.PhaseCompleted),
+				assertDeployment(assertEnvs(map[string]func(string) error{
+					limitNameInPrometheusLabelsEnv: expectEnv(limitNameInPrometheusLabelsEnv, "true"),
+				})),
+			),
+		},
+
+		{
+			Name: "Redis cached storage deployed with the cache settings",
+			InitObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ratelimit-redis",
+						Namespace: "redhat-test-marin3r",
+					},
+					Data: map[string][]byte{
+						"URL": []byte("test-url"),
+					},
+				},
+				rateLimitPod,
+			},
+			Reconciler: &RateLimitServiceReconciler{
+				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+				StorageConfig: marin3rconfig.RateLimitStorageConfig{
+					Mode:  marin3rconfig.StorageModeRedisCached,
+					Cache: &marin3rconfig.RateLimitCacheConfig{FlushingPeriodMs: 500},
+				},
+				Installation:    &integreatlyv1alpha1.RHMI{},
+				Namespace:       "redhat-test-marin3r",
+				RedisSecretName: "ratelimit-redis",
+				ConfigManager:   &config.ConfigReadWriterMock{},
+			},
+			ProductConfig: &quota.ProductConfigMock{
+				ConfigureFunc: func(obj metav1.Object) error {
+					return nil
+				},
+			},
+			Assert: allOf(
+				assertNoError,
+				assertPhase(integreatlyv1alpha1.PhaseCompleted),
+				assertDeployment(assertEnvs(map[string]func(string) error{
+					"REDIS_URL":                                   expectEnv("REDIS_URL", "redis://test-url"),
+					"REDIS_LOCAL_CACHE_ENABLED":                   expectEnv("REDIS_LOCAL_CACHE_ENABLED", "true"),
+					"REDIS_LOCAL_CACHE_FLUSHING_PERIOD_MS":        expectEnv("REDIS_LOCAL_CACHE_FLUSHING_PERIOD_MS", "500"),
+					"REDIS_LOCAL_CACHE_TTL_RATIO_CACHED_COUNTERS": expectEnv("REDIS_LOCAL_CACHE_TTL_RATIO_CACHED_COUNTERS", ""),
+				})),
+			),
+		},
 	}
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.Name, func(t *testing.T) {
 			client := utils.NewTestClient(scheme, scenario.InitObjs...)
+			if scenario.Reconciler.LimitadorURL == "" {
+				scenario.Reconciler.LimitadorURL = limitador.URL
+			}
 			phase, err := scenario.Reconciler.ReconcileRateLimitService(context.TODO(), client, scenario.ProductConfig)
 
 			if err := scenario.Assert(client, phase, err); err != nil {
@@ -340,6 +498,15 @@ func assertEnvs(assertions map[string]func(string) error) func(*appsv1.Deploymen
 	}
 }
 
+func expectEnv(name, expected string) func(string) error {
+	return func(value string) error {
+		if value != expected {
+			return fmt.Errorf("unexpected value for %s: %q, expected %q", name, value, expected)
+		}
+		return nil
+	}
+}
+
 func TestRateLimitServiceReconciler_differentLimitSettings(t *testing.T) {
 	type fields struct {
 		Namespace       string
@@ -464,11 +631,11 @@ func TestRateLimitServiceReconciler_getLimitadorSetting(t *testing.T) {
 	}
 
 	type fields struct {
-		Namespace       string
-		RedisSecretName string
-		Installation    *integreatlyv1alpha1.RHMI
-		RateLimitConfig marin3rconfig.RateLimitConfig
-		PodExecutor     resources.PodExecutorInterface
+		Namespace             string
+		RedisSecretName       string
+		Installation          *integreatlyv1alpha1.RHMI
+		RateLimitConfig       marin3rconfig.RateLimitConfig
+		ShadowRateLimitConfig *marin3rconfig.RateLimitConfig
 	}
 	type args struct {
 		ctx    context.Context
@@ -483,6 +650,10 @@ func TestRateLimitServiceReconciler_getLimitadorSetting(t *testing.T) {
 	}{
 		{
 			name: "test get rhoam limitator config",
+			args: args{
+				ctx:    context.TODO(),
+				client: utils.NewTestClient(scheme),
+			},
 			fields: fields{
 				Installation: &integreatlyv1alpha1.RHMI{
 					Spec: integreatlyv1alpha1.RHMISpec{
@@ -507,6 +678,10 @@ func TestRateLimitServiceReconciler_getLimitadorSetting(t *testing.T) {
 		},
 		{
 			name: "test error get rhoam limitator config",
+			args: args{
+				ctx:    context.TODO(),
+				client: utils.NewTestClient(scheme),
+			},
 			fields: fields{
 				Installation: &integreatlyv1alpha1.RHMI{
 					Spec: integreatlyv1alpha1.RHMISpec{
@@ -518,6 +693,174 @@ func TestRateLimitServiceReconciler_getLimitadorSetting(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "test get rhoam limitator config with descriptor rules",
+			args: args{
+				ctx: context.TODO(),
+				client: utils.NewTestClient(scheme, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      marin3rconfig.DescriptorConfigMapName,
+						Namespace: "redhat-rhoam-operator",
+					},
+					Data: map[string]string{
+						marin3rconfig.DescriptorConfigKey: `[
+							{"name": "orders", "pathPrefix": "/orders", "method": "POST", "perKey": "user_key", "unit": "minute", "requests_per_unit": 10},
+							{"name": "search", "pathPrefix": "/search", "unit": "second", "requests_per_unit": 5}
+						]`,
+					},
+				}),
+			},
+			fields: fields{
+				Installation: &integreatlyv1alpha1.RHMI{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "redhat-rhoam-operator"},
+					Spec: integreatlyv1alpha1.RHMISpec{
+						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
+					},
+				},
+				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
+			},
+			want: []limitadorLimit{
+				{
+					Namespace: ratelimit.RateLimitDomain,
+					MaxValue:  1,
+					Seconds:   1,
+					Conditions: []string{
+						fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue),
+					},
+					Variables: []string{
+						genericKey,
+					},
+				},
+				{
+					Namespace: ratelimit.RateLimitDomain,
+					MaxValue:  10,
+					Seconds:   60,
+					Conditions: []string{
+						"descriptor_rule == orders",
+					},
+					Variables: []string{
+						"user_key",
+					},
+				},
+				{
+					Namespace: ratelimit.RateLimitDomain,
+					MaxValue:  5,
+					Seconds:   1,
+					Conditions: []string{
+						"descriptor_rule == search",
+					},
+					Variables: []string{
+						"descriptor_rule",
+					},
+				},
+			},
+		},
+		{
+			name: "test get rhoam limitator config with the portals rate limited",
+			args: args{
+				ctx: context.TODO(),
+				client: utils.NewTestClient(scheme, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      marin3rconfig.PortalConfigMapName,
+						Namespace: "redhat-rhoam-operator",
+					},
+					Data: map[string]string{
+						marin3rconfig.PortalConfigKey: `{"enabled": true, "per_client_ip": {"unit": "minute", "requests_per_unit": 300}, "per_session": {"unit": "second", "requests_per_unit": 20}}`,
+					},
+				}),
+			},
+			fields: fields{
+				Installation: &integreatlyv1alpha1.RHMI{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "redhat-rhoam-operator"},
+					Spec: integreatlyv1alpha1.RHMISpec{
+						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
+					},
+				},
+				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
+			},
+			want: []limitadorLimit{
+				{
+					Namespace: ratelimit.RateLimitDomain,
+					MaxValue:  1,
+					Seconds:   1,
+					Conditions: []string{
+						fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue),
+					},
+					Variables: []string{
+						genericKey,
+					},
+				},
+				{
+					Namespace:  ratelimit.RateLimitProviderPortalDomain,
+					MaxValue:   300,
+					Seconds:    60,
+					Conditions: []string{"portal_limit == per_client_ip"},
+					Variables:  []string{"remote_address"},
+				},
+				{
+					Namespace:  ratelimit.RateLimitProviderPortalDomain,
+					MaxValue:   20,
+					Seconds:    1,
+					Conditions: []string{"portal_limit == per_session"},
+					Variables:  []string{"portal_session"},
+				},
+				{
+					Namespace:  ratelimit.RateLimitDeveloperPortalDomain,
+					MaxValue:   300,
+					Seconds:    60,
+					Conditions: []string{"portal_limit == per_client_ip"},
+					Variables:  []string{"remote_address"},
+				},
+				{
+					Namespace:  ratelimit.RateLimitDeveloperPortalDomain,
+					MaxValue:   20,
+					Seconds:    1,
+					Conditions: []string{"portal_limit == per_session"},
+					Variables:  []string{"portal_session"},
+				},
+			},
+		},
+		{
+			name: "test get rhoam limitator config with a shadow rate limit",
+			args: args{
+				ctx:    context.TODO(),
+				client: utils.NewTestClient(scheme),
+			},
+			fields: fields{
+				Installation: &integreatlyv1alpha1.RHMI{
+					Spec: integreatlyv1alpha1.RHMISpec{
+						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
+					},
+				},
+				RateLimitConfig:       marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 100},
+				ShadowRateLimitConfig: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 50},
+			},
+			want: []limitadorLimit{
+				{
+					Namespace: ratelimit.RateLimitDomain,
+					MaxValue:  100,
+					Seconds:   60,
+					Conditions: []string{
+						fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue),
+					},
+					Variables: []string{
+						genericKey,
+					},
+				},
+				{
+					Namespace: ratelimit.RateLimitShadowDomain,
+					Name:      shadowGlobalLimitName,
+					MaxValue:  50,
+					Seconds:   60,
+					Conditions: []string{
+						fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue),
+					},
+					Variables: []string{
+						genericKey,
+					},
+				},
+			},
+		},
 		{
 			name: "test get rhoam multitenant limitator config",
 			args: args{
@@ -566,6 +909,90 @@ func TestRateLimitServiceReconciler_getLimitadorSetting(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "test get rhoam multitenant limitator config with a shadow rate limit and descriptor rules",
+			args: args{
+				ctx: context.TODO(),
+				client: utils.NewTestClient(scheme,
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      multitenantLimitConfigMap,
+							Namespace: "test",
+						},
+						Data: map[string]string{
+							multitenantRateLimit: "10",
+						},
+					},
+					&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      marin3rconfig.DescriptorConfigMapName,
+							Namespace: "redhat-rhoam-operator",
+						},
+						Data: map[string]string{
+							marin3rconfig.DescriptorConfigKey: `[{"name": "search", "pathPrefix": "/search", "unit": "second", "requests_per_unit": 5}]`,
+						},
+					},
+				),
+			},
+			fields: fields{
+				Namespace: "test",
+				Installation: &integreatlyv1alpha1.RHMI{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "redhat-rhoam-operator"},
+					Spec: integreatlyv1alpha1.RHMISpec{
+						Type: string(integreatlyv1alpha1.InstallationTypeMultitenantManagedApi),
+					},
+				},
+				RateLimitConfig:       marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
+				ShadowRateLimitConfig: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 50},
+			},
+			want: []limitadorLimit{
+				{
+					Namespace:  ratelimit.RateLimitDomain,
+					MaxValue:   1,
+					Seconds:    1,
+					Conditions: []string{fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue)},
+					Variables:  []string{genericKey},
+				},
+				{
+					Namespace:  ratelimit.RateLimitDomain,
+					MaxValue:   10,
+					Seconds:    1,
+					Conditions: []string{fmt.Sprintf("%s == %s", headerMatch, multitenantDescriptorValue)},
+					Variables:  []string{headerKey},
+				},
+				{
+					Namespace:  ratelimit.RateLimitDomain,
+					MaxValue:   5,
+					Seconds:    1,
+					Conditions: []string{"descriptor_rule == search"},
+					Variables:  []string{"descriptor_rule"},
+				},
+				{
+					Namespace:  ratelimit.RateLimitShadowDomain,
+					Name:       shadowGlobalLimitName,
+					MaxValue:   50,
+					Seconds:    60,
+					Conditions: []string{fmt.Sprintf("%s == %s", genericKey, ratelimit.RateLimitDescriptorValue)},
+					Variables:  []string{genericKey},
+				},
+				{
+					Namespace:  ratelimit.RateLimitShadowDomain,
+					Name:       shadowTenantLimitName,
+					MaxValue:   10,
+					Seconds:    1,
+					Conditions: []string{fmt.Sprintf("%s == %s", headerMatch, multitenantDescriptorValue)},
+					Variables:  []string{headerKey},
+				},
+				{
+					Namespace:  ratelimit.RateLimitShadowDomain,
+					Name:       "search",
+					MaxValue:   5,
+					Seconds:    1,
+					Conditions: []string{"descriptor_rule == search"},
+					Variables:  []string{"descriptor_rule"},
+				},
+			},
+		},
 		{
 			name: "test error get rhoam multitenant limitator config",
 			args: args{
@@ -588,11 +1015,11 @@ func TestRateLimitServiceReconciler_getLimitadorSetting(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := &RateLimitServiceReconciler{
-				Namespace:       tt.fields.Namespace,
-				RedisSecretName: tt.fields.RedisSecretName,
-				Installation:    tt.fields.Installation,
-				RateLimitConfig: tt.fields.RateLimitConfig,
-				PodExecutor:     tt.fields.PodExecutor,
+				Namespace:             tt.fields.Namespace,
+				RedisSecretName:       tt.fields.RedisSecretName,
+				Installation:          tt.fields.Installation,
+				RateLimitConfig:       tt.fields.RateLimitConfig,
+				ShadowRateLimitConfig: tt.fields.ShadowRateLimitConfig,
 			}
 			got, err := r.getLimitadorSetting(tt.args.ctx, tt.args.client)
 			if (err != nil) != tt.wantErr {
@@ -647,196 +1074,187 @@ func TestRateLimitServiceReconciler_ensureLimits(t *testing.T) {
 		},
 	}
 
-	configManager := &config.ConfigReadWriterMock{
-		ReadObservabilityFunc: func() (*config.Observability, error) {
-			return &config.Observability{
-				Config: config.ProductConfig{
-					"NAMESPACE": namespace,
-				},
-			}, nil
+	rateLimitService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: quota.RateLimitName, Namespace: namespace},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "1.1.1.1",
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 8080}},
 		},
 	}
 
-	rateLimitService := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{Name: quota.RateLimitName, Namespace: namespace},
-		Spec:       corev1.ServiceSpec{ClusterIP: "1.1.1.1"},
+	globalLimit := func(namespace string, maxValue uint32) limitadorLimit {
+		return limitadorLimit{
+			Namespace:  namespace,
+			MaxValue:   maxValue,
+			Seconds:    60,
+			Conditions: []string{"generic_key == slowpath"},
+			Variables:  []string{"generic_key"},
+		}
 	}
 
-	type fields struct {
-		Namespace       string
-		RedisSecretName string
-		Installation    *integreatlyv1alpha1.RHMI
-		RateLimitConfig marin3rconfig.RateLimitConfig
-		PodExecutor     resources.PodExecutorInterface
-		ConfigManager   config.ConfigReadWriter
+	shadowGlobalLimit := func(maxValue uint32) limitadorLimit {
+		limit := globalLimit(ratelimit.RateLimitShadowDomain, maxValue)
+		limit.Name = shadowGlobalLimitName
+		return limit
 	}
-	type args struct {
-		ctx    context.Context
-		client k8sclient.Client
+
+	installation := &integreatlyv1alpha1.RHMI{
+		Spec: integreatlyv1alpha1.RHMISpec{
+			Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
+		},
 	}
+
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    integreatlyv1alpha1.StatusPhase
-		wantErr bool
+		name                  string
+		client                k8sclient.Client
+		limitador             func(*fakeLimitador)
+		limits                map[string][]limitadorLimit
+		rateLimitConfig       marin3rconfig.RateLimitConfig
+		shadowRateLimitConfig *marin3rconfig.RateLimitConfig
+		// noLimitadorURL resolves the Limitador URL from the rate limit service
+		noLimitadorURL bool
+		want           integreatlyv1alpha1.StatusPhase
+		wantErr        bool
+		wantDeleted    []string
+		wantPodDeleted bool
 	}{
 		{
 			name: "test phase failed listing rate limit pods",
-			fields: fields{
-				Namespace: namespace,
-			},
-			args: args{
-				ctx: context.TODO(),
-				client: &moqclient.SigsClientInterfaceMock{ListFunc: func(ctx context.Context, list k8sclient.ObjectList, opts ...k8sclient.ListOption) error {
-					return fmt.Errorf("listError")
-				}},
-			},
+			client: &moqclient.SigsClientInterfaceMock{ListFunc: func(ctx context.Context, list k8sclient.ObjectList, opts ...k8sclient.ListOption) error {
+				return fmt.Errorf("listError")
+			}},
 			want:    integreatlyv1alpha1.PhaseFailed,
 			wantErr: true,
 		},
 		{
-			name: "test phase failed if pod is pending",
-			fields: fields{
-				Namespace: namespace,
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPodPending),
-			},
+			name:    "test phase failed if pod is pending",
+			client:  utils.NewTestClient(scheme, rateLimitPodPending),
 			want:    integreatlyv1alpha1.PhaseFailed,
 			wantErr: true,
 		},
 		{
-			name: "test phase failed if pod has failed",
-			fields: fields{
-				Namespace: namespace,
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPodFailed),
-			},
+			name:    "test phase failed if pod has failed",
+			client:  utils.NewTestClient(scheme, rateLimitPodFailed),
 			want:    integreatlyv1alpha1.PhaseFailed,
 			wantErr: true,
 		},
 		{
-			name: "test phase waiting components in rate limits pods are not up yet",
-			fields: fields{
-				Namespace: namespace,
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme),
-			},
-			want: integreatlyv1alpha1.PhaseAwaitingComponents,
+			name:   "test phase waiting components in rate limits pods are not up yet",
+			client: utils.NewTestClient(scheme),
+			want:   integreatlyv1alpha1.PhaseAwaitingComponents,
 		},
 		{
-			name: "test phase failed if unable to list limits from rate limit pod",
-			fields: fields{
-				Namespace: namespace,
-				PodExecutor: &resources.PodExecutorInterfaceMock{ExecuteRemoteCommandFunc: func(ns string, podName string, command []string) (string, string, error) {
-					return "", "", fmt.Errorf("listError")
-				}},
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPod),
+			name:           "test phase failed if the rate limit service is not found",
+			client:         utils.NewTestClient(scheme, rateLimitPod),
+			noLimitadorURL: true,
+			want:           integreatlyv1alpha1.PhaseFailed,
+			wantErr:        true,
+		},
+		{
+			name:   "test phase failed if unable to list limits from limitador",
+			client: utils.NewTestClient(scheme, rateLimitPod),
+			limitador: func(f *fakeLimitador) {
+				f.status = http.StatusServiceUnavailable
 			},
 			want:    integreatlyv1alpha1.PhaseFailed,
 			wantErr: true,
 		},
 		{
-			name: "test phase failed marshalling json response",
-			fields: fields{
-				Namespace: namespace,
-				PodExecutor: &resources.PodExecutorInterfaceMock{ExecuteRemoteCommandFunc: func(ns string, podName string, command []string) (string, string, error) {
-					return "notJson", "", nil
-				}},
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPod),
+			name:   "test phase failed marshalling json response",
+			client: utils.NewTestClient(scheme, rateLimitPod),
+			limitador: func(f *fakeLimitador) {
+				f.rawResponse = "notJson"
 			},
 			want:    integreatlyv1alpha1.PhaseFailed,
 			wantErr: true,
 		},
 		{
-			name: "test phase failed deleting limits in redis",
-			fields: fields{
-				Installation: &integreatlyv1alpha1.RHMI{
-					Spec: integreatlyv1alpha1.RHMISpec{
-						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
-					},
-				},
-				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
-				Namespace:       namespace,
-				PodExecutor: &resources.PodExecutorInterfaceMock{ExecuteRemoteCommandFunc: func(ns string, podName string, command []string) (string, string, error) {
-					return "[{\"namespace\":\"apicast-ratelimit\",\"max_value\":70,\"seconds\":60,\"name\":null,\"conditions\":[\"generic_key == slowpath\"],\"variables\":[\"generic_key\"]}]", "", nil
-				}},
-				ConfigManager: &config.ConfigReadWriterMock{ReadObservabilityFunc: func() (*config.Observability, error) {
-					return nil, fmt.Errorf("genericError")
-				}}},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPod),
+			name:   "test phase failed deleting limits in redis",
+			client: utils.NewTestClient(scheme, rateLimitPod),
+			limitador: func(f *fakeLimitador) {
+				f.deleteStatus = http.StatusInternalServerError
 			},
-			want:    integreatlyv1alpha1.PhaseFailed,
-			wantErr: true,
+			limits:          map[string][]limitadorLimit{ratelimit.RateLimitDomain: {globalLimit(ratelimit.RateLimitDomain, 70)}},
+			rateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+			want:            integreatlyv1alpha1.PhaseFailed,
+			wantErr:         true,
 		},
 		{
-			name: "test phase in progress after deleting rate limit pod due to differences",
-			fields: fields{
-				Installation: &integreatlyv1alpha1.RHMI{
-					Spec: integreatlyv1alpha1.RHMISpec{
-						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
-					},
-				},
-				Namespace:       namespace,
-				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
-				PodExecutor: &resources.PodExecutorInterfaceMock{ExecuteRemoteCommandFunc: func(ns string, podName string, command []string) (string, string, error) {
-					return "[{\"namespace\":\"apicast-ratelimit\",\"max_value\":1,\"seconds\":60,\"name\":null,\"conditions\":[\"generic_key == slowpath\"],\"variables\":[\"generic_key\"]}]", "", nil
-				}},
-				ConfigManager: configManager,
+			name:            "test phase in progress after deleting rate limit pod due to differences",
+			client:          utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
+			limits:          map[string][]limitadorLimit{ratelimit.RateLimitDomain: {globalLimit(ratelimit.RateLimitDomain, 1)}},
+			rateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
+			want:            integreatlyv1alpha1.PhaseInProgress,
+			wantDeleted:     []string{ratelimit.RateLimitDomain, ratelimit.RateLimitShadowDomain, ratelimit.RateLimitProviderPortalDomain, ratelimit.RateLimitDeveloperPortalDomain},
+			wantPodDeleted:  true,
+		},
+		{
+			name:            "test phase complete when no differences found",
+			client:          utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
+			limits:          map[string][]limitadorLimit{ratelimit.RateLimitDomain: {globalLimit(ratelimit.RateLimitDomain, 1)}},
+			rateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+			want:            integreatlyv1alpha1.PhaseCompleted,
+		},
+		{
+			name:   "test phase in progress when the shadow limits are missing",
+			client: utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
+			limits: map[string][]limitadorLimit{
+				ratelimit.RateLimitDomain: {globalLimit(ratelimit.RateLimitDomain, 1)},
 			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
+			rateLimitConfig:       marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+			shadowRateLimitConfig: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 2},
+			want:                  integreatlyv1alpha1.PhaseInProgress,
+			wantDeleted:           []string{ratelimit.RateLimitDomain, ratelimit.RateLimitShadowDomain, ratelimit.RateLimitProviderPortalDomain, ratelimit.RateLimitDeveloperPortalDomain},
+			wantPodDeleted:        true,
+		},
+		{
+			name:   "test phase complete when the shadow limits are found",
+			client: utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
+			limits: map[string][]limitadorLimit{
+				ratelimit.RateLimitDomain:       {globalLimit(ratelimit.RateLimitDomain, 1)},
+				ratelimit.RateLimitShadowDomain: {shadowGlobalLimit(2)},
 			},
-			want: integreatlyv1alpha1.PhaseInProgress,
+			rateLimitConfig:       marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+			shadowRateLimitConfig: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 2},
+			want:                  integreatlyv1alpha1.PhaseCompleted,
 		},
 		{
-			name: "test phase complete when no differences found",
-			fields: fields{
-				Installation: &integreatlyv1alpha1.RHMI{
-					Spec: integreatlyv1alpha1.RHMISpec{
-						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
-					},
-				},
-				Namespace:       namespace,
-				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
-				PodExecutor: &resources.PodExecutorInterfaceMock{ExecuteRemoteCommandFunc: func(ns string, podName string, command []string) (string, string, error) {
-					return "[{\"namespace\":\"apicast-ratelimit\",\"max_value\":1,\"seconds\":60,\"name\":null,\"conditions\":[\"generic_key == slowpath\"],\"variables\":[\"generic_key\"]}]", "", nil
+			name:   "test phase in progress when the portal limits are left once disabled",
+			client: utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
+			limits: map[string][]limitadorLimit{
+				ratelimit.RateLimitDomain: {globalLimit(ratelimit.RateLimitDomain, 1)},
+				ratelimit.RateLimitProviderPortalDomain: {{
+					Namespace:  ratelimit.RateLimitProviderPortalDomain,
+					MaxValue:   300,
+					Seconds:    60,
+					Conditions: []string{"portal_limit == per_client_ip"},
+					Variables:  []string{"remote_address"},
 				}},
-				ConfigManager: configManager,
 			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitPod, rateLimitService),
-			},
-			want: integreatlyv1alpha1.PhaseCompleted,
+			rateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
+			want:            integreatlyv1alpha1.PhaseInProgress,
+			wantDeleted:     []string{ratelimit.RateLimitDomain, ratelimit.RateLimitShadowDomain, ratelimit.RateLimitProviderPortalDomain, ratelimit.RateLimitDeveloperPortalDomain},
+			wantPodDeleted:  true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			limitador := newFakeLimitador(t, tt.limits)
+			if tt.limitador != nil {
+				tt.limitador(limitador)
+			}
+
 			r := &RateLimitServiceReconciler{
-				Namespace:       tt.fields.Namespace,
-				RedisSecretName: tt.fields.RedisSecretName,
-				Installation:    tt.fields.Installation,
-				RateLimitConfig: tt.fields.RateLimitConfig,
-				PodExecutor:     tt.fields.PodExecutor,
-				ConfigManager:   tt.fields.ConfigManager,
+				Namespace:             namespace,
+				Installation:          installation,
+				RateLimitConfig:       tt.rateLimitConfig,
+				ShadowRateLimitConfig: tt.shadowRateLimitConfig,
+				LimitadorURL:          limitador.URL,
+			}
+			if tt.noLimitadorURL {
+				r.LimitadorURL = ""
 			}
-			got, err := r.ensureLimits(tt.args.ctx, tt.args.client)
+			got, err := r.ensureLimits(context.TODO(), tt.client)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ensureLimits() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -844,134 +1262,74 @@ func TestRateLimitServiceReconciler_ensureLimits(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("ensureLimits() got = %v, want %v", got, tt.want)
 			}
+			if deleted := limitador.deletedNamespaces(); !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("ensureLimits() deleted the limits of %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.wantPodDeleted {
+				err := tt.client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(rateLimitPod), &corev1.Pod{})
+				if !k8serrors.IsNotFound(err) {
+					t.Errorf("expected the rate limit pod to be deleted, got %v", err)
+				}
+			}
 		})
 	}
 }
 
-func TestRateLimitServiceReconciler_deleteRedisLimits(t *testing.T) {
+func TestRateLimitServiceReconciler_getLimitadorClient(t *testing.T) {
 	scheme, err := utils.NewTestScheme()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	const namespace = "redhat-test-marin3r"
-
-	configManager := &config.ConfigReadWriterMock{
-		ReadObservabilityFunc: func() (*config.Observability, error) {
-			return &config.Observability{
-				Config: config.ProductConfig{
-					"NAMESPACE": namespace,
-				},
-			}, nil
+	r := &RateLimitServiceReconciler{Namespace: "redhat-test-marin3r"}
+	client := utils.NewTestClient(scheme, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: quota.RateLimitName, Namespace: "redhat-test-marin3r"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "1.1.1.1",
+			Ports:     []corev1.ServicePort{{Name: "grpc", Port: 8081}, {Name: "http", Port: 8080}},
 		},
-	}
+	})
 
-	rateLimitService := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{Name: quota.RateLimitName, Namespace: namespace},
-		Spec:       corev1.ServiceSpec{ClusterIP: "1.1.1.1"},
+	limitadorClient, err := r.getLimitadorClient(context.TODO(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	type fields struct {
-		Namespace       string
-		RedisSecretName string
-		Installation    *integreatlyv1alpha1.RHMI
-		RateLimitConfig marin3rconfig.RateLimitConfig
-		ConfigManager   config.ConfigReadWriter
+	if url := limitadorClient.(*LimitadorClient).URL; url != "http://1.1.1.1:8080" {
+		t.Errorf("expected the http port of the rate limit service, got %s", url)
 	}
-	type args struct {
-		ctx    context.Context
-		client k8sclient.Client
+
+	if _, err := r.getLimitadorClient(context.TODO(), utils.NewTestClient(scheme)); err == nil {
+		t.Errorf("expected an error without the rate limit service")
 	}
+}
+
+func TestRateLimitServiceReconciler_deleteRedisLimits(t *testing.T) {
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    integreatlyv1alpha1.StatusPhase
-		wantErr bool
+		name         string
+		deleteStatus int
+		want         integreatlyv1alpha1.StatusPhase
+		wantErr      bool
+		wantDeleted  []string
 	}{
 		{
-			name: "test phase failed getting observability config",
-			fields: fields{
-				Installation: &integreatlyv1alpha1.RHMI{
-					Spec: integreatlyv1alpha1.RHMISpec{
-						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
-					},
-				},
-				Namespace:       namespace,
-				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
-				ConfigManager: &config.ConfigReadWriterMock{ReadObservabilityFunc: func() (*config.Observability, error) {
-					return nil, fmt.Errorf("genericError")
-				}},
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme),
-			},
-			want:    integreatlyv1alpha1.PhaseFailed,
-			wantErr: true,
-		},
-		{
-			name: "test phase failed getting rate limit service",
-			fields: fields{
-				Installation: &integreatlyv1alpha1.RHMI{
-					Spec: integreatlyv1alpha1.RHMISpec{
-						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
-					},
-				},
-				Namespace:       namespace,
-				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 1},
-				ConfigManager:   configManager,
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme),
-			},
-			want:    integreatlyv1alpha1.PhaseFailed,
-			wantErr: true,
+			name:         "test phase failed if unable to delete limits from limitador",
+			deleteStatus: http.StatusInternalServerError,
+			want:         integreatlyv1alpha1.PhaseFailed,
+			wantErr:      true,
 		},
 		{
-			name: "test phase failed if unable to delete limits from rate limit pod",
-			fields: fields{
-				Namespace:     namespace,
-				ConfigManager: configManager,
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme),
-			},
-			want:    integreatlyv1alpha1.PhaseFailed,
-			wantErr: true,
-		},
-		{
-			name: "test phase complete when successfully deleted limits",
-			fields: fields{
-				Installation: &integreatlyv1alpha1.RHMI{
-					Spec: integreatlyv1alpha1.RHMISpec{
-						Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
-					},
-				},
-				Namespace:       namespace,
-				RateLimitConfig: marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 1},
-				ConfigManager:   configManager,
-			},
-			args: args{
-				ctx:    context.TODO(),
-				client: utils.NewTestClient(scheme, rateLimitService),
-			},
-			want: integreatlyv1alpha1.PhaseCompleted,
+			name:        "test phase complete when successfully deleted limits",
+			want:        integreatlyv1alpha1.PhaseCompleted,
+			wantDeleted: []string{ratelimit.RateLimitDomain, ratelimit.RateLimitShadowDomain, ratelimit.RateLimitProviderPortalDomain, ratelimit.RateLimitDeveloperPortalDomain},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := &RateLimitServiceReconciler{
-				Namespace:       tt.fields.Namespace,
-				RedisSecretName: tt.fields.RedisSecretName,
-				Installation:    tt.fields.Installation,
-				RateLimitConfig: tt.fields.RateLimitConfig,
-				ConfigManager:   tt.fields.ConfigManager,
-			}
+			limitador := newFakeLimitador(t, nil)
+			limitador.deleteStatus = tt.deleteStatus
 
-			got, err := r.deleteRedisLimits(tt.args.ctx, tt.args.client, NewLimitadorClient(resources.PodExecutor{}, tt.fields.Namespace, ""))
+			r := &RateLimitServiceReconciler{Namespace: "redhat-test-marin3r"}
+			got, err := r.deleteRedisLimits(context.TODO(), NewLimitadorClient(limitador.URL))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("deleteRedisLimits() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -979,6 +1337,43 @@ func TestRateLimitServiceReconciler_deleteRedisLimits(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("deleteRedisLimits() got = %v, want %v", got, tt.want)
 			}
+			if deleted := limitador.deletedNamespaces(); !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("deleteRedisLimits() deleted the limits of %v, want %v", deleted, tt.wantDeleted)
+			}
 		})
 	}
 }
+
+func TestGetEnforcedRateLimit(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enforcedLimit, err := GetEnforcedRateLimit(context.TODO(), utils.NewTestClient(scheme), "test")
+	if err != nil || enforcedLimit != nil {
+		t.Fatalf("expected no rate limit before the rate limit service is configured, got %v %v", enforcedLimit, err)
+	}
+
+	client := utils.NewTestClient(scheme, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: RateLimitingConfigMapName, Namespace: "test"},
+		Data: map[string]string{
+			RateLimitingConfigMapDataName: `
+- namespace: apicast-ratelimit
+  max_value: 13860
+  seconds: 60
+  conditions:
+  - generic_key == slowpath
+  variables:
+  - generic_key
+`,
+		},
+	})
+	enforcedLimit, err = GetEnforcedRateLimit(context.TODO(), client, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enforcedLimit == nil || *enforcedLimit != (marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 13860}) {
+		t.Errorf("unexpected enforced rate limit %v", enforcedLimit)
+	}
+}
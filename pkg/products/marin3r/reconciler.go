@@ -2,18 +2,22 @@ package marin3r
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/integr8ly/integreatly-operator/pkg/products/grafana"
+	"strings"
 
 	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
 
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 	"github.com/pkg/errors"
 
+	crov1 "github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1"
 	"github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1/types"
 	croUtil "github.com/integr8ly/cloud-resource-operator/pkg/client"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/owner"
 	prometheus "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -46,11 +50,17 @@ type Reconciler struct {
 	ConfigManager   config.ConfigReadWriter
 	Config          *config.Marin3r
 	RateLimitConfig marin3rconfig.RateLimitConfig
-	AlertsConfig    map[string]*marin3rconfig.AlertConfig
-	installation    *integreatlyv1alpha1.RHMI
-	mpm             marketplace.MarketplaceInterface
-	log             l.Logger
-	recorder        record.EventRecorder
+	// ShadowRateLimitConfig is the rate limit evaluated in shadow mode, if any
+	ShadowRateLimitConfig *marin3rconfig.RateLimitConfig
+	// RateLimitStorageConfig is the storage of the rate limit counters of the quota
+	RateLimitStorageConfig marin3rconfig.RateLimitStorageConfig
+	AlertsConfig           map[string]*marin3rconfig.AlertConfig
+	// resolveRedisPrimary resolves the primary of the sentinel topology
+	resolveRedisPrimary RedisPrimaryResolver
+	installation        *integreatlyv1alpha1.RHMI
+	mpm                 marketplace.MarketplaceInterface
+	log                 l.Logger
+	recorder            record.EventRecorder
 }
 
 func (r *Reconciler) GetPreflightObject(ns string) k8sclient.Object {
@@ -88,13 +98,14 @@ func NewReconciler(configManager config.ConfigReadWriter, installation *integrea
 	}
 
 	return &Reconciler{
-		ConfigManager: configManager,
-		Config:        productConfig,
-		installation:  installation,
-		mpm:           mpm,
-		log:           logger,
-		Reconciler:    resources.NewReconciler(mpm).WithProductDeclaration(*productDeclaration),
-		recorder:      recorder,
+		ConfigManager:       configManager,
+		Config:              productConfig,
+		installation:        installation,
+		mpm:                 mpm,
+		log:                 logger,
+		Reconciler:          resources.NewReconciler(mpm).WithProductDeclaration(*productDeclaration),
+		recorder:            recorder,
+		resolveRedisPrimary: resolveSentinelPrimary,
 	}, nil
 }
 
@@ -141,7 +152,12 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, nil
 	}
 
-	r.RateLimitConfig = productConfig.GetRateLimitConfig()
+	r.RateLimitStorageConfig = productConfig.GetRateLimitStorageConfig()
+	phase, err = r.resolveRateLimits(ctx, client, productConfig.GetRateLimitConfig(), productNamespace)
+	if err != nil {
+		events.HandleError(r.recorder, installation, phase, "Failed to resolve rate limit shadow mode", err)
+		return phase, err
+	}
 
 	alertsConfig, err := marin3rconfig.GetAlertConfig(ctx, client, r.installation.Namespace)
 	if err != nil {
@@ -181,7 +197,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 	}
 
 	r.log.Info("about to start reconciling the discovery service")
-	phase, err = r.reconcileDiscoveryService(ctx, client, productNamespace)
+	discoveryServiceStatus, phase, err := r.reconcileDiscoveryService(ctx, client, productConfig)
 	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.recorder, installation, phase, "Failed to reconcile DiscoveryService cr", err)
 		return phase, err
@@ -226,8 +242,17 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, nil
 	}
 
-	phase, err = NewRateLimitServiceReconciler(r.RateLimitConfig, installation, productNamespace, externalRedisSecretName, resources.NewPodExecutor(r.log), r.ConfigManager).
-		ReconcileRateLimitService(ctx, client, productConfig)
+	responseConfig, err := marin3rconfig.GetResponseConfig(ctx, client, r.installation.Namespace)
+	if err != nil {
+		events.HandleError(r.recorder, installation, integreatlyv1alpha1.PhaseFailed, "Failed to get rate limit response configuration", err)
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	rateLimitServiceReconciler := NewRateLimitServiceReconciler(r.RateLimitConfig, installation, productNamespace, externalRedisSecretName, r.ConfigManager)
+	rateLimitServiceReconciler.ShadowRateLimitConfig = r.ShadowRateLimitConfig
+	rateLimitServiceReconciler.StorageConfig = r.RateLimitStorageConfig
+	rateLimitServiceReconciler.ResponseConfig = responseConfig
+	phase, err = rateLimitServiceReconciler.ReconcileRateLimitService(ctx, client, productConfig)
 	if err != nil {
 		events.HandleError(r.recorder, installation, phase, "Failed to reconcile rate limit service", err)
 		return phase, err
@@ -242,7 +267,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, err
 	}
 
-	alertsReconciler := r.newAlertReconciler(r.log, r.installation.Spec.Type, config.GetOboNamespace(r.installation.Namespace))
+	alertsReconciler, err := r.newAlertReconciler(r.log, r.installation.Spec.Type, config.GetOboNamespace(r.installation.Namespace))
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
 	if phase, err := alertsReconciler.ReconcileAlerts(ctx, client); err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.recorder, installation, phase, "Failed to reconcile Marin3r alerts", err)
 		return phase, err
@@ -255,6 +283,12 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, err
 	}
 
+	storageAlertsReconciler := r.newStorageAlertsReconciler(r.log, r.installation.Spec.Type, config.GetOboNamespace(r.installation.Namespace))
+	if phase, err := storageAlertsReconciler.ReconcileAlerts(ctx, client); err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+		events.HandleError(r.recorder, installation, phase, "Failed to reconcile rate limit storage alerts", err)
+		return phase, err
+	}
+
 	rejectedRequestsAlertReconciler, err := r.newRejectedRequestsAlertsReconciler(r.log, r.installation.Spec.Type, config.GetOboNamespace(r.installation.Namespace))
 	if err != nil {
 		events.HandleError(r.recorder, installation, phase, "Failed to instantiate rejected requests alert reconciler", err)
@@ -268,6 +302,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 	productStatus.Host = r.Config.GetHost()
 	productStatus.Version = r.Config.GetProductVersion()
 	productStatus.OperatorVersion = r.Config.GetOperatorVersion()
+	productStatus.Components = []integreatlyv1alpha1.ComponentSizingStatus{discoveryServiceStatus}
 
 	events.HandleProductComplete(r.recorder, installation, integreatlyv1alpha1.ProductsStage, r.Config.GetProductName())
 	r.log.Info("Installation successful")
@@ -296,26 +331,102 @@ func (r *Reconciler) reconcileAlerts(ctx context.Context, client k8sclient.Clien
 	}
 
 	grafanaDashboardURL := fmt.Sprintf("%s/d/66ab72e0d012aacf34f907be9d81cd9e/rate-limiting", grafanaConsoleURL)
-	alertReconciler, err := r.newAlertsReconciler(grafanaDashboardURL, namespace)
+	alertReconciler, statuses, err := r.newAlertsReconciler(grafanaDashboardURL, namespace)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
 
+	if err := r.reconcileAlertsStatus(ctx, client, statuses); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to reconcile rate limit alerts status: %w", err)
+	}
+
 	phase, err := alertReconciler.ReconcileAlerts(ctx, client)
 	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.recorder, installation, phase, "Failed to reconcile alerts", err)
 		return phase, err
 	}
+
+	if err := deleteRemovedScopedAlerts(ctx, client, namespace, statuses); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func (r *Reconciler) reconcileRedis(ctx context.Context, client k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
-	r.log.Info("Creating backend redis instance in marin3r reconcile")
+// reconcileAlertsStatus writes the validation result of each alert in the rate limit
+// alerts ConfigMap, so customers get feedback on their alert configuration
+func (r *Reconciler) reconcileAlertsStatus(ctx context.Context, client k8sclient.Client, statuses map[string]marin3rconfig.AlertConfigStatus) error {
+	for name, status := range statuses {
+		if !status.Valid {
+			r.log.Warningf("Invalid rate limit alert config", l.Fields{"alertName": name, "error": status.Message})
+		}
+	}
 
-	ns := r.installation.Namespace
+	statusJSON, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	statusConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      marin3rconfig.AlertStatusConfigMapName,
+			Namespace: r.installation.Namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, client, statusConfigMap, func() error {
+		owner.AddIntegreatlyOwnerAnnotations(statusConfigMap, r.installation)
+		statusConfigMap.Data = map[string]string{"alerts": string(statusJSON)}
+		return nil
+	})
+	return err
+}
 
+// deleteRemovedScopedAlerts deletes the PrometheusRules of scopes that no longer
+// have any valid alerts configured
+func deleteRemovedScopedAlerts(ctx context.Context, client k8sclient.Client, namespace string, statuses map[string]marin3rconfig.AlertConfigStatus) error {
+	current := map[string]bool{}
+	for _, status := range statuses {
+		if status.Valid {
+			current[status.PrometheusRule] = true
+		}
+	}
+
+	rules := &monv1.PrometheusRuleList{}
+	if err := client.List(ctx, rules, k8sclient.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list prometheus rules: %w", err)
+	}
+	for _, rule := range rules.Items {
+		if !strings.Contains(rule.Name, scopedAlertPrefix) || current[rule.Name] {
+			continue
+		}
+		if err := client.Delete(ctx, rule); err != nil && !k8serr.IsNotFound(err) {
+			return fmt.Errorf("failed to delete prometheus rule %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// reconcileRedis provisions the Redis of the rate limit storage, and writes its connection URL
+// in the externalRedisSecretName Secret
+func (r *Reconciler) reconcileRedis(ctx context.Context, client k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
+	ns := r.installation.Namespace
 	redisName := fmt.Sprintf("%s%s", constants.RateLimitRedisPrefix, r.installation.Name)
-	rateLimitRedis, err := croUtil.ReconcileRedis(ctx, client, defaultInstallationNamespace, r.installation.Spec.Type, croUtil.TierProduction, redisName, ns, redisName, ns, "", false, false, func(cr metav1.Object) error {
+
+	if !r.RateLimitStorageConfig.UsesStandaloneRedis() {
+		if err := r.deleteStandaloneRedis(ctx, client, redisName, ns); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+	}
+	if !r.RateLimitStorageConfig.UsesRedis() {
+		r.log.Info("Rate limit counters are stored in memory, skipping the backend redis")
+		return integreatlyv1alpha1.PhaseCompleted, nil
+	}
+	if r.RateLimitStorageConfig.GetTopology() == marin3rconfig.RedisTopologySentinel {
+		return r.reconcileSentinelRedis(ctx, client)
+	}
+
+	r.log.Info("Creating backend redis instance in marin3r reconcile")
+
+	rateLimitRedis, err := croUtil.ReconcileRedis(ctx, client, defaultInstallationNamespace, r.installation.Spec.Type, croUtil.TierProduction, redisName, ns, redisName, ns, r.RateLimitStorageConfig.Size, false, false, func(cr metav1.Object) error {
 		owner.AddIntegreatlyOwnerAnnotations(cr, r.installation)
 		return nil
 	})
@@ -337,24 +448,8 @@ func (r *Reconciler) reconcileRedis(ctx context.Context, client k8sclient.Client
 	}
 
 	// create system redis external connection secret needed for the 3scale apimanager
-	redisSecret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      externalRedisSecretName,
-			Namespace: r.Config.GetNamespace(),
-		},
-		Data: map[string][]byte{},
-	}
-	_, err = controllerutil.CreateOrUpdate(ctx, client, redisSecret, func() error {
-		uri := systemCredSec.Data["uri"]
-		port := systemCredSec.Data["port"]
-
-		conn := fmt.Sprintf("%s:%s", uri, port)
-		redisSecret.Data["URL"] = []byte(conn)
-
-		return nil
-	})
-	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed create or update redis secret: %w", err)
+	if err := r.reconcileRedisSecret(ctx, client, fmt.Sprintf("%s:%s", systemCredSec.Data["uri"], systemCredSec.Data["port"]), marin3rconfig.RedisTopologyStandalone); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
 	}
 
 	phase, err := resources.ReconcileRedisAlerts(ctx, client, r.installation, rateLimitRedis, r.log)
@@ -374,10 +469,91 @@ func (r *Reconciler) reconcileRedis(ctx context.Context, client k8sclient.Client
 	return phase, nil
 }
 
-func (r *Reconciler) reconcileDiscoveryService(ctx context.Context, client k8sclient.Client, productNamespace string) (integreatlyv1alpha1.StatusPhase, error) {
+// deleteStandaloneRedis deletes the Redis provisioned by the cloud resource operator once the
+// counters are no longer stored in it, e.g. after the quota switched to the sentinel topology.
+// Its alerts are kept until the Redis is deleted so that a failed deletion still alerts
+func (r *Reconciler) deleteStandaloneRedis(ctx context.Context, client k8sclient.Client, name, ns string) error {
+	redis := &crov1.Redis{}
+	err := client.Get(ctx, k8sclient.ObjectKey{Name: name, Namespace: ns}, redis)
+	if err != nil && !k8serr.IsNotFound(err) {
+		return fmt.Errorf("failed to get the standalone rate limit redis: %w", err)
+	}
+	if k8serr.IsNotFound(err) {
+		redis.Name = name
+		redis.Namespace = ns
+		redis.Labels = map[string]string{"productName": string(integreatlyv1alpha1.ProductMarin3r)}
+		return resources.DeleteRedisAlerts(ctx, client, r.installation, redis)
+	}
+
+	if redis.DeletionTimestamp == nil {
+		r.log.Infof("Deleting the standalone rate limit redis", l.Fields{"redis": name})
+		if err := client.Delete(ctx, redis); err != nil && !k8serr.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the standalone rate limit redis: %w", err)
+		}
+	}
+	return nil
+}
+
+// reconcileSentinelRedis writes the address of the primary currently elected by the Sentinels
+// in the externalRedisSecretName Secret, the rate limit service is redeployed on failovers.
+// Between the reconciles the failovers are followed by ReconcileSentinelPrimary
+func (r *Reconciler) reconcileSentinelRedis(ctx context.Context, client k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
+	redisURL, err := getSentinelRedisURL(ctx, client, r.Config.GetNamespace(), r.resolveRedisPrimary)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			r.log.Warningf("Waiting for the redis sentinel secret", l.Fields{"secret": marin3rconfig.RedisSentinelSecretName})
+			return integreatlyv1alpha1.PhaseAwaitingComponents, nil
+		}
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	if err := r.reconcileRedisSecret(ctx, client, redisURL, marin3rconfig.RedisTopologySentinel); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+// reconcileRedisSecret writes the connection URL of the rate limit Redis, without its scheme,
+// along with the topology of the Redis
+func (r *Reconciler) reconcileRedisSecret(ctx context.Context, client k8sclient.Client, redisURL string, topology marin3rconfig.RedisTopology) error {
+	redisSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      externalRedisSecretName,
+			Namespace: r.Config.GetNamespace(),
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, client, redisSecret, func() error {
+		if redisSecret.Annotations == nil {
+			redisSecret.Annotations = map[string]string{}
+		}
+		redisSecret.Annotations[redisTopologyAnnotation] = string(topology)
+		if redisSecret.Data == nil {
+			redisSecret.Data = map[string][]byte{}
+		}
+		redisSecret.Data["URL"] = []byte(redisURL)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed create or update redis secret: %w", err)
+	}
+	return nil
+}
+
+// reconcileDiscoveryService creates the discovery service sized by the quota, and returns its
+// effective sizing
+func (r *Reconciler) reconcileDiscoveryService(ctx context.Context, client k8sclient.Client, productConfig quota.ProductConfig) (integreatlyv1alpha1.ComponentSizingStatus, integreatlyv1alpha1.StatusPhase, error) {
 	threescaleConfig, err := r.ConfigManager.ReadThreeScale()
 	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, errors.Wrap(err, "could not read 3scale config from marin3r reconciler")
+		return integreatlyv1alpha1.ComponentSizingStatus{}, integreatlyv1alpha1.PhaseFailed, errors.Wrap(err, "could not read 3scale config from marin3r reconciler")
+	}
+
+	image := fmt.Sprintf("quay.io/3scale/marin3r:v%s", integreatlyv1alpha1.VersionMarin3r)
+	var resourceRequirements *corev1.ResourceRequirements
+	componentConfig := productConfig.GetComponentConfig(quota.DiscoveryServiceName)
+	if componentConfig.Image != "" {
+		image = componentConfig.Image
+	}
+	if len(componentConfig.Resources.Requests) > 0 || len(componentConfig.Resources.Limits) > 0 {
+		resourceRequirements = componentConfig.Resources.DeepCopy()
 	}
 
 	discoveryService := &marin3roperator.DiscoveryService{
@@ -388,17 +564,21 @@ func (r *Reconciler) reconcileDiscoveryService(ctx context.Context, client k8scl
 	}
 
 	_, err = controllerutil.CreateOrUpdate(ctx, client, discoveryService, func() error {
-		image := fmt.Sprintf("quay.io/3scale/marin3r:v%s", integreatlyv1alpha1.VersionMarin3r)
 		discoveryService.Spec.Image = &image
+		discoveryService.Spec.Resources = resourceRequirements
 		return nil
 	})
 	if err != nil {
 		if !k8serr.IsAlreadyExists(err) {
-			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("error reconciling resource: %w", err)
+			return integreatlyv1alpha1.ComponentSizingStatus{}, integreatlyv1alpha1.PhaseFailed, fmt.Errorf("error reconciling resource: %w", err)
 		}
 	}
 
-	return integreatlyv1alpha1.PhaseCompleted, nil
+	var status corev1.ResourceRequirements
+	if resourceRequirements != nil {
+		status = *resourceRequirements
+	}
+	return quota.GetComponentSizingStatus(quota.DiscoveryServiceName, image, 0, status), integreatlyv1alpha1.PhaseCompleted, nil
 }
 
 func (r *Reconciler) deleteDiscoveryService(ctx context.Context, client k8sclient.Client) error {
@@ -450,7 +630,7 @@ func (r *Reconciler) reconcileSubscription(ctx context.Context, serverClient k8s
 }
 
 func (r *Reconciler) preUpgradeBackupExecutor() backup.BackupExecutor {
-	if r.installation.Spec.UseClusterStorage != "false" {
+	if r.installation.Spec.UseClusterStorage != "false" || !r.RateLimitStorageConfig.UsesStandaloneRedis() {
 		return backup.NewNoopBackupExecutor()
 	}
 
@@ -461,6 +641,34 @@ func (r *Reconciler) preUpgradeBackupExecutor() backup.BackupExecutor {
 	)
 }
 
+// resolveRateLimits sets the rate limit to enforce and the rate limit to evaluate in shadow
+// mode. With the shadow mode enabled, the rate limit of a quota change is only enforced
+// once it is promoted in the rate-limit-shadow ConfigMap
+func (r *Reconciler) resolveRateLimits(ctx context.Context, client k8sclient.Client, quotaLimit marin3rconfig.RateLimitConfig, namespace string) (integreatlyv1alpha1.StatusPhase, error) {
+	shadowConfig, err := marin3rconfig.GetShadowConfig(ctx, client, r.installation.Namespace)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	var enforcedLimit *marin3rconfig.RateLimitConfig
+	if shadowConfig.IsEnabled() {
+		enforcedLimit, err = GetEnforcedRateLimit(ctx, client, namespace)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+	}
+
+	r.RateLimitConfig, r.ShadowRateLimitConfig = shadowConfig.ResolveRateLimits(quotaLimit, enforcedLimit)
+	if r.ShadowRateLimitConfig != nil {
+		r.log.Infof("Evaluating rate limit in shadow mode", l.Fields{
+			"enforced": fmt.Sprintf("%d per %s", r.RateLimitConfig.RequestsPerUnit, r.RateLimitConfig.Unit),
+			"shadow":   fmt.Sprintf("%d per %s", r.ShadowRateLimitConfig.RequestsPerUnit, r.ShadowRateLimitConfig.Unit),
+		})
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
 func (r *Reconciler) reconcileServiceMonitor(ctx context.Context, client k8sclient.Client, namespace string) (integreatlyv1alpha1.StatusPhase, error) {
 	r.log.Info("Start reconcileServiceMonitor for marin3r")
 
@@ -482,6 +690,21 @@ func (r *Reconciler) reconcileServiceMonitor(ctx context.Context, client k8sclie
 						Key: "",
 					},
 					Port: "http",
+					// the requests reported to the shadow limits are kept apart from the API usage
+					MetricRelabelConfigs: []*prometheus.RelabelConfig{
+						{
+							SourceLabels: []prometheus.LabelName{"__name__", "limitador_namespace"},
+							Regex:        fmt.Sprintf("limited_calls;%s", ratelimit.RateLimitShadowDomain),
+							TargetLabel:  "__name__",
+							Replacement:  ratelimit.ShadowLimitedCallsMetric,
+							Action:       "replace",
+						},
+						{
+							SourceLabels: []prometheus.LabelName{"__name__", "limitador_namespace"},
+							Regex:        fmt.Sprintf("(authorized_calls|limited_calls);%s", ratelimit.RateLimitShadowDomain),
+							Action:       "drop",
+						},
+					},
 				},
 			},
 			Selector: metav1.LabelSelector{
@@ -2,8 +2,11 @@ package marin3r
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"testing"
 
+	"github.com/integr8ly/integreatly-operator/pkg/resources/constants"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/marketplace"
 	"github.com/integr8ly/integreatly-operator/utils"
 	"k8s.io/client-go/tools/record"
@@ -11,11 +14,16 @@ import (
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
 
+	marin3roperator "github.com/3scale-ops/marin3r/apis/operator.marin3r/v1alpha1"
+	crov1 "github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1"
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	"github.com/integr8ly/integreatly-operator/pkg/config"
 	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
 	routev1 "github.com/openshift/api/route/v1"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -166,6 +174,34 @@ func TestAlertCreation(t *testing.T) {
 			Recorder:     setupRecorder(),
 			want:         integreatlyv1alpha1.PhaseCompleted,
 		},
+		{
+			name: "removes alerts of scopes no longer configured and writes the alerts status",
+			serverClient: func() k8sclient.Client {
+				staleRule := &monv1.PrometheusRule{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "marin3r-api-usage-scope-tenant-removed",
+						Namespace: getBasicInstallation().Name,
+					},
+				}
+				return utils.NewTestClient(scheme, getRateLimitConfigMap(), getGrafanaRoute(), staleRule)
+			},
+			installation: getBasicInstallation(),
+			FakeConfig:   getBasicConfig(),
+			Recorder:     setupRecorder(),
+			want:         integreatlyv1alpha1.PhaseCompleted,
+			wantFn: func(c k8sclient.Client) error {
+				rule := &monv1.PrometheusRule{}
+				err := c.Get(context.TODO(), k8sclient.ObjectKey{Name: "marin3r-api-usage-scope-tenant-removed", Namespace: getBasicInstallation().Name}, rule)
+				if !k8serr.IsNotFound(err) {
+					return fmt.Errorf("expected the stale scoped alert to be deleted, got %v", err)
+				}
+				status := &corev1.ConfigMap{}
+				if err := c.Get(context.TODO(), k8sclient.ObjectKey{Name: marin3rconfig.AlertStatusConfigMapName, Namespace: defaultInstallationNamespace}, status); err != nil {
+					return fmt.Errorf("expected the alerts status config map: %v", err)
+				}
+				return nil
+			},
+		},
 		{
 			name: "returns PhaseInProgress when grafana not installed",
 			serverClient: func() k8sclient.Client {
@@ -251,39 +287,64 @@ func TestReconcileServiceMonitor(t *testing.T) {
 }
 
 func TestReconcileDiscoveryService(t *testing.T) {
-	namespaceName := "test-namespace"
 	scheme, err := utils.NewTestScheme()
 	if err != nil {
 		t.Fatal(err)
 	}
-	client := utils.NewTestClient(scheme)
 
-	type args struct {
-		namespace string
-	}
 	tests := []struct {
-		name    string
-		args    args
-		FakeMPM *marketplace.MarketplaceInterfaceMock
-		want    integreatlyv1alpha1.StatusPhase
-		wantErr bool
+		name            string
+		componentConfig quota.ResourceConfig
+		FakeMPM         *marketplace.MarketplaceInterfaceMock
+		want            integreatlyv1alpha1.StatusPhase
+		wantErr         bool
+		wantStatus      integreatlyv1alpha1.ComponentSizingStatus
+		wantResources   *corev1.ResourceRequirements
 	}{
 		{
 			name:    "success on valid namespace",
-			args:    args{namespace: namespaceName},
 			want:    integreatlyv1alpha1.PhaseCompleted,
 			wantErr: false,
+			wantStatus: integreatlyv1alpha1.ComponentSizingStatus{
+				Name:  quota.DiscoveryServiceName,
+				Image: fmt.Sprintf("quay.io/3scale/marin3r:v%s", integreatlyv1alpha1.VersionMarin3r),
+			},
+		},
+		{
+			name: "success sizing the discovery service from the quota",
+			componentConfig: quota.ResourceConfig{
+				Image: "quay.io/example/marin3r:v1",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				},
+			},
+			want:    integreatlyv1alpha1.PhaseCompleted,
+			wantErr: false,
+			wantStatus: integreatlyv1alpha1.ComponentSizingStatus{
+				Name:     quota.DiscoveryServiceName,
+				Image:    "quay.io/example/marin3r:v1",
+				Requests: "cpu=100m",
+			},
+			wantResources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			client := utils.NewTestClient(scheme)
 			r, err := NewReconciler(getBasicConfig(), getBasicInstallation(), tt.FakeMPM, setupRecorder(), getLogger(), localProductDeclaration)
 			r.RateLimitConfig = RateLimitConfig
 			if err != nil {
 				t.Fatalf("Could not create new reconiler")
 			}
+			productConfig := &quota.ProductConfigMock{
+				GetComponentConfigFunc: func(name string) quota.ResourceConfig {
+					return tt.componentConfig
+				},
+			}
 
-			got, err := r.reconcileDiscoveryService(context.TODO(), client, tt.args.namespace)
+			status, got, err := r.reconcileDiscoveryService(context.TODO(), client, productConfig)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("reconcileDiscoveryService() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -291,6 +352,17 @@ func TestReconcileDiscoveryService(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("reconcileDiscoveryService() got = %v, want %v", got, tt.want)
 			}
+			if status != tt.wantStatus {
+				t.Errorf("reconcileDiscoveryService() status = %v, want %v", status, tt.wantStatus)
+			}
+
+			discoveryService := &marin3roperator.DiscoveryService{}
+			if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: discoveryServiceName, Namespace: "3scale"}, discoveryService); err != nil {
+				t.Fatalf("failed to get discovery service: %v", err)
+			}
+			if !reflect.DeepEqual(discoveryService.Spec.Resources, tt.wantResources) {
+				t.Errorf("reconcileDiscoveryService() resources = %v, want %v", discoveryService.Spec.Resources, tt.wantResources)
+			}
 		})
 	}
 }
@@ -298,3 +370,151 @@ func TestReconcileDiscoveryService(t *testing.T) {
 func getLogger() l.Logger {
 	return l.NewLoggerWithContext(l.Fields{l.ProductLogContext: integreatlyv1alpha1.ProductMarin3r})
 }
+
+func TestReconcileRedis_DeletesStandaloneRedis(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	installation := getBasicInstallation()
+	redisName := constants.RateLimitRedisPrefix + installation.Name
+	oboNamespace := config.GetOboNamespace(installation.Namespace)
+	rule := func(name string) *monv1.PrometheusRule {
+		return &monv1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: oboNamespace}}
+	}
+	client := utils.NewTestClient(scheme,
+		&crov1.Redis{ObjectMeta: metav1.ObjectMeta{Name: redisName, Namespace: installation.Namespace}},
+		rule("availability-rule-"+redisName),
+		rule("redis-memory-usage-high"),
+	)
+
+	r, err := NewReconciler(getBasicConfig(), installation, nil, setupRecorder(), getLogger(), localProductDeclaration)
+	if err != nil {
+		t.Fatalf("Could not create new reconiler")
+	}
+	r.RateLimitStorageConfig = marin3rconfig.RateLimitStorageConfig{Mode: marin3rconfig.StorageModeInMemory}
+
+	// The alerts of the Redis are kept until it is deleted
+	for _, wantAlerts := range []bool{true, false} {
+		if phase, err := r.reconcileRedis(context.TODO(), client); err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+			t.Fatalf("reconcileRedis() got = %v, %v", phase, err)
+		}
+		err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: redisName, Namespace: installation.Namespace}, &crov1.Redis{})
+		if !k8serr.IsNotFound(err) {
+			t.Fatalf("expected the standalone redis to be deleted, got %v", err)
+		}
+		err = client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(rule("availability-rule-"+redisName)), &monv1.PrometheusRule{})
+		if (err == nil) != wantAlerts {
+			t.Errorf("expected the redis alerts to exist %v, got %v", wantAlerts, err)
+		}
+	}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(rule("redis-memory-usage-high")), &monv1.PrometheusRule{}); err != nil {
+		t.Errorf("expected the shared redis alerts to be kept, got %v", err)
+	}
+}
+
+func TestReconcileRedis(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		storageConfig marin3rconfig.RateLimitStorageConfig
+		sentinelData  map[string][]byte
+		resolver      RedisPrimaryResolver
+		want          integreatlyv1alpha1.StatusPhase
+		wantErr       bool
+		wantURL       string
+	}{
+		{
+			name:          "in memory storage does not provision redis",
+			storageConfig: marin3rconfig.RateLimitStorageConfig{Mode: marin3rconfig.StorageModeInMemory},
+			want:          integreatlyv1alpha1.PhaseCompleted,
+		},
+		{
+			name:          "sentinel topology waits for the sentinel secret",
+			storageConfig: marin3rconfig.RateLimitStorageConfig{Topology: marin3rconfig.RedisTopologySentinel},
+			want:          integreatlyv1alpha1.PhaseAwaitingComponents,
+		},
+		{
+			name:          "sentinel topology fails without the master name",
+			storageConfig: marin3rconfig.RateLimitStorageConfig{Topology: marin3rconfig.RedisTopologySentinel},
+			sentinelData:  map[string][]byte{marin3rconfig.RedisSentinelAddressesKey: []byte("sentinel-0:26379")},
+			want:          integreatlyv1alpha1.PhaseFailed,
+			wantErr:       true,
+		},
+		{
+			name:          "sentinel topology fails when the primary can not be resolved",
+			storageConfig: marin3rconfig.RateLimitStorageConfig{Topology: marin3rconfig.RedisTopologySentinel},
+			sentinelData: map[string][]byte{
+				marin3rconfig.RedisSentinelAddressesKey:  []byte("sentinel-0:26379"),
+				marin3rconfig.RedisSentinelMasterNameKey: []byte("ratelimit"),
+			},
+			resolver: func(context.Context, []string, string, string) (string, error) {
+				return "", fmt.Errorf("no sentinel reachable")
+			},
+			want:    integreatlyv1alpha1.PhaseFailed,
+			wantErr: true,
+		},
+		{
+			name:          "sentinel topology writes the url of the primary",
+			storageConfig: marin3rconfig.RateLimitStorageConfig{Topology: marin3rconfig.RedisTopologySentinel},
+			sentinelData: map[string][]byte{
+				marin3rconfig.RedisSentinelAddressesKey:  []byte("sentinel-0:26379,sentinel-1:26379"),
+				marin3rconfig.RedisSentinelMasterNameKey: []byte("ratelimit"),
+				marin3rconfig.RedisSentinelPasswordKey:   []byte("secret"),
+			},
+			resolver: func(_ context.Context, sentinels []string, masterName, password string) (string, error) {
+				if len(sentinels) != 2 || masterName != "ratelimit" || password != "secret" {
+					return "", fmt.Errorf("unexpected sentinel settings %v %s %s", sentinels, masterName, password)
+				}
+				return "10.0.0.1:6379", nil
+			},
+			want:    integreatlyv1alpha1.PhaseCompleted,
+			wantURL: ":secret@10.0.0.1:6379",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReconciler(getBasicConfig(), getBasicInstallation(), nil, setupRecorder(), getLogger(), localProductDeclaration)
+			if err != nil {
+				t.Fatalf("Could not create new reconiler")
+			}
+			r.RateLimitStorageConfig = tt.storageConfig
+			r.resolveRedisPrimary = tt.resolver
+
+			client := utils.NewTestClient(scheme)
+			if tt.sentinelData != nil {
+				client = utils.NewTestClient(scheme, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: marin3rconfig.RedisSentinelSecretName, Namespace: r.Config.GetNamespace()},
+					Data:       tt.sentinelData,
+				})
+			}
+
+			got, err := r.reconcileRedis(context.TODO(), client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("reconcileRedis() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("reconcileRedis() got = %v, want %v", got, tt.want)
+			}
+
+			redisSecret := &corev1.Secret{}
+			err = client.Get(context.TODO(), k8sclient.ObjectKey{Name: externalRedisSecretName, Namespace: r.Config.GetNamespace()}, redisSecret)
+			if tt.wantURL == "" {
+				if !k8serr.IsNotFound(err) {
+					t.Errorf("expected no redis secret, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get redis secret: %v", err)
+			}
+			if string(redisSecret.Data["URL"]) != tt.wantURL {
+				t.Errorf("expected redis url %s, got %s", tt.wantURL, redisSecret.Data["URL"])
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package marin3r
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SentinelPollInterval is how often ReconcileSentinelPrimary resolves the primary
+	SentinelPollInterval = 30 * time.Second
+
+	sentinelTimeout = 5 * time.Second
+	// redisTopologyAnnotation holds the topology of the Redis of the externalRedisSecretName Secret
+	redisTopologyAnnotation = "integreatly.org/redis-topology"
+)
+
+// RedisPrimaryResolver returns the host:port address of the primary monitored by the Sentinels
+// under the master name
+type RedisPrimaryResolver func(ctx context.Context, sentinels []string, masterName, password string) (string, error)
+
+var _ RedisPrimaryResolver = resolveSentinelPrimary
+
+// resolveSentinelPrimary asks each Sentinel in turn for the address of the primary, the first
+// Sentinel which knows it is trusted
+func resolveSentinelPrimary(ctx context.Context, sentinels []string, masterName, password string) (string, error) {
+	if len(sentinels) == 0 {
+		return "", fmt.Errorf("no sentinels to resolve the redis primary %s from", masterName)
+	}
+
+	var errs []string
+	for _, sentinel := range sentinels {
+		primary, err := querySentinelPrimary(ctx, strings.TrimSpace(sentinel), masterName, password)
+		if err == nil {
+			return primary, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", sentinel, err))
+	}
+	return "", fmt.Errorf("failed to resolve the redis primary %s: %s", masterName, strings.Join(errs, "; "))
+}
+
+// ReconcileSentinelPrimary follows the failovers of the sentinel topology between the reconciles
+// of the installation, which only resolve the primary every resync period. When the Sentinels
+// elected another primary, its address is written in the externalRedisSecretName Secret and the
+// rate limit service is redeployed against it. Nothing is done for the other topologies
+func ReconcileSentinelPrimary(ctx context.Context, client k8sclient.Client, namespace string) error {
+	redisSecret := &corev1.Secret{}
+	if err := client.Get(ctx, k8sclient.ObjectKey{Name: externalRedisSecretName, Namespace: namespace}, redisSecret); err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get redis secret: %w", err)
+	}
+	if redisSecret.Annotations[redisTopologyAnnotation] != string(marin3rconfig.RedisTopologySentinel) {
+		return nil
+	}
+
+	redisURL, err := getSentinelRedisURL(ctx, client, namespace, resolveSentinelPrimary)
+	if err != nil {
+		return err
+	}
+	if string(redisSecret.Data["URL"]) == redisURL {
+		return nil
+	}
+
+	redisSecret.Data["URL"] = []byte(redisURL)
+	if err := client.Update(ctx, redisSecret); err != nil {
+		return fmt.Errorf("failed to update redis secret: %w", err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := client.Get(ctx, k8sclient.ObjectKey{Name: quota.RateLimitName, Namespace: namespace}, deployment); err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get the rate limit service: %w", err)
+	}
+	for i, container := range deployment.Spec.Template.Spec.Containers {
+		for j, env := range container.Env {
+			if env.Name == redisURLEnv {
+				deployment.Spec.Template.Spec.Containers[i].Env[j].Value = getRedisURLEnvValue(redisURL)
+			}
+		}
+	}
+	if err := client.Update(ctx, deployment); err != nil {
+		return fmt.Errorf("failed to redeploy the rate limit service: %w", err)
+	}
+	return nil
+}
+
+// getSentinelRedisURL returns the connection URL of the primary elected by the Sentinels of the
+// RedisSentinelSecretName Secret, without its scheme
+func getSentinelRedisURL(ctx context.Context, client k8sclient.Client, namespace string, resolve RedisPrimaryResolver) (string, error) {
+	sentinelSecret := &corev1.Secret{}
+	err := client.Get(ctx, k8sclient.ObjectKey{Name: marin3rconfig.RedisSentinelSecretName, Namespace: namespace}, sentinelSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to get redis sentinel secret: %w", err)
+	}
+
+	sentinels := strings.Split(string(sentinelSecret.Data[marin3rconfig.RedisSentinelAddressesKey]), ",")
+	masterName := string(sentinelSecret.Data[marin3rconfig.RedisSentinelMasterNameKey])
+	password := string(sentinelSecret.Data[marin3rconfig.RedisSentinelPasswordKey])
+	if masterName == "" || sentinels[0] == "" {
+		return "", fmt.Errorf("the %s secret must set the %s and %s keys", marin3rconfig.RedisSentinelSecretName, marin3rconfig.RedisSentinelAddressesKey, marin3rconfig.RedisSentinelMasterNameKey)
+	}
+
+	primary, err := resolve(ctx, sentinels, masterName, password)
+	if err != nil {
+		return "", err
+	}
+	if password != "" {
+		primary = fmt.Sprintf("%s@%s", url.UserPassword("", password).String(), primary)
+	}
+	return primary, nil
+}
+
+func querySentinelPrimary(ctx context.Context, sentinel, masterName, password string) (string, error) {
+	dialer := &net.Dialer{Timeout: sentinelTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", sentinel)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(sentinelTimeout)); err != nil {
+		return "", err
+	}
+
+	reader := bufio.NewReader(conn)
+	if password != "" {
+		if _, err := sendRedisCommand(conn, reader, "AUTH", password); err != nil {
+			return "", fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	reply, err := sendRedisCommand(conn, reader, "SENTINEL", "get-master-addr-by-name", masterName)
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("unknown redis primary %s", masterName)
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+// sendRedisCommand sends the command with the Redis serialization protocol and returns its
+// reply, which is expected to be a status, a bulk string or an array of bulk strings
+func sendRedisCommand(conn net.Conn, reader *bufio.Reader, args ...string) ([]string, error) {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(command.String())); err != nil {
+		return nil, err
+	}
+
+	line, err := readRedisLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	switch line[0] {
+	case '+':
+		return []string{line[1:]}, nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case '$':
+		value, err := readRedisBulkString(reader, line)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return []string{*value}, nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array reply %q", line)
+		}
+		reply := []string{}
+		for i := 0; i < length; i++ {
+			header, err := readRedisLine(reader)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readRedisBulkString(reader, header)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				reply = append(reply, *value)
+			}
+		}
+		return reply, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
+
+// readRedisBulkString reads the bulk string of the header, nil is returned for null bulk strings
+func readRedisBulkString(reader *bufio.Reader, header string) (*string, error) {
+	if header[0] != '$' {
+		return nil, fmt.Errorf("unexpected redis reply %q, expected a bulk string", header)
+	}
+	length, err := strconv.Atoi(header[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis bulk string reply %q", header)
+	}
+	if length < 0 {
+		return nil, nil
+	}
+	value, err := readRedisLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(value) != length {
+		return nil, fmt.Errorf("invalid redis bulk string of length %d: %q", length, value)
+	}
+	return &value, nil
+}
+
+func readRedisLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("empty redis reply")
+	}
+	return line, nil
+}
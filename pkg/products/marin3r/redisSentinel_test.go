@@ -0,0 +1,222 @@
+package marin3r
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	moqclient "github.com/integr8ly/integreatly-operator/pkg/client"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
+	"github.com/integr8ly/integreatly-operator/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeSentinel answers the AUTH and SENTINEL get-master-addr-by-name commands
+func fakeSentinel(t *testing.T, password string, primaries map[string][2]string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readFakeCommand(reader)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(args[0]) {
+					case "AUTH":
+						if args[1] != password {
+							fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+							continue
+						}
+						fmt.Fprint(conn, "+OK\r\n")
+					case "SENTINEL":
+						primary, ok := primaries[args[2]]
+						if !ok {
+							fmt.Fprint(conn, "*-1\r\n")
+							continue
+						}
+						fmt.Fprintf(conn, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(primary[0]), primary[0], len(primary[1]), primary[1])
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func readFakeCommand(reader *bufio.Reader) ([]string, error) {
+	header, err := readRedisLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	if _, err := fmt.Sscanf(header, "*%d", &length); err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		argHeader, err := readRedisLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		arg, err := readRedisBulkString(reader, argHeader)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, *arg)
+	}
+	return args, nil
+}
+
+func TestResolveSentinelPrimary(t *testing.T) {
+	sentinel := fakeSentinel(t, "secret", map[string][2]string{"ratelimit": {"10.0.0.1", "6379"}})
+
+	// An unreachable sentinel is skipped
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachableAddress := unreachable.Addr().String()
+	_ = unreachable.Close()
+
+	primary, err := resolveSentinelPrimary(context.TODO(), []string{unreachableAddress, sentinel}, "ratelimit", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primary != "10.0.0.1:6379" {
+		t.Errorf("expected primary 10.0.0.1:6379, got %s", primary)
+	}
+
+	if _, err := resolveSentinelPrimary(context.TODO(), []string{sentinel}, "ratelimit", "wrong"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+	if _, err := resolveSentinelPrimary(context.TODO(), []string{sentinel}, "unknown", "secret"); err == nil || !strings.Contains(err.Error(), "unknown redis primary") {
+		t.Errorf("expected an unknown primary error, got %v", err)
+	}
+	if _, err := resolveSentinelPrimary(context.TODO(), nil, "ratelimit", ""); err == nil {
+		t.Errorf("expected an error without sentinels")
+	}
+}
+
+func TestReconcileSentinelPrimary(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const namespace = "redhat-test-marin3r"
+	sentinel := fakeSentinel(t, "", map[string][2]string{"ratelimit": {"10.0.0.2", "6379"}})
+
+	redisSecret := func(topology marin3rconfig.RedisTopology) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        externalRedisSecretName,
+				Namespace:   namespace,
+				Annotations: map[string]string{redisTopologyAnnotation: string(topology)},
+			},
+			Data: map[string][]byte{"URL": []byte("10.0.0.1:6379")},
+		}
+	}
+	sentinelSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: marin3rconfig.RedisSentinelSecretName, Namespace: namespace},
+		Data: map[string][]byte{
+			marin3rconfig.RedisSentinelAddressesKey:  []byte(sentinel),
+			marin3rconfig.RedisSentinelMasterNameKey: []byte("ratelimit"),
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: quota.RateLimitName, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: quota.RateLimitName,
+						Env:  []corev1.EnvVar{{Name: redisURLEnv, Value: "redis://10.0.0.1:6379"}},
+					}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		topology marin3rconfig.RedisTopology
+		wantURL  string
+	}{
+		{
+			name:     "standalone topology is left to the reconciler",
+			topology: marin3rconfig.RedisTopologyStandalone,
+			wantURL:  "10.0.0.1:6379",
+		},
+		{
+			name:     "sentinel topology follows the failover",
+			topology: marin3rconfig.RedisTopologySentinel,
+			wantURL:  "10.0.0.2:6379",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := utils.NewTestClient(scheme, redisSecret(tt.topology), sentinelSecret, deployment.DeepCopy())
+
+			if err := ReconcileSentinelPrimary(context.TODO(), client, namespace); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			secret := &corev1.Secret{}
+			if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: externalRedisSecretName, Namespace: namespace}, secret); err != nil {
+				t.Fatal(err)
+			}
+			if string(secret.Data["URL"]) != tt.wantURL {
+				t.Errorf("expected redis url %s, got %s", tt.wantURL, secret.Data["URL"])
+			}
+			got := &appsv1.Deployment{}
+			if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(deployment), got); err != nil {
+				t.Fatal(err)
+			}
+			if env := got.Spec.Template.Spec.Containers[0].Env[0].Value; env != "redis://"+tt.wantURL {
+				t.Errorf("expected the rate limit service to use redis://%s, got %s", tt.wantURL, env)
+			}
+		})
+	}
+}
+
+func TestReconcileSentinelPrimary_redisSecretNotRead(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rate limit service without an external redis has no redis secret
+	if err := ReconcileSentinelPrimary(context.TODO(), utils.NewTestClient(scheme), "redhat-test-marin3r"); err != nil {
+		t.Errorf("unexpected error without a redis secret: %v", err)
+	}
+
+	client := moqclient.NewSigsClientMoqWithScheme(scheme)
+	client.GetFunc = func(ctx context.Context, key types.NamespacedName, obj k8sclient.Object, opts ...k8sclient.GetOption) error {
+		return errors.New("test error")
+	}
+	if err := ReconcileSentinelPrimary(context.TODO(), client, "redhat-test-marin3r"); err == nil {
+		t.Error("expected an error when the redis secret cannot be read")
+	}
+}
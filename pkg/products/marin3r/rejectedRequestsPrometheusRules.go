@@ -6,6 +6,7 @@ import (
 
 	"github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
 	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
 	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -36,7 +37,7 @@ func (r *Reconciler) newRejectedRequestsAlertsReconciler(logger l.Logger, instal
 				Namespace: ns,
 				Rules: []monv1.Rule{
 					{
-						Alert: "RHOAMApiUsageRejectedRequestsMismatch",
+						Alert: ratelimit.RejectedRequestsAlertName,
 						Annotations: map[string]string{
 							"message": "The volume of rejected requests doesn't match the expected volume given the incoming requests and the configuration",
 						},
@@ -0,0 +1,52 @@
+package marin3r
+
+import (
+	"fmt"
+
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	inMemoryStorageAlertName = "marin3r-ratelimit-in-memory-storage"
+
+	inMemoryCountersResetExpr = "increase(kube_pod_container_status_restarts_total{namespace='%s', container='ratelimit'}[10m]) > 0"
+)
+
+// newStorageAlertsReconciler returns the alerts of the storage mode of the rate limit counters,
+// the alerts of the other storage modes are removed. The alerts of the standalone Redis are
+// reconciled along with it. The in memory storage runs a single replica, see
+// marin3rconfig.RateLimitStorageConfig.Validate
+func (r *Reconciler) newStorageAlertsReconciler(logger l.Logger, installType, ns string) resources.AlertReconciler {
+	installationName := resources.InstallationNames[installType]
+
+	inMemoryAlert := resources.AlertConfiguration{
+		AlertName: inMemoryStorageAlertName,
+		GroupName: "ratelimit-storage.rules",
+		Namespace: ns,
+		Rules: []monv1.Rule{
+			{
+				Alert: "RHOAMRateLimitInMemoryCountersReset",
+				Annotations: map[string]string{
+					"message": "The rate limit service restarted and lost its in memory counters, the API usage of the current windows is not limited accurately",
+				},
+				Expr:   intstr.FromString(fmt.Sprintf(inMemoryCountersResetExpr, r.Config.GetNamespace())),
+				Labels: map[string]string{"severity": "info", "product": installationName},
+			},
+		},
+	}
+
+	alertReconciler := &resources.AlertReconcilerImpl{
+		ProductName:  "Marin3r",
+		Installation: r.installation,
+		Log:          logger,
+	}
+	if r.RateLimitStorageConfig.UsesRedis() {
+		alertReconciler.RemovedAlerts = []resources.AlertConfiguration{inMemoryAlert}
+	} else {
+		alertReconciler.Alerts = []resources.AlertConfiguration{inMemoryAlert}
+	}
+	return alertReconciler
+}
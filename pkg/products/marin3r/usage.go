@@ -0,0 +1,312 @@
+package marin3r
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/integreatly-operator/pkg/metrics"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// UsageConfigMapName holds the API usage rolled up per day under the UsageDailyKey key and
+	// per month under the UsageMonthlyKey key, as JSON objects of the requests per usage key
+	// per period, e.g. {"2024-01-31": {"generic_key == slowpath": 1200}}. The rollups are a lower
+	// bound of the usage: the requests counted in a window after its last read, or while the
+	// counters can not be read, are not rolled up. The ConfigMap is annotated with
+	// UsageAccuracyAnnotation to tell its readers
+	UsageConfigMapName = "rate-limit-usage"
+	UsageDailyKey      = "daily"
+	UsageMonthlyKey    = "monthly"
+	// UsageAccuracyAnnotation is set to UsageAccuracyLowerBound on the UsageConfigMapName ConfigMap
+	UsageAccuracyAnnotation = "integreatly.org/usage-accuracy"
+	UsageAccuracyLowerBound = "lower-bound"
+	// usageWindowsKey holds the last observation of each counter
+	usageWindowsKey = "windows"
+
+	usageDayLayout       = "2006-01-02"
+	usageMonthLayout     = "2006-01"
+	usageRetentionDays   = 31
+	usageRetentionMonths = 12
+
+	// MinUsagePollInterval and MaxUsagePollInterval bound how often the counters are read
+	MinUsagePollInterval = 10 * time.Second
+	MaxUsagePollInterval = time.Minute
+)
+
+// usageRollup is the number of requests per usage key per period
+type usageRollup map[string]map[string]int64
+
+// usageWindow is the last observation of a Limitador counter, so the requests counted in a
+// window are only rolled up once across reads
+type usageWindow struct {
+	// End is the unix time the window of the counter expires at
+	End  int64 `json:"end"`
+	Used int64 `json:"used"`
+}
+
+// ReconcileUsage reads the counters of the Limitador limits, exports their usage as the
+// rhoam_ratelimit_usage metrics and rolls it up per day and per month in the
+// UsageConfigMapName ConfigMap. It returns when the counters should be read next, the requests
+// counted in a window after its last read are not rolled up, so the counters are read at least
+// twice per window of the shortest limit
+func ReconcileUsage(ctx context.Context, client k8sclient.Client, namespace string, now time.Time) (time.Duration, error) {
+	counters, err := getUsageCounters(ctx, client, namespace)
+	if err != nil {
+		return MinUsagePollInterval, fmt.Errorf("failed to get rate limit counters: %w", err)
+	}
+
+	samples := make([]metrics.RateLimitUsageSample, 0, len(counters))
+	for _, counter := range counters {
+		samples = append(samples, metrics.RateLimitUsageSample{
+			Limit:    strings.Join(counter.Limit.Conditions, ","),
+			Tenant:   counter.SetVariables[headerKey],
+			Used:     getCounterUsed(counter.limitadorCounter),
+			MaxValue: int64(counter.Limit.MaxValue),
+		})
+	}
+	metrics.SetRateLimitUsage(samples)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      UsageConfigMapName,
+			Namespace: namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, client, configMap, func() error {
+		if configMap.Annotations == nil {
+			configMap.Annotations = map[string]string{}
+		}
+		configMap.Annotations[UsageAccuracyAnnotation] = UsageAccuracyLowerBound
+		data, err := rollUpUsage(configMap.Data, counters, now)
+		if err != nil {
+			return err
+		}
+		configMap.Data = data
+		return nil
+	})
+	if err != nil {
+		return MinUsagePollInterval, fmt.Errorf("failed to roll up rate limit usage: %w", err)
+	}
+
+	return getUsagePollInterval(counters), nil
+}
+
+// usageCounter is a Limitador counter read from a replica of the rate limit service
+type usageCounter struct {
+	limitadorCounter
+	// Replica is the pod the counter is kept in, empty when the replicas share the counters
+	Replica string
+}
+
+// getUsageCounters reads the counters from the Limitador pods directly, as the rate limit
+// service would balance the reads across them. The counters are read from every pod when they
+// are kept in memory, and from the first ready pod when they are shared in Redis
+func getUsageCounters(ctx context.Context, client k8sclient.Client, namespace string) ([]usageCounter, error) {
+	pods := &corev1.PodList{}
+	if err := client.List(ctx, pods, k8sclient.InNamespace(namespace), k8sclient.MatchingLabels{"app": quota.RateLimitName}); err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+
+	var counters []usageCounter
+	for _, pod := range pods.Items {
+		limitadorURL := getPodLimitadorURL(pod)
+		if pod.Status.Phase != corev1.PodRunning || limitadorURL == "" {
+			continue
+		}
+		podCounters, err := NewLimitadorClient(limitadorURL).GetCounters(ctx, ratelimit.RateLimitDomain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the counters of pod %s: %w", pod.Name, err)
+		}
+
+		inMemory := !usesRedisStorage(pod)
+		for _, counter := range podCounters {
+			usage := usageCounter{limitadorCounter: counter}
+			if inMemory {
+				usage.Replica = pod.Name
+			}
+			counters = append(counters, usage)
+		}
+		if !inMemory {
+			return counters, nil
+		}
+	}
+	return counters, nil
+}
+
+// getPodLimitadorURL returns the URL of the Limitador HTTP API of the pod, empty when the pod
+// has no IP yet
+func getPodLimitadorURL(pod corev1.Pod) string {
+	if pod.Status.PodIP == "" {
+		return ""
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == "http" {
+				return fmt.Sprintf("http://%s:%d", pod.Status.PodIP, port.ContainerPort)
+			}
+		}
+	}
+	return ""
+}
+
+// usesRedisStorage returns whether the Limitador pod keeps its counters in Redis, see getStorageEnvs
+func usesRedisStorage(pod corev1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == "REDIS_URL" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getUsagePollInterval returns half the window of the shortest limit, bounded by the
+// MinUsagePollInterval and MaxUsagePollInterval
+func getUsagePollInterval(counters []usageCounter) time.Duration {
+	interval := MaxUsagePollInterval
+	for _, counter := range counters {
+		if window := time.Duration(counter.Limit.Seconds) * time.Second / 2; window < interval {
+			interval = window
+		}
+	}
+	if interval < MinUsagePollInterval {
+		return MinUsagePollInterval
+	}
+	return interval
+}
+
+// rollUpUsage adds the requests counted since the previous observation of the counters to the
+// daily and monthly rollups of the data. The requests counted after the last observation of a
+// window are missed, so the rollups only ever undercount the usage
+func rollUpUsage(data map[string]string, counters []usageCounter, now time.Time) (map[string]string, error) {
+	daily := usageRollup{}
+	monthly := usageRollup{}
+	windows := map[string]usageWindow{}
+	for key, v := range map[string]interface{}{UsageDailyKey: &daily, UsageMonthlyKey: &monthly, usageWindowsKey: &windows} {
+		if data[key] == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(data[key]), v); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s usage: %w", key, err)
+		}
+	}
+
+	now = now.UTC()
+	day := now.Format(usageDayLayout)
+	month := now.Format(usageMonthLayout)
+
+	observed := map[string]usageWindow{}
+	for _, counter := range counters {
+		id := getCounterID(counter)
+		window := usageWindow{
+			End:  now.Unix() + counter.ExpiresInSeconds,
+			Used: getCounterUsed(counter.limitadorCounter),
+		}
+
+		requests := window.Used
+		if previous, ok := windows[id]; ok && isSameUsageWindow(previous, window, counter.Limit.Seconds) {
+			requests = window.Used - previous.Used
+		}
+		observed[id] = window
+		if requests <= 0 {
+			continue
+		}
+
+		key := getUsageKey(counter.limitadorCounter)
+		daily.add(day, key, requests)
+		monthly.add(month, key, requests)
+	}
+
+	daily.prune(usageRetentionDays)
+	monthly.prune(usageRetentionMonths)
+
+	rolledUp := map[string]string{}
+	for key, v := range map[string]interface{}{UsageDailyKey: daily, UsageMonthlyKey: monthly, usageWindowsKey: observed} {
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s usage: %w", key, err)
+		}
+		rolledUp[key] = string(value)
+	}
+	return rolledUp, nil
+}
+
+func (u usageRollup) add(period, key string, requests int64) {
+	if u[period] == nil {
+		u[period] = map[string]int64{}
+	}
+	u[period][key] += requests
+}
+
+// prune keeps the latest periods of the rollup, the period layouts sort chronologically
+func (u usageRollup) prune(retention int) {
+	periods := make([]string, 0, len(u))
+	for period := range u {
+		periods = append(periods, period)
+	}
+	if len(periods) <= retention {
+		return
+	}
+	sort.Strings(periods)
+	for _, period := range periods[:len(periods)-retention] {
+		delete(u, period)
+	}
+}
+
+// isSameUsageWindow returns whether both observations of a counter belong to the same window,
+// allowing for the rounding of the expiry of the counter to seconds
+func isSameUsageWindow(previous, current usageWindow, seconds uint64) bool {
+	tolerance := int64(seconds / 2)
+	if tolerance > 2 {
+		tolerance = 2
+	}
+	diff := current.End - previous.End
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance && current.Used >= previous.Used
+}
+
+func getCounterUsed(counter limitadorCounter) int64 {
+	used := int64(counter.Limit.MaxValue) - counter.Remaining
+	if used < 0 {
+		return 0
+	}
+	return used
+}
+
+// getUsageKey returns the key the usage of the counter is rolled up under, the tenant in
+// multitenant installations and the conditions of the limit otherwise
+func getUsageKey(counter limitadorCounter) string {
+	if tenant := counter.SetVariables[headerKey]; tenant != "" {
+		return fmt.Sprintf("%s=%s", headerKey, tenant)
+	}
+	return strings.Join(counter.Limit.Conditions, ",")
+}
+
+// getCounterID identifies the counter of a limit for a set of descriptor values in a replica
+func getCounterID(counter usageCounter) string {
+	variables := make([]string, 0, len(counter.SetVariables))
+	for name, value := range counter.SetVariables {
+		variables = append(variables, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(variables)
+
+	id := fmt.Sprintf("%s|%d|%s", strings.Join(counter.Limit.Conditions, ","), counter.Limit.Seconds, strings.Join(variables, ","))
+	if counter.Replica != "" {
+		return counter.Replica + "|" + id
+	}
+	return id
+}
@@ -0,0 +1,210 @@
+package marin3r
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReconcileUsage(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globalLimit := limitadorLimit{
+		Namespace:  ratelimit.RateLimitDomain,
+		MaxValue:   100,
+		Seconds:    60,
+		Conditions: []string{"generic_key == slowpath"},
+		Variables:  []string{"generic_key"},
+	}
+	tenantLimit := limitadorLimit{
+		Namespace:  ratelimit.RateLimitDomain,
+		MaxValue:   20,
+		Seconds:    60,
+		Conditions: []string{"header_match == per-mt-limit"},
+		Variables:  []string{"tenant"},
+	}
+
+	limitador := newFakeLimitador(t, nil)
+	limitador.counters[ratelimit.RateLimitDomain] = []limitadorCounter{
+		{Limit: globalLimit, SetVariables: map[string]string{"generic_key": "slowpath"}, Remaining: 70, ExpiresInSeconds: 40},
+		{Limit: tenantLimit, SetVariables: map[string]string{"tenant": "tenant-a"}, Remaining: 15, ExpiresInSeconds: 40},
+	}
+	// the pods share the counters in Redis, so only the first one is read
+	unreachable := newFakeLimitador(t, nil)
+	unreachable.status = http.StatusInternalServerError
+	client := utils.NewTestClient(scheme,
+		limitadorPod(t, "ratelimit-a", limitador.URL, true),
+		limitadorPod(t, "ratelimit-b", unreachable.URL, true),
+	)
+	now := time.Date(2024, time.January, 31, 23, 59, 0, 0, time.UTC)
+
+	interval, err := ReconcileUsage(context.TODO(), client, "redhat-test-marin3r", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interval != 30*time.Second {
+		t.Errorf("expected the counters to be read twice per window, got %s", interval)
+	}
+	assertUsage(t, client, UsageDailyKey, usageRollup{
+		"2024-01-31": {"generic_key == slowpath": 30, "tenant=tenant-a": 5},
+	})
+	configMap := &corev1.ConfigMap{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: UsageConfigMapName, Namespace: "redhat-test-marin3r"}, configMap); err != nil {
+		t.Fatalf("failed to get usage ConfigMap: %v", err)
+	}
+	if configMap.Annotations[UsageAccuracyAnnotation] != UsageAccuracyLowerBound {
+		t.Errorf("expected the usage to be annotated as a lower bound, got %v", configMap.Annotations)
+	}
+
+	// The same windows observed again only add the new requests
+	limitador.counters[ratelimit.RateLimitDomain][0].Remaining = 60
+	limitador.counters[ratelimit.RateLimitDomain][0].ExpiresInSeconds = 30
+	limitador.counters[ratelimit.RateLimitDomain][1].ExpiresInSeconds = 30
+	if _, err := ReconcileUsage(context.TODO(), client, "redhat-test-marin3r", now.Add(10*time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertUsage(t, client, UsageDailyKey, usageRollup{
+		"2024-01-31": {"generic_key == slowpath": 40, "tenant=tenant-a": 5},
+	})
+
+	// A new window on the next day adds all its requests to the next day and month
+	limitador.counters[ratelimit.RateLimitDomain] = limitador.counters[ratelimit.RateLimitDomain][:1]
+	limitador.counters[ratelimit.RateLimitDomain][0].Remaining = 90
+	limitador.counters[ratelimit.RateLimitDomain][0].ExpiresInSeconds = 50
+	if _, err := ReconcileUsage(context.TODO(), client, "redhat-test-marin3r", now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertUsage(t, client, UsageDailyKey, usageRollup{
+		"2024-01-31": {"generic_key == slowpath": 40, "tenant=tenant-a": 5},
+		"2024-02-01": {"generic_key == slowpath": 10},
+	})
+	assertUsage(t, client, UsageMonthlyKey, usageRollup{
+		"2024-01": {"generic_key == slowpath": 40, "tenant=tenant-a": 5},
+		"2024-02": {"generic_key == slowpath": 10},
+	})
+
+	limitador.status = http.StatusInternalServerError
+	if _, err := ReconcileUsage(context.TODO(), client, "redhat-test-marin3r", now); err == nil {
+		t.Errorf("expected an error when the counters can not be read")
+	}
+}
+
+func TestReconcileUsage_InMemoryReplicas(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globalLimit := limitadorLimit{
+		Namespace:  ratelimit.RateLimitDomain,
+		MaxValue:   100,
+		Seconds:    1,
+		Conditions: []string{"generic_key == slowpath"},
+		Variables:  []string{"generic_key"},
+	}
+	var pods []runtime.Object
+	for _, replica := range []struct {
+		name      string
+		remaining int64
+	}{{"ratelimit-a", 90}, {"ratelimit-b", 80}} {
+		limitador := newFakeLimitador(t, nil)
+		limitador.counters[ratelimit.RateLimitDomain] = []limitadorCounter{
+			{Limit: globalLimit, SetVariables: map[string]string{"generic_key": "slowpath"}, Remaining: replica.remaining, ExpiresInSeconds: 1},
+		}
+		pods = append(pods, limitadorPod(t, replica.name, limitador.URL, false))
+	}
+	client := utils.NewTestClient(scheme, pods...)
+
+	interval, err := ReconcileUsage(context.TODO(), client, "redhat-test-marin3r", time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if interval != MinUsagePollInterval {
+		t.Errorf("expected the poll interval to be bounded, got %s", interval)
+	}
+	assertUsage(t, client, UsageDailyKey, usageRollup{
+		"2024-01-31": {"generic_key == slowpath": 30},
+	})
+}
+
+// limitadorPod returns a running rate limit pod serving the Limitador API at the URL
+func limitadorPod(t *testing.T, name, limitadorURL string, redis bool) *corev1.Pod {
+	t.Helper()
+
+	parsed, err := url.Parse(limitadorURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(parsed.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := corev1.Container{
+		Name:  quota.RateLimitName,
+		Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: int32(port)}},
+	}
+	if redis {
+		container.Env = []corev1.EnvVar{{Name: "REDIS_URL", Value: "redis://test-url"}}
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "redhat-test-marin3r",
+			Labels:    map[string]string{"app": quota.RateLimitName},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{container}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: parsed.Hostname(),
+		},
+	}
+}
+
+func TestUsageRollup_prune(t *testing.T) {
+	rollup := usageRollup{
+		"2024-01-30": {"generic_key == slowpath": 1},
+		"2024-01-31": {"generic_key == slowpath": 2},
+		"2024-02-01": {"generic_key == slowpath": 3},
+	}
+	rollup.prune(2)
+
+	if !reflect.DeepEqual(rollup, usageRollup{
+		"2024-01-31": {"generic_key == slowpath": 2},
+		"2024-02-01": {"generic_key == slowpath": 3},
+	}) {
+		t.Errorf("expected the oldest period to be pruned, got %v", rollup)
+	}
+}
+
+func assertUsage(t *testing.T, client k8sclient.Client, key string, want usageRollup) {
+	t.Helper()
+
+	configMap := &corev1.ConfigMap{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: UsageConfigMapName, Namespace: "redhat-test-marin3r"}, configMap); err != nil {
+		t.Fatalf("failed to get usage ConfigMap: %v", err)
+	}
+	got := usageRollup{}
+	if err := json.Unmarshal([]byte(configMap.Data[key]), &got); err != nil {
+		t.Fatalf("failed to unmarshal %s usage: %v", key, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %s usage %v, got %v", key, want, got)
+	}
+}
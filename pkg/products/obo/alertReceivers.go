@@ -0,0 +1,167 @@
+package obo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	receiverSecretURLKey        = "url"
+	receiverSecretTokenKey      = "token"
+	receiverSecretChannelKey    = "channel"
+	receiverSecretSeveritiesKey = "severities"
+
+	defaultReceiverSeverities = "critical"
+)
+
+var (
+	severityRegexp = regexp.MustCompile(`^[a-z]+$`)
+	// Values are rendered into single quoted YAML strings in the alertmanager config
+	unsafeValueRegexp = regexp.MustCompile(`['\s\\]`)
+	// receiverConfigMinVersions is the first Alertmanager version supporting each receiver
+	// config, the configs missing are supported by every version shipped
+	receiverConfigMinVersions = map[string]string{
+		"msteams_configs": "v0.26.0",
+	}
+)
+
+// alertReceiver is an optional receiver configured from a secret referenced on the RHMI spec
+type alertReceiver struct {
+	// name of the receiver in the alertmanager config, also prefixes the template params
+	name       string
+	secretName string
+	// configKey is the receiver config the receiver is rendered as
+	configKey    string
+	httpsOnly    bool
+	allowToken   bool
+	allowChannel bool
+}
+
+func getAlertReceivers(installation *integreatlyv1alpha1.RHMI) []alertReceiver {
+	return []alertReceiver{
+		{name: "Webhook", secretName: installation.Spec.WebhookSecret, configKey: "webhook_configs", allowToken: true},
+		{name: "Slack", secretName: installation.Spec.SlackSecret, configKey: "slack_configs", httpsOnly: true, allowChannel: true},
+		{name: "Teams", secretName: installation.Spec.TeamsSecret, configKey: "msteams_configs", httpsOnly: true},
+	}
+}
+
+// isReceiverConfigSupported returns whether the receiver config is supported by the
+// Alertmanager version
+func isReceiverConfigSupported(configKey, alertManagerVersion string) (bool, error) {
+	minVersion, ok := receiverConfigMinVersions[configKey]
+	if !ok {
+		return true, nil
+	}
+	version, err := semver.NewVersion(alertManagerVersion)
+	if err != nil {
+		return false, fmt.Errorf("invalid alertmanager version %s: %w", alertManagerVersion, err)
+	}
+	return !version.LessThan(semver.MustParse(minVersion)), nil
+}
+
+// getAlertReceiverParams returns the alertmanager template params of the optional receivers
+// referenced on the RHMI spec. An error is returned if a referenced secret is missing or
+// malformed, or the receiver is not supported by the Alertmanager version, so an invalid
+// configuration is never written
+func getAlertReceiverParams(ctx context.Context, serverClient k8sclient.Client, installation *integreatlyv1alpha1.RHMI, alertManagerVersion string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, receiver := range getAlertReceivers(installation) {
+		if receiver.secretName == "" {
+			continue
+		}
+
+		supported, err := isReceiverConfigSupported(receiver.configKey, alertManagerVersion)
+		if err != nil {
+			return nil, err
+		}
+		if !supported {
+			return nil, fmt.Errorf("%s receiver requires Alertmanager %s or later, the installed Alertmanager is %s",
+				strings.ToLower(receiver.name), receiverConfigMinVersions[receiver.configKey], alertManagerVersion)
+		}
+
+		secret := &corev1.Secret{}
+		if err := serverClient.Get(ctx, types.NamespacedName{Name: receiver.secretName, Namespace: installation.Namespace}, secret); err != nil {
+			return nil, fmt.Errorf("could not obtain %s receiver secret: %w", strings.ToLower(receiver.name), err)
+		}
+
+		receiverParams, err := receiver.validate(secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s receiver secret %s: %w", strings.ToLower(receiver.name), receiver.secretName, err)
+		}
+		for key, value := range receiverParams {
+			params[receiver.name+key] = value
+		}
+	}
+	return params, nil
+}
+
+func (r alertReceiver) validate(secret *corev1.Secret) (map[string]string, error) {
+	params := map[string]string{}
+
+	rawURL := strings.TrimSpace(string(secret.Data[receiverSecretURLKey]))
+	if rawURL == "" {
+		return nil, fmt.Errorf("%s is required", receiverSecretURLKey)
+	}
+	parsedURL, err := url.ParseRequestURI(rawURL)
+	if err != nil || parsedURL.Host == "" || unsafeValueRegexp.MatchString(rawURL) {
+		return nil, fmt.Errorf("%s is not a valid URL", receiverSecretURLKey)
+	}
+	if parsedURL.Scheme != "https" && (r.httpsOnly || parsedURL.Scheme != "http") {
+		return nil, fmt.Errorf("%s has unsupported scheme %s", receiverSecretURLKey, parsedURL.Scheme)
+	}
+	params["URL"] = rawURL
+
+	if token := string(secret.Data[receiverSecretTokenKey]); token != "" {
+		if !r.allowToken {
+			return nil, fmt.Errorf("%s is not supported", receiverSecretTokenKey)
+		}
+		if unsafeValueRegexp.MatchString(token) {
+			return nil, fmt.Errorf("%s must not contain whitespace, quotes or backslashes", receiverSecretTokenKey)
+		}
+		params["Token"] = token
+	}
+
+	if channel := strings.TrimSpace(string(secret.Data[receiverSecretChannelKey])); channel != "" {
+		if !r.allowChannel {
+			return nil, fmt.Errorf("%s is not supported", receiverSecretChannelKey)
+		}
+		if !(strings.HasPrefix(channel, "#") || strings.HasPrefix(channel, "@")) || unsafeValueRegexp.MatchString(channel) {
+			return nil, fmt.Errorf("%s must start with # or @ and must not contain whitespace or quotes", receiverSecretChannelKey)
+		}
+		params["Channel"] = channel
+	}
+
+	severities, err := parseSeverities(string(secret.Data[receiverSecretSeveritiesKey]))
+	if err != nil {
+		return nil, err
+	}
+	params["Severities"] = severities
+
+	return params, nil
+}
+
+// parseSeverities converts a comma separated list of severities into the regular
+// expression matched against the severity label by the receiver route
+func parseSeverities(list string) (string, error) {
+	if strings.TrimSpace(list) == "" {
+		list = defaultReceiverSeverities
+	}
+	var severities []string
+	for _, severity := range strings.Split(list, ",") {
+		severity = strings.TrimSpace(severity)
+		if !severityRegexp.MatchString(severity) {
+			return "", fmt.Errorf("invalid severity %q", severity)
+		}
+		severities = append(severities, severity)
+	}
+	return strings.Join(severities, "|"), nil
+}
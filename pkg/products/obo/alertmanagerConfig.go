@@ -32,7 +32,7 @@ const (
 	openShiftConsoleRoute     = "console"
 	openShiftConsoleNamespace = "openshift-console"
 
-	alertManagerServiceName = "rhoam-alertmanager"
+	alertManagerServiceName = config.AlertManagerServiceName
 )
 
 func getSmtpHost(smtpSecret *corev1.Secret) string {
@@ -103,6 +103,13 @@ func ReconcileAlertManagerSecrets(ctx context.Context, serverClient k8sclient.Cl
 		log.Warningf("Could not get DMS secret", l.Fields{"error": err.Error()})
 	}
 
+	// Get the optional webhook, Slack and Teams receivers
+	alertManagerVersion := config.NewObservability(config.ProductConfig{}).GetAlertManagerVersion()
+	receiverParams, err := getAlertReceiverParams(ctx, serverClient, installation, alertManagerVersion)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
 	// only set the to address to a real value for managed deployments
 	smtpToSREAddress := alertmanagerRoute
 	smtpToSREAddressCRVal := installation.Spec.AlertingEmailAddresses.CSSRE
@@ -147,7 +154,7 @@ func ReconcileAlertManagerSecrets(ctx context.Context, serverClient k8sclient.Cl
 	clusterID := string(clusterVersion.Spec.ClusterID)
 
 	// parse the config template into a secret object
-	templateUtil := NewTemplateHelper(map[string]string{
+	templateParams := map[string]string{
 		"SMTPHost":              getSmtpHost(smtpSecret),
 		"SMTPPort":              getSmtpPort(smtpSecret),
 		"SMTPFrom":              smtpAlertFromAddress,
@@ -163,7 +170,13 @@ func ReconcileAlertManagerSecrets(ctx context.Context, serverClient k8sclient.Cl
 		"clusterName":           clusterName,
 		"clusterConsole":        clusterConsoleRoute,
 		"html":                  `{{ template "email.integreatly.html" . }}`,
-	})
+		// Addresses configured on rate limit alerts, see marin3rconfig.RecipientsLabel
+		"Recipients": `{{ .CommonLabels.recipients }}`,
+	}
+	for key, value := range receiverParams {
+		templateParams[key] = value
+	}
+	templateUtil := NewTemplateHelper(templateParams)
 
 	templatePath := GetTemplatePath()
 	path := fmt.Sprintf("%s/%s", templatePath, config.AlertManagerCustomTemplatePath)
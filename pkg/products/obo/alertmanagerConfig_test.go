@@ -5,9 +5,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/ghodss/yaml"
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	moqclient "github.com/integr8ly/integreatly-operator/pkg/client"
 	"github.com/integr8ly/integreatly-operator/pkg/config"
@@ -16,6 +19,7 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -170,6 +174,7 @@ func TestReconciler_reconcileAlertManagerSecrets(t *testing.T) {
 		"clusterName":           clusterName,
 		"clusterConsole":        clusterConsoleRoute,
 		"html":                  `{{ template "email.integreatly.html" . }}`,
+		"Recipients":            `{{ .CommonLabels.recipients }}`,
 	})
 
 	templatePath := GetTemplatePath()
@@ -347,6 +352,7 @@ func TestReconciler_reconcileAlertManagerSecrets(t *testing.T) {
 					"clusterName":           clusterName,
 					"clusterConsole":        clusterConsoleRoute,
 					"html":                  `{{ template "email.integreatly.html" . }}`,
+					"Recipients":            `{{ .CommonLabels.recipients }}`,
 				})
 
 				templatePath := GetTemplatePath()
@@ -845,3 +851,329 @@ func Test_getSmtpPassword(t *testing.T) {
 	}
 
 }
+
+func TestReconciler_getAlertReceiverParams(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiverSecret := func(name string, data map[string]string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: defaultInstallationNamespace,
+			},
+			Data: map[string][]byte{},
+		}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return secret
+	}
+
+	tests := []struct {
+		name                string
+		installation        func() *integreatlyv1alpha1.RHMI
+		secrets             []runtime.Object
+		alertManagerVersion string
+		want                map[string]string
+		wantErr             bool
+	}{
+		{
+			name:         "no receivers configured",
+			installation: basicInstallation,
+			want:         map[string]string{},
+		},
+		{
+			name: "webhook, slack and teams receivers",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.WebhookSecret = "webhook"
+				installation.Spec.SlackSecret = "slack"
+				installation.Spec.TeamsSecret = "teams"
+				return installation
+			},
+			secrets: []runtime.Object{
+				receiverSecret("webhook", map[string]string{"url": "http://incident.example.com/alerts", "token": "abc123"}),
+				receiverSecret("slack", map[string]string{"url": "https://hooks.slack.com/services/T/B/X", "channel": "#rhoam-oncall", "severities": "critical, warning"}),
+				receiverSecret("teams", map[string]string{"url": "https://example.webhook.office.com/webhookb2/x"}),
+			},
+			alertManagerVersion: "v0.26.0",
+			want: map[string]string{
+				"WebhookURL":        "http://incident.example.com/alerts",
+				"WebhookToken":      "abc123",
+				"WebhookSeverities": "critical",
+				"SlackURL":          "https://hooks.slack.com/services/T/B/X",
+				"SlackChannel":      "#rhoam-oncall",
+				"SlackSeverities":   "critical|warning",
+				"TeamsURL":          "https://example.webhook.office.com/webhookb2/x",
+				"TeamsSeverities":   "critical",
+			},
+		},
+		{
+			name: "teams is rejected by alertmanager versions before v0.26.0",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.TeamsSecret = "teams"
+				return installation
+			},
+			secrets:             []runtime.Object{receiverSecret("teams", map[string]string{"url": "https://example.webhook.office.com/webhookb2/x"})},
+			alertManagerVersion: "v0.25.1",
+			wantErr:             true,
+		},
+		{
+			name: "referenced secret is missing",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.WebhookSecret = "webhook"
+				return installation
+			},
+			wantErr: true,
+		},
+		{
+			name: "slack requires https",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.SlackSecret = "slack"
+				return installation
+			},
+			secrets: []runtime.Object{receiverSecret("slack", map[string]string{"url": "http://hooks.slack.com/services/T/B/X"})},
+			wantErr: true,
+		},
+		{
+			name: "url is required",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.TeamsSecret = "teams"
+				return installation
+			},
+			secrets: []runtime.Object{receiverSecret("teams", map[string]string{"severities": "critical"})},
+			wantErr: true,
+		},
+		{
+			name: "token with quotes is rejected",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.WebhookSecret = "webhook"
+				return installation
+			},
+			secrets: []runtime.Object{receiverSecret("webhook", map[string]string{"url": "https://incident.example.com", "token": "a'b"})},
+			wantErr: true,
+		},
+		{
+			name: "invalid severity is rejected",
+			installation: func() *integreatlyv1alpha1.RHMI {
+				installation := basicInstallation()
+				installation.Spec.WebhookSecret = "webhook"
+				return installation
+			},
+			secrets: []runtime.Object{receiverSecret("webhook", map[string]string{"url": "https://incident.example.com", "severities": "critical|info"})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alertManagerVersion := tt.alertManagerVersion
+			if alertManagerVersion == "" {
+				alertManagerVersion = config.NewObservability(config.ProductConfig{}).GetAlertManagerVersion()
+			}
+			got, err := getAlertReceiverParams(context.TODO(), utils.NewTestClient(scheme, tt.secrets...), tt.installation(), alertManagerVersion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getAlertReceiverParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getAlertReceiverParams() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertManagerConfigTemplate_receivers(t *testing.T) {
+	templateUtil := NewTemplateHelper(map[string]string{
+		"SMTPHost":          "smtp.example.com",
+		"SMTPPort":          "587",
+		"SMTPFrom":          mockAlertFromAddress,
+		"SMTPToSREAddress":  mockAlertingEmailAddress,
+		"WebhookURL":        "https://incident.example.com/alerts",
+		"WebhookToken":      "abc123",
+		"WebhookSeverities": "critical|warning",
+		"SlackURL":          "https://hooks.slack.com/services/T/B/X",
+		"SlackSeverities":   "critical",
+	})
+	configData, err := templateUtil.LoadTemplate(config.AlertManagerConfigTemplatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alertmanagerConfig := struct {
+		Route struct {
+			Routes []map[string]interface{} `json:"routes"`
+		} `json:"route"`
+		Receivers []map[string]interface{} `json:"receivers"`
+	}{}
+	if err := yaml.Unmarshal(configData, &alertmanagerConfig); err != nil {
+		t.Fatalf("rendered config is not valid YAML: %v\n%s", err, configData)
+	}
+
+	routes := alertmanagerConfig.Route.Routes
+	if routes[0]["receiver"] != "webhook" || routes[1]["receiver"] != "slack" || routes[0]["continue"] != true {
+		t.Errorf("expected receiver routes to be evaluated first and continue, got %v", routes[:2])
+	}
+	if routes[2]["receiver"] != "critical" {
+		t.Errorf("expected existing routes to follow, got %v", routes[2])
+	}
+
+	var receiverNames []string
+	for _, receiver := range alertmanagerConfig.Receivers {
+		receiverNames = append(receiverNames, receiver["name"].(string))
+	}
+	if !strings.Contains(strings.Join(receiverNames, ","), "webhook,slack") || strings.Contains(strings.Join(receiverNames, ","), "teams") {
+		t.Errorf("unexpected receivers %v", receiverNames)
+	}
+}
+
+// TestAlertManagerConfigTemplate_shippedVersion renders the config with every receiver
+// enabled and checks each receiver config rendered is supported by the shipped Alertmanager
+func TestAlertManagerConfigTemplate_shippedVersion(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shippedVersion := config.NewObservability(config.ProductConfig{}).GetAlertManagerVersion()
+
+	installation := basicInstallation()
+	installation.Spec.WebhookSecret = "webhook"
+	installation.Spec.SlackSecret = "slack"
+	installation.Spec.TeamsSecret = "teams"
+	var secrets []runtime.Object
+	for _, name := range []string{"webhook", "slack", "teams"} {
+		secrets = append(secrets, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultInstallationNamespace},
+			Data:       map[string][]byte{"url": []byte("https://" + name + ".example.com/alerts")},
+		})
+	}
+
+	params, err := getAlertReceiverParams(context.TODO(), utils.NewTestClient(scheme, secrets...), installation, shippedVersion)
+	teamsSupported, versionErr := isReceiverConfigSupported("msteams_configs", shippedVersion)
+	if versionErr != nil {
+		t.Fatal(versionErr)
+	}
+	if !teamsSupported {
+		if err == nil {
+			t.Fatalf("expected the teams receiver to be rejected by Alertmanager %s", shippedVersion)
+		}
+		// Render the receivers the shipped version supports
+		installation.Spec.TeamsSecret = ""
+		params, err = getAlertReceiverParams(context.TODO(), utils.NewTestClient(scheme, secrets...), installation, shippedVersion)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	params["SMTPHost"] = "smtp.example.com"
+	params["SMTPPort"] = "587"
+	configData, err := NewTemplateHelper(params).LoadTemplate(config.AlertManagerConfigTemplatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alertmanagerConfig := struct {
+		Receivers []map[string]interface{} `json:"receivers"`
+	}{}
+	if err := yaml.Unmarshal(configData, &alertmanagerConfig); err != nil {
+		t.Fatalf("rendered config is not valid YAML: %v\n%s", err, configData)
+	}
+
+	for _, receiver := range alertmanagerConfig.Receivers {
+		for key := range receiver {
+			if !strings.HasSuffix(key, "_configs") {
+				continue
+			}
+			supported, err := isReceiverConfigSupported(key, shippedVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !supported {
+				t.Errorf("receiver %s renders %s, which Alertmanager %s does not support", receiver["name"], key, shippedVersion)
+			}
+		}
+	}
+}
+
+// TestAlertManagerConfigTemplate_recipientsRoute checks the recipients of a rate limit alert are
+// notified as well as the receivers the alert is routed to without recipients
+func TestAlertManagerConfigTemplate_recipientsRoute(t *testing.T) {
+	configData, err := NewTemplateHelper(map[string]string{
+		"SMTPHost":              "smtp.example.com",
+		"SMTPPort":              "587",
+		"SMTPFrom":              mockAlertFromAddress,
+		"SMTPToSREAddress":      mockAlertingEmailAddress,
+		"SMTPToCustomerAddress": mockCustomerAlertingEmailAddress,
+		"Recipients":            `{{ .CommonLabels.recipients }}`,
+	}).LoadTemplate(config.AlertManagerConfigTemplatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alertmanagerConfig := struct {
+		Route struct {
+			Routes []map[string]interface{} `json:"routes"`
+		} `json:"route"`
+	}{}
+	if err := yaml.Unmarshal(configData, &alertmanagerConfig); err != nil {
+		t.Fatalf("rendered config is not valid YAML: %v\n%s", err, configData)
+	}
+
+	tests := []struct {
+		labels    map[string]string
+		receivers []string
+	}{
+		{
+			labels:    map[string]string{"alertname": "RHOAMApiUsageLevel1ThresholdExceeded", "severity": "warning", "recipients": "ops@example.com"},
+			receivers: []string{"recipients", "BUandCustomer"},
+		},
+		{
+			labels:    map[string]string{"alertname": "RHOAMApiUsageOverLimit", "severity": "warning", "recipients": "ops@example.com"},
+			receivers: []string{"recipients", "BUandCustomer"},
+		},
+		{
+			labels:    map[string]string{"alertname": "RHOAMApiUsageLevel2ThresholdExceeded", "severity": "warning"},
+			receivers: []string{"BUandCustomer"},
+		},
+	}
+	for _, tt := range tests {
+		if receivers := matchingReceivers(t, alertmanagerConfig.Route.Routes, tt.labels); !reflect.DeepEqual(receivers, tt.receivers) {
+			t.Errorf("expected %v to be sent to %v, got %v", tt.labels, tt.receivers, receivers)
+		}
+	}
+}
+
+// matchingReceivers returns the receivers of the routes an alert matches, the first matching
+// route stops the evaluation unless it continues
+func matchingReceivers(t *testing.T, routes []map[string]interface{}, labels map[string]string) []string {
+	var receivers []string
+	for _, route := range routes {
+		matches := true
+		if match, ok := route["match"].(map[string]interface{}); ok {
+			for name, value := range match {
+				matches = matches && labels[name] == value
+			}
+		}
+		if matchRe, ok := route["match_re"].(map[string]interface{}); ok {
+			for name, value := range matchRe {
+				re, err := regexp.Compile("^(?:" + value.(string) + ")$")
+				if err != nil {
+					t.Fatal(err)
+				}
+				matches = matches && re.MatchString(labels[name])
+			}
+		}
+		if !matches {
+			continue
+		}
+		receivers = append(receivers, route["receiver"].(string))
+		if route["continue"] != true {
+			break
+		}
+	}
+	return receivers
+}
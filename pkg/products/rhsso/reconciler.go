@@ -15,6 +15,7 @@ import (
 	"github.com/integr8ly/integreatly-operator/pkg/config"
 	"github.com/integr8ly/integreatly-operator/pkg/products/rhssocommon"
 	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/events"
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/marketplace"
@@ -57,6 +58,10 @@ const (
 	SSOLabelValue  = "integreatly"
 	RHSSOProfile   = "RHSSO"
 	multiTenantCPU = 2000
+
+	// Audit reasons for the deletion of Keycloak users
+	removedUserReason  = "user removed from OpenShift or no longer in an active IDP"
+	excludedUserReason = "user excluded by the sync rules"
 )
 
 type Reconciler struct {
@@ -238,7 +243,12 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, err
 	}
 
-	phase, err = r.newAlertsReconciler(r.Log, r.Installation.Spec.Type, config.GetOboNamespace(r.Installation.Namespace)).ReconcileAlerts(ctx, serverClient)
+	alertsReconciler, err := r.newAlertsReconciler(r.Log, r.Installation.Spec.Type, config.GetOboNamespace(r.Installation.Namespace))
+	if err != nil {
+		events.HandleError(r.Recorder, installation, integreatlyv1alpha1.PhaseFailed, "Failed to get alerts reconciler", err)
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	phase, err = alertsReconciler.ReconcileAlerts(ctx, serverClient)
 	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.Recorder, installation, phase, "Failed to reconcile alerts", err)
 		return phase, err
@@ -415,31 +425,30 @@ func (r *Reconciler) reconcileComponents(ctx context.Context, installation *inte
 		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to sync openshift idp client secret: %w", err)
 	}
 
-	// Get all currently existing keycloak users
-	keycloakUsers, err := GetKeycloakUsers(ctx, serverClient, r.Config.GetNamespace())
-	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to list the keycloak users: %w", err)
-	}
-
-	// Sync keycloak with openshift users
-	users, err := syncronizeWithOpenshiftUsers(ctx, keycloakUsers, serverClient, r.Config.GetNamespace(), r.Installation, r.Log)
-	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to synchronize the users: %w", err)
-	}
+	// Users are propagated by the user sync controller when it is running
+	if !userHelper.IsIncrementalSyncEnabled() {
+		// Get all currently existing keycloak users
+		keycloakUsers, err := GetKeycloakUsers(ctx, serverClient, r.Config.GetNamespace())
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to list the keycloak users: %w", err)
+		}
 
-	// Create / update the synchronized users
-	for _, user := range users {
-		or, conflictFound, err := r.createOrUpdateKeycloakUser(ctx, user, serverClient)
+		// Sync keycloak with openshift users
+		users, err := syncronizeWithOpenshiftUsers(ctx, keycloakUsers, serverClient, r.Config.GetNamespace(), r.Installation, r.Log)
 		if err != nil {
-			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to create/update the customer admin user: %w", err)
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to synchronize the users: %w", err)
 		}
-		r.Log.Infof("Operation result", l.Fields{"keycloakuser": user.UserName, "result": or})
 
-		if conflictFound && integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(installation.Spec.Type)) {
-			r.Log.Infof("Conflict error found", l.Fields{"keycloak-user": user.UserName})
-			err := authenticated.DeleteUser(user.UserName, keycloakRealmName)
+		// Create / update the synchronized users
+		for _, user := range users {
+			or, conflictFound, err := createOrUpdateKeycloakUser(ctx, user, serverClient, r.Config.GetNamespace())
 			if err != nil {
-				r.Log.Error(fmt.Sprintf("failed to delete keycloak-user %s using the keycloak authenticated client", user.UserName), err)
+				return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to create/update the customer admin user: %w", err)
+			}
+			r.Log.Infof("Operation result", l.Fields{"keycloakuser": user.UserName, "result": or})
+
+			if conflictFound {
+				deleteConflictingUser(authenticated, installation, user.UserName, r.Log)
 			}
 		}
 	}
@@ -622,15 +631,17 @@ func (r *Reconciler) setupGithubIDP(ctx context.Context, kc *keycloak.Keycloak,
 	return nil
 }
 
-func getUserDiff(keycloakUsers []keycloak.KeycloakAPIUser, openshiftUsers []usersv1.User, groups *usersv1.GroupList) (added []usersv1.User, deleted []keycloak.KeycloakAPIUser) {
+func getUserDiff(keycloakUsers []keycloak.KeycloakAPIUser, openshiftUsers []usersv1.User, groups *usersv1.GroupList, syncRules *userHelper.SyncRules) (added []usersv1.User, deleted []keycloak.KeycloakAPIUser) {
 	for _, osUser := range openshiftUsers {
-		if !kcContainsOsUser(keycloakUsers, osUser) && !userHelper.IsInExclusionGroup(osUser, groups) {
+		if !kcContainsOsUser(keycloakUsers, osUser) && !syncRules.IsExcluded(osUser, groups) {
 			added = append(added, osUser)
 		}
 	}
 
 	for _, kcUser := range keycloakUsers {
-		if !rhssocommon.OsUserInKc(openshiftUsers, kcUser) {
+		// Users removed from OpenShift, or excluded by the sync rules after being synchronised
+		osUser := getOpenshiftUserByName(openshiftUsers, kcUser.UserName)
+		if osUser == nil || syncRules.IsExcluded(*osUser, groups) {
 			deleted = append(deleted, kcUser)
 		}
 	}
@@ -638,6 +649,28 @@ func getUserDiff(keycloakUsers []keycloak.KeycloakAPIUser, openshiftUsers []user
 	return added, deleted
 }
 
+// splitDeletedUsers separates the users removed from OpenShift or no longer in an active
+// IDP from the users excluded by the sync rules, so each deletion is audited with its reason
+func splitDeletedUsers(deletedUsers []keycloak.KeycloakAPIUser, openshiftUsers []usersv1.User) (removed []keycloak.KeycloakAPIUser, excluded []keycloak.KeycloakAPIUser) {
+	for _, kcUser := range deletedUsers {
+		if getOpenshiftUserByName(openshiftUsers, kcUser.UserName) == nil {
+			removed = append(removed, kcUser)
+		} else {
+			excluded = append(excluded, kcUser)
+		}
+	}
+	return removed, excluded
+}
+
+func getOpenshiftUserByName(openshiftUsers []usersv1.User, userName string) *usersv1.User {
+	for i := range openshiftUsers {
+		if openshiftUsers[i].Name == userName {
+			return &openshiftUsers[i]
+		}
+	}
+	return nil
+}
+
 func syncronizeWithOpenshiftUsers(ctx context.Context, keycloakUsers []keycloak.KeycloakAPIUser, serverClient k8sclient.Client, ns string, installation *integreatlyv1alpha1.RHMI, logger l.Logger) ([]keycloak.KeycloakAPIUser, error) {
 	var openshiftUsers *usersv1.UserList
 	var err error
@@ -653,9 +686,19 @@ func syncronizeWithOpenshiftUsers(ctx context.Context, keycloakUsers []keycloak.
 		return nil, err
 	}
 
-	added, deletedUsers := getUserDiff(keycloakUsers, openshiftUsers.Items, groups)
+	syncRules, err := userHelper.GetSyncRules(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		return nil, err
+	}
 
-	keycloakUsers, err = rhssocommon.DeleteKeycloakUsers(keycloakUsers, deletedUsers, ns, ctx, serverClient)
+	added, deletedUsers := getUserDiff(keycloakUsers, openshiftUsers.Items, groups, syncRules)
+
+	removedUsers, excludedUsers := splitDeletedUsers(deletedUsers, openshiftUsers.Items)
+	keycloakUsers, err = rhssocommon.DeleteKeycloakUsers(keycloakUsers, removedUsers, ns, removedUserReason, ctx, serverClient)
+	if err != nil {
+		return nil, err
+	}
+	keycloakUsers, err = rhssocommon.DeleteKeycloakUsers(keycloakUsers, excludedUsers, ns, excludedUserReason, ctx, serverClient)
 	if err != nil {
 		return nil, err
 	}
@@ -676,22 +719,7 @@ func syncronizeWithOpenshiftUsers(ctx context.Context, keycloakUsers []keycloak.
 			}
 		}
 
-		newKeycloakUser := keycloak.KeycloakAPIUser{
-			Enabled:       true,
-			UserName:      osUser.Name,
-			EmailVerified: true,
-			Email:         email,
-			FederatedIdentities: []keycloak.FederatedIdentity{
-				{
-					IdentityProvider: idpAlias,
-					UserID:           string(osUser.UID),
-					UserName:         osUser.Name,
-				},
-			},
-		}
-		userHelper.AppendUpdateProfileActionForUserWithoutEmail(&newKeycloakUser)
-
-		keycloakUsers = append(keycloakUsers, newKeycloakUser)
+		keycloakUsers = append(keycloakUsers, newKeycloakUser(osUser, email))
 	}
 
 	if err != nil && !k8serr.IsNotFound(err) {
@@ -704,6 +732,25 @@ func syncronizeWithOpenshiftUsers(ctx context.Context, keycloakUsers []keycloak.
 	return keycloakUsers, nil
 }
 
+func newKeycloakUser(osUser usersv1.User, email string) keycloak.KeycloakAPIUser {
+	newKeycloakUser := keycloak.KeycloakAPIUser{
+		Enabled:       true,
+		UserName:      osUser.Name,
+		EmailVerified: true,
+		Email:         email,
+		FederatedIdentities: []keycloak.FederatedIdentity{
+			{
+				IdentityProvider: idpAlias,
+				UserID:           string(osUser.UID),
+				UserName:         osUser.Name,
+			},
+		},
+	}
+	userHelper.AppendUpdateProfileActionForUserWithoutEmail(&newKeycloakUser)
+
+	return newKeycloakUser
+}
+
 func kcContainsOsUser(kcUsers []keycloak.KeycloakAPIUser, osUser usersv1.User) bool {
 	for _, kcu := range kcUsers {
 		if kcu.UserName == osUser.Name {
@@ -714,12 +761,12 @@ func kcContainsOsUser(kcUsers []keycloak.KeycloakAPIUser, osUser usersv1.User) b
 	return false
 }
 
-func (r *Reconciler) createOrUpdateKeycloakUser(ctx context.Context, user keycloak.KeycloakAPIUser, serverClient k8sclient.Client) (controllerutil.OperationResult, bool, error) {
+func createOrUpdateKeycloakUser(ctx context.Context, user keycloak.KeycloakAPIUser, serverClient k8sclient.Client, ns string) (controllerutil.OperationResult, bool, error) {
 	conflictFound := false
 	kcUser := &keycloak.KeycloakUser{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      userHelper.GetValidGeneratedUserName(user),
-			Namespace: r.Config.GetNamespace(),
+			Namespace: ns,
 		},
 	}
 
@@ -727,7 +774,12 @@ func (r *Reconciler) createOrUpdateKeycloakUser(ctx context.Context, user keyclo
 		return "", false, fmt.Errorf("failed to get valid generated username")
 	}
 
+	var before *keycloak.KeycloakAPIUser
 	op, err := controllerutil.CreateOrUpdate(ctx, serverClient, kcUser, func() error {
+		if kcUser.ResourceVersion != "" {
+			existing := kcUser.Spec.User
+			before = &existing
+		}
 		kcUser.Spec.RealmSelector = &metav1.LabelSelector{
 			MatchLabels: GetInstanceLabels(),
 		}
@@ -738,6 +790,13 @@ func (r *Reconciler) createOrUpdateKeycloakUser(ctx context.Context, user keyclo
 		}
 		return nil
 	})
+	if op != controllerutil.OperationResultNone || err != nil {
+		action := audit.ActionUpdate
+		if before == nil {
+			action = audit.ActionCreate
+		}
+		rhssocommon.RecordKeycloakUserChange(ctx, ns, action, "openshift user synchronised", before, &user, err)
+	}
 
 	return op, conflictFound, err
 }
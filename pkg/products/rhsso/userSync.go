@@ -0,0 +1,175 @@
+package rhsso
+
+import (
+	"context"
+	"fmt"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/products/rhssocommon"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
+	keycloak "github.com/integr8ly/keycloak-client/apis/keycloak/v1alpha1"
+	keycloakCommon "github.com/integr8ly/keycloak-client/pkg/common"
+	usersv1 "github.com/openshift/api/user/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// OperationResultDeleted is returned by SyncOpenshiftUser when the keycloak user was removed
+	OperationResultDeleted controllerutil.OperationResult = "deleted"
+)
+
+// SyncOpenshiftUsers performs a full synchronisation of every OpenShift user into the
+// openshift realm. It is used by the user sync controller as a periodic safety net
+// for any events missed by the incremental sync. The authenticated client is used to
+// resolve conflicting users in multitenant installations and may be nil otherwise
+func SyncOpenshiftUsers(ctx context.Context, serverClient k8sclient.Client, authenticated keycloakCommon.KeycloakInterface, installation *integreatlyv1alpha1.RHMI, ns string, logger l.Logger) error {
+	keycloakUsers, err := GetKeycloakUsers(ctx, serverClient, ns)
+	if err != nil {
+		return fmt.Errorf("failed to list the keycloak users: %w", err)
+	}
+
+	users, err := syncronizeWithOpenshiftUsers(ctx, keycloakUsers, serverClient, ns, installation, logger)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize the users: %w", err)
+	}
+
+	for _, user := range users {
+		or, conflictFound, err := createOrUpdateKeycloakUser(ctx, user, serverClient, ns)
+		if err != nil {
+			return fmt.Errorf("failed to create/update keycloak user %s: %w", user.UserName, err)
+		}
+		logger.Infof("Operation result", l.Fields{"keycloakuser": user.UserName, "result": or})
+
+		if conflictFound {
+			deleteConflictingUser(authenticated, installation, user.UserName, logger)
+		}
+	}
+
+	return nil
+}
+
+// SyncOpenshiftUser applies the changes for a single OpenShift user to the openshift
+// realm. Only the user, its identities and the exclusion groups are read, so the cost
+// does not grow with the number of users on the cluster
+func SyncOpenshiftUser(ctx context.Context, serverClient k8sclient.Client, authenticated keycloakCommon.KeycloakInterface, installation *integreatlyv1alpha1.RHMI, ns string, userName string, logger l.Logger) (controllerutil.OperationResult, error) {
+	existing, err := getKeycloakUserByUserName(ctx, serverClient, ns, userName)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	osUser := &usersv1.User{}
+	err = serverClient.Get(ctx, k8sclient.ObjectKey{Name: userName}, osUser)
+	if err != nil && !k8serr.IsNotFound(err) {
+		return controllerutil.OperationResultNone, fmt.Errorf("failed to get user %s: %w", userName, err)
+	}
+
+	active := false
+	if err == nil {
+		active, err = userHelper.IsUserInActiveIDP(ctx, serverClient, *osUser)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	// Users excluded by the sync rules are removed even when already synchronised
+	excluded := false
+	if active {
+		syncRules, err := userHelper.GetSyncRules(ctx, serverClient, installation.Namespace)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		excluded, err = syncRules.IsUserExcluded(ctx, serverClient, *osUser)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+	}
+
+	// User removed from OpenShift, no longer part of an active IDP or excluded
+	if !active || excluded {
+		if existing == nil {
+			return controllerutil.OperationResultNone, nil
+		}
+		reason := removedUserReason
+		if excluded {
+			reason = excludedUserReason
+		}
+		err := serverClient.Delete(ctx, existing)
+		rhssocommon.RecordKeycloakUserChange(ctx, ns, audit.ActionDelete, reason, &existing.Spec.User, nil, err)
+		if err != nil && !k8serr.IsNotFound(err) {
+			return controllerutil.OperationResultNone, fmt.Errorf("failed to delete keycloak user %s: %w", userName, err)
+		}
+		return OperationResultDeleted, nil
+	}
+
+	var user keycloak.KeycloakAPIUser
+	if existing != nil {
+		user = existing.Spec.User
+	} else {
+		identities, err := userHelper.GetUserIdentities(ctx, serverClient, *osUser)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		email := userHelper.GetEmailFromIdentity(*osUser, identities)
+		if email == "" {
+			email, err = userHelper.SetUserNameAsEmail(osUser.Name)
+			if err != nil {
+				return controllerutil.OperationResultNone, err
+			}
+		}
+		user = newKeycloakUser(*osUser, email)
+	}
+	user.ClientRoles = getKeycloakRoles(integreatlyv1alpha1.InstallationType(installation.Spec.Type))
+
+	or, conflictFound, err := createOrUpdateKeycloakUser(ctx, user, serverClient, ns)
+	if err != nil {
+		return or, fmt.Errorf("failed to create/update keycloak user %s: %w", userName, err)
+	}
+	if conflictFound {
+		deleteConflictingUser(authenticated, installation, userName, logger)
+	}
+	return or, nil
+}
+
+// GetKeycloakName returns the name of the Keycloak CR of the openshift realm
+func GetKeycloakName() string {
+	return keycloakName
+}
+
+// deleteConflictingUser removes a user whose KeycloakUser failed with a conflict from the
+// realm through the keycloak API, so the keycloak operator can create it again on its next
+// reconcile. Only multitenant installations resolve conflicts
+func deleteConflictingUser(authenticated keycloakCommon.KeycloakInterface, installation *integreatlyv1alpha1.RHMI, userName string, logger l.Logger) {
+	if !integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(installation.Spec.Type)) {
+		return
+	}
+	logger.Infof("Conflict error found", l.Fields{"keycloak-user": userName})
+	if authenticated == nil {
+		logger.Warningf("No keycloak authenticated client to delete the conflicting user", l.Fields{"keycloak-user": userName})
+		return
+	}
+	if err := authenticated.DeleteUser(userName, keycloakRealmName); err != nil {
+		logger.Error(fmt.Sprintf("failed to delete keycloak-user %s using the keycloak authenticated client", userName), err)
+	}
+}
+
+func getKeycloakUserByUserName(ctx context.Context, serverClient k8sclient.Client, ns string, userName string) (*keycloak.KeycloakUser, error) {
+	users := &keycloak.KeycloakUserList{}
+	listOptions := []k8sclient.ListOption{
+		k8sclient.MatchingLabels(GetInstanceLabels()),
+		k8sclient.InNamespace(ns),
+	}
+	if err := serverClient.List(ctx, users, listOptions...); err != nil {
+		return nil, fmt.Errorf("failed to list keycloak users: %w", err)
+	}
+
+	for i := range users.Items {
+		if users.Items[i].Spec.User.UserName == userName {
+			return &users.Items[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,274 @@
+package rhsso
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
+	"github.com/integr8ly/integreatly-operator/utils"
+	keycloak "github.com/integr8ly/keycloak-client/apis/keycloak/v1alpha1"
+	keycloakCommon "github.com/integr8ly/keycloak-client/pkg/common"
+	configv1 "github.com/openshift/api/config/v1"
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestSyncOpenshiftUser(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	installation := &integreatlyv1alpha1.RHMI{
+		Spec: integreatlyv1alpha1.RHMISpec{
+			Type: string(integreatlyv1alpha1.InstallationTypeManagedApi),
+		},
+	}
+
+	oauth := &configv1.OAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec: configv1.OAuthSpec{
+			IdentityProviders: []configv1.IdentityProvider{{Name: "testidp"}},
+		},
+	}
+
+	newUser := func(name string) *usersv1.User {
+		return &usersv1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")},
+			Identities: []string{"testidp:" + name},
+		}
+	}
+	newIdentity := func(userName string) *usersv1.Identity {
+		return &usersv1.Identity{
+			ObjectMeta:   metav1.ObjectMeta{Name: "testidp:" + userName},
+			ProviderName: "testidp",
+			User:         corev1.ObjectReference{Name: userName},
+			Extra:        map[string]string{"email": userName + "@example.com"},
+		}
+	}
+	existingKeycloakUser := func(userName string) *keycloak.KeycloakUser {
+		user := newKeycloakUser(*newUser(userName), userName+"@example.com")
+		return &keycloak.KeycloakUser{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "generated-" + userName + "-" + userName + "-uid",
+				Namespace: defaultOperandNamespace,
+				Labels:    GetInstanceLabels(),
+			},
+			Spec: keycloak.KeycloakUserSpec{User: user},
+		}
+	}
+
+	tests := []struct {
+		Name           string
+		UserName       string
+		InitObjs       []runtime.Object
+		ExpectedResult controllerutil.OperationResult
+		ExpectUser     bool
+	}{
+		{
+			Name:           "creates keycloak user for new user in active IDP",
+			UserName:       "alice",
+			InitObjs:       []runtime.Object{oauth, newUser("alice"), newIdentity("alice")},
+			ExpectedResult: controllerutil.OperationResultCreated,
+			ExpectUser:     true,
+		},
+		{
+			Name:     "skips new user in exclusion group",
+			UserName: "alice",
+			InitObjs: []runtime.Object{oauth, newUser("alice"), newIdentity("alice"), &usersv1.Group{
+				ObjectMeta: metav1.ObjectMeta{Name: "osd-sre-admins"},
+				Users:      []string{"alice"},
+			}},
+			ExpectedResult: controllerutil.OperationResultNone,
+			ExpectUser:     false,
+		},
+		{
+			Name:     "deletes synchronised keycloak user once excluded",
+			UserName: "alice",
+			InitObjs: []runtime.Object{oauth, newUser("alice"), newIdentity("alice"), existingKeycloakUser("alice"), &usersv1.Group{
+				ObjectMeta: metav1.ObjectMeta{Name: "osd-sre-admins"},
+				Users:      []string{"alice"},
+			}},
+			ExpectedResult: OperationResultDeleted,
+			ExpectUser:     false,
+		},
+		{
+			Name:           "deletes keycloak user when OpenShift user is removed",
+			UserName:       "alice",
+			InitObjs:       []runtime.Object{oauth, existingKeycloakUser("alice")},
+			ExpectedResult: OperationResultDeleted,
+			ExpectUser:     false,
+		},
+		{
+			Name:           "deletes keycloak user when identity is no longer in an active IDP",
+			UserName:       "alice",
+			InitObjs:       []runtime.Object{&configv1.OAuth{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}, newUser("alice"), newIdentity("alice"), existingKeycloakUser("alice")},
+			ExpectedResult: OperationResultDeleted,
+			ExpectUser:     false,
+		},
+		{
+			Name:           "no change for unknown user",
+			UserName:       "bob",
+			InitObjs:       []runtime.Object{oauth},
+			ExpectedResult: controllerutil.OperationResultNone,
+			ExpectUser:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serverClient := utils.NewTestClient(scheme, tt.InitObjs...)
+
+			result, err := SyncOpenshiftUser(context.TODO(), serverClient, nil, installation, defaultOperandNamespace, tt.UserName, getLogger())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.ExpectedResult {
+				t.Fatalf("expected result %s, got %s", tt.ExpectedResult, result)
+			}
+
+			users := &keycloak.KeycloakUserList{}
+			if err := serverClient.List(context.TODO(), users, k8sclient.InNamespace(defaultOperandNamespace)); err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, user := range users.Items {
+				if user.Spec.User.UserName == tt.UserName {
+					found = true
+					if user.Spec.User.ClientRoles == nil {
+						t.Fatalf("expected client roles to be set on %s", tt.UserName)
+					}
+				}
+			}
+			if found != tt.ExpectUser {
+				t.Fatalf("expected keycloak user present: %v, got %v", tt.ExpectUser, found)
+			}
+		})
+	}
+}
+
+func TestSyncOpenshiftUser_conflict(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oauth := &configv1.OAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec: configv1.OAuthSpec{
+			IdentityProviders: []configv1.IdentityProvider{{Name: "testidp"}},
+		},
+	}
+	osUser := &usersv1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", UID: types.UID("alice-uid")},
+		Identities: []string{"testidp:alice"},
+	}
+	identity := &usersv1.Identity{
+		ObjectMeta:   metav1.ObjectMeta{Name: "testidp:alice"},
+		ProviderName: "testidp",
+		User:         corev1.ObjectReference{Name: "alice"},
+		Extra:        map[string]string{"email": "alice@example.com"},
+	}
+	conflictingUser := func() *keycloak.KeycloakUser {
+		return &keycloak.KeycloakUser{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "generated-alice-alice-uid",
+				Namespace: defaultOperandNamespace,
+				Labels:    GetInstanceLabels(),
+			},
+			Spec: keycloak.KeycloakUserSpec{User: newKeycloakUser(*osUser, "alice@example.com")},
+			Status: keycloak.KeycloakUserStatus{
+				Phase:   "failing",
+				Message: "failed to create user: (409) 409 Conflict",
+			},
+		}
+	}
+
+	tests := []struct {
+		Name              string
+		InstallationType  integreatlyv1alpha1.InstallationType
+		ExpectDeletedUser bool
+	}{
+		{
+			Name:              "deletes conflicting user through the keycloak api in multitenant installations",
+			InstallationType:  integreatlyv1alpha1.InstallationTypeMultitenantManagedApi,
+			ExpectDeletedUser: true,
+		},
+		{
+			Name:              "leaves conflicting user in single tenant installations",
+			InstallationType:  integreatlyv1alpha1.InstallationTypeManagedApi,
+			ExpectDeletedUser: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			installation := &integreatlyv1alpha1.RHMI{
+				Spec: integreatlyv1alpha1.RHMISpec{Type: string(tt.InstallationType)},
+			}
+			serverClient := utils.NewTestClient(scheme, oauth, osUser, identity, conflictingUser())
+
+			var deleted []string
+			authenticated := &keycloakCommon.KeycloakInterfaceMock{
+				DeleteUserFunc: func(userID string, realmName string) error {
+					if realmName != keycloakRealmName {
+						t.Fatalf("expected realm %s, got %s", keycloakRealmName, realmName)
+					}
+					deleted = append(deleted, userID)
+					return nil
+				},
+			}
+
+			if _, err := SyncOpenshiftUser(context.TODO(), serverClient, authenticated, installation, defaultOperandNamespace, "alice", getLogger()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.ExpectDeletedUser && (len(deleted) != 1 || deleted[0] != "alice") {
+				t.Fatalf("expected alice to be deleted through the keycloak api, got %v", deleted)
+			}
+			if !tt.ExpectDeletedUser && len(deleted) != 0 {
+				t.Fatalf("expected no user to be deleted, got %v", deleted)
+			}
+
+			if err := SyncOpenshiftUsers(context.TODO(), serverClient, authenticated, installation, defaultOperandNamespace, getLogger()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.ExpectDeletedUser && len(deleted) != 2 {
+				t.Fatalf("expected the full sync to delete alice through the keycloak api, got %v", deleted)
+			}
+		})
+	}
+}
+
+func TestGetUserDiff_excludedUsers(t *testing.T) {
+	openshiftUsers := []usersv1.User{
+		{ObjectMeta: metav1.ObjectMeta{Name: "alice"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "sre"}},
+	}
+	keycloakUsers := []keycloak.KeycloakAPIUser{
+		{UserName: "alice"},
+		{UserName: "sre"},
+		{UserName: "removed"},
+	}
+	groups := &usersv1.GroupList{Items: []usersv1.Group{
+		{ObjectMeta: metav1.ObjectMeta{Name: "osd-sre-admins"}, Users: []string{"sre"}},
+	}}
+
+	added, deleted := getUserDiff(keycloakUsers, openshiftUsers, groups, userHelper.DefaultSyncRules())
+	if len(added) != 0 {
+		t.Fatalf("expected no added users, got %v", added)
+	}
+	var deletedNames []string
+	for _, user := range deleted {
+		deletedNames = append(deletedNames, user.UserName)
+	}
+	if !reflect.DeepEqual(deletedNames, []string{"sre", "removed"}) {
+		t.Fatalf("expected the excluded and removed users to be deleted, got %v", deletedNames)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	"github.com/integr8ly/integreatly-operator/pkg/config"
 	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/backup"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/cluster"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/constants"
@@ -536,7 +537,9 @@ func (r *Reconciler) ReconcileStatefulSet(ctx context.Context, serverClient k8sc
 	)
 }
 
-func DeleteKeycloakUsers(allKcUsers []keycloak.KeycloakAPIUser, deletedUsers []keycloak.KeycloakAPIUser, ns string, ctx context.Context, serverClient k8sclient.Client) ([]keycloak.KeycloakAPIUser, error) {
+// DeleteKeycloakUsers deletes the KeycloakUser CRs of the deleted users, recording the
+// reason for the deletion in the audit trail
+func DeleteKeycloakUsers(allKcUsers []keycloak.KeycloakAPIUser, deletedUsers []keycloak.KeycloakAPIUser, ns string, reason string, ctx context.Context, serverClient k8sclient.Client) ([]keycloak.KeycloakAPIUser, error) {
 
 	for _, delUser := range deletedUsers {
 
@@ -565,6 +568,7 @@ func DeleteKeycloakUsers(allKcUsers []keycloak.KeycloakAPIUser, deletedUsers []k
 			return nil, fmt.Errorf("failed to get valid generated username")
 		}
 		err := serverClient.Delete(ctx, kcUser)
+		RecordKeycloakUserChange(ctx, ns, audit.ActionDelete, reason, &delUser, nil, err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to delete keycloak user: %w", err)
 		}
@@ -573,6 +577,36 @@ func DeleteKeycloakUsers(allKcUsers []keycloak.KeycloakAPIUser, deletedUsers []k
 	return allKcUsers, nil
 }
 
+// RecordKeycloakUserChange writes an audit entry for a change to a KeycloakUser CR. Only
+// the identifying fields and granted roles and groups of the user are recorded
+func RecordKeycloakUserChange(ctx context.Context, ns string, action string, reason string, before *keycloak.KeycloakAPIUser, after *keycloak.KeycloakAPIUser, err error) {
+	entry := audit.Entry{
+		Product: "keycloak",
+		Action:  action,
+		Reason:  reason,
+		Result:  audit.ResultOf(err),
+	}
+	if before != nil {
+		entry.Resource = ns + "/keycloakuser/" + before.UserName
+		entry.Before = keycloakUserAuditView(before)
+	}
+	if after != nil {
+		entry.Resource = ns + "/keycloakuser/" + after.UserName
+		entry.After = keycloakUserAuditView(after)
+	}
+	audit.Record(ctx, entry)
+}
+
+func keycloakUserAuditView(user *keycloak.KeycloakAPIUser) map[string]interface{} {
+	return map[string]interface{}{
+		"username":    user.UserName,
+		"enabled":     user.Enabled,
+		"realmRoles":  user.RealmRoles,
+		"clientRoles": user.ClientRoles,
+		"groups":      user.Groups,
+	}
+}
+
 func OsUserInKc(osUsers []usersv1.User, kcUser keycloak.KeycloakAPIUser) bool {
 	for _, osu := range osUsers {
 		if osu.Name == kcUser.UserName {
@@ -3,6 +3,7 @@ package rhssouser
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/integr8ly/integreatly-operator/version"
 
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
 
 	"github.com/integr8ly/integreatly-operator/pkg/products/rhsso"
@@ -67,6 +69,14 @@ const (
 	userSsoConsoleLink          = "rhoam-user-sso-console-link"
 
 	userSSOIcon = "data:image/svg+xml;base64,PD94bWwgdmVyc2lvbj0iMS4wIiBlbmNvZGluZz0idXRmLTgiPz4KPCEtLSBHZW5lcmF0b3I6IEFkb2JlIElsbHVzdHJhdG9yIDI1LjIuMCwgU1ZHIEV4cG9ydCBQbHVnLUluIC4gU1ZHIFZlcnNpb246IDYuMDAgQnVpbGQgMCkgIC0tPgo8c3ZnIHZlcnNpb249IjEuMSIgaWQ9IkxheWVyXzEiIHhtbG5zPSJodHRwOi8vd3d3LnczLm9yZy8yMDAwL3N2ZyIgeG1sbnM6eGxpbms9Imh0dHA6Ly93d3cudzMub3JnLzE5OTkveGxpbmsiIHg9IjBweCIgeT0iMHB4IgoJIHZpZXdCb3g9IjAgMCAzNyAzNyIgc3R5bGU9ImVuYWJsZS1iYWNrZ3JvdW5kOm5ldyAwIDAgMzcgMzc7IiB4bWw6c3BhY2U9InByZXNlcnZlIj4KPHN0eWxlIHR5cGU9InRleHQvY3NzIj4KCS5zdDB7ZmlsbDojRUUwMDAwO30KCS5zdDF7ZmlsbDojRkZGRkZGO30KPC9zdHlsZT4KPGc+Cgk8cGF0aCBkPSJNMjcuNSwwLjVoLTE4Yy00Ljk3LDAtOSw0LjAzLTksOXYxOGMwLDQuOTcsNC4wMyw5LDksOWgxOGM0Ljk3LDAsOS00LjAzLDktOXYtMThDMzYuNSw0LjUzLDMyLjQ3LDAuNSwyNy41LDAuNUwyNy41LDAuNXoiCgkJLz4KCTxnPgoJCTxwYXRoIGNsYXNzPSJzdDAiIGQ9Ik0yNSwyMi4zN2MtMC45NSwwLTEuNzUsMC42My0yLjAyLDEuNWgtMS44NVYyMS41YzAtMC4zNS0wLjI4LTAuNjItMC42Mi0wLjYycy0wLjYyLDAuMjgtMC42MiwwLjYydjMKCQkJYzAsMC4zNSwwLjI4LDAuNjIsMC42MiwwLjYyaDIuNDhjMC4yNywwLjg3LDEuMDcsMS41LDIuMDIsMS41YzEuMTcsMCwyLjEyLTAuOTUsMi4xMi0yLjEyUzI2LjE3LDIyLjM3LDI1LDIyLjM3eiBNMjUsMjUuMzcKCQkJYy0wLjQ4LDAtMC44OC0wLjM5LTAuODgtMC44OHMwLjM5LTAuODgsMC44OC0wLjg4czAuODgsMC4zOSwwLjg4LDAuODhTMjUuNDgsMjUuMzcsMjUsMjUuMzd6Ii8+CgkJPHBhdGggY2xhc3M9InN0MCIgZD0iTTIwLjUsMTYuMTJjMC4zNCwwLDAuNjItMC4yOCwwLjYyLTAuNjJ2LTIuMzhoMS45MWMwLjMyLDAuNzcsMS4wOCwxLjMxLDEuOTYsMS4zMQoJCQljMS4xNywwLDIuMTItMC45NSwyLjEyLTIuMTJzLTAuOTUtMi4xMi0yLjEyLTIuMTJjLTEuMDIsMC0xLjg4LDAuNzMtMi4wOCwxLjY5SDIwLjVjLTAuMzQsMC0wLjYyLDAuMjgtMC42MiwwLjYydjMKCQkJQzE5Ljg3LDE1Ljg1LDIwLjE2LDE2LjEyLDIwLjUsMTYuMTJ6IE0yNSwxMS40M2MwLjQ4LDAsMC44OCwwLjM5LDAuODgsMC44OHMtMC4zOSwwLjg4LTAuODgsMC44OHMtMC44OC0wLjM5LTAuODgtMC44OAoJCQlTMjQuNTIsMTEuNDMsMjUsMTEuNDN6Ii8+CgkJPHBhdGggY2xhc3M9InN0MCIgZD0iTTEyLjEyLDE5Ljk2di0wLjg0aDIuMzhjMC4zNCwwLDAuNjItMC4yOCwwLjYyLTAuNjJzLTAuMjgtMC42Mi0wLjYyLTAuNjJoLTIuMzh2LTAuOTEKCQkJYzAtMC4zNS0wLjI4LTAuNjItMC42Mi0wLjYyaC0zYy0wLjM0LDAtMC42MiwwLjI4LTAuNjIsMC42MnYzYzAsMC4zNSwwLjI4LDAuNjIsMC42MiwwLjYyaDNDMTEuODQsMjAuNTksMTIuMTIsMjAuMzEsMTIuMTIsMTkuOTYKCQkJeiBNMTAuODcsMTkuMzRIOS4xMnYtMS43NWgxLjc1VjE5LjM0eiIvPgoJCTxwYXRoIGNsYXNzPSJzdDAiIGQ9Ik0yOC41LDE2LjM0aC0zYy0wLjM0LDAtMC42MiwwLjI4LTAuNjIsMC42MnYwLjkxSDIyLjVjLTAuMzQsMC0wLjYyLDAuMjgtMC42MiwwLjYyczAuMjgsMC42MiwwLjYyLDAuNjJoMi4zOAoJCQl2MC44NGMwLDAuMzUsMC4yOCwwLjYyLDAuNjIsMC42MmgzYzAuMzQsMCwwLjYyLTAuMjgsMC42Mi0wLjYydi0zQzI5LjEyLDE2LjYyLDI4Ljg0LDE2LjM0LDI4LjUsMTYuMzR6IE0yNy44NywxOS4zNGgtMS43NXYtMS43NQoJCQloMS43NVYxOS4zNHoiLz4KCQk8cGF0aCBjbGFzcz0ic3QwIiBkPSJNMTYuNSwyMC44N2MtMC4zNCwwLTAuNjMsMC4yOC0wLjYzLDAuNjJ2Mi4zOGgtMS44NWMtMC4yNy0wLjg3LTEuMDctMS41LTIuMDItMS41CgkJCWMtMS4xNywwLTIuMTIsMC45NS0yLjEyLDIuMTJzMC45NSwyLjEyLDIuMTIsMi4xMmMwLjk1LDAsMS43NS0wLjYzLDIuMDItMS41aDIuNDhjMC4zNCwwLDAuNjItMC4yOCwwLjYyLTAuNjJ2LTMKCQkJQzE3LjEyLDIxLjE1LDE2Ljg0LDIwLjg3LDE2LjUsMjAuODd6IE0xMiwyNS4zN2MtMC40OCwwLTAuODgtMC4zOS0wLjg4LTAuODhzMC4zOS0wLjg4LDAuODgtMC44OHMwLjg4LDAuMzksMC44OCwwLjg4CgkJCVMxMi40OCwyNS4zNywxMiwyNS4zN3oiLz4KCQk8cGF0aCBjbGFzcz0ic3QwIiBkPSJNMTYuNSwxMS44N2gtMi40MmMtMC4yLTAuOTctMS4wNi0xLjY5LTIuMDgtMS42OWMtMS4xNywwLTIuMTIsMC45NS0yLjEyLDIuMTJzMC45NSwyLjEyLDIuMTIsMi4xMgoJCQljMC44OCwwLDEuNjQtMC41NCwxLjk2LTEuMzFoMS45MXYyLjM4YzAsMC4zNSwwLjI4LDAuNjIsMC42MywwLjYyczAuNjItMC4yOCwwLjYyLTAuNjJ2LTNDMTcuMTIsMTIuMTUsMTYuODQsMTEuODcsMTYuNSwxMS44N3oKCQkJIE0xMiwxMy4xOGMtMC40OCwwLTAuODgtMC4zOS0wLjg4LTAuODhzMC4zOS0wLjg4LDAuODgtMC44OHMwLjg4LDAuMzksMC44OCwwLjg4UzEyLjQ4LDEzLjE4LDEyLDEzLjE4eiIvPgoJPC9nPgoJPHBhdGggY2xhc3M9InN0MSIgZD0iTTE4LjUsMjIuNjJjLTIuMjcsMC00LjEzLTEuODUtNC4xMy00LjEyczEuODUtNC4xMiw0LjEzLTQuMTJzNC4xMiwxLjg1LDQuMTIsNC4xMlMyMC43NywyMi42MiwxOC41LDIyLjYyegoJCSBNMTguNSwxNS42MmMtMS41OCwwLTIuODgsMS4yOS0yLjg4LDIuODhzMS4yOSwyLjg4LDIuODgsMi44OHMyLjg4LTEuMjksMi44OC0yLjg4UzIwLjA4LDE1LjYyLDE4LjUsMTUuNjJ6Ii8+CjwvZz4KPC9zdmc+Cg=="
+
+	// groupMappedUserAttribute marks the users added to the master realm by a group mapping,
+	// which are removed once they are no longer a member of any mapped group
+	groupMappedUserAttribute = "rhoam-group-mapped"
+
+	// Audit reasons for the deletion of master realm users
+	deletedUserReason  = "user no longer exists in OpenShift"
+	unmappedUserReason = "user no longer a member of any mapped group"
 )
 
 var realmManagersClientRoles = []string{
@@ -271,7 +281,12 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, err
 	}
 
-	phase, err = r.newAlertsReconciler(r.Log, r.Installation.Spec.Type, config.GetOboNamespace(r.Installation.Namespace)).ReconcileAlerts(ctx, serverClient)
+	alertsReconciler, err := r.newAlertsReconciler(r.Log, r.Installation.Spec.Type, config.GetOboNamespace(r.Installation.Namespace))
+	if err != nil {
+		events.HandleError(r.Recorder, installation, integreatlyv1alpha1.PhaseFailed, "Failed to get alerts reconciler", err)
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	phase, err = alertsReconciler.ReconcileAlerts(ctx, serverClient)
 	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.Recorder, installation, phase, "Failed to reconcile alerts", err)
 		return phase, err
@@ -429,11 +444,16 @@ func (r *Reconciler) reconcileComponents(ctx context.Context, installation *inte
 		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to list the keycloak users: %w", err)
 	}
 
-	_, err = r.reconcileGroups(ctx, serverClient, kc)
+	groupMappings, err := userHelper.GetGroupMappings(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	_, err = r.reconcileGroups(ctx, serverClient, kc, groupMappings)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
-	_, err = r.reconcileAdminUsers(ctx, serverClient, kcClient, keycloakUsers)
+	_, err = r.reconcileAdminUsers(ctx, serverClient, kcClient, keycloakUsers, groupMappings)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
@@ -446,7 +466,7 @@ func (r *Reconciler) reconcileComponents(ctx context.Context, installation *inte
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func (r *Reconciler) reconcileGroups(ctx context.Context, serverClient k8sclient.Client, kc *keycloak.Keycloak) (integreatlyv1alpha1.StatusPhase, error) {
+func (r *Reconciler) reconcileGroups(ctx context.Context, serverClient k8sclient.Client, kc *keycloak.Keycloak, groupMappings []userHelper.GroupMapping) (integreatlyv1alpha1.StatusPhase, error) {
 
 	rolesConfigured, err := r.Config.GetDevelopersGroupConfigured()
 	if err != nil {
@@ -469,15 +489,25 @@ func (r *Reconciler) reconcileGroups(ctx context.Context, serverClient k8sclient
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to reconcile dedicated-admins group: %v", err)
 	}
+
+	_, err = r.reconcileMappedGroups(kc, groupMappings)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to reconcile mapped groups: %w", err)
+	}
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func (r *Reconciler) reconcileAdminUsers(ctx context.Context, serverClient k8sclient.Client, kcClient keycloakCommon.KeycloakInterface, keycloakUsers []keycloak.KeycloakAPIUser) (integreatlyv1alpha1.StatusPhase, error) {
+func (r *Reconciler) reconcileAdminUsers(ctx context.Context, serverClient k8sclient.Client, kcClient keycloakCommon.KeycloakInterface, keycloakUsers []keycloak.KeycloakAPIUser, groupMappings []userHelper.GroupMapping) (integreatlyv1alpha1.StatusPhase, error) {
 
-	// Sync keycloak with openshift users
-	users, err := syncAdminUsersInMasterRealm(keycloakUsers, ctx, serverClient, r.Config.GetNamespace())
-	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to synchronize the users: %w", err)
+	users := keycloakUsers
+	// Users are propagated by the user sync controller when it is running
+	if !userHelper.IsIncrementalSyncEnabled() {
+		// Sync keycloak with openshift users
+		var err error
+		users, err = syncAdminUsersInMasterRealm(keycloakUsers, groupMappings, ctx, serverClient, r.Config.GetNamespace())
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to synchronize the users: %w", err)
+		}
 	}
 
 	// Create / update the synchronized users
@@ -486,19 +516,11 @@ func (r *Reconciler) reconcileAdminUsers(ctx context.Context, serverClient k8scl
 			continue
 		}
 
-		// If the ID is not set, check if the user is already on Keycloak,
-		// and set the ID on the CR to avoid the Keycloak operator from trying
-		// to create the user, causing a conflict
-		if user.ID == "" {
-			kcUser, err := kcClient.FindUserByUsername(user.UserName, masterRealmName)
-			if err != nil && err.Error() != "not found" {
-				return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("error attempting to retrieve user: %w", err)
-			} else if err == nil {
-				user.ID = kcUser.ID
-			}
+		if err := setKeycloakUserID(kcClient, &user); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
 		}
 
-		or, err := r.createOrUpdateKeycloakAdmin(user, ctx, serverClient)
+		or, err := createOrUpdateKeycloakAdmin(user, ctx, serverClient, r.Config.GetNamespace())
 		if err != nil {
 			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to create/update the customer admin user: %w", err)
 		} else {
@@ -509,6 +531,9 @@ func (r *Reconciler) reconcileAdminUsers(ctx context.Context, serverClient k8scl
 	if err := r.reconcileGroupMembership(users, kcClient, masterRealmName); err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
+	if err := removeStaleGroupMembership(users, kcClient, masterRealmName, groupMappings); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
 
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
@@ -596,18 +621,39 @@ func (r *Reconciler) updateMasterRealm(ctx context.Context, serverClient k8sclie
 	return kcr, nil
 }
 
-func (r *Reconciler) createOrUpdateKeycloakAdmin(user keycloak.KeycloakAPIUser, ctx context.Context, serverClient k8sclient.Client) (controllerutil.OperationResult, error) {
+// If the ID is not set, check if the user is already on Keycloak,
+// and set the ID on the CR to avoid the Keycloak operator from trying
+// to create the user, causing a conflict
+func setKeycloakUserID(kcClient keycloakCommon.KeycloakInterface, user *keycloak.KeycloakAPIUser) error {
+	if user.ID != "" {
+		return nil
+	}
+	kcUser, err := kcClient.FindUserByUsername(user.UserName, masterRealmName)
+	if err != nil && err.Error() != "not found" {
+		return fmt.Errorf("error attempting to retrieve user: %w", err)
+	} else if err == nil {
+		user.ID = kcUser.ID
+	}
+	return nil
+}
+
+func createOrUpdateKeycloakAdmin(user keycloak.KeycloakAPIUser, ctx context.Context, serverClient k8sclient.Client, ns string) (controllerutil.OperationResult, error) {
 	kcUser := &keycloak.KeycloakUser{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      userHelper.GetValidGeneratedUserName(user),
-			Namespace: r.Config.GetNamespace(),
+			Namespace: ns,
 		},
 	}
 	if kcUser.Name == "" {
 		return "", fmt.Errorf("failed to get valid generated username")
 	}
 
+	var before *keycloak.KeycloakAPIUser
 	or, err := controllerutil.CreateOrUpdate(ctx, serverClient, kcUser, func() error {
+		if kcUser.ResourceVersion != "" {
+			existing := kcUser.Spec.User
+			before = &existing
+		}
 		kcUser.Spec.RealmSelector = &metav1.LabelSelector{
 			MatchLabels: getMasterLabels(),
 		}
@@ -616,6 +662,18 @@ func (r *Reconciler) createOrUpdateKeycloakAdmin(user keycloak.KeycloakAPIUser,
 
 		return nil
 	})
+	if or != controllerutil.OperationResultNone || err != nil {
+		action := audit.ActionUpdate
+		switch {
+		case before == nil:
+			action = audit.ActionCreate
+		case hasAdminPrivileges(&user) && !hasAdminPrivileges(before):
+			action = audit.ActionPromote
+		case !hasAdminPrivileges(&user) && hasAdminPrivileges(before):
+			action = audit.ActionDemote
+		}
+		rhssocommon.RecordKeycloakUserChange(ctx, ns, action, "dedicated-admins or mapped group membership changed", before, &user, err)
+	}
 	if err != nil {
 		return or, err
 	}
@@ -640,7 +698,7 @@ func getMasterLabels() map[string]string {
 	}
 }
 
-func syncAdminUsersInMasterRealm(keycloakUsers []keycloak.KeycloakAPIUser, ctx context.Context, serverClient k8sclient.Client, ns string) ([]keycloak.KeycloakAPIUser, error) {
+func syncAdminUsersInMasterRealm(keycloakUsers []keycloak.KeycloakAPIUser, groupMappings []userHelper.GroupMapping, ctx context.Context, serverClient k8sclient.Client, ns string) ([]keycloak.KeycloakAPIUser, error) {
 
 	openshiftUsers := &usersv1.UserList{}
 	err := serverClient.List(ctx, openshiftUsers)
@@ -661,7 +719,7 @@ func syncAdminUsersInMasterRealm(keycloakUsers []keycloak.KeycloakAPIUser, ctx c
 	// demoted => existing KC user, removed from dedicated-admins group, demote KC privileges
 	added, deleted, promoted, demoted := getUserDiff(keycloakUsers, openshiftUsers.Items, dedicatedAdminUsers)
 
-	keycloakUsers, err = rhssocommon.DeleteKeycloakUsers(keycloakUsers, deleted, ns, ctx, serverClient)
+	keycloakUsers, err = rhssocommon.DeleteKeycloakUsers(keycloakUsers, deleted, ns, deletedUserReason, ctx, serverClient)
 	if err != nil {
 		return nil, err
 	}
@@ -669,6 +727,13 @@ func syncAdminUsersInMasterRealm(keycloakUsers []keycloak.KeycloakAPIUser, ctx c
 	keycloakUsers = addKeycloakUsers(keycloakUsers, added)
 	keycloakUsers = promoteKeycloakUsers(keycloakUsers, promoted)
 	keycloakUsers = demoteKeycloakUsers(keycloakUsers, demoted)
+	keycloakUsers = applyGroupMappings(keycloakUsers, openshiftUsers.Items, *openshiftGroups, groupMappings)
+
+	// unmapped => added by a group mapping, no longer a member of any mapped group
+	keycloakUsers, err = rhssocommon.DeleteKeycloakUsers(keycloakUsers, getUnmappedUsers(keycloakUsers, groupMappings), ns, unmappedUserReason, ctx, serverClient)
+	if err != nil {
+		return nil, err
+	}
 
 	return keycloakUsers, nil
 }
@@ -779,6 +844,106 @@ func demoteKeycloakUsers(allUsers []keycloak.KeycloakAPIUser, demoted []keycloak
 	return allUsers
 }
 
+// applyGroupMappings sets the Keycloak groups mapped to the OpenShift groups of each user.
+// Members of mapped groups that are not yet in the master realm are added without admin
+// privileges
+func applyGroupMappings(keycloakUsers []keycloak.KeycloakAPIUser, osUsers []usersv1.User, groups usersv1.GroupList, groupMappings []userHelper.GroupMapping) []keycloak.KeycloakAPIUser {
+	mappedGroups := getMappedKeycloakGroups(groupMappings)
+	if len(mappedGroups) == 0 {
+		return keycloakUsers
+	}
+
+	for _, osUser := range osUsers {
+		userGroups := userHelper.GetMappedKeycloakGroups(groupMappings, osUser.Name, &groups)
+
+		found := false
+		for i, kcUser := range keycloakUsers {
+			if len(kcUser.FederatedIdentities) >= 1 && kcUser.FederatedIdentities[0].UserID == string(osUser.UID) {
+				keycloakUsers[i].Groups = setMappedGroups(kcUser.Groups, userGroups, mappedGroups)
+				found = true
+				break
+			}
+		}
+
+		if !found && len(userGroups) > 0 {
+			keycloakUsers = append(keycloakUsers, keycloak.KeycloakAPIUser{
+				Enabled:       true,
+				UserName:      osUser.Name,
+				EmailVerified: true,
+				FederatedIdentities: []keycloak.FederatedIdentity{
+					{
+						IdentityProvider: idpAlias,
+						UserID:           string(osUser.UID),
+						UserName:         osUser.Name,
+					},
+				},
+				RealmRoles: []string{"offline_access", "uma_authorization"},
+				ClientRoles: map[string][]string{
+					"account": {
+						"manage-account",
+						"manage-account-links",
+						"view-profile",
+					},
+				},
+				Groups:     userGroups,
+				Attributes: map[string][]string{groupMappedUserAttribute: {"true"}},
+			})
+		}
+	}
+	return keycloakUsers
+}
+
+// getUnmappedUsers returns the users added to the master realm by a group mapping which are
+// no longer a member of any mapped group and have not been promoted since
+func getUnmappedUsers(keycloakUsers []keycloak.KeycloakAPIUser, groupMappings []userHelper.GroupMapping) []keycloak.KeycloakAPIUser {
+	mappedGroups := getMappedKeycloakGroups(groupMappings)
+
+	var unmapped []keycloak.KeycloakAPIUser
+	for i := range keycloakUsers {
+		user := keycloakUsers[i]
+		if !isGroupMappedUser(user) || hasAdminPrivileges(&user) {
+			continue
+		}
+		mapped := false
+		for _, group := range user.Groups {
+			if contains(mappedGroups, group) {
+				mapped = true
+				break
+			}
+		}
+		if !mapped {
+			unmapped = append(unmapped, user)
+		}
+	}
+	return unmapped
+}
+
+func isGroupMappedUser(user keycloak.KeycloakAPIUser) bool {
+	return len(user.Attributes[groupMappedUserAttribute]) > 0
+}
+
+// setMappedGroups replaces the mapped groups in the user groups with the ones the user is
+// currently mapped to, leaving any other groups unchanged
+func setMappedGroups(groups, userGroups, mappedGroups []string) []string {
+	result := []string{}
+	for _, group := range groups {
+		if !contains(mappedGroups, group) {
+			result = append(result, group)
+		}
+	}
+	return append(result, userGroups...)
+}
+
+func getMappedKeycloakGroups(groupMappings []userHelper.GroupMapping) []string {
+	var groups []string
+	for _, mapping := range groupMappings {
+		if mapping.KeycloakGroup != "" && !contains(groups, mapping.KeycloakGroup) {
+			groups = append(groups, mapping.KeycloakGroup)
+		}
+	}
+	return groups
+}
+
 // NOTE: The users type has a Groups field on it but it does not seem to get populated
 // hence the need to check by name which is not ideal. However, this is the only field
 // available on the Group type
@@ -1064,6 +1229,58 @@ func (r *Reconciler) reconcileDedicatedAdminsGroup(kc *keycloak.Keycloak) (integ
 	return integreatlyv1alpha1.PhaseCompleted, err
 }
 
+// Create the groups targeted by the group mappings configuration with their realm and
+// client roles
+func (r *Reconciler) reconcileMappedGroups(kc *keycloak.Keycloak, groupMappings []userHelper.GroupMapping) (integreatlyv1alpha1.StatusPhase, error) {
+	if len(getMappedKeycloakGroups(groupMappings)) == 0 {
+		return integreatlyv1alpha1.PhaseCompleted, nil
+	}
+
+	// Get Keycloak client
+	kcClient, err := r.KeycloakClientFactory.AuthenticatedClient(*kc)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	for _, mapping := range groupMappings {
+		if mapping.KeycloakGroup == "" {
+			continue
+		}
+
+		// Sort the clients so the roles are mapped in a consistent order
+		clientNames := make([]string, 0, len(mapping.ClientRoles))
+		for clientName := range mapping.ClientRoles {
+			clientNames = append(clientNames, clientName)
+		}
+		sort.Strings(clientNames)
+
+		clientRoles := []*keycloakClientRole{}
+		for _, clientName := range clientNames {
+			for _, roleName := range mapping.ClientRoles[clientName] {
+				clientRoles = append(clientRoles, &keycloakClientRole{
+					ClientName: clientName,
+					RoleName:   roleName,
+				})
+			}
+		}
+
+		groupSpec := &keycloakGroupSpec{
+			Name:        mapping.KeycloakGroup,
+			RealmName:   masterRealmName,
+			RealmRoles:  mapping.RealmRoles,
+			ClientRoles: clientRoles,
+			ChildGroups: []*keycloakGroupSpec{},
+		}
+
+		_, err = reconcileGroup(kcClient, groupSpec)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to reconcile group %s mapped from %s: %w", mapping.KeycloakGroup, mapping.OpenshiftGroup, err)
+		}
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
 func mapClientRoleToGroup(kcClient keycloakCommon.KeycloakInterface, realmName, groupID, clientID, roleName string) error {
 	return mapRoleToGroupByName(roleName,
 		func() ([]*keycloak.KeycloakUserRole, error) {
@@ -1326,6 +1543,41 @@ func (r *Reconciler) reconcileGroupMembership(users []keycloak.KeycloakAPIUser,
 	return nil
 }
 
+// Remove the users represented by a Keycloak CR from the mapped groups they are no longer
+// mapped to. Members added to the groups by the customer are left unchanged
+func removeStaleGroupMembership(users []keycloak.KeycloakAPIUser, kcClient keycloakCommon.KeycloakInterface, realm string, groupMappings []userHelper.GroupMapping) error {
+	managedUsers := map[string]keycloak.KeycloakAPIUser{}
+	for _, user := range users {
+		managedUsers[user.UserName] = user
+	}
+
+	for _, groupName := range getMappedKeycloakGroups(groupMappings) {
+		group, err := kcClient.FindGroupByPath(groupName, realm)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			continue
+		}
+
+		members, err := kcClient.ListUsersInGroup(realm, group.ID)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			user, ok := managedUsers[member.UserName]
+			if !ok || contains(user.Groups, groupName) {
+				continue
+			}
+			if err := kcClient.DeleteUserFromGroup(realm, member.ID, group.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // Query the clients in a realm and return a map where the key is the client name
 // (`ClientID` field) and the value is the struct with the client information
 func listClientsByName(kcClient keycloakCommon.KeycloakInterface, realmName string) (map[string]*keycloak.KeycloakAPIClient, error) {
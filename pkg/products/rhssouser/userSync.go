@@ -0,0 +1,120 @@
+package rhssouser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/integr8ly/integreatly-operator/pkg/products/rhsso"
+	"github.com/integr8ly/integreatly-operator/pkg/products/rhssocommon"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
+	keycloak "github.com/integr8ly/keycloak-client/apis/keycloak/v1alpha1"
+	keycloakCommon "github.com/integr8ly/keycloak-client/pkg/common"
+	usersv1 "github.com/openshift/api/user/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// SyncAdminUsers performs a full synchronisation of the dedicated-admins group and the
+// mapped groups into the master realm. It is used by the user sync controller as a periodic safety net for any
+// events missed by the incremental sync
+func SyncAdminUsers(ctx context.Context, serverClient k8sclient.Client, kcClient keycloakCommon.KeycloakInterface, ns string, groupMappings []userHelper.GroupMapping, logger l.Logger) error {
+	keycloakUsers, err := getUsers(ctx, serverClient, ns)
+	if err != nil {
+		return fmt.Errorf("failed to list the keycloak users: %w", err)
+	}
+
+	users, err := syncAdminUsersInMasterRealm(keycloakUsers, groupMappings, ctx, serverClient, ns)
+	if err != nil {
+		return fmt.Errorf("failed to synchronize the users: %w", err)
+	}
+
+	for _, user := range users {
+		if user.UserName == "" {
+			continue
+		}
+		if err := setKeycloakUserID(kcClient, &user); err != nil {
+			return err
+		}
+		or, err := createOrUpdateKeycloakAdmin(user, ctx, serverClient, ns)
+		if err != nil {
+			return fmt.Errorf("failed to create/update keycloak user %s: %w", user.UserName, err)
+		}
+		logger.Infof("Operation result", l.Fields{"keycloakuser": user.UserName, "result": or})
+	}
+
+	return nil
+}
+
+// SyncAdminUser applies the changes for a single OpenShift user to the master realm,
+// adding, promoting, demoting or deleting it depending on its dedicated-admins membership,
+// and setting the Keycloak groups mapped to its OpenShift groups
+func SyncAdminUser(ctx context.Context, serverClient k8sclient.Client, kcClient keycloakCommon.KeycloakInterface, ns string, groupMappings []userHelper.GroupMapping, userName string) (controllerutil.OperationResult, error) {
+	keycloakUsers, err := getUsers(ctx, serverClient, ns)
+	if err != nil {
+		return controllerutil.OperationResultNone, fmt.Errorf("failed to list the keycloak users: %w", err)
+	}
+
+	osUser := &usersv1.User{}
+	err = serverClient.Get(ctx, k8sclient.ObjectKey{Name: userName}, osUser)
+	if k8serr.IsNotFound(err) {
+		// User no longer exists in OpenShift, remove from SSO
+		for _, kcUser := range keycloakUsers {
+			if kcUser.UserName == userName {
+				_, err := rhssocommon.DeleteKeycloakUsers(keycloakUsers, []keycloak.KeycloakAPIUser{kcUser}, ns, deletedUserReason, ctx, serverClient)
+				if err != nil && !k8serr.IsNotFound(err) {
+					return controllerutil.OperationResultNone, err
+				}
+				return rhsso.OperationResultDeleted, nil
+			}
+		}
+		return controllerutil.OperationResultNone, nil
+	}
+	if err != nil {
+		return controllerutil.OperationResultNone, fmt.Errorf("failed to get user %s: %w", userName, err)
+	}
+
+	openshiftGroups, err := userHelper.GetMappedOpenshiftGroups(ctx, serverClient, groupMappings, dedicatedAdminsGroupName)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	isAdmin := contains(getOsUsersInAdminsGroup(*openshiftGroups), userName)
+
+	var users []keycloak.KeycloakAPIUser
+	kcUser := getKeyCloakUser(*osUser, keycloakUsers)
+	switch {
+	case isAdmin && kcUser == nil:
+		users = addKeycloakUsers(nil, []usersv1.User{*osUser})
+	case isAdmin && !hasAdminPrivileges(kcUser):
+		users = promoteKeycloakUsers([]keycloak.KeycloakAPIUser{*kcUser}, []keycloak.KeycloakAPIUser{*kcUser})
+	case !isAdmin && kcUser != nil && hasAdminPrivileges(kcUser):
+		users = demoteKeycloakUsers([]keycloak.KeycloakAPIUser{*kcUser}, []keycloak.KeycloakAPIUser{*kcUser})
+	case kcUser != nil:
+		users = []keycloak.KeycloakAPIUser{*kcUser}
+	}
+
+	users = applyGroupMappings(users, []usersv1.User{*osUser}, *openshiftGroups, groupMappings)
+	if len(users) == 0 {
+		return controllerutil.OperationResultNone, nil
+	}
+
+	if unmapped := getUnmappedUsers(users, groupMappings); len(unmapped) > 0 {
+		_, err := rhssocommon.DeleteKeycloakUsers(keycloakUsers, unmapped, ns, unmappedUserReason, ctx, serverClient)
+		if err != nil && !k8serr.IsNotFound(err) {
+			return controllerutil.OperationResultNone, err
+		}
+		return rhsso.OperationResultDeleted, nil
+	}
+
+	user := users[0]
+	if err := setKeycloakUserID(kcClient, &user); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	return createOrUpdateKeycloakAdmin(user, ctx, serverClient, ns)
+}
+
+// GetKeycloakName returns the name of the user SSO Keycloak CR
+func GetKeycloakName() string {
+	return keycloakName
+}
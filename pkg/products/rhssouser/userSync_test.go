@@ -0,0 +1,152 @@
+package rhssouser
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/pkg/products/rhsso"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
+	"github.com/integr8ly/integreatly-operator/utils"
+	keycloak "github.com/integr8ly/keycloak-client/apis/keycloak/v1alpha1"
+	keycloakCommon "github.com/integr8ly/keycloak-client/pkg/common"
+	usersv1 "github.com/openshift/api/user/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSyncAdminUser_groupMappings(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupMappings := []userHelper.GroupMapping{
+		{OpenshiftGroup: "support", KeycloakGroup: "support"},
+	}
+	osUser := func() *usersv1.User {
+		return &usersv1.User{ObjectMeta: metav1.ObjectMeta{Name: "bob", UID: types.UID("bob-uid")}}
+	}
+	mappedUser := keycloak.KeycloakAPIUser{
+		ID:       "bob-id",
+		Enabled:  true,
+		UserName: "bob",
+		FederatedIdentities: []keycloak.FederatedIdentity{
+			{IdentityProvider: idpAlias, UserID: "bob-uid", UserName: "bob"},
+		},
+		RealmRoles: []string{"offline_access", "uma_authorization"},
+		Groups:     []string{"support"},
+		Attributes: map[string][]string{groupMappedUserAttribute: {"true"}},
+	}
+	keycloakUser := func(user keycloak.KeycloakAPIUser) *keycloak.KeycloakUser {
+		return &keycloak.KeycloakUser{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      userHelper.GetValidGeneratedUserName(user),
+				Namespace: defaultNamespace,
+				Labels:    getMasterLabels(),
+			},
+			Spec: keycloak.KeycloakUserSpec{User: user},
+		}
+	}
+	supportGroup := func(users ...string) *usersv1.Group {
+		return &usersv1.Group{ObjectMeta: metav1.ObjectMeta{Name: "support"}, Users: users}
+	}
+
+	tests := []struct {
+		Name          string
+		InitObjs      []k8sclient.Object
+		ExpectDeleted bool
+	}{
+		{
+			Name:          "keeps mapped user while member of a mapped group",
+			InitObjs:      []k8sclient.Object{osUser(), supportGroup("bob"), keycloakUser(mappedUser)},
+			ExpectDeleted: false,
+		},
+		{
+			Name:          "removes mapped user no longer member of a mapped group",
+			InitObjs:      []k8sclient.Object{osUser(), supportGroup(), keycloakUser(mappedUser)},
+			ExpectDeleted: true,
+		},
+		{
+			Name: "keeps user not added by a group mapping",
+			InitObjs: []k8sclient.Object{osUser(), supportGroup(), keycloakUser(func() keycloak.KeycloakAPIUser {
+				user := mappedUser
+				user.Attributes = nil
+				return user
+			}())},
+			ExpectDeleted: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serverClient := utils.NewTestClient(scheme)
+			for _, obj := range tt.InitObjs {
+				if err := serverClient.Create(context.TODO(), obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := SyncAdminUser(context.TODO(), serverClient, &keycloakCommon.KeycloakInterfaceMock{}, defaultNamespace, groupMappings, "bob")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if deleted := result == rhsso.OperationResultDeleted; deleted != tt.ExpectDeleted {
+				t.Fatalf("expected deleted result: %v, got %s", tt.ExpectDeleted, result)
+			}
+
+			err = serverClient.Get(context.TODO(), k8sclient.ObjectKey{Name: userHelper.GetValidGeneratedUserName(mappedUser), Namespace: defaultNamespace}, &keycloak.KeycloakUser{})
+			if err != nil && !k8serr.IsNotFound(err) {
+				t.Fatal(err)
+			}
+			if found := err == nil; found == tt.ExpectDeleted {
+				t.Fatalf("expected keycloak user present: %v, got %v", !tt.ExpectDeleted, found)
+			}
+		})
+	}
+}
+
+func TestSyncAdminUsers_removesUnmappedUsers(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mappedUser := keycloak.KeycloakAPIUser{
+		ID:       "bob-id",
+		UserName: "bob",
+		FederatedIdentities: []keycloak.FederatedIdentity{
+			{IdentityProvider: idpAlias, UserID: "bob-uid", UserName: "bob"},
+		},
+		Groups:     []string{"support"},
+		Attributes: map[string][]string{groupMappedUserAttribute: {"true"}},
+	}
+	serverClient := utils.NewTestClient(scheme,
+		&usersv1.User{ObjectMeta: metav1.ObjectMeta{Name: "bob", UID: types.UID("bob-uid")}},
+		&usersv1.Group{ObjectMeta: metav1.ObjectMeta{Name: "support"}},
+		&keycloak.KeycloakUser{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      userHelper.GetValidGeneratedUserName(mappedUser),
+				Namespace: defaultNamespace,
+				Labels:    getMasterLabels(),
+			},
+			Spec: keycloak.KeycloakUserSpec{User: mappedUser},
+		},
+	)
+	groupMappings := []userHelper.GroupMapping{
+		{OpenshiftGroup: "support", KeycloakGroup: "support"},
+	}
+
+	if err := SyncAdminUsers(context.TODO(), serverClient, &keycloakCommon.KeycloakInterfaceMock{}, defaultNamespace, groupMappings, getLogger()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	users := &keycloak.KeycloakUserList{}
+	if err := serverClient.List(context.TODO(), users, k8sclient.InNamespace(defaultNamespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(users.Items) != 0 {
+		t.Fatalf("expected the unmapped user to be removed, got %v", users.Items)
+	}
+}
@@ -2,6 +2,10 @@ package threescale
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
+
+	envoyaccesslogv3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	envoycorev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoylistenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	envoyratelimitconfigv3 "github.com/envoyproxy/go-control-plane/envoy/config/ratelimit/v3"
@@ -14,6 +18,7 @@ import (
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
 	"google.golang.org/protobuf/types/known/anypb"
 )
@@ -38,6 +43,23 @@ const (
 	tenantHeaderName         = "tenant"
 	safeRegex                = ".*apicast.*"
 	multitenantDescriptorKey = "per-mt-limit"
+
+	// The envoy sidecar of system-app forwards the requests of each portal to its container
+	SystemAppNodeID                   = "system-app-ratelimit"
+	SystemAppContainerAddress         = "127.0.0.1"
+	SystemAppEnvoyProxyAddress        = "0.0.0.0"
+	SystemProviderContainerPort       = 3000
+	SystemProviderClusterName         = "system-provider-ratelimit"
+	SystemProviderEnvoyProxyPort      = 3010
+	SystemProviderEnvoyProxyPortName  = "envoy-provider"
+	SystemProviderServiceName         = "system-provider-proxy"
+	SystemDeveloperContainerPort      = 3001
+	SystemDeveloperClusterName        = "system-developer-ratelimit"
+	SystemDeveloperEnvoyProxyPort     = 3011
+	SystemDeveloperEnvoyProxyPortName = "envoy-developer"
+	SystemDeveloperServiceName        = "system-developer-proxy"
+	// portalSessionHeaderName holds the session of the portal requests for the per session limit
+	portalSessionHeaderName = "x-rhoam-portal-session"
 )
 
 /*
@@ -114,7 +136,27 @@ var multiTenantRatelimitDescriptor = envoyroutev3.RateLimit{
 
 *
 */
-func getAPICastHTTPFilters() ([]*hcm.HttpFilter, error) {
+func getAPICastHTTPFilters(responseConfig *marin3rconfig.ResponseConfig) ([]*hcm.HttpFilter, error) {
+	tsHTTPRateLimitFilter, err := getRateLimitHTTPFilter(ratelimit.RateLimitDomain, responseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	routerFiler, err := getRouterHTTPFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	httpFilters := []*hcm.HttpFilter{
+		tsHTTPRateLimitFilter,
+		routerFiler,
+	}
+
+	return httpFilters, nil
+}
+
+// getRateLimitHTTPFilter checks the requests against the limits of the Limitador namespace
+func getRateLimitHTTPFilter(domain string, responseConfig *marin3rconfig.ResponseConfig) (*hcm.HttpFilter, error) {
 	/*
 		Defines http filters for the rate limit service
 		   httpFilters:
@@ -128,35 +170,41 @@ func getAPICastHTTPFilters() ([]*hcm.HttpFilter, error) {
 		       stage: 0
 		     name: envoy.envoy.filters.http.ratelimit
 	*/
-	ratelimitSerial, err := anypb.New(
-		&envoyratelimitv3.RateLimit{
-			Domain: ratelimit.RateLimitDomain,
-			Stage:  0,
-			RateLimitService: &envoyratelimitconfigv3.RateLimitServiceConfig{
-				GrpcService: &envoycorev3.GrpcService{
-					TargetSpecifier: &envoycorev3.GrpcService_EnvoyGrpc_{
-						EnvoyGrpc: &envoycorev3.GrpcService_EnvoyGrpc{
-							ClusterName: ratelimit.RateLimitClusterName,
-						},
-					},
-					Timeout: &duration.Duration{
-						Seconds: 2,
+	ratelimitFilter := &envoyratelimitv3.RateLimit{
+		Domain: domain,
+		Stage:  0,
+		RateLimitService: &envoyratelimitconfigv3.RateLimitServiceConfig{
+			GrpcService: &envoycorev3.GrpcService{
+				TargetSpecifier: &envoycorev3.GrpcService_EnvoyGrpc_{
+					EnvoyGrpc: &envoycorev3.GrpcService_EnvoyGrpc{
+						ClusterName: ratelimit.RateLimitClusterName,
 					},
 				},
-				TransportApiVersion: envoycorev3.ApiVersion_V3,
+				Timeout: &duration.Duration{
+					Seconds: 2,
+				},
 			},
+			TransportApiVersion: envoycorev3.ApiVersion_V3,
 		},
-	)
+	}
+	// adds the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers
+	if responseConfig.IsEnabled() {
+		ratelimitFilter.EnableXRatelimitHeaders = envoyratelimitv3.RateLimit_DRAFT_VERSION_03
+	}
+
+	ratelimitSerial, err := anypb.New(ratelimitFilter)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert rate limit filter for rate limiting")
 	}
 
-	var tsHTTPRateLimitFilter = hcm.HttpFilter{
+	return &hcm.HttpFilter{
 		Name:       "envoy.filters.http.ratelimit",
 		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: ratelimitSerial},
-	}
+	}, nil
+}
 
+func getRouterHTTPFilter() (*hcm.HttpFilter, error) {
 	routerSerial, err := anypb.New(
 		&router.Router{},
 	)
@@ -165,17 +213,10 @@ func getAPICastHTTPFilters() ([]*hcm.HttpFilter, error) {
 		return nil, fmt.Errorf("failed to convert router filter for Apicast ratelimit envoy configuration")
 	}
 
-	var routerFiler = hcm.HttpFilter{
+	return &hcm.HttpFilter{
 		Name:       "envoy.filters.http.router",
 		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: routerSerial},
-	}
-
-	httpFilters := []*hcm.HttpFilter{
-		&tsHTTPRateLimitFilter,
-		&routerFiler,
-	}
-
-	return httpFilters, nil
+	}, nil
 }
 
 /*
@@ -193,7 +234,7 @@ end
 return result;
 end
 */
-func getMultitenantAPICastHTTPFilters() ([]*hcm.HttpFilter, error) {
+func getMultitenantAPICastHTTPFilters(responseConfig *marin3rconfig.ResponseConfig) ([]*hcm.HttpFilter, error) {
 
 	luaFunctionToAddTSHeaders := "function envoy_on_request(request_handle) host = request_handle:headers():get('Host') local headers = request_handle:headers() split_string = Split(host, '-apicast') headers:add('tenant', split_string[1]) end function Split(s, delimiter) result = {}; for match in (s..delimiter):gmatch('(.-)'..delimiter) do table.insert(result, match); end return result; end"
 
@@ -214,7 +255,7 @@ func getMultitenantAPICastHTTPFilters() ([]*hcm.HttpFilter, error) {
 		},
 	}
 
-	filters, err := getAPICastHTTPFilters()
+	filters, err := getAPICastHTTPFilters(responseConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -251,7 +292,40 @@ func getMultitenantAPICastHTTPFilters() ([]*hcm.HttpFilter, error) {
 
 *
 */
-func getBackendListenerHTTPFilters() ([]*hcm.HttpFilter, error) {
+func getBackendListenerHTTPFilters(responseConfig *marin3rconfig.ResponseConfig) ([]*hcm.HttpFilter, error) {
+	responseFilter, err := getRateLimitResponseHTTPFilter(responseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := getAPICastHTTPFilters(responseConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	httpFilters := append([]*hcm.HttpFilter{responseFilter}, filters...)
+	return httpFilters, nil
+}
+
+/*
+function envoy_on_response(response_handle)
+local headers = response_handle:headers()
+local limit = headers:get("x-ratelimit-limit")
+if limit ~= nil then headers:replace("ratelimit-limit", string.match(limit, "^%d+") or limit) end
+local remaining = headers:get("x-ratelimit-remaining")
+if remaining ~= nil then headers:replace("ratelimit-remaining", remaining) end
+local reset = headers:get("x-ratelimit-reset")
+if reset ~= nil then headers:replace("ratelimit-reset", reset) end
+if headers:get("x-envoy-ratelimited") ~= nil then
+headers:add("3scale-rejection-reason", "limits_exceeded")
+if reset ~= nil then headers:replace("retry-after", reset) end
+end
+end
+*/
+// getRateLimitResponseHTTPFilter flags the rejected requests with the 3scale-rejection-reason
+// header. With the rate limit response enabled, it also sets the RateLimit-* headers from the
+// X-RateLimit-* headers of the rate limit filter, and the Retry-After header of the rejected requests
+func getRateLimitResponseHTTPFilter(responseConfig *marin3rconfig.ResponseConfig) (*hcm.HttpFilter, error) {
 
 	// function envoy_on_response(response_handle)
 	// 	rate_limit = response_handle:headers():get("x-envoy-ratelimited")
@@ -260,6 +334,9 @@ func getBackendListenerHTTPFilters() ([]*hcm.HttpFilter, error) {
 	// 	end
 	// end
 	luaFunctionToAddTSHeaders := "function envoy_on_response(response_handle) rate_limit = response_handle:headers():get('x-envoy-ratelimited') if rate_limit ~= nil then response_handle:headers():add('3scale-rejection-reason', 'limits_exceeded') end end"
+	if responseConfig.IsEnabled() {
+		luaFunctionToAddTSHeaders = "function envoy_on_response(response_handle) local headers = response_handle:headers() local limit = headers:get('x-ratelimit-limit') if limit ~= nil then headers:replace('ratelimit-limit', string.match(limit, '^%d+') or limit) end local remaining = headers:get('x-ratelimit-remaining') if remaining ~= nil then headers:replace('ratelimit-remaining', remaining) end local reset = headers:get('x-ratelimit-reset') if reset ~= nil then headers:replace('ratelimit-reset', reset) end if headers:get('x-envoy-ratelimited') ~= nil then headers:add('3scale-rejection-reason', 'limits_exceeded') if reset ~= nil then headers:replace('retry-after', reset) end end end"
+	}
 
 	luaFilter := &lua.Lua{
 		InlineCode: luaFunctionToAddTSHeaders,
@@ -269,21 +346,182 @@ func getBackendListenerHTTPFilters() ([]*hcm.HttpFilter, error) {
 		return nil, fmt.Errorf("failed to convert HttpConnectionManager for rate limiting: %v", err)
 	}
 
-	httpFilters := []*hcm.HttpFilter{
-		{
-			Name: "envoy.filters.http.lua",
-			ConfigType: &hcm.HttpFilter_TypedConfig{
-				TypedConfig: pbst,
-			},
+	return &hcm.HttpFilter{
+		Name: "envoy.filters.http.lua",
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: pbst,
 		},
+	}, nil
+}
+
+/*
+localReplyConfig:
+  mappers:
+  - filter:
+      statusCodeFilter:
+        comparison:
+          op: EQ
+          value:
+            defaultValue: 429
+            runtimeKey: ratelimit.rejection_status_code
+    bodyFormatOverride:
+      contentType: application/json
+      textFormatSource:
+        inlineString: template
+*/
+// getRateLimitLocalReplyConfig replaces the body of the requests rejected by the rate limit
+// filter with the rejection template, nil is returned when the rate limit response is disabled
+func getRateLimitLocalReplyConfig(responseConfig *marin3rconfig.ResponseConfig) *hcm.LocalReplyConfig {
+	if !responseConfig.IsEnabled() {
+		return nil
+	}
+
+	return &hcm.LocalReplyConfig{
+		Mappers: []*hcm.ResponseMapper{{
+			Filter: &envoyaccesslogv3.AccessLogFilter{
+				FilterSpecifier: &envoyaccesslogv3.AccessLogFilter_StatusCodeFilter{
+					StatusCodeFilter: &envoyaccesslogv3.StatusCodeFilter{
+						Comparison: &envoyaccesslogv3.ComparisonFilter{
+							Op: envoyaccesslogv3.ComparisonFilter_EQ,
+							Value: &envoycorev3.RuntimeUInt32{
+								DefaultValue: http.StatusTooManyRequests,
+								RuntimeKey:   "ratelimit.rejection_status_code",
+							},
+						},
+					},
+				},
+			},
+			BodyFormatOverride: &envoycorev3.SubstitutionFormatString{
+				Format: &envoycorev3.SubstitutionFormatString_TextFormatSource{
+					TextFormatSource: &envoycorev3.DataSource{
+						Specifier: &envoycorev3.DataSource_InlineString{
+							InlineString: responseConfig.GetTemplate(),
+						},
+					},
+				},
+				ContentType: "application/json",
+			},
+		}},
+	}
+}
+
+/*
+Reports the descriptors of the request to the shadow limits, e.g. for a multitenant installation
+with a rule limiting the POST requests to /orders per user_key
+
+	function envoy_on_request(request_handle)
+	local headers = request_handle:headers()
+	local function quote(s) ... end
+	local function report(values)
+	  ...
+	  request_handle:httpCall('ratelimit-shadow', {
+	    [':method'] = 'POST', [':path'] = '/check_and_report',
+	    [':authority'] = 'ratelimit-shadow', ['content-type'] = 'application/json'
+	  }, '{"namespace": "apicast-ratelimit-shadow", "values": {...}, "delta": 1}', 2000, true)
+	end
+	local path = headers:get(':path') or ''
+	local method = headers:get(':method') or ''
+	report({['generic_key'] = 'slowpath'})
+	local tenant = headers:get('tenant')
+	if tenant ~= nil and string.find(headers:get('host') or '', 'apicast', 1, true) then
+	  report({['header_match'] = 'per-mt-limit', ['tenant'] = tenant})
+	end
+	if string.sub(path, 1, 7) == '/orders' and method == 'POST' then
+	  local key = headers:get('user_key')
+	  if key ~= nil then report({['descriptor_rule'] = 'orders', ['user_key'] = key}) end
+	end
+	end
+*/
+// getShadowHTTPFilter reports each request to the shadow limits of the rate limit service, with
+// the same descriptors the rate limit filter sends for it. The ratelimit filter of the envoy
+// version in use can not evaluate limits without enforcing them, so the descriptors are sent
+// by an asynchronous call, the request is never held or rejected by the shadow limits
+func getShadowHTTPFilter(installation *integreatlyv1alpha1.RHMI, descriptorRules []marin3rconfig.DescriptorRule) (*hcm.HttpFilter, error) {
+	code := &strings.Builder{}
+	code.WriteString(`function envoy_on_request(request_handle) local headers = request_handle:headers() `)
+	code.WriteString(`local function quote(s) return '"' .. string.gsub(s, '[%c"\\]', function(c) return string.format('\\u%04x', string.byte(c)) end) .. '"' end `)
+	fmt.Fprintf(code,
+		`local function report(values) local entries = {} for key, value in pairs(values) do table.insert(entries, quote(key) .. ': ' .. quote(value)) end `+
+			`request_handle:httpCall(%[1]s, {[':method'] = 'POST', [':path'] = '/check_and_report', [':authority'] = %[1]s, ['content-type'] = 'application/json'}, `+
+			`'{"namespace": "%[2]s", "values": {' .. table.concat(entries, ', ') .. '}, "delta": 1}', 2000, true) end `,
+		luaString(ratelimit.RateLimitShadowClusterName), ratelimit.RateLimitShadowDomain,
+	)
+	code.WriteString(`local path = headers:get(':path') or '' local method = headers:get(':method') or '' `)
+
+	fmt.Fprintf(code, `report({['generic_key'] = %s}) `, luaString(ratelimit.RateLimitDescriptorValue))
+	if integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(installation.Spec.Type)) {
+		fmt.Fprintf(code,
+			`local tenant = headers:get(%[1]s) if tenant ~= nil and string.find(headers:get('host') or '', 'apicast', 1, true) then report({['header_match'] = %[2]s, [%[1]s] = tenant}) end `,
+			luaString(tenantHeaderName), luaString(multitenantDescriptorKey),
+		)
+	}
+
+	for _, rule := range descriptorRules {
+		conditions := []string{"true"}
+		if rule.PathPrefix != "" {
+			conditions = append(conditions, fmt.Sprintf("string.sub(path, 1, %d) == %s", len(rule.PathPrefix), luaString(rule.PathPrefix)))
+		}
+		if rule.Method != "" {
+			conditions = append(conditions, fmt.Sprintf("method == %s", luaString(rule.Method)))
+		}
+		ruleValue := fmt.Sprintf("[%s] = %s", luaString(marin3rconfig.DescriptorRuleKey), luaString(rule.Name))
+
+		fmt.Fprintf(code, `if %s then `, strings.Join(conditions, " and "))
+		switch rule.PerKey {
+		case marin3rconfig.PerKeyUserKey, marin3rconfig.PerKeyAppID:
+			// the descriptor is not sent when the header is missing
+			fmt.Fprintf(code, `local key = headers:get(%s) if key ~= nil then report({%s, [%s] = key}) end `,
+				luaString(rule.PerKey), ruleValue, luaString(rule.CounterKey()))
+		case marin3rconfig.PerKeyClientIP:
+			fmt.Fprintf(code, `local address = string.match(request_handle:streamInfo():downstreamRemoteAddress(), '^%%[?(.-)%%]?:%%d+$') if address ~= nil then report({%s, [%s] = address}) end `,
+				ruleValue, luaString(rule.CounterKey()))
+		default:
+			fmt.Fprintf(code, `report({%s}) `, ruleValue)
+		}
+		code.WriteString(`end `)
 	}
-	filters, err := getAPICastHTTPFilters()
+	code.WriteString(`end`)
+
+	pbst, err := anypb.New(&lua.Lua{
+		InlineCode: code.String(),
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to convert shadow lua filter for rate limiting: %v", err)
 	}
 
-	httpFilters = append(httpFilters, filters...)
-	return httpFilters, nil
+	return &hcm.HttpFilter{
+		Name: "envoy.filters.http.lua",
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: pbst,
+		},
+	}, nil
+}
+
+// luaString returns the value as a Lua string literal, escaping quotes, backslashes and the
+// characters that are not printable ASCII
+func luaString(value string) string {
+	literal := &strings.Builder{}
+	literal.WriteString("'")
+	for _, b := range []byte(value) {
+		if b < ' ' || b > '~' || b == '\\' || b == '\'' {
+			fmt.Fprintf(literal, "\\%03d", b)
+			continue
+		}
+		literal.WriteByte(b)
+	}
+	literal.WriteString("'")
+	return literal.String()
+}
+
+// insertBeforeRateLimitFilter inserts the filter before the rate limit filter, so it sees the
+// request headers the rate limit filter builds the descriptors from
+func insertBeforeRateLimitFilter(filters []*hcm.HttpFilter, filter *hcm.HttpFilter) []*hcm.HttpFilter {
+	for i, f := range filters {
+		if f.Name == "envoy.filters.http.ratelimit" {
+			return append(filters[:i], append([]*hcm.HttpFilter{filter}, filters[i:]...)...)
+		}
+	}
+	return append([]*hcm.HttpFilter{filter}, filters...)
 }
 
 /*
@@ -304,7 +542,7 @@ virtualHosts:
     descriptorValue: slowpath
     stage: 0
 */
-func getAPICastVirtualHosts(installation *integreatlyv1alpha1.RHMI, clusterName string) []*envoyroutev3.VirtualHost {
+func getAPICastVirtualHosts(installation *integreatlyv1alpha1.RHMI, clusterName string, descriptorRules []marin3rconfig.DescriptorRule) []*envoyroutev3.VirtualHost {
 	virtualHost := envoyroutev3.VirtualHost{
 		Name:    clusterName,
 		Domains: []string{"*"},
@@ -324,7 +562,7 @@ func getAPICastVirtualHosts(installation *integreatlyv1alpha1.RHMI, clusterName
 						Timeout: &duration.Duration{
 							Seconds: 75,
 						},
-						RateLimits: getRateLimitsPerInstallType(installation),
+						RateLimits: append(getRateLimitsPerInstallType(installation), getDescriptorRateLimits(descriptorRules)...),
 					},
 				},
 			},
@@ -345,6 +583,98 @@ func getRateLimitsPerInstallType(installation *integreatlyv1alpha1.RHMI) []*envo
 	return routes
 }
 
+/*
+Defines the actions of the customer descriptor rules, e.g. for a rule limiting the POST requests
+to /orders per user_key
+
+	rate_limits:
+	- actions:
+	    - header_value_match:
+	        descriptor_key: descriptor_rule
+	        descriptor_value: orders
+	        headers:
+	        - name: :path
+	          string_match:
+	            prefix: /orders
+	        - name: :method
+	          string_match:
+	            exact: POST
+	    - request_headers:
+	        header_name: user_key
+	        descriptor_key: user_key
+*/
+func getDescriptorRateLimits(rules []marin3rconfig.DescriptorRule) []*envoyroutev3.RateLimit {
+	var rateLimits []*envoyroutev3.RateLimit
+
+	for _, rule := range rules {
+		var headers []*envoyroutev3.HeaderMatcher
+		if rule.PathPrefix != "" {
+			headers = append(headers, stringHeaderMatcher(":path", &matcher.StringMatcher{
+				MatchPattern: &matcher.StringMatcher_Prefix{Prefix: rule.PathPrefix},
+			}))
+		}
+		if rule.Method != "" {
+			headers = append(headers, stringHeaderMatcher(":method", &matcher.StringMatcher{
+				MatchPattern: &matcher.StringMatcher_Exact{Exact: rule.Method},
+			}))
+		}
+
+		// rules without a match apply to every request
+		ruleAction := &envoyroutev3.RateLimit_Action{
+			ActionSpecifier: &envoyroutev3.RateLimit_Action_GenericKey_{
+				GenericKey: &envoyroutev3.RateLimit_Action_GenericKey{
+					DescriptorKey:   marin3rconfig.DescriptorRuleKey,
+					DescriptorValue: rule.Name,
+				},
+			},
+		}
+		if len(headers) > 0 {
+			ruleAction = &envoyroutev3.RateLimit_Action{
+				ActionSpecifier: &envoyroutev3.RateLimit_Action_HeaderValueMatch_{
+					HeaderValueMatch: &envoyroutev3.RateLimit_Action_HeaderValueMatch{
+						DescriptorKey:   marin3rconfig.DescriptorRuleKey,
+						DescriptorValue: rule.Name,
+						Headers:         headers,
+					},
+				},
+			}
+		}
+		actions := []*envoyroutev3.RateLimit_Action{ruleAction}
+
+		switch rule.PerKey {
+		case marin3rconfig.PerKeyUserKey, marin3rconfig.PerKeyAppID:
+			actions = append(actions, &envoyroutev3.RateLimit_Action{
+				ActionSpecifier: &envoyroutev3.RateLimit_Action_RequestHeaders_{
+					RequestHeaders: &envoyroutev3.RateLimit_Action_RequestHeaders{
+						HeaderName:    rule.PerKey,
+						DescriptorKey: rule.CounterKey(),
+					},
+				},
+			})
+		case marin3rconfig.PerKeyClientIP:
+			actions = append(actions, &envoyroutev3.RateLimit_Action{
+				ActionSpecifier: &envoyroutev3.RateLimit_Action_RemoteAddress_{
+					RemoteAddress: &envoyroutev3.RateLimit_Action_RemoteAddress{},
+				},
+			})
+		}
+
+		rateLimits = append(rateLimits, &envoyroutev3.RateLimit{
+			Stage:   &wrappers.UInt32Value{Value: 0},
+			Actions: actions,
+		})
+	}
+
+	return rateLimits
+}
+
+func stringHeaderMatcher(name string, stringMatcher *matcher.StringMatcher) *envoyroutev3.HeaderMatcher {
+	return &envoyroutev3.HeaderMatcher{
+		Name:                 name,
+		HeaderMatchSpecifier: &envoyroutev3.HeaderMatcher_StringMatch{StringMatch: stringMatcher},
+	}
+}
+
 /*
 *
 virtual_hosts:
@@ -408,7 +738,7 @@ func getBackendListenerVitualHosts(clusterName string) []*envoyroutev3.VirtualHo
 
 *
 */
-func getListenerResourceFilters(virtualHosts []*envoyroutev3.VirtualHost, httpFilters []*hcm.HttpFilter) ([]*envoylistenerv3.Filter, error) {
+func getListenerResourceFilters(virtualHosts []*envoyroutev3.VirtualHost, httpFilters []*hcm.HttpFilter, localReplyConfig *hcm.LocalReplyConfig) ([]*envoylistenerv3.Filter, error) {
 	manager := &hcm.HttpConnectionManager{
 		CodecType:  hcm.HttpConnectionManager_AUTO,
 		StatPrefix: "ingress_http",
@@ -421,7 +751,8 @@ func getListenerResourceFilters(virtualHosts []*envoyroutev3.VirtualHost, httpFi
 		HttpProtocolOptions: &envoycorev3.Http1ProtocolOptions{
 			EnableTrailers: true,
 		},
-		HttpFilters: httpFilters,
+		HttpFilters:      httpFilters,
+		LocalReplyConfig: localReplyConfig,
 	}
 
 	pbst, err := anypb.New(manager)
@@ -436,3 +767,170 @@ func getListenerResourceFilters(virtualHosts []*envoyroutev3.VirtualHost, httpFi
 
 	return filters, nil
 }
+
+/*
+function envoy_on_request(request_handle)
+local headers = request_handle:headers()
+headers:remove('x-rhoam-portal-session')
+local cookie = headers:get('cookie')
+if cookie ~= nil then
+local session = string.match('; ' .. cookie, '; user_session=([^;]+)')
+if session ~= nil then headers:add('x-rhoam-portal-session', session) end
+end
+end
+*/
+// getPortalSessionHTTPFilter copies the session cookie of the portal requests to a header, for
+// the per session limit to count the requests by it. The header sent by the clients is removed
+func getPortalSessionHTTPFilter(sessionCookie string) (*hcm.HttpFilter, error) {
+	luaFunctionToAddSessionHeader := fmt.Sprintf(
+		"function envoy_on_request(request_handle) local headers = request_handle:headers() headers:remove('%[1]s') local cookie = headers:get('cookie') if cookie ~= nil then local session = string.match('; ' .. cookie, '; %[2]s=([^;]+)') if session ~= nil then headers:add('%[1]s', session) end end end",
+		portalSessionHeaderName, sessionCookie,
+	)
+
+	pbst, err := anypb.New(&lua.Lua{
+		InlineCode: luaFunctionToAddSessionHeader,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert portal session lua filter for rate limiting: %v", err)
+	}
+
+	return &hcm.HttpFilter{
+		Name: "envoy.filters.http.lua",
+		ConfigType: &hcm.HttpFilter_TypedConfig{
+			TypedConfig: pbst,
+		},
+	}, nil
+}
+
+/*
+*
+
+	httpFilters:
+	- name: envoy.filters.http.lua
+	- config:
+		domain: system-provider-ratelimit
+		rate_limit_service:
+		grpc_service:
+			envoy_grpc:
+			cluster_name: ratelimit
+			timeout: 2s
+		stage: 0
+	  name: envoy.filters.http.ratelimit
+	- name: envoy.filters.http.router
+
+*
+*/
+// getPortalHTTPFilters checks the portal requests against the limits of the Limitador namespace
+// of the portal
+func getPortalHTTPFilters(domain string, portalConfig *marin3rconfig.PortalConfig) ([]*hcm.HttpFilter, error) {
+	sessionFilter, err := getPortalSessionHTTPFilter(portalConfig.GetSessionCookie())
+	if err != nil {
+		return nil, err
+	}
+
+	ratelimitFilter, err := getRateLimitHTTPFilter(domain, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	routerFilter, err := getRouterHTTPFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	return []*hcm.HttpFilter{sessionFilter, ratelimitFilter, routerFilter}, nil
+}
+
+/*
+*
+virtual_hosts:
+  - name: system-provider-ratelimit
+    domains: ["*"]
+    routes:
+  - match:
+    prefix: "/"
+    route:
+    cluster: system-provider-ratelimit
+    timeout: 75s
+    rate_limits:
+  - actions:
+  - generic_key:
+    descriptor_key: portal_limit
+    descriptor_value: per_client_ip
+  - remote_address: {}
+  - actions:
+  - generic_key:
+    descriptor_key: portal_limit
+    descriptor_value: per_session
+  - request_headers:
+    header_name: x-rhoam-portal-session
+    descriptor_key: portal_session
+
+*
+*/
+// getPortalVirtualHosts rate limits the portal requests by the configured limits. The client IP
+// is the last address of the X-Forwarded-For header set by the OpenShift router, and the
+// requests without a session are not counted by the per session limit
+func getPortalVirtualHosts(clusterName string, portalConfig *marin3rconfig.PortalConfig) []*envoyroutev3.VirtualHost {
+	var rateLimits []*envoyroutev3.RateLimit
+	for _, portalLimit := range portalConfig.Limits() {
+		actions := []*envoyroutev3.RateLimit_Action{{
+			ActionSpecifier: &envoyroutev3.RateLimit_Action_GenericKey_{
+				GenericKey: &envoyroutev3.RateLimit_Action_GenericKey{
+					DescriptorKey:   marin3rconfig.PortalLimitKey,
+					DescriptorValue: portalLimit.Name,
+				},
+			},
+		}}
+
+		if portalLimit.Name == marin3rconfig.PortalLimitPerSession {
+			actions = append(actions, &envoyroutev3.RateLimit_Action{
+				ActionSpecifier: &envoyroutev3.RateLimit_Action_RequestHeaders_{
+					RequestHeaders: &envoyroutev3.RateLimit_Action_RequestHeaders{
+						HeaderName:    portalSessionHeaderName,
+						DescriptorKey: marin3rconfig.PortalSessionKey,
+					},
+				},
+			})
+		} else {
+			actions = append(actions, &envoyroutev3.RateLimit_Action{
+				ActionSpecifier: &envoyroutev3.RateLimit_Action_RemoteAddress_{
+					RemoteAddress: &envoyroutev3.RateLimit_Action_RemoteAddress{},
+				},
+			})
+		}
+
+		rateLimits = append(rateLimits, &envoyroutev3.RateLimit{
+			Stage:   &wrappers.UInt32Value{Value: 0},
+			Actions: actions,
+		})
+	}
+
+	return []*envoyroutev3.VirtualHost{
+		{
+			Name:    clusterName,
+			Domains: []string{"*"},
+
+			Routes: []*envoyroutev3.Route{
+				{
+					Match: &envoyroutev3.RouteMatch{
+						PathSpecifier: &envoyroutev3.RouteMatch_Prefix{
+							Prefix: "/",
+						},
+					},
+					Action: &envoyroutev3.Route_Route{
+						Route: &envoyroutev3.RouteAction{
+							ClusterSpecifier: &envoyroutev3.RouteAction_Cluster{
+								Cluster: clusterName,
+							},
+							Timeout: &duration.Duration{
+								Seconds: 75,
+							},
+							RateLimits: rateLimits,
+						},
+					},
+				},
+			},
+		},
+	}
+}
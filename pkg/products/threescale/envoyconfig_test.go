@@ -0,0 +1,215 @@
+package threescale
+
+import (
+	"strings"
+	"testing"
+
+	envoyroutev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	lua "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	envoyratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ratelimit/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
+)
+
+func TestGetDescriptorRateLimits(t *testing.T) {
+	rules := []marin3rconfig.DescriptorRule{
+		{Name: "orders", PathPrefix: "/orders", Method: "POST", PerKey: marin3rconfig.PerKeyUserKey, Unit: "minute", RequestsPerUnit: 10},
+		{Name: "per-client", PerKey: marin3rconfig.PerKeyClientIP, Unit: "second", RequestsPerUnit: 5},
+	}
+
+	rateLimits := getDescriptorRateLimits(rules)
+	if len(rateLimits) != 2 {
+		t.Fatalf("expected a rate limit per rule, got %d", len(rateLimits))
+	}
+
+	orders := rateLimits[0].Actions
+	if len(orders) != 2 {
+		t.Fatalf("expected the rule and per key actions, got %v", orders)
+	}
+	match := orders[0].GetHeaderValueMatch()
+	if match == nil || match.DescriptorKey != marin3rconfig.DescriptorRuleKey || match.DescriptorValue != "orders" {
+		t.Fatalf("expected a header match on the rule, got %v", orders[0])
+	}
+	if len(match.Headers) != 2 || match.Headers[0].Name != ":path" || match.Headers[0].GetStringMatch().GetPrefix() != "/orders" ||
+		match.Headers[1].Name != ":method" || match.Headers[1].GetStringMatch().GetExact() != "POST" {
+		t.Errorf("unexpected headers %v", match.Headers)
+	}
+	if headers := orders[1].GetRequestHeaders(); headers == nil || headers.HeaderName != "user_key" || headers.DescriptorKey != "user_key" {
+		t.Errorf("expected the requests to be counted per user_key, got %v", orders[1])
+	}
+
+	perClient := rateLimits[1].Actions
+	if key := perClient[0].GetGenericKey(); key == nil || key.DescriptorKey != marin3rconfig.DescriptorRuleKey || key.DescriptorValue != "per-client" {
+		t.Errorf("expected a rule without a match to apply to every request, got %v", perClient[0])
+	}
+	if _, ok := perClient[1].ActionSpecifier.(*envoyroutev3.RateLimit_Action_RemoteAddress_); !ok {
+		t.Errorf("expected the requests to be counted per client IP, got %v", perClient[1])
+	}
+}
+
+func TestGetAPICastVirtualHostsWithDescriptorRules(t *testing.T) {
+	installation := &integreatlyv1alpha1.RHMI{
+		Spec: integreatlyv1alpha1.RHMISpec{Type: string(integreatlyv1alpha1.InstallationTypeManagedApi)},
+	}
+	rules := []marin3rconfig.DescriptorRule{{Name: "orders", PathPrefix: "/orders", Unit: "minute", RequestsPerUnit: 10}}
+
+	virtualHosts := getAPICastVirtualHosts(installation, ApicastClusterName, rules)
+	rateLimits := virtualHosts[0].Routes[0].GetRoute().RateLimits
+	if len(rateLimits) != 2 || rateLimits[0] != &tsRatelimitDescriptor {
+		t.Errorf("expected the global limit to be kept alongside the rule, got %v", rateLimits)
+	}
+}
+
+func TestGetShadowHTTPFilter(t *testing.T) {
+	installation := &integreatlyv1alpha1.RHMI{
+		Spec: integreatlyv1alpha1.RHMISpec{Type: string(integreatlyv1alpha1.InstallationTypeMultitenantManagedApi)},
+	}
+	rules := []marin3rconfig.DescriptorRule{
+		{Name: "orders", PathPrefix: "/orders", Method: "POST", PerKey: marin3rconfig.PerKeyUserKey, Unit: "minute", RequestsPerUnit: 10},
+		{Name: "per-client", PerKey: marin3rconfig.PerKeyClientIP, Unit: "second", RequestsPerUnit: 5},
+		{Name: "search", PathPrefix: "/search", Unit: "second", RequestsPerUnit: 5},
+	}
+
+	filter, err := getShadowHTTPFilter(installation, rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	luaFilter := &lua.Lua{}
+	if err := filter.GetTypedConfig().UnmarshalTo(luaFilter); err != nil {
+		t.Fatalf("expected a lua filter: %v", err)
+	}
+	for _, expected := range []string{
+		ratelimit.RateLimitShadowClusterName, ratelimit.RateLimitShadowDomain, "/check_and_report", ", true)",
+		"report({['generic_key'] = 'slowpath'})",
+		"report({['header_match'] = 'per-mt-limit', ['tenant'] = tenant})",
+		"if true and string.sub(path, 1, 7) == '/orders' and method == 'POST' then local key = headers:get('user_key') if key ~= nil then report({['descriptor_rule'] = 'orders', ['user_key'] = key}) end end",
+		"report({['descriptor_rule'] = 'per-client', ['remote_address'] = address})",
+		"if true and string.sub(path, 1, 7) == '/search' then report({['descriptor_rule'] = 'search'}) end",
+	} {
+		if !strings.Contains(luaFilter.InlineCode, expected) {
+			t.Errorf("expected the shadow filter to contain %q, got %s", expected, luaFilter.InlineCode)
+		}
+	}
+}
+
+func TestLuaString(t *testing.T) {
+	if got := luaString(`it's a \ path`); got != `'it\039s a \092 path'` {
+		t.Errorf("unexpected lua string %s", got)
+	}
+}
+
+func TestInsertBeforeRateLimitFilter(t *testing.T) {
+	filters, err := getMultitenantAPICastHTTPFilters(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadowFilter := &hcm.HttpFilter{Name: "shadow"}
+
+	filters = insertBeforeRateLimitFilter(filters, shadowFilter)
+	if len(filters) != 4 || filters[1] != shadowFilter || filters[2].Name != "envoy.filters.http.ratelimit" {
+		t.Errorf("expected the shadow filter after the tenant filter and before the rate limit filter, got %v", filters)
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	responseConfig := &marin3rconfig.ResponseConfig{Enabled: true}
+
+	if getRateLimitLocalReplyConfig(nil) != nil || getRateLimitLocalReplyConfig(&marin3rconfig.ResponseConfig{}) != nil {
+		t.Errorf("expected no local reply config with the rate limit response disabled")
+	}
+	localReplyConfig := getRateLimitLocalReplyConfig(responseConfig)
+	if localReplyConfig == nil || len(localReplyConfig.Mappers) != 1 {
+		t.Fatalf("expected a local reply mapper, got %v", localReplyConfig)
+	}
+	mapper := localReplyConfig.Mappers[0]
+	if code := mapper.Filter.GetStatusCodeFilter().GetComparison().GetValue().GetDefaultValue(); code != 429 {
+		t.Errorf("expected the rejected requests to be mapped, got status code %d", code)
+	}
+	if body := mapper.BodyFormatOverride.GetTextFormatSource().GetInlineString(); body != marin3rconfig.DefaultRejectionTemplate {
+		t.Errorf("expected the default rejection template, got %s", body)
+	}
+
+	filters, err := getBackendListenerHTTPFilters(responseConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	luaFilter := &lua.Lua{}
+	if err := filters[0].GetTypedConfig().UnmarshalTo(luaFilter); err != nil {
+		t.Fatalf("expected a lua filter: %v", err)
+	}
+	for _, expected := range []string{"ratelimit-limit", "ratelimit-remaining", "ratelimit-reset", "retry-after", "3scale-rejection-reason"} {
+		if !strings.Contains(luaFilter.InlineCode, expected) {
+			t.Errorf("expected the response filter to set %s, got %s", expected, luaFilter.InlineCode)
+		}
+	}
+	// the rejected requests are local replies of the rate limit filter, which only pass through
+	// the filters before it. The RateLimit-* headers are set before checking for a rejection, so
+	// the rejected responses get them alongside Retry-After
+	rejected := strings.Index(luaFilter.InlineCode, "x-envoy-ratelimited")
+	for _, header := range []string{"'ratelimit-limit'", "'ratelimit-remaining'", "'ratelimit-reset'"} {
+		if index := strings.Index(luaFilter.InlineCode, header); index < 0 || index > rejected {
+			t.Errorf("expected %s to be set on the rejected responses, got %s", header, luaFilter.InlineCode)
+		}
+	}
+	if !strings.Contains(luaFilter.InlineCode[rejected:], "headers:replace('retry-after', reset)") {
+		t.Errorf("expected Retry-After to be set on the rejected responses, got %s", luaFilter.InlineCode)
+	}
+	ratelimitFilter := &envoyratelimitv3.RateLimit{}
+	if err := filters[1].GetTypedConfig().UnmarshalTo(ratelimitFilter); err != nil {
+		t.Fatalf("expected a rate limit filter: %v", err)
+	}
+	if ratelimitFilter.EnableXRatelimitHeaders != envoyratelimitv3.RateLimit_DRAFT_VERSION_03 {
+		t.Errorf("expected the rate limit headers to be enabled")
+	}
+}
+
+func TestPortalRateLimits(t *testing.T) {
+	portalConfig := &marin3rconfig.PortalConfig{
+		Enabled:     true,
+		PerClientIP: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 300},
+		PerSession:  &marin3rconfig.RateLimitConfig{Unit: "second", RequestsPerUnit: 20},
+	}
+
+	rateLimits := getPortalVirtualHosts(SystemProviderClusterName, portalConfig)[0].Routes[0].GetRoute().RateLimits
+	if len(rateLimits) != 2 {
+		t.Fatalf("expected a rate limit per portal limit, got %v", rateLimits)
+	}
+	perClientIP := rateLimits[0].Actions
+	if key := perClientIP[0].GetGenericKey(); key == nil || key.DescriptorKey != marin3rconfig.PortalLimitKey || key.DescriptorValue != marin3rconfig.PortalLimitPerClientIP {
+		t.Errorf("expected the per client IP limit descriptor, got %v", perClientIP[0])
+	}
+	if _, ok := perClientIP[1].ActionSpecifier.(*envoyroutev3.RateLimit_Action_RemoteAddress_); !ok {
+		t.Errorf("expected the requests to be counted per client IP, got %v", perClientIP[1])
+	}
+	perSession := rateLimits[1].Actions
+	if key := perSession[0].GetGenericKey(); key == nil || key.DescriptorValue != marin3rconfig.PortalLimitPerSession {
+		t.Errorf("expected the per session limit descriptor, got %v", perSession[0])
+	}
+	if headers := perSession[1].GetRequestHeaders(); headers == nil || headers.HeaderName != portalSessionHeaderName || headers.DescriptorKey != marin3rconfig.PortalSessionKey {
+		t.Errorf("expected the requests to be counted per session, got %v", perSession[1])
+	}
+
+	filters, err := getPortalHTTPFilters(ratelimit.RateLimitDeveloperPortalDomain, portalConfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	luaFilter := &lua.Lua{}
+	if err := filters[0].GetTypedConfig().UnmarshalTo(luaFilter); err != nil {
+		t.Fatalf("expected a lua filter: %v", err)
+	}
+	for _, expected := range []string{"headers:remove('" + portalSessionHeaderName + "')", "'; user_session=([^;]+)'"} {
+		if !strings.Contains(luaFilter.InlineCode, expected) {
+			t.Errorf("expected the session filter to contain %q, got %s", expected, luaFilter.InlineCode)
+		}
+	}
+	ratelimitFilter := &envoyratelimitv3.RateLimit{}
+	if err := filters[1].GetTypedConfig().UnmarshalTo(ratelimitFilter); err != nil {
+		t.Fatalf("expected a rate limit filter: %v", err)
+	}
+	if ratelimitFilter.Domain != ratelimit.RateLimitDeveloperPortalDomain {
+		t.Errorf("expected the limits of the developer portal namespace, got %s", ratelimitFilter.Domain)
+	}
+}
@@ -1,12 +1,37 @@
 package threescale
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+)
 
 func TestAlertThreeScaleContainerHighMemorySeverity(t *testing.T) {
-	alert := alertThreeScaleContainerHighMemory("dummy", "dummy-namespace")
-	severity := alert.Labels["severity"]
+	alerts, err := resources.GetAlertRules("threescale", resources.AlertTemplateParams{
+		InstallationName: "dummy",
+		Namespace:        "dummy-observability",
+		ProductNamespace: "dummy-namespace",
+		Values: map[string]interface{}{
+			"ContainerCPUMetric": "container_cpu",
+			"CustomDomain":       false,
+			"ZyncReplicas":       1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error rendering alerts: %v", err)
+	}
 
-	if severity != "info" {
-		t.Fatalf("severity level; Expected: info, Got: %v", severity)
+	for _, alert := range alerts {
+		for _, rule := range alert.Rules.([]monv1.Rule) {
+			if rule.Alert != "ThreeScaleContainerHighMemory" {
+				continue
+			}
+			if severity := rule.Labels["severity"]; severity != "info" {
+				t.Fatalf("severity level; Expected: info, Got: %v", severity)
+			}
+			return
+		}
 	}
+	t.Fatal("ThreeScaleContainerHighMemory alert not found")
 }
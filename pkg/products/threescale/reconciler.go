@@ -40,6 +40,7 @@ import (
 
 	"github.com/integr8ly/integreatly-operator/pkg/resources/events"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/slo"
 
 	"github.com/integr8ly/integreatly-operator/pkg/resources/backup"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/owner"
@@ -49,6 +50,7 @@ import (
 	crov1 "github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1"
 	"github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1/types"
 	croUtil "github.com/integr8ly/cloud-resource-operator/pkg/client"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/audit"
 	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
 
 	threescalev1 "github.com/3scale/3scale-operator/apis/apps/v1alpha1"
@@ -57,6 +59,7 @@ import (
 	keycloak "github.com/integr8ly/keycloak-client/apis/keycloak/v1alpha1"
 
 	"github.com/integr8ly/integreatly-operator/pkg/config"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
 	"github.com/integr8ly/integreatly-operator/pkg/products/rhsso"
 	"github.com/integr8ly/integreatly-operator/pkg/resources"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/cluster"
@@ -70,6 +73,7 @@ import (
 	usersv1 "github.com/openshift/api/user/v1"
 	appsv1Client "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	oauthClient "github.com/openshift/client-go/oauth/clientset/versioned/typed/oauth/v1"
+	k8sappsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -507,7 +511,8 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 		return phase, err
 	}
 
-	phase, err = r.reconcileRatelimitingTo3scaleComponents(ctx, serverClient, r.installation)
+	envoySidecarConfig := ratelimit.GetEnvoySidecarConfig(productConfig)
+	phase, err = r.reconcileRatelimitingTo3scaleComponents(ctx, serverClient, r.installation, envoySidecarConfig)
 	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 		events.HandleError(r.recorder, installation, phase, "Failed to reconcile rate limiting to 3scale components", err)
 		return phase, err
@@ -561,6 +566,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, installation *integreatlyv1a
 	productStatus.Host = r.Config.GetHost()
 	productStatus.Version = r.Config.GetProductVersion()
 	productStatus.OperatorVersion = r.Config.GetOperatorVersion()
+	productStatus.Components = []integreatlyv1alpha1.ComponentSizingStatus{envoySidecarConfig.GetStatus()}
 
 	events.HandleProductComplete(r.recorder, installation, integreatlyv1alpha1.ProductsStage, r.Config.GetProductName())
 	r.log.Infof("Installation reconciled successfully", l.Fields{"productStatus": r.Config.GetProductName()})
@@ -679,12 +685,12 @@ func (r *Reconciler) reconcileSMTPCredentials(ctx context.Context, serverClient
 			}
 
 			if smtpUpdated {
-				err = r.RolloutDeployment(ctx, "system-app")
+				err = r.RolloutDeployment(ctx, serverClient, "system-app")
 				if err != nil {
 					r.log.Error("Rollout system-app deployment", err)
 				}
 
-				err = r.RolloutDeployment(ctx, "system-sidekiq")
+				err = r.RolloutDeployment(ctx, serverClient, "system-sidekiq")
 				if err != nil {
 					r.log.Error("Rollout system-sidekiq deployment", err)
 				}
@@ -951,12 +957,17 @@ func (r *Reconciler) resyncRoutes(ctx context.Context, client k8sclient.Client)
 	ns := r.Config.GetNamespace()
 	podname := ""
 
+	podSelector, err := r.getPodSelector(ctx, client, "system-sidekiq")
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
 	pods := &corev1.PodList{}
 	listOpts := []k8sclient.ListOption{
 		k8sclient.InNamespace(ns),
-		k8sclient.MatchingLabels(map[string]string{"deploymentConfig": "system-sidekiq"}),
+		k8sclient.MatchingLabels(podSelector),
 	}
-	err := client.List(ctx, pods, listOpts...)
+	err = client.List(ctx, pods, listOpts...)
 	if err != nil {
 		r.log.Error("Error getting list of pods", err)
 		return integreatlyv1alpha1.PhaseFailed, err
@@ -1468,7 +1479,7 @@ func (r *Reconciler) getOAuthClientName() string {
 	return r.installation.Spec.NamespacePrefix + string(r.Config.GetProductName())
 }
 
-func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, _ *integreatlyv1alpha1.RHMI, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
+func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, installation *integreatlyv1alpha1.RHMI, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
 	r.log.Info("Reconciling openshift users to 3scale")
 
 	rhssoConfig, err := r.ConfigManager.ReadRHSSO()
@@ -1498,6 +1509,29 @@ func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, _ *integreatly
 		return integreatlyv1alpha1.PhaseInProgress, err
 	}
 
+	groupMappings, err := userHelper.GetGroupMappings(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		r.log.Info("Failed to retrieve group mappings: " + err.Error())
+		return integreatlyv1alpha1.PhaseInProgress, err
+	}
+	syncRules, err := userHelper.GetSyncRules(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		r.log.Info("Failed to retrieve user sync rules: " + err.Error())
+		return integreatlyv1alpha1.PhaseInProgress, err
+	}
+	openshiftGroups := &usersv1.GroupList{}
+	err = serverClient.List(ctx, openshiftGroups)
+	if err != nil {
+		r.log.Info("Failed to list openshift groups: " + err.Error())
+		return integreatlyv1alpha1.PhaseInProgress, err
+	}
+
+	// Excluded users are left out of the diff, removing them from 3scale when they were
+	// synchronised before being excluded
+	kcu, err = filterExcludedUsers(ctx, serverClient, kcu, syncRules, openshiftGroups)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseInProgress, err
+	}
 	added, deleted, updated := r.getUserDiff(ctx, serverClient, kcu, tsUsers.Users)
 	// reset the user action metric before we re-reconcile
 	// in order to get up to date metrics on user creation
@@ -1520,6 +1554,14 @@ func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, _ *integreatly
 			}
 
 			metrics.SetThreeScaleUserAction(statusCode, strconv.Itoa(tsUser.UserDetails.Id), http.MethodDelete)
+			audit.Record(ctx, audit.Entry{
+				Product:  string(integreatlyv1alpha1.Product3Scale),
+				Action:   audit.ActionDelete,
+				Resource: "user/" + tsUser.UserDetails.Username,
+				Reason:   "user no longer exists in RHSSO",
+				Before:   tsUser.UserDetails,
+				Result:   audit.ResultOfStatus(statusCode, http.StatusOK, err),
+			})
 
 			if statusCode != http.StatusOK {
 				r.log.Error(fmt.Sprintf("Failed to delete keycloak user %d from 3scale with status code %d", tsUser.UserDetails.Id, statusCode), errors.New("error on http request"))
@@ -1536,7 +1578,17 @@ func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, _ *integreatly
 				continue
 			}
 
-			_, err = r.tsClient.UpdateUser(tsUser.UserDetails.Id, strings.ToLower(genKcUser.Spec.User.UserName), tsUser.UserDetails.Email, *accessToken)
+			newUserName := strings.ToLower(genKcUser.Spec.User.UserName)
+			_, err = r.tsClient.UpdateUser(tsUser.UserDetails.Id, newUserName, tsUser.UserDetails.Email, *accessToken)
+			audit.Record(ctx, audit.Entry{
+				Product:  string(integreatlyv1alpha1.Product3Scale),
+				Action:   audit.ActionUpdate,
+				Resource: "user/" + tsUser.UserDetails.Username,
+				Reason:   "user name changed in RHSSO",
+				Before:   map[string]string{"username": tsUser.UserDetails.Username},
+				After:    map[string]string{"username": newUserName},
+				Result:   audit.ResultOf(err),
+			})
 			if err != nil {
 				r.log.Warning("Failed to updating 3scale user details: " + err.Error())
 			}
@@ -1567,6 +1619,14 @@ func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, _ *integreatly
 			// The reconciler will continue to allow the installation to happen and a metric
 			// will be exposed and alert fire to alert to the creation failure
 			metrics.SetThreeScaleUserAction(statusCode, kcUser.UserName, http.MethodPost)
+			audit.Record(ctx, audit.Entry{
+				Product:  string(integreatlyv1alpha1.Product3Scale),
+				Action:   audit.ActionCreate,
+				Resource: "user/" + strings.ToLower(kcUser.UserName),
+				Reason:   "user added to RHSSO",
+				After:    map[string]string{"username": strings.ToLower(kcUser.UserName), "email": strings.ToLower(kcUser.Email)},
+				Result:   audit.ResultOfStatus(statusCode, http.StatusCreated, err),
+			})
 
 			if statusCode != http.StatusCreated {
 				r.log.Error(fmt.Sprintf("Failed to add keycloak user %s to 3scale with status code %d", kcUser.UserName, statusCode), errors.New("error on http request"))
@@ -1580,21 +1640,15 @@ func (r *Reconciler) reconcileOpenshiftUsers(ctx context.Context, _ *integreatly
 		return phase, err
 	}
 
-	openshiftAdminGroup := &usersv1.Group{}
-	err = serverClient.Get(ctx, k8sclient.ObjectKey{Name: "dedicated-admins"}, openshiftAdminGroup)
-	if err != nil && !k8serr.IsNotFound(err) {
-		r.log.Info("Failed to retrieve dedicated admins: " + err.Error())
-		return integreatlyv1alpha1.PhaseInProgress, err
-	}
 	newTsUsers, err := r.tsClient.GetUsers(*accessToken)
 	if err != nil {
 		r.log.Info("Failed to get users: " + err.Error())
 		return integreatlyv1alpha1.PhaseInProgress, err
 	}
 
-	err = syncOpenshiftAdminMembership(openshiftAdminGroup, newTsUsers, *systemAdminUsername, r.tsClient, *accessToken)
+	err = syncOpenshiftGroupMembership(ctx, groupMappings, openshiftGroups, newTsUsers, *systemAdminUsername, r.tsClient, *accessToken)
 	if err != nil {
-		r.log.Info("Failed to sync openshift admin membership: " + err.Error())
+		r.log.Info("Failed to sync openshift group membership: " + err.Error())
 		return integreatlyv1alpha1.PhaseInProgress, err
 	}
 
@@ -1876,6 +1930,14 @@ func (r *Reconciler) reconcile3scaleMultiTenancy(ctx context.Context, serverClie
 			)
 
 			err = r.tsClient.DeleteTenant(*accessToken, account.Id)
+			audit.Record(ctx, audit.Entry{
+				Product:  string(integreatlyv1alpha1.Product3Scale),
+				Action:   audit.ActionDelete,
+				Resource: "tenant/" + account.OrgName,
+				Reason:   "tenant account broken, deleting for recreation",
+				Before:   account,
+				Result:   audit.ResultOf(err),
+			})
 			if err != nil {
 				r.log.Errorf("Error deleting broken account",
 					l.Fields{
@@ -1921,6 +1983,17 @@ func (r *Reconciler) reconcile3scaleMultiTenancy(ctx context.Context, serverClie
 
 		// Create 3scale account
 		newSignupAccount, err := r.tsClient.CreateTenant(*accessToken, account, pw, emailAddrs[idx])
+		auditEntry := audit.Entry{
+			Product:  string(integreatlyv1alpha1.Product3Scale),
+			Action:   audit.ActionCreate,
+			Resource: "tenant/" + account.OrgName,
+			Reason:   "multi-tenant user added",
+			Result:   audit.ResultOf(err),
+		}
+		if newSignupAccount != nil {
+			auditEntry.After = newSignupAccount.AccountDetail
+		}
+		audit.Record(ctx, auditEntry)
 		if err != nil {
 			r.log.Errorf("Error creating tenant account",
 				l.Fields{"tenantAccountName": account.OrgName},
@@ -1962,6 +2035,16 @@ func (r *Reconciler) reconcile3scaleMultiTenancy(ctx context.Context, serverClie
 		},
 	)
 	err = r.tsClient.DeleteTenants(*accessToken, accountsToBeDeleted)
+	for _, account := range accountsToBeDeleted {
+		audit.Record(ctx, audit.Entry{
+			Product:  string(integreatlyv1alpha1.Product3Scale),
+			Action:   audit.ActionDelete,
+			Resource: "tenant/" + account.OrgName,
+			Reason:   "multi-tenant user removed",
+			Before:   account,
+			Result:   audit.ResultOf(err),
+		})
+	}
 	if err != nil {
 		r.log.Error("error deleting tenant accounts:", err)
 		return integreatlyv1alpha1.PhaseFailed, err
@@ -2308,18 +2391,51 @@ func (r *Reconciler) preUpgradeBackupExecutor() backup.BackupExecutor {
 	)
 }
 
-func syncOpenshiftAdminMembership(openshiftAdminGroup *usersv1.Group, newTsUsers *Users, systemAdminUsername string, tsClient ThreeScaleInterface, accessToken string) error {
+// filterExcludedUsers removes the users excluded by the sync rules from the RHSSO users, so
+// they are never created in 3scale and are deleted from 3scale once excluded
+func filterExcludedUsers(ctx context.Context, serverClient k8sclient.Client, kcUsers []keycloak.KeycloakAPIUser, syncRules *userHelper.SyncRules, openshiftGroups *usersv1.GroupList) ([]keycloak.KeycloakAPIUser, error) {
+	var filtered []keycloak.KeycloakAPIUser
+	for _, kcUser := range kcUsers {
+		osUser := &usersv1.User{}
+		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: kcUser.UserName}, osUser)
+		if k8serr.IsNotFound(err) {
+			osUser.Name = kcUser.UserName
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get openshift user %s: %w", kcUser.UserName, err)
+		}
+
+		if !syncRules.IsExcluded(*osUser, openshiftGroups) {
+			filtered = append(filtered, kcUser)
+		}
+	}
+	return filtered, nil
+}
+
+// syncOpenshiftGroupMembership sets the 3scale role of each user to the role mapped to
+// its OpenShift groups. Users not in any group mapped to a 3scale role are left unchanged
+func syncOpenshiftGroupMembership(ctx context.Context, groupMappings []userHelper.GroupMapping, openshiftGroups *usersv1.GroupList, newTsUsers *Users, systemAdminUsername string, tsClient ThreeScaleInterface, accessToken string) error {
 	for _, tsUser := range newTsUsers.Users {
 		// skip if ts user is the system user admin
 		if tsUser.UserDetails.Username == systemAdminUsername {
 			continue
 		}
 
-		// In workshop mode, developer users also get admin permissions in 3scale
-		if (userIsOpenshiftAdmin(tsUser, openshiftAdminGroup)) && tsUser.UserDetails.Role != adminRole {
-			res, err := tsClient.SetUserAsAdmin(tsUser.UserDetails.Id, accessToken)
-			if err != nil || res.StatusCode != http.StatusOK {
-				return err
+		switch userHelper.GetMappedThreeScaleRole(groupMappings, tsUser.UserDetails.Username, openshiftGroups) {
+		case userHelper.ThreeScaleRoleAdmin:
+			if tsUser.UserDetails.Role != adminRole {
+				res, err := tsClient.SetUserAsAdmin(tsUser.UserDetails.Id, accessToken)
+				recordRoleChange(ctx, tsUser, audit.ActionPromote, adminRole, res, err)
+				if err != nil || res.StatusCode != http.StatusOK {
+					return err
+				}
+			}
+		case userHelper.ThreeScaleRoleMember:
+			if tsUser.UserDetails.Role != memberRole {
+				res, err := tsClient.SetUserAsMember(tsUser.UserDetails.Id, accessToken)
+				recordRoleChange(ctx, tsUser, audit.ActionDemote, memberRole, res, err)
+				if err != nil || res.StatusCode != http.StatusOK {
+					return err
+				}
 			}
 		}
 	}
@@ -2327,6 +2443,22 @@ func syncOpenshiftAdminMembership(openshiftAdminGroup *usersv1.Group, newTsUsers
 	return nil
 }
 
+func recordRoleChange(ctx context.Context, tsUser *User, action string, role string, res *http.Response, err error) {
+	statusCode := http.StatusServiceUnavailable
+	if res != nil {
+		statusCode = res.StatusCode
+	}
+	audit.Record(ctx, audit.Entry{
+		Product:  string(integreatlyv1alpha1.Product3Scale),
+		Action:   action,
+		Resource: "user/" + tsUser.UserDetails.Username,
+		Reason:   "openshift group membership changed",
+		Before:   map[string]string{"role": tsUser.UserDetails.Role},
+		After:    map[string]string{"role": role},
+		Result:   audit.ResultOfStatus(statusCode, http.StatusOK, err),
+	})
+}
+
 func (r *Reconciler) reconcileServiceDiscovery(ctx context.Context, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
 
 	if string(r.Config.GetProductVersion()) != string(integreatlyv1alpha1.Version3Scale) {
@@ -2367,13 +2499,13 @@ func (r *Reconciler) reconcileServiceDiscovery(ctx context.Context, serverClient
 	}
 
 	if status != controllerutil.OperationResultNone {
-		err = r.RolloutDeployment(ctx, "system-app")
+		err = r.RolloutDeployment(ctx, serverClient, "system-app")
 		if err != nil {
 			r.log.Info("Failed to rollout deployment (system-app):" + err.Error())
 			return integreatlyv1alpha1.PhaseInProgress, err
 		}
 
-		err = r.RolloutDeployment(ctx, "system-sidekiq")
+		err = r.RolloutDeployment(ctx, serverClient, "system-sidekiq")
 		if err != nil {
 			r.log.Info("Failed to rollout deployment (system-sidekiq)" + err.Error())
 			return integreatlyv1alpha1.PhaseInProgress, err
@@ -2480,8 +2612,19 @@ func getToken(ctx context.Context, serverClient k8sclient.Client, namespace, tok
 	return &accessToken, nil
 }
 
-func (r *Reconciler) RolloutDeployment(ctx context.Context, name string) error {
-	_, err := r.appsv1Client.DeploymentConfigs(r.Config.GetNamespace()).Instantiate(ctx, name, &appsv1.DeploymentRequest{
+// RolloutDeployment rolls out new pods of the 3scale component, whether it is deployed as a
+// Deployment or a DeploymentConfig
+func (r *Reconciler) RolloutDeployment(ctx context.Context, serverClient k8sclient.Client, name string) error {
+	deployment := &k8sappsv1.Deployment{}
+	err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: name, Namespace: r.Config.GetNamespace()}, deployment)
+	if err == nil {
+		return resources.RestartDeployment(ctx, serverClient, deployment)
+	}
+	if !k8serr.IsNotFound(err) {
+		return err
+	}
+
+	_, err = r.appsv1Client.DeploymentConfigs(r.Config.GetNamespace()).Instantiate(ctx, name, &appsv1.DeploymentRequest{
 		Name:   name,
 		Force:  true,
 		Latest: true,
@@ -2605,16 +2748,6 @@ func tsContainsKc(tsusers []*User, kcUser keycloak.KeycloakAPIUser) bool {
 	return false
 }
 
-func userIsOpenshiftAdmin(tsUser *User, adminGroup *usersv1.Group) bool {
-	for _, userName := range adminGroup.Users {
-		if strings.EqualFold(tsUser.UserDetails.Username, userName) {
-			return true
-		}
-	}
-
-	return false
-}
-
 func (r *Reconciler) getKeycloakClientSpec(id, clientSecret string) keycloak.KeycloakClientSpec {
 	fullScopeAllowed := true
 
@@ -2887,21 +3020,26 @@ func (r *Reconciler) deleteConsoleLink(ctx context.Context, serverClient k8sclie
 func (r *Reconciler) reconcileDeploymentConfigs(ctx context.Context, serverClient k8sclient.Client, productNamespace string) (integreatlyv1alpha1.StatusPhase, error) {
 
 	for _, name := range threeScaleDeploymentConfigs {
-		deploymentConfig := &appsv1.DeploymentConfig{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      name,
-				Namespace: productNamespace,
-			},
+		workload, err := resources.GetWorkload(ctx, serverClient, name, productNamespace)
+		if err != nil {
+			if k8serr.IsNotFound(err) {
+				return integreatlyv1alpha1.PhaseInProgress, nil
+			}
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		templateSelector, err := resources.WorkloadTemplateSelector(workload)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
 		}
 
 		phase, err := resources.UpdatePodTemplateIfExists(
 			ctx,
 			serverClient,
-			resources.SelectFromDeploymentConfig,
+			templateSelector,
 			resources.AllMutationsOf(
 				resources.MutateZoneTopologySpreadConstraints("app"),
 			),
-			deploymentConfig,
+			workload,
 		)
 		if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
 			return phase, err
@@ -2911,49 +3049,41 @@ func (r *Reconciler) reconcileDeploymentConfigs(ctx context.Context, serverClien
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-// Deployment configs are rescaled when adding topologySpreadConstraints, PodTopology etc
-// Should check that these deployment configs are ready before returning phase complete in CR
+// Deployments and deployment configs are rescaled when adding topologySpreadConstraints, PodTopology etc
+// Should check that they are ready before returning phase complete in CR
 func (r *Reconciler) ensureDeploymentConfigsReady(ctx context.Context, serverClient k8sclient.Client, productNamespace string) (integreatlyv1alpha1.StatusPhase, error) {
 	for _, name := range threeScaleDeploymentConfigs {
-		deploymentConfig := &appsv1.DeploymentConfig{}
-
-		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: name, Namespace: productNamespace}, deploymentConfig)
-
+		workload, err := resources.GetWorkload(ctx, serverClient, name, productNamespace)
 		if err != nil {
 			return integreatlyv1alpha1.PhaseFailed, err
 		}
 
-		// Rollout new dc if there is a failed condition
-		for _, condition := range deploymentConfig.Status.Conditions {
-			if condition.Status == corev1.ConditionFalse {
-				r.log.Warningf("3scale dc in a failed condition, rolling out new deployment", l.Fields{"dc": name})
-				err = r.RolloutDeployment(ctx, name)
-				if err != nil {
-					return integreatlyv1alpha1.PhaseFailed, err
-				}
-
-				return integreatlyv1alpha1.PhaseCreatingComponents, nil
+		// Rollout new pods if there is a failed condition
+		if resources.IsWorkloadFailed(workload) {
+			r.log.Warningf("3scale deployment in a failed condition, rolling out new deployment", l.Fields{"deployment": name})
+			err = r.RolloutDeployment(ctx, serverClient, name)
+			if err != nil {
+				return integreatlyv1alpha1.PhaseFailed, err
 			}
+
+			return integreatlyv1alpha1.PhaseCreatingComponents, nil
 		}
 
 		//  Check that replicas are fully rolled out
-		for _, condition := range deploymentConfig.Status.Conditions {
-			if condition.Status != corev1.ConditionTrue || (deploymentConfig.Status.Replicas != deploymentConfig.Status.AvailableReplicas ||
-				deploymentConfig.Status.ReadyReplicas != deploymentConfig.Status.UpdatedReplicas) {
-				r.log.Infof("waiting for 3scale dc to become ready", l.Fields{"dc": name})
-				return integreatlyv1alpha1.PhaseInProgress, fmt.Errorf("waiting for 3scale deployment config %s to become available", name)
-			}
+		if !resources.IsWorkloadReady(workload) {
+			r.log.Infof("waiting for 3scale deployment to become ready", l.Fields{"deployment": name})
+			return integreatlyv1alpha1.PhaseInProgress, fmt.Errorf("waiting for 3scale deployment %s to become available", name)
 		}
 	}
 
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context, serverClient k8sclient.Client, installation *integreatlyv1alpha1.RHMI) (integreatlyv1alpha1.StatusPhase, error) {
+func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context, serverClient k8sclient.Client, installation *integreatlyv1alpha1.RHMI, envoySidecarConfig ratelimit.EnvoySidecarConfig) (integreatlyv1alpha1.StatusPhase, error) {
 
 	r.log.Info("Reconciling rate limiting settings to 3scale components")
 
-	proxyServer := ratelimit.NewEnvoyProxyServer(ctx, serverClient, r.log)
+	proxyServer := ratelimit.NewEnvoyProxyServer(ctx, serverClient, r.log, envoySidecarConfig)
 
 	err := r.createBackendListenerProxyService(ctx, serverClient)
 	if err != nil {
@@ -3048,26 +3178,65 @@ func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context
 		},
 	}
 
+	responseConfig, err := marin3rconfig.GetResponseConfig(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
 	var apicastHTTPFilters []*hcm.HttpFilter
 	// apicast filters based on installation type
 	if !integreatlyv1alpha1.IsRHOAMMultitenant(integreatlyv1alpha1.InstallationType(r.installation.Spec.Type)) {
-		apicastHTTPFilters, err = getAPICastHTTPFilters()
+		apicastHTTPFilters, err = getAPICastHTTPFilters(responseConfig)
 		if err != nil {
 			r.log.Errorf("Failed to create envoyconfig filters for multitenant RHOAM", l.Fields{"APICast": ApicastClusterName}, err)
 			return integreatlyv1alpha1.PhaseFailed, err
 		}
 	} else {
-		apicastHTTPFilters, err = getMultitenantAPICastHTTPFilters()
+		apicastHTTPFilters, err = getMultitenantAPICastHTTPFilters(responseConfig)
 		if err != nil {
 			r.log.Errorf("Failed to create envoyconfig filters for multitenant RHOAM", l.Fields{"APICast": ApicastClusterName}, err)
 			return integreatlyv1alpha1.PhaseFailed, err
 		}
 	}
+	if responseConfig.IsEnabled() {
+		responseFilter, err := getRateLimitResponseHTTPFilter(responseConfig)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		apicastHTTPFilters = append([]*hcm.HttpFilter{responseFilter}, apicastHTTPFilters...)
+	}
+
+	descriptorRules, err := marin3rconfig.GetDescriptorRules(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	apiCastClusters := []*envoyclusterv3.Cluster{apiCastClusterResource, ratelimitClusterResource}
+
+	// shadow mode reports the requests to the shadow limits through the http port of the rate limit service
+	shadowConfig, err := marin3rconfig.GetShadowConfig(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	if shadowConfig.IsEnabled() {
+		shadowFilter, err := getShadowHTTPFilter(installation, descriptorRules)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		apicastHTTPFilters = insertBeforeRateLimitFilter(apicastHTTPFilters, shadowFilter)
+
+		apiCastClusters = append(apiCastClusters, ratelimit.CreateClusterResource(
+			ratelimitServiceCR.Spec.ClusterIP,
+			ratelimit.RateLimitShadowClusterName,
+			getRatelimitServiceHTTPPort(ratelimitServiceCR),
+		))
+	}
 
 	// apicast listener
 	apiCastFilters, err := getListenerResourceFilters(
-		getAPICastVirtualHosts(installation, ApicastClusterName),
+		getAPICastVirtualHosts(installation, ApicastClusterName, descriptorRules),
 		apicastHTTPFilters,
+		getRateLimitLocalReplyConfig(responseConfig),
 	)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
@@ -3084,8 +3253,11 @@ func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context
 	apiCastRuntimes := ratelimit.CreateRuntimesResource()
 
 	// create envoy config for apicast
-	apiCastProxyConfig := ratelimit.NewEnvoyConfig(ApicastClusterName, r.Config.GetNamespace(), ApicastNodeID)
-	err = apiCastProxyConfig.CreateEnvoyConfig(ctx, serverClient, []*envoyclusterv3.Cluster{apiCastClusterResource, ratelimitClusterResource}, []*envoylistenerv3.Listener{apiCastListenerResource}, apiCastRuntimes, installation)
+	apiCastProxyConfig, err := r.newEnvoyConfig(ApicastClusterName, ApicastNodeID)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	err = apiCastProxyConfig.CreateEnvoyConfig(ctx, serverClient, apiCastClusters, []*envoylistenerv3.Listener{apiCastListenerResource}, apiCastRuntimes, installation)
 	if err != nil {
 		r.log.Errorf("Failed to create envoyconfig for apicast", l.Fields{"APICast": ApicastClusterName}, err)
 		return integreatlyv1alpha1.PhaseFailed, err
@@ -3098,7 +3270,7 @@ func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context
 		BackendContainerPort,
 	)
 
-	backendHTTPFilters, err := getBackendListenerHTTPFilters()
+	backendHTTPFilters, err := getBackendListenerHTTPFilters(responseConfig)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
@@ -3106,6 +3278,7 @@ func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context
 	backendFilters, err := getListenerResourceFilters(
 		getBackendListenerVitualHosts(BackendClusterName),
 		backendHTTPFilters,
+		getRateLimitLocalReplyConfig(responseConfig),
 	)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
@@ -3121,13 +3294,173 @@ func (r *Reconciler) reconcileRatelimitingTo3scaleComponents(ctx context.Context
 	backendRuntimes := ratelimit.CreateRuntimesResource()
 
 	// create envoy config for backend listener
-	backendProxyConfig := ratelimit.NewEnvoyConfig(BackendClusterName, r.Config.GetNamespace(), BackendNodeID)
+	backendProxyConfig, err := r.newEnvoyConfig(BackendClusterName, BackendNodeID)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
 	err = backendProxyConfig.CreateEnvoyConfig(ctx, serverClient, []*envoyclusterv3.Cluster{backendClusterResource, ratelimitClusterResource}, []*envoylistenerv3.Listener{backendListenerResource}, backendRuntimes, installation)
 	if err != nil {
 		r.log.Errorf("Failed to create envoyconfig for backend-listener", l.Fields{"BackendListener": BackendClusterName}, err)
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
 
+	// the admin and developer portals are only rate limited once enabled by the customer
+	portalConfig, err := marin3rconfig.GetPortalConfig(ctx, serverClient, installation.Namespace)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	if !portalConfig.IsEnabled() {
+		return r.removePortalRatelimiting(ctx, serverClient, envoySidecarConfig)
+	}
+
+	return r.reconcilePortalRatelimiting(ctx, serverClient, installation, envoySidecarConfig, portalConfig, ratelimitClusterResource)
+}
+
+// newEnvoyConfig returns the EnvoyConfig of the envoy sidecars of a 3scale component, the
+// apicast, backend-listener and system-app revisions are gated by the same health checks, and
+// rolled back when the rejected requests alert starts firing
+func (r *Reconciler) newEnvoyConfig(name, nodeID string) (*ratelimit.EnvoyConfig, error) {
+	rejectedRequestsCheck, err := ratelimit.NewRejectedRequestsHealthCheck(slo.GetServiceURL(config.GetOboNamespace(r.installation.Namespace)))
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.NewEnvoyConfig(name, r.Config.GetNamespace(), nodeID, rejectedRequestsCheck), nil
+}
+
+// portalProxy routes the requests of a portal through the envoy sidecar of system-app
+type portalProxy struct {
+	routeLabel       string
+	serviceName      string
+	proxyServiceName string
+	clusterName      string
+	domain           string
+	containerPort    int
+	proxyPort        int
+	proxyPortName    string
+}
+
+var portalProxies = []portalProxy{
+	{
+		routeLabel:       labelRouteToSystemProvider,
+		serviceName:      "system-provider",
+		proxyServiceName: SystemProviderServiceName,
+		clusterName:      SystemProviderClusterName,
+		domain:           ratelimit.RateLimitProviderPortalDomain,
+		containerPort:    SystemProviderContainerPort,
+		proxyPort:        SystemProviderEnvoyProxyPort,
+		proxyPortName:    SystemProviderEnvoyProxyPortName,
+	},
+	{
+		routeLabel:       labelRouteToSystemDeveloper,
+		serviceName:      "system-developer",
+		proxyServiceName: SystemDeveloperServiceName,
+		clusterName:      SystemDeveloperClusterName,
+		domain:           ratelimit.RateLimitDeveloperPortalDomain,
+		containerPort:    SystemDeveloperContainerPort,
+		proxyPort:        SystemDeveloperEnvoyProxyPort,
+		proxyPortName:    SystemDeveloperEnvoyProxyPortName,
+	},
+}
+
+// reconcilePortalRatelimiting adds the envoy sidecar to system-app and routes the admin and
+// developer portals through it, each portal is rate limited in its own Limitador namespace
+func (r *Reconciler) reconcilePortalRatelimiting(ctx context.Context, serverClient k8sclient.Client, installation *integreatlyv1alpha1.RHMI, envoySidecarConfig ratelimit.EnvoySidecarConfig, portalConfig *marin3rconfig.PortalConfig, ratelimitClusterResource *envoyclusterv3.Cluster) (integreatlyv1alpha1.StatusPhase, error) {
+
+	r.log.Info("Reconciling rate limiting settings to 3scale portals")
+
+	clusters := []*envoyclusterv3.Cluster{ratelimitClusterResource}
+	var listeners []*envoylistenerv3.Listener
+	var proxyPorts []ratelimit.EnvoyProxyPort
+	for _, portal := range portalProxies {
+		clusters = append(clusters, ratelimit.CreateClusterResource(
+			SystemAppContainerAddress,
+			portal.clusterName,
+			portal.containerPort,
+		))
+
+		httpFilters, err := getPortalHTTPFilters(portal.domain, portalConfig)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		filters, err := getListenerResourceFilters(
+			getPortalVirtualHosts(portal.clusterName, portalConfig),
+			httpFilters,
+			nil,
+		)
+		if err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		listeners = append(listeners, ratelimit.CreateListenerResource(
+			portal.clusterName,
+			SystemAppEnvoyProxyAddress,
+			portal.proxyPort,
+			filters,
+		))
+
+		proxyPorts = append(proxyPorts, ratelimit.EnvoyProxyPort{Name: portal.proxyPortName, Port: portal.proxyPort})
+	}
+
+	// create envoy config for system-app
+	systemAppProxyConfig, err := r.newEnvoyConfig(SystemAppNodeID, SystemAppNodeID)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	err = systemAppProxyConfig.CreateEnvoyConfig(ctx, serverClient, clusters, listeners, ratelimit.CreateRuntimesResource(), installation)
+	if err != nil {
+		r.log.Errorf("Failed to create envoyconfig for system-app", l.Fields{"SystemApp": SystemAppNodeID}, err)
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	// creates envoy proxy sidecar container for system-app
+	proxyServer := ratelimit.NewEnvoyProxyServer(ctx, serverClient, r.log, envoySidecarConfig)
+	phase, err := proxyServer.AddEnvoyProxySidecar(systemAppDCName, r.Config.GetNamespace(), SystemAppNodeID, proxyPorts...)
+	if phase != integreatlyv1alpha1.PhaseCompleted {
+		return phase, err
+	}
+
+	for _, portal := range portalProxies {
+		if err := r.createPortalProxyService(ctx, serverClient, portal); err != nil {
+			return integreatlyv1alpha1.PhaseInProgress, err
+		}
+		if err := r.routePortalToService(ctx, serverClient, portal.routeLabel, portal.proxyServiceName); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+	}
+
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
+
+// removePortalRatelimiting routes the admin and developer portals back to system-app and removes
+// its envoy sidecar
+func (r *Reconciler) removePortalRatelimiting(ctx context.Context, serverClient k8sclient.Client, envoySidecarConfig ratelimit.EnvoySidecarConfig) (integreatlyv1alpha1.StatusPhase, error) {
+	for _, portal := range portalProxies {
+		if err := r.routePortalToService(ctx, serverClient, portal.routeLabel, portal.serviceName); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, err
+		}
+		if err := k8sclient.IgnoreNotFound(serverClient.Delete(ctx, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      portal.proxyServiceName,
+				Namespace: r.Config.GetNamespace(),
+			},
+		})); err != nil {
+			return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to delete %s service: %v", portal.proxyServiceName, err)
+		}
+	}
+
+	proxyServer := ratelimit.NewEnvoyProxyServer(ctx, serverClient, r.log, envoySidecarConfig)
+	phase, err := proxyServer.RemoveEnvoyProxySidecar(systemAppDCName, r.Config.GetNamespace())
+	if phase != integreatlyv1alpha1.PhaseCompleted {
+		return phase, err
+	}
+
+	systemAppProxyConfig, err := r.newEnvoyConfig(SystemAppNodeID, SystemAppNodeID)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+	if err := systemAppProxyConfig.DeleteEnvoyConfig(ctx, serverClient); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
@@ -3158,8 +3491,24 @@ func getRatelimitServicePort(rateLimitService *corev1.Service) int {
 	return 0
 }
 
+// getRatelimitServiceHTTPPort returns the http port of the rate limit service. The service port
+// is returned as the cluster connects to the ClusterIP, which also works for named target ports
+func getRatelimitServiceHTTPPort(rateLimitService *corev1.Service) int {
+	for _, port := range rateLimitService.Spec.Ports {
+		if port.Name == "http" {
+			return int(port.Port)
+		}
+	}
+	return 0
+}
+
 func (r *Reconciler) createBackendListenerProxyService(ctx context.Context, serverClient k8sclient.Client) error {
 
+	podSelector, err := r.getPodSelector(ctx, serverClient, backendListenerDCName)
+	if err != nil {
+		return err
+	}
+
 	backendListenerService := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      BackendServiceName,
@@ -3177,9 +3526,7 @@ func (r *Reconciler) createBackendListenerProxyService(ctx context.Context, serv
 				TargetPort: intstr.FromInt(BackendEnvoyProxyPort),
 			},
 		}
-		backendListenerService.Spec.Selector = map[string]string{
-			"deploymentConfig": backendListenerDCName,
-		}
+		backendListenerService.Spec.Selector = podSelector
 		return nil
 	}); err != nil {
 		return err
@@ -3203,6 +3550,111 @@ func (r *Reconciler) createBackendListenerProxyService(ctx context.Context, serv
 	return nil
 }
 
+// createPortalProxyService exposes the envoy sidecar port of the portal. The named target port
+// only selects the system-app pods the envoy sidecar has been added to
+func (r *Reconciler) createPortalProxyService(ctx context.Context, serverClient k8sclient.Client, portal portalProxy) error {
+
+	podSelector, err := r.getPodSelector(ctx, serverClient, systemAppDCName)
+	if err != nil {
+		return err
+	}
+
+	portalService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      portal.proxyServiceName,
+			Namespace: r.Config.GetNamespace(),
+		},
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, serverClient, portalService, func() error {
+		owner.AddIntegreatlyOwnerAnnotations(portalService, r.installation)
+		portalService.Spec.Ports = []corev1.ServicePort{
+			{
+				Name:       "http",
+				Protocol:   "TCP",
+				Port:       int32(portal.proxyPort),
+				TargetPort: intstr.FromString(portal.proxyPortName),
+			},
+		}
+		portalService.Spec.Selector = podSelector
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// routePortalToService points the routes of the portal, one per tenant, to the service
+func (r *Reconciler) routePortalToService(ctx context.Context, serverClient k8sclient.Client, routeLabel, serviceName string) error {
+	routed, err := routePortalToService(ctx, serverClient, r.Config.GetNamespace(), routeLabel, serviceName)
+	for _, route := range routed {
+		r.log.Infof("Routed 3scale portal route to service",
+			l.Fields{"Route": route, "ServiceName": serviceName},
+		)
+	}
+	return err
+}
+
+// ReconcilePortalRoutes points the routes of the rate limited portals back to the envoy sidecar
+// of system-app, and returns the routes it had to point back. The portal routes are owned by
+// zync, which resets them to the system-provider and system-developer services when it syncs
+// the tenant domains. The portal requests bypass the rate limits until the routes are pointed
+// back, which the installation reconcile only does every resync period
+func ReconcilePortalRoutes(ctx context.Context, serverClient k8sclient.Client, installationNamespace, namespace string) ([]string, error) {
+	portalConfig, err := marin3rconfig.GetPortalConfig(ctx, serverClient, installationNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if !portalConfig.IsEnabled() {
+		return nil, nil
+	}
+
+	var rerouted []string
+	for _, portal := range portalProxies {
+		// the proxy service is created once the envoy sidecar of system-app is ready
+		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: portal.proxyServiceName, Namespace: namespace}, &corev1.Service{})
+		if k8serr.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return rerouted, fmt.Errorf("failed to get %s service: %v", portal.proxyServiceName, err)
+		}
+		routed, err := routePortalToService(ctx, serverClient, namespace, portal.routeLabel, portal.proxyServiceName)
+		rerouted = append(rerouted, routed...)
+		if err != nil {
+			return rerouted, err
+		}
+	}
+	return rerouted, nil
+}
+
+// routePortalToService points the routes of the portal to the service, and returns the routes
+// which pointed to another service
+func routePortalToService(ctx context.Context, serverClient k8sclient.Client, namespace, routeLabel, serviceName string) ([]string, error) {
+	routes := &routev1.RouteList{}
+	if err := serverClient.List(ctx, routes,
+		k8sclient.InNamespace(namespace),
+		k8sclient.MatchingLabels{"zync.3scale.net/route-to": routeLabel},
+	); err != nil {
+		return nil, fmt.Errorf("error listing the %s routes: %v", routeLabel, err)
+	}
+
+	var routed []string
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		if route.Spec.To.Name == serviceName {
+			continue
+		}
+		route.Spec.To.Name = serviceName
+		if err := serverClient.Update(ctx, route); err != nil {
+			return routed, fmt.Errorf("error updating the %s route to the %s service: %v", route.Name, serviceName, err)
+		}
+		routed = append(routed, route.Name)
+	}
+	return routed, nil
+}
+
 func (r *Reconciler) getBackendListenerRoute(ctx context.Context, serverClient k8sclient.Client) (*routev1.Route, error) {
 	backendRoute := &routev1.Route{}
 	err := serverClient.Get(ctx, k8sclient.ObjectKey{
@@ -3497,25 +3949,43 @@ func (r *Reconciler) reconcileServiceMonitor(ctx context.Context, client k8sclie
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func (r *Reconciler) reconcileDcEnvarEmailAddress(ctx context.Context, serverClient k8sclient.Client, dcName string, updateFn func(dc *appsv1.DeploymentConfig, value string) bool) (integreatlyv1alpha1.StatusPhase, error) {
+func (r *Reconciler) reconcileDcEnvarEmailAddress(ctx context.Context, serverClient k8sclient.Client, dcName string, updateFn func(template *corev1.PodTemplateSpec, value string) bool) (integreatlyv1alpha1.StatusPhase, error) {
 	existingSMTPFromAddress, err := resources.GetSMTPFromAddress(ctx, serverClient, r.log, r.installation)
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
 
+	// changing the pod template of a Deployment rolls it out
+	deployment := &k8sappsv1.Deployment{}
+	err = serverClient.Get(ctx, k8sclient.ObjectKey{Name: dcName, Namespace: r.Config.GetNamespace()}, deployment)
+	if err == nil {
+		if updateFn(&deployment.Spec.Template, existingSMTPFromAddress) {
+			if err := serverClient.Update(ctx, deployment); err != nil {
+				return integreatlyv1alpha1.PhaseFailed, err
+			}
+		}
+		return integreatlyv1alpha1.PhaseCompleted, nil
+	}
+	if !k8serr.IsNotFound(err) {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
 	dc, err := r.appsv1Client.DeploymentConfigs(r.Config.GetNamespace()).Get(ctx, dcName, metav1.GetOptions{})
 	if err != nil {
 		return integreatlyv1alpha1.PhaseFailed, err
 	}
+	if dc.Spec.Template == nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("deploymentconfig %s has no pod template", dcName)
+	}
 
-	updated := updateFn(dc, existingSMTPFromAddress)
+	updated := updateFn(dc.Spec.Template, existingSMTPFromAddress)
 
 	if updated {
 		_, err = r.appsv1Client.DeploymentConfigs(dc.Namespace).Update(ctx, dc, metav1.UpdateOptions{})
 		if err != nil {
 			return integreatlyv1alpha1.PhaseFailed, err
 		}
-		err = r.RolloutDeployment(ctx, dcName)
+		err = r.RolloutDeployment(ctx, serverClient, dcName)
 		if err != nil {
 			r.log.Error(fmt.Sprintf("Rollout %v deployment", dcName), err)
 			return integreatlyv1alpha1.PhaseFailed, err
@@ -3526,6 +3996,20 @@ func (r *Reconciler) reconcileDcEnvarEmailAddress(ctx context.Context, serverCli
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
+// getPodSelector returns the labels of the pods of the 3scale component, 3scale labels the pods
+// of DeploymentConfigs with the name of the deploymentConfig
+func (r *Reconciler) getPodSelector(ctx context.Context, serverClient k8sclient.Client, name string) (map[string]string, error) {
+	deployment := &k8sappsv1.Deployment{}
+	err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: name, Namespace: r.Config.GetNamespace()}, deployment)
+	if err == nil && deployment.Spec.Selector != nil {
+		return deployment.Spec.Selector.MatchLabels, nil
+	}
+	if err != nil && !k8serr.IsNotFound(err) {
+		return nil, err
+	}
+	return map[string]string{"deploymentConfig": name}, nil
+}
+
 func (r *Reconciler) syncInvitationEmail(ctx context.Context, serverClient k8sclient.Client) (integreatlyv1alpha1.StatusPhase, error) {
 	fromAddress, err := resources.GetSMTPFromAddress(ctx, serverClient, r.log, r.installation)
 	if err != nil {
@@ -3535,10 +4019,15 @@ func (r *Reconciler) syncInvitationEmail(ctx context.Context, serverClient k8scl
 	ns := r.Config.GetNamespace()
 	podname := ""
 
+	podSelector, err := r.getPodSelector(ctx, serverClient, systemAppDCName)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
 	pods := &corev1.PodList{}
 	listOpts := []k8sclient.ListOption{
 		k8sclient.InNamespace(ns),
-		k8sclient.MatchingLabels(map[string]string{"deploymentConfig": systemAppDCName}),
+		k8sclient.MatchingLabels(podSelector),
 	}
 	err = serverClient.List(ctx, pods, listOpts...)
 	if err != nil {
@@ -3573,22 +4062,22 @@ func (r *Reconciler) syncInvitationEmail(ctx context.Context, serverClient k8scl
 	}
 }
 
-func updateContainerSupportEmail(dc *appsv1.DeploymentConfig, existingSMTPFromAddress string, envar string) bool {
+func updateContainerSupportEmail(template *corev1.PodTemplateSpec, existingSMTPFromAddress string, envar string) bool {
 	updated := false
-	for index, container := range dc.Spec.Template.Spec.Containers {
+	for index, container := range template.Spec.Containers {
 		found := false
 		for i, envVar := range container.Env {
 			if envVar.Name == envar {
 				found = true
 				if envVar.Value != existingSMTPFromAddress {
-					dc.Spec.Template.Spec.Containers[index].Env[i].Value = existingSMTPFromAddress
+					template.Spec.Containers[index].Env[i].Value = existingSMTPFromAddress
 					updated = true
 				}
 			}
 		}
 		if !found {
 
-			dc.Spec.Template.Spec.Containers[index].Env = append(dc.Spec.Template.Spec.Containers[index].Env, corev1.EnvVar{
+			template.Spec.Containers[index].Env = append(template.Spec.Containers[index].Env, corev1.EnvVar{
 				Name:  envar,
 				Value: existingSMTPFromAddress,
 			})
@@ -3598,8 +4087,8 @@ func updateContainerSupportEmail(dc *appsv1.DeploymentConfig, existingSMTPFromAd
 	return updated
 }
 
-func updateSystemAppAddresses(dc *appsv1.DeploymentConfig, value string) bool {
-	return updateContainerSupportEmail(dc, value, "SUPPORT_EMAIL")
+func updateSystemAppAddresses(template *corev1.PodTemplateSpec, value string) bool {
+	return updateContainerSupportEmail(template, value, "SUPPORT_EMAIL")
 
 }
 
@@ -3624,9 +4113,9 @@ func (r *Reconciler) checkHTTsProxyApicastServiceReconciled(ctx context.Context,
 	return integreatlyv1alpha1.PhaseCompleted, false, nil
 }
 
-func updateSystemSidekiqAddresses(dc *appsv1.DeploymentConfig, value string) bool {
-	support := updateContainerSupportEmail(dc, value, "SUPPORT_EMAIL")
-	notification := updateContainerSupportEmail(dc, value, "NOTIFICATION_EMAIL")
+func updateSystemSidekiqAddresses(template *corev1.PodTemplateSpec, value string) bool {
+	support := updateContainerSupportEmail(template, value, "SUPPORT_EMAIL")
+	notification := updateContainerSupportEmail(template, value, "NOTIFICATION_EMAIL")
 
 	if support || notification {
 		return true
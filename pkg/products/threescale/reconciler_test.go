@@ -22,10 +22,12 @@ import (
 	"github.com/integr8ly/integreatly-operator/pkg/products/mcg"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/marketplace"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/ratelimit"
 	obv1 "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	noobaav1 "github.com/noobaa/noobaa-operator/v5/pkg/apis/noobaa/v1alpha1"
 	customdomainv1alpha1 "github.com/openshift/custom-domains-operator/api/v1alpha1"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	k8sappsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 
 	moqclient "github.com/integr8ly/integreatly-operator/pkg/client"
@@ -49,6 +51,7 @@ import (
 	oauthClient "github.com/openshift/client-go/oauth/clientset/versioned/typed/oauth/v1"
 
 	"github.com/integr8ly/integreatly-operator/pkg/resources/sts"
+	userHelper "github.com/integr8ly/integreatly-operator/pkg/resources/user"
 	openshiftappsv1 "github.com/openshift/api/apps/v1"
 	cloudcredentialv1 "github.com/openshift/api/operator/v1"
 	fakeappsv1Client "github.com/openshift/client-go/apps/clientset/versioned/fake"
@@ -56,6 +59,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	marin3rv1alpha1 "github.com/3scale-ops/marin3r/apis/marin3r/v1alpha1"
+	marin3rconfig "github.com/integr8ly/integreatly-operator/pkg/products/marin3r/config"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 )
 
 var (
@@ -169,6 +176,9 @@ func TestReconciler_Reconcile3scale(t *testing.T) {
 					GetActiveQuotaFunc: func() string {
 						return quota.OneHundredMillionQuotaName
 					},
+					GetComponentConfigFunc: func(name string) quota.ResourceConfig {
+						return quota.ResourceConfig{}
+					},
 				},
 				uninstall: false,
 			},
@@ -223,6 +233,9 @@ func TestReconciler_Reconcile3scale(t *testing.T) {
 					GetActiveQuotaFunc: func() string {
 						return quota.OneHundredThousandQuotaName
 					},
+					GetComponentConfigFunc: func(name string) quota.ResourceConfig {
+						return quota.ResourceConfig{}
+					},
 				},
 				uninstall: false,
 			},
@@ -277,6 +290,9 @@ func TestReconciler_Reconcile3scale(t *testing.T) {
 					GetActiveQuotaFunc: func() string {
 						return quota.OneMillionQuotaName
 					},
+					GetComponentConfigFunc: func(name string) quota.ResourceConfig {
+						return quota.ResourceConfig{}
+					},
 				},
 				uninstall: false,
 			},
@@ -331,6 +347,9 @@ func TestReconciler_Reconcile3scale(t *testing.T) {
 					GetActiveQuotaFunc: func() string {
 						return quota.FiveMillionQuotaName
 					},
+					GetComponentConfigFunc: func(name string) quota.ResourceConfig {
+						return quota.ResourceConfig{}
+					},
 				},
 				uninstall: false,
 			},
@@ -393,6 +412,9 @@ func TestReconciler_Reconcile3scale(t *testing.T) {
 					GetActiveQuotaFunc: func() string {
 						return quota.TenMillionQuotaName
 					},
+					GetComponentConfigFunc: func(name string) quota.ResourceConfig {
+						return quota.ResourceConfig{}
+					},
 				},
 				uninstall: false,
 			},
@@ -518,6 +540,16 @@ func TestReconciler_Reconcile3scale(t *testing.T) {
 			if got != tt.want {
 				t.Errorf("Reconcile() got = %v, want %v", got, tt.want)
 			}
+			if got == integreatlyv1alpha1.PhaseCompleted {
+				wantComponents := []integreatlyv1alpha1.ComponentSizingStatus{{
+					Name:     quota.EnvoySidecarName,
+					Image:    ratelimit.EnvoyImage,
+					Requests: "cpu=190m,memory=90Mi",
+				}}
+				if !reflect.DeepEqual(tt.args.productStatus.Components, wantComponents) {
+					t.Errorf("Reconcile() components = %v, want %v", tt.args.productStatus.Components, wantComponents)
+				}
+			}
 			if tt.assert {
 				err = tt.assertInstallationSuccessful()
 				if err != nil {
@@ -1008,10 +1040,15 @@ func TestReconciler_syncOpenshiftAdmimMembership(t *testing.T) {
 		},
 	}
 
-	openshiftAdminGroup := &usersv1.Group{
-		Users: usersv1.OptionalNames{
-			"user1",
-			"user2",
+	openshiftGroups := &usersv1.GroupList{
+		Items: []usersv1.Group{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "dedicated-admins"},
+				Users: usersv1.OptionalNames{
+					"user1",
+					"user2",
+				},
+			},
 		},
 	}
 
@@ -1046,7 +1083,7 @@ func TestReconciler_syncOpenshiftAdmimMembership(t *testing.T) {
 		},
 	}
 
-	err := syncOpenshiftAdminMembership(openshiftAdminGroup, newTsUsers, "", &tsClientMock, "")
+	err := syncOpenshiftGroupMembership(context.TODO(), userHelper.DefaultGroupMappings(), openshiftGroups, newTsUsers, "", &tsClientMock, "")
 
 	if err != nil {
 		t.Fatalf("Unexpected error when reconcilling openshift admin membership: %s", err)
@@ -1057,6 +1094,84 @@ func TestReconciler_syncOpenshiftAdmimMembership(t *testing.T) {
 	}
 }
 
+func TestReconciler_syncOpenshiftGroupMembership(t *testing.T) {
+	var demoted []int
+
+	tsClientMock := ThreeScaleInterfaceMock{
+		SetUserAsAdminFunc: func(userID int, accessToken string) (*http.Response, error) {
+			t.Fatalf("Unexpected call to `SetUserAsAdmin`. Called with userID %d", userID)
+
+			return &http.Response{
+				StatusCode: 200,
+			}, nil
+		},
+		SetUserAsMemberFunc: func(userID int, accessToken string) (*http.Response, error) {
+			demoted = append(demoted, userID)
+
+			return &http.Response{
+				StatusCode: 200,
+			}, nil
+		},
+	}
+
+	groupMappings := append(userHelper.DefaultGroupMappings(), userHelper.GroupMapping{
+		OpenshiftGroup: "support",
+		ThreeScaleRole: userHelper.ThreeScaleRoleMember,
+	})
+
+	openshiftGroups := &usersv1.GroupList{
+		Items: []usersv1.Group{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "dedicated-admins"},
+				Users:      usersv1.OptionalNames{"user2"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "support"},
+				Users:      usersv1.OptionalNames{"user1", "user2"},
+			},
+		},
+	}
+
+	newTsUsers := &Users{
+		Users: []*User{
+			{
+				UserDetails: UserDetails{
+					Id:   1,
+					Role: adminRole,
+					// User is only in a group mapped to member. Should be demoted
+					Username: "user1",
+				},
+			},
+			{
+				UserDetails: UserDetails{
+					Id:   2,
+					Role: adminRole,
+					// User is also in a group mapped to admin. Admin takes
+					// precedence so should be ignored
+					Username: "user2",
+				},
+			},
+			{
+				UserDetails: UserDetails{
+					Id:   3,
+					Role: adminRole,
+					// User is not in any mapped group. Should be ignored
+					Username: "user3",
+				},
+			},
+		},
+	}
+
+	err := syncOpenshiftGroupMembership(context.TODO(), groupMappings, openshiftGroups, newTsUsers, "", &tsClientMock, "")
+	if err != nil {
+		t.Fatalf("Unexpected error when reconcilling openshift group membership: %s", err)
+	}
+
+	if len(demoted) != 1 || demoted[0] != 1 {
+		t.Fatalf("Expected only user with ID 1 to be set as member, got %v", demoted)
+	}
+}
+
 func TestReconciler_ensureDeploymentConfigsReady(t *testing.T) {
 	scheme, err := utils.NewTestScheme()
 	if err != nil {
@@ -1133,6 +1248,36 @@ func TestReconciler_ensureDeploymentConfigsReady(t *testing.T) {
 			},
 			want: integreatlyv1alpha1.PhaseCreatingComponents,
 		},
+		{
+			name: "Test - Deployment exceeded its progress deadline - Restart success - PhaseCreatingComponents",
+			fields: fields{
+				Config: config.NewThreeScale(config.ProductConfig{
+					"NAMESPACE": defaultInstallationNamespace,
+				}),
+				log: getLogger(),
+			},
+			args: args{
+				ctx: context.TODO(),
+				serverClient: utils.NewTestClient(scheme,
+					&k8sappsv1.Deployment{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "apicast-production",
+							Namespace: defaultInstallationNamespace,
+						},
+						Status: k8sappsv1.DeploymentStatus{
+							Conditions: []k8sappsv1.DeploymentCondition{
+								{
+									Type:   k8sappsv1.DeploymentProgressing,
+									Status: corev1.ConditionFalse,
+								},
+							},
+						},
+					},
+				),
+				productNamespace: defaultInstallationNamespace,
+			},
+			want: integreatlyv1alpha1.PhaseCreatingComponents,
+		},
 		{
 			name: "Test - Waiting for replicas to be rolled out - Condition Unknown - PhaseInProgress",
 			fields: fields{
@@ -3916,7 +4061,7 @@ func TestReconciler_reconcileSystemAppSupportEmailAddress(t *testing.T) {
 		ctx          context.Context
 		serverClient k8sclient.Client
 		dcName       string
-		updateFn     func(dc *openshiftappsv1.DeploymentConfig, value string) bool
+		updateFn     func(template *corev1.PodTemplateSpec, value string) bool
 	}
 
 	tests := []struct {
@@ -4167,7 +4312,7 @@ func Test_updateContainerSupportEmail(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := updateContainerSupportEmail(tt.args.dc, tt.args.existingSMTPFromAddress, tt.args.envar); got != tt.want {
+			if got := updateContainerSupportEmail(tt.args.dc.Spec.Template, tt.args.existingSMTPFromAddress, tt.args.envar); got != tt.want {
 				t.Errorf("updateContainerSupportEmail() = %v, want %v", got, tt.want)
 			}
 
@@ -4254,7 +4399,7 @@ func Test_updateSystemAppAddresses(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := updateSystemAppAddresses(tt.args.dc, tt.args.value); got != tt.want {
+			if got := updateSystemAppAddresses(tt.args.dc.Spec.Template, tt.args.value); got != tt.want {
 				t.Errorf("updateSystemAppAddresses() = %v, want %v", got, tt.want)
 			}
 		})
@@ -4361,7 +4506,7 @@ func Test_updateSystemSidekiqAddresses(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := updateSystemSidekiqAddresses(tt.args.dc, tt.args.value); got != tt.want {
+			if got := updateSystemSidekiqAddresses(tt.args.dc.Spec.Template, tt.args.value); got != tt.want {
 				t.Errorf("updateSystemSidekiqAddresses() = %v, want %v", got, tt.want)
 			}
 		})
@@ -4799,3 +4944,136 @@ func TestIsQuotaChanged(t *testing.T) {
 		})
 	}
 }
+
+func TestReconciler_portalRatelimiting(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ns = "redhat-test-3scale"
+	systemApp := &k8sappsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: systemAppDCName, Namespace: ns},
+		Spec: k8sappsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"deployment": systemAppDCName}},
+		},
+	}
+	portalRoute := func(name, label, service string) *routev1.Route {
+		return &routev1.Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+				Labels:    map[string]string{"zync.3scale.net/route-to": label},
+			},
+			Spec: routev1.RouteSpec{To: routev1.RouteTargetReference{Kind: "Service", Name: service}},
+		}
+	}
+	client := utils.NewTestClient(scheme, systemApp,
+		portalRoute("zync-3scale-provider-a", labelRouteToSystemProvider, "system-provider"),
+		portalRoute("zync-3scale-developer-a", labelRouteToSystemDeveloper, "system-developer"),
+		portalRoute("zync-3scale-master", "system-master", "system-master"),
+	)
+
+	r := &Reconciler{
+		Config:       config.NewThreeScale(config.ProductConfig{"NAMESPACE": ns}),
+		log:          getLogger(),
+		installation: getTestInstallation("managed"),
+	}
+	portalConfig := &marin3rconfig.PortalConfig{
+		Enabled:     true,
+		PerClientIP: &marin3rconfig.RateLimitConfig{Unit: "minute", RequestsPerUnit: 300},
+	}
+	ratelimitCluster := ratelimit.CreateClusterResource("1.1.1.1", ratelimit.RateLimitClusterName, 8081)
+
+	assertRoute := func(name, service string) {
+		t.Helper()
+		route := &routev1.Route{}
+		if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: name, Namespace: ns}, route); err != nil {
+			t.Fatal(err)
+		}
+		if route.Spec.To.Name != service {
+			t.Errorf("expected the %s route to point to %s, got %s", name, service, route.Spec.To.Name)
+		}
+	}
+
+	phase, err := r.reconcilePortalRatelimiting(context.TODO(), client, r.installation, ratelimit.EnvoySidecarConfig{Image: ratelimit.EnvoyImage}, portalConfig, ratelimitCluster)
+	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+		t.Fatalf("expected the portals to be rate limited, got %s %v", phase, err)
+	}
+	systemAppEnvoyConfig := &marin3rv1alpha1.EnvoyConfig{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: SystemAppNodeID, Namespace: ns}, systemAppEnvoyConfig); err != nil {
+		t.Errorf("expected the system-app envoy config: %v", err)
+	}
+	// the system-app revisions are gated like the apicast and backend-listener ones
+	if systemAppEnvoyConfig.Annotations[ratelimit.EnvoyConfigRevisionAnnotation] == "" {
+		t.Errorf("expected the system-app envoy config to be applied as a revision, got %v", systemAppEnvoyConfig.Annotations)
+	}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: SystemAppNodeID + ratelimit.EnvoyConfigRevisionsSuffix, Namespace: ns}, &corev1.ConfigMap{}); err != nil {
+		t.Errorf("expected the system-app envoy config revisions: %v", err)
+	}
+	deployment := &k8sappsv1.Deployment{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(systemApp), deployment); err != nil {
+		t.Fatal(err)
+	}
+	if ports := deployment.Spec.Template.Annotations["marin3r.3scale.net/ports"]; ports != "envoy-provider:3010,envoy-developer:3011" {
+		t.Errorf("expected the envoy sidecar to listen on the portal ports, got %s", ports)
+	}
+	service := &corev1.Service{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: SystemDeveloperServiceName, Namespace: ns}, service); err != nil {
+		t.Fatal(err)
+	}
+	if service.Spec.Ports[0].TargetPort.StrVal != SystemDeveloperEnvoyProxyPortName || service.Spec.Selector["deployment"] != systemAppDCName {
+		t.Errorf("expected the proxy service to select the envoy sidecar port of system-app, got %v", service.Spec)
+	}
+	assertRoute("zync-3scale-provider-a", SystemProviderServiceName)
+	assertRoute("zync-3scale-developer-a", SystemDeveloperServiceName)
+	assertRoute("zync-3scale-master", "system-master")
+
+	// The routes reset by zync are pointed back to the envoy sidecar while the portals are
+	// rate limited
+	route := &routev1.Route{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: "zync-3scale-provider-a", Namespace: ns}, route); err != nil {
+		t.Fatal(err)
+	}
+	route.Spec.To.Name = "system-provider"
+	if err := client.Update(context.TODO(), route); err != nil {
+		t.Fatal(err)
+	}
+	rerouted, err := ReconcilePortalRoutes(context.TODO(), client, r.installation.Namespace, ns)
+	if err != nil || len(rerouted) != 0 {
+		t.Fatalf("expected the routes to be left while the portals are not rate limited, got %v %v", rerouted, err)
+	}
+	assertRoute("zync-3scale-provider-a", "system-provider")
+
+	if err := client.Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: marin3rconfig.PortalConfigMapName, Namespace: r.installation.Namespace},
+		Data:       map[string]string{marin3rconfig.PortalConfigKey: `{"enabled":true,"per_client_ip":{"unit":"minute","requests_per_unit":300}}`},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rerouted, err = ReconcilePortalRoutes(context.TODO(), client, r.installation.Namespace, ns)
+	if err != nil || len(rerouted) != 1 || rerouted[0] != "zync-3scale-provider-a" {
+		t.Fatalf("expected the provider route to be pointed back, got %v %v", rerouted, err)
+	}
+	assertRoute("zync-3scale-provider-a", SystemProviderServiceName)
+	assertRoute("zync-3scale-developer-a", SystemDeveloperServiceName)
+
+	phase, err = r.removePortalRatelimiting(context.TODO(), client, ratelimit.EnvoySidecarConfig{Image: ratelimit.EnvoyImage})
+	if err != nil || phase != integreatlyv1alpha1.PhaseCompleted {
+		t.Fatalf("expected the portal rate limiting to be removed, got %s %v", phase, err)
+	}
+	assertRoute("zync-3scale-provider-a", "system-provider")
+	assertRoute("zync-3scale-developer-a", "system-developer")
+	if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(systemApp), deployment); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := deployment.Spec.Template.Annotations["marin3r.3scale.net/node-id"]; ok {
+		t.Errorf("expected the envoy sidecar annotations to be removed, got %v", deployment.Spec.Template.Annotations)
+	}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: SystemProviderServiceName, Namespace: ns}, &corev1.Service{}); !k8serr.IsNotFound(err) {
+		t.Errorf("expected the proxy service to be deleted, got %v", err)
+	}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: SystemAppNodeID, Namespace: ns}, &marin3rv1alpha1.EnvoyConfig{}); !k8serr.IsNotFound(err) {
+		t.Errorf("expected the system-app envoy config to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package resources
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// alertRuleFiles holds the alert rules of each product in alertrules/<product>.yaml. Each file
+// is a text/template rendered with AlertTemplateParams. The template delimiters are [[ and ]]
+// so the Prometheus templating used in alert annotations, e.g. {{ $labels.namespace }}, is
+// left untouched. Every alert gets the product label of the installation unless it sets its own
+//
+//go:embed alertrules/*.yaml
+var alertRuleFiles embed.FS
+
+// alertRuleValueFiles holds example Values for the alert rule files that use them, as a list of
+// value sets in alertrules/values/<product>.yaml. ValidateAlertRules renders the rules once per
+// value set so every branch of a template is checked
+//
+//go:embed alertrules/values/*.yaml
+var alertRuleValueFiles embed.FS
+
+const (
+	alertRulesDir           = "alertrules"
+	alertRuleValuesDir      = "alertrules/values"
+	alertTemplateLeftDelim  = "[["
+	alertTemplateRightDelim = "]]"
+)
+
+var (
+	alertCatalogue     map[string]*template.Template
+	alertCatalogueErr  error
+	alertCatalogueOnce sync.Once
+
+	validAlertSeverities = map[string]bool{"critical": true, "warning": true, "info": true}
+)
+
+// AlertTemplateParams are the values available to the alert rule templates
+type AlertTemplateParams struct {
+	InstallationName string
+	// Namespace the PrometheusRules are created in
+	Namespace         string
+	ProductNamespace  string
+	OperatorNamespace string
+	// Values holds the product specific values of a rule file, e.g. replica counts
+	Values map[string]interface{}
+}
+
+var alertTemplateFuncs = template.FuncMap{
+	// sopUrl returns the SOP URL constant with the given name, e.g. [[ sopUrl "SopUrlEndpointAvailableAlert" ]]
+	"sopUrl": getSopUrl,
+	// rhoamSopUrl returns the SOP URL of an alert in the RHOAM alerts directory
+	"rhoamSopUrl": func(alertName string) string {
+		return sopUrlRhoamBase + alertName + ".asciidoc"
+	},
+	"upper": strings.ToUpper,
+}
+
+type alertRuleFile struct {
+	Alerts []alertRuleGroup `json:"alerts"`
+}
+
+type alertRuleGroup struct {
+	Name     string       `json:"name"`
+	Group    string       `json:"group"`
+	Interval string       `json:"interval,omitempty"`
+	Rules    []monv1.Rule `json:"rules"`
+}
+
+// ValidateAlertRules parses every alert rule file and validates the rendered rules, including
+// their PromQL expressions. It is called when the operator starts so an invalid rule is found
+// before it reaches Prometheus
+func ValidateAlertRules() error {
+	catalogue, err := getAlertCatalogue()
+	if err != nil {
+		return err
+	}
+
+	params := AlertTemplateParams{
+		InstallationName:  "rhoam",
+		Namespace:         "redhat-rhoam-observability",
+		ProductNamespace:  "redhat-rhoam-product",
+		OperatorNamespace: "redhat-rhoam-operator",
+	}
+	names := map[string]string{}
+	for product := range catalogue {
+		valueSets, err := getAlertRuleExampleValues(product)
+		if err != nil {
+			return err
+		}
+		productNames := map[string]bool{}
+		for _, values := range valueSets {
+			params.Values = values
+			alerts, err := GetAlertRules(product, params)
+			if err != nil {
+				return err
+			}
+			for _, alert := range alerts {
+				productNames[alert.AlertName] = true
+			}
+		}
+		for name := range productNames {
+			if other, ok := names[name]; ok {
+				return fmt.Errorf("alert %s is defined for both %s and %s", name, other, product)
+			}
+			names[name] = product
+		}
+	}
+	return nil
+}
+
+// getAlertRuleExampleValues returns the example value sets of a rule file. Files without
+// example values are rendered once without Values
+func getAlertRuleExampleValues(product string) ([]map[string]interface{}, error) {
+	data, err := alertRuleValueFiles.ReadFile(path.Join(alertRuleValuesDir, product+".yaml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []map[string]interface{}{nil}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rule values for %s: %w", product, err)
+	}
+
+	var valueSets []map[string]interface{}
+	if err := yaml.Unmarshal(data, &valueSets); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alert rule values for %s: %w", product, err)
+	}
+	if len(valueSets) == 0 {
+		return nil, fmt.Errorf("alert rule values for %s are empty", product)
+	}
+	return valueSets, nil
+}
+
+// AlertRuleFiles returns the names of the alert rule files, e.g. "threescale" for
+// alertrules/threescale.yaml
+func AlertRuleFiles() ([]string, error) {
+	catalogue, err := getAlertCatalogue()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(catalogue))
+	for file := range catalogue {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// GetAlertRules renders the alert rule file of a product into the alert configurations
+// reconciled by AlertReconcilerImpl
+func GetAlertRules(product string, params AlertTemplateParams) ([]AlertConfiguration, error) {
+	catalogue, err := getAlertCatalogue()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, ok := catalogue[product]
+	if !ok {
+		return nil, fmt.Errorf("no alert rules defined for %s", product)
+	}
+
+	return renderAlertRules(product, tmpl, params)
+}
+
+func getAlertCatalogue() (map[string]*template.Template, error) {
+	alertCatalogueOnce.Do(func() {
+		alertCatalogue, alertCatalogueErr = loadAlertCatalogue()
+	})
+	return alertCatalogue, alertCatalogueErr
+}
+
+func loadAlertCatalogue() (map[string]*template.Template, error) {
+	entries, err := alertRuleFiles.ReadDir(alertRulesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+
+	catalogue := map[string]*template.Template{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := alertRuleFiles.ReadFile(path.Join(alertRulesDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read alert rules %s: %w", entry.Name(), err)
+		}
+		product := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		tmpl, err := parseAlertRuleTemplate(product, string(data))
+		if err != nil {
+			return nil, err
+		}
+		catalogue[product] = tmpl
+	}
+	return catalogue, nil
+}
+
+func parseAlertRuleTemplate(product, data string) (*template.Template, error) {
+	tmpl, err := template.New(product).
+		Delims(alertTemplateLeftDelim, alertTemplateRightDelim).
+		Funcs(alertTemplateFuncs).
+		Option("missingkey=error").
+		Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules for %s: %w", product, err)
+	}
+	return tmpl, nil
+}
+
+func renderAlertRules(product string, tmpl *template.Template, params AlertTemplateParams) ([]AlertConfiguration, error) {
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, params); err != nil {
+		return nil, fmt.Errorf("failed to render alert rules for %s: %w", product, err)
+	}
+
+	file := alertRuleFile{}
+	if err := yaml.UnmarshalStrict(rendered.Bytes(), &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alert rules for %s: %w", product, err)
+	}
+
+	var alerts []AlertConfiguration
+	for _, group := range file.Alerts {
+		if err := validateAlertRuleGroup(group); err != nil {
+			return nil, fmt.Errorf("invalid alert rules for %s: %w", product, err)
+		}
+		rules := make([]monv1.Rule, 0, len(group.Rules))
+		for _, rule := range group.Rules {
+			if rule.Alert != "" {
+				if rule.Labels == nil {
+					rule.Labels = map[string]string{}
+				}
+				if _, ok := rule.Labels["product"]; !ok {
+					rule.Labels["product"] = params.InstallationName
+				}
+			}
+			rules = append(rules, rule)
+		}
+		alerts = append(alerts, AlertConfiguration{
+			AlertName: group.Name,
+			GroupName: group.Group,
+			Namespace: params.Namespace,
+			Interval:  group.Interval,
+			Rules:     rules,
+		})
+	}
+	return alerts, nil
+}
+
+func validateAlertRuleGroup(group alertRuleGroup) error {
+	if group.Name == "" || group.Group == "" {
+		return fmt.Errorf("name and group are required")
+	}
+	if len(group.Rules) == 0 {
+		return fmt.Errorf("%s has no rules", group.Name)
+	}
+	if group.Interval != "" {
+		if _, err := model.ParseDuration(group.Interval); err != nil {
+			return fmt.Errorf("%s has an invalid interval: %w", group.Name, err)
+		}
+	}
+	for _, rule := range group.Rules {
+		if err := ValidateAlertRule(rule); err != nil {
+			return fmt.Errorf("%s: %w", group.Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateAlertRule checks that a rule is either an alert or a recording rule with a valid
+// PromQL expression. Alerts must have a known severity
+func ValidateAlertRule(rule monv1.Rule) error {
+	name := rule.Alert
+	if name == "" {
+		name = rule.Record
+	}
+	if (rule.Alert == "") == (rule.Record == "") {
+		return fmt.Errorf("rule %q must set exactly one of alert or record", name)
+	}
+	if _, err := parser.ParseExpr(rule.Expr.String()); err != nil {
+		return fmt.Errorf("rule %s has an invalid expression: %w", name, err)
+	}
+	if rule.For != "" {
+		if _, err := model.ParseDuration(string(rule.For)); err != nil {
+			return fmt.Errorf("rule %s has an invalid for duration: %w", name, err)
+		}
+	}
+	if rule.Alert != "" && !validAlertSeverities[rule.Labels["severity"]] {
+		return fmt.Errorf("alert %s has an invalid severity %q", name, rule.Labels["severity"])
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestValidateAlertRules(t *testing.T) {
+	if err := ValidateAlertRules(); err != nil {
+		t.Fatalf("alert rule catalogue is invalid: %v", err)
+	}
+}
+
+func TestGetAlertRules(t *testing.T) {
+	params := AlertTemplateParams{
+		InstallationName:  "rhoam",
+		Namespace:         "test-observability",
+		ProductNamespace:  "test-marin3r",
+		OperatorNamespace: "test-operator",
+	}
+
+	alerts, err := GetAlertRules("marin3r", params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) == 0 {
+		t.Fatal("expected marin3r alerts")
+	}
+	for _, alert := range alerts {
+		if alert.Namespace != params.Namespace {
+			t.Errorf("expected alert %s in namespace %s, got %s", alert.AlertName, params.Namespace, alert.Namespace)
+		}
+		for _, rule := range alert.Rules.([]monv1.Rule) {
+			if rule.Labels["product"] != params.InstallationName {
+				t.Errorf("expected product label on %s, got %v", rule.Alert, rule.Labels)
+			}
+			if strings.Contains(rule.Expr.String(), "[[") {
+				t.Errorf("expected template to be rendered in %s: %s", rule.Alert, rule.Expr.String())
+			}
+		}
+	}
+
+	if _, err := GetAlertRules("unknown", params); err == nil {
+		t.Error("expected an error for a product without alert rules")
+	}
+}
+
+func TestRenderAlertRules(t *testing.T) {
+	params := AlertTemplateParams{InstallationName: "rhoam", Namespace: "test", OperatorNamespace: "test-operator"}
+
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{
+			name: "valid rules keep the prometheus templating in annotations",
+			rules: `
+alerts:
+  - name: test-alerts
+    group: test.rules
+    rules:
+      - alert: TestAlert
+        annotations:
+          message: "{{ $labels.pod }} is down in [[ .OperatorNamespace ]]"
+        expr: "up{namespace='[[ .OperatorNamespace ]]'} < 1"
+        for: 5m
+        labels:
+          severity: warning
+`,
+		},
+		{
+			name: "invalid expression",
+			rules: `
+alerts:
+  - name: test-alerts
+    group: test.rules
+    rules:
+      - alert: TestAlert
+        expr: "sum(up{namespace='[[ .OperatorNamespace ]]'} < 1"
+        labels:
+          severity: warning
+`,
+			wantErr: "invalid expression",
+		},
+		{
+			name: "invalid for duration",
+			rules: `
+alerts:
+  - name: test-alerts
+    group: test.rules
+    rules:
+      - alert: TestAlert
+        expr: up < 1
+        for: five minutes
+        labels:
+          severity: warning
+`,
+			wantErr: "invalid for duration",
+		},
+		{
+			name: "missing severity",
+			rules: `
+alerts:
+  - name: test-alerts
+    group: test.rules
+    rules:
+      - alert: TestAlert
+        expr: up < 1
+`,
+			wantErr: "invalid severity",
+		},
+		{
+			name: "unknown template param",
+			rules: `
+alerts:
+  - name: test-alerts
+    group: test.rules
+    rules:
+      - alert: TestAlert
+        expr: "up{namespace='[[ .Unknown ]]'} < 1"
+        labels:
+          severity: warning
+`,
+			wantErr: "failed to render",
+		},
+		{
+			name: "unknown field",
+			rules: `
+alerts:
+  - name: test-alerts
+    group: test.rules
+    rules:
+      - alert: TestAlert
+        expression: up < 1
+`,
+			wantErr: "failed to unmarshal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseAlertRuleTemplate("test", tt.rules)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			alerts, err := renderAlertRules("test", tmpl, params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rule := alerts[0].Rules.([]monv1.Rule)[0]
+			if rule.Annotations["message"] != "{{ $labels.pod }} is down in test-operator" {
+				t.Errorf("unexpected message: %s", rule.Annotations["message"])
+			}
+			if rule.Expr != intstr.FromString("up{namespace='test-operator'} < 1") {
+				t.Errorf("unexpected expression: %s", rule.Expr.String())
+			}
+		})
+	}
+}
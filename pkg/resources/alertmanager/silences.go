@@ -0,0 +1,287 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+)
+
+const (
+	// SilenceCreatedBy identifies the silences created by the operator for the duration of an
+	// upgrade or maintenance window. Each silence records the key of the operation it was
+	// created for in its comment so it can be found and expired once the operation completes
+	SilenceCreatedBy = "rhoam-operator"
+
+	SilenceStateActive  = "active"
+	SilenceStatePending = "pending"
+	SilenceStateExpired = "expired"
+
+	// ServiceAccountTokenFile is the token of the operator service account, sent to
+	// Alertmanager so it can be fronted by a proxy authorizing the operator
+	ServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token" // #nosec G101 -- path to the mounted token, not a credential
+
+	requestTimeout = 10 * time.Second
+	// silenceExtendThreshold is how close to its end an extendable silence is moved to the
+	// end of its request, so a silence is not updated on every reconcile
+	silenceExtendThreshold = 15 * time.Minute
+)
+
+// ErrUnauthorized is returned when Alertmanager rejects the operator credentials
+var ErrUnauthorized = errors.New("alertmanager rejected the operator credentials")
+
+var now = time.Now
+
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type SilenceStatus struct {
+	State string `json:"state"`
+}
+
+type Silence struct {
+	ID        string         `json:"id,omitempty"`
+	Matchers  []Matcher      `json:"matchers"`
+	StartsAt  time.Time      `json:"startsAt"`
+	EndsAt    time.Time      `json:"endsAt"`
+	CreatedBy string         `json:"createdBy"`
+	Comment   string         `json:"comment"`
+	Status    *SilenceStatus `json:"status,omitempty"`
+}
+
+// SilenceRequest describes a silence the operator keeps in place while an operation runs
+type SilenceRequest struct {
+	// Key identifies the operation, e.g. "upgrade-1.2.0"
+	Key      string
+	Reason   string
+	Matchers []Matcher
+	// EndsAt bounds the silence in case the operation never completes
+	EndsAt time.Time
+	// MaxDuration extends an existing silence to EndsAt while the operation is still
+	// requested, up to MaxDuration after the silence started. Silences of requests without
+	// a MaxDuration are never extended
+	MaxDuration time.Duration
+}
+
+type SilenceClient interface {
+	ListSilences(ctx context.Context) ([]Silence, error)
+	CreateSilence(ctx context.Context, silence Silence) (string, error)
+	ExpireSilence(ctx context.Context, id string) error
+}
+
+type silenceClient struct {
+	baseURL         string
+	bearerTokenFile string
+	httpClient      *http.Client
+}
+
+var _ SilenceClient = &silenceClient{}
+
+// NewSilenceClient returns a client of the Alertmanager API at baseURL. When bearerTokenFile
+// exists its token is sent with every request, it is read per request as it is rotated
+func NewSilenceClient(baseURL, bearerTokenFile string) SilenceClient {
+	return &silenceClient{
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		bearerTokenFile: bearerTokenFile,
+		httpClient:      &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// GetServiceURL returns the in cluster URL of the Alertmanager in the observability namespace
+func GetServiceURL(oboNamespace string) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d", config.AlertManagerServiceName, oboNamespace, config.AlertManagerPort)
+}
+
+func (c *silenceClient) ListSilences(ctx context.Context) ([]Silence, error) {
+	var silences []Silence
+	if err := c.do(ctx, http.MethodGet, "/api/v2/silences", nil, &silences); err != nil {
+		return nil, fmt.Errorf("failed to list silences: %w", err)
+	}
+	return silences, nil
+}
+
+func (c *silenceClient) CreateSilence(ctx context.Context, silence Silence) (string, error) {
+	created := struct {
+		SilenceID string `json:"silenceID"`
+	}{}
+	if err := c.do(ctx, http.MethodPost, "/api/v2/silences", silence, &created); err != nil {
+		return "", fmt.Errorf("failed to create silence: %w", err)
+	}
+	return created.SilenceID, nil
+}
+
+func (c *silenceClient) ExpireSilence(ctx context.Context, id string) error {
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/silence/"+id, nil, nil); err != nil {
+		return fmt.Errorf("failed to expire silence %s: %w", id, err)
+	}
+	return nil
+}
+
+func (c *silenceClient) do(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.bearerTokenFile != "" {
+		token, err := os.ReadFile(c.bearerTokenFile)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read bearer token: %w", err)
+		}
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: status code %d", ErrUnauthorized, resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// ReconcileSilences creates a silence for each request that does not already have one and
+// expires the operator managed silences whose operation has completed. Existing silences
+// are only extended for requests with a MaxDuration, the others stay bounded by the EndsAt
+// of their first reconcile. The operator managed silences in place afterwards are returned
+func ReconcileSilences(ctx context.Context, client SilenceClient, requests []SilenceRequest) ([]Silence, error) {
+	silences, err := client.ListSilences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	managed := map[string]Silence{}
+	for _, silence := range silences {
+		if silence.CreatedBy != SilenceCreatedBy || silence.Status == nil || silence.Status.State == SilenceStateExpired {
+			continue
+		}
+		key := getSilenceKey(silence)
+		if key == "" {
+			continue
+		}
+		managed[key] = silence
+	}
+
+	var result []Silence
+	requested := map[string]bool{}
+	for _, request := range requests {
+		requested[request.Key] = true
+		if existing, ok := managed[request.Key]; ok {
+			extended, err := extendSilence(ctx, client, existing, request)
+			if err != nil {
+				return result, err
+			}
+			result = append(result, extended)
+			continue
+		}
+		if !request.EndsAt.After(now()) {
+			continue
+		}
+
+		silence := Silence{
+			Matchers:  request.Matchers,
+			StartsAt:  now().UTC(),
+			EndsAt:    request.EndsAt.UTC(),
+			CreatedBy: SilenceCreatedBy,
+			Comment:   fmt.Sprintf("[%s] %s", request.Key, request.Reason),
+		}
+		id, err := client.CreateSilence(ctx, silence)
+		if err != nil {
+			return result, err
+		}
+		silence.ID = id
+		silence.Status = &SilenceStatus{State: SilenceStateActive}
+		result = append(result, silence)
+	}
+
+	for key, silence := range managed {
+		if requested[key] {
+			continue
+		}
+		if err := client.ExpireSilence(ctx, silence.ID); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// extendSilence moves the end of an existing silence to the EndsAt of its request once it
+// is due to end within silenceExtendThreshold, bounded by the MaxDuration of the request
+func extendSilence(ctx context.Context, client SilenceClient, silence Silence, request SilenceRequest) (Silence, error) {
+	if request.MaxDuration == 0 {
+		return silence, nil
+	}
+	endsAt := request.EndsAt
+	if maxEndsAt := silence.StartsAt.Add(request.MaxDuration); endsAt.After(maxEndsAt) {
+		endsAt = maxEndsAt
+	}
+	if !silence.EndsAt.Before(endsAt.Add(-silenceExtendThreshold)) {
+		return silence, nil
+	}
+
+	// Posting a silence with the ID of an existing one updates it
+	status := silence.Status
+	silence.Status = nil
+	silence.EndsAt = endsAt.UTC()
+	id, err := client.CreateSilence(ctx, silence)
+	if err != nil {
+		return silence, fmt.Errorf("failed to extend silence %s: %w", silence.ID, err)
+	}
+	silence.ID = id
+	silence.Status = status
+	return silence, nil
+}
+
+// GetSilenceReason returns the reason recorded in the comment of an operator managed silence
+func GetSilenceReason(silence Silence) string {
+	_, reason, found := strings.Cut(silence.Comment, "] ")
+	if !found {
+		return silence.Comment
+	}
+	return reason
+}
+
+func getSilenceKey(silence Silence) string {
+	if !strings.HasPrefix(silence.Comment, "[") {
+		return ""
+	}
+	key, _, found := strings.Cut(strings.TrimPrefix(silence.Comment, "["), "]")
+	if !found {
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,217 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAlertmanager struct {
+	silences map[string]Silence
+	nextID   int
+}
+
+func newFakeAlertmanager(silences ...Silence) *fakeAlertmanager {
+	am := &fakeAlertmanager{silences: map[string]Silence{}}
+	for _, silence := range silences {
+		am.silences[silence.ID] = silence
+	}
+	return am
+}
+
+func (am *fakeAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v2/silences":
+		var silences []Silence
+		for _, silence := range am.silences {
+			silences = append(silences, silence)
+		}
+		_ = json.NewEncoder(w).Encode(silences)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v2/silences":
+		silence := Silence{}
+		if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if existing, ok := am.silences[silence.ID]; ok && silence.ID != "" {
+			// Updating an active silence keeps its ID and start
+			silence.StartsAt = existing.StartsAt
+		} else {
+			am.nextID++
+			silence.ID = strings.Repeat("n", am.nextID)
+		}
+		silence.Status = &SilenceStatus{State: SilenceStateActive}
+		am.silences[silence.ID] = silence
+		_ = json.NewEncoder(w).Encode(map[string]string{"silenceID": silence.ID})
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/silence/")
+		silence, ok := am.silences[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		silence.Status = &SilenceStatus{State: SilenceStateExpired}
+		am.silences[id] = silence
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestReconcileSilences(t *testing.T) {
+	fixedNow := time.Date(2023, 6, 1, 2, 30, 0, 0, time.UTC)
+	now = func() time.Time { return fixedNow }
+	defer func() { now = time.Now }()
+
+	matchers := []Matcher{{Name: "product", Value: "rhoam", IsEqual: true}}
+
+	tests := []struct {
+		name            string
+		existing        []Silence
+		requests        []SilenceRequest
+		wantSilences    []string
+		wantActiveCount int
+		wantExpired     []string
+		wantEndsAt      map[string]time.Time
+	}{
+		{
+			name:            "creates a silence for a new request",
+			requests:        []SilenceRequest{{Key: "upgrade-1.2.0", Reason: "upgrade", Matchers: matchers, EndsAt: fixedNow.Add(time.Hour)}},
+			wantSilences:    []string{"upgrade"},
+			wantActiveCount: 1,
+		},
+		{
+			name: "keeps an existing silence without extending it",
+			existing: []Silence{
+				{ID: "a", CreatedBy: SilenceCreatedBy, Comment: "[upgrade-1.2.0] upgrade", EndsAt: fixedNow.Add(time.Minute), Status: &SilenceStatus{State: SilenceStateActive}},
+			},
+			requests:        []SilenceRequest{{Key: "upgrade-1.2.0", Reason: "upgrade", Matchers: matchers, EndsAt: fixedNow.Add(time.Hour)}},
+			wantSilences:    []string{"upgrade"},
+			wantActiveCount: 1,
+		},
+		{
+			name: "extends an existing silence due to end while the operation runs",
+			existing: []Silence{
+				{ID: "a", CreatedBy: SilenceCreatedBy, Comment: "[upgrade-1.2.0] upgrade", StartsAt: fixedNow.Add(-2 * time.Hour), EndsAt: fixedNow.Add(time.Minute), Status: &SilenceStatus{State: SilenceStateActive}},
+			},
+			requests:        []SilenceRequest{{Key: "upgrade-1.2.0", Reason: "upgrade", Matchers: matchers, EndsAt: fixedNow.Add(2 * time.Hour), MaxDuration: 12 * time.Hour}},
+			wantSilences:    []string{"upgrade"},
+			wantActiveCount: 1,
+			wantEndsAt:      map[string]time.Time{"a": fixedNow.Add(2 * time.Hour)},
+		},
+		{
+			name: "does not extend a silence far from its end",
+			existing: []Silence{
+				{ID: "a", CreatedBy: SilenceCreatedBy, Comment: "[upgrade-1.2.0] upgrade", StartsAt: fixedNow.Add(-time.Minute), EndsAt: fixedNow.Add(2*time.Hour - time.Minute), Status: &SilenceStatus{State: SilenceStateActive}},
+			},
+			requests:        []SilenceRequest{{Key: "upgrade-1.2.0", Reason: "upgrade", Matchers: matchers, EndsAt: fixedNow.Add(2 * time.Hour), MaxDuration: 12 * time.Hour}},
+			wantSilences:    []string{"upgrade"},
+			wantActiveCount: 1,
+			wantEndsAt:      map[string]time.Time{"a": fixedNow.Add(2*time.Hour - time.Minute)},
+		},
+		{
+			name: "does not extend a silence past its max duration",
+			existing: []Silence{
+				{ID: "a", CreatedBy: SilenceCreatedBy, Comment: "[upgrade-1.2.0] upgrade", StartsAt: fixedNow.Add(-11 * time.Hour), EndsAt: fixedNow.Add(time.Minute), Status: &SilenceStatus{State: SilenceStateActive}},
+			},
+			requests:        []SilenceRequest{{Key: "upgrade-1.2.0", Reason: "upgrade", Matchers: matchers, EndsAt: fixedNow.Add(2 * time.Hour), MaxDuration: 12 * time.Hour}},
+			wantSilences:    []string{"upgrade"},
+			wantActiveCount: 1,
+			wantEndsAt:      map[string]time.Time{"a": fixedNow.Add(time.Hour)},
+		},
+		{
+			name: "expires managed silences that are no longer requested",
+			existing: []Silence{
+				{ID: "a", CreatedBy: SilenceCreatedBy, Comment: "[upgrade-1.2.0] upgrade", Status: &SilenceStatus{State: SilenceStateActive}},
+				{ID: "b", CreatedBy: "sre", Comment: "[upgrade-1.2.0] manual", Status: &SilenceStatus{State: SilenceStateActive}},
+			},
+			wantActiveCount: 1,
+			wantExpired:     []string{"a"},
+		},
+		{
+			name:     "does not create a silence that has already ended",
+			requests: []SilenceRequest{{Key: "maintenance", Reason: "maintenance", Matchers: matchers, EndsAt: fixedNow}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			am := newFakeAlertmanager(tt.existing...)
+			server := httptest.NewServer(am)
+			defer server.Close()
+
+			silences, err := ReconcileSilences(context.TODO(), NewSilenceClient(server.URL, ""), tt.requests)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var reasons []string
+			for _, silence := range silences {
+				reasons = append(reasons, GetSilenceReason(silence))
+			}
+			if strings.Join(reasons, ",") != strings.Join(tt.wantSilences, ",") {
+				t.Errorf("expected silences %v, got %v", tt.wantSilences, reasons)
+			}
+
+			active := 0
+			for _, silence := range am.silences {
+				if silence.Status.State == SilenceStateActive {
+					active++
+				}
+			}
+			if active != tt.wantActiveCount {
+				t.Errorf("expected %d active silences, got %d", tt.wantActiveCount, active)
+			}
+			for id, endsAt := range tt.wantEndsAt {
+				if !am.silences[id].EndsAt.Equal(endsAt) {
+					t.Errorf("expected silence %s to end at %v, got %v", id, endsAt, am.silences[id].EndsAt)
+				}
+			}
+			for _, id := range tt.wantExpired {
+				if am.silences[id].Status.State != SilenceStateExpired {
+					t.Errorf("expected silence %s to be expired", id)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileSilences_error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := ReconcileSilences(context.TODO(), NewSilenceClient(server.URL, ""), nil); err == nil {
+		t.Fatal("expected an error when alertmanager is unavailable")
+	}
+}
+
+func TestSilenceClient_bearerToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("abc123\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]Silence{})
+	}))
+	defer server.Close()
+
+	if _, err := NewSilenceClient(server.URL, tokenFile).ListSilences(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := NewSilenceClient(server.URL, filepath.Join(t.TempDir(), "missing")).ListSilences(context.TODO())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized without a token, got %v", err)
+	}
+}
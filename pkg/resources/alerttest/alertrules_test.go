@@ -0,0 +1,525 @@
+package alerttest
+
+import (
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	testOperatorNamespace      = "redhat-rhoam-operator"
+	testProductNamespace       = "redhat-rhoam-product"
+	testObservabilityNamespace = "redhat-rhoam-observability"
+)
+
+type alertRuleFileTest struct {
+	values map[string]interface{}
+	tests  []Test
+}
+
+// alertRuleFileTests covers every alert rule file in pkg/resources/alertrules
+var alertRuleFileTests = map[string]alertRuleFileTest{
+	"addon-parameters-secret": {
+		values: map[string]interface{}{"SecretName": "addon-managed-api-service-parameters"},
+		tests: []Test{
+			{
+				Name: "fires when the secret is missing",
+				InputSeries: []Series{
+					{Series: `kube_secret_info{namespace="redhat-rhoam-operator", secret="other"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 4 * time.Minute, AlertName: "AddonManagedApiServiceParametersExists"},
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "AddonManagedApiServiceParametersExists",
+						ExpAlerts: []Alert{{
+							Labels:      map[string]string{"severity": "warning", "product": "rhoam"},
+							Annotations: map[string]string{"description": "The addon-managed-api-service-parameters secret has been removed from the redhat-rhoam-operator namespace, this secret should be generated and managed by Hive"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "does not fire when the secret exists",
+				InputSeries: []Series{
+					{Series: `kube_secret_info{namespace="redhat-rhoam-operator", secret="addon-managed-api-service-parameters"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 10 * time.Minute, AlertName: "AddonManagedApiServiceParametersExists"},
+				},
+			},
+		},
+	},
+	"cloudresources": {
+		values: map[string]interface{}{"RedisNames": []interface{}{"backend_redis_test"}},
+		tests: []Test{
+			{
+				Name: "failed VPC action",
+				InputSeries: []Series{
+					{Series: `cro_vpc_action{namespace="redhat-rhoam-operator", status="failed", error="timeout"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "RHOAMCloudResourceOperatorVPCActionFailed",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"namespace": testOperatorNamespace, "status": "failed", "error": "timeout", "severity": "critical", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "snapshot not found",
+				InputSeries: []Series{
+					{Series: "cro_redis_snapshot_not_found_backend_redis_test", Values: "2+0x5"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  time.Minute,
+						AlertName: "RHOAMCloudResourceOperatorElasticCacheSnapshotsNotFound",
+						ExpAlerts: []Alert{{Labels: map[string]string{"severity": "warning", "product": "rhoam"}}},
+					},
+				},
+			},
+			{
+				Name: "metrics missing without cluster storage",
+				InputSeries: []Series{
+					{Series: `rhoam_spec{use_cluster_storage="false", namespace="redhat-rhoam-operator"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 4 * time.Minute, AlertName: "RHOAMCloudResourceOperatorMetricsMissing"},
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "RHOAMCloudResourceOperatorMetricsMissing",
+						ExpAlerts: []Alert{{Labels: map[string]string{"severity": "critical", "product": "rhoam"}}},
+					},
+				},
+			},
+			{
+				Name: "metrics are not expected with cluster storage",
+				InputSeries: []Series{
+					{Series: `rhoam_spec{use_cluster_storage="true", namespace="redhat-rhoam-operator"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 10 * time.Minute, AlertName: "RHOAMCloudResourceOperatorMetricsMissing"},
+				},
+			},
+		},
+	},
+	"deadmanssnitch-secret": {
+		values: map[string]interface{}{"NamespacePrefix": "redhat-rhoam-", "Installing": false},
+		tests: []Test{
+			{
+				Name: "fires when the secret is missing",
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 9 * time.Minute, AlertName: "DeadMansSnitchSecretExists"},
+					{
+						EvalTime:  10 * time.Minute,
+						AlertName: "DeadMansSnitchSecretExists",
+						ExpAlerts: []Alert{{Labels: map[string]string{"severity": "warning", "product": "rhoam"}}},
+					},
+				},
+			},
+			{
+				Name: "does not fire when the secret exists",
+				InputSeries: []Series{
+					{Series: `kube_secret_info{namespace="redhat-rhoam-operator", secret="redhat-rhoam-deadmanssnitch"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 10 * time.Minute, AlertName: "DeadMansSnitchSecretExists"},
+				},
+			},
+		},
+	},
+	"grafana": {
+		tests: []Test{
+			{
+				Name: "grafana service has no endpoints",
+				InputSeries: []Series{
+					{Series: `kube_endpoint_address_available{endpoint="grafana-service", namespace="redhat-rhoam-operator"}`, Values: "0+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "GrafanaServiceEndpointDown",
+						ExpAlerts: []Alert{{
+							Labels:      map[string]string{"endpoint": "grafana-service", "namespace": testOperatorNamespace, "severity": "warning", "product": "rhoam"},
+							Annotations: map[string]string{"message": "No grafana-service endpoints in namespace redhat-rhoam-operator. Expected at least 1."},
+						}},
+					},
+				},
+			},
+			{
+				Name: "grafana operator pod not ready",
+				InputSeries: []Series{
+					{Series: `kube_pod_status_ready{condition="true", namespace="redhat-rhoam-operator", pod="grafana-operator-1"}`, Values: "0+0x10"},
+					{Series: `kube_pod_status_phase{phase="Running", namespace="redhat-rhoam-operator", pod="grafana-operator-1"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "GrafanaOperatorPod",
+						ExpAlerts: []Alert{{Labels: map[string]string{"severity": "warning", "product": "rhoam"}}},
+					},
+				},
+			},
+		},
+	},
+	"marin3r": {
+		tests: []Test{
+			{
+				Name: "rate limit service has no endpoints",
+				InputSeries: []Series{
+					{Series: `kube_endpoint_address_available{endpoint="ratelimit", namespace="redhat-rhoam-product"}`, Values: "0+0x10"},
+					{Series: `kube_endpoint_address_available{endpoint="rhmi-registry-cs", namespace="redhat-rhoam-operator"}`, Values: "0+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "Marin3rRateLimitServiceEndpointDown",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"endpoint": "ratelimit", "namespace": testProductNamespace, "severity": "warning", "product": "rhoam"},
+						}},
+					},
+					{EvalTime: 7 * time.Minute, AlertName: "Marin3rOperatorRhmiRegistryCsServiceEndpointDown"},
+					{
+						EvalTime:  8 * time.Minute,
+						AlertName: "Marin3rOperatorRhmiRegistryCsServiceEndpointDown",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"endpoint": "rhmi-registry-cs", "namespace": testOperatorNamespace, "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"mcg": {
+		values: map[string]interface{}{"ThreescaleBucket": "3scale-operator-bucket"},
+		tests: []Test{
+			{
+				Name: "noobaa core pod is missing",
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "NooBaaCorePod",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"condition": "true", "namespace": testOperatorNamespace, "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "3scale bucket is almost full",
+				InputSeries: []Series{
+					{Series: `NooBaa_bucket_capacity{namespace="redhat-rhoam-operator", bucket_name="3scale-operator-bucket-abc"}`, Values: "96+0x10"},
+					{Series: `NooBaa_bucket_capacity{namespace="redhat-rhoam-operator", bucket_name="other-bucket"}`, Values: "99+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "NooBaaBucketCapacityOver95Percent",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"namespace": testOperatorNamespace, "bucket_name": "3scale-operator-bucket-abc", "severity": "critical", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"obo": {
+		values: map[string]interface{}{"NamespacePrefix": "redhat-rhoam-", "Multitenant": true},
+		tests: []Test{
+			{
+				Name: "image pull back off in a product namespace",
+				InputSeries: []Series{
+					{Series: `kube_pod_container_status_waiting_reason{reason="ImagePullBackOff", namespace="redhat-rhoam-3scale", pod="apicast-1", container="apicast"}`, Values: "1+0x10"},
+					{Series: `kube_pod_container_status_waiting_reason{reason="ImagePullBackOff", namespace="customer", pod="app-1", container="app"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "KubePodImagePullBackOff",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"reason": "ImagePullBackOff", "namespace": "redhat-rhoam-3scale", "pod": "apicast-1", "container": "apicast", "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "installation stuck reconciling",
+				InputSeries: []Series{
+					{Series: `rhoam_status{stage="products", pod="operator-1"}`, Values: "1+0x10"},
+					{Series: `rhoam_version{to_version="", version="1.2.0", pod="operator-1"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 9 * time.Minute, AlertName: "RHOAMIsInReconcilingErrorState"},
+					{
+						EvalTime:  10 * time.Minute,
+						AlertName: "RHOAMIsInReconcilingErrorState",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"stage": "products", "pod": "operator-1", "version": "1.2.0", "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "tenant failed to provision",
+				InputSeries: []Series{
+					{Series: `tenants_summary{provisioningStatus="failed", user="tenant-a"}`, Values: "1+0x10"},
+					{Series: `tenants_summary{provisioningStatus="3scale account ready", user="tenant-b"}`, Values: "1+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  10 * time.Minute,
+						AlertName: "ApiManagementTenantCRFailed",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"provisioningStatus": "failed", "user": "tenant-a", "severity": "critical", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"postgres": {
+		values: map[string]interface{}{
+			"ResourceName":      "threescale-postgres-rhoam",
+			"ResourceNamespace": testOperatorNamespace,
+			"ProductName":       "3scale",
+			"Strategy":          "aws",
+			"AlertPrefix":       "Threescale-Postgres-Rhoam",
+			"SSO":               false,
+			"Complete":          true,
+		},
+		tests: []Test{
+			{
+				Name: "postgres instance unavailable",
+				InputSeries: []Series{
+					{Series: `cro_postgres_available{exported_namespace="redhat-rhoam-operator", resourceID="threescale-postgres-rhoam", productName="3scale"}`, Values: "0+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "Threescale-Postgres-RhoamPostgresInstanceUnavailable",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"exported_namespace": testOperatorNamespace, "resourceID": "threescale-postgres-rhoam", "productName": "3scale", "severity": "critical", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "postgres storage low",
+				InputSeries: []Series{
+					{Series: `cro_postgres_free_storage_average{instanceID="threescale-postgres", productName="3scale"}`, Values: "5+0x30"},
+					{Series: `cro_postgres_current_allocated_storage{instanceID="threescale-postgres", productName="3scale"}`, Values: "100+0x30"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 29 * time.Minute, AlertName: "PostgresStorageLow"},
+					{
+						EvalTime:  30 * time.Minute,
+						AlertName: "PostgresStorageLow",
+						ExpAlerts: []Alert{{
+							Labels:      map[string]string{"instanceID": "threescale-postgres", "productName": "3scale", "severity": "warning", "product": "rhoam"},
+							Annotations: map[string]string{"description": "The postgres instance threescale-postgres for product 3scale, storage is currently under 10 percent of its capacity"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"redis": {
+		values: map[string]interface{}{
+			"ResourceName":      "ratelimit-service-redis-rhoam",
+			"ResourceNamespace": testOperatorNamespace,
+			"ProductName":       "marin3r",
+			"Strategy":          "aws",
+			"AlertPrefix":       "Ratelimit-Service-Redis-Rhoam",
+			"RateLimit":         true,
+			"Complete":          true,
+		},
+		tests: []Test{
+			{
+				Name: "rate limit redis unavailable only warns",
+				InputSeries: []Series{
+					{Series: `cro_redis_available{exported_namespace="redhat-rhoam-operator", resourceID="ratelimit-service-redis-rhoam", productName="marin3r"}`, Values: "0+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "Ratelimit-Service-Redis-RhoamRedisCacheUnavailable",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"exported_namespace": testOperatorNamespace, "resourceID": "ratelimit-service-redis-rhoam", "productName": "marin3r", "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"redis-cpu": {
+		values: map[string]interface{}{"ProductName": "3scale"},
+		tests: []Test{
+			{
+				Name: "redis cpu high",
+				InputSeries: []Series{
+					{Series: `cro_redis_engine_cpu_utilization_average{instanceID="backend-redis-001", resourceID="backend-redis", namespace="redhat-rhoam-operator", productName="3scale"}`, Values: "95+0x20"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 14 * time.Minute, AlertName: "RedisCpuUsageHigh"},
+					{
+						EvalTime:  15 * time.Minute,
+						AlertName: "RedisCpuUsageHigh",
+						ExpAlerts: []Alert{{
+							Labels:      map[string]string{"instanceID": "backend-redis-001", "resourceID": "backend-redis", "namespace": testOperatorNamespace, "productName": "3scale", "severity": "warning", "product": "rhoam"},
+							Annotations: map[string]string{"description": "Redis Cpu for instance backend-redis-001 is 90 percent or higher for the last hour. Redis Custom Resource: backend-redis in namespace redhat-rhoam-operator for the product: 3scale"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"rhsso": {
+		tests: []Test{
+			{
+				Name: "keycloak pods are missing",
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "KeycloakInstanceNotAvailable",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"condition": "true", "namespace": testProductNamespace, "route": "keycloak", "service": "keycloak", "severity": "critical", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+			{
+				Name: "keycloak error budget burns fast",
+				InputSeries: []Series{
+					{Series: `haproxy_backend_http_responses_total{route="keycloak", exported_namespace="redhat-rhoam-product", code="5xx"}`, Values: "0+60x20"},
+					{Series: `haproxy_backend_http_responses_total{route="keycloak", exported_namespace="redhat-rhoam-product", code="2xx"}`, Values: "0+60x20"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  10 * time.Minute,
+						AlertName: "RHOAMRhssoAvailability5mto1hErrorBudgetBurn",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"route": "keycloak", "service": "keycloak", "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"rhssouser": {
+		tests: []Test{
+			{
+				Name: "keycloak service has no endpoints",
+				InputSeries: []Series{
+					{Series: `kube_endpoint_address_available{endpoint="keycloak", namespace="redhat-rhoam-product"}`, Values: "0+0x10"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  5 * time.Minute,
+						AlertName: "RHOAMUserRhssoKeycloakServiceEndpointDown",
+						ExpAlerts: []Alert{{
+							Labels: map[string]string{"endpoint": "keycloak", "namespace": testProductNamespace, "severity": "warning", "product": "rhoam"},
+						}},
+					},
+				},
+			},
+		},
+	},
+	"smtp-secret": {
+		values: map[string]interface{}{"NamespacePrefix": "redhat-rhoam-"},
+		tests: []Test{
+			{
+				Name: "fires when the secret is missing",
+				InputSeries: []Series{
+					{Series: `kube_secret_info{namespace="redhat-rhoam-operator", secret="redhat-rhoam-smtp"}`, Values: "1+0x4 stale"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 14 * time.Minute, AlertName: "SendgridSmtpSecretExists"},
+					{
+						EvalTime:  15 * time.Minute,
+						AlertName: "SendgridSmtpSecretExists",
+						ExpAlerts: []Alert{{Labels: map[string]string{"severity": "critical", "product": "rhoam"}}},
+					},
+				},
+			},
+		},
+	},
+	"threescale": {
+		values: map[string]interface{}{
+			"ContainerCPUMetric": "node_namespace_pod_container:container_cpu_usage_seconds_total:sum_irate",
+			"CustomDomain":       false,
+			"ZyncReplicas":       1,
+		},
+		tests: []Test{
+			{
+				Name: "container memory high",
+				InputSeries: []Series{
+					{Series: `container_memory_usage_bytes{container="system-app", pod="system-app-1", namespace="redhat-rhoam-product"}`, Values: "95+0x20"},
+					{Series: `kube_pod_container_resource_limits{container="system-app", pod="system-app-1", namespace="redhat-rhoam-product", resource="memory"}`, Values: "100+0x20"},
+				},
+				AlertRuleTests: []AlertTestCase{
+					{
+						EvalTime:  15 * time.Minute,
+						AlertName: "ThreeScaleContainerHighMemory",
+						ExpAlerts: []Alert{{
+							Labels:      map[string]string{"container": "system-app", "pod": "system-app-1", "severity": "info", "product": "rhoam"},
+							Annotations: map[string]string{"message": "The system-app Container in the system-app-1 Pod has been using 95% of available memory for longer than 15 minutes."},
+						}},
+					},
+				},
+			},
+			{
+				Name: "critical metrics missing",
+				AlertRuleTests: []AlertTestCase{
+					{EvalTime: 29 * time.Minute, AlertName: "RHOAMThreescaleCriticalMetricsMissing"},
+					{
+						EvalTime:  30 * time.Minute,
+						AlertName: "RHOAMThreescaleCriticalMetricsMissing",
+						ExpAlerts: []Alert{{Labels: map[string]string{"severity": "critical", "product": "rhoam"}}},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestAlertRuleFiles(t *testing.T) {
+	files, err := resources.AlertRuleFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			fileTest, ok := alertRuleFileTests[file]
+			if !ok {
+				t.Fatalf("no alert rule tests for alertrules/%s.yaml", file)
+			}
+
+			alerts, err := resources.GetAlertRules(file, resources.AlertTemplateParams{
+				InstallationName:  "rhoam",
+				Namespace:         testObservabilityNamespace,
+				ProductNamespace:  testProductNamespace,
+				OperatorNamespace: testOperatorNamespace,
+				Values:            fileTest.values,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			RunAlertRuleTests(t, &resources.AlertReconcilerImpl{
+				ProductName: file,
+				Log:         l.NewLogger(),
+				Installation: &integreatlyv1alpha1.RHMI{
+					ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: testOperatorNamespace},
+				},
+				Alerts: alerts,
+			}, fileTest.tests)
+		})
+	}
+}
@@ -0,0 +1,393 @@
+// Package alerttest evaluates the alert rules of an AlertReconciler against synthetic time
+// series in process with the Prometheus rule engine, the same way promtool rule unit tests
+// do, so alerts and recorded series can be covered by table driven tests under go test.
+//
+// Input series use the promtool notation: "1+1x10" expands to 11 values starting at 1,
+// "_" is a missing sample, "_x5" five missing samples and "stale" a stale marker.
+package alerttest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/utils"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/util/testutil"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+)
+
+const (
+	defaultInterval    = time.Minute
+	evaluationInterval = time.Minute
+)
+
+// Series is an input series, e.g. {Series: `up{job="3scale"}`, Values: "1+0x10 0x5"}
+type Series struct {
+	Series string
+	Values string
+}
+
+// Alert is an alert expected to be firing. Labels must match exactly, apart from alertname.
+// Only the annotations listed are compared
+type Alert struct {
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// AlertTestCase lists the alerts expected to be firing with a name at a point in the test
+type AlertTestCase struct {
+	EvalTime  time.Duration
+	AlertName string
+	ExpAlerts []Alert
+}
+
+// Sample is a sample an expression is expected to return, e.g. {Labels: `{slo="sso"}`, Value: 0.5}
+type Sample struct {
+	Labels string
+	Value  float64
+}
+
+// ExprTestCase lists the samples an expression, e.g. a recorded series, is expected to return
+// at a point in the test. Values are compared with a relative tolerance of 1e-6
+type ExprTestCase struct {
+	EvalTime   time.Duration
+	Expr       string
+	ExpSamples []Sample
+}
+
+type Test struct {
+	Name string
+	// Interval between the values of the input series, defaults to one minute
+	Interval       time.Duration
+	InputSeries    []Series
+	AlertRuleTests []AlertTestCase
+	ExprTests      []ExprTestCase
+}
+
+// RuleGroup is a rule group rendered by an AlertReconciler
+type RuleGroup struct {
+	Name  string
+	Rules []monv1.Rule
+}
+
+// RenderRules reconciles the alerts of the reconciler into a fake client and returns the
+// rule groups of the PrometheusRules it created
+func RenderRules(reconciler resources.AlertReconciler) ([]RuleGroup, error) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		return nil, err
+	}
+	client := utils.NewTestClient(scheme)
+	if _, err := reconciler.ReconcileAlerts(context.TODO(), client); err != nil {
+		return nil, fmt.Errorf("failed to reconcile alerts: %w", err)
+	}
+
+	var groups []RuleGroup
+	oboRules := &monv1.PrometheusRuleList{}
+	if err := client.List(context.TODO(), oboRules); err != nil {
+		return nil, err
+	}
+	for _, rule := range oboRules.Items {
+		for _, group := range rule.Spec.Groups {
+			groups = append(groups, RuleGroup{Name: group.Name, Rules: group.Rules})
+		}
+	}
+
+	monitoringRules := &monitoringv1.PrometheusRuleList{}
+	if err := client.List(context.TODO(), monitoringRules); err != nil {
+		return nil, err
+	}
+	for _, rule := range monitoringRules.Items {
+		for _, group := range rule.Spec.Groups {
+			var rules []monv1.Rule
+			for _, r := range group.Rules {
+				rules = append(rules, monv1.Rule{
+					Record:      r.Record,
+					Alert:       r.Alert,
+					Expr:        r.Expr,
+					For:         monv1.Duration(r.For),
+					Labels:      r.Labels,
+					Annotations: r.Annotations,
+				})
+			}
+			groups = append(groups, RuleGroup{Name: group.Name, Rules: rules})
+		}
+	}
+	return groups, nil
+}
+
+// RunAlertRuleTests renders the rules of the reconciler and runs each test against them
+func RunAlertRuleTests(t *testing.T, reconciler resources.AlertReconciler, tests []Test) {
+	t.Helper()
+
+	groups, err := RenderRules(reconciler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if err := runTest(t, groups, tt); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func runTest(t testutil.T, groups []RuleGroup, test Test) error {
+	interval := test.Interval
+	if interval == 0 {
+		interval = defaultInterval
+	}
+
+	var load strings.Builder
+	fmt.Fprintf(&load, "load %s\n", model.Duration(interval))
+	for _, input := range test.InputSeries {
+		fmt.Fprintf(&load, "  %s %s\n", input.Series, input.Values)
+	}
+	suite, err := promql.NewLazyLoader(t, load.String(), promql.LazyLoaderOpts{})
+	if err != nil {
+		suite.Close()
+		return fmt.Errorf("invalid input series: %w", err)
+	}
+	defer suite.Close()
+	suite.SubqueryInterval = evaluationInterval
+
+	ruleGroups, err := newRuleGroups(suite, groups)
+	if err != nil {
+		return err
+	}
+
+	knownAlerts := map[string]bool{}
+	for _, group := range ruleGroups {
+		for _, rule := range group.Rules() {
+			if alertRule, ok := rule.(*rules.AlertingRule); ok {
+				knownAlerts[alertRule.Name()] = true
+			}
+		}
+	}
+
+	testsByTime := map[time.Duration][]AlertTestCase{}
+	var maxEvalTime time.Duration
+	for _, testCase := range test.AlertRuleTests {
+		if testCase.EvalTime%evaluationInterval != 0 {
+			return fmt.Errorf("eval time %s of %s is not a multiple of the evaluation interval %s", testCase.EvalTime, testCase.AlertName, evaluationInterval)
+		}
+		if !knownAlerts[testCase.AlertName] {
+			return fmt.Errorf("no alert rule named %s", testCase.AlertName)
+		}
+		testsByTime[testCase.EvalTime] = append(testsByTime[testCase.EvalTime], testCase)
+		if testCase.EvalTime > maxEvalTime {
+			maxEvalTime = testCase.EvalTime
+		}
+	}
+	exprTestsByTime := map[time.Duration][]ExprTestCase{}
+	for _, testCase := range test.ExprTests {
+		if testCase.EvalTime%evaluationInterval != 0 {
+			return fmt.Errorf("eval time %s of %s is not a multiple of the evaluation interval %s", testCase.EvalTime, testCase.Expr, evaluationInterval)
+		}
+		exprTestsByTime[testCase.EvalTime] = append(exprTestsByTime[testCase.EvalTime], testCase)
+		if testCase.EvalTime > maxEvalTime {
+			maxEvalTime = testCase.EvalTime
+		}
+	}
+
+	start := time.Unix(0, 0).UTC()
+	var errs []string
+	for offset := time.Duration(0); offset <= maxEvalTime; offset += evaluationInterval {
+		ts := start.Add(offset)
+		var evalErr error
+		suite.WithSamplesTill(ts, func(err error) {
+			if err != nil {
+				evalErr = err
+				return
+			}
+			for _, group := range ruleGroups {
+				group.Eval(suite.Context(), ts)
+				for _, rule := range group.Rules() {
+					if rule.LastError() != nil {
+						evalErr = fmt.Errorf("failed to evaluate %s at %s: %w", rule.Name(), offset, rule.LastError())
+						return
+					}
+				}
+			}
+		})
+		if evalErr != nil {
+			return evalErr
+		}
+
+		for _, testCase := range testsByTime[offset] {
+			if err := checkAlerts(ruleGroups, testCase); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		for _, testCase := range exprTestsByTime[offset] {
+			if err := checkExpr(suite, ts, testCase); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// newRuleGroups builds Prometheus rule groups evaluated by the query engine of the suite
+func newRuleGroups(suite *promql.LazyLoader, groups []RuleGroup) ([]*rules.Group, error) {
+	opts := &rules.ManagerOptions{
+		QueryFunc:  rules.EngineQueryFunc(suite.QueryEngine(), suite.Storage()),
+		Appendable: suite.Storage(),
+		Context:    context.Background(),
+		NotifyFunc: func(ctx context.Context, expr string, alerts ...*rules.Alert) {},
+		Logger:     log.NewNopLogger(),
+	}
+
+	var ruleGroups []*rules.Group
+	for _, group := range groups {
+		var groupRules []rules.Rule
+		for _, rule := range group.Rules {
+			expr, err := parser.ParseExpr(rule.Expr.String())
+			if err != nil {
+				return nil, fmt.Errorf("rule %s%s has an invalid expression: %w", rule.Alert, rule.Record, err)
+			}
+			if rule.Record != "" {
+				groupRules = append(groupRules, rules.NewRecordingRule(rule.Record, expr, labels.FromMap(rule.Labels)))
+				continue
+			}
+			var forDur model.Duration
+			if rule.For != "" {
+				if forDur, err = model.ParseDuration(string(rule.For)); err != nil {
+					return nil, fmt.Errorf("rule %s has an invalid for duration: %w", rule.Alert, err)
+				}
+			}
+			// Alerts are created as restored so they fire without waiting for the ALERTS_FOR_STATE series
+			groupRules = append(groupRules, rules.NewAlertingRule(rule.Alert, expr, time.Duration(forDur),
+				labels.FromMap(rule.Labels), labels.FromMap(rule.Annotations), nil, "", true, log.NewNopLogger()))
+		}
+		ruleGroups = append(ruleGroups, rules.NewGroup(rules.GroupOptions{
+			Name:     group.Name,
+			Interval: evaluationInterval,
+			Rules:    groupRules,
+			Opts:     opts,
+		}))
+	}
+	return ruleGroups, nil
+}
+
+func checkAlerts(groups []*rules.Group, testCase AlertTestCase) error {
+	var got []*rules.Alert
+	for _, group := range groups {
+		for _, rule := range group.Rules() {
+			alertRule, ok := rule.(*rules.AlertingRule)
+			if !ok || alertRule.Name() != testCase.AlertName {
+				continue
+			}
+			for _, alert := range alertRule.ActiveAlerts() {
+				if alert.State == rules.StateFiring {
+					got = append(got, alert)
+				}
+			}
+		}
+	}
+
+	var gotLabels, expLabels []string
+	for _, alert := range got {
+		gotLabels = append(gotLabels, alert.Labels.WithoutLabels(labels.AlertName).String())
+	}
+	for _, alert := range testCase.ExpAlerts {
+		expLabels = append(expLabels, labels.FromMap(alert.Labels).String())
+	}
+	sort.Strings(gotLabels)
+	sort.Strings(expLabels)
+	if strings.Join(gotLabels, ",") != strings.Join(expLabels, ",") {
+		return fmt.Errorf("alertname: %s, time: %s,\n    exp: %v,\n    got: %v", testCase.AlertName, testCase.EvalTime, expLabels, gotLabels)
+	}
+
+	for _, exp := range testCase.ExpAlerts {
+		expSet := labels.FromMap(exp.Labels)
+		for _, alert := range got {
+			if labels.Compare(alert.Labels.WithoutLabels(labels.AlertName), expSet) != 0 {
+				continue
+			}
+			for name, value := range exp.Annotations {
+				if gotValue := alert.Annotations.Get(name); gotValue != value {
+					return fmt.Errorf("alertname: %s, time: %s, annotation %s:\n    exp: %q,\n    got: %q", testCase.AlertName, testCase.EvalTime, name, value, gotValue)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkExpr evaluates the expression against the input series and the series recorded so far
+func checkExpr(suite *promql.LazyLoader, ts time.Time, testCase ExprTestCase) error {
+	query, err := suite.QueryEngine().NewInstantQuery(suite.Queryable(), testCase.Expr, ts)
+	if err != nil {
+		return fmt.Errorf("expr: %s, invalid expression: %w", testCase.Expr, err)
+	}
+	defer query.Close()
+	result := query.Exec(suite.Context())
+	if result.Err != nil {
+		return fmt.Errorf("expr: %s, time: %s, failed to evaluate: %w", testCase.Expr, testCase.EvalTime, result.Err)
+	}
+	vector, err := result.Vector()
+	if err != nil {
+		if scalar, scalarErr := result.Scalar(); scalarErr == nil {
+			vector = promql.Vector{{Point: promql.Point{T: scalar.T, V: scalar.V}}}
+		} else {
+			return fmt.Errorf("expr: %s, time: %s, unexpected result type %s", testCase.Expr, testCase.EvalTime, result.Value.Type())
+		}
+	}
+
+	got := map[string]float64{}
+	for _, sample := range vector {
+		got[sample.Metric.String()] = sample.V
+	}
+	exp := map[string]float64{}
+	for _, sample := range testCase.ExpSamples {
+		metric, err := parser.ParseMetric(sample.Labels)
+		if err != nil {
+			return fmt.Errorf("expr: %s, invalid expected labels %s: %w", testCase.Expr, sample.Labels, err)
+		}
+		exp[metric.String()] = sample.Value
+	}
+
+	match := len(got) == len(exp)
+	for metric, value := range exp {
+		gotValue, ok := got[metric]
+		match = match && ok && almostEqual(gotValue, value)
+	}
+	if !match {
+		return fmt.Errorf("expr: %s, time: %s,\n    exp: %s,\n    got: %s", testCase.Expr, testCase.EvalTime, formatSamples(exp), formatSamples(got))
+	}
+	return nil
+}
+
+func almostEqual(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= 1e-6*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func formatSamples(samples map[string]float64) string {
+	var formatted []string
+	for metric, value := range samples {
+		formatted = append(formatted, fmt.Sprintf("%s %g", metric, value))
+	}
+	sort.Strings(formatted)
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
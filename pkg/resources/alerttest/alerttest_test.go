@@ -0,0 +1,188 @@
+package alerttest
+
+import (
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newTestAlertReconciler(rules ...monv1.Rule) resources.AlertReconciler {
+	return &resources.AlertReconcilerImpl{
+		ProductName: "test",
+		Log:         l.NewLogger(),
+		Installation: &integreatlyv1alpha1.RHMI{
+			ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-rhoam-operator"},
+		},
+		Alerts: []resources.AlertConfiguration{
+			{
+				AlertName: "test-alerts",
+				GroupName: "test.rules",
+				Namespace: "redhat-rhoam-observability",
+				Rules:     rules,
+			},
+		},
+	}
+}
+
+func TestRunAlertRuleTests(t *testing.T) {
+	reconciler := newTestAlertReconciler(
+		monv1.Rule{
+			Alert:       "MetricMissing",
+			Expr:        intstr.FromString("absent(rhoam_version) == 1"),
+			For:         "10m",
+			Labels:      map[string]string{"severity": "critical"},
+			Annotations: map[string]string{"message": "rhoam_version has been missing for 10+ minutes"},
+		},
+		monv1.Rule{
+			Alert:       "PodNotReady",
+			Expr:        intstr.FromString("sum by(pod, namespace) (kube_pod_status_ready{condition='true', namespace='test'} * on(pod, namespace) group_left() kube_pod_status_phase{phase='Running'}) < 1"),
+			For:         "5m",
+			Labels:      map[string]string{"severity": "warning"},
+			Annotations: map[string]string{"message": "{{ $labels.pod }} is not ready"},
+		},
+		monv1.Rule{
+			Record: "job:requests:rate5m",
+			Expr:   intstr.FromString("sum by(job) (rate(requests_total[5m]))"),
+		},
+		monv1.Rule{
+			Alert:       "HighRequestRate",
+			Expr:        intstr.FromString("job:requests:rate5m > 1"),
+			Labels:      map[string]string{"severity": "warning"},
+			Annotations: map[string]string{"message": "{{ $value | humanize }} requests per second"},
+		},
+	)
+
+	RunAlertRuleTests(t, reconciler, []Test{
+		{
+			Name: "absent metric fires after the for duration and resolves when it returns",
+			InputSeries: []Series{
+				{Series: "rhoam_version", Values: "stale _x14 1+0x10"},
+			},
+			AlertRuleTests: []AlertTestCase{
+				{EvalTime: 9 * time.Minute, AlertName: "MetricMissing"},
+				{
+					EvalTime:  10 * time.Minute,
+					AlertName: "MetricMissing",
+					ExpAlerts: []Alert{{
+						Labels:      map[string]string{"severity": "critical"},
+						Annotations: map[string]string{"message": "rhoam_version has been missing for 10+ minutes"},
+					}},
+				},
+				{EvalTime: 16 * time.Minute, AlertName: "MetricMissing"},
+			},
+		},
+		{
+			Name: "group_left join and label templating",
+			InputSeries: []Series{
+				{Series: `kube_pod_status_ready{condition="true", namespace="test", pod="a"}`, Values: "1+0x10"},
+				{Series: `kube_pod_status_ready{condition="true", namespace="test", pod="b"}`, Values: "0+0x10"},
+				{Series: `kube_pod_status_phase{phase="Running", namespace="test", pod="a"}`, Values: "1+0x10"},
+				{Series: `kube_pod_status_phase{phase="Running", namespace="test", pod="b"}`, Values: "1+0x10"},
+			},
+			AlertRuleTests: []AlertTestCase{
+				{EvalTime: 4 * time.Minute, AlertName: "PodNotReady"},
+				{
+					EvalTime:  5 * time.Minute,
+					AlertName: "PodNotReady",
+					ExpAlerts: []Alert{{
+						Labels:      map[string]string{"severity": "warning", "namespace": "test", "pod": "b"},
+						Annotations: map[string]string{"message": "b is not ready"},
+					}},
+				},
+			},
+		},
+		{
+			Name: "recording rules feed alerts",
+			InputSeries: []Series{
+				{Series: `requests_total{job="api", instance="1"}`, Values: "0+120x10"},
+				{Series: `requests_total{job="web", instance="1"}`, Values: "0+30x10"},
+			},
+			AlertRuleTests: []AlertTestCase{
+				{
+					EvalTime:  6 * time.Minute,
+					AlertName: "HighRequestRate",
+					ExpAlerts: []Alert{{
+						Labels:      map[string]string{"severity": "warning", "job": "api"},
+						Annotations: map[string]string{"message": "2 requests per second"},
+					}},
+				},
+			},
+			ExprTests: []ExprTestCase{
+				{
+					EvalTime: 6 * time.Minute,
+					Expr:     "job:requests:rate5m",
+					ExpSamples: []Sample{
+						{Labels: `job:requests:rate5m{job="api"}`, Value: 2},
+						{Labels: `job:requests:rate5m{job="web"}`, Value: 0.5},
+					},
+				},
+				{EvalTime: 6 * time.Minute, Expr: `job:requests:rate5m{job="none"}`},
+				{EvalTime: 6 * time.Minute, Expr: "scalar(job:requests:rate5m{job=\"web\"}) * 3", ExpSamples: []Sample{{Labels: "{}", Value: 1.5}}},
+			},
+		},
+	})
+}
+
+func TestRunTest_errors(t *testing.T) {
+	groups, err := RenderRules(newTestAlertReconciler(monv1.Rule{
+		Alert:  "InvalidMatching",
+		Expr:   intstr.FromString("up * on(job) group_left() job_info"),
+		Labels: map[string]string{"severity": "warning"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		test Test
+	}{
+		{
+			name: "evaluation error",
+			test: Test{
+				InputSeries: []Series{
+					{Series: `up{job="api", instance="1"}`, Values: "1+0x5"},
+					{Series: `job_info{job="api", instance="1"}`, Values: "1+0x5"},
+					{Series: `job_info{job="api", instance="2"}`, Values: "1+0x5"},
+				},
+				AlertRuleTests: []AlertTestCase{{EvalTime: time.Minute, AlertName: "InvalidMatching"}},
+			},
+		},
+		{
+			name: "unknown alert",
+			test: Test{AlertRuleTests: []AlertTestCase{{EvalTime: time.Minute, AlertName: "Unknown"}}},
+		},
+		{
+			name: "eval time between evaluations",
+			test: Test{AlertRuleTests: []AlertTestCase{{EvalTime: 90 * time.Second, AlertName: "InvalidMatching"}}},
+		},
+		{
+			name: "unexpected samples",
+			test: Test{
+				InputSeries: []Series{{Series: `up{job="api"}`, Values: "1+0x5"}},
+				ExprTests:   []ExprTestCase{{EvalTime: time.Minute, Expr: "up", ExpSamples: []Sample{{Labels: `up{job="api"}`, Value: 0}}}},
+			},
+		},
+		{
+			name: "missing samples",
+			test: Test{ExprTests: []ExprTestCase{{EvalTime: time.Minute, Expr: "up", ExpSamples: []Sample{{Labels: `up{job="api"}`, Value: 1}}}}},
+		},
+		{
+			name: "invalid input series",
+			test: Test{InputSeries: []Series{{Series: "up", Values: "1 a"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runTest(t, groups, tt.test); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
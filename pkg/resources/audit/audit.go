@@ -0,0 +1,305 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Structured audit trail of the mutations the operator performs against the APIs of the
+// managed products. Entries are written to the configured Sink, by default a JSON stream
+// on stdout that can be shipped to a SIEM
+
+const (
+	DefaultActor = "rhoam-operator"
+
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionPromote = "promote"
+	ActionDemote  = "demote"
+
+	ResultSuccess = "success"
+
+	SinkStdout    = "stdout"
+	SinkConfigMap = "configmap"
+	SinkNone      = "none"
+
+	ConfigMapName        = "rhoam-audit-log"
+	ConfigMapKey         = "entries"
+	DefaultConfigMapSize = 200
+	// DefaultConfigMapMaxBytes keeps the entries well below the 1MiB limit of a ConfigMap
+	DefaultConfigMapMaxBytes = 768 * 1024
+
+	// omittedValue replaces the before and after values of an entry too large to be kept
+	omittedValue = "omitted: exceeds the audit ConfigMap size"
+)
+
+// Entry describes a single mutation performed by the operator
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Actor is the component performing the change, e.g. "rhoam-operator/usersync"
+	Actor string `json:"actor"`
+	// Product is the managed product whose API was called
+	Product string `json:"product"`
+	// Action is the kind of mutation, e.g. create or delete
+	Action string `json:"action"`
+	// Resource identifies the object changed, e.g. "user/alice"
+	Resource string `json:"resource"`
+	// Reason explains why the operator performed the change
+	Reason string      `json:"reason,omitempty"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+	// Result is "success" or the error returned by the API
+	Result string `json:"result"`
+}
+
+// Sink persists audit entries
+type Sink interface {
+	Write(ctx context.Context, entry Entry) error
+}
+
+var (
+	sinkMu sync.RWMutex
+	sink   Sink = NewStreamSink(os.Stdout)
+	now         = time.Now
+	log         = l.NewLoggerWithContext(l.Fields{l.ComponentLogContext: "audit"})
+)
+
+// SetSink replaces the sink audit entries are written to
+func SetSink(s Sink) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sink = s
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose audit entries are recorded as performed by the actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set on the context, DefaultActor if none is set
+func ActorFrom(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return DefaultActor
+}
+
+// ControllerActor returns the actor of a controller of the operator, optionally followed by
+// the product it reconciles, e.g. "rhoam-operator/rhmi/3scale"
+func ControllerActor(controller string, product ...string) string {
+	return strings.Join(append([]string{DefaultActor, controller}, product...), "/")
+}
+
+// Record writes an audit entry. Failures to write are logged rather than returned so a
+// broken sink never blocks reconciling the products
+func Record(ctx context.Context, entry Entry) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = now().UTC()
+	}
+	if entry.Actor == "" {
+		entry.Actor = ActorFrom(ctx)
+	}
+	if entry.Result == "" {
+		entry.Result = ResultSuccess
+	}
+
+	sinkMu.RLock()
+	s := sink
+	sinkMu.RUnlock()
+	if s == nil {
+		return
+	}
+
+	if err := s.Write(ctx, entry); err != nil {
+		log.Errorf("Failed to write audit entry", l.Fields{"product": entry.Product, "action": entry.Action, "resource": entry.Resource}, err)
+	}
+}
+
+// ResultOf returns the entry result for the error returned by a mutation
+func ResultOf(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ResultSuccess
+}
+
+// ResultOfStatus returns the entry result for a mutation returning an HTTP status code
+func ResultOfStatus(statusCode int, expected int, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if statusCode != expected {
+		return fmt.Sprintf("unexpected status code %d", statusCode)
+	}
+	return ResultSuccess
+}
+
+// StreamSink writes each entry as a line of JSON
+type StreamSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func NewStreamSink(writer io.Writer) *StreamSink {
+	return &StreamSink{writer: writer}
+}
+
+type streamEntry struct {
+	Type string `json:"type"`
+	Entry
+}
+
+func (s *StreamSink) Write(_ context.Context, entry Entry) error {
+	line, err := json.Marshal(streamEntry{Type: "audit", Entry: entry})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintln(s.writer, string(line))
+	return err
+}
+
+// ConfigMapSink keeps the most recent entries in a ConfigMap, discarding the oldest once
+// the configured number of entries or maxBytes of data is reached
+type ConfigMapSink struct {
+	client    k8sclient.Client
+	namespace string
+	size      int
+	maxBytes  int
+	mu        sync.Mutex
+}
+
+func NewConfigMapSink(client k8sclient.Client, namespace string, size int) *ConfigMapSink {
+	if size <= 0 {
+		size = DefaultConfigMapSize
+	}
+	return &ConfigMapSink{
+		client:    client,
+		namespace: namespace,
+		size:      size,
+		maxBytes:  DefaultConfigMapMaxBytes,
+	}
+}
+
+func (s *ConfigMapSink) Write(ctx context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ConfigMapName,
+				Namespace: s.namespace,
+			},
+		}
+
+		_, err := controllerutil.CreateOrUpdate(ctx, s.client, configMap, func() error {
+			if configMap.Data == nil {
+				configMap.Data = map[string]string{}
+			}
+
+			var entries []Entry
+			if existing, ok := configMap.Data[ConfigMapKey]; ok && existing != "" {
+				if err := json.Unmarshal([]byte(existing), &entries); err != nil {
+					// A corrupted buffer is replaced rather than blocking further entries
+					log.Warning("Discarding unreadable audit entries: " + err.Error())
+					entries = nil
+				}
+			}
+
+			entries = append(entries, entry)
+			if len(entries) > s.size {
+				entries = entries[len(entries)-s.size:]
+			}
+
+			data, err := marshalEntries(entries, s.maxBytes)
+			if err != nil {
+				return err
+			}
+			configMap.Data[ConfigMapKey] = data
+			return nil
+		})
+		return err
+	})
+}
+
+// marshalEntries marshals the most recent entries fitting in maxBytes. The before and after
+// values of an entry too large to fit on its own are omitted
+func marshalEntries(entries []Entry, maxBytes int) (string, error) {
+	var kept []string
+	size := len("[]")
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return "", err
+		}
+		if len(kept) == 0 && size+len(data) > maxBytes {
+			entry.Before, entry.After = omittedValue, omittedValue
+			if data, err = json.Marshal(entry); err != nil {
+				return "", err
+			}
+		}
+		if len(kept) > 0 {
+			size++ // separator
+		}
+		if size+len(data) > maxBytes {
+			break
+		}
+		size += len(data)
+		kept = append([]string{string(data)}, kept...)
+	}
+	return "[" + strings.Join(kept, ",") + "]", nil
+}
+
+// GetEntries returns the entries currently held by the sink, oldest first
+func (s *ConfigMapSink) GetEntries(ctx context.Context) ([]Entry, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, k8sclient.ObjectKey{Name: ConfigMapName, Namespace: s.namespace}, configMap); err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal([]byte(configMap.Data[ConfigMapKey]), &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// NewSink returns the sink for the given type. The ConfigMap sink requires the operator
+// namespace and falls back to stdout when it is not known
+func NewSink(sinkType string, client k8sclient.Client, namespace string, size int) (Sink, error) {
+	switch sinkType {
+	case SinkStdout, "":
+		return NewStreamSink(os.Stdout), nil
+	case SinkConfigMap:
+		if namespace == "" {
+			log.Warning("Operator namespace unknown, writing audit entries to stdout")
+			return NewStreamSink(os.Stdout), nil
+		}
+		return NewConfigMapSink(client, namespace, size), nil
+	case SinkNone:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown audit sink %s, must be one of %s, %s or %s", sinkType, SinkStdout, SinkConfigMap, SinkNone)
+}
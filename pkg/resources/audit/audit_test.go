@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRecord(t *testing.T) {
+	now = func() time.Time { return time.Unix(1000, 0) }
+	defer func() { now = time.Now }()
+
+	buf := &bytes.Buffer{}
+	SetSink(NewStreamSink(buf))
+	defer SetSink(NewStreamSink(&bytes.Buffer{}))
+
+	Record(context.TODO(), Entry{
+		Product:  "3scale",
+		Action:   ActionDelete,
+		Resource: "user/alice",
+		Reason:   "user no longer exists in RHSSO",
+		Result:   ResultOf(errors.New("not found")),
+	})
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"type":      "audit",
+		"timestamp": "1970-01-01T00:16:40Z",
+		"actor":     DefaultActor,
+		"product":   "3scale",
+		"action":    ActionDelete,
+		"resource":  "user/alice",
+		"reason":    "user no longer exists in RHSSO",
+		"result":    "not found",
+	}
+	for key, value := range expected {
+		if got[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, got[key])
+		}
+	}
+}
+
+func TestConfigMapSink(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewConfigMapSink(utils.NewTestClient(scheme), "rhoam-operator", 3)
+
+	for i := 0; i < 5; i++ {
+		err := sink.Write(context.TODO(), Entry{
+			Product:  "rhsso",
+			Action:   ActionCreate,
+			Resource: fmt.Sprintf("keycloakuser/user%d", i),
+			Result:   ResultSuccess,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := sink.GetEntries(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	// The oldest entries are discarded first
+	if entries[0].Resource != "keycloakuser/user2" || entries[2].Resource != "keycloakuser/user4" {
+		t.Fatalf("unexpected entries retained: %v", entries)
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	if _, err := NewSink("syslog", nil, "", 0); err == nil {
+		t.Fatal("expected error for unknown sink")
+	}
+	s, err := NewSink(SinkConfigMap, nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*StreamSink); !ok {
+		t.Fatalf("expected fallback to stream sink without namespace, got %T", s)
+	}
+}
+
+func TestConfigMapSink_maxBytes(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := NewConfigMapSink(utils.NewTestClient(scheme), "rhoam-operator", 100)
+	sink.maxBytes = 1024
+
+	large := strings.Repeat("x", 600)
+	for i := 0; i < 10; i++ {
+		err := sink.Write(context.TODO(), Entry{
+			Product:  "3scale",
+			Action:   ActionUpdate,
+			Resource: fmt.Sprintf("user/user%d", i),
+			Before:   large,
+			After:    large,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := sink.GetEntries(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each entry is larger than the limit on its own, so is kept without its values
+	latest := entries[len(entries)-1]
+	if latest.Resource != "user/user9" || latest.Before != omittedValue || latest.After != omittedValue {
+		t.Fatalf("expected the latest entry to be kept without its values, got %v", latest)
+	}
+
+	sink.maxBytes = 4096
+	for i := 0; i < 10; i++ {
+		if err := sink.Write(context.TODO(), Entry{Product: "3scale", Action: ActionUpdate, Resource: fmt.Sprintf("user/user%d", i), Before: large}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := sink.client.Get(context.TODO(), k8sclient.ObjectKey{Name: ConfigMapName, Namespace: "rhoam-operator"}, configMap); err != nil {
+		t.Fatal(err)
+	}
+	if size := len(configMap.Data[ConfigMapKey]); size > sink.maxBytes {
+		t.Fatalf("expected at most %d bytes of entries, got %d", sink.maxBytes, size)
+	}
+	entries, err = sink.GetEntries(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 || entries[len(entries)-1].Resource != "user/user9" {
+		t.Fatalf("expected the most recent entries to be kept, got %d entries", len(entries))
+	}
+}
+
+func TestRecord_actor(t *testing.T) {
+	buf := &bytes.Buffer{}
+	SetSink(NewStreamSink(buf))
+	defer SetSink(NewStreamSink(&bytes.Buffer{}))
+
+	ctx := WithActor(context.TODO(), ControllerActor("rhmi", "3scale"))
+	Record(ctx, Entry{Product: "3scale", Action: ActionCreate, Resource: "user/alice"})
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["actor"] != "rhoam-operator/rhmi/3scale" {
+		t.Fatalf("expected the actor from the context, got %v", got["actor"])
+	}
+}
@@ -0,0 +1,229 @@
+// Package dashboard builds Grafana dashboards from typed panels so the generated JSON is
+// always well formed, and validates the dashboards before they are handed to Grafana
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+const (
+	// schemaVersion is the Grafana dashboard schema the panels are modelled on
+	schemaVersion = 21
+	// gridWidth is the number of columns of the Grafana dashboard grid
+	gridWidth = 24
+	// maxUIDLength is the longest uid Grafana accepts
+	maxUIDLength = 40
+)
+
+var variableRegexp = regexp.MustCompile(`\$(\w+)`)
+
+type Dashboard struct {
+	Title         string      `json:"title"`
+	UID           string      `json:"uid"`
+	Version       int         `json:"version"`
+	SchemaVersion int         `json:"schemaVersion"`
+	Editable      bool        `json:"editable"`
+	GraphTooltip  int         `json:"graphTooltip"`
+	Refresh       string      `json:"refresh,omitempty"`
+	Style         string      `json:"style"`
+	Timezone      string      `json:"timezone"`
+	Tags          []string    `json:"tags"`
+	Links         []string    `json:"links"`
+	Time          TimeRange   `json:"time"`
+	Timepicker    Timepicker  `json:"timepicker"`
+	Annotations   Annotations `json:"annotations"`
+	Templating    Templating  `json:"templating"`
+	Panels        []Panel     `json:"panels"`
+}
+
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Timepicker struct {
+	RefreshIntervals []string `json:"refresh_intervals"`
+}
+
+type Annotations struct {
+	List []Annotation `json:"list"`
+}
+
+type Annotation struct {
+	BuiltIn    int    `json:"builtIn"`
+	Datasource string `json:"datasource"`
+	Enable     bool   `json:"enable"`
+	Hide       bool   `json:"hide"`
+	IconColor  string `json:"iconColor"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+}
+
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a dashboard variable, panels reference it as $Name
+type Variable struct {
+	Name    string           `json:"name"`
+	Type    string           `json:"type"`
+	Query   string           `json:"query"`
+	Hide    int              `json:"hide"`
+	Current VariableOption   `json:"current"`
+	Options []VariableOption `json:"options"`
+}
+
+type VariableOption struct {
+	Selected bool   `json:"selected"`
+	Text     string `json:"text"`
+	Value    string `json:"value"`
+}
+
+// New returns an empty editable dashboard showing the last 6 hours
+func New(title, uid string) *Dashboard {
+	return &Dashboard{
+		Title:         title,
+		UID:           uid,
+		Version:       1,
+		SchemaVersion: schemaVersion,
+		Editable:      true,
+		Style:         "dark",
+		Tags:          []string{},
+		Links:         []string{},
+		Time:          TimeRange{From: "now-6h", To: "now"},
+		Timepicker: Timepicker{
+			RefreshIntervals: []string{"5s", "10s", "30s", "1m", "5m", "15m", "30m", "1h", "2h", "1d"},
+		},
+		Annotations: Annotations{List: []Annotation{{
+			BuiltIn:    1,
+			Datasource: "-- Grafana --",
+			Enable:     true,
+			Hide:       true,
+			IconColor:  "rgba(0, 211, 255, 1)",
+			Name:       "Annotations & Alerts",
+			Type:       "dashboard",
+		}}},
+		Templating: Templating{List: []Variable{}},
+		Panels:     []Panel{},
+	}
+}
+
+// WithVersion sets the version of the dashboard, bump it whenever the generated dashboard changes
+func (d *Dashboard) WithVersion(version int) *Dashboard {
+	d.Version = version
+	return d
+}
+
+func (d *Dashboard) WithRefresh(refresh string) *Dashboard {
+	d.Refresh = refresh
+	return d
+}
+
+func (d *Dashboard) WithTimeRange(from, to string) *Dashboard {
+	d.Time = TimeRange{From: from, To: to}
+	return d
+}
+
+func (d *Dashboard) WithTags(tags ...string) *Dashboard {
+	d.Tags = append(d.Tags, tags...)
+	return d
+}
+
+// WithConstant adds a hidden constant variable to the dashboard
+func (d *Dashboard) WithConstant(name, value string) *Dashboard {
+	option := VariableOption{Text: value, Value: value}
+	selected := option
+	selected.Selected = true
+	d.Templating.List = append(d.Templating.List, Variable{
+		Name:    name,
+		Type:    "constant",
+		Query:   value,
+		Hide:    2,
+		Current: option,
+		Options: []VariableOption{selected},
+	})
+	return d
+}
+
+// AddPanel places the panel at the grid position, panel ids and target ref ids are assigned in order
+func (d *Dashboard) AddPanel(panel Panel, position GridPos) *Dashboard {
+	panel.ID = len(d.Panels) + 1
+	panel.GridPos = position
+	for i := range panel.Targets {
+		panel.Targets[i].RefID = string(rune('A' + i))
+	}
+	d.Panels = append(d.Panels, panel)
+	return d
+}
+
+// Validate checks the dashboard is accepted by Grafana and the queries of its panels are valid
+// PromQL once the dashboard variables are substituted
+func (d *Dashboard) Validate() error {
+	if d.Title == "" {
+		return fmt.Errorf("dashboard title is required")
+	}
+	if d.UID == "" || len(d.UID) > maxUIDLength {
+		return fmt.Errorf("dashboard %s: uid must be between 1 and %d characters", d.Title, maxUIDLength)
+	}
+
+	variables := map[string]string{}
+	for _, variable := range d.Templating.List {
+		if _, ok := variables[variable.Name]; ok {
+			return fmt.Errorf("dashboard %s: duplicate variable %s", d.Title, variable.Name)
+		}
+		variables[variable.Name] = variable.Query
+	}
+
+	for _, panel := range d.Panels {
+		if err := panel.validate(variables); err != nil {
+			return fmt.Errorf("dashboard %s: panel %q: %w", d.Title, panel.Title, err)
+		}
+	}
+	return nil
+}
+
+// JSON validates the dashboard and returns its JSON model
+func (d *Dashboard) JSON() (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+	// Queries are not escaped for HTML so the JSON stays readable when edited in Grafana
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(d); err != nil {
+		return "", fmt.Errorf("dashboard %s: %w", d.Title, err)
+	}
+	return strings.TrimSuffix(data.String(), "\n"), nil
+}
+
+// substituteVariables replaces the variables referenced in the value with their query
+func substituteVariables(value string, variables map[string]string) (string, error) {
+	var err error
+	substituted := variableRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+		query, ok := variables[strings.TrimPrefix(reference, "$")]
+		if !ok {
+			err = fmt.Errorf("undefined variable %s", reference)
+		}
+		return query
+	})
+	return substituted, err
+}
+
+func validateExpr(expr string, variables map[string]string) error {
+	substituted, err := substituteVariables(expr, variables)
+	if err != nil {
+		return err
+	}
+	if _, err := parser.ParseExpr(substituted); err != nil {
+		return fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	return nil
+}
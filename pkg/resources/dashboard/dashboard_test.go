@@ -0,0 +1,89 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDashboardJSON(t *testing.T) {
+	d := New("Requests", "requests").
+		WithConstant("limit", "100").
+		AddPanel(NewRow("Usage"), GridPos{H: 1, W: 24}).
+		AddPanel(NewSingleStat("Requests", "sum(rate(requests_total[5m]))").WithThresholds("$limit", true), GridPos{H: 5, W: 3, Y: 1}).
+		AddPanel(NewGraph("Requests over time",
+			Target{Expr: "sum(rate(requests_total[5m]))", LegendFormat: "requests"},
+			Target{Expr: "$limit", LegendFormat: "limit"}), GridPos{H: 10, W: 21, X: 3, Y: 1})
+
+	model, err := d.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var parsed struct {
+		Panels []map[string]interface{} `json:"panels"`
+	}
+	if err := json.Unmarshal([]byte(model), &parsed); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if len(parsed.Panels) != 3 {
+		t.Fatalf("expected 3 panels, got %d", len(parsed.Panels))
+	}
+	for i, panel := range parsed.Panels {
+		if panel["id"] != float64(i+1) {
+			t.Errorf("expected panel ids to be assigned in order, got %v", panel["id"])
+		}
+	}
+	if _, ok := parsed.Panels[0]["colors"]; ok {
+		t.Errorf("expected the row to have no single stat options")
+	}
+	if parsed.Panels[1]["thresholds"] != "$limit" || parsed.Panels[1]["nullPointMode"] != "connected" {
+		t.Errorf("unexpected single stat panel %v", parsed.Panels[1])
+	}
+	targets := parsed.Panels[2]["targets"].([]interface{})
+	if targets[1].(map[string]interface{})["refId"] != "B" {
+		t.Errorf("expected the target ref ids to be assigned in order, got %v", targets)
+	}
+}
+
+func TestDashboardValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		dashboard *Dashboard
+		wantErr   string
+	}{
+		{
+			name:      "uid too long",
+			dashboard: New("Requests", strings.Repeat("a", 41)),
+			wantErr:   "uid must be between 1 and 40 characters",
+		},
+		{
+			name:      "invalid query",
+			dashboard: New("Requests", "requests").AddPanel(NewSingleStat("Requests", "sum(rate(requests_total[5m])"), GridPos{H: 5, W: 3}),
+			wantErr:   "invalid expression",
+		},
+		{
+			name:      "undefined variable",
+			dashboard: New("Requests", "requests").AddPanel(NewSingleStat("Requests", "up").WithThresholds("$limit", true), GridPos{H: 5, W: 3}),
+			wantErr:   "undefined variable $limit",
+		},
+		{
+			name:      "panel outside of the grid",
+			dashboard: New("Requests", "requests").AddPanel(NewGraph("Requests", Target{Expr: "up"}), GridPos{H: 5, W: 12, X: 16}),
+			wantErr:   "outside of the 24 column grid",
+		},
+		{
+			name:      "panel without targets",
+			dashboard: New("Requests", "requests").AddPanel(NewGraph("Requests"), GridPos{H: 5, W: 12}),
+			wantErr:   "at least one target is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.dashboard.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package dashboard
+
+import (
+	"fmt"
+)
+
+const (
+	PanelTypeRow        = "row"
+	PanelTypeSingleStat = "singlestat"
+	PanelTypeGraph      = "graph"
+)
+
+// Panel is a dashboard panel, the options of its type are set by NewRow, NewSingleStat or NewGraph
+type Panel struct {
+	ID          int      `json:"id"`
+	Type        string   `json:"type"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Datasource  string   `json:"datasource,omitempty"`
+	GridPos     GridPos  `json:"gridPos"`
+	Interval    string   `json:"interval,omitempty"`
+	Transparent bool     `json:"transparent,omitempty"`
+	Targets     []Target `json:"targets,omitempty"`
+	// NullPointMode is how missing values are shown, e.g. connected or null as zero
+	NullPointMode string `json:"nullPointMode,omitempty"`
+
+	*RowOptions
+	*SingleStatOptions
+	*GraphOptions
+}
+
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Target struct {
+	Expr         string `json:"expr"`
+	Instant      bool   `json:"instant"`
+	Interval     string `json:"interval,omitempty"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	RefID        string `json:"refId"`
+}
+
+type RowOptions struct {
+	Collapsed bool    `json:"collapsed"`
+	Panels    []Panel `json:"panels"`
+}
+
+type SingleStatOptions struct {
+	ColorBackground bool     `json:"colorBackground"`
+	ColorValue      bool     `json:"colorValue"`
+	Colors          []string `json:"colors"`
+	Format          string   `json:"format"`
+	// Thresholds are the comma separated values the colors change at, dashboard variables may be used
+	Thresholds      string     `json:"thresholds"`
+	Prefix          string     `json:"prefix"`
+	Postfix         string     `json:"postfix"`
+	PrefixFontSize  string     `json:"prefixFontSize"`
+	PostfixFontSize string     `json:"postfixFontSize"`
+	ValueFontSize   string     `json:"valueFontSize"`
+	ValueName       string     `json:"valueName"`
+	MappingType     int        `json:"mappingType"`
+	ValueMaps       []ValueMap `json:"valueMaps"`
+}
+
+type ValueMap struct {
+	Op    string `json:"op"`
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+type GraphOptions struct {
+	Decimals     int     `json:"decimals"`
+	Fill         int     `json:"fill"`
+	FillGradient int     `json:"fillGradient"`
+	Lines        bool    `json:"lines"`
+	Linewidth    int     `json:"linewidth"`
+	Legend       Legend  `json:"legend"`
+	Tooltip      Tooltip `json:"tooltip"`
+	XAxis        XAxis   `json:"xaxis"`
+	YAxes        []YAxis `json:"yaxes"`
+}
+
+type Legend struct {
+	Show bool `json:"show"`
+}
+
+type Tooltip struct {
+	Shared    bool   `json:"shared"`
+	Sort      int    `json:"sort"`
+	ValueType string `json:"value_type"`
+}
+
+type XAxis struct {
+	Mode string `json:"mode"`
+	Show bool   `json:"show"`
+}
+
+type YAxis struct {
+	Format  string `json:"format"`
+	LogBase int    `json:"logBase"`
+	Show    bool   `json:"show"`
+}
+
+// NewRow returns a row grouping the panels placed below it
+func NewRow(title string) Panel {
+	return Panel{
+		Type:       PanelTypeRow,
+		Title:      title,
+		RowOptions: &RowOptions{Panels: []Panel{}},
+	}
+}
+
+// NewSingleStat returns a panel showing the current value of an instant query, coloured
+// green, orange and red by its thresholds
+func NewSingleStat(title, expr string) Panel {
+	return Panel{
+		Type:          PanelTypeSingleStat,
+		Title:         title,
+		Targets:       []Target{{Expr: expr, Instant: true}},
+		NullPointMode: "connected",
+		SingleStatOptions: &SingleStatOptions{
+			Colors:          []string{"#299c46", "rgba(237, 129, 40, 0.89)", "#d44a3a"},
+			Format:          "none",
+			PrefixFontSize:  "50%",
+			PostfixFontSize: "50%",
+			ValueFontSize:   "80%",
+			ValueName:       "avg",
+			MappingType:     1,
+			ValueMaps:       []ValueMap{{Op: "=", Text: "N/A", Value: "null"}},
+		},
+	}
+}
+
+// NewGraph returns a time series graph of the targets
+func NewGraph(title string, targets ...Target) Panel {
+	return Panel{
+		Type:          PanelTypeGraph,
+		Title:         title,
+		Targets:       targets,
+		NullPointMode: "null as zero",
+		GraphOptions: &GraphOptions{
+			Fill:      1,
+			Lines:     true,
+			Linewidth: 1,
+			Legend:    Legend{Show: true},
+			Tooltip:   Tooltip{Shared: true, ValueType: "individual"},
+			XAxis:     XAxis{Mode: "time", Show: true},
+			YAxes:     []YAxis{{Format: "short", LogBase: 1, Show: true}, {Format: "short", LogBase: 1}},
+		},
+	}
+}
+
+func (p Panel) WithDescription(description string) Panel {
+	p.Description = description
+	return p
+}
+
+func (p Panel) WithDatasource(datasource string) Panel {
+	p.Datasource = datasource
+	return p
+}
+
+func (p Panel) WithInterval(interval string) Panel {
+	p.Interval = interval
+	return p
+}
+
+func (p Panel) WithTransparent() Panel {
+	p.Transparent = true
+	return p
+}
+
+// WithThresholds sets the thresholds and background colouring of a single stat panel
+func (p Panel) WithThresholds(thresholds string, colorBackground bool) Panel {
+	if p.SingleStatOptions != nil {
+		options := *p.SingleStatOptions
+		options.Thresholds = thresholds
+		options.ColorBackground = colorBackground
+		p.SingleStatOptions = &options
+	}
+	return p
+}
+
+func (p Panel) WithPostfix(postfix string) Panel {
+	if p.SingleStatOptions != nil {
+		options := *p.SingleStatOptions
+		options.Postfix = postfix
+		p.SingleStatOptions = &options
+	}
+	return p
+}
+
+// WithValueName sets the reduction of a single stat panel, e.g. avg, first or current
+func (p Panel) WithValueName(valueName string) Panel {
+	if p.SingleStatOptions != nil {
+		options := *p.SingleStatOptions
+		options.ValueName = valueName
+		p.SingleStatOptions = &options
+	}
+	return p
+}
+
+func (p Panel) WithFillGradient(fillGradient int) Panel {
+	if p.GraphOptions != nil {
+		options := *p.GraphOptions
+		options.FillGradient = fillGradient
+		p.GraphOptions = &options
+	}
+	return p
+}
+
+func (p Panel) validate(variables map[string]string) error {
+	if p.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if p.GridPos.W <= 0 || p.GridPos.H <= 0 || p.GridPos.X < 0 || p.GridPos.Y < 0 || p.GridPos.X+p.GridPos.W > gridWidth {
+		return fmt.Errorf("grid position %+v is outside of the %d column grid", p.GridPos, gridWidth)
+	}
+	if p.Type != PanelTypeRow && len(p.Targets) == 0 {
+		return fmt.Errorf("at least one target is required")
+	}
+	for _, target := range p.Targets {
+		if err := validateExpr(target.Expr, variables); err != nil {
+			return err
+		}
+	}
+	if p.SingleStatOptions != nil {
+		if _, err := substituteVariables(p.SingleStatOptions.Thresholds, variables); err != nil {
+			return fmt.Errorf("thresholds: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logrus "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// DefaultLevelName sets the level of every logger without a level of its own
+	DefaultLevelName = "default"
+
+	// LogLevelsAnnotation on the RHMI CR and the LogLevelsKey of the LogLevelsConfigMapName
+	// ConfigMap hold comma separated name=level pairs, e.g. "default=info,3scale=debug".
+	// Names are the controller, product, stage or component a logger was created for
+	LogLevelsAnnotation    = "integreatly.org/log-levels"
+	LogLevelsConfigMapName = "rhoam-log-levels"
+	LogLevelsKey           = "levels"
+
+	// CorrelationIDLogContext is attached to every line logged during a single reconcile
+	CorrelationIDLogContext = "reconcileID"
+
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var levels = &levelRegistry{
+	defaultLevel: logrus.InfoLevel,
+	named:        map[string]logrus.Level{},
+}
+
+type levelRegistry struct {
+	mu           sync.RWMutex
+	defaultLevel logrus.Level
+	named        map[string]logrus.Level
+}
+
+func (r *levelRegistry) levelFor(name string) logrus.Level {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if level, ok := r.named[name]; ok {
+		return level
+	}
+	return r.defaultLevel
+}
+
+// ParseLevels parses comma separated name=level pairs, e.g. "default=info,3scale=debug"
+func ParseLevels(spec string) (map[string]logrus.Level, error) {
+	parsed := map[string]logrus.Level{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, levelName, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid log level %q, expected name=level", pair)
+		}
+		level, err := logrus.ParseLevel(strings.TrimSpace(levelName))
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level for %s: %w", name, err)
+		}
+		parsed[name] = level
+	}
+	return parsed, nil
+}
+
+// SetLevels replaces the configured log levels. Loggers without a level of their own use
+// the DefaultLevelName entry, or info if it is not set
+func SetLevels(configured map[string]logrus.Level) {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+
+	levels.defaultLevel = logrus.InfoLevel
+	levels.named = map[string]logrus.Level{}
+	for name, level := range configured {
+		if name == DefaultLevelName {
+			levels.defaultLevel = level
+			continue
+		}
+		levels.named[name] = level
+	}
+
+	// The standard logger filters before the per logger level is checked so it must allow
+	// the most verbose level configured
+	mostVerbose := levels.defaultLevel
+	for _, level := range levels.named {
+		if level > mostVerbose {
+			mostVerbose = level
+		}
+	}
+	logrus.SetLevel(mostVerbose)
+}
+
+// GetLevels returns the configured log levels in the format accepted by ParseLevels
+func GetLevels() string {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	pairs := []string{fmt.Sprintf("%s=%s", DefaultLevelName, levels.defaultLevel)}
+	for name, level := range levels.named {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, level))
+	}
+	sort.Strings(pairs[1:])
+	return strings.Join(pairs, ",")
+}
+
+// NewCorrelationID returns a unique ID to identify the lines logged during a reconcile
+func NewCorrelationID() string {
+	return string(uuid.NewUUID())
+}
+
+// ConfigureFormat sets the format of the standard logger. The json format uses the same
+// keys as the controller-runtime zap logger so both can be parsed together
+func ConfigureFormat(format string) error {
+	switch format {
+	case FormatText, "":
+		logrus.SetFormatter(&logrus.TextFormatter{
+			ForceColors:      true,
+			FullTimestamp:    true,
+			QuoteEmptyFields: false,
+		})
+	case FormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyTime:  "ts",
+				logrus.FieldKeyMsg:   "msg",
+				logrus.FieldKeyLevel: "level",
+			},
+		})
+	default:
+		return fmt.Errorf("unknown log format %s, must be one of %s or %s", format, FormatText, FormatJSON)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"reflect"
+
 	logrus "github.com/sirupsen/logrus"
 )
 
@@ -13,6 +15,8 @@ const (
 
 type Logger struct {
 	Logger *logrus.Entry
+	// name selects the configured log level, see SetLevels
+	name string
 }
 type Fields map[string]interface{}
 
@@ -26,7 +30,45 @@ func NewLoggerWithContext(fields Fields) Logger {
 	logger := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields(fields))
 	return Logger{
 		Logger: logger,
+		name:   levelName(fields),
+	}
+}
+
+// The most specific context a logger is created with names its level. Contexts are often
+// typed strings, e.g. the product name of a product logger
+func levelName(fields Fields) string {
+	for _, context := range []string{ProductLogContext, ComponentLogContext, StageLogContext, ControllerLogContext} {
+		value := reflect.ValueOf(fields[context])
+		if value.Kind() == reflect.String {
+			return value.String()
+		}
 	}
+	return DefaultLevelName
+}
+
+// WithCorrelationID returns a copy of the logger attaching the ID to every line logged
+func (l Logger) WithCorrelationID(id string) Logger {
+	entry := l.Logger
+	if entry == nil {
+		entry = logrus.NewEntry(logrus.StandardLogger())
+	}
+	return Logger{
+		Logger: entry.WithField(CorrelationIDLogContext, id),
+		name:   l.name,
+	}
+}
+
+// CorrelationID returns the ID attached by WithCorrelationID, or an empty string
+func (l Logger) CorrelationID() string {
+	if l.Logger == nil {
+		return ""
+	}
+	id, _ := l.Logger.Data[CorrelationIDLogContext].(string)
+	return id
+}
+
+func (l Logger) enabled(level logrus.Level) bool {
+	return levels.levelFor(l.name) >= level
 }
 
 func (l Logger) WithContext(fields Fields) *logrus.Entry {
@@ -34,22 +76,37 @@ func (l Logger) WithContext(fields Fields) *logrus.Entry {
 }
 
 func (l Logger) Infof(message string, fields map[string]interface{}) {
+	if !l.enabled(logrus.InfoLevel) {
+		return
+	}
 	l.Logger.WithFields(fields).Info(message)
 }
 
 func (l Logger) Info(message string) {
+	if !l.enabled(logrus.InfoLevel) {
+		return
+	}
 	l.Logger.Info(message)
 }
 
 func (l Logger) Debugf(message string, fields map[string]interface{}) {
+	if !l.enabled(logrus.DebugLevel) {
+		return
+	}
 	l.Logger.WithFields(fields).Debug(message)
 }
 
 func (l Logger) Debug(message string) {
+	if !l.enabled(logrus.DebugLevel) {
+		return
+	}
 	l.Logger.Debug(message)
 }
 
 func (l Logger) Errorf(message string, fields map[string]interface{}, err error) {
+	if !l.enabled(logrus.ErrorLevel) {
+		return
+	}
 	fields = addError(fields, err)
 	l.Logger.WithFields(fields).Errorf(message)
 }
@@ -78,9 +135,15 @@ func (l Logger) Fatal(message string, err error) {
 }
 
 func (l Logger) Warningf(message string, fields map[string]interface{}) {
+	if !l.enabled(logrus.WarnLevel) {
+		return
+	}
 	l.Logger.WithFields(fields).Warning(message)
 }
 
 func (l Logger) Warning(message string) {
+	if !l.enabled(logrus.WarnLevel) {
+		return
+	}
 	l.Logger.Warning(message)
 }
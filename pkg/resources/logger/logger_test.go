@@ -1,8 +1,15 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"os"
+	"strings"
 	"testing"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/sirupsen/logrus"
 )
 
 func TestLogger(t *testing.T) {
@@ -24,3 +31,78 @@ func TestLogger(t *testing.T) {
 	log.Error("This is a Error log with nil err object", nil)
 	log.Errorf("This is a Errorf log with nil err object", nil, nil)
 }
+
+func TestLoggerLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logrus.SetOutput(buf)
+	defer logrus.SetOutput(os.Stderr)
+	defer SetLevels(nil)
+
+	levels, err := ParseLevels("default=warn, 3scale=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLevels(levels)
+
+	// Product loggers are created with the product name, as done by the RHMI controller
+	NewLoggerWithContext(Fields{ProductLogContext: integreatlyv1alpha1.ProductRHSSO}).Info("rhsso info")
+	NewLoggerWithContext(Fields{ProductLogContext: integreatlyv1alpha1.Product3Scale}).Debug("3scale debug")
+	NewLoggerWithContext(Fields{ProductLogContext: integreatlyv1alpha1.ProductRHSSO}).Warning("rhsso warning")
+
+	if strings.Contains(buf.String(), "rhsso info") {
+		t.Errorf("expected rhsso info to be filtered, got %q", buf.String())
+	}
+	for _, expected := range []string{"3scale debug", "rhsso warning"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q to be logged, got %q", expected, buf.String())
+		}
+	}
+	if GetLevels() != "default=warning,3scale=debug" {
+		t.Errorf("unexpected levels %s", GetLevels())
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	for _, spec := range []string{"threescale", "=debug", "threescale=loud"} {
+		if _, err := ParseLevels(spec); err == nil {
+			t.Errorf("expected error parsing %q", spec)
+		}
+	}
+	levels, err := ParseLevels("")
+	if err != nil || len(levels) != 0 {
+		t.Errorf("expected no levels for empty spec, got %v %v", levels, err)
+	}
+}
+
+func TestLoggerCorrelationID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logrus.SetOutput(buf)
+	defer logrus.SetOutput(os.Stderr)
+	if err := ConfigureFormat(FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ConfigureFormat(FormatText) }()
+
+	log := NewLoggerWithContext(Fields{ControllerLogContext: "test"}).WithCorrelationID("1234")
+	if log.CorrelationID() != "1234" {
+		t.Fatalf("expected correlation ID 1234, got %s", log.CorrelationID())
+	}
+	log.Info("with correlation ID")
+
+	line := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	for key, value := range map[string]interface{}{CorrelationIDLogContext: "1234", "msg": "with correlation ID", "level": "info"} {
+		if line[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, line[key])
+		}
+	}
+	if _, ok := line["ts"]; !ok {
+		t.Errorf("expected ts key, got %v", line)
+	}
+
+	if err := ConfigureFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
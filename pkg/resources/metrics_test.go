@@ -2,18 +2,26 @@ package resources
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	crov1 "github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1"
+	croTypes "github.com/integr8ly/cloud-resource-operator/apis/integreatly/v1alpha1/types"
 	"github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 	"github.com/integr8ly/integreatly-operator/utils"
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
@@ -313,3 +321,99 @@ func getRHMIcr() *v1alpha1.RHMI {
 		},
 	}
 }
+
+func TestReconcilePostgresAlerts(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		phase         croTypes.StatusPhase
+		productName   string
+		wantPhase     v1alpha1.StatusPhase
+		wantRules     []string
+		wantNoRules   []string
+		wantSeverity  string
+		wantSopSuffix string
+	}{
+		{
+			name:        "only the failure alerts are created before the postgres is complete",
+			phase:       croTypes.PhaseInProgress,
+			productName: "3scale",
+			wantPhase:   v1alpha1.PhaseAwaitingComponents,
+			wantRules: []string{
+				"resource-status-phase-failed-rule-threescale-postgres-test",
+				"resource-deletion-status-phase-failed-rule-threescale-postgres-test",
+			},
+			wantNoRules: []string{"availability-rule-threescale-postgres-test"},
+		},
+		{
+			name:          "availability alerts are critical for 3scale",
+			phase:         croTypes.PhaseComplete,
+			productName:   "3scale",
+			wantPhase:     v1alpha1.PhaseCompleted,
+			wantRules:     []string{"availability-rule-threescale-postgres-test", "postgres-storage-low"},
+			wantSeverity:  "critical",
+			wantSopSuffix: "Threescale-Postgres-TestPostgresInstanceUnavailable.asciidoc",
+		},
+		{
+			name:          "availability alerts only warn for sso",
+			phase:         croTypes.PhaseComplete,
+			productName:   "rhsso",
+			wantPhase:     v1alpha1.PhaseCompleted,
+			wantRules:     []string{"availability-rule-threescale-postgres-test"},
+			wantSeverity:  "warning",
+			wantSopSuffix: "postgres_instance_unavailable.asciidoc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := utils.NewTestClient(scheme)
+			postgres := &crov1.Postgres{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "threescale-postgres-test",
+					Namespace: rhoamNS,
+					Labels:    map[string]string{"productName": tt.productName},
+				},
+				Status: croTypes.ResourceTypeStatus{Phase: tt.phase, Strategy: "aws"},
+			}
+			inst := &v1alpha1.RHMI{
+				ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-rhoam"},
+				Spec:       v1alpha1.RHMISpec{Type: string(v1alpha1.InstallationTypeManagedApi)},
+			}
+
+			phase, err := ReconcilePostgresAlerts(context.TODO(), client, inst, postgres, l.NewLogger())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if phase != tt.wantPhase {
+				t.Fatalf("expected phase %s, got %s", tt.wantPhase, phase)
+			}
+
+			for _, name := range tt.wantRules {
+				rule := &monv1.PrometheusRule{}
+				if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: name, Namespace: config.GetOboNamespace(inst.Namespace)}, rule); err != nil {
+					t.Fatalf("expected prometheus rule %s: %v", name, err)
+				}
+				if name != "availability-rule-threescale-postgres-test" {
+					continue
+				}
+				alert := rule.Spec.Groups[0].Rules[0]
+				if alert.Labels["severity"] != tt.wantSeverity {
+					t.Errorf("expected severity %s, got %s", tt.wantSeverity, alert.Labels["severity"])
+				}
+				if !strings.HasSuffix(alert.Annotations["sop_url"], tt.wantSopSuffix) {
+					t.Errorf("expected sop url ending in %s, got %s", tt.wantSopSuffix, alert.Annotations["sop_url"])
+				}
+			}
+			for _, name := range tt.wantNoRules {
+				rule := &monv1.PrometheusRule{}
+				if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: name, Namespace: config.GetOboNamespace(inst.Namespace)}, rule); !k8serr.IsNotFound(err) {
+					t.Errorf("expected prometheus rule %s to not exist, got %v", name, err)
+				}
+			}
+		})
+	}
+}
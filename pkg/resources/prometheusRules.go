@@ -80,7 +80,11 @@ func (r *AlertReconcilerImpl) ReconcileAlerts(ctx context.Context, client k8scli
 }
 
 func (r *AlertReconcilerImpl) reconcileRule(ctx context.Context, client k8sclient.Client, alert AlertConfiguration) (controllerutil.OperationResult, error) {
+	return reconcileAlertRule(ctx, client, alert)
+}
 
+// reconcileAlertRule creates or updates the PrometheusRule of an alert configuration
+func reconcileAlertRule(ctx context.Context, client k8sclient.Client, alert AlertConfiguration) (controllerutil.OperationResult, error) {
 	var alertRulesType interface{} = alert.Rules
 
 	switch alertRules := alertRulesType.(type) {
@@ -26,9 +26,15 @@ var _ ProductConfig = &ProductConfigMock{}
 //			GetActiveQuotaFunc: func() string {
 //				panic("mock out the GetActiveQuota method")
 //			},
+//			GetComponentConfigFunc: func(name string) ResourceConfig {
+//				panic("mock out the GetComponentConfig method")
+//			},
 //			GetRateLimitConfigFunc: func() marin3rconfig.RateLimitConfig {
 //				panic("mock out the GetRateLimitConfig method")
 //			},
+//			GetRateLimitStorageConfigFunc: func() marin3rconfig.RateLimitStorageConfig {
+//				panic("mock out the GetRateLimitStorageConfig method")
+//			},
 //			GetReplicasFunc: func(ddcssName string) int32 {
 //				panic("mock out the GetReplicas method")
 //			},
@@ -48,9 +54,15 @@ type ProductConfigMock struct {
 	// GetActiveQuotaFunc mocks the GetActiveQuota method.
 	GetActiveQuotaFunc func() string
 
+	// GetComponentConfigFunc mocks the GetComponentConfig method.
+	GetComponentConfigFunc func(name string) ResourceConfig
+
 	// GetRateLimitConfigFunc mocks the GetRateLimitConfig method.
 	GetRateLimitConfigFunc func() marin3rconfig.RateLimitConfig
 
+	// GetRateLimitStorageConfigFunc mocks the GetRateLimitStorageConfig method.
+	GetRateLimitStorageConfigFunc func() marin3rconfig.RateLimitStorageConfig
+
 	// GetReplicasFunc mocks the GetReplicas method.
 	GetReplicasFunc func(ddcssName string) int32
 
@@ -67,9 +79,17 @@ type ProductConfigMock struct {
 		// GetActiveQuota holds details about calls to the GetActiveQuota method.
 		GetActiveQuota []struct {
 		}
+		// GetComponentConfig holds details about calls to the GetComponentConfig method.
+		GetComponentConfig []struct {
+			// Name is the name argument value.
+			Name string
+		}
 		// GetRateLimitConfig holds details about calls to the GetRateLimitConfig method.
 		GetRateLimitConfig []struct {
 		}
+		// GetRateLimitStorageConfig holds details about calls to the GetRateLimitStorageConfig method.
+		GetRateLimitStorageConfig []struct {
+		}
 		// GetReplicas holds details about calls to the GetReplicas method.
 		GetReplicas []struct {
 			// DdcssName is the ddcssName argument value.
@@ -81,11 +101,13 @@ type ProductConfigMock struct {
 			DdcssName string
 		}
 	}
-	lockConfigure          sync.RWMutex
-	lockGetActiveQuota     sync.RWMutex
-	lockGetRateLimitConfig sync.RWMutex
-	lockGetReplicas        sync.RWMutex
-	lockGetResourceConfig  sync.RWMutex
+	lockConfigure                 sync.RWMutex
+	lockGetActiveQuota            sync.RWMutex
+	lockGetComponentConfig        sync.RWMutex
+	lockGetRateLimitConfig        sync.RWMutex
+	lockGetRateLimitStorageConfig sync.RWMutex
+	lockGetReplicas               sync.RWMutex
+	lockGetResourceConfig         sync.RWMutex
 }
 
 // Configure calls ConfigureFunc.
@@ -147,6 +169,38 @@ func (mock *ProductConfigMock) GetActiveQuotaCalls() []struct {
 	return calls
 }
 
+// GetComponentConfig calls GetComponentConfigFunc.
+func (mock *ProductConfigMock) GetComponentConfig(name string) ResourceConfig {
+	if mock.GetComponentConfigFunc == nil {
+		panic("ProductConfigMock.GetComponentConfigFunc: method is nil but ProductConfig.GetComponentConfig was just called")
+	}
+	callInfo := struct {
+		Name string
+	}{
+		Name: name,
+	}
+	mock.lockGetComponentConfig.Lock()
+	mock.calls.GetComponentConfig = append(mock.calls.GetComponentConfig, callInfo)
+	mock.lockGetComponentConfig.Unlock()
+	return mock.GetComponentConfigFunc(name)
+}
+
+// GetComponentConfigCalls gets all the calls that were made to GetComponentConfig.
+// Check the length with:
+//
+//	len(mockedProductConfig.GetComponentConfigCalls())
+func (mock *ProductConfigMock) GetComponentConfigCalls() []struct {
+	Name string
+} {
+	var calls []struct {
+		Name string
+	}
+	mock.lockGetComponentConfig.RLock()
+	calls = mock.calls.GetComponentConfig
+	mock.lockGetComponentConfig.RUnlock()
+	return calls
+}
+
 // GetRateLimitConfig calls GetRateLimitConfigFunc.
 func (mock *ProductConfigMock) GetRateLimitConfig() marin3rconfig.RateLimitConfig {
 	if mock.GetRateLimitConfigFunc == nil {
@@ -174,6 +228,33 @@ func (mock *ProductConfigMock) GetRateLimitConfigCalls() []struct {
 	return calls
 }
 
+// GetRateLimitStorageConfig calls GetRateLimitStorageConfigFunc.
+func (mock *ProductConfigMock) GetRateLimitStorageConfig() marin3rconfig.RateLimitStorageConfig {
+	if mock.GetRateLimitStorageConfigFunc == nil {
+		panic("ProductConfigMock.GetRateLimitStorageConfigFunc: method is nil but ProductConfig.GetRateLimitStorageConfig was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockGetRateLimitStorageConfig.Lock()
+	mock.calls.GetRateLimitStorageConfig = append(mock.calls.GetRateLimitStorageConfig, callInfo)
+	mock.lockGetRateLimitStorageConfig.Unlock()
+	return mock.GetRateLimitStorageConfigFunc()
+}
+
+// GetRateLimitStorageConfigCalls gets all the calls that were made to GetRateLimitStorageConfig.
+// Check the length with:
+//
+//	len(mockedProductConfig.GetRateLimitStorageConfigCalls())
+func (mock *ProductConfigMock) GetRateLimitStorageConfigCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockGetRateLimitStorageConfig.RLock()
+	calls = mock.calls.GetRateLimitStorageConfig
+	mock.lockGetRateLimitStorageConfig.RUnlock()
+	return calls
+}
+
 // GetReplicas calls GetReplicasFunc.
 func (mock *ProductConfigMock) GetReplicas(ddcssName string) int32 {
 	if mock.GetReplicasFunc == nil {
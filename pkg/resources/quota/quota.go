@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/integr8ly/integreatly-operator/pkg/resources/cluster"
 	configv1 "github.com/openshift/api/config/v1"
@@ -33,6 +35,8 @@ const (
 	KeycloakName                = "rhssouser"
 	GrafanaName                 = "grafana"
 	NoobaaCoreName              = "noobaa-core"
+	EnvoySidecarName            = "envoy_sidecar"
+	DiscoveryServiceName        = "discovery_service"
 	OneHundredThousandQuotaName = "100K"
 	OneMillionQuotaName         = "1 Million"
 	FiveMillionQuotaName        = "5 Million"
@@ -50,12 +54,14 @@ var (
 			BackendWorkerName,
 			ApicastProductionName,
 			ApicastStagingName,
+			EnvoySidecarName,
 		},
 		v1alpha1.ProductRHSSOUser: {
 			KeycloakName,
 		},
 		v1alpha1.ProductMarin3r: {
 			RateLimitName,
+			DiscoveryServiceName,
 		},
 		v1alpha1.ProductGrafana: {
 			GrafanaName,
@@ -68,6 +74,8 @@ type Quota struct {
 	productConfigs  map[v1alpha1.ProductName]QuotaProductConfig
 	isUpdated       bool
 	rateLimitConfig marin3rconfig.RateLimitConfig
+	// rateLimitStorageConfig is the storage of the rate limit counters
+	rateLimitStorageConfig marin3rconfig.RateLimitStorageConfig
 }
 
 //go:generate moq -out product_config_moq.go . ProductConfig
@@ -75,7 +83,9 @@ type ProductConfig interface {
 	Configure(obj metav1.Object) error
 	GetResourceConfig(ddcssName string) (corev1.ResourceRequirements, bool)
 	GetReplicas(ddcssName string) int32
+	GetComponentConfig(name string) ResourceConfig
 	GetRateLimitConfig() marin3rconfig.RateLimitConfig
+	GetRateLimitStorageConfig() marin3rconfig.RateLimitStorageConfig
 	GetActiveQuota() string
 }
 
@@ -90,13 +100,18 @@ type QuotaProductConfig struct {
 type ResourceConfig struct {
 	Replicas  int32                       `json:"replicas,omitempty"`
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Image overrides the image of the envoy sidecars and the discovery service
+	Image string `json:"image,omitempty"`
+	// Concurrency is the number of worker threads of the envoy sidecars
+	Concurrency int32 `json:"concurrency,omitempty"`
 }
 
 type quotaConfigReceiver struct {
-	Name      string                        `json:"name,omitempty"`
-	Param     string                        `json:"param"`
-	RateLimit marin3rconfig.RateLimitConfig `json:"rate-limiting,omitempty"`
-	Resources map[string]ResourceConfig     `json:"resources,omitempty"`
+	Name             string                               `json:"name,omitempty"`
+	Param            string                               `json:"param"`
+	RateLimit        marin3rconfig.RateLimitConfig        `json:"rate-limiting,omitempty"`
+	RateLimitStorage marin3rconfig.RateLimitStorageConfig `json:"rate-limiting-storage,omitempty"`
+	Resources        map[string]ResourceConfig            `json:"resources,omitempty"`
 }
 
 func GetQuota(ctx context.Context, c client.Client, quotaParam string, QuotaConfig *corev1.ConfigMap, retQuota *Quota) error {
@@ -151,6 +166,10 @@ func GetQuota(ctx context.Context, c client.Client, quotaParam string, QuotaConf
 
 	//populate rate limit configuration
 	retQuota.rateLimitConfig = quotaReceiver.RateLimit
+	if err := quotaReceiver.RateLimitStorage.Validate(quotaReceiver.Resources[RateLimitName].Replicas); err != nil {
+		return fmt.Errorf("invalid rate limit storage of the '%s' quota: %w", quotaReceiver.Name, err)
+	}
+	retQuota.rateLimitStorageConfig = quotaReceiver.RateLimitStorage
 	return nil
 }
 
@@ -178,6 +197,13 @@ func (p QuotaProductConfig) GetResourceConfig(ddcssName string) (corev1.Resource
 	return p.resourceConfigs[ddcssName].Resources, true
 }
 
+// GetComponentConfig returns the configuration of a component which is not sized through
+// Configure, such as the envoy sidecars. The zero value is returned when the quota does not
+// size the component, its defaults then apply
+func (p QuotaProductConfig) GetComponentConfig(name string) ResourceConfig {
+	return p.resourceConfigs[name]
+}
+
 func (p QuotaProductConfig) GetRateLimitConfig() marin3rconfig.RateLimitConfig {
 	return p.quota.rateLimitConfig
 }
@@ -186,6 +212,11 @@ func (s *Quota) GetRateLimitConfig() marin3rconfig.RateLimitConfig {
 	return s.rateLimitConfig
 }
 
+// GetRateLimitStorageConfig returns the storage of the rate limit counters of the quota
+func (p QuotaProductConfig) GetRateLimitStorageConfig() marin3rconfig.RateLimitStorageConfig {
+	return p.quota.rateLimitStorageConfig
+}
+
 func (p QuotaProductConfig) GetActiveQuota() string {
 	return p.quota.name
 }
@@ -199,6 +230,9 @@ func (p QuotaProductConfig) Configure(obj metav1.Object) error {
 
 	switch t := obj.(type) {
 	case *appsv1.DeploymentConfig:
+		if t.Spec.Template == nil {
+			t.Spec.Template = &corev1.PodTemplateSpec{}
+		}
 		p.mutateReplicas(&t.Spec.Replicas, name)
 		p.mutatePodTemplate(t.Spec.Template, name)
 	case *appsv12.Deployment:
@@ -363,3 +397,23 @@ func checkApiManager(t *threescalev1.APIManager) {
 	checkResourceBlock(t.Spec.Backend.WorkerSpec.Resources)
 
 }
+
+// GetComponentSizingStatus returns the effective sizing of a component for the product status
+func GetComponentSizingStatus(name, image string, concurrency int32, resources corev1.ResourceRequirements) v1alpha1.ComponentSizingStatus {
+	return v1alpha1.ComponentSizingStatus{
+		Name:        name,
+		Image:       image,
+		Requests:    formatResourceList(resources.Requests),
+		Limits:      formatResourceList(resources.Limits),
+		Concurrency: concurrency,
+	}
+}
+
+func formatResourceList(resourceList corev1.ResourceList) string {
+	quantities := make([]string, 0, len(resourceList))
+	for name, quantity := range resourceList {
+		quantities = append(quantities, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(quantities)
+	return strings.Join(quantities, ",")
+}
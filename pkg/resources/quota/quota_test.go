@@ -97,30 +97,32 @@ func TestGetQuota(t *testing.T) {
 									},
 								},
 							}
-							rcs[ApicastStagingName] = ResourceConfig{0, corev1.ResourceRequirements{}}
-							rcs[BackendListenerName] = ResourceConfig{0, corev1.ResourceRequirements{}}
-							rcs[BackendWorkerName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[ApicastStagingName] = ResourceConfig{}
+							rcs[BackendListenerName] = ResourceConfig{}
+							rcs[BackendWorkerName] = ResourceConfig{}
+							rcs[EnvoySidecarName] = ResourceConfig{}
 						}),
 						quota: pointerToQuota,
 					},
 					v1alpha1.ProductGrafana: {
 						v1alpha1.ProductGrafana,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[GrafanaName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[GrafanaName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
 					v1alpha1.ProductMarin3r: {
 						v1alpha1.ProductMarin3r,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[RateLimitName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[RateLimitName] = ResourceConfig{}
+							rcs[DiscoveryServiceName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
 					v1alpha1.ProductRHSSOUser: {
 						v1alpha1.ProductRHSSOUser,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[KeycloakName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[KeycloakName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
@@ -172,30 +174,32 @@ func TestGetQuota(t *testing.T) {
 									},
 								},
 							}
-							rcs[ApicastStagingName] = ResourceConfig{0, corev1.ResourceRequirements{}}
-							rcs[ApicastProductionName] = ResourceConfig{0, corev1.ResourceRequirements{}}
-							rcs[BackendWorkerName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[ApicastStagingName] = ResourceConfig{}
+							rcs[ApicastProductionName] = ResourceConfig{}
+							rcs[BackendWorkerName] = ResourceConfig{}
+							rcs[EnvoySidecarName] = ResourceConfig{}
 						}),
 						quota: pointerToQuota,
 					},
 					v1alpha1.ProductGrafana: {
 						v1alpha1.ProductGrafana,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[GrafanaName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[GrafanaName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
 					v1alpha1.ProductMarin3r: {
 						productName: v1alpha1.ProductMarin3r,
 						resourceConfigs: map[string]ResourceConfig{
-							RateLimitName: {0, corev1.ResourceRequirements{}},
+							RateLimitName:        {},
+							DiscoveryServiceName: {},
 						},
 						quota: pointerToQuota,
 					},
 					v1alpha1.ProductRHSSOUser: {
 						productName: v1alpha1.ProductRHSSOUser,
 						resourceConfigs: map[string]ResourceConfig{
-							KeycloakName: {0, corev1.ResourceRequirements{}},
+							KeycloakName: {},
 						},
 						quota: pointerToQuota,
 					},
@@ -248,37 +252,39 @@ func TestGetQuota(t *testing.T) {
 									},
 								},
 							}
-							rcs[ApicastStagingName] = ResourceConfig{0, corev1.ResourceRequirements{}}
-							rcs[BackendListenerName] = ResourceConfig{0, corev1.ResourceRequirements{}}
-							rcs[BackendWorkerName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[ApicastStagingName] = ResourceConfig{}
+							rcs[BackendListenerName] = ResourceConfig{}
+							rcs[BackendWorkerName] = ResourceConfig{}
+							rcs[EnvoySidecarName] = ResourceConfig{}
 						}),
 						quota: pointerToQuota,
 					},
 					v1alpha1.ProductGrafana: {
 						v1alpha1.ProductGrafana,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[GrafanaName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[GrafanaName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
 					v1alpha1.ProductMarin3r: {
 						v1alpha1.ProductMarin3r,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[RateLimitName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[RateLimitName] = ResourceConfig{}
+							rcs[DiscoveryServiceName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
 					v1alpha1.ProductRHSSOUser: {
 						v1alpha1.ProductRHSSOUser,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[KeycloakName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[KeycloakName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
 					v1alpha1.ProductMCG: {
 						v1alpha1.ProductMCG,
 						getResourceConfig(func(rcs map[string]ResourceConfig) {
-							rcs[NoobaaCoreName] = ResourceConfig{0, corev1.ResourceRequirements{}}
+							rcs[NoobaaCoreName] = ResourceConfig{}
 						}),
 						pointerToQuota,
 					},
@@ -1060,6 +1066,69 @@ func getDeployment(name string, modifyFn func(d *appsv1.Deployment)) *appsv1.Dep
 	return mock
 }
 
+func TestQuotaProductConfig_GetComponentConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	quotaConfig := getQuotaConfig(func(cm *corev1.ConfigMap) {
+		cm.Data[ConfigMapData] = `[{"name": "50 Million", "param": "500", "rate-limiting": {"unit": "minute", "requests_per_unit": 34722},
+			"resources": {
+				"envoy_sidecar": {"image": "quay.io/example/envoy:v1", "concurrency": 4, "resources": {"requests": {"cpu": "500m", "memory": "200Mi"}}},
+				"discovery_service": {"resources": {"limits": {"cpu": "1", "memory": "1Gi"}}}
+			}}]`
+	})
+	quota := &Quota{}
+	err = GetQuota(context.TODO(), fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(buildTestInfra(configv1.AWSPlatformType)).Build(), "500", quotaConfig, quota)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sidecarConfig := quota.GetProduct(v1alpha1.Product3Scale).GetComponentConfig(EnvoySidecarName)
+	if sidecarConfig.Image != "quay.io/example/envoy:v1" || sidecarConfig.Concurrency != 4 || !sidecarConfig.Resources.Requests.Cpu().Equal(resource.MustParse("500m")) {
+		t.Errorf("unexpected envoy sidecar config %v", sidecarConfig)
+	}
+
+	discoveryServiceConfig := quota.GetProduct(v1alpha1.ProductMarin3r).GetComponentConfig(DiscoveryServiceName)
+	if !discoveryServiceConfig.Resources.Limits.Memory().Equal(resource.MustParse("1Gi")) {
+		t.Errorf("unexpected discovery service config %v", discoveryServiceConfig)
+	}
+
+	status := GetComponentSizingStatus(EnvoySidecarName, sidecarConfig.Image, sidecarConfig.Concurrency, sidecarConfig.Resources)
+	if status.Requests != "cpu=500m,memory=200Mi" || status.Limits != "" || status.Concurrency != 4 {
+		t.Errorf("unexpected sizing status %v", status)
+	}
+}
+
+func TestQuotaProductConfig_GetRateLimitStorageConfig(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(buildTestInfra(configv1.AWSPlatformType)).Build()
+
+	quotaConfig := getQuotaConfig(func(cm *corev1.ConfigMap) {
+		cm.Data[ConfigMapData] = `[{"name": "50 Million", "param": "500", "rate-limiting": {"unit": "minute", "requests_per_unit": 34722},
+			"rate-limiting-storage": {"mode": "redis_cached", "topology": "sentinel", "cache": {"flushing_period_ms": 200}}}]`
+	})
+	quota := &Quota{}
+	if err := GetQuota(context.TODO(), client, "500", quotaConfig, quota); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	storageConfig := quota.GetProduct(v1alpha1.ProductMarin3r).GetRateLimitStorageConfig()
+	if storageConfig.GetMode() != marin3rconfig.StorageModeRedisCached || storageConfig.GetTopology() != marin3rconfig.RedisTopologySentinel || storageConfig.Cache.FlushingPeriodMs != 200 {
+		t.Errorf("unexpected rate limit storage config %v", storageConfig)
+	}
+
+	quotaConfig = getQuotaConfig(func(cm *corev1.ConfigMap) {
+		cm.Data[ConfigMapData] = `[{"name": "50 Million", "param": "500", "rate-limiting-storage": {"mode": "disk"}}]`
+	})
+	if err := GetQuota(context.TODO(), client, "500", quotaConfig, &Quota{}); err == nil {
+		t.Errorf("expected an error for an unknown storage mode")
+	}
+}
+
 func getQuotaConfig(modifyFn func(*corev1.ConfigMap)) *corev1.ConfigMap {
 	mock := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName},
@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/3scale-ops/marin3r/pkg/envoy"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
 	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
 
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
-	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/quota"
 	corev1 "k8s.io/api/core/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	k8sTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -20,31 +23,115 @@ import (
 
 const (
 	EnvoyImage = "registry.redhat.io/openshift-service-mesh/proxyv2-rhel8:2.3.4-3"
+
+	defaultEnvoyRequestsCPU    = "190m"
+	defaultEnvoyRequestsMemory = "90Mi"
+
+	envoyImageAnnotation          = "marin3r.3scale.net/envoy-image"
+	envoyExtraArgsAnnotation      = "marin3r.3scale.net/envoy-extra-args"
+	envoyResourcesAnnotationKey   = "marin3r.3scale.net/resources.%s.%s"
+	envoyConcurrencyArgumentValue = "--concurrency %d"
 )
 
+// EnvoySidecarConfig sizes the envoy sidecar containers marin3r injects in the 3scale components
+type EnvoySidecarConfig struct {
+	Image     string
+	Resources corev1.ResourceRequirements
+	// Concurrency is the number of envoy worker threads, envoy defaults to the number of
+	// cores of the node when it is not set
+	Concurrency int32
+}
+
+// GetEnvoySidecarConfig returns the envoy sidecar configuration of the quota, defaulting to the
+// EnvoyImage image and the default requests when they are not configured
+func GetEnvoySidecarConfig(productConfig quota.ProductConfig) EnvoySidecarConfig {
+	sidecarConfig := EnvoySidecarConfig{
+		Image: EnvoyImage,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(defaultEnvoyRequestsCPU),
+				corev1.ResourceMemory: resource.MustParse(defaultEnvoyRequestsMemory),
+			},
+		},
+	}
+
+	componentConfig := productConfig.GetComponentConfig(quota.EnvoySidecarName)
+	if componentConfig.Image != "" {
+		sidecarConfig.Image = componentConfig.Image
+	}
+	for name, quantity := range componentConfig.Resources.Requests {
+		sidecarConfig.Resources.Requests[name] = quantity
+	}
+	if len(componentConfig.Resources.Limits) > 0 {
+		sidecarConfig.Resources.Limits = componentConfig.Resources.Limits.DeepCopy()
+	}
+	sidecarConfig.Concurrency = componentConfig.Concurrency
+
+	return sidecarConfig
+}
+
+// GetStatus returns the effective sizing of the envoy sidecars for the product status
+func (c EnvoySidecarConfig) GetStatus() integreatlyv1alpha1.ComponentSizingStatus {
+	return quota.GetComponentSizingStatus(quota.EnvoySidecarName, c.Image, c.Concurrency, c.Resources)
+}
+
+// setAnnotations sets the marin3r annotations configuring the image, resources and extra
+// arguments of the envoy sidecar, removing the ones that are not configured
+func (c EnvoySidecarConfig) setAnnotations(annotations map[string]string) {
+	annotations[envoyImageAnnotation] = c.Image
+
+	for requirement, resourceList := range map[string]corev1.ResourceList{
+		"requests": c.Resources.Requests,
+		"limits":   c.Resources.Limits,
+	} {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			key := fmt.Sprintf(envoyResourcesAnnotationKey, requirement, name)
+			if quantity, ok := resourceList[name]; ok {
+				annotations[key] = quantity.String()
+			} else {
+				delete(annotations, key)
+			}
+		}
+	}
+
+	if c.Concurrency > 0 {
+		annotations[envoyExtraArgsAnnotation] = fmt.Sprintf(envoyConcurrencyArgumentValue, c.Concurrency)
+	} else {
+		delete(annotations, envoyExtraArgsAnnotation)
+	}
+}
+
+// EnvoyProxyPort is a named port the envoy sidecar container listens on
+type EnvoyProxyPort struct {
+	Name string
+	Port int
+}
+
 type envoyProxyServer struct {
-	ctx    context.Context
-	client k8sclient.Client
-	log    l.Logger
+	ctx     context.Context
+	client  k8sclient.Client
+	log     l.Logger
+	sidecar EnvoySidecarConfig
 }
 
-func NewEnvoyProxyServer(ctx context.Context, client k8sclient.Client, logger l.Logger) *envoyProxyServer {
+func NewEnvoyProxyServer(ctx context.Context, client k8sclient.Client, logger l.Logger, sidecar EnvoySidecarConfig) *envoyProxyServer {
 	return &envoyProxyServer{
-		ctx:    ctx,
-		client: client,
-		log:    logger,
+		ctx:     ctx,
+		client:  client,
+		log:     logger,
+		sidecar: sidecar,
 	}
 }
 
-func (envoyProxy *envoyProxyServer) CreateEnvoyProxyContainer(dcName, namespace, envoyNodeID, svcProxyName, svcProxyPortName string, svcProxyPort int) (integreatlyv1alpha1.StatusPhase, error) {
+func (envoyProxy *envoyProxyServer) CreateEnvoyProxyContainer(workloadName, namespace, envoyNodeID, svcProxyName, svcProxyPortName string, svcProxyPort int) (integreatlyv1alpha1.StatusPhase, error) {
 
 	envoyProxy.log.Infof(
 		"Creating envoy sidecar container for: ",
-		l.Fields{"DeploymentConfig": dcName, "Namespace": namespace},
+		l.Fields{"Workload": workloadName, "Namespace": namespace},
 	)
 
-	// patches deployment config to add the sidecar container
-	phase, err := envoyProxy.patchDeploymentConfig(dcName, namespace, envoyNodeID, svcProxyPort)
+	// patches the deployment or deployment config to add the sidecar container
+	phase, err := envoyProxy.patchWorkload(workloadName, namespace, envoyNodeID, EnvoyProxyPort{Name: "envoy-https", Port: svcProxyPort})
 	if err != nil {
 		return phase, err
 	}
@@ -58,48 +145,104 @@ func (envoyProxy *envoyProxyServer) CreateEnvoyProxyContainer(dcName, namespace,
 	return phase, nil
 }
 
-func (envoyProxy *envoyProxyServer) patchDeploymentConfig(dcName, namespace, envoyNodeID string, svcProxyPort int) (integreatlyv1alpha1.StatusPhase, error) {
+// AddEnvoyProxySidecar adds the sidecar container listening on the ports to the workload, the
+// services of the workload are left unchanged for the requests to be routed to the ports separately
+func (envoyProxy *envoyProxyServer) AddEnvoyProxySidecar(workloadName, namespace, envoyNodeID string, ports ...EnvoyProxyPort) (integreatlyv1alpha1.StatusPhase, error) {
+
+	envoyProxy.log.Infof(
+		"Creating envoy sidecar container for: ",
+		l.Fields{"Workload": workloadName, "Namespace": namespace},
+	)
 
-	dc, phase, err := getDeploymentConfig(envoyProxy.ctx, envoyProxy.client, dcName, namespace)
+	return envoyProxy.patchWorkload(workloadName, namespace, envoyNodeID, ports...)
+}
+
+// RemoveEnvoyProxySidecar removes the MARIN3R labels and annotations from the workload, for
+// marin3r to remove the sidecar container
+func (envoyProxy *envoyProxyServer) RemoveEnvoyProxySidecar(workloadName, namespace string) (integreatlyv1alpha1.StatusPhase, error) {
+
+	workload, err := resources.GetWorkload(envoyProxy.ctx, envoyProxy.client, workloadName, namespace)
 	if err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to get %s deploymentconfig on namespace %s : %w", dcName, namespace, err)
+		if k8serr.IsNotFound(err) {
+			return integreatlyv1alpha1.PhaseCompleted, nil
+		}
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to get %s deployment on namespace %s : %w", workloadName, namespace, err)
 	}
-	if phase == integreatlyv1alpha1.PhaseAwaitingComponents {
-		envoyProxy.log.Infof(
-			"Waiting for deploymentconfig to be available",
-			l.Fields{"DeploymentConfig": dcName},
-		)
-		return phase, nil
+	template, err := resources.WorkloadPodTemplate(workload)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	if _, ok := template.Labels["marin3r.3scale.net/status"]; !ok {
+		return integreatlyv1alpha1.PhaseCompleted, nil
 	}
 
-	if dc.Spec.Template.Labels == nil {
-		dc.Spec.Template.SetLabels(make(map[string]string))
+	envoyProxy.log.Infof(
+		"Removing envoy sidecar container from: ",
+		l.Fields{"Workload": workloadName, "Namespace": namespace},
+	)
+
+	delete(template.Labels, "marin3r.3scale.net/status")
+	for annotation := range template.Annotations {
+		if strings.HasPrefix(annotation, "marin3r.3scale.net/") {
+			delete(template.Annotations, annotation)
+		}
 	}
-	if dc.Spec.Template.Annotations == nil {
-		dc.Spec.Template.SetAnnotations(make(map[string]string))
+
+	if err := envoyProxy.client.Update(envoyProxy.ctx, workload); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to remove MARIN3R labels from %s deployment: %v", workloadName, err)
 	}
+	return integreatlyv1alpha1.PhaseCompleted, nil
+}
 
-	envoyPort := fmt.Sprintf("envoy-https:%s", strconv.Itoa(svcProxyPort))
+func (envoyProxy *envoyProxyServer) patchWorkload(workloadName, namespace, envoyNodeID string, ports ...EnvoyProxyPort) (integreatlyv1alpha1.StatusPhase, error) {
+
+	workload, err := resources.GetWorkload(envoyProxy.ctx, envoyProxy.client, workloadName, namespace)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			envoyProxy.log.Infof(
+				"Waiting for deployment to be available",
+				l.Fields{"Workload": workloadName},
+			)
+			return integreatlyv1alpha1.PhaseAwaitingComponents, nil
+		}
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to get %s deployment on namespace %s : %w", workloadName, namespace, err)
+	}
+	template, err := resources.WorkloadPodTemplate(workload)
+	if err != nil {
+		return integreatlyv1alpha1.PhaseFailed, err
+	}
+
+	if template.Labels == nil {
+		template.SetLabels(make(map[string]string))
+	}
+	if template.Annotations == nil {
+		template.SetAnnotations(make(map[string]string))
+	}
+
+	var envoyPorts []string
+	for _, port := range ports {
+		envoyPorts = append(envoyPorts, fmt.Sprintf("%s:%s", port.Name, strconv.Itoa(port.Port)))
+	}
+	envoyPort := strings.Join(envoyPorts, ",")
 
 	envoyProxy.log.Infof(
 		"adding MARIN3R annotations and labels: ", l.Fields{
 			"marin3r.3scale.net/node-id":           envoyNodeID,
 			"marin3r.3scale.net/ports":             envoyPort,
-			"marin3r.3scale.net/envoy-image":       EnvoyImage,
+			"marin3r.3scale.net/envoy-image":       envoyProxy.sidecar.Image,
 			"marin3r.3scale.net/status":            "enabled",
 			"marin3r.3scale.net/envoy-api-version": envoy.APIv3.String(),
 		})
 
-	dc.Spec.Template.Labels["marin3r.3scale.net/status"] = "enabled"
-	dc.Spec.Template.Annotations["marin3r.3scale.net/node-id"] = envoyNodeID
-	dc.Spec.Template.Annotations["marin3r.3scale.net/ports"] = envoyPort
-	dc.Spec.Template.Annotations["marin3r.3scale.net/envoy-api-version"] = envoy.APIv3.String()
-	dc.Spec.Template.Annotations["marin3r.3scale.net/envoy-image"] = EnvoyImage
-	dc.Spec.Template.Annotations["marin3r.3scale.net/resources.requests.cpu"] = "190m"
-	dc.Spec.Template.Annotations["marin3r.3scale.net/resources.requests.memory"] = "90Mi"
+	template.Labels["marin3r.3scale.net/status"] = "enabled"
+	template.Annotations["marin3r.3scale.net/node-id"] = envoyNodeID
+	template.Annotations["marin3r.3scale.net/ports"] = envoyPort
+	template.Annotations["marin3r.3scale.net/envoy-api-version"] = envoy.APIv3.String()
+	envoyProxy.sidecar.setAnnotations(template.Annotations)
 
-	if err := envoyProxy.client.Update(envoyProxy.ctx, dc); err != nil {
-		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to apply MARIN3R labels to %s deploymentconfig: %v", dcName, err)
+	if err := envoyProxy.client.Update(envoyProxy.ctx, workload); err != nil {
+		return integreatlyv1alpha1.PhaseFailed, fmt.Errorf("failed to apply MARIN3R labels to %s deployment: %v", workloadName, err)
 	}
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
@@ -137,20 +280,6 @@ func (envoyProxy *envoyProxyServer) patchService(svcName, namespace, portName st
 	return integreatlyv1alpha1.PhaseCompleted, nil
 }
 
-func getDeploymentConfig(ctx context.Context, client k8sclient.Client, dcName string, dcNamespace string) (*appsv1.DeploymentConfig, integreatlyv1alpha1.StatusPhase, error) {
-	apiCastDeploymentConfig := &appsv1.DeploymentConfig{}
-
-	err := client.Get(ctx, k8sTypes.NamespacedName{Name: dcName, Namespace: dcNamespace}, apiCastDeploymentConfig)
-
-	if err != nil {
-		if k8serr.IsNotFound(err) {
-			return nil, integreatlyv1alpha1.PhaseAwaitingComponents, nil
-		}
-		return nil, integreatlyv1alpha1.PhaseFailed, err
-	}
-	return apiCastDeploymentConfig, integreatlyv1alpha1.PhaseInProgress, nil
-}
-
 func getService(ctx context.Context, client k8sclient.Client, svcName string, svcNamespace string) (*corev1.Service, integreatlyv1alpha1.StatusPhase, error) {
 	service := &corev1.Service{}
 	err := client.Get(ctx, k8sTypes.NamespacedName{Name: svcName, Namespace: svcNamespace}, service)
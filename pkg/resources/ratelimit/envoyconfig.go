@@ -19,11 +19,13 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	marin3rv1alpha1 "github.com/3scale-ops/marin3r/apis/marin3r/v1alpha1"
 	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
 	"github.com/integr8ly/integreatly-operator/pkg/resources/owner"
+	"github.com/integr8ly/integreatly-operator/version"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/yaml"
@@ -34,8 +36,23 @@ const (
 	RateLimitDomain          = "apicast-ratelimit"
 	RateLimitDescriptorValue = "slowpath"
 	TransportSocketName      = "envoy.transport_sockets.tls"
+
+	// RateLimitShadowDomain is the Limitador namespace of the shadow limits, which are
+	// evaluated for the API requests without rejecting them
+	RateLimitShadowDomain      = "apicast-ratelimit-shadow"
+	RateLimitShadowClusterName = "ratelimit-shadow"
+	// ShadowLimitedCallsMetric counts the requests the shadow limits would have rejected
+	ShadowLimitedCallsMetric = "ratelimit_shadow_limited_calls"
+
+	// RateLimitProviderPortalDomain and RateLimitDeveloperPortalDomain are the Limitador
+	// namespaces of the limits of the 3scale admin and developer portals
+	RateLimitProviderPortalDomain  = "system-provider-ratelimit"
+	RateLimitDeveloperPortalDomain = "system-developer-ratelimit"
 )
 
+// RateLimitPortalDomains are the Limitador namespaces of the limits of the 3scale portals
+var RateLimitPortalDomains = []string{RateLimitProviderPortalDomain, RateLimitDeveloperPortalDomain}
+
 func DeleteEnvoyConfigsInNamespaces(ctx context.Context, client k8sclient.Client, namespaces ...string) (integreatlyv1alpha1.StatusPhase, error) {
 	phase := integreatlyv1alpha1.PhaseCompleted
 
@@ -83,13 +100,23 @@ type EnvoyConfig struct {
 	name      string
 	namespace string
 	nodeID    string
+	// healthChecks gate the new revisions of the envoy resources, see reconcileRevision
+	healthChecks []EnvoyConfigHealthCheck
+	// operatorVersion is recorded in the revisions, failed revisions are retried on upgrades
+	operatorVersion string
+	now             func() time.Time
 }
 
-func NewEnvoyConfig(name, namespace, nodeID string) *EnvoyConfig {
+// NewEnvoyConfig returns the EnvoyConfig of the node ID, the new revisions of its envoy resources
+// are gated by the envoy sidecar health checks in addition to the given ones
+func NewEnvoyConfig(name, namespace, nodeID string, healthChecks ...EnvoyConfigHealthCheck) *EnvoyConfig {
 	return &EnvoyConfig{
-		name:      name,
-		namespace: namespace,
-		nodeID:    nodeID,
+		name:            name,
+		namespace:       namespace,
+		nodeID:          nodeID,
+		healthChecks:    append([]EnvoyConfigHealthCheck{CheckEnvoyConfigPublished, CheckEnvoySidecarsReady}, healthChecks...),
+		operatorVersion: version.GetVersion(),
+		now:             time.Now,
 	}
 }
 
@@ -106,13 +133,6 @@ func NewEnvoyConfig(name, namespace, nodeID string) *EnvoyConfig {
 *
 */
 func (ec *EnvoyConfig) CreateEnvoyConfig(ctx context.Context, client k8sclient.Client, clusterResources []*envoyclusterv3.Cluster, listenerResources []*envoylistenerv3.Listener, runtimes *envoy_runtime.Runtime, installation *integreatlyv1alpha1.RHMI) error {
-	envoyconfig := &marin3rv1alpha1.EnvoyConfig{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      ec.name,
-			Namespace: ec.namespace,
-		},
-	}
-
 	envoyClusterResource := []marin3rv1alpha1.EnvoyResource{}
 	for _, cluster := range clusterResources {
 		jsonClusterResource, err := ResourcesToJSON(cluster)
@@ -167,18 +187,34 @@ func (ec *EnvoyConfig) CreateEnvoyConfig(ctx context.Context, client k8sclient.C
 		Value: string(yamlRuntimeResource),
 	})
 
-	_, err = controllerutil.CreateOrUpdate(ctx, client, envoyconfig, func() error {
+	return ec.reconcileRevision(ctx, client, &marin3rv1alpha1.EnvoyResources{
+		Clusters:  envoyClusterResource,
+		Listeners: envoyListenerResource,
+		Runtimes:  envoyRuntimeResource,
+	}, installation)
+}
+
+// applyRevision sets the envoy resources of the revision in the EnvoyConfig
+func (ec *EnvoyConfig) applyRevision(ctx context.Context, client k8sclient.Client, envoyResources *marin3rv1alpha1.EnvoyResources, revision string, installation *integreatlyv1alpha1.RHMI) error {
+	envoyconfig := &marin3rv1alpha1.EnvoyConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ec.name,
+			Namespace: ec.namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, client, envoyconfig, func() error {
 		owner.AddIntegreatlyOwnerAnnotations(envoyconfig, installation)
+		if envoyconfig.Annotations == nil {
+			envoyconfig.Annotations = map[string]string{}
+		}
+		envoyconfig.Annotations[EnvoyConfigRevisionAnnotation] = revision
 		serialization := envoyserializer.YAML
 		envoyAPIVersion := envoy.APIv3
 		envoyconfig.Spec.NodeID = ec.nodeID
 		envoyconfig.Spec.EnvoyAPI = &envoyAPIVersion
 		envoyconfig.Spec.Serialization = &serialization
-		envoyconfig.Spec.EnvoyResources = &marin3rv1alpha1.EnvoyResources{
-			Clusters:  envoyClusterResource,
-			Listeners: envoyListenerResource,
-			Runtimes:  envoyRuntimeResource,
-		}
+		envoyconfig.Spec.EnvoyResources = envoyResources
 		return nil
 	})
 	if err != nil {
@@ -187,6 +223,19 @@ func (ec *EnvoyConfig) CreateEnvoyConfig(ctx context.Context, client k8sclient.C
 	return nil
 }
 
+// DeleteEnvoyConfig deletes the EnvoyConfig along with its revisions
+func (ec *EnvoyConfig) DeleteEnvoyConfig(ctx context.Context, client k8sclient.Client) error {
+	for _, obj := range []k8sclient.Object{
+		&marin3rv1alpha1.EnvoyConfig{ObjectMeta: metav1.ObjectMeta{Name: ec.name, Namespace: ec.namespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ec.name + EnvoyConfigRevisionsSuffix, Namespace: ec.namespace}},
+	} {
+		if err := k8sclient.IgnoreNotFound(client.Delete(ctx, obj)); err != nil {
+			return fmt.Errorf("failed to delete envoy config %s: %v", ec.name, err)
+		}
+	}
+	return nil
+}
+
 /*
 *
 Creates envoy config cluster resource
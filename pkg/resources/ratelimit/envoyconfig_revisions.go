@@ -0,0 +1,425 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	marin3rv1alpha1 "github.com/3scale-ops/marin3r/apis/marin3r/v1alpha1"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/owner"
+	prometheusApi "github.com/prometheus/client_golang/api"
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// EnvoyConfigRevisionAnnotation holds the revision of the envoy resources of an EnvoyConfig
+	EnvoyConfigRevisionAnnotation = "integreatly.org/envoy-config-revision"
+	// EnvoyConfigRevisionsSuffix is the suffix of the name of the ConfigMap holding the revisions
+	// of an EnvoyConfig, the history is kept under the EnvoyConfigHistoryKey key and the envoy
+	// resources of each revision under the revision key
+	EnvoyConfigRevisionsSuffix = "-revisions"
+	EnvoyConfigHistoryKey      = "history"
+
+	// KnownGoodRevisions is the number of known-good revisions kept to roll back to, and of
+	// failed revisions kept to not apply them again
+	KnownGoodRevisions = 5
+	// HealthGatePeriod is how long the envoy sidecars are watched after a new revision is
+	// applied before it becomes known-good
+	HealthGatePeriod = 5 * time.Minute
+
+	// HealthGateTimeout is how long the health checks of a pending revision may fail to be
+	// evaluated before the revision fails
+	HealthGateTimeout = 3 * HealthGatePeriod
+	// FailedRevisionRetryPeriod is how long a failed revision waits before being applied again,
+	// doubled on each failed attempt up to MaxFailedRevisionRetryPeriod
+	FailedRevisionRetryPeriod    = time.Hour
+	MaxFailedRevisionRetryPeriod = 24 * time.Hour
+
+	// RejectedRequestsAlertName fires when the volume of the requests rejected by Limitador does
+	// not match the rate limit configuration, see the rejected requests alerts of marin3r
+	RejectedRequestsAlertName = "RHOAMApiUsageRejectedRequestsMismatch"
+	// rejectedRequestsQueryTimeout bounds the query of the alert state, so the rejected requests
+	// health check does not hold up the reconcile
+	rejectedRequestsQueryTimeout = 2 * time.Second
+
+	envoySidecarContainerName = "envoy-sidecar"
+	envoyNodeIDAnnotation     = "marin3r.3scale.net/node-id"
+	envoyStatusLabel          = "marin3r.3scale.net/status"
+)
+
+type RevisionState string
+
+const (
+	RevisionPending   RevisionState = "Pending"
+	RevisionKnownGood RevisionState = "KnownGood"
+	RevisionFailed    RevisionState = "Failed"
+)
+
+// EnvoyConfigRevision is an entry of the revision history of an EnvoyConfig
+type EnvoyConfigRevision struct {
+	Revision  string        `json:"revision"`
+	State     RevisionState `json:"state"`
+	AppliedAt metav1.Time   `json:"appliedAt"`
+	// Reason is why the revision failed, or why the health checks could not be evaluated
+	Reason string `json:"reason,omitempty"`
+	// OperatorVersion is the version of the operator which applied the revision
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// Attempts is the number of times the revision failed, FailedAt the last time
+	Attempts int          `json:"attempts,omitempty"`
+	FailedAt *metav1.Time `json:"failedAt,omitempty"`
+}
+
+// EnvoyConfigHealthCheck returns why the envoy sidecars of the EnvoyConfig are unhealthy since
+// its revision was applied at appliedAt, or an empty reason when they are healthy. The checks
+// of conditions which take time to converge, such as the readiness, only fail once settled
+type EnvoyConfigHealthCheck func(ctx context.Context, client k8sclient.Client, envoyConfig *marin3rv1alpha1.EnvoyConfig, appliedAt time.Time, settled bool) (string, error)
+
+// reconcileRevision applies the envoy resources as a new revision of the EnvoyConfig. A new
+// revision is pending until the health checks pass for the HealthGatePeriod, and rolled back
+// to the latest known-good revision as soon as one of them fails, or when they cannot be
+// evaluated for the HealthGateTimeout. Failed revisions are applied again once their retry
+// backoff elapsed or the operator version changed, the EnvoyConfig keeps the current revision
+// until then
+func (ec *EnvoyConfig) reconcileRevision(ctx context.Context, client k8sclient.Client, envoyResources *marin3rv1alpha1.EnvoyResources, installation *integreatlyv1alpha1.RHMI) error {
+	revision, err := getEnvoyResourcesRevision(envoyResources)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := ec.getRevisions(ctx, client)
+	if err != nil {
+		return err
+	}
+	history, err := getRevisionHistory(revisions)
+	if err != nil {
+		return err
+	}
+
+	envoyConfig := &marin3rv1alpha1.EnvoyConfig{}
+	if err := client.Get(ctx, k8sclient.ObjectKey{Name: ec.name, Namespace: ec.namespace}, envoyConfig); err != nil && !k8serr.IsNotFound(err) {
+		return fmt.Errorf("failed to get envoy config CR %v", err)
+	}
+	currentRevision := envoyConfig.Annotations[EnvoyConfigRevisionAnnotation]
+	now := ec.now()
+
+	retry := false
+	attempts := 0
+	if entry := findRevision(history, revision); entry != nil && entry.State == RevisionFailed {
+		if !ec.isRetryDue(*entry, now) {
+			return nil
+		}
+		retry = true
+		if entry.OperatorVersion == ec.operatorVersion {
+			attempts = entry.Attempts
+		}
+	}
+
+	if currentRevision != revision || retry {
+		// Pending revisions replaced before being gated are dropped
+		history = removeRevision(history, currentRevision, RevisionPending)
+		history = append(removeRevision(history, revision, ""), EnvoyConfigRevision{
+			Revision:        revision,
+			State:           RevisionPending,
+			AppliedAt:       metav1.NewTime(now),
+			OperatorVersion: ec.operatorVersion,
+			Attempts:        attempts,
+		})
+		if err := ec.saveRevisions(ctx, client, revisions, history, revision, envoyResources, installation); err != nil {
+			return err
+		}
+		return ec.applyRevision(ctx, client, envoyResources, revision, installation)
+	}
+
+	entry := findRevision(history, revision)
+	if entry == nil {
+		// The revision was applied before the revisions were recorded
+		history = append(history, EnvoyConfigRevision{
+			Revision:        revision,
+			State:           RevisionKnownGood,
+			AppliedAt:       metav1.NewTime(now),
+			OperatorVersion: ec.operatorVersion,
+		})
+	} else if entry.State == RevisionPending {
+		settled := now.Sub(entry.AppliedAt.Time) >= HealthGatePeriod
+		reason, err := ec.checkHealth(ctx, client, envoyConfig, entry.AppliedAt.Time, settled)
+		if err != nil {
+			reason = fmt.Sprintf("health checks could not be evaluated: %v", err)
+		}
+		switch {
+		case err != nil && now.Sub(entry.AppliedAt.Time) < HealthGateTimeout:
+			entry.Reason = reason
+		case reason != "":
+			failedAt := metav1.NewTime(now)
+			entry.State = RevisionFailed
+			entry.Reason = reason
+			entry.Attempts++
+			entry.FailedAt = &failedAt
+			return ec.rollback(ctx, client, revisions, history, installation)
+		case settled:
+			entry.State = RevisionKnownGood
+			entry.Reason = ""
+		}
+	}
+
+	if err := ec.saveRevisions(ctx, client, revisions, history, revision, envoyResources, installation); err != nil {
+		return err
+	}
+	return ec.applyRevision(ctx, client, envoyResources, revision, installation)
+}
+
+// rollback applies the latest known-good revision of the history
+func (ec *EnvoyConfig) rollback(ctx context.Context, client k8sclient.Client, revisions *corev1.ConfigMap, history []EnvoyConfigRevision, installation *integreatlyv1alpha1.RHMI) error {
+	var knownGood *EnvoyConfigRevision
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].State == RevisionKnownGood {
+			knownGood = &history[i]
+			break
+		}
+	}
+	if knownGood == nil {
+		// There is no revision to roll back to, the failed revision is kept
+		return ec.saveRevisions(ctx, client, revisions, history, "", nil, installation)
+	}
+
+	envoyResources := &marin3rv1alpha1.EnvoyResources{}
+	if err := json.Unmarshal([]byte(revisions.Data[knownGood.Revision]), envoyResources); err != nil {
+		return fmt.Errorf("failed to unmarshal envoy config revision %s: %w", knownGood.Revision, err)
+	}
+	knownGood.AppliedAt = metav1.NewTime(ec.now())
+
+	if err := ec.saveRevisions(ctx, client, revisions, history, "", nil, installation); err != nil {
+		return err
+	}
+	return ec.applyRevision(ctx, client, envoyResources, knownGood.Revision, installation)
+}
+
+// isRetryDue returns whether the failed revision can be applied again, which is once the retry
+// backoff of its failed attempts elapsed or the operator version changed
+func (ec *EnvoyConfig) isRetryDue(entry EnvoyConfigRevision, now time.Time) bool {
+	if entry.OperatorVersion != ec.operatorVersion {
+		return true
+	}
+	if entry.FailedAt == nil {
+		return false
+	}
+	backoff := FailedRevisionRetryPeriod
+	for i := 1; i < entry.Attempts && backoff < MaxFailedRevisionRetryPeriod; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxFailedRevisionRetryPeriod {
+		backoff = MaxFailedRevisionRetryPeriod
+	}
+	return now.Sub(entry.FailedAt.Time) >= backoff
+}
+
+func (ec *EnvoyConfig) checkHealth(ctx context.Context, client k8sclient.Client, envoyConfig *marin3rv1alpha1.EnvoyConfig, appliedAt time.Time, settled bool) (string, error) {
+	for _, healthCheck := range ec.healthChecks {
+		reason, err := healthCheck(ctx, client, envoyConfig, appliedAt, settled)
+		if err != nil || reason != "" {
+			return reason, err
+		}
+	}
+	return "", nil
+}
+
+func (ec *EnvoyConfig) getRevisions(ctx context.Context, client k8sclient.Client) (*corev1.ConfigMap, error) {
+	revisions := &corev1.ConfigMap{}
+	err := client.Get(ctx, k8sclient.ObjectKey{Name: ec.name + EnvoyConfigRevisionsSuffix, Namespace: ec.namespace}, revisions)
+	if err != nil {
+		if k8serr.IsNotFound(err) {
+			return &corev1.ConfigMap{}, nil
+		}
+		return nil, fmt.Errorf("failed to get envoy config revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// saveRevisions stores the history along with the envoy resources of the revision, and prunes
+// the revisions beyond the KnownGoodRevisions latest known-good and failed ones
+func (ec *EnvoyConfig) saveRevisions(ctx context.Context, client k8sclient.Client, revisions *corev1.ConfigMap, history []EnvoyConfigRevision, revision string, envoyResources *marin3rv1alpha1.EnvoyResources, installation *integreatlyv1alpha1.RHMI) error {
+	history = pruneRevisionHistory(history)
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal envoy config revision history: %w", err)
+	}
+
+	data := map[string]string{EnvoyConfigHistoryKey: string(historyJSON)}
+	for _, entry := range history {
+		if entry.State != RevisionFailed && revisions.Data[entry.Revision] != "" {
+			data[entry.Revision] = revisions.Data[entry.Revision]
+		}
+	}
+	if envoyResources != nil {
+		resourcesJSON, err := json.Marshal(envoyResources)
+		if err != nil {
+			return fmt.Errorf("failed to marshal envoy config revision %s: %w", revision, err)
+		}
+		data[revision] = string(resourcesJSON)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ec.name + EnvoyConfigRevisionsSuffix,
+			Namespace: ec.namespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, client, configMap, func() error {
+		owner.AddIntegreatlyOwnerAnnotations(configMap, installation)
+		configMap.Data = data
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save envoy config revisions: %w", err)
+	}
+	revisions.Data = data
+	return nil
+}
+
+func getRevisionHistory(revisions *corev1.ConfigMap) ([]EnvoyConfigRevision, error) {
+	var history []EnvoyConfigRevision
+	if revisions.Data[EnvoyConfigHistoryKey] == "" {
+		return history, nil
+	}
+	if err := json.Unmarshal([]byte(revisions.Data[EnvoyConfigHistoryKey]), &history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal envoy config revision history: %w", err)
+	}
+	return history, nil
+}
+
+// getEnvoyResourcesRevision hashes the envoy resources, which are serialized to JSON
+// deterministically
+func getEnvoyResourcesRevision(envoyResources *marin3rv1alpha1.EnvoyResources) (string, error) {
+	resourcesJSON, err := json.Marshal(envoyResources)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal envoy resources: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(resourcesJSON))[:16], nil
+}
+
+func findRevision(history []EnvoyConfigRevision, revision string) *EnvoyConfigRevision {
+	for i := range history {
+		if history[i].Revision == revision {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// removeRevision removes the revision from the history, only when it is in the given state if set
+func removeRevision(history []EnvoyConfigRevision, revision string, state RevisionState) []EnvoyConfigRevision {
+	var kept []EnvoyConfigRevision
+	for _, entry := range history {
+		if entry.Revision == revision && (state == "" || entry.State == state) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+func pruneRevisionHistory(history []EnvoyConfigRevision) []EnvoyConfigRevision {
+	counts := map[RevisionState]int{}
+	var pruned []EnvoyConfigRevision
+	for i := len(history) - 1; i >= 0; i-- {
+		counts[history[i].State]++
+		if history[i].State != RevisionPending && counts[history[i].State] > KnownGoodRevisions {
+			continue
+		}
+		pruned = append([]EnvoyConfigRevision{history[i]}, pruned...)
+	}
+	return pruned
+}
+
+// CheckEnvoyConfigPublished fails when marin3r could not roll back a revision rejected by envoy,
+// or has not published the revision once settled, e.g. because envoy rejected it
+func CheckEnvoyConfigPublished(_ context.Context, _ k8sclient.Client, envoyConfig *marin3rv1alpha1.EnvoyConfig, _ time.Time, settled bool) (string, error) {
+	if (envoyConfig.Status.CacheState != nil && *envoyConfig.Status.CacheState == marin3rv1alpha1.RollbackFailedState) ||
+		meta.IsStatusConditionTrue(envoyConfig.Status.Conditions, marin3rv1alpha1.RollbackFailedCondition) {
+		return "envoy rejected the revision and marin3r failed to roll it back", nil
+	}
+	status := envoyConfig.Status
+	if settled && status.DesiredVersion != nil && status.PublishedVersion != nil && *status.DesiredVersion != *status.PublishedVersion {
+		return fmt.Sprintf("marin3r published version %s instead of %s", *status.PublishedVersion, *status.DesiredVersion), nil
+	}
+	return "", nil
+}
+
+// CheckEnvoySidecarsReady fails when an envoy sidecar of the node ID restarted since the revision
+// was applied, or is not ready once settled
+func CheckEnvoySidecarsReady(ctx context.Context, client k8sclient.Client, envoyConfig *marin3rv1alpha1.EnvoyConfig, appliedAt time.Time, settled bool) (string, error) {
+	pods := &corev1.PodList{}
+	if err := client.List(ctx, pods, k8sclient.InNamespace(envoyConfig.Namespace), k8sclient.MatchingLabels{envoyStatusLabel: "enabled"}); err != nil {
+		return "", fmt.Errorf("failed to list envoy sidecar pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Annotations[envoyNodeIDAnnotation] != envoyConfig.Spec.NodeID || pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.Name != envoySidecarContainerName {
+				continue
+			}
+			if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil && terminated.FinishedAt.Time.After(appliedAt) {
+				return fmt.Sprintf("envoy sidecar of pod %s restarted: %s", pod.Name, terminated.Reason), nil
+			}
+			if settled && !containerStatus.Ready {
+				return fmt.Sprintf("envoy sidecar of pod %s is not ready", pod.Name), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// NewRejectedRequestsHealthCheck returns a health check failing once settled when the
+// RejectedRequestsAlertName alert started firing since the revision was applied, as the new
+// limits or descriptors are likely wrong. The alert state is read with a single query of the
+// Prometheus at prometheusURL. The check is advisory: it passes when the alert state cannot be
+// read, so an unavailable Prometheus never holds up or fails a revision
+func NewRejectedRequestsHealthCheck(prometheusURL string) (EnvoyConfigHealthCheck, error) {
+	client, err := prometheusApi.NewClient(prometheusApi.Config{Address: prometheusURL})
+	if err != nil {
+		return nil, err
+	}
+	api := prometheusv1.NewAPI(client)
+
+	return func(ctx context.Context, _ k8sclient.Client, _ *marin3rv1alpha1.EnvoyConfig, appliedAt time.Time, settled bool) (string, error) {
+		if !settled {
+			return "", nil
+		}
+		firing, err := isAlertFiringSince(ctx, api, RejectedRequestsAlertName, appliedAt)
+		if err != nil || !firing {
+			return "", nil
+		}
+		return fmt.Sprintf("the %s alert started firing after the revision was applied", RejectedRequestsAlertName), nil
+	}, nil
+}
+
+// isAlertFiringSince returns whether the alert is firing and was not firing at the given time
+func isAlertFiringSince(ctx context.Context, api prometheusv1.API, alertName string, since time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, rejectedRequestsQueryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	firing := fmt.Sprintf(`ALERTS{alertname="%s",alertstate="firing"}`, alertName)
+	query := fmt.Sprintf("%[1]s unless %[1]s offset %[2]ds", firing, int64(now.Sub(since).Seconds()))
+	result, _, err := api.Query(ctx, query, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to query the %s alert state: %w", alertName, err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return false, fmt.Errorf("unexpected result type %s for the %s alert state", result.Type(), alertName)
+	}
+	return len(vector) > 0, nil
+}
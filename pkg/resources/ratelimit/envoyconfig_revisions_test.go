@@ -0,0 +1,275 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	marin3rv1alpha1 "github.com/3scale-ops/marin3r/apis/marin3r/v1alpha1"
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestEnvoyConfig_reconcileRevision(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := utils.NewTestClient(scheme)
+	installation := &integreatlyv1alpha1.RHMI{ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-test-operator"}}
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	unhealthy := ""
+	ec := NewEnvoyConfig("apicast-ratelimit", "redhat-test-3scale", "apicast", func(context.Context, k8sclient.Client, *marin3rv1alpha1.EnvoyConfig, time.Time, bool) (string, error) {
+		return unhealthy, nil
+	})
+	ec.now = func() time.Time { return now }
+
+	first := &marin3rv1alpha1.EnvoyResources{Clusters: []marin3rv1alpha1.EnvoyResource{{Value: "name: first"}}}
+	second := &marin3rv1alpha1.EnvoyResources{Clusters: []marin3rv1alpha1.EnvoyResource{{Value: "name: second"}}}
+	firstRevision, _ := getEnvoyResourcesRevision(first)
+	secondRevision, _ := getEnvoyResourcesRevision(second)
+
+	// A new revision is pending until the health gate period elapsed
+	if err := ec.reconcileRevision(context.TODO(), client, first, installation); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRevision(t, client, firstRevision, map[string]RevisionState{firstRevision: RevisionPending})
+
+	now = now.Add(HealthGatePeriod)
+	if err := ec.reconcileRevision(context.TODO(), client, first, installation); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRevision(t, client, firstRevision, map[string]RevisionState{firstRevision: RevisionKnownGood})
+
+	// A failing revision is rolled back to the known-good one and not applied again
+	if err := ec.reconcileRevision(context.TODO(), client, second, installation); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertRevision(t, client, secondRevision, map[string]RevisionState{firstRevision: RevisionKnownGood, secondRevision: RevisionPending})
+
+	unhealthy = "envoy sidecar restarted"
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := ec.reconcileRevision(context.TODO(), client, second, installation); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertRevision(t, client, firstRevision, map[string]RevisionState{firstRevision: RevisionKnownGood, secondRevision: RevisionFailed})
+	}
+
+	envoyConfig := &marin3rv1alpha1.EnvoyConfig{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: "apicast-ratelimit", Namespace: "redhat-test-3scale"}, envoyConfig); err != nil {
+		t.Fatalf("failed to get envoy config: %v", err)
+	}
+	if envoyConfig.Spec.EnvoyResources.Clusters[0].Value != "name: first" {
+		t.Errorf("expected the known-good envoy resources to be applied, got %v", envoyConfig.Spec.EnvoyResources)
+	}
+}
+
+func TestEnvoyConfig_reconcileRevisionRetry(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := utils.NewTestClient(scheme)
+	installation := &integreatlyv1alpha1.RHMI{ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-test-operator"}}
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var healthErr error
+	unhealthy := ""
+	ec := NewEnvoyConfig("apicast-ratelimit", "redhat-test-3scale", "apicast", func(context.Context, k8sclient.Client, *marin3rv1alpha1.EnvoyConfig, time.Time, bool) (string, error) {
+		return unhealthy, healthErr
+	})
+	ec.now = func() time.Time { return now }
+	ec.operatorVersion = "1.0.0"
+
+	resources := &marin3rv1alpha1.EnvoyResources{Clusters: []marin3rv1alpha1.EnvoyResource{{Value: "name: first"}}}
+	revision, _ := getEnvoyResourcesRevision(resources)
+	reconcile := func() {
+		t.Helper()
+		if err := ec.reconcileRevision(context.TODO(), client, resources, installation); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// A revision whose health checks cannot be evaluated stays pending until the timeout
+	healthErr = fmt.Errorf("failed to list envoy sidecar pods")
+	reconcile()
+	now = now.Add(HealthGatePeriod)
+	reconcile()
+	assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionPending})
+
+	now = now.Add(HealthGateTimeout - HealthGatePeriod)
+	reconcile()
+	assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionFailed})
+
+	// A failed revision is retried once the backoff elapsed, which doubles on each attempt
+	healthErr = nil
+	unhealthy = "envoy sidecar restarted"
+	for _, backoff := range []time.Duration{FailedRevisionRetryPeriod, 2 * FailedRevisionRetryPeriod} {
+		now = now.Add(backoff - time.Minute)
+		reconcile()
+		assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionFailed})
+
+		now = now.Add(time.Minute)
+		reconcile()
+		assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionPending})
+
+		now = now.Add(time.Minute)
+		reconcile()
+		assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionFailed})
+	}
+
+	// A failed revision is retried straight away by a new operator version
+	unhealthy = ""
+	ec.operatorVersion = "1.1.0"
+	reconcile()
+	assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionPending})
+
+	now = now.Add(HealthGatePeriod)
+	reconcile()
+	assertRevision(t, client, revision, map[string]RevisionState{revision: RevisionKnownGood})
+}
+
+func TestCheckEnvoySidecarsReady(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	appliedAt := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	envoyConfig := &marin3rv1alpha1.EnvoyConfig{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "redhat-test-3scale"},
+		Spec:       marin3rv1alpha1.EnvoyConfigSpec{NodeID: "apicast"},
+	}
+	pod := func(ready bool, terminatedAt time.Time) *corev1.Pod {
+		status := corev1.ContainerStatus{Name: envoySidecarContainerName, Ready: ready}
+		if !terminatedAt.IsZero() {
+			status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "Error", FinishedAt: metav1.NewTime(terminatedAt)}
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "apicast-production-1",
+				Namespace:   "redhat-test-3scale",
+				Labels:      map[string]string{envoyStatusLabel: "enabled"},
+				Annotations: map[string]string{envoyNodeIDAnnotation: "apicast"},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{status}},
+		}
+	}
+
+	tests := []struct {
+		Name      string
+		Pod       *corev1.Pod
+		Settled   bool
+		Unhealthy bool
+	}{
+		{Name: "test ready sidecar is healthy", Pod: pod(true, time.Time{}), Settled: true},
+		{Name: "test sidecar not ready yet is healthy until settled", Pod: pod(false, time.Time{})},
+		{Name: "test sidecar not ready once settled is unhealthy", Pod: pod(false, time.Time{}), Settled: true, Unhealthy: true},
+		{Name: "test sidecar restarted before the revision is healthy", Pod: pod(true, appliedAt.Add(-time.Minute)), Settled: true},
+		{Name: "test sidecar restarted after the revision is unhealthy", Pod: pod(true, appliedAt.Add(time.Minute)), Unhealthy: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			reason, err := CheckEnvoySidecarsReady(context.TODO(), utils.NewTestClient(scheme, tt.Pod), envoyConfig, appliedAt, tt.Settled)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (reason != "") != tt.Unhealthy {
+				t.Errorf("expected unhealthy %v, got reason %q", tt.Unhealthy, reason)
+			}
+		})
+	}
+}
+
+func TestNewRejectedRequestsHealthCheck(t *testing.T) {
+	appliedAt := time.Now().Add(-HealthGatePeriod)
+	firing := `{"metric":{"__name__":"ALERTS","alertname":"RHOAMApiUsageRejectedRequestsMismatch","alertstate":"firing"},"value":[1686000000,"1"]}`
+
+	tests := []struct {
+		Name      string
+		Status    int
+		Result    string
+		Settled   bool
+		Queried   bool
+		Unhealthy bool
+	}{
+		{Name: "test alert not evaluated until settled", Status: http.StatusOK, Result: firing},
+		{Name: "test alert firing since the revision is unhealthy", Status: http.StatusOK, Result: firing, Settled: true, Queried: true, Unhealthy: true},
+		{Name: "test alert not firing since the revision is healthy", Status: http.StatusOK, Settled: true, Queried: true},
+		{Name: "test unavailable prometheus does not hold up the revision", Status: http.StatusServiceUnavailable, Settled: true, Queried: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			queried := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queried = true
+				if err := r.ParseForm(); err != nil || !strings.Contains(r.Form.Get("query"), RejectedRequestsAlertName) {
+					t.Errorf("unexpected query %v", r.Form)
+				}
+				if tt.Status != http.StatusOK {
+					w.WriteHeader(tt.Status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[` + tt.Result + `]}}`))
+			}))
+			defer server.Close()
+
+			healthCheck, err := NewRejectedRequestsHealthCheck(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reason, err := healthCheck(context.TODO(), nil, &marin3rv1alpha1.EnvoyConfig{}, appliedAt, tt.Settled)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if queried != tt.Queried {
+				t.Errorf("expected queried %v, got %v", tt.Queried, queried)
+			}
+			if (reason != "") != tt.Unhealthy {
+				t.Errorf("expected unhealthy %v, got reason %q", tt.Unhealthy, reason)
+			}
+		})
+	}
+}
+
+func assertRevision(t *testing.T, client k8sclient.Client, revision string, want map[string]RevisionState) {
+	t.Helper()
+
+	envoyConfig := &marin3rv1alpha1.EnvoyConfig{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: "apicast-ratelimit", Namespace: "redhat-test-3scale"}, envoyConfig); err != nil {
+		t.Fatalf("failed to get envoy config: %v", err)
+	}
+	if got := envoyConfig.Annotations[EnvoyConfigRevisionAnnotation]; got != revision {
+		t.Errorf("expected revision %s to be applied, got %s", revision, got)
+	}
+
+	revisions := &corev1.ConfigMap{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKey{Name: "apicast-ratelimit" + EnvoyConfigRevisionsSuffix, Namespace: "redhat-test-3scale"}, revisions); err != nil {
+		t.Fatalf("failed to get envoy config revisions: %v", err)
+	}
+	history, err := getRevisionHistory(revisions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]RevisionState{}
+	for _, entry := range history {
+		got[entry.Revision] = entry.State
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected revisions %v, got %v", want, got)
+	}
+	for revision, state := range want {
+		if got[revision] != state {
+			t.Errorf("expected revision %s to be %s, got %s", revision, state, got[revision])
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package slo defines the service level objectives of the core RHOAM components and
+// generates the recording rules, multi-window multi-burn-rate alerts and error budget
+// gauges for them, see https://sre.google/workbook/alerting-on-slos/
+package slo
+
+import (
+	"fmt"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+)
+
+const (
+	SLITypeAvailability = "availability"
+	SLITypeLatency      = "latency"
+
+	// Period is the window the error budget of every objective is calculated over,
+	// matching the 7 days of the critical SLO dashboards
+	Period = "7d"
+
+	// Route name prefixes of the routes created by zync for the 3scale gateway and admin portal
+	gatewayRoutes     = "^zync-3scale-api-.*"
+	adminPortalRoutes = "^zync-3scale-provider-.*"
+	ssoRoutes         = "^keycloak.*"
+)
+
+// Objective is a service level objective of a core component
+type Objective struct {
+	// Name identifies the objective in the slo label of the generated rules
+	Name string
+	// AlertName is the name of the generated burn rate alerts
+	AlertName   string
+	Component   string
+	SLIType     string
+	Description string
+	// Target is the ratio of good events the component should serve over the Period
+	Target float64
+	// Indicator is an optional PromQL expression recorded in the indicatorMetric series on
+	// every evaluation, for objectives whose events are counted from the recorded samples
+	Indicator string
+	// Errors and Total return PromQL expressions of the number of bad events and of all
+	// events over the window
+	Errors func(window string) string
+	Total  func(window string) string
+}
+
+// ErrorRatio returns a PromQL expression of the ratio of bad events over the window
+func (o Objective) ErrorRatio(window string) string {
+	return fmt.Sprintf("(%s) / (%s)", o.Errors(window), o.Total(window))
+}
+
+// GetObjectives returns the objectives of the core components of the installation
+func GetObjectives(installation *integreatlyv1alpha1.RHMI) []Objective {
+	nsPrefix := installation.Spec.NamespacePrefix
+	threescaleNamespace := nsPrefix + "3scale"
+	rhssoNamespace := nsPrefix + "rhsso"
+
+	return []Objective{
+		{
+			Name:        "threescale-gateway-availability",
+			AlertName:   "ThreeScaleGatewayAvailabilityErrorBudgetBurn",
+			Component:   "3scale gateway",
+			SLIType:     SLITypeAvailability,
+			Description: "99.5% of API gateway requests are served without a 5xx error",
+			Target:      0.995,
+			Errors:      routeErrors(gatewayRoutes, threescaleNamespace),
+			Total:       routeRequests(gatewayRoutes, threescaleNamespace),
+		},
+		{
+			Name:        "threescale-gateway-latency",
+			AlertName:   "ThreeScaleGatewayLatencyErrorBudgetBurn",
+			Component:   "3scale gateway",
+			SLIType:     SLITypeLatency,
+			Description: "the average API gateway response time is below 1s in 99% of minutes",
+			Target:      0.99,
+			Indicator:   routeLatencyOverThreshold(gatewayRoutes, threescaleNamespace, 1000),
+			Errors:      indicatorSum("threescale-gateway-latency"),
+			Total:       indicatorCount("threescale-gateway-latency"),
+		},
+		{
+			Name:        "threescale-admin-portal-availability",
+			AlertName:   "ThreeScaleAdminPortalAvailabilityErrorBudgetBurn",
+			Component:   "3scale admin portal",
+			SLIType:     SLITypeAvailability,
+			Description: "99% of admin portal requests are served without a 5xx error",
+			Target:      0.99,
+			Errors:      routeErrors(adminPortalRoutes, threescaleNamespace),
+			Total:       routeRequests(adminPortalRoutes, threescaleNamespace),
+		},
+		{
+			Name:        "threescale-admin-portal-latency",
+			AlertName:   "ThreeScaleAdminPortalLatencyErrorBudgetBurn",
+			Component:   "3scale admin portal",
+			SLIType:     SLITypeLatency,
+			Description: "the average admin portal response time is below 2s in 95% of minutes",
+			Target:      0.95,
+			Indicator:   routeLatencyOverThreshold(adminPortalRoutes, threescaleNamespace, 2000),
+			Errors:      indicatorSum("threescale-admin-portal-latency"),
+			Total:       indicatorCount("threescale-admin-portal-latency"),
+		},
+		{
+			Name:        "rhsso-availability",
+			AlertName:   "RhssoAvailabilityErrorBudgetBurn",
+			Component:   "SSO",
+			SLIType:     SLITypeAvailability,
+			Description: "99% of SSO requests are served without a 5xx error",
+			Target:      0.99,
+			Errors:      routeErrors(ssoRoutes, rhssoNamespace),
+			Total:       routeRequests(ssoRoutes, rhssoNamespace),
+		},
+		{
+			Name:        "rhsso-latency",
+			AlertName:   "RhssoLatencyErrorBudgetBurn",
+			Component:   "SSO",
+			SLIType:     SLITypeLatency,
+			Description: "the average SSO response time is below 1s in 95% of minutes",
+			Target:      0.95,
+			Indicator:   routeLatencyOverThreshold(ssoRoutes, rhssoNamespace, 1000),
+			Errors:      indicatorSum("rhsso-latency"),
+			Total:       indicatorCount("rhsso-latency"),
+		},
+	}
+}
+
+// routeErrors is the number of 5xx responses of the routes reported by the OpenShift router
+func routeErrors(routes, namespace string) func(string) string {
+	return func(window string) string {
+		// The 5xx series are only exported once a route returned a 5xx response
+		return fmt.Sprintf(`sum(increase(haproxy_backend_http_responses_total{route=~"%s", exported_namespace="%s", code="5xx"}[%s])) or vector(0)`,
+			routes, namespace, window)
+	}
+}
+
+// routeRequests is the number of responses of the routes reported by the OpenShift router
+func routeRequests(routes, namespace string) func(string) string {
+	return func(window string) string {
+		return fmt.Sprintf(`sum(increase(haproxy_backend_http_responses_total{route=~"%s", exported_namespace="%s"}[%s]))`,
+			routes, namespace, window)
+	}
+}
+
+// routeLatencyOverThreshold is 1 when the average response time of the slowest route exceeds
+// the threshold and 0 otherwise, the router does not expose latency histograms
+func routeLatencyOverThreshold(routes, namespace string, thresholdMilliseconds int) string {
+	return fmt.Sprintf(`max(haproxy_backend_http_average_response_latency_milliseconds{route=~"%s", exported_namespace="%s"}) > bool %d`,
+		routes, namespace, thresholdMilliseconds)
+}
+
+// indicatorSum is the number of evaluations the recorded indicator of the objective was bad in
+func indicatorSum(name string) func(string) string {
+	return func(window string) string {
+		return fmt.Sprintf(`sum_over_time(%s{%s="%s"}[%s])`, indicatorMetric, SLOLabel, name, window)
+	}
+}
+
+// indicatorCount is the number of evaluations the indicator of the objective was recorded in
+func indicatorCount(name string) func(string) string {
+	return func(window string) string {
+		return fmt.Sprintf(`count_over_time(%s{%s="%s"}[%s])`, indicatorMetric, SLOLabel, name, window)
+	}
+}
@@ -0,0 +1,143 @@
+package slo
+
+import (
+	"fmt"
+	"strings"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// SLOLabel identifies the objective of the generated series and alerts
+	SLOLabel = "slo"
+
+	indicatorMetric            = "slo:sli:bool"
+	errorRatioMetric           = "slo:sli_error:ratio_rate%s"
+	objectiveMetric            = "slo:objective:ratio"
+	ErrorBudgetRemainingMetric = "slo:error_budget_remaining:ratio"
+)
+
+// burnRateAlert fires when the error budget is consumed at factor times the sustainable
+// rate over both the short and the long window of any of its window pairs
+type burnRateAlert struct {
+	severity string
+	forDur   string
+	windows  []burnRateWindow
+}
+
+type burnRateWindow struct {
+	short  string
+	long   string
+	factor float64
+}
+
+var burnRateAlerts = []burnRateAlert{
+	{
+		severity: "critical",
+		forDur:   "2m",
+		windows:  []burnRateWindow{{short: "5m", long: "1h", factor: 14.4}, {short: "30m", long: "6h", factor: 6}},
+	},
+	{
+		severity: "warning",
+		forDur:   "15m",
+		windows:  []burnRateWindow{{short: "2h", long: "1d", factor: 3}, {short: "6h", long: "3d", factor: 1}},
+	},
+}
+
+// recordedWindows are the windows the error ratio of every objective is recorded for
+var recordedWindows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+
+// NewAlertReconciler returns the reconciler of the SLO recording rules and burn rate alerts
+func NewAlertReconciler(logger l.Logger, installation *integreatlyv1alpha1.RHMI) resources.AlertReconciler {
+	installationName := resources.InstallationNames[installation.Spec.Type]
+	namespace := config.GetOboNamespace(installation.Namespace)
+
+	var alerts []resources.AlertConfiguration
+	for _, objective := range GetObjectives(installation) {
+		alerts = append(alerts, resources.AlertConfiguration{
+			AlertName: fmt.Sprintf("slo-%s", objective.Name),
+			GroupName: fmt.Sprintf("slo-%s.rules", objective.Name),
+			Namespace: namespace,
+			Rules:     getRules(objective, installationName),
+		})
+	}
+
+	return &resources.AlertReconcilerImpl{
+		ProductName:  "SLO",
+		Installation: installation,
+		Log:          logger,
+		Alerts:       alerts,
+	}
+}
+
+// getRules returns the recording rules of an objective followed by its burn rate alerts,
+// rules in a group are evaluated in order so the alerts use the latest recorded ratios
+func getRules(objective Objective, installationName string) []monv1.Rule {
+	sloLabels := map[string]string{SLOLabel: objective.Name}
+	selector := fmt.Sprintf(`{%s="%s"}`, SLOLabel, objective.Name)
+
+	var rules []monv1.Rule
+	if objective.Indicator != "" {
+		rules = append(rules, monv1.Rule{
+			Record: indicatorMetric,
+			Expr:   intstr.FromString(objective.Indicator),
+			Labels: sloLabels,
+		})
+	}
+	for _, window := range recordedWindows {
+		rules = append(rules, monv1.Rule{
+			Record: fmt.Sprintf(errorRatioMetric, window),
+			// Drop the NaN ratio of windows without any requests
+			Expr:   intstr.FromString(fmt.Sprintf("(%s) >= 0", objective.ErrorRatio(window))),
+			Labels: sloLabels,
+		})
+	}
+	rules = append(rules,
+		// The budget is the ratio of the events over the whole period, so it is not skewed by
+		// the ratios of windows with little traffic
+		monv1.Rule{
+			Record: fmt.Sprintf(errorRatioMetric, Period),
+			Expr:   intstr.FromString(fmt.Sprintf("(%s) >= 0", objective.ErrorRatio(Period))),
+			Labels: sloLabels,
+		},
+		monv1.Rule{
+			Record: objectiveMetric,
+			Expr:   intstr.FromString(fmt.Sprintf("vector(%g)", objective.Target)),
+			Labels: sloLabels,
+		},
+		monv1.Rule{
+			Record: ErrorBudgetRemainingMetric,
+			Expr:   intstr.FromString(fmt.Sprintf("1 - (%s%s / (1 - %g))", fmt.Sprintf(errorRatioMetric, Period), selector, objective.Target)),
+			Labels: sloLabels,
+		},
+	)
+
+	for _, alert := range burnRateAlerts {
+		var conditions []string
+		var windows []string
+		for _, window := range alert.windows {
+			conditions = append(conditions, fmt.Sprintf("(%[1]s%[3]s > (%[4]g * (1 - %[5]g)) and %[2]s%[3]s > (%[4]g * (1 - %[5]g)))",
+				fmt.Sprintf(errorRatioMetric, window.short), fmt.Sprintf(errorRatioMetric, window.long), selector, window.factor, objective.Target))
+			windows = append(windows, fmt.Sprintf("%s/%s", window.short, window.long))
+		}
+
+		rules = append(rules, monv1.Rule{
+			Alert: fmt.Sprintf("%s%s", strings.ToUpper(installationName), objective.AlertName),
+			Annotations: map[string]string{
+				"sop_url": resources.SopUrlAlertsAndTroubleshooting,
+				"message": fmt.Sprintf("The %s %s error budget is burning too fast over the %s windows, objective: %s",
+					objective.Component, objective.SLIType, strings.Join(windows, " or "), objective.Description),
+			},
+			Expr:   intstr.FromString(strings.Join(conditions, " or ")),
+			For:    monv1.Duration(alert.forDur),
+			Labels: map[string]string{"severity": alert.severity, SLOLabel: objective.Name, "product": installationName},
+		})
+	}
+
+	return rules
+}
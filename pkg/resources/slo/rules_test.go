@@ -0,0 +1,121 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/resources"
+	"github.com/integr8ly/integreatly-operator/pkg/resources/alerttest"
+	l "github.com/integr8ly/integreatly-operator/pkg/resources/logger"
+	monv1 "github.com/rhobs/obo-prometheus-operator/pkg/apis/monitoring/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getInstallation() *integreatlyv1alpha1.RHMI {
+	return &integreatlyv1alpha1.RHMI{
+		ObjectMeta: metav1.ObjectMeta{Name: "rhoam", Namespace: "redhat-rhoam-operator"},
+		Spec: integreatlyv1alpha1.RHMISpec{
+			Type:            string(integreatlyv1alpha1.InstallationTypeManagedApi),
+			NamespacePrefix: "redhat-rhoam-",
+		},
+	}
+}
+
+func TestNewAlertReconcilerRulesAreValid(t *testing.T) {
+	reconciler := NewAlertReconciler(l.NewLogger(), getInstallation()).(*resources.AlertReconcilerImpl)
+	if len(reconciler.Alerts) != len(GetObjectives(getInstallation())) {
+		t.Fatalf("expected a rule per objective, got %d", len(reconciler.Alerts))
+	}
+	for _, alert := range reconciler.Alerts {
+		if alert.Namespace != "redhat-rhoam-operator-observability" {
+			t.Errorf("unexpected namespace %s for %s", alert.Namespace, alert.AlertName)
+		}
+		for _, rule := range alert.Rules.([]monv1.Rule) {
+			if err := resources.ValidateAlertRule(rule); err != nil {
+				t.Errorf("invalid rule in %s: %v", alert.AlertName, err)
+			}
+		}
+	}
+}
+
+func TestBurnRateAlerts(t *testing.T) {
+	gatewayRoute := `{route="zync-3scale-api-orders", exported_namespace="redhat-rhoam-3scale"`
+	ssoRoute := `{route="keycloak", exported_namespace="redhat-rhoam-rhsso"}`
+
+	alerttest.RunAlertRuleTests(t, NewAlertReconciler(l.NewLogger(), getInstallation()), []alerttest.Test{
+		{
+			Name: "gateway 5xx spike burns the error budget",
+			InputSeries: []alerttest.Series{
+				{Series: "haproxy_backend_http_responses_total" + gatewayRoute + `, code="2xx"}`, Values: "0+60x30"},
+				{Series: "haproxy_backend_http_responses_total" + gatewayRoute + `, code="5xx"}`, Values: "0+60x30"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{EvalTime: 2 * time.Minute, AlertName: "RHOAMThreeScaleGatewayAvailabilityErrorBudgetBurn"},
+				{
+					EvalTime:  3 * time.Minute,
+					AlertName: "RHOAMThreeScaleGatewayAvailabilityErrorBudgetBurn",
+					ExpAlerts: []alerttest.Alert{{
+						Labels:      map[string]string{"severity": "critical", SLOLabel: "threescale-gateway-availability", "product": "rhoam"},
+						Annotations: map[string]string{"sop_url": resources.SopUrlAlertsAndTroubleshooting},
+					}},
+				},
+				{EvalTime: 3 * time.Minute, AlertName: "RHOAMThreeScaleAdminPortalAvailabilityErrorBudgetBurn"},
+			},
+		},
+		{
+			Name: "healthy gateway keeps its error budget",
+			InputSeries: []alerttest.Series{
+				{Series: "haproxy_backend_http_responses_total" + gatewayRoute + `, code="2xx"}`, Values: "0+1000x30"},
+				{Series: "haproxy_backend_http_responses_total" + gatewayRoute + `, code="5xx"}`, Values: "0+1x30"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{EvalTime: 30 * time.Minute, AlertName: "RHOAMThreeScaleGatewayAvailabilityErrorBudgetBurn"},
+			},
+		},
+		{
+			// 100 errors of 10200 requests, the average of the ratios of the 5m windows would
+			// be skewed by the errors during the last 10m of little traffic
+			Name: "error budget is the ratio of the errors over the period",
+			InputSeries: []alerttest.Series{
+				{Series: "haproxy_backend_http_responses_total" + gatewayRoute + `, code="2xx"}`, Values: "0+1000x10 10010+10x9"},
+				{Series: "haproxy_backend_http_responses_total" + gatewayRoute + `, code="5xx"}`, Values: "0x10 10+10x9"},
+			},
+			ExprTests: []alerttest.ExprTestCase{
+				{
+					EvalTime:   20 * time.Minute,
+					Expr:       `slo:error_budget_remaining:ratio{slo="threescale-gateway-availability"}`,
+					ExpSamples: []alerttest.Sample{{Labels: `slo:error_budget_remaining:ratio{slo="threescale-gateway-availability"}`, Value: 1 - (100.0/10200)/(1-0.995)}},
+				},
+				{
+					EvalTime: 20 * time.Minute,
+					Expr:     `slo:error_budget_remaining:ratio{slo="threescale-admin-portal-availability"}`,
+				},
+			},
+		},
+		{
+			Name: "slow SSO responses burn the latency error budget",
+			InputSeries: []alerttest.Series{
+				{Series: "haproxy_backend_http_average_response_latency_milliseconds" + ssoRoute, Values: "200x5 1500x20"},
+			},
+			AlertRuleTests: []alerttest.AlertTestCase{
+				{EvalTime: 5 * time.Minute, AlertName: "RHOAMRhssoLatencyErrorBudgetBurn"},
+				{
+					EvalTime:  10 * time.Minute,
+					AlertName: "RHOAMRhssoLatencyErrorBudgetBurn",
+					ExpAlerts: []alerttest.Alert{{
+						Labels: map[string]string{"severity": "critical", SLOLabel: "rhsso-latency", "product": "rhoam"},
+					}},
+				},
+			},
+			ExprTests: []alerttest.ExprTestCase{
+				{
+					// 5 of the 11 evaluations are over the threshold
+					EvalTime:   10 * time.Minute,
+					Expr:       `slo:error_budget_remaining:ratio{slo="rhsso-latency"}`,
+					ExpSamples: []alerttest.Sample{{Labels: `slo:error_budget_remaining:ratio{slo="rhsso-latency"}`, Value: 1 - (5.0/11)/(1-0.95)}},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+	"github.com/integr8ly/integreatly-operator/pkg/config"
+	prometheusApi "github.com/prometheus/client_golang/api"
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const requestTimeout = 10 * time.Second
+
+// BudgetClient reads the remaining error budget of each objective from Prometheus
+type BudgetClient interface {
+	GetErrorBudgetRemaining(ctx context.Context) (map[string]float64, error)
+}
+
+type budgetClient struct {
+	api prometheusv1.API
+}
+
+var _ BudgetClient = &budgetClient{}
+
+func NewBudgetClient(baseURL string) (BudgetClient, error) {
+	client, err := prometheusApi.NewClient(prometheusApi.Config{Address: baseURL})
+	if err != nil {
+		return nil, err
+	}
+	return &budgetClient{api: prometheusv1.NewAPI(client)}, nil
+}
+
+// GetServiceURL returns the in cluster URL of the Prometheus in the observability namespace
+func GetServiceURL(oboNamespace string) string {
+	return fmt.Sprintf("http://%s.%s.svc:%d", config.PrometheusServiceName, oboNamespace, config.PrometheusPort)
+}
+
+func (c *budgetClient) GetErrorBudgetRemaining(ctx context.Context) (map[string]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	result, _, err := c.api.Query(ctx, ErrorBudgetRemainingMetric, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", ErrorBudgetRemainingMetric, err)
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s for %s", result.Type(), ErrorBudgetRemainingMetric)
+	}
+
+	budgets := map[string]float64{}
+	for _, sample := range vector {
+		name := string(sample.Metric[SLOLabel])
+		if name == "" || math.IsNaN(float64(sample.Value)) {
+			continue
+		}
+		budgets[name] = float64(sample.Value)
+	}
+	return budgets, nil
+}
+
+// GetStatus returns the status of every objective of the installation. The remaining error
+// budget is left empty for objectives Prometheus has not recorded a budget for yet
+func GetStatus(installation *integreatlyv1alpha1.RHMI, budgets map[string]float64) []integreatlyv1alpha1.SLOStatus {
+	var status []integreatlyv1alpha1.SLOStatus
+	for _, objective := range GetObjectives(installation) {
+		sloStatus := integreatlyv1alpha1.SLOStatus{
+			Name:      objective.Name,
+			Objective: formatPercentage(objective.Target),
+		}
+		if budget, ok := budgets[objective.Name]; ok {
+			sloStatus.ErrorBudgetRemaining = formatPercentage(budget)
+		}
+		status = append(status, sloStatus)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
+
+func formatPercentage(ratio float64) string {
+	return fmt.Sprintf("%.2f%%", ratio*100)
+}
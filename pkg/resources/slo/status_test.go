@@ -0,0 +1,63 @@
+package slo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	integreatlyv1alpha1 "github.com/integr8ly/integreatly-operator/apis/v1alpha1"
+)
+
+func TestGetErrorBudgetRemaining(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.Form.Get("query") != ErrorBudgetRemainingMetric {
+			t.Errorf("unexpected query %v", r.Form)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"__name__":"slo:error_budget_remaining:ratio","slo":"rhsso-availability"},"value":[1686000000,"0.75"]},
+			{"metric":{"__name__":"slo:error_budget_remaining:ratio","slo":"rhsso-latency"},"value":[1686000000,"NaN"]},
+			{"metric":{"__name__":"slo:error_budget_remaining:ratio"},"value":[1686000000,"0.5"]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewBudgetClient(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	budgets, err := client.GetErrorBudgetRemaining(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := map[string]float64{"rhsso-availability": 0.75}; !reflect.DeepEqual(budgets, expected) {
+		t.Errorf("expected budgets %v, got %v", expected, budgets)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	status := GetStatus(getInstallation(), map[string]float64{"rhsso-availability": 0.75, "threescale-gateway-latency": -0.5})
+
+	expected := map[string]integreatlyv1alpha1.SLOStatus{
+		"rhsso-availability":         {Name: "rhsso-availability", Objective: "99.00%", ErrorBudgetRemaining: "75.00%"},
+		"threescale-gateway-latency": {Name: "threescale-gateway-latency", Objective: "99.00%", ErrorBudgetRemaining: "-50.00%"},
+		"rhsso-latency":              {Name: "rhsso-latency", Objective: "95.00%"},
+	}
+	if len(status) != len(GetObjectives(getInstallation())) {
+		t.Fatalf("expected a status per objective, got %v", status)
+	}
+	for i, sloStatus := range status {
+		if i > 0 && status[i-1].Name > sloStatus.Name {
+			t.Errorf("expected the status to be sorted by name, got %v", status)
+		}
+		if exp, ok := expected[sloStatus.Name]; ok && sloStatus != exp {
+			t.Errorf("expected %v, got %v", exp, sloStatus)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package resources
 
+import "fmt"
+
 // #nosec G101 -- false positive from urls containing `DnsBypass`
 const (
 	sopUrlAddonManagedApiServiceParametersExists               = "https://gitlab.cee.redhat.com/rhcloudservices/integreatly-help/blob/master/sops/rhoam/alerts/AddonManagedApiServiceParameters.asciidoc"
@@ -55,4 +57,70 @@ const (
 	SopUrlPersistentVolumeErrors                               = "https://gitlab.cee.redhat.com/rhcloudservices/integreatly-help/blob/master/sops/rhoam/alerts/pvc_storage.asciidoc#persistentvolumeerrors"
 	SopApiManagementTenantCRFailed                             = "https://gitlab.cee.redhat.com/rhcloudservices/integreatly-help/blob/master/sops/rhoam/alerts/ApiManagementTenantCRFailed.asciidoc"
 	SopUrlRHOAMCloudResourceOperatorMetricsMissing             = "https://gitlab.cee.redhat.com/rhcloudservices/integreatly-help/blob/master/sops/rhoam/alerts/RHOAMCloudResourceOperatorMetricsMissing.asciidoc"
+	SopUrlThreescaleApicastWorkerRestart                       = "https://github.com/3scale/3scale-Operations/blob/master/sops/alerts/apicast_worker_restart.adoc"
 )
+
+// sopUrls maps the SOP URL constants by name for the alert rule templates
+var sopUrls = map[string]string{
+	"sopUrlAddonManagedApiServiceParametersExists":               sopUrlAddonManagedApiServiceParametersExists,
+	"sopUrlPostgresInstanceUnavailable":                          sopUrlPostgresInstanceUnavailable,
+	"sopUrlPostgresConnectionFailed":                             sopUrlPostgresConnectionFailed,
+	"sopUrlRedisCacheUnavailable":                                sopUrlRedisCacheUnavailable,
+	"sopUrlRedisConnectionFailed":                                sopUrlRedisConnectionFailed,
+	"sopUrlPostgresResourceStatusPhasePending":                   sopUrlPostgresResourceStatusPhasePending,
+	"sopUrlPostgresResourceStatusPhaseFailed":                    sopUrlPostgresResourceStatusPhaseFailed,
+	"sopUrlRedisResourceStatusPhasePending":                      sopUrlRedisResourceStatusPhasePending,
+	"sopUrlRedisResourceStatusPhaseFailed":                       sopUrlRedisResourceStatusPhaseFailed,
+	"sopUrlPostgresWillFill":                                     sopUrlPostgresWillFill,
+	"sopUrlPostgresFreeableMemoryLow":                            sopUrlPostgresFreeableMemoryLow,
+	"sopUrlRedisMemoryUsageHigh":                                 sopUrlRedisMemoryUsageHigh,
+	"sopUrlPostgresCpuUsageHigh":                                 sopUrlPostgresCpuUsageHigh,
+	"sopUrlRedisCpuUsageHigh":                                    sopUrlRedisCpuUsageHigh,
+	"sopUrlRedisServiceMaintenanceCritical":                      sopUrlRedisServiceMaintenanceCritical,
+	"SopUrlEndpointAvailableAlert":                               SopUrlEndpointAvailableAlert,
+	"SopUrlAlertsAndTroubleshooting":                             SopUrlAlertsAndTroubleshooting,
+	"sopUrlCloudResourceDeletionStatusFailed":                    sopUrlCloudResourceDeletionStatusFailed,
+	"sopUrlSendGridSmtpSecretExists":                             sopUrlSendGridSmtpSecretExists,
+	"SopUrlDeadMansSnitchSecretExists":                           SopUrlDeadMansSnitchSecretExists,
+	"SopUrlMarin3rEnvoyApicastProductionContainerDown":           SopUrlMarin3rEnvoyApicastProductionContainerDown,
+	"SopUrlMarin3rEnvoyApicastStagingContainerDown":              SopUrlMarin3rEnvoyApicastStagingContainerDown,
+	"SopUrlOperatorInstallDelayed":                               SopUrlOperatorInstallDelayed,
+	"SopUrlUpgradeExpectedDurationExceeded":                      SopUrlUpgradeExpectedDurationExceeded,
+	"SopUrlRHOAMIsInReconcilingErrorState":                       SopUrlRHOAMIsInReconcilingErrorState,
+	"SopUrlRHOAMCloudResourceOperatorMetricsServiceEndpointDown": SopUrlRHOAMCloudResourceOperatorMetricsServiceEndpointDown,
+	"SopUrlRHOAMCloudResourceOperatorVPCActionFailed":            SopUrlRHOAMCloudResourceOperatorVPCActionFailed,
+	"SopUrlRHOAMThreeScaleApicastProductionServiceEndpointDown":  SopUrlRHOAMThreeScaleApicastProductionServiceEndpointDown,
+	"SopUrlRHOAMThreeScaleApicastStagingServiceEndpointDown":     SopUrlRHOAMThreeScaleApicastStagingServiceEndpointDown,
+	"SopUrlRHOAMThreeScaleBackendListenerServiceEndpointDown":    SopUrlRHOAMThreeScaleBackendListenerServiceEndpointDown,
+	"SopUrlRHOAMThreeScaleZyncServiceEndpointDown":               SopUrlRHOAMThreeScaleZyncServiceEndpointDown,
+	"SopUrlRHOAMThreeScaleZyncDatabaseServiceEndpointDown":       SopUrlRHOAMThreeScaleZyncDatabaseServiceEndpointDown,
+	"SopUrlThreeScaleBackendWorkerPod":                           SopUrlThreeScaleBackendWorkerPod,
+	"SopUrlThreeScaleAdminUIBBT":                                 SopUrlThreeScaleAdminUIBBT,
+	"SopUrlThreeScaleDeveloperUIBBT":                             SopUrlThreeScaleDeveloperUIBBT,
+	"SopUrlThreeScaleSystemAdminUIBBT":                           SopUrlThreeScaleSystemAdminUIBBT,
+	"SopUrlPodDistributionIncorrect":                             SopUrlPodDistributionIncorrect,
+	"SopUrlSloRhssoAvailabilityAlert":                            SopUrlSloRhssoAvailabilityAlert,
+	"SopUrlSloUserSsoAvailabilityAlert":                          SopUrlSloUserSsoAvailabilityAlert,
+	"SopUrlTestFireAlerts":                                       SopUrlTestFireAlerts,
+	"SopUrlRHOAMServiceDefinition":                               SopUrlRHOAMServiceDefinition,
+	"SopUrlDnsBypassThreeScaleAdminUI":                           SopUrlDnsBypassThreeScaleAdminUI,
+	"SopUrlDnsBypassThreeScaleDeveloperUI":                       SopUrlDnsBypassThreeScaleDeveloperUI,
+	"SopUrlDnsBypassThreeScaleSystemAdminUI":                     SopUrlDnsBypassThreeScaleSystemAdminUI,
+	"SopUrlKeycloakInstanceNotAvailable":                         SopUrlKeycloakInstanceNotAvailable,
+	"SopUrlCriticalMetricsMissing":                               SopUrlCriticalMetricsMissing,
+	"SopUrlClusterSchedulableResourcesLow":                       SopUrlClusterSchedulableResourcesLow,
+	"SopUrlKubePersistentVolumeFillingUp4h":                      SopUrlKubePersistentVolumeFillingUp4h,
+	"SopUrlKubePersistentVolumeFillingUp":                        SopUrlKubePersistentVolumeFillingUp,
+	"SopUrlPersistentVolumeErrors":                               SopUrlPersistentVolumeErrors,
+	"SopApiManagementTenantCRFailed":                             SopApiManagementTenantCRFailed,
+	"SopUrlRHOAMCloudResourceOperatorMetricsMissing":             SopUrlRHOAMCloudResourceOperatorMetricsMissing,
+	"SopUrlThreescaleApicastWorkerRestart":                       SopUrlThreescaleApicastWorkerRestart,
+}
+
+func getSopUrl(name string) (string, error) {
+	sopUrl, ok := sopUrls[name]
+	if !ok {
+		return "", fmt.Errorf("unknown SOP URL %s", name)
+	}
+	return sopUrl, nil
+}
@@ -0,0 +1,188 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// GroupMappingsConfigMapName is the ConfigMap in the operator namespace holding the
+	// OpenShift group to Keycloak group and role mappings
+	GroupMappingsConfigMapName = "user-group-mappings"
+	GroupMappingsKey           = "mappings"
+
+	ThreeScaleRoleAdmin  = "admin"
+	ThreeScaleRoleMember = "member"
+)
+
+// Keycloak groups managed by the operator itself which can not be the target of a mapping
+var reservedKeycloakGroups = []string{
+	"dedicated-admins",
+	"rhmi-developers",
+	"realm-managers",
+}
+
+// GroupMapping grants the members of an OpenShift group membership of a Keycloak group,
+// with the given roles, in the user SSO master realm and/or a role in 3scale
+type GroupMapping struct {
+	// OpenshiftGroup is the name of the OpenShift group whose members receive the mapping
+	OpenshiftGroup string `json:"openshiftGroup"`
+	// KeycloakGroup is created in the master realm if missing and the members are added to it
+	KeycloakGroup string `json:"keycloakGroup,omitempty"`
+	// RealmRoles are realm roles mapped to the Keycloak group
+	RealmRoles []string `json:"realmRoles,omitempty"`
+	// ClientRoles are client roles mapped to the Keycloak group, keyed by client name
+	ClientRoles map[string][]string `json:"clientRoles,omitempty"`
+	// ThreeScaleRole is the 3scale role of the members, either "admin" or "member"
+	ThreeScaleRole string `json:"threescaleRole,omitempty"`
+}
+
+// DefaultGroupMappings returns the mappings used when no configuration is provided. It
+// matches the behaviour prior to mappings being configurable
+func DefaultGroupMappings() []GroupMapping {
+	return []GroupMapping{
+		{
+			OpenshiftGroup: "dedicated-admins",
+			ThreeScaleRole: ThreeScaleRoleAdmin,
+		},
+	}
+}
+
+// GetGroupMappings reads the group mappings from the operator namespace, returning the
+// default mappings if the ConfigMap does not exist
+func GetGroupMappings(ctx context.Context, serverClient k8sclient.Client, namespace string) ([]GroupMapping, error) {
+	configMap := &corev1.ConfigMap{}
+	err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: GroupMappingsConfigMapName, Namespace: namespace}, configMap)
+	if k8serr.IsNotFound(err) {
+		return DefaultGroupMappings(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s ConfigMap: %w", GroupMappingsConfigMapName, err)
+	}
+
+	mappingsJSON, ok := configMap.Data[GroupMappingsKey]
+	if !ok {
+		return DefaultGroupMappings(), nil
+	}
+
+	return ParseGroupMappings(mappingsJSON)
+}
+
+// ParseGroupMappings unmarshals and validates a JSON list of group mappings
+func ParseGroupMappings(mappingsJSON string) ([]GroupMapping, error) {
+	var mappings []GroupMapping
+	if err := json.Unmarshal([]byte(mappingsJSON), &mappings); err != nil {
+		return nil, fmt.Errorf("failed to parse group mappings: %w", err)
+	}
+	if err := ValidateGroupMappings(mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+func ValidateGroupMappings(mappings []GroupMapping) error {
+	for i, mapping := range mappings {
+		if mapping.OpenshiftGroup == "" {
+			return fmt.Errorf("group mapping %d: openshiftGroup is required", i)
+		}
+		if mapping.KeycloakGroup == "" && (len(mapping.RealmRoles) > 0 || len(mapping.ClientRoles) > 0) {
+			return fmt.Errorf("group mapping %s: keycloakGroup is required when roles are set", mapping.OpenshiftGroup)
+		}
+		if strings.Contains(mapping.KeycloakGroup, "/") {
+			return fmt.Errorf("group mapping %s: keycloakGroup %s must not be a path", mapping.OpenshiftGroup, mapping.KeycloakGroup)
+		}
+		for _, reserved := range reservedKeycloakGroups {
+			if mapping.KeycloakGroup == reserved {
+				return fmt.Errorf("group mapping %s: keycloakGroup %s is managed by the operator", mapping.OpenshiftGroup, reserved)
+			}
+		}
+		switch mapping.ThreeScaleRole {
+		case "", ThreeScaleRoleAdmin, ThreeScaleRoleMember:
+		default:
+			return fmt.Errorf("group mapping %s: threescaleRole must be %s or %s", mapping.OpenshiftGroup, ThreeScaleRoleAdmin, ThreeScaleRoleMember)
+		}
+	}
+	return nil
+}
+
+// GetMappedOpenshiftGroups gets the OpenShift groups referenced by the mappings, and any
+// additional groups, by name rather than listing every group on the cluster. Groups that
+// do not exist are skipped
+func GetMappedOpenshiftGroups(ctx context.Context, serverClient k8sclient.Client, mappings []GroupMapping, additionalGroups ...string) (*usersv1.GroupList, error) {
+	groupNames := append([]string{}, additionalGroups...)
+	for _, mapping := range mappings {
+		groupNames = appendIfMissing(groupNames, mapping.OpenshiftGroup)
+	}
+
+	groups := &usersv1.GroupList{}
+	for _, groupName := range groupNames {
+		group := &usersv1.Group{}
+		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: groupName}, group)
+		if k8serr.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not get group %s: %w", groupName, err)
+		}
+		groups.Items = append(groups.Items, *group)
+	}
+	return groups, nil
+}
+
+// GetMappedKeycloakGroups returns the Keycloak groups the user should be a member of
+func GetMappedKeycloakGroups(mappings []GroupMapping, userName string, groups *usersv1.GroupList) []string {
+	var keycloakGroups []string
+	for _, mapping := range mappings {
+		if mapping.KeycloakGroup == "" || !isGroupMember(userName, mapping.OpenshiftGroup, groups) {
+			continue
+		}
+		keycloakGroups = appendIfMissing(keycloakGroups, mapping.KeycloakGroup)
+	}
+	return keycloakGroups
+}
+
+// GetMappedThreeScaleRole returns the 3scale role for the user, or an empty string if
+// none of the user's groups are mapped to a role. Admin takes precedence over member
+func GetMappedThreeScaleRole(mappings []GroupMapping, userName string, groups *usersv1.GroupList) string {
+	role := ""
+	for _, mapping := range mappings {
+		if mapping.ThreeScaleRole == "" || !isGroupMember(userName, mapping.OpenshiftGroup, groups) {
+			continue
+		}
+		if mapping.ThreeScaleRole == ThreeScaleRoleAdmin {
+			return ThreeScaleRoleAdmin
+		}
+		role = mapping.ThreeScaleRole
+	}
+	return role
+}
+
+func isGroupMember(userName, groupName string, groups *usersv1.GroupList) bool {
+	for _, group := range groups.Items {
+		if group.Name != groupName {
+			continue
+		}
+		for _, groupUser := range group.Users {
+			if strings.EqualFold(groupUser, userName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendIfMissing(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
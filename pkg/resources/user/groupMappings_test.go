@@ -0,0 +1,146 @@
+package user
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGetGroupMappings(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configMap := func(mappings string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: GroupMappingsConfigMapName, Namespace: "rhoam-operator"},
+			Data:       map[string]string{GroupMappingsKey: mappings},
+		}
+	}
+
+	tests := []struct {
+		Name     string
+		InitObjs []runtime.Object
+		Expected []GroupMapping
+		WantErr  bool
+	}{
+		{
+			Name:     "defaults when config map is missing",
+			Expected: DefaultGroupMappings(),
+		},
+		{
+			Name:     "reads mappings from config map",
+			InitObjs: []runtime.Object{configMap(`[{"openshiftGroup":"support","keycloakGroup":"support","clientRoles":{"master-realm":["view-realm"]}}]`)},
+			Expected: []GroupMapping{
+				{
+					OpenshiftGroup: "support",
+					KeycloakGroup:  "support",
+					ClientRoles:    map[string][]string{"master-realm": {"view-realm"}},
+				},
+			},
+		},
+		{
+			Name:     "error on reserved keycloak group",
+			InitObjs: []runtime.Object{configMap(`[{"openshiftGroup":"support","keycloakGroup":"dedicated-admins"}]`)},
+			WantErr:  true,
+		},
+		{
+			Name:     "error on invalid 3scale role",
+			InitObjs: []runtime.Object{configMap(`[{"openshiftGroup":"support","threescaleRole":"owner"}]`)},
+			WantErr:  true,
+		},
+		{
+			Name:     "error on roles without keycloak group",
+			InitObjs: []runtime.Object{configMap(`[{"openshiftGroup":"support","realmRoles":["create-realm"]}]`)},
+			WantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			serverClient := utils.NewTestClient(scheme, tt.InitObjs...)
+
+			mappings, err := GetGroupMappings(context.TODO(), serverClient, "rhoam-operator")
+			if (err != nil) != tt.WantErr {
+				t.Fatalf("GetGroupMappings() error = %v, wantErr %v", err, tt.WantErr)
+			}
+			if !tt.WantErr && !reflect.DeepEqual(mappings, tt.Expected) {
+				t.Fatalf("expected %v, got %v", tt.Expected, mappings)
+			}
+		})
+	}
+}
+
+func TestGetMappedThreeScaleRole(t *testing.T) {
+	mappings := []GroupMapping{
+		{OpenshiftGroup: "support", KeycloakGroup: "support", ThreeScaleRole: ThreeScaleRoleMember},
+		{OpenshiftGroup: "dedicated-admins", ThreeScaleRole: ThreeScaleRoleAdmin},
+	}
+	groups := &usersv1.GroupList{
+		Items: []usersv1.Group{
+			{ObjectMeta: metav1.ObjectMeta{Name: "support"}, Users: []string{"alice", "bob"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "dedicated-admins"}, Users: []string{"bob"}},
+		},
+	}
+
+	tests := []struct {
+		UserName       string
+		ExpectedRole   string
+		ExpectedGroups []string
+	}{
+		{UserName: "alice", ExpectedRole: ThreeScaleRoleMember, ExpectedGroups: []string{"support"}},
+		{UserName: "bob", ExpectedRole: ThreeScaleRoleAdmin, ExpectedGroups: []string{"support"}},
+		{UserName: "carol", ExpectedRole: "", ExpectedGroups: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.UserName, func(t *testing.T) {
+			if role := GetMappedThreeScaleRole(mappings, tt.UserName, groups); role != tt.ExpectedRole {
+				t.Fatalf("expected role %q, got %q", tt.ExpectedRole, role)
+			}
+			if keycloakGroups := GetMappedKeycloakGroups(mappings, tt.UserName, groups); !reflect.DeepEqual(keycloakGroups, tt.ExpectedGroups) {
+				t.Fatalf("expected groups %v, got %v", tt.ExpectedGroups, keycloakGroups)
+			}
+		})
+	}
+}
+
+func TestGetMappedOpenshiftGroups(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	group := func(name string, users ...string) *usersv1.Group {
+		return &usersv1.Group{ObjectMeta: metav1.ObjectMeta{Name: name}, Users: users}
+	}
+	serverClient := utils.NewTestClient(scheme,
+		group("dedicated-admins", "alice"),
+		group("support", "bob"),
+		group("unrelated", "carol"),
+	)
+	mappings := []GroupMapping{
+		{OpenshiftGroup: "support", KeycloakGroup: "support"},
+		{OpenshiftGroup: "missing", ThreeScaleRole: ThreeScaleRoleMember},
+	}
+
+	groups, err := GetMappedOpenshiftGroups(context.TODO(), serverClient, mappings, "dedicated-admins")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, group := range groups.Items {
+		names = append(names, group.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"dedicated-admins", "support"}) {
+		t.Fatalf("expected only the mapped and additional groups, got %v", names)
+	}
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"context"
+	"sync/atomic"
+
+	v1 "github.com/openshift/api/config/v1"
+	usersv1 "github.com/openshift/api/user/v1"
+	"github.com/pkg/errors"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// incrementalSync is set once the user sync controller is running. While set, the
+// RHSSO product reconcilers no longer list every OpenShift user on each loop and
+// leave propagating users to Keycloak to the controller
+var incrementalSync atomic.Bool
+
+func SetIncrementalSyncEnabled(enabled bool) {
+	incrementalSync.Store(enabled)
+}
+
+func IsIncrementalSyncEnabled() bool {
+	return incrementalSync.Load()
+}
+
+// IsUserInActiveIDP is the single user equivalent of GetUsersInActiveIDPs. Only the
+// identities referenced by the user are fetched rather than every identity on the cluster
+func IsUserInActiveIDP(ctx context.Context, serverClient k8sclient.Client, user usersv1.User) (bool, error) {
+	if len(user.Identities) == 0 {
+		return false, nil
+	}
+
+	oAuths := &v1.OAuthList{}
+	if err := serverClient.List(ctx, oAuths); err != nil {
+		return false, errors.Wrap(err, "could not list oAuths")
+	}
+	idpNames := map[string]bool{}
+	for _, oauth := range oAuths.Items {
+		for _, idp := range oauth.Spec.IdentityProviders {
+			idpNames[idp.Name] = true
+		}
+	}
+
+	for _, identityName := range user.Identities {
+		identity := &usersv1.Identity{}
+		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: identityName}, identity)
+		if k8serr.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "could not get identity %s", identityName)
+		}
+		if idpNames[identity.ProviderName] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetUserIdentities fetches the identities referenced by the user, skipping any that no
+// longer exist
+func GetUserIdentities(ctx context.Context, serverClient k8sclient.Client, user usersv1.User) (usersv1.IdentityList, error) {
+	identities := usersv1.IdentityList{}
+	for _, identityName := range user.Identities {
+		identity := &usersv1.Identity{}
+		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: identityName}, identity)
+		if k8serr.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return identities, errors.Wrapf(err, "could not get identity %s", identityName)
+		}
+		identities.Items = append(identities.Items, *identity)
+	}
+	return identities, nil
+}
@@ -0,0 +1,228 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SyncRulesConfigMapName is the ConfigMap in the operator namespace holding the rules
+	// deciding which OpenShift users are propagated to rhmi-developers, RHSSO and 3scale
+	SyncRulesConfigMapName = "user-sync-rules"
+	SyncRulesKey           = "rules"
+)
+
+// SyncRules decide which OpenShift users are synchronised. When include rules are set a
+// user must match at least one of them. A user matching any exclude rule is never
+// synchronised, regardless of the include rules
+type SyncRules struct {
+	Include []SyncRule `json:"include,omitempty"`
+	Exclude []SyncRule `json:"exclude,omitempty"`
+}
+
+// SyncRule matches a user when all of the criteria set on the rule match
+type SyncRule struct {
+	// Groups matches members of any of the named OpenShift groups
+	Groups []string `json:"groups,omitempty"`
+	// IdentityProviders matches users with an identity from any of the named providers
+	IdentityProviders []string `json:"identityProviders,omitempty"`
+	// UserNamePattern matches user names against a regular expression
+	UserNamePattern string `json:"userNamePattern,omitempty"`
+	// Annotation matches users annotated with the key, or with key=value if a value is given
+	Annotation string `json:"annotation,omitempty"`
+
+	userNameRegexp *regexp.Regexp
+}
+
+// DefaultSyncRules returns the rules used when no configuration is provided. It excludes
+// the SRE groups, matching the behaviour prior to the rules being configurable
+func DefaultSyncRules() *SyncRules {
+	return &SyncRules{
+		Exclude: []SyncRule{
+			{
+				Groups: []string{
+					"layered-cs-sre-admins",
+					"osd-sre-admins",
+				},
+			},
+		},
+	}
+}
+
+// GetSyncRules reads the sync rules from the operator namespace, returning the default
+// rules if the ConfigMap does not exist. The default exclusions always apply, a customer
+// configuration can add to them but never synchronise the SRE groups
+func GetSyncRules(ctx context.Context, serverClient k8sclient.Client, namespace string) (*SyncRules, error) {
+	configMap := &corev1.ConfigMap{}
+	err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: SyncRulesConfigMapName, Namespace: namespace}, configMap)
+	if k8serr.IsNotFound(err) {
+		return DefaultSyncRules(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s ConfigMap: %w", SyncRulesConfigMapName, err)
+	}
+
+	rulesJSON, ok := configMap.Data[SyncRulesKey]
+	if !ok {
+		return DefaultSyncRules(), nil
+	}
+
+	rules, err := ParseSyncRules(rulesJSON)
+	if err != nil {
+		return nil, err
+	}
+	rules.Exclude = append(rules.Exclude, DefaultSyncRules().Exclude...)
+
+	return rules, nil
+}
+
+// ParseSyncRules unmarshals and validates the JSON sync rules
+func ParseSyncRules(rulesJSON string) (*SyncRules, error) {
+	rules := &SyncRules{}
+	if err := json.Unmarshal([]byte(rulesJSON), rules); err != nil {
+		return nil, fmt.Errorf("failed to parse sync rules: %w", err)
+	}
+
+	ruleSets := []struct {
+		name  string
+		rules []SyncRule
+	}{
+		{name: "include", rules: rules.Include},
+		{name: "exclude", rules: rules.Exclude},
+	}
+	for _, ruleSet := range ruleSets {
+		for i := range ruleSet.rules {
+			rule := &ruleSet.rules[i]
+			if len(rule.Groups) == 0 && len(rule.IdentityProviders) == 0 && rule.UserNamePattern == "" && rule.Annotation == "" {
+				return nil, fmt.Errorf("%s sync rule %d: at least one criteria must be set", ruleSet.name, i)
+			}
+			if rule.UserNamePattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(rule.UserNamePattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s sync rule %d: invalid userNamePattern: %w", ruleSet.name, i, err)
+			}
+			rule.userNameRegexp = re
+		}
+	}
+
+	return rules, nil
+}
+
+// IsExcluded returns true if the user must not be synchronised
+func (r *SyncRules) IsExcluded(user usersv1.User, groups *usersv1.GroupList) bool {
+	for _, rule := range r.Exclude {
+		if rule.matches(user, groups) {
+			return true
+		}
+	}
+	if len(r.Include) == 0 {
+		return false
+	}
+	for _, rule := range r.Include {
+		if rule.matches(user, groups) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsUserExcluded is the single user equivalent of IsExcluded. Only the groups referenced
+// by the rules are fetched rather than every group on the cluster
+func (r *SyncRules) IsUserExcluded(ctx context.Context, serverClient k8sclient.Client, user usersv1.User) (bool, error) {
+	groups := &usersv1.GroupList{}
+	for _, groupName := range r.groupNames() {
+		group := &usersv1.Group{}
+		err := serverClient.Get(ctx, k8sclient.ObjectKey{Name: groupName}, group)
+		if k8serr.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not get group %s: %w", groupName, err)
+		}
+		groups.Items = append(groups.Items, *group)
+	}
+
+	return r.IsExcluded(user, groups), nil
+}
+
+func (r *SyncRules) groupNames() []string {
+	var names []string
+	for _, ruleSet := range [][]SyncRule{r.Include, r.Exclude} {
+		for _, rule := range ruleSet {
+			for _, groupName := range rule.Groups {
+				names = appendIfMissing(names, groupName)
+			}
+		}
+	}
+	return names
+}
+
+func (rule SyncRule) matches(user usersv1.User, groups *usersv1.GroupList) bool {
+	if len(rule.Groups) > 0 && !isMemberOfAny(user.Name, rule.Groups, groups) {
+		return false
+	}
+	if len(rule.IdentityProviders) > 0 && !hasIdentityFromAny(user, rule.IdentityProviders) {
+		return false
+	}
+	if rule.UserNamePattern != "" {
+		re := rule.userNameRegexp
+		if re == nil {
+			re = regexp.MustCompile(rule.UserNamePattern)
+		}
+		if !re.MatchString(user.Name) {
+			return false
+		}
+	}
+	if rule.Annotation != "" && !hasAnnotation(user, rule.Annotation) {
+		return false
+	}
+	return true
+}
+
+func isMemberOfAny(userName string, groupNames []string, groups *usersv1.GroupList) bool {
+	for _, group := range groups.Items {
+		for _, groupName := range groupNames {
+			if group.Name != groupName {
+				continue
+			}
+			for _, groupUser := range group.Users {
+				if groupUser == userName {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Identity names are of the form <provider name>:<provider user name>
+func hasIdentityFromAny(user usersv1.User, providers []string) bool {
+	for _, identity := range user.Identities {
+		providerName := strings.SplitN(identity, ":", 2)[0]
+		for _, provider := range providers {
+			if providerName == provider {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAnnotation(user usersv1.User, annotation string) bool {
+	key, value, hasValue := strings.Cut(annotation, "=")
+	actual, ok := user.Annotations[key]
+	if !ok {
+		return false
+	}
+	return !hasValue || actual == value
+}
@@ -0,0 +1,152 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	usersv1 "github.com/openshift/api/user/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSyncRules_IsExcluded(t *testing.T) {
+	groups := &usersv1.GroupList{
+		Items: []usersv1.Group{
+			{ObjectMeta: metav1.ObjectMeta{Name: "osd-sre-admins"}, Users: []string{"sre"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "developers"}, Users: []string{"alice", "bob"}},
+		},
+	}
+	newUser := func(name string, identities []string, annotations map[string]string) usersv1.User {
+		return usersv1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Identities: identities,
+		}
+	}
+
+	tests := []struct {
+		Name     string
+		Rules    string
+		User     usersv1.User
+		Expected bool
+	}{
+		{
+			Name:     "default rules exclude sre group",
+			User:     newUser("sre", nil, nil),
+			Expected: true,
+		},
+		{
+			Name:     "default rules include other users",
+			User:     newUser("alice", nil, nil),
+			Expected: false,
+		},
+		{
+			Name:     "exclude by username pattern",
+			Rules:    `{"exclude":[{"userNamePattern":"^svc-"}]}`,
+			User:     newUser("svc-backup", nil, nil),
+			Expected: true,
+		},
+		{
+			Name:     "exclude by annotation value",
+			Rules:    `{"exclude":[{"annotation":"rhoam/sync=false"}]}`,
+			User:     newUser("alice", nil, map[string]string{"rhoam/sync": "false"}),
+			Expected: true,
+		},
+		{
+			Name:     "annotation with different value does not match",
+			Rules:    `{"exclude":[{"annotation":"rhoam/sync=false"}]}`,
+			User:     newUser("alice", nil, map[string]string{"rhoam/sync": "true"}),
+			Expected: false,
+		},
+		{
+			Name:     "include by identity provider excludes other providers",
+			Rules:    `{"include":[{"identityProviders":["corp-ldap"]}]}`,
+			User:     newUser("alice", []string{"htpasswd:alice"}, nil),
+			Expected: true,
+		},
+		{
+			Name:     "include by identity provider",
+			Rules:    `{"include":[{"identityProviders":["corp-ldap"]}]}`,
+			User:     newUser("alice", []string{"corp-ldap:alice"}, nil),
+			Expected: false,
+		},
+		{
+			Name:     "criteria in a rule must all match",
+			Rules:    `{"exclude":[{"groups":["developers"],"userNamePattern":"^b"}]}`,
+			User:     newUser("alice", nil, nil),
+			Expected: false,
+		},
+		{
+			Name:     "exclude takes precedence over include",
+			Rules:    `{"include":[{"groups":["developers"]}],"exclude":[{"userNamePattern":"^bob$"}]}`,
+			User:     newUser("bob", nil, nil),
+			Expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			rules := DefaultSyncRules()
+			if tt.Rules != "" {
+				var err error
+				rules, err = ParseSyncRules(tt.Rules)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if excluded := rules.IsExcluded(tt.User, groups); excluded != tt.Expected {
+				t.Fatalf("expected excluded %v, got %v", tt.Expected, excluded)
+			}
+		})
+	}
+}
+
+func TestParseSyncRules(t *testing.T) {
+	invalid := []string{
+		`{"exclude":[{}]}`,
+		`{"exclude":[{"userNamePattern":"("}]}`,
+		`not json`,
+	}
+	for _, rules := range invalid {
+		if _, err := ParseSyncRules(rules); err == nil {
+			t.Fatalf("expected error parsing %s", rules)
+		}
+	}
+}
+
+func TestParseSyncRules_errorNamesRuleList(t *testing.T) {
+	tests := map[string]string{
+		`{"include":[{"groups":["developers"]},{}]}`: "include sync rule 1",
+		`{"exclude":[{"userNamePattern":"("}]}`:      "exclude sync rule 0",
+	}
+	for rules, expected := range tests {
+		_, err := ParseSyncRules(rules)
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Fatalf("expected error starting with %q parsing %s, got %v", expected, rules, err)
+		}
+	}
+}
+
+func TestGetSyncRules_keepsDefaultExclusions(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverClient := utils.NewTestClient(scheme, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: SyncRulesConfigMapName, Namespace: "rhoam-operator"},
+		Data:       map[string]string{SyncRulesKey: `{"exclude":[{"groups":["contractors"]}]}`},
+	})
+
+	rules, err := GetSyncRules(context.TODO(), serverClient, "rhoam-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, groupName := range []string{"contractors", "osd-sre-admins", "layered-cs-sre-admins"} {
+		groups := &usersv1.GroupList{Items: []usersv1.Group{{ObjectMeta: metav1.ObjectMeta{Name: groupName}, Users: []string{"user"}}}}
+		if !rules.IsExcluded(usersv1.User{ObjectMeta: metav1.ObjectMeta{Name: "user"}}, groups) {
+			t.Fatalf("expected members of %s to be excluded", groupName)
+		}
+	}
+}
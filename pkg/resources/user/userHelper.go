@@ -24,13 +24,6 @@ const (
 	defaultEmailDomain          = "@rhmi.io"
 )
 
-var (
-	exclusionGroups = []string{
-		"layered-cs-sre-admins",
-		"osd-sre-admins",
-	}
-)
-
 type MultiTenantUser struct {
 	Username   string
 	TenantName string
@@ -86,24 +79,6 @@ func GetValidGeneratedUserName(keycloakUser keycloak.KeycloakAPIUser) string {
 	return fmt.Sprintf("%v%v", GeneratedNamePrefix, processedString)
 }
 
-func IsInExclusionGroup(user usersv1.User, groups *usersv1.GroupList) bool {
-
-	// Below is a slightly complex way to determine if the user exists in an exlcusion group
-	// Ideally we would use the user.Groups field but this does not seem to get populated.
-	for _, group := range groups.Items {
-		for _, xGroup := range exclusionGroups {
-			if group.Name == xGroup {
-				for _, groupUser := range group.Users {
-					if groupUser == user.Name {
-						return true
-					}
-				}
-			}
-		}
-	}
-	return false
-}
-
 // User has no Identity ID on user CR => not an active user.
 // User has identity ID on user CR, Identity CR exist and are part of an active IDP => Is an active user.
 // User has identity ID on user CR, Identity CR do not exist => Not an active user. Assume the identity CR is associated with a non active IDP. User can log in to rectify
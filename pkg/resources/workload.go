@@ -0,0 +1,135 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// restartedAtAnnotation is the pod template annotation `oc rollout restart` sets to roll out a Deployment
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// GetWorkload returns the Deployment with the name, or the DeploymentConfig with the name for
+// products that are still deployed with DeploymentConfigs. A NotFound error is returned when
+// neither exists
+func GetWorkload(ctx context.Context, client k8sclient.Client, name, namespace string) (k8sclient.Object, error) {
+	key := k8sclient.ObjectKey{Name: name, Namespace: namespace}
+
+	deployment := &appsv1.Deployment{}
+	err := client.Get(ctx, key, deployment)
+	if err == nil {
+		return deployment, nil
+	}
+	if !k8serr.IsNotFound(err) {
+		return nil, err
+	}
+
+	deploymentConfig := &openshiftappsv1.DeploymentConfig{}
+	err = client.Get(ctx, key, deploymentConfig)
+	if err == nil {
+		return deploymentConfig, nil
+	}
+	// clusters without the OpenShift apps API can not have DeploymentConfigs
+	if runtime.IsNotRegisteredError(err) || meta.IsNoMatchError(err) {
+		return nil, k8serr.NewNotFound(schema.GroupResource{Group: appsv1.GroupName, Resource: "deployments"}, name)
+	}
+	return nil, err
+}
+
+// WorkloadTemplateSelector returns the PodTemplateSelector of the kind of the workload
+func WorkloadTemplateSelector(obj k8sclient.Object) (PodTemplateSelector, error) {
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		return SelectFromDeployment, nil
+	case *openshiftappsv1.DeploymentConfig:
+		return SelectFromDeploymentConfig, nil
+	default:
+		return nil, fmt.Errorf("%T is not a Deployment or DeploymentConfig", obj)
+	}
+}
+
+// WorkloadPodTemplate returns the pod template of a Deployment or DeploymentConfig
+func WorkloadPodTemplate(obj k8sclient.Object) (*corev1.PodTemplateSpec, error) {
+	selector, err := WorkloadTemplateSelector(obj)
+	if err != nil {
+		return nil, err
+	}
+	template := selector(obj)
+	if template == nil {
+		return nil, fmt.Errorf("%s has no pod template", obj.GetName())
+	}
+	return template, nil
+}
+
+// IsWorkloadFailed returns true when the rollout of the Deployment or DeploymentConfig failed
+// and a new rollout is needed
+func IsWorkloadFailed(obj k8sclient.Object) bool {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		for _, condition := range workload.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse {
+				return true
+			}
+			if condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue {
+				return true
+			}
+		}
+	case *openshiftappsv1.DeploymentConfig:
+		for _, condition := range workload.Status.Conditions {
+			if condition.Status == corev1.ConditionFalse {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsWorkloadReady returns true when every replica of the Deployment or DeploymentConfig is
+// updated and available
+func IsWorkloadReady(obj k8sclient.Object) bool {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		if IsWorkloadFailed(workload) {
+			return false
+		}
+		for _, condition := range workload.Status.Conditions {
+			if condition.Type == appsv1.DeploymentAvailable && condition.Status != corev1.ConditionTrue {
+				return false
+			}
+		}
+		return workload.Status.ObservedGeneration >= workload.Generation &&
+			workload.Status.Replicas == workload.Status.AvailableReplicas &&
+			workload.Status.ReadyReplicas == workload.Status.UpdatedReplicas
+	case *openshiftappsv1.DeploymentConfig:
+		for _, condition := range workload.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue || (workload.Status.Replicas != workload.Status.AvailableReplicas ||
+				workload.Status.ReadyReplicas != workload.Status.UpdatedReplicas) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// RestartDeployment rolls out new pods of the Deployment the same way `oc rollout restart` does
+func RestartDeployment(ctx context.Context, client k8sclient.Client, deployment *appsv1.Deployment) error {
+	patch := k8sclient.MergeFrom(deployment.DeepCopy())
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	deployment.Spec.Template.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+	if err := client.Patch(ctx, deployment, patch); err != nil {
+		return fmt.Errorf("failed to restart deployment %s: %w", deployment.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/integr8ly/integreatly-operator/utils"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetWorkload(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "system-app", Namespace: "3scale"}}
+	deploymentConfig := &openshiftappsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "system-sidekiq", Namespace: "3scale"},
+		Spec:       openshiftappsv1.DeploymentConfigSpec{Template: &corev1.PodTemplateSpec{}},
+	}
+	client := utils.NewTestClient(scheme, deployment, deploymentConfig)
+
+	workload, err := GetWorkload(context.TODO(), client, "system-app", "3scale")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := workload.(*appsv1.Deployment); !ok {
+		t.Errorf("expected a Deployment, got %T", workload)
+	}
+
+	workload, err = GetWorkload(context.TODO(), client, "system-sidekiq", "3scale")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := workload.(*openshiftappsv1.DeploymentConfig); !ok {
+		t.Errorf("expected a DeploymentConfig, got %T", workload)
+	}
+	if template, err := WorkloadPodTemplate(workload); err != nil || template == nil {
+		t.Errorf("expected the pod template of the DeploymentConfig, got %v", err)
+	}
+
+	if _, err = GetWorkload(context.TODO(), client, "zync", "3scale"); !k8serr.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got %v", err)
+	}
+
+	// clusters without DeploymentConfigs
+	if _, err = GetWorkload(context.TODO(), fake.NewClientBuilder().Build(), "zync", "3scale"); !k8serr.IsNotFound(err) {
+		t.Errorf("expected a NotFound error without the DeploymentConfig API, got %v", err)
+	}
+}
+
+func TestWorkloadStatus(t *testing.T) {
+	scenarios := []struct {
+		Name     string
+		Workload k8sclient.Object
+		Failed   bool
+		Ready    bool
+	}{
+		{
+			Name: "Deployment rolled out",
+			Workload: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{
+					Replicas: 2, AvailableReplicas: 2, ReadyReplicas: 2, UpdatedReplicas: 2,
+					Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}},
+				},
+			},
+			Ready: true,
+		},
+		{
+			Name: "Deployment rolling out",
+			Workload: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Replicas: 2, AvailableReplicas: 1, ReadyReplicas: 1, UpdatedReplicas: 2},
+			},
+		},
+		{
+			Name: "Deployment exceeded its progress deadline",
+			Workload: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{
+					Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse}},
+				},
+			},
+			Failed: true,
+		},
+		{
+			Name: "DeploymentConfig rolled out",
+			Workload: &openshiftappsv1.DeploymentConfig{
+				Status: openshiftappsv1.DeploymentConfigStatus{
+					Replicas: 1, AvailableReplicas: 1, ReadyReplicas: 1, UpdatedReplicas: 1,
+					Conditions: []openshiftappsv1.DeploymentCondition{{Status: corev1.ConditionTrue}},
+				},
+			},
+			Ready: true,
+		},
+		{
+			Name: "DeploymentConfig failed",
+			Workload: &openshiftappsv1.DeploymentConfig{
+				Status: openshiftappsv1.DeploymentConfigStatus{
+					Conditions: []openshiftappsv1.DeploymentCondition{{Status: corev1.ConditionFalse}},
+				},
+			},
+			Failed: true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.Name, func(t *testing.T) {
+			if failed := IsWorkloadFailed(scenario.Workload); failed != scenario.Failed {
+				t.Errorf("IsWorkloadFailed() = %v, want %v", failed, scenario.Failed)
+			}
+			if ready := IsWorkloadReady(scenario.Workload); ready != scenario.Ready {
+				t.Errorf("IsWorkloadReady() = %v, want %v", ready, scenario.Ready)
+			}
+		})
+	}
+}
+
+func TestRestartDeployment(t *testing.T) {
+	scheme, err := utils.NewTestScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "system-app", Namespace: "3scale"}}
+	client := utils.NewTestClient(scheme, deployment)
+
+	if err := RestartDeployment(context.TODO(), client, deployment); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restarted := &appsv1.Deployment{}
+	if err := client.Get(context.TODO(), k8sclient.ObjectKeyFromObject(deployment), restarted); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.Spec.Template.Annotations[restartedAtAnnotation] == "" {
+		t.Errorf("expected the pod template to be annotated with the restart time")
+	}
+}